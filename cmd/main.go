@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,10 +12,57 @@ import (
 	"syscall"
 	"time"
 
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/clients/hlr"
+	"ai_dialer_mini/internal/clients/mysql"
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/clients/punctuation"
+	"ai_dialer_mini/internal/clients/redis"
+	"ai_dialer_mini/internal/clients/sqlite"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/xfyun"
 	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/lang"
+	"ai_dialer_mini/internal/logger"
 	"ai_dialer_mini/internal/middleware"
+	"ai_dialer_mini/internal/migrations"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/repositories"
 	"ai_dialer_mini/internal/routes"
 	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/assist"
+	"ai_dialer_mini/internal/services/campaign"
+	"ai_dialer_mini/internal/services/consent"
+	"ai_dialer_mini/internal/services/cron"
+	"ai_dialer_mini/internal/services/dataset"
+	"ai_dialer_mini/internal/services/dnd"
+	"ai_dialer_mini/internal/services/fallback"
+	"ai_dialer_mini/internal/services/filler"
+	"ai_dialer_mini/internal/services/flow"
+	"ai_dialer_mini/internal/services/hooks"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/llmcache"
+	"ai_dialer_mini/internal/services/outbox"
+	"ai_dialer_mini/internal/services/postprocess"
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/qa"
+	"ai_dialer_mini/internal/services/redaction"
+	"ai_dialer_mini/internal/services/scripting"
+	"ai_dialer_mini/internal/services/sentiment"
+	"ai_dialer_mini/internal/services/session"
+	"ai_dialer_mini/internal/services/streamauth"
+	"ai_dialer_mini/internal/services/tap"
+	"ai_dialer_mini/internal/services/tenant"
+	"ai_dialer_mini/internal/services/throttle"
+	"ai_dialer_mini/internal/services/wrapup"
 	"ai_dialer_mini/internal/services/ws"
 
 	"github.com/gin-gonic/gin"
@@ -22,6 +71,25 @@ import (
 func main() {
 	// 配置日志输出
 	log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+
+	// 数据库迁移子命令
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("数据库迁移失败: %v\n", err)
+		}
+		return
+	}
+
+	// 服务子命令: serve [--role=api,media,worker]，不带子命令时按配置文件中的角色运行
+	var roleFlag string
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		fs := flag.NewFlagSet("serve", flag.ExitOnError)
+		fs.StringVar(&roleFlag, "role", "", "进程角色，逗号分隔的 api、media、worker 或 all")
+		if err := fs.Parse(os.Args[2:]); err != nil {
+			log.Fatalf("解析启动参数失败: %v\n", err)
+		}
+	}
+
 	log.Println("开始初始化服务...")
 
 	// 加载配置文件
@@ -29,8 +97,22 @@ func main() {
 	if err != nil {
 		log.Fatalf("加载配置文件失败: %v\n", err)
 	}
+	logger.Configure(cfg.Logging)
 	log.Println("配置文件加载成功")
 
+	// 进程角色：api处理HTTP接口，media处理通话事件和音频，worker执行后台任务
+	if roleFlag != "" {
+		cfg.Server.Role = roleFlag
+	}
+	roles, err := config.ParseRoles(cfg.Server.Role)
+	if err != nil {
+		log.Fatalf("进程角色无效: %v\n", err)
+	}
+	log.Printf("进程角色: %s\n", roles)
+
+	// 进程生命周期：启动完成后才就绪，收到关闭信号时先排空进行中的通话再退出
+	lifecycleManager := lifecycle.New(cfg.Lifecycle)
+
 	// 创建对话服务
 	dialogService := services.NewDialogService(cfg)
 	if dialogService == nil {
@@ -39,12 +121,550 @@ func main() {
 		log.Println("对话服务初始化成功")
 	}
 
-	// 创建WebSocket服务
-	wsService := ws.NewASRServer(cfg, dialogService)
-	if wsService == nil {
-		log.Println("警告: WebSocket服务初始化失败")
+	// 静态加密：启用后通话转写和Redis中的对话历史加密保存，密钥无效时不能以明文保存，直接退出
+	var keyring *encryption.Keyring
+	if cfg.Encryption.Enabled {
+		keyring, err = encryption.New(cfg.Encryption)
+		if err != nil {
+			log.Fatalf("静态加密配置无效: %v\n", err)
+		}
+		log.Printf("静态加密已启用，租户: %s\n", cfg.Encryption.Tenant)
+	}
+
+	// 后台任务上下文，服务关闭时取消
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	// 大模型熔断：Ollama连续失败后返回兜底回复，熔断期间定期探测服务是否恢复
+	if breaker, ok := dialogService.LLMClient().(*ollama.Breaker); ok {
+		go breaker.Run(bgCtx)
+		log.Println("Ollama熔断已启用")
+	}
+
+	// 连接数据库并启动发件箱投递任务
+	var store *repositories.Store
+	var cdrService *services.CDRService
+	var ob *outbox.Outbox
+	db, dialect, err := openDatabase(cfg)
+	if err != nil {
+		log.Printf("警告: 数据库连接失败，通话详单与外部推送不可用: %v\n", err)
 	} else {
-		log.Println("WebSocket服务初始化成功")
+		defer db.Close()
+		if dialect.IsSQLite() {
+			log.Printf("使用SQLite数据库: %s\n", cfg.Database.SQLite.Path)
+		}
+		ob = outbox.New(db, outbox.Config{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+			Timeout:      cfg.Webhook.Timeout,
+		})
+		ob.SetDialect(dialect)
+		if err := checkSchema(bgCtx, db, dialect, cfg.MySQL.AutoMigrate || dialect.IsSQLite()); err != nil {
+			log.Printf("警告: %v，通话详单与外部推送不可用\n", err)
+		} else {
+			store = repositories.NewStore(db)
+			store.SetDialect(dialect)
+			store.SetKeyring(keyring)
+			if cfg.Redaction.Enabled {
+				rules, err := redaction.New(cfg.Redaction)
+				if err != nil {
+					log.Fatalf("转写脱敏配置无效: %v\n", err)
+				}
+				store.SetRedactor(rules)
+				log.Println("转写脱敏已启用")
+			}
+			cdrService = services.NewCDRService(store, ob, cfg.Webhook)
+			if executor, err := wrapup.New(cfg.WrapUp, cfg.Webhook.CRMURL); err != nil {
+				log.Printf("警告: 挂机收尾动作配置无效: %v\n", err)
+			} else {
+				cdrService.SetWrapUp(executor)
+			}
+			if roles.Has(config.RoleWorker) {
+				go ob.Run(bgCtx)
+				log.Println("数据库连接成功，发件箱投递任务已启动")
+			} else {
+				log.Println("数据库连接成功")
+			}
+		}
+	}
+
+	// 拨打前号码状态查询，MySQL不可用时只过滤不记录
+	var reachability *services.ReachabilityService
+	if cfg.HLR.Enabled {
+		hlrClient, err := hlr.NewHTTPClient(cfg.HLR)
+		if err != nil {
+			log.Printf("警告: 号码状态查询不可用: %v\n", err)
+		} else {
+			reachability = services.NewReachabilityService(hlrClient, store, cfg.HLR.MaxAge)
+			log.Println("号码状态查询已启用")
+		}
+	}
+
+	// 连接Redis
+	var idempotencyService *services.IdempotencyService
+	redisClient, err := redis.NewClient(redis.Config{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	if err != nil {
+		log.Printf("警告: Redis连接失败，幂等请求不可用: %v\n", err)
+	} else {
+		defer redisClient.Close()
+		idempotencyService = services.NewIdempotencyService(redisClient, cfg.API.IdempotencyTTL)
+		log.Println("Redis连接成功")
+	}
+
+	// 对话会话存储：配置为redis时对话历史可在重启后恢复并在多实例间共享
+	if cfg.Session.Store == session.StoreRedis {
+		if redisClient == nil {
+			log.Println("警告: Redis不可用，对话历史保存在本地内存")
+		} else {
+			sessionStore := session.NewRedisStore(redisClient, cfg.Session)
+			sessionStore.SetKeyring(keyring)
+			dialogService.SetSessionStore(sessionStore)
+			log.Println("对话历史保存在Redis")
+		}
+	}
+
+	// 大模型回复缓存：相同的问候语和常见问题直接返回缓存的回复，不再请求大模型
+	if cfg.LLM.Cache.Enabled {
+		if redisClient == nil {
+			log.Println("警告: Redis不可用，大模型回复缓存未启用")
+		} else {
+			dialogService.SetCache(llmcache.New(redisClient, cfg.LLM.Cache, cfg.LLM.Model()))
+			log.Println("大模型回复缓存已启用")
+		}
+	}
+
+	// 连接FreeSWITCH并注册通话事件处理
+	var callService *services.CallServiceImpl
+	var eslClient *freeswitch.ESLClient
+	if cfg.FreeSWITCH.Host != "" {
+		eslClient = freeswitch.NewESLClient(freeswitch.ESLConfig{
+			Host:              cfg.FreeSWITCH.Host,
+			Port:              cfg.FreeSWITCH.Port,
+			Password:          cfg.FreeSWITCH.Password,
+			Events:            cfg.FreeSWITCH.Events,
+			Filters:           cfg.FreeSWITCH.Filters,
+			ReconnectDelay:    cfg.FreeSWITCH.ReconnectDelay,
+			MaxReconnectDelay: cfg.FreeSWITCH.MaxReconnectDelay,
+		})
+		if err := eslClient.Connect(); err != nil {
+			log.Printf("警告: 连接FreeSWITCH失败: %v\n", err)
+		} else {
+			defer eslClient.Close()
+			callService = services.NewCallService(eslClient)
+			if roles.Has(config.RoleMedia) {
+				lifecycleManager.Track("calls", callService.Sessions().Count)
+			}
+			if cdrService != nil {
+				callService.SetCDRService(cdrService)
+			}
+			if cfg.WrapUp.Duration > 0 {
+				callService.SetWrapUp(cfg.WrapUp.Duration)
+				log.Printf("话后处理已启用，时长: %v\n", cfg.WrapUp.Duration)
+			}
+			if cfg.Recording.Consent.Enabled {
+				callService.SetConsent(consent.New(cfg.Recording.Consent))
+				log.Println("录音授权已启用")
+			}
+			log.Println("FreeSWITCH连接成功")
+		}
+	}
+
+	// 通话音频流：应答后FreeSWITCH通过短期签名地址推送通话音频
+	var streamSigner *streamauth.Signer
+	if cfg.AudioStream.Enabled && roles.Has(config.RoleMedia) {
+		streamSigner, err = streamauth.New(cfg.AudioStream)
+		if err != nil {
+			log.Fatalf("通话音频流配置无效: %v\n", err)
+		}
+		if callService != nil {
+			eslClient.EnableAudioStreams(streamSigner.Sign, freeswitch.AudioStreamConfig{
+				MaxRestarts:  cfg.AudioStream.MaxRestarts,
+				RestartDelay: cfg.AudioStream.RestartDelay,
+				SampleRate:   cfg.AudioStream.SampleRate,
+				Codec:        cfg.AudioStream.Codec,
+			})
+		}
+		log.Println("通话音频流已启用")
+	}
+
+	// 呼入：匹配路由的呼入通道自动应答，应答后经音频流接入AI对话
+	if cfg.Inbound.Enabled && callService != nil && roles.Has(config.RoleMedia) {
+		if streamSigner == nil {
+			log.Printf("警告: 未启用通话音频流，呼入通话应答后无法接入AI对话\n")
+		}
+		callService.SetInbound(inbound.New(cfg.Inbound))
+		log.Println("呼入已启用")
+	}
+
+	// 多租户：外呼按租户设置通道变量和外显号码，音频流连接按租户使用独立的讯飞凭据、大模型和AI人设
+	tenants := tenant.New(cfg.Tenancy)
+	if tenants != nil {
+		if callService != nil {
+			callService.SetTenants(tenants)
+		}
+		log.Printf("多租户已启用，租户数: %d\n", len(cfg.Tenancy.Tenants))
+	}
+
+	// Lua脚本钩子：与编译时注册的钩子一起在会话事件时调用
+	var scriptEngine *scripting.Engine
+	if cfg.Scripting.Enabled {
+		if engine, err := scripting.New(cfg.Scripting); err != nil {
+			log.Printf("警告: 加载Lua脚本失败，脚本钩子不可用: %v\n", err)
+		} else {
+			scriptEngine = engine
+			hooks.Register(scriptEngine)
+			log.Printf("已加载Lua脚本: %d个\n", scriptEngine.Len())
+		}
+	}
+
+	// 实时转写保存：通话中的客户语音、AI回复、按键和接通挂断事件写入数据库，供质检和合规审查导出
+	var transcriptRecorder *services.TranscriptRecorder
+	if cfg.Transcript.Live && store != nil {
+		transcriptRecorder = services.NewTranscriptRecorder(store.Transcripts)
+		if analyzer := sentiment.New(cfg.Transcript.Sentiment, dialogService.LLMClient()); analyzer != nil {
+			transcriptRecorder.SetSentiment(analyzer)
+			log.Printf("客户情绪识别已启用: %s", cfg.Transcript.Sentiment.Provider)
+		}
+		if callService != nil {
+			callService.SetTranscripts(transcriptRecorder)
+		}
+		log.Println("通话转写实时保存已启用")
+	}
+
+	// 会话事件钩子：编译时通过hooks.Register注册的钩子在通话接通、客户说完一句话、AI回复和挂机时调用
+	sessionHooks := hooks.Default()
+	if sessionHooks != nil {
+		if callService != nil {
+			callService.SetHooks(sessionHooks)
+			if scriptEngine != nil {
+				scriptEngine.SetVariables(callService)
+			}
+		}
+		log.Printf("已注册会话事件钩子: %d个\n", sessionHooks.Len())
+	}
+
+	// 通话事件只由media角色处理，避免多个进程重复写入详单；
+	// 未配置订阅列表时只订阅已注册处理器的事件，需在通话服务和音频流注册处理器之后订阅
+	if callService != nil && roles.Has(config.RoleMedia) {
+		if err := eslClient.SubscribeEvents(); err != nil {
+			log.Printf("警告: 订阅FreeSWITCH事件失败: %v\n", err)
+		}
+	}
+
+	// 录音归档：挂机后将录音转码为配置的格式存入对象存储，数据库可用时登记索引供查询和下载
+	var recordingService *services.RecordingService
+	if cfg.Recording.Enabled && roles.Has(config.RoleAPI) && store != nil {
+		if objectStore, err := storage.New(cfg.Storage); err != nil {
+			log.Printf("警告: 对象存储初始化失败，录音查询和下载不可用: %v\n", err)
+		} else {
+			recordingService = services.NewRecordingService(store, objectStore)
+		}
+	}
+	if cfg.Recording.Enabled && roles.Has(config.RoleMedia) && callService != nil {
+		recordingCodec, err := codec.New(cfg.Recording.Codec)
+		if err != nil {
+			log.Printf("警告: 录音编码器初始化失败，录音不归档: %v\n", err)
+		} else if objectStore, err := storage.New(cfg.Storage); err != nil {
+			log.Printf("警告: 对象存储初始化失败，录音不归档: %v\n", err)
+		} else {
+			archiver := services.NewRecordingArchiver(objectStore, recordingCodec, cfg.Recording)
+			if cfg.Recording.Transcribe {
+				if store != nil {
+					archiver.SetTranscriber(services.NewOfflineTranscriber(xfyun.NewASRClient(cfg.ASR.XFYun, nil),
+						store.Transcripts, cfg.Recording.LeftSpeaker, cfg.Recording.RightSpeaker))
+				} else {
+					log.Println("警告: 数据库不可用，录音不做离线转写")
+				}
+			}
+			if store != nil {
+				archiver.SetIndex(store.Recordings)
+			}
+			go archiver.Run(bgCtx)
+			callService.SetRecordingArchiver(archiver)
+			log.Printf("录音归档已启用，格式: %s\n", recordingCodec.Extension())
+		}
+	}
+
+	// 外呼任务：按任务的拨打速率领取到期线索发起呼叫，挂机后更新线索结果
+	var campaignManager *campaign.Manager
+	if store != nil {
+		if callService != nil {
+			campaignManager = campaign.New(store, callService, cfg.Campaign)
+			if reachability != nil {
+				reachability.SetUnreachableHandler(campaignManager.MarkUnreachable)
+				campaignManager.SetReachability(reachability)
+			}
+			campaignManager.SetLifecycle(lifecycleManager)
+			if cfg.Campaign.QuietHours.Enabled {
+				quietHours, err := dnd.New(cfg.Campaign.QuietHours)
+				if err != nil {
+					log.Printf("警告: 免打扰时段配置无效，不限制拨打时段: %v", err)
+				} else {
+					campaignManager.SetQuietHours(quietHours)
+					log.Printf("外呼免打扰时段已启用，被叫当地 %s-%s 允许拨打", cfg.Campaign.QuietHours.Start, cfg.Campaign.QuietHours.End)
+				}
+			}
+			if roles.Has(config.RoleWorker) {
+				go campaignManager.Run(bgCtx)
+			}
+		} else {
+			campaignManager = campaign.New(store, nil, cfg.Campaign)
+			log.Println("警告: FreeSWITCH不可用，外呼任务只能管理，不会发起呼叫")
+		}
+		if cdrService != nil {
+			cdrService.SetLeadResults(campaignManager)
+		}
+		campaignManager.SetOutbox(ob)
+		go campaignManager.RunMetrics(bgCtx)
+	}
+
+	// 通话自动质检：挂机后按评分表为通话转写打分，未启用时仍可手动评估
+	var qaEvaluator *qa.Evaluator
+	if store != nil {
+		qaEvaluator = qa.New(store, dialogService.LLMClient(), cfg.QA)
+		if cfg.QA.Enabled && roles.Has(config.RoleWorker) {
+			go qaEvaluator.Run(bgCtx)
+			log.Println("通话自动质检已启用")
+		}
+	}
+
+	// 定时任务调度：周期性任务按cron表达式注册，执行情况通过运维接口查看
+	location, _ := cfg.Cron.Location()
+	scheduler := cron.New(location)
+	var datasetExporter *dataset.Exporter
+	if store != nil {
+		datasetExporter = dataset.New(store, cfg.Dataset)
+		if cfg.Dataset.Schedule != "" {
+			if err := scheduler.Register("dataset_export", cfg.Dataset.Schedule, datasetExporter.ExportScheduled); err != nil {
+				log.Printf("警告: %v\n", err)
+			}
+		}
+	}
+	if store != nil && keyring != nil && cfg.Encryption.RekeySchedule != "" {
+		rekey := services.NewTranscriptService(store).Rekey
+		if err := scheduler.Register("transcript_rekey", cfg.Encryption.RekeySchedule, rekey); err != nil {
+			log.Printf("警告: %v\n", err)
+		}
+	}
+	if roles.Has(config.RoleWorker) {
+		go scheduler.Run(bgCtx)
+	}
+
+	// 网关接通统计，配置了落地网关时外呼经网关呼出，接通率过低的网关排在最后
+	var gatewayService *services.GatewayService
+	if store != nil {
+		gatewayService = services.NewGatewayService(store, cfg.Routing)
+		go gatewayService.Run(bgCtx)
+	}
+	if callService != nil && len(cfg.Routing.Gateways) > 0 {
+		if gatewayService != nil {
+			callService.SetGatewayRouter(gatewayService)
+		} else {
+			callService.SetGatewayRouter(services.NewGatewayService(nil, cfg.Routing))
+		}
+		log.Printf("外呼路由已启用，落地网关: %v\n", cfg.Routing.Gateways)
+	}
+
+	// 创建监控面板推送中心，Redis可用时通过发布订阅接收所有实例的通话事件
+	dashboardHub := services.NewWSService()
+	go dashboardHub.Run()
+	eventBridge := services.NewEventBridge(redisClient, cfg.Redis.EventChannel, dashboardHub)
+	go eventBridge.Run(bgCtx)
+	if callService != nil && roles.Has(config.RoleMedia) {
+		callService.SetEventPublisher(eventBridge)
+	}
+	if callService != nil {
+		// ESL连接断开期间收不到通话事件，断开和恢复时向监控面板推送告警
+		eslClient.OnDisconnect(func(err error) {
+			publishSwitchStatus(eventBridge, fmt.Sprintf("FreeSWITCH连接断开，正在重连: %v", err))
+		})
+		eslClient.OnReconnect(func() {
+			publishSwitchStatus(eventBridge, "FreeSWITCH连接已恢复")
+		})
+	}
+
+	if cfg.WebSocket.DevAllowAllOrigins {
+		log.Println("警告: 已开启websocket.dev_allow_all_origins，任意网页都可以连接WebSocket接口，不要在生产环境使用")
+	}
+
+	// 创建WebSocket服务，只在media角色运行
+	var wsService *ws.ASRServer
+	if roles.Has(config.RoleMedia) {
+		wsService = ws.NewASRServer(cfg, dialogService)
+		if wsService == nil {
+			log.Println("警告: WebSocket服务初始化失败")
+		} else {
+			wsService.Events = eventBridge
+			if cfg.ASR.XFYun.Pool.Enabled {
+				pool := xfyun.NewConnPool(cfg.ASR.XFYun)
+				go pool.Run(bgCtx)
+				wsService.ASRClient.SetPool(pool)
+				log.Printf("讯飞连接预热已启用，空闲连接数: %d\n", cfg.ASR.XFYun.Pool.Size)
+			}
+			wsService.Lifecycle = lifecycleManager
+			lifecycleManager.Track("websockets", wsService.ActiveConnections)
+			lifecycleManager.Depend("asr", wsService.ASRDependency)
+			wsService.Hooks = sessionHooks
+			if transcriptRecorder != nil {
+				wsService.Transcripts = transcriptRecorder
+			}
+			wsService.Tenants = tenants
+			if cfg.ASR.Whisper.ServerURL != "" {
+				// 默认后端排在第一位，启用故障切换时另一个后端作为备用
+				providers := []models.ASRProvider{wsService.ASRClient.Provider(), asr.NewWhisperClient(cfg.ASR.Whisper)}
+				if cfg.ASR.Provider == config.ProviderWhisper {
+					providers[0], providers[1] = providers[1], providers[0]
+				}
+				wsService.ASR = asr.NewRouter(cfg.ASR.Failover, providers...)
+				log.Printf("Whisper识别后端已启用，默认后端: %s，故障切换: %v\n", cfg.ASR.Provider, cfg.ASR.Failover.Enabled)
+			}
+			if scriptEngine != nil {
+				scriptEngine.SetSpeaker(wsService)
+			}
+			if callService != nil {
+				wsService.Calls = callService.Sessions()
+				wsService.Control = callService
+				if cfg.Recording.Consent.Enabled {
+					wsService.Consent = callService
+				}
+				if cfg.Inbound.Enabled {
+					wsService.Inbound = callService.Sessions()
+				}
+				if cfg.DTMF.Enabled {
+					callService.Sessions().SetMenus(cfg.DTMF, wsService.HandleDTMF)
+					wsService.Menus = callService.Sessions()
+				}
+			}
+			if cfg.Intent.Enabled {
+				engine, err := intent.New(cfg.Intent)
+				if err != nil {
+					log.Printf("警告: 意图识别初始化失败: %v\n", err)
+				} else {
+					wsService.Intents = engine
+					log.Printf("意图识别已启用，共 %d 条规则\n", len(cfg.Intent.Rules))
+				}
+			}
+			var punctuator postprocess.Punctuator
+			if cfg.ASR.Punctuation.Enabled {
+				client, err := punctuation.NewClient(cfg.ASR.Punctuation)
+				if err != nil {
+					log.Printf("警告: 文本后处理服务不可用: %v\n", err)
+				} else {
+					punctuator = client
+					log.Printf("识别结果文本后处理已启用: %s\n", cfg.ASR.Punctuation.URL)
+				}
+			}
+			if chain := postprocess.New(cfg.ASR.PostProcess, punctuator); len(chain) > 0 {
+				wsService.PostProcess = chain
+				log.Printf("识别结果后处理已启用，共 %d 个步骤\n", len(chain))
+			}
+			ttsProvider, err := services.NewTTSProvider(cfg)
+			if err != nil {
+				log.Printf("警告: 语音合成初始化失败，AI回复只返回文本: %v\n", err)
+			} else if ttsProvider != nil {
+				wsService.TTS = ttsProvider
+				log.Printf("语音合成已启用: %s\n", cfg.TTS.Provider)
+				if cfg.TTS.Fillers.Enabled {
+					fillers, err := filler.New(bgCtx, ttsProvider, cfg.TTS.Fillers)
+					if err != nil {
+						log.Printf("警告: 应答语音初始化失败: %v\n", err)
+					} else {
+						wsService.Fillers = fillers
+						log.Printf("应答语音已启用，共 %d 条\n", len(cfg.TTS.Fillers.Phrases))
+					}
+				}
+				if cfg.TTS.Playback.Enabled && callService != nil {
+					playback, err := services.NewSpeechPlayback(eslClient, cfg.TTS.Playback)
+					if err != nil {
+						log.Printf("警告: 通话语音播放初始化失败: %v\n", err)
+					} else {
+						wsService.Playback = playback
+						callService.Sessions().SetPlayback(playback)
+						log.Println("通话语音播放已启用")
+					}
+				}
+			}
+			log.Println("WebSocket服务初始化成功")
+		}
+	}
+
+	// 语音识别降级：识别连续失败时按活动暂停拨号、转人工或致歉后回拨，不可用期间定期探测恢复
+	var asrMonitor *fallback.Monitor
+	if cfg.ASRFallback.Enabled && wsService != nil {
+		probe := wsService.ASRClient.Ping
+		if wsService.ASR != nil {
+			probe = wsService.ASR.Ping
+		}
+		asrMonitor = fallback.NewMonitor(cfg.ASRFallback, probe)
+		go asrMonitor.Run(bgCtx)
+		wsService.ASRHealth = asrMonitor
+		if campaignManager != nil {
+			campaignManager.SetASRFallback(asrMonitor)
+		}
+		log.Println("语音识别降级策略已启用")
+	}
+
+	// 外呼自动限速：语音识别或大模型的P95延迟、错误率超过阈值时降低拨打速率，指标恢复后恢复
+	if cfg.Campaign.AutoThrottle.Enabled && campaignManager != nil {
+		governor := throttle.New(cfg.Campaign.AutoThrottle)
+		governor.SetPublisher(eventBridge)
+		dialogService.SetLatencyObserver(governor)
+		if wsService != nil {
+			wsService.Metrics = governor
+		} else {
+			log.Println("警告: 本实例未处理语音识别，自动限速只统计大模型指标")
+		}
+		campaignManager.SetThrottle(governor)
+		log.Println("外呼自动限速已启用")
+	}
+
+	// 创建通话实时监听
+	var audioTap *tap.Tap
+	if cfg.AudioTap.Enabled && wsService != nil {
+		audioTap = tap.New(cfg.AudioTap)
+		wsService.Tap = audioTap
+		log.Println("通话实时监听已启用")
+	}
+
+	// 创建坐席辅助，未配置知识片段时使用默认提示词配置的业务知识
+	if cfg.Assist.Enabled && wsService != nil {
+		wsService.Assist = assist.New(cfg.Assist, cfg.LLM.Prompt.Knowledge)
+		log.Println("坐席辅助已启用")
+	}
+
+	// 预录提示音：api角色提供上传接口，media角色在通话中播放
+	var promptLibrary *promptaudio.Library
+	if cfg.PromptAudio.Enabled && (roles.Has(config.RoleAPI) || wsService != nil) {
+		if objectStore, err := storage.New(cfg.Storage); err != nil {
+			log.Printf("警告: 对象存储初始化失败，预录提示音不可用: %v\n", err)
+		} else {
+			promptLibrary = promptaudio.New(objectStore, cfg.PromptAudio)
+			if wsService != nil {
+				wsService.Prompts = promptLibrary
+			}
+			log.Println("预录提示音已启用")
+		}
+	}
+
+	// 创建飞行记录仪
+	var flightRecorder *recorder.Recorder
+	if cfg.Recorder.Enabled && wsService != nil {
+		objectStore, err := storage.New(cfg.Storage)
+		if err != nil {
+			log.Printf("警告: 对象存储初始化失败，飞行记录仪不可用: %v\n", err)
+		} else {
+			flightRecorder = recorder.New(objectStore, cfg.Recorder)
+			go flightRecorder.Run(bgCtx)
+			dialogService.SetRecorder(flightRecorder)
+			wsService.ASRClient.SetRecorder(flightRecorder)
+			wsService.Recorder = flightRecorder
+			log.Printf("飞行记录仪已启用，抽样比例: %.2f%%\n", cfg.Recorder.SampleRate*100)
+		}
 	}
 
 	// 创建Gin引擎
@@ -57,8 +677,101 @@ func main() {
 	r.Use(middleware.Logger())
 	log.Println("中间件注册成功")
 
-	// 注册所有路由
-	routes.RegisterRoutes(r, wsService, cfg.XFYun, cfg.Ollama)
+	// 按进程角色注册路由，所有角色都提供健康检查、就绪检查和排空
+	routes.RegisterLifecycleRoutes(r, handlers.NewLifecycleHandler(lifecycleManager), cfg.Admin.Tokens)
+	routes.RegisterMetricsRoutes(r)
+	if roles.Has(config.RoleMedia) {
+		registerMediaRoutes(r, cfg, wsService, streamSigner, audioTap, flightRecorder)
+	} else {
+		routes.RegisterHealthRoutes(r)
+	}
+	if roles.Has(config.RoleWorker) && len(cfg.Admin.Tokens) > 0 {
+		routes.RegisterCronRoutes(r, handlers.NewCronHandler(scheduler), cfg.Admin.Tokens)
+	}
+	if roles.Has(config.RoleAPI) {
+		routes.RegisterDashboardRoutes(r, dashboardHub)
+		routes.RegisterSessionRoutes(r, handlers.NewSessionHandler(dialogService))
+		routes.RegisterCacheRoutes(r, handlers.NewCacheHandler())
+		if len(cfg.Admin.Tokens) > 0 {
+			routes.RegisterAdminRoutes(r, handlers.NewLogLevelHandler("config.yaml"), cfg.Admin.Tokens)
+		} else {
+			log.Println("警告: 未配置运维管理令牌(admin.tokens)，运维管理接口不可用")
+		}
+		if callService != nil {
+			callHandler := handlers.NewCallHandler(callService, idempotencyService)
+			callHandler.SetReachability(reachability)
+			callHandler.SetLifecycle(lifecycleManager)
+			if asrMonitor != nil {
+				var leads fallback.LeadScheduler
+				if store != nil {
+					leads = store.Leads
+				}
+				callHandler.SetASRFallback(asrMonitor, leads)
+			}
+			routes.RegisterCallRoutes(r, callHandler)
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterCallControlRoutes(r, callHandler, cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，挂断、转接和发送按键接口不可用")
+			}
+		}
+		if recordingService != nil {
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterRecordingRoutes(r, handlers.NewRecordingHandler(recordingService), cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，录音查询和下载接口不可用")
+			}
+		}
+		if promptLibrary != nil {
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterPromptAudioRoutes(r, handlers.NewPromptAudioHandler(promptLibrary), cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，提示音上传接口不可用")
+			}
+		}
+		if len(cfg.API.Tokens) > 0 {
+			routes.RegisterFlowRoutes(r, handlers.NewFlowHandler(flow.NewLinter(promptLibrary)), cfg.API.Tokens)
+		} else {
+			log.Println("警告: 未配置接口令牌(api.tokens)，通话流程检查和导出接口不可用")
+		}
+		if store != nil {
+			transcriptService := services.NewTranscriptService(store)
+			if cfg.Transcript.PinyinDict != "" {
+				pinyin, err := lang.LoadPinyin(cfg.Transcript.PinyinDict)
+				if err != nil {
+					log.Printf("警告: 拼音字典加载失败，转写检索不支持拼音: %v\n", err)
+				} else {
+					transcriptService.SetPinyin(pinyin)
+				}
+			}
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterTranscriptRoutes(r, handlers.NewTranscriptHandler(transcriptService), cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，转写检索和导出接口不可用")
+			}
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterAnnotationRoutes(r, handlers.NewAnnotationHandler(services.NewAnnotationService(store)), cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，通话标签和备注接口不可用")
+			}
+			if len(cfg.API.Tokens) > 0 {
+				routes.RegisterQARoutes(r, handlers.NewQAHandler(qaEvaluator), cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，通话质检接口不可用")
+			}
+			if len(cfg.Admin.Tokens) > 0 {
+				routes.RegisterDatasetRoutes(r, handlers.NewDatasetHandler(datasetExporter), cfg.Admin.Tokens)
+			}
+			routes.RegisterGatewayRoutes(r, handlers.NewGatewayHandler(gatewayService))
+			if len(cfg.API.Tokens) > 0 {
+				campaignHandler := handlers.NewCampaignHandler(campaignManager)
+				routes.RegisterCampaignRoutes(r, campaignHandler, cfg.API.Tokens)
+				routes.RegisterCampaignPreviewRoutes(r, campaignHandler, cfg.API.Tokens)
+			} else {
+				log.Println("警告: 未配置接口令牌(api.tokens)，外呼任务和预览拨号接口不可用")
+			}
+		}
+	}
 	log.Println("路由注册成功")
 
 	// 创建HTTP服务器
@@ -78,13 +791,24 @@ func main() {
 		}
 	}()
 
+	lifecycleManager.MarkReady()
 	log.Println("服务器启动成功")
 
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("收到关闭信号，正在关闭服务器...")
+	log.Println("收到关闭信号，正在排空进行中的通话...")
+
+	// 排空期间就绪检查返回503，HTTP服务继续处理进行中的通话和排空进度查询
+	if err := lifecycleManager.Wait(context.Background()); err != nil {
+		log.Printf("警告: %v\n", err)
+		// 宽限期内未结束的WebSocket连接由服务端关闭，按排空统计断开原因
+		if wsService != nil {
+			wsService.CloseAll()
+		}
+	}
+	log.Println("正在关闭服务器...")
 
 	// 设置关闭超时
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -93,6 +817,128 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("服务器关闭失败: %v\n", err)
 	}
+	flightRecorder.FlushAll(ctx)
 
 	log.Println("服务器已关闭")
 }
+
+// publishSwitchStatus 推送FreeSWITCH连接状态变化
+func publishSwitchStatus(events models.EventPublisher, text string) {
+	if err := events.Publish(context.Background(), &models.CallEvent{
+		Type: models.EventTypeSwitchConnection,
+		Text: text,
+	}); err != nil {
+		log.Printf("推送FreeSWITCH连接状态失败: %v", err)
+	}
+}
+
+// registerMediaRoutes 注册media角色的路由：语音识别WebSocket、通话音频流、实时监听和飞行记录仪
+func registerMediaRoutes(r *gin.Engine, cfg *config.Config, wsService *ws.ASRServer, streamSigner *streamauth.Signer,
+	audioTap *tap.Tap, flightRecorder *recorder.Recorder) {
+	routes.RegisterRoutes(r, wsService, cfg.ASR.XFYun, cfg.LLM.Ollama)
+	if wsService != nil && wsService.ASRClient.Pool() != nil && len(cfg.Admin.Tokens) > 0 {
+		routes.RegisterASRPoolRoutes(r, handlers.NewASRPoolHandler(wsService.ASRClient.Pool()), cfg.Admin.Tokens)
+	}
+	if streamSigner != nil && wsService != nil {
+		routes.RegisterStreamRoutes(r, wsService, streamSigner)
+		if len(cfg.API.Tokens) > 0 {
+			routes.RegisterCallDialogRoutes(r, handlers.NewCallDialogHandler(wsService), cfg.API.Tokens)
+		}
+	}
+	if audioTap != nil {
+		routes.RegisterTapRoutes(r, handlers.NewTapHandler(audioTap), cfg.AudioTap.Tokens)
+	}
+	if wsService != nil && wsService.Assist != nil {
+		routes.RegisterAssistRoutes(r, handlers.NewAssistHandler(wsService.Assist), cfg.Assist.Tokens)
+	}
+	if flightRecorder != nil {
+		routes.RegisterRecorderRoutes(r, handlers.NewRecorderHandler(flightRecorder))
+	}
+}
+
+// openDatabase 按配置的驱动打开数据库，返回对应的SQL方言
+func openDatabase(cfg *config.Config) (*sql.DB, database.Dialect, error) {
+	if cfg.Database.Driver == database.SQLite {
+		db, err := sqlite.Open(cfg.Database.SQLite.Path)
+		return db, database.SQLite, err
+	}
+	db, err := mysql.Open(cfg.MySQL.DSN())
+	return db, database.MySQL, err
+}
+
+// checkSchema 检查数据库版本，开启自动迁移时执行待执行的迁移
+func checkSchema(ctx context.Context, db *sql.DB, dialect database.Dialect, autoMigrate bool) error {
+	migrator, err := migrations.NewWithDialect(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	if autoMigrate {
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("已执行 %d 个数据库迁移\n", count)
+		}
+		return nil
+	}
+	return migrator.Verify(ctx)
+}
+
+// runMigrate 执行数据库迁移子命令: migrate up | migrate down [-steps N] | migrate status
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: ai_dialer_mini migrate up|down|status [-steps N] [-config config.yaml]")
+	}
+
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	steps := fs.Int("steps", 1, "回滚的迁移数量")
+	configPath := fs.String("config", "config.yaml", "配置文件路径")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置文件失败: %v", err)
+	}
+	db, dialect, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrations.NewWithDialect(db, dialect)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		count, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("已执行 %d 个迁移，当前版本: %d\n", count, migrator.Latest())
+	case "down":
+		count, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("已回滚 %d 个迁移\n", count)
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("当前版本: %d，最新版本: %d\n", status.Current, status.Latest)
+		for _, m := range status.Pending {
+			log.Printf("待执行: %04d_%s\n", m.Version, m.Name)
+		}
+	default:
+		return fmt.Errorf("未知的迁移命令: %s", args[0])
+	}
+	return nil
+}
@@ -1,4 +1,5 @@
 import json
+import os
 import time
 import hmac
 import base64
@@ -27,10 +28,10 @@ STATUS_LAST_FRAME = 2  # 最后一帧的标识
 
 class XFYunASRServer:
     def __init__(self):
-        # 科大讯飞API配置
-        self.APPID = "c0de4f24"
-        self.APISecret = "NWRhZDBkNzA5ZDQxNGMzYmQ1NWMwMWNh"
-        self.APIKey = "51012a35448538a8396dc564cf050f68"
+        # 科大讯飞API配置，从环境变量读取
+        self.APPID = os.environ.get("XFYUN_APP_ID", "")
+        self.APISecret = os.environ.get("XFYUN_API_SECRET", "")
+        self.APIKey = os.environ.get("XFYUN_API_KEY", "")
         
         # 科大讯飞实时语音识别接口地址
         self.xf_url = "wss://ws-api.xfyun.cn/v2/iat"
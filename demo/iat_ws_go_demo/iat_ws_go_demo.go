@@ -28,9 +28,9 @@ import (
  */
 var (
 	hostUrl   = "wss://iat-api.xfyun.cn/v2/iat"
-	appid     = "c0de4f24"
-	apiSecret = "NWRhZDBkNzA5ZDQxNGMzYmQ1NWMwMWNh"
-	apiKey    = "51012a35448538a8396dc564cf050f68"
+	appid     = os.Getenv("XFYUN_APP_ID")
+	apiSecret = os.Getenv("XFYUN_API_SECRET")
+	apiKey    = os.Getenv("XFYUN_API_KEY")
 	file      = "16k_10.pcm" //请填写您的音频文件路径
 
 )
@@ -0,0 +1,133 @@
+// Package codec 将录音编码为存储格式：WAV原样保存，FLAC无损压缩，Opus有损压缩
+// FLAC和Opus通过ffmpeg转码，长期保存的录音可按存储成本和音质需要选择格式；
+// 另提供通话音频流的G.711（PCMU/PCMA）解码
+package codec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// 存储格式
+const (
+	FormatWAV  = "wav"  // 不压缩
+	FormatFLAC = "flac" // 无损压缩，约为WAV的一半
+	FormatOpus = "opus" // 有损压缩，语音16kbps即可清晰可辨
+)
+
+// defaultOpusBitrate Opus默认码率（kbps）
+const defaultOpusBitrate = 24
+
+// Config 录音编码配置
+type Config struct {
+	Format  string `yaml:"format"`  // 存储格式，wav、flac或opus，默认wav
+	Bitrate int    `yaml:"bitrate"` // Opus码率（kbps），默认24
+	FFmpeg  string `yaml:"ffmpeg"`  // ffmpeg可执行文件路径，默认从PATH查找
+}
+
+// Codec 录音编码器
+type Codec interface {
+	// Encode 将WAV录音编码为存储格式
+	Encode(ctx context.Context, wav []byte) ([]byte, error)
+
+	// Extension 文件扩展名，不含点
+	Extension() string
+
+	// ContentType 存储时使用的MIME类型
+	ContentType() string
+}
+
+// New 根据配置创建编码器，FLAC和Opus要求能找到ffmpeg
+func New(config Config) (Codec, error) {
+	switch config.Format {
+	case FormatWAV, "":
+		return wavCodec{}, nil
+	case FormatFLAC, FormatOpus:
+	default:
+		return nil, fmt.Errorf("不支持的录音格式: %s", config.Format)
+	}
+
+	ffmpeg := config.FFmpeg
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	path, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return nil, fmt.Errorf("录音格式%s需要ffmpeg: %v", config.Format, err)
+	}
+
+	if config.Format == FormatFLAC {
+		return &ffmpegCodec{
+			path:        path,
+			args:        []string{"-c:a", "flac", "-f", "flac"},
+			extension:   "flac",
+			contentType: "audio/flac",
+		}, nil
+	}
+
+	bitrate := config.Bitrate
+	if bitrate <= 0 {
+		bitrate = defaultOpusBitrate
+	}
+	return &ffmpegCodec{
+		path:        path,
+		args:        []string{"-c:a", "libopus", "-b:a", strconv.Itoa(bitrate) + "k", "-application", "voip", "-f", "ogg"},
+		extension:   "opus",
+		contentType: "audio/ogg",
+	}, nil
+}
+
+// wavCodec 原样保存WAV
+type wavCodec struct{}
+
+// Encode 返回原始数据
+func (wavCodec) Encode(ctx context.Context, wav []byte) ([]byte, error) {
+	return wav, nil
+}
+
+// Extension 文件扩展名
+func (wavCodec) Extension() string {
+	return "wav"
+}
+
+// ContentType MIME类型
+func (wavCodec) ContentType() string {
+	return "audio/wav"
+}
+
+// ffmpegCodec 通过ffmpeg转码，数据经标准输入输出传递，不落临时文件
+type ffmpegCodec struct {
+	path        string
+	args        []string
+	extension   string
+	contentType string
+}
+
+// Encode 调用ffmpeg转码
+func (c *ffmpegCodec) Encode(ctx context.Context, wav []byte) ([]byte, error) {
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-f", "wav", "-i", "pipe:0"}, c.args...)
+	args = append(args, "pipe:1")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.path, args...)
+	cmd.Stdin = bytes.NewReader(wav)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("转码为%s失败: %v %s", c.extension, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Extension 文件扩展名
+func (c *ffmpegCodec) Extension() string {
+	return c.extension
+}
+
+// ContentType MIME类型
+func (c *ffmpegCodec) ContentType() string {
+	return c.contentType
+}
@@ -0,0 +1,179 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// 通话音频流的编码
+const (
+	EncodingL16  = "l16"  // 16位小端线性PCM
+	EncodingPCMU = "pcmu" // G.711 µ-law
+	EncodingPCMA = "pcma" // G.711 A-law
+)
+
+// encodingAliases 音频编码的常见写法
+var encodingAliases = map[string]string{
+	"":         EncodingL16,
+	"l16":      EncodingL16,
+	"pcm":      EncodingL16,
+	"linear16": EncodingL16,
+	"pcmu":     EncodingPCMU,
+	"ulaw":     EncodingPCMU,
+	"mulaw":    EncodingPCMU,
+	"g711u":    EncodingPCMU,
+	"pcma":     EncodingPCMA,
+	"alaw":     EncodingPCMA,
+	"g711a":    EncodingPCMA,
+}
+
+// ParseEncoding 解析音频编码名称，不区分大小写，未指定时为L16
+func ParseEncoding(name string) (string, error) {
+	encoding, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("不支持的音频编码: %s", name)
+	}
+	return encoding, nil
+}
+
+// G.711每个字节对应的16位采样，初始化时生成
+var (
+	ulawTable [256]int16
+	alawTable [256]int16
+)
+
+func init() {
+	for i := 0; i < 256; i++ {
+		ulawTable[i] = decodeULaw(byte(i))
+		alawTable[i] = decodeALaw(byte(i))
+	}
+}
+
+// DecodeToL16 将音频解码为16位小端PCM，L16原样返回；G.711每个字节解码为一个采样，采样率不变
+func DecodeToL16(encoding string, data []byte) []byte {
+	var table *[256]int16
+	switch encoding {
+	case EncodingPCMU:
+		table = &ulawTable
+	case EncodingPCMA:
+		table = &alawTable
+	default:
+		return data
+	}
+	out := make([]byte, len(data)*2)
+	for i, b := range data {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(table[b]))
+	}
+	return out
+}
+
+// EncodeULaw 将16位小端PCM编码为G.711 µ-law
+func EncodeULaw(pcm []byte) []byte {
+	out := make([]byte, len(pcm)/2)
+	for i := range out {
+		out[i] = encodeULaw(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return out
+}
+
+// EncodeALaw 将16位小端PCM编码为G.711 A-law
+func EncodeALaw(pcm []byte) []byte {
+	out := make([]byte, len(pcm)/2)
+	for i := range out {
+		out[i] = encodeALaw(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	return out
+}
+
+// G.711编解码参数，与ITU-T G.711参考实现一致
+const (
+	ulawBias = 0x84
+	ulawClip = 32635
+)
+
+// segmentEnds 各段的上限
+var segmentEnds = [8]int{0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF, 0x3FFF, 0x7FFF}
+
+// segment 查找采样所在的段，超出范围时返回8
+func segment(v int) int {
+	for i, end := range segmentEnds {
+		if v <= end {
+			return i
+		}
+	}
+	return len(segmentEnds)
+}
+
+// decodeULaw 解码一个µ-law字节
+func decodeULaw(u byte) int16 {
+	u = ^u
+	t := (int(u&0x0F) << 3) + ulawBias
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(ulawBias - t)
+	}
+	return int16(t - ulawBias)
+}
+
+// encodeULaw 编码一个采样为µ-law
+func encodeULaw(sample int16) byte {
+	v := int(sample)
+	mask := byte(0xFF)
+	if v < 0 {
+		v = -v
+		mask = 0x7F
+	}
+	if v > ulawClip {
+		v = ulawClip
+	}
+	v += ulawBias
+	seg := segment(v)
+	if seg >= 8 {
+		return 0x7F ^ mask
+	}
+	return (byte(seg<<4) | byte((v>>(seg+3))&0x0F)) ^ mask
+}
+
+// decodeALaw 解码一个A-law字节
+func decodeALaw(a byte) int16 {
+	a ^= 0x55
+	t := int(a&0x0F) << 4
+	seg := int(a&0x70) >> 4
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t = (t + 0x108) << (seg - 1)
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// encodeALaw 编码一个采样为A-law
+func encodeALaw(sample int16) byte {
+	v := int(sample)
+	mask := byte(0xD5)
+	if v < 0 {
+		v = -v - 8
+		mask = 0x55
+		if v < 0 {
+			v = 0
+		}
+	}
+	seg := segment(v)
+	if seg >= 8 {
+		return 0x7F ^ mask
+	}
+	a := byte(seg << 4)
+	if seg < 2 {
+		a |= byte((v >> 4) & 0x0F)
+	} else {
+		a |= byte((v >> (seg + 3)) & 0x0F)
+	}
+	return a ^ mask
+}
@@ -0,0 +1,163 @@
+// Package pcm 处理16位小端PCM音频：解析WAV、拆分声道、按能量切分语音片段
+package pcm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrInvalidWAV WAV格式无效或不是16位PCM
+var ErrInvalidWAV = errors.New("WAV格式无效")
+
+// Audio 16位小端PCM音频，多声道时按采样交错存放
+type Audio struct {
+	PCM        []byte // PCM数据
+	SampleRate int    // 采样率
+	Channels   int    // 声道数
+}
+
+// DecodeWAV 解析WAV文件，只支持16位PCM
+func DecodeWAV(data []byte) (*Audio, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, ErrInvalidWAV
+	}
+
+	audio := &Audio{}
+	var hasFormat bool
+	for pos := 12; pos+8 <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size > len(body) {
+			// FreeSWITCH录音未正常结束时data块大小可能未回填，取到文件末尾
+			size = len(body)
+		}
+		body = body[:size]
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, ErrInvalidWAV
+			}
+			format := binary.LittleEndian.Uint16(body[0:2])
+			bits := binary.LittleEndian.Uint16(body[14:16])
+			if format != 1 || bits != 16 {
+				return nil, fmt.Errorf("%w: 只支持16位PCM（格式%d，位深%d）", ErrInvalidWAV, format, bits)
+			}
+			audio.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			audio.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, ErrInvalidWAV
+			}
+			audio.PCM = body
+			return audio, nil
+		}
+		pos += 8 + size + size%2
+	}
+	return nil, ErrInvalidWAV
+}
+
+// Duration 音频时长
+func (a *Audio) Duration() time.Duration {
+	bytesPerSecond := a.SampleRate * a.Channels * 2
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(len(a.PCM)) * time.Second / time.Duration(bytesPerSecond)
+}
+
+// Channel 取出单个声道（从0开始）的PCM数据
+func (a *Audio) Channel(index int) []byte {
+	if a.Channels <= 1 {
+		return a.PCM
+	}
+	frame := a.Channels * 2
+	out := make([]byte, 0, len(a.PCM)/a.Channels)
+	for i := index * 2; i+1 < len(a.PCM); i += frame {
+		out = append(out, a.PCM[i], a.PCM[i+1])
+	}
+	return out
+}
+
+// SegmentConfig 语音片段切分参数
+type SegmentConfig struct {
+	Threshold  float64       // 静音阈值（RMS，0~32768），低于该值视为静音
+	MinSilence time.Duration // 静音持续超过该时长时切分
+	MinSpeech  time.Duration // 短于该时长的片段丢弃（咳嗽、按键音等）
+	Window     time.Duration // 能量计算窗口
+}
+
+// DefaultSegmentConfig 默认切分参数，适用于电话语音
+var DefaultSegmentConfig = SegmentConfig{
+	Threshold:  500,
+	MinSilence: 600 * time.Millisecond,
+	MinSpeech:  200 * time.Millisecond,
+	Window:     20 * time.Millisecond,
+}
+
+// Segment 语音片段
+type Segment struct {
+	Start time.Duration // 相对音频开始的起始时间
+	End   time.Duration // 相对音频开始的结束时间
+	PCM   []byte        // 片段PCM数据
+}
+
+// Split 按短时能量将单声道PCM切分为语音片段
+func Split(pcm []byte, sampleRate int, config SegmentConfig) []Segment {
+	windowBytes := int(int64(sampleRate)*int64(config.Window)/int64(time.Second)) * 2
+	if windowBytes <= 0 {
+		return nil
+	}
+	offset := func(pos int) time.Duration {
+		return time.Duration(pos/2) * time.Second / time.Duration(sampleRate)
+	}
+
+	var segments []Segment
+	start, lastVoice := -1, -1
+	flush := func(end int) {
+		if start >= 0 && offset(end)-offset(start) >= config.MinSpeech {
+			segments = append(segments, Segment{Start: offset(start), End: offset(end), PCM: pcm[start:end]})
+		}
+		start, lastVoice = -1, -1
+	}
+
+	for pos := 0; pos < len(pcm); pos += windowBytes {
+		end := pos + windowBytes
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+		if RMS(pcm[pos:end]) >= config.Threshold {
+			if start < 0 {
+				start = pos
+			}
+			lastVoice = end
+			continue
+		}
+		if start >= 0 && offset(end)-offset(lastVoice) >= config.MinSilence {
+			flush(lastVoice)
+		}
+	}
+	if start >= 0 {
+		flush(lastVoice)
+	}
+	return segments
+}
+
+// RMS 计算一段16位PCM的均方根能量
+func RMS(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
@@ -0,0 +1,95 @@
+// Package resample 16位小端单声道PCM的采样率转换，用于8kHz电话音频与16kHz识别音频互转
+package resample
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Resampler 线性插值的流式采样率转换器，跨分片保留插值所需的上一个采样，分片边界不产生爆音；
+// 降采样前没有低通滤波，只适用于电话语音这类高频能量很少的信号
+type Resampler struct {
+	from, to int
+	step     float64 // 相邻输出采样在输入中的间隔
+	pos      float64 // 下一个输出采样在输入中的位置，以上一分片的最后一个采样为0
+	prev     int16   // 上一分片的最后一个采样
+	hasPrev  bool
+	odd      []byte // 上一分片末尾不足一个采样的字节
+}
+
+// New 创建从from采样率转换到to采样率的转换器
+func New(from, to int) (*Resampler, error) {
+	if from <= 0 || to <= 0 {
+		return nil, fmt.Errorf("采样率必须大于0: %d -> %d", from, to)
+	}
+	return &Resampler{from: from, to: to, step: float64(from) / float64(to)}, nil
+}
+
+// From 输入采样率
+func (r *Resampler) From() int {
+	return r.from
+}
+
+// To 输出采样率
+func (r *Resampler) To() int {
+	return r.to
+}
+
+// Process 转换一个分片，返回可以输出的采样；采样率相同时原样返回
+func (r *Resampler) Process(pcm []byte) []byte {
+	if r.from == r.to {
+		return pcm
+	}
+	if len(r.odd) > 0 {
+		pcm = append(append([]byte(nil), r.odd...), pcm...)
+		r.odd = nil
+	}
+	if len(pcm)%2 != 0 {
+		r.odd = []byte{pcm[len(pcm)-1]}
+		pcm = pcm[:len(pcm)-1]
+	}
+	if len(pcm) == 0 {
+		return nil
+	}
+
+	// 上一分片的最后一个采样放在开头，与本分片的采样一起插值
+	samples := make([]int16, 0, len(pcm)/2+1)
+	if r.hasPrev {
+		samples = append(samples, r.prev)
+	}
+	for i := 0; i+1 < len(pcm); i += 2 {
+		samples = append(samples, int16(binary.LittleEndian.Uint16(pcm[i:])))
+	}
+
+	last := float64(len(samples) - 1)
+	out := make([]byte, 0, int(float64(len(samples))/r.step+2)*2)
+	for ; r.pos < last; r.pos += r.step {
+		i := int(r.pos)
+		frac := r.pos - float64(i)
+		v := float64(samples[i])*(1-frac) + float64(samples[i+1])*frac
+		out = binary.LittleEndian.AppendUint16(out, uint16(int16(v)))
+	}
+
+	// 位置换算到以本分片最后一个采样为0，下一分片从该采样继续插值
+	r.pos -= last
+	r.prev = samples[len(samples)-1]
+	r.hasPrev = true
+	return out
+}
+
+// Convert 一次性转换一段完整音频
+func Convert(pcm []byte, from, to int) ([]byte, error) {
+	r, err := New(from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := r.Process(pcm)
+	if from != to && len(pcm) >= 2 {
+		// 末尾采样之后没有后续采样可插值，补上最后一个采样使时长与输入一致
+		want := int(int64(len(pcm)/2) * int64(to) / int64(from))
+		for len(out)/2 < want {
+			out = binary.LittleEndian.AppendUint16(out, uint16(r.prev))
+		}
+	}
+	return out, nil
+}
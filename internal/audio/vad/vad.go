@@ -0,0 +1,86 @@
+// Package vad 对实时音频流做语音活动检测，按短时能量判断客户开始说话
+package vad
+
+import (
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+)
+
+// window 能量计算窗口
+const window = 20 * time.Millisecond
+
+// Config 语音活动检测配置
+type Config struct {
+	Enabled    bool          `yaml:"enabled"`     // 是否启用
+	Threshold  float64       `yaml:"threshold"`   // 语音能量阈值（RMS，0~32768），高于该值视为有声
+	MinSpeech  time.Duration `yaml:"min_speech"`  // 有声持续超过该时长才判定开始说话，过滤咳嗽、按键音等短促声音
+	MinSilence time.Duration `yaml:"min_silence"` // 静音持续超过该时长判定说话结束，之后可再次触发
+}
+
+// Detector 单路音频流的语音活动检测，非并发安全
+type Detector struct {
+	config      Config
+	windowBytes int
+
+	buf      []byte
+	speaking bool
+	voiced   time.Duration // 连续有声时长
+	silence  time.Duration // 连续静音时长
+}
+
+// NewDetector 创建语音活动检测，sampleRate为16位单声道PCM的采样率
+func NewDetector(config Config, sampleRate int) *Detector {
+	return &Detector{
+		config:      config,
+		windowBytes: int(int64(sampleRate)*int64(window)/int64(time.Second)) * 2,
+	}
+}
+
+// Speaking 当前是否处于说话状态
+func (d *Detector) Speaking() bool {
+	return d.speaking
+}
+
+// Write 输入一段PCM，客户开始说话时返回true，每次说话只返回一次
+func (d *Detector) Write(data []byte) bool {
+	if d.windowBytes <= 0 {
+		return false
+	}
+	d.buf = append(d.buf, data...)
+
+	started := false
+	for len(d.buf) >= d.windowBytes {
+		frame := d.buf[:d.windowBytes]
+		d.buf = d.buf[d.windowBytes:]
+
+		if pcm.RMS(frame) >= d.config.Threshold {
+			d.voiced += window
+			d.silence = 0
+			if !d.speaking && d.voiced >= d.config.MinSpeech {
+				d.speaking = true
+				started = true
+			}
+			continue
+		}
+
+		d.silence += window
+		if !d.speaking {
+			d.voiced = 0
+		} else if d.silence >= d.config.MinSilence {
+			d.speaking = false
+			d.voiced = 0
+		}
+	}
+	// 避免切片底层数组无限增长
+	d.buf = append([]byte(nil), d.buf...)
+	return started
+}
+
+// Reset 清除检测状态
+func (d *Detector) Reset() {
+	d.buf = nil
+	d.speaking = false
+	d.voiced = 0
+	d.silence = 0
+}
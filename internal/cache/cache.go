@@ -0,0 +1,255 @@
+// Package cache 进程内缓存：按容量LRU淘汰、按TTL过期，并发加载同一键时只加载一次，记录命中率等统计
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Options 缓存配置
+type Options struct {
+	Name     string        // 缓存名称，非空时登记到统计列表
+	Capacity int           // 最大条目数，超过时淘汰最久未使用的条目，0为不限
+	TTL      time.Duration // 默认有效期，0为不过期
+}
+
+// Stats 缓存统计
+type Stats struct {
+	Name        string  `json:"name"`        // 缓存名称
+	Size        int     `json:"size"`        // 当前条目数
+	Capacity    int     `json:"capacity"`    // 最大条目数
+	Hits        uint64  `json:"hits"`        // 命中次数
+	Misses      uint64  `json:"misses"`      // 未命中次数
+	HitRate     float64 `json:"hit_rate"`    // 命中率
+	Loads       uint64  `json:"loads"`       // 加载次数（并发加载同一键只计一次）
+	LoadErrors  uint64  `json:"load_errors"` // 加载失败次数
+	Evictions   uint64  `json:"evictions"`   // 因容量淘汰的条目数
+	Expirations uint64  `json:"expirations"` // 因过期删除的条目数
+}
+
+// entry 缓存条目
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// call 进行中的加载
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache 带TTL的LRU缓存，可并发使用
+type Cache[K comparable, V any] struct {
+	opts Options
+
+	mu      sync.Mutex
+	items   map[K]*list.Element
+	order   *list.List // 表头为最近使用
+	loading map[K]*call[V]
+
+	hits, misses, loads, loadErrors, evictions, expirations atomic.Uint64
+}
+
+// New 创建缓存
+func New[K comparable, V any](opts Options) *Cache[K, V] {
+	c := &Cache[K, V]{
+		opts:    opts,
+		items:   make(map[K]*list.Element),
+		order:   list.New(),
+		loading: make(map[K]*call[V]),
+	}
+	if opts.Name != "" {
+		register(opts.Name, c)
+	}
+	return c
+}
+
+// Get 读取缓存，过期条目视为未命中并删除
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return value, ok
+}
+
+// get 在持有锁时读取条目，不计统计
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	e := elem.Value.(*entry[K, V])
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		c.remove(elem)
+		c.expirations.Add(1)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set 写入缓存，使用默认有效期
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.opts.TTL)
+}
+
+// SetWithTTL 写入缓存并指定有效期，ttl为0时不过期
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry[K, V])
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.opts.Capacity > 0 && c.order.Len() > c.opts.Capacity {
+		c.remove(c.order.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// Delete 删除缓存条目
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Clear 清空缓存，统计保留
+func (c *Cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+// Len 当前条目数（含尚未清理的过期条目）
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove 在持有锁时删除条目
+func (c *Cache[K, V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry[K, V]).key)
+}
+
+// GetOrLoad 读取缓存，未命中时调用load加载并写入；并发请求同一键时只调用一次load，
+// 其余请求等待该结果。加载失败不写入缓存
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, key K, load func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if value, ok := c.get(key); ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return value, nil
+	}
+	c.misses.Add(1)
+
+	if inflight, ok := c.loading[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-inflight.done:
+			return inflight.value, inflight.err
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+
+	inflight := &call[V]{done: make(chan struct{})}
+	c.loading[key] = inflight
+	c.mu.Unlock()
+
+	c.loads.Add(1)
+	inflight.value, inflight.err = load(ctx)
+	if inflight.err != nil {
+		c.loadErrors.Add(1)
+	} else {
+		c.Set(key, inflight.value)
+	}
+
+	c.mu.Lock()
+	delete(c.loading, key)
+	c.mu.Unlock()
+	close(inflight.done)
+	return inflight.value, inflight.err
+}
+
+// Stats 获取统计
+func (c *Cache[K, V]) Stats() Stats {
+	stats := Stats{
+		Name:        c.opts.Name,
+		Size:        c.Len(),
+		Capacity:    c.opts.Capacity,
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Loads:       c.loads.Load(),
+		LoadErrors:  c.loadErrors.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// statser 可提供统计的缓存
+type statser interface {
+	Stats() Stats
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]statser)
+)
+
+// register 登记具名缓存，同名缓存后登记的覆盖先登记的
+func register(name string, c statser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = c
+}
+
+// All 获取所有具名缓存的统计，按名称排序
+func All() []Stats {
+	registryMu.Lock()
+	caches := make([]statser, 0, len(registry))
+	for _, c := range registry {
+		caches = append(caches, c)
+	}
+	registryMu.Unlock()
+
+	stats := make([]Stats, len(caches))
+	for i, c := range caches {
+		stats[i] = c.Stats()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
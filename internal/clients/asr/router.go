@@ -0,0 +1,195 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// FailoverConfig 识别后端故障切换配置
+type FailoverConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Threshold int           `yaml:"threshold"` // 后端连续失败次数，达到后新的识别会话改用下一个后端
+	Cooldown  time.Duration `yaml:"cooldown"`  // 切换后经过该时间再尝试原后端
+}
+
+// Validate 校验故障切换配置
+func (c FailoverConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Threshold <= 0 {
+		return fmt.Errorf("threshold: 启用故障切换时必须大于0")
+	}
+	if c.Cooldown <= 0 {
+		return fmt.Errorf("cooldown: 启用故障切换时必须大于0")
+	}
+	return nil
+}
+
+// ErrUnknownProvider 未配置的识别后端
+var ErrUnknownProvider = errors.New("未配置的语音识别后端")
+
+// providerHealth 后端的连续失败统计
+type providerHealth struct {
+	failures  int
+	trippedAt time.Time // 连续失败达到阈值的时间，未达到时为零值
+}
+
+// Router 多个识别后端之间的选择和故障切换：每通电话可指定使用的后端，
+// 启用故障切换时后端连续失败达到阈值后新的识别会话依次改用其余后端，冷却时间过后再尝试原后端
+type Router struct {
+	config    FailoverConfig
+	providers []models.ASRProvider // 第一个为默认后端
+
+	mu     sync.Mutex
+	health map[string]*providerHealth
+}
+
+// NewRouter 创建识别后端路由，providers的第一个为默认后端
+func NewRouter(config FailoverConfig, providers ...models.ASRProvider) *Router {
+	return &Router{config: config, providers: providers, health: make(map[string]*providerHealth)}
+}
+
+// Has 是否配置了该名称的后端
+func (r *Router) Has(name string) bool {
+	for _, p := range r.providers {
+		if p.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Select 返回优先使用preferred的识别后端，preferred为空时使用默认后端；启用故障切换时其余后端依次备用。
+// replace中的后端替换同名的已配置后端（如使用租户独立凭据的讯飞客户端），失败统计按后端名称共享
+func (r *Router) Select(preferred string, replace ...models.ASRProvider) (models.ASRProvider, error) {
+	if preferred != "" && !r.Has(preferred) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, preferred)
+	}
+	order := make([]models.ASRProvider, 0, len(r.providers))
+	for _, p := range r.providers {
+		for _, rp := range replace {
+			if rp != nil && rp.Name() == p.Name() {
+				p = rp
+			}
+		}
+		if p.Name() == preferred {
+			order = append([]models.ASRProvider{p}, order...)
+		} else {
+			order = append(order, p)
+		}
+	}
+	if !r.config.Enabled {
+		order = order[:1]
+	}
+	return &route{router: r, order: order}, nil
+}
+
+// Ping 探测后端是否可用，任一后端可用即可用
+func (r *Router) Ping(ctx context.Context) error {
+	var err error
+	for _, p := range r.providers {
+		if err = p.Ping(ctx); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// available 后端是否可用：未达到失败阈值，或已过冷却时间
+func (r *Router) available(name string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	return h == nil || h.trippedAt.IsZero() || now.Sub(h.trippedAt) >= r.config.Cooldown
+}
+
+// report 记录一次识别会话的结果，成功时清零连续失败次数
+func (r *Router) report(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	if h == nil {
+		h = &providerHealth{}
+		r.health[name] = h
+	}
+	if err == nil {
+		h.failures = 0
+		h.trippedAt = time.Time{}
+		return
+	}
+	h.failures++
+	if r.config.Enabled && h.failures >= r.config.Threshold {
+		if h.trippedAt.IsZero() {
+			log.Printf("语音识别后端 %s 连续失败%d次，切换到备用后端: %v", name, h.failures, err)
+		}
+		// 冷却后的试探仍失败时重新计时
+		h.trippedAt = time.Now()
+	}
+}
+
+// route 一次选择的识别后端，按顺序尝试
+type route struct {
+	router *Router
+	order  []models.ASRProvider
+}
+
+// Name 首选后端的名称
+func (rt *route) Name() string {
+	return rt.order[0].Name()
+}
+
+// OpenStream 依次使用可用的后端开始流式识别，后端都不可用时仍尝试首选后端
+func (rt *route) OpenStream(sessionID string) (models.ASRStream, error) {
+	now := time.Now()
+	var lastErr error
+	tried := false
+	for _, p := range rt.order {
+		if !rt.router.available(p.Name(), now) {
+			continue
+		}
+		tried = true
+		stream, err := rt.open(p, sessionID)
+		if err == nil {
+			return stream, nil
+		}
+		lastErr = err
+	}
+	if !tried {
+		return rt.open(rt.order[0], sessionID)
+	}
+	return nil, lastErr
+}
+
+// open 使用后端开始流式识别，会话结束后按结果更新后端的失败统计
+func (rt *route) open(p models.ASRProvider, sessionID string) (models.ASRStream, error) {
+	stream, err := p.OpenStream(sessionID)
+	if err != nil {
+		if !errors.Is(err, models.ErrSessionIDRequired) {
+			rt.router.report(p.Name(), err)
+		}
+		return nil, err
+	}
+	go func() {
+		rt.router.report(p.Name(), stream.Err())
+	}()
+	return stream, nil
+}
+
+// ReleaseSession 释放所有后端为会话保留的资源
+func (rt *route) ReleaseSession(sessionID string) {
+	for _, p := range rt.order {
+		p.ReleaseSession(sessionID)
+	}
+}
+
+// Ping 探测首选后端是否可用
+func (rt *route) Ping(ctx context.Context) error {
+	return rt.order[0].Ping(ctx)
+}
@@ -1,103 +1,273 @@
 package asr
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
-	"ai_dialer_mini/internal/clients/ws"
 	"ai_dialer_mini/internal/models"
+
+	"github.com/gorilla/websocket"
 )
 
-// WhisperClient 实现与 ASR 服务器的 WebSocket 通信
+// WhisperProviderName Whisper语音识别后端名称
+const WhisperProviderName = "whisper"
+
+// whisperFinalTimeout 发送结束帧后等待最终识别结果的时间
+const whisperFinalTimeout = 5 * time.Second
+
+// ErrWhisperStreamClosed 识别会话已结束，不能继续发送音频
+var ErrWhisperStreamClosed = errors.New("识别会话已结束")
+
+// WhisperConfig Whisper识别服务配置
+type WhisperConfig struct {
+	ServerURL        string        `yaml:"server_url"`        // 识别服务的WebSocket地址，如 ws://localhost:9000/asr
+	Model            string        `yaml:"model"`             // 识别模型，如 large-v3，留空使用服务端默认模型
+	Language         string        `yaml:"language"`          // 识别语言，如 zh，留空由服务端自动检测
+	HandshakeTimeout time.Duration `yaml:"handshake_timeout"` // 握手超时时间，默认5秒
+}
+
+// Validate 校验配置，未配置服务地址时不启用
+func (c WhisperConfig) Validate() error {
+	if c.ServerURL == "" {
+		return nil
+	}
+	if c.HandshakeTimeout < 0 {
+		return fmt.Errorf("handshake_timeout: 不能小于0")
+	}
+	return nil
+}
+
+// WhisperClient Whisper识别服务客户端，实现models.ASRProvider接口：
+// 每次流式识别建立独立的WebSocket连接，第一帧携带模型和语言参数，
+// 服务端检测到句尾时返回final结果并结束会话，未检测句尾的服务端在收到结束帧后返回最终结果
 type WhisperClient struct {
-	wsClient *ws.Client
-	grammar  string
+	config WhisperConfig
+	dialer websocket.Dialer
 }
 
-// NewWhisperClient 创建新的 Whisper 客户端
-func NewWhisperClient(serverURL string) *WhisperClient {
-	config := ws.Config{
-		URL:               serverURL,
-		ReconnectInterval: 5 * time.Second,
-		MaxRetries:        3,
-		HeartbeatInterval: 30 * time.Second,
-		HeartbeatMessage:  []byte(`{"type":"ping"}`),
+// NewWhisperClient 创建Whisper识别服务客户端
+func NewWhisperClient(config WhisperConfig) *WhisperClient {
+	if config.HandshakeTimeout <= 0 {
+		config.HandshakeTimeout = 5 * time.Second
 	}
+	return &WhisperClient{
+		config: config,
+		dialer: websocket.Dialer{HandshakeTimeout: config.HandshakeTimeout},
+	}
+}
 
-	client := &WhisperClient{
-		wsClient: ws.NewClient(config),
+// Name 后端名称
+func (c *WhisperClient) Name() string {
+	return WhisperProviderName
+}
+
+// OpenStream 建立识别连接，开始一次流式识别
+func (c *WhisperClient) OpenStream(sessionID string) (models.ASRStream, error) {
+	if sessionID == "" {
+		return nil, models.ErrSessionIDRequired
+	}
+	conn, _, err := c.dialer.Dial(c.config.ServerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接Whisper识别服务失败: %v", err)
 	}
+	stream := &whisperStream{
+		conn:    conn,
+		config:  c.config,
+		results: make(chan models.ASRResult, 16),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go stream.receive()
+	return stream, nil
+}
 
-	// 注册消息处理器
-	client.wsClient.RegisterHandler("result", client.handleResult)
+// ReleaseSession Whisper不为会话保留连接，无需释放
+func (c *WhisperClient) ReleaseSession(sessionID string) {}
 
-	return client
+// Ping 建立一次连接后立即关闭，用于探测识别服务是否可用
+func (c *WhisperClient) Ping(ctx context.Context) error {
+	conn, _, err := c.dialer.DialContext(ctx, c.config.ServerURL, nil)
+	if err != nil {
+		return fmt.Errorf("连接Whisper识别服务失败: %v", err)
+	}
+	return conn.Close()
 }
 
-// Connect 连接到 ASR 服务器
-func (c *WhisperClient) Connect() error {
-	return c.wsClient.Connect()
+// whisperStream 单个Whisper流式识别会话
+type whisperStream struct {
+	conn   *websocket.Conn
+	config WhisperConfig
+
+	mu       sync.Mutex // 串行化写操作
+	started  bool       // 已发送第一帧
+	closed   bool       // 已调用Close
+	timedOut bool       // 等待最终结果超时
+	ended    bool       // 已发送结束帧，之后收到的结果为最终结果
+	sentAt   time.Time
+	latency  time.Duration
+
+	results chan models.ASRResult
+	quit    chan struct{}
+	quitted sync.Once
+	done    chan struct{}
+	err     error
 }
 
-// Close 关闭连接
-func (c *WhisperClient) Close() error {
-	return c.wsClient.Close()
+// Results 识别结果通道，调用方需持续读取直至通道关闭
+func (s *whisperStream) Results() <-chan models.ASRResult {
+	return s.results
 }
 
-// SetGrammar 设置语法
-func (c *WhisperClient) SetGrammar(grammar string) error {
-	c.grammar = grammar
-	req := models.WhisperRequest{
-		Grammar: grammar,
-	}
-	return c.wsClient.SendMessage(req)
+// Done 识别会话结束时关闭
+func (s *whisperStream) Done() <-chan struct{} {
+	return s.done
 }
 
-// SendAudioFrame 发送音频帧
-func (c *WhisperClient) SendAudioFrame(audio []byte) error {
-	req := models.WhisperRequest{
-		Data: struct {
-			Status   int    `json:"status"`
-			Format   string `json:"format"`
-			Audio    string `json:"audio"`
-			Encoding string `json:"encoding"`
-		}{
-			Status:   1, // 中间帧
-			Format:   "audio/L16;rate=16000",
-			Audio:    base64.StdEncoding.EncodeToString(audio),
-			Encoding: "raw",
-		},
-	}
-	return c.wsClient.SendMessage(req)
+// Err 等待识别会话结束，返回会话异常结束的原因
+func (s *whisperStream) Err() error {
+	<-s.done
+	return s.err
 }
 
-// SendEndFrame 发送结束帧
-func (c *WhisperClient) SendEndFrame() error {
-	req := models.WhisperRequest{
-		Data: struct {
-			Status   int    `json:"status"`
-			Format   string `json:"format"`
-			Audio    string `json:"audio"`
-			Encoding string `json:"encoding"`
-		}{
-			Status:   2, // 结束帧
-			Format:   "audio/L16;rate=16000",
-			Audio:    "",
-			Encoding: "raw",
-		},
+// Latency 等待识别会话结束，返回最终结果相对最后一帧音频的延迟
+func (s *whisperStream) Latency() time.Duration {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latency
+}
+
+// Write 发送一段PCM音频
+func (s *whisperStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrWhisperStreamClosed
 	}
-	return c.wsClient.SendMessage(req)
+	select {
+	case <-s.done:
+		return 0, ErrWhisperStreamClosed
+	default:
+	}
+	status := StatusContinueFrame
+	if !s.started {
+		status = StatusFirstFrame
+	}
+	if err := s.send(p, status); err != nil {
+		return 0, err
+	}
+	s.started = true
+	return len(p), nil
 }
 
-// handleResult 处理识别结果
-func (c *WhisperClient) handleResult(message []byte) error {
-	var resp models.WhisperResponse
-	if err := json.Unmarshal(message, &resp); err != nil {
-		return err
+// Close 发送结束帧并等待最终识别结果，超时后关闭连接
+func (s *whisperStream) Close() error {
+	s.mu.Lock()
+	var err error
+	wait := false
+	if !s.closed {
+		s.closed = true
+		select {
+		case <-s.done:
+		default:
+			if s.started {
+				err = s.send(nil, StatusLastFrame)
+				s.ended = err == nil
+				wait = s.ended
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if wait {
+		select {
+		case <-s.done:
+		case <-time.After(whisperFinalTimeout):
+			s.mu.Lock()
+			s.timedOut = true
+			s.mu.Unlock()
+		}
 	}
+	s.quitted.Do(func() { close(s.quit) })
+	s.conn.Close()
+	<-s.done
+	return err
+}
 
-	log.Printf("收到识别结果: %s", resp.Text)
+// send 发送一帧音频，第一帧携带模型和语言参数，需持有锁
+func (s *whisperStream) send(data []byte, status int) error {
+	req := models.WhisperRequest{}
+	if status == StatusFirstFrame {
+		req.Model = s.config.Model
+		req.Language = s.config.Language
+	}
+	req.Data.Status = status
+	req.Data.Format = "audio/L16;rate=16000"
+	req.Data.Audio = base64.StdEncoding.EncodeToString(data)
+	req.Data.Encoding = "raw"
+	message, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %v", err)
+	}
+	if err := s.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		return fmt.Errorf("发送音频数据失败: %v", err)
+	}
+	s.sentAt = time.Now()
 	return nil
 }
+
+// receive 读取识别结果，收到最终结果或连接异常时结束会话
+func (s *whisperStream) receive() {
+	defer close(s.done)
+	defer close(s.results)
+
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			s.mu.Lock()
+			closed, timedOut := s.closed, s.timedOut
+			s.mu.Unlock()
+			if timedOut {
+				s.err = fmt.Errorf("等待最终识别结果超时")
+			} else if !closed {
+				s.err = fmt.Errorf("读取识别结果失败: %v", err)
+			}
+			return
+		}
+
+		var resp models.WhisperResponse
+		if err := json.Unmarshal(message, &resp); err != nil {
+			s.err = fmt.Errorf("解析识别结果失败: %v", err)
+			return
+		}
+		if resp.Type != "" && resp.Type != "result" {
+			continue
+		}
+		if resp.Error != "" {
+			s.err = fmt.Errorf("服务器错误: %s", resp.Error)
+			return
+		}
+
+		s.mu.Lock()
+		final := resp.Final || s.ended
+		if final {
+			s.latency = time.Since(s.sentAt)
+		}
+		s.mu.Unlock()
+		select {
+		case s.results <- models.ASRResult{Text: resp.Text, IsFinal: final}:
+		case <-s.quit:
+			return
+		}
+		if final {
+			s.conn.Close()
+			return
+		}
+	}
+}
@@ -43,13 +43,16 @@ func TestXunfeiClientReadMessage(t *testing.T) {
 }
 
 func TestXunfeiASR(t *testing.T) {
-	// 创建客户端配置
+	// 创建客户端配置，密钥从环境变量读取，未设置时跳过
 	config := Config{
-		AppID:     "c0de4f24",
-		APIKey:    "51012a35448538a8396dc564cf050f68",
-		APISecret: "NWRhZDBkNzA5ZDQxNGMzYmQ1NWMwMWNh",
+		AppID:     os.Getenv("XFYUN_APP_ID"),
+		APIKey:    os.Getenv("XFYUN_API_KEY"),
+		APISecret: os.Getenv("XFYUN_API_SECRET"),
 		HostURL:   "wss://iat-api.xfyun.cn/v2/iat",
 	}
+	if config.AppID == "" || config.APIKey == "" || config.APISecret == "" {
+		t.Skip("未设置XFYUN_APP_ID、XFYUN_API_KEY、XFYUN_API_SECRET环境变量")
+	}
 
 	// 创建客户端
 	client := NewXunfeiClient(config)
@@ -0,0 +1,244 @@
+package freeswitch
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mod_audio_stream 自定义事件子类
+const (
+	AudioStreamEventConnect    = "mod_audio_stream::connect"
+	AudioStreamEventDisconnect = "mod_audio_stream::disconnect"
+	AudioStreamEventError      = "mod_audio_stream::error"
+)
+
+// defaultRestartDelay 音频流异常后重新启动前的默认等待时间
+const defaultRestartDelay = time.Second
+
+// defaultSampleRate 默认推送音频的采样率，与讯飞ASR一致，无需重采样
+const defaultSampleRate = 16000
+
+// AudioStreamState 音频流状态
+type AudioStreamState string
+
+// 音频流状态
+const (
+	AudioStreamStarting   AudioStreamState = "starting"   // 已下发启动命令，等待连接
+	AudioStreamStreaming  AudioStreamState = "streaming"  // 已连接，正在推送音频
+	AudioStreamRestarting AudioStreamState = "restarting" // 连接异常，等待重新启动
+	AudioStreamFailed     AudioStreamState = "failed"     // 重启次数用尽
+)
+
+// AudioStreamConfig 音频流生命周期配置
+type AudioStreamConfig struct {
+	MaxRestarts  int           // 单个通话mod_audio_stream异常后最多重新启动的次数，0表示不重启
+	RestartDelay time.Duration // 重新启动前的等待时间
+	SampleRate   int           // 推送音频的采样率，8000或16000，为0时按16000；通过地址的rate参数告知音频流服务
+	Codec        string        // 推送音频的编码，pcmu或pcma，为空时为L16；通过地址的codec参数告知音频流服务
+}
+
+// StreamURLFunc 生成通话音频流地址，每次启动（含重启）都会重新调用，以便使用新的签名
+type StreamURLFunc func(callUUID string) (string, error)
+
+// CommandSender 发送FreeSWITCH api命令
+type CommandSender interface {
+	SendCommand(command string) (string, error)
+}
+
+// AudioStream 单个通话的音频流状态
+type AudioStream struct {
+	UUID      string           `json:"uuid"`
+	State     AudioStreamState `json:"state"`
+	Restarts  int              `json:"restarts"`             // 已重新启动的次数
+	LastError string           `json:"last_error,omitempty"` // 最近一次异常原因
+	StartedAt time.Time        `json:"started_at"`
+}
+
+// AudioStreamManager 管理通话的uuid_audio_stream生命周期：
+// 按配置生成地址启动音频流，mod_audio_stream报告异常时重新启动，通道挂断时停止跟踪
+type AudioStreamManager struct {
+	sender    CommandSender
+	streamURL StreamURLFunc
+	config    AudioStreamConfig
+
+	mu      sync.Mutex
+	streams map[string]*AudioStream
+}
+
+// NewAudioStreamManager 创建音频流管理器
+func NewAudioStreamManager(sender CommandSender, streamURL StreamURLFunc, config AudioStreamConfig) *AudioStreamManager {
+	if config.RestartDelay <= 0 {
+		config.RestartDelay = defaultRestartDelay
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = defaultSampleRate
+	}
+	return &AudioStreamManager{
+		sender:    sender,
+		streamURL: streamURL,
+		config:    config,
+		streams:   make(map[string]*AudioStream),
+	}
+}
+
+// Start 启动通话音频流并开始跟踪其状态
+func (m *AudioStreamManager) Start(uuid string) error {
+	m.mu.Lock()
+	m.streams[uuid] = &AudioStream{UUID: uuid, State: AudioStreamStarting, StartedAt: time.Now()}
+	m.mu.Unlock()
+
+	if err := m.start(uuid); err != nil {
+		m.mu.Lock()
+		delete(m.streams, uuid)
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// start 生成地址并下发uuid_audio_stream启动命令
+func (m *AudioStreamManager) start(uuid string) error {
+	streamURL, err := m.streamURL(uuid)
+	if err != nil {
+		return fmt.Errorf("生成音频流地址失败: %v", err)
+	}
+	rate := fmt.Sprintf("%dk", m.config.SampleRate/1000)
+	if m.config.SampleRate != defaultSampleRate || m.config.Codec != "" {
+		// 非默认的采样率和编码在地址中注明，音频流服务据此解码和重采样
+		u, err := url.Parse(streamURL)
+		if err != nil {
+			return fmt.Errorf("解析音频流地址失败: %v", err)
+		}
+		query := u.Query()
+		if m.config.SampleRate != defaultSampleRate {
+			query.Set("rate", strconv.Itoa(m.config.SampleRate))
+		}
+		if m.config.Codec != "" {
+			query.Set("codec", m.config.Codec)
+		}
+		u.RawQuery = query.Encode()
+		streamURL = u.String()
+	}
+	resp, err := m.sender.SendCommand(fmt.Sprintf("uuid_audio_stream %s start %s mono %s", uuid, streamURL, rate))
+	if err != nil {
+		return fmt.Errorf("启动音频流失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("启动音频流失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// Stop 停止通话音频流
+func (m *AudioStreamManager) Stop(uuid string) error {
+	m.mu.Lock()
+	_, ok := m.streams[uuid]
+	delete(m.streams, uuid)
+	m.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	resp, err := m.sender.SendCommand(fmt.Sprintf("uuid_audio_stream %s stop", uuid))
+	if err != nil {
+		return fmt.Errorf("停止音频流失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("停止音频流失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// Get 返回通话音频流状态
+func (m *AudioStreamManager) Get(uuid string) (AudioStream, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stream, ok := m.streams[uuid]
+	if !ok {
+		return AudioStream{}, false
+	}
+	return *stream, true
+}
+
+// List 返回所有正在跟踪的音频流
+func (m *AudioStreamManager) List() []AudioStream {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	streams := make([]AudioStream, 0, len(m.streams))
+	for _, stream := range m.streams {
+		streams = append(streams, *stream)
+	}
+	return streams
+}
+
+// HandleEvent 处理mod_audio_stream自定义事件和通道挂断事件
+func (m *AudioStreamManager) HandleEvent(headers map[string]string) error {
+	uuid := headers["Unique-ID"]
+	if uuid == "" {
+		return nil
+	}
+
+	switch headers["Event-Name"] {
+	case "CHANNEL_HANGUP":
+		// 通道挂断后mod_audio_stream随之关闭连接，只需停止跟踪
+		m.mu.Lock()
+		delete(m.streams, uuid)
+		m.mu.Unlock()
+	case "CUSTOM":
+		switch headers["Event-Subclass"] {
+		case AudioStreamEventConnect:
+			m.mu.Lock()
+			if stream, ok := m.streams[uuid]; ok {
+				stream.State = AudioStreamStreaming
+			}
+			m.mu.Unlock()
+		case AudioStreamEventError, AudioStreamEventDisconnect:
+			m.scheduleRestart(uuid, headers["_body"])
+		}
+	}
+	return nil
+}
+
+// scheduleRestart 音频流异常断开后在重启次数内延迟重新启动
+func (m *AudioStreamManager) scheduleRestart(uuid, reason string) {
+	m.mu.Lock()
+	stream, ok := m.streams[uuid]
+	if !ok || stream.State == AudioStreamRestarting || stream.State == AudioStreamFailed {
+		m.mu.Unlock()
+		return
+	}
+	stream.LastError = strings.TrimSpace(reason)
+	if stream.Restarts >= m.config.MaxRestarts {
+		stream.State = AudioStreamFailed
+		m.mu.Unlock()
+		log.Printf("音频流异常且重启次数已用尽 - UUID: %s, 原因: %s", uuid, reason)
+		return
+	}
+	stream.State = AudioStreamRestarting
+	stream.Restarts++
+	attempt := stream.Restarts
+	m.mu.Unlock()
+
+	log.Printf("音频流异常，%v后第%d次重新启动 - UUID: %s, 原因: %s", m.config.RestartDelay, attempt, uuid, reason)
+	time.AfterFunc(m.config.RestartDelay, func() {
+		// 等待期间通道可能已挂断
+		m.mu.Lock()
+		stream, ok := m.streams[uuid]
+		if !ok || stream.State != AudioStreamRestarting {
+			m.mu.Unlock()
+			return
+		}
+		stream.State = AudioStreamStarting
+		m.mu.Unlock()
+
+		if err := m.start(uuid); err != nil {
+			log.Printf("重新启动音频流失败 - UUID: %s: %v", uuid, err)
+			m.scheduleRestart(uuid, err.Error())
+		}
+	})
+}
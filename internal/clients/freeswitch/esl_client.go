@@ -2,28 +2,72 @@ package freeswitch
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/logger"
 )
 
+// eventLog 事件处理日志采样器，外呼高峰时每秒可能有上百个事件
+var eventLog = logger.Sampled("esl.event")
+
+// 重连等待时间的默认值
+const (
+	defaultReconnectDelay    = time.Second
+	defaultMaxReconnectDelay = 30 * time.Second
+)
+
+// ErrNotConnected 未连接到FreeSWITCH，或连接已断开正在重连
+var ErrNotConnected = errors.New("未连接")
+
 // ESLConfig ESL客户端配置
 type ESLConfig struct {
-	Host     string
-	Port     int
-	Password string
+	Host              string
+	Port              int
+	Password          string
+	Events            []string      // 订阅的事件，为空时只订阅已注册处理器的事件；包含all时订阅全部事件
+	Filters           []EventFilter // 事件过滤，配置后只接收头部与任一过滤条件匹配的事件
+	ReconnectDelay    time.Duration // 连接断开后首次重连前的等待时间，之后每次失败翻倍，为0时为1秒
+	MaxReconnectDelay time.Duration // 重连等待时间的上限，为0时为30秒
 }
 
-// ESLClient ESL客户端
+// EventFilter ESL事件过滤条件，对应filter命令
+type EventFilter struct {
+	Header string `yaml:"header"` // 事件头部名称，如Caller-Context
+	Value  string `yaml:"value"`  // 头部的值
+}
+
+// ESLClient ESL客户端，连接意外断开后按指数退避自动重连，重新认证并恢复事件订阅
 type ESLClient struct {
 	config   ESLConfig
-	conn     net.Conn
-	reader   *bufio.Reader
-	handlers map[string]EventHandler
+	handlers map[string][]EventHandler
+	custom   map[string]bool // 需要订阅的CUSTOM事件子类
 	mu       sync.RWMutex
-	running  bool
+	streams  *AudioStreamManager
+
+	cmdMu        sync.Mutex    // 串行化命令，FreeSWITCH按发送顺序返回命令响应
+	conn         *eslConn      // 当前连接，断开重连期间为nil
+	subscribed   bool          // 是否已订阅事件，重连后据此重新订阅
+	closed       bool          // 是否已调用Close
+	stop         chan struct{} // Close时关闭，停止重连
+	onDisconnect []func(err error)
+	onReconnect  []func()
+}
+
+// eslConn 一条已认证的ESL连接，只由读取循环读取，命令响应经replies转交给等待的命令
+type eslConn struct {
+	net.Conn
+	replies chan map[string]string
+	done    chan struct{} // 读取循环结束时关闭
 }
 
 // EventHandler 事件处理函数类型
@@ -31,214 +75,501 @@ type EventHandler func(headers map[string]string) error
 
 // NewESLClient 创建新的ESL客户端
 func NewESLClient(config ESLConfig) *ESLClient {
+	if config.ReconnectDelay <= 0 {
+		config.ReconnectDelay = defaultReconnectDelay
+	}
+	if config.MaxReconnectDelay < config.ReconnectDelay {
+		config.MaxReconnectDelay = defaultMaxReconnectDelay
+		if config.MaxReconnectDelay < config.ReconnectDelay {
+			config.MaxReconnectDelay = config.ReconnectDelay
+		}
+	}
 	return &ESLClient{
 		config:   config,
-		handlers: make(map[string]EventHandler),
-		running:  false,
+		handlers: make(map[string][]EventHandler),
+		custom:   make(map[string]bool),
+		stop:     make(chan struct{}),
 	}
 }
 
-// NewESLClientWithDefaultConfig 创建新的ESL客户端，使用默认配置
-func NewESLClientWithDefaultConfig() *ESLClient {
-	return NewESLClient(ESLConfig{
-		Host:     "127.0.0.1",
-		Port:     8021,
-		Password: "ClueCon",
-	})
-}
-
 // Connect 连接到FreeSWITCH
 func (c *ESLClient) Connect() error {
+	conn, reader, err := c.dial()
+	if err != nil {
+		return err
+	}
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	if c.closed {
+		c.closed = false
+		c.stop = make(chan struct{})
+	}
+	c.conn = conn
+	c.mu.Unlock()
+
+	log.Println("认证成功，连接已建立")
+
+	// 启动事件读取循环
+	go c.readEventLoop(conn, reader)
+
+	return nil
+}
 
-	// 建立TCP连接
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+// dial 建立TCP连接并认证
+func (c *ESLClient) dial() (*eslConn, *bufio.Reader, error) {
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
 	conn, err := net.Dial("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("连接失败: %v", err)
+		return nil, nil, fmt.Errorf("连接失败: %v", err)
 	}
-
-	c.conn = conn
-	c.reader = bufio.NewReader(conn)
+	reader := bufio.NewReader(conn)
 
 	// 读取欢迎信息
-	headers, err := c.readHeaders()
+	headers, err := ReadMessage(reader)
 	if err != nil {
-		c.conn.Close()
-		return fmt.Errorf("读取欢迎信息失败: %v", err)
+		conn.Close()
+		return nil, nil, fmt.Errorf("读取欢迎信息失败: %v", err)
 	}
 
 	// 验证是否是认证请求
 	if headers["Content-Type"] != "auth/request" {
-		c.conn.Close()
-		return fmt.Errorf("未收到认证请求: %s", headers["Content-Type"])
+		conn.Close()
+		return nil, nil, fmt.Errorf("未收到认证请求: %s", headers["Content-Type"])
 	}
 
 	// 发送认证
 	authCmd := fmt.Sprintf("auth %s\n\n", c.config.Password)
-	if _, err := c.conn.Write([]byte(authCmd)); err != nil {
-		c.conn.Close()
-		return fmt.Errorf("发送认证失败: %v", err)
+	if _, err := conn.Write([]byte(authCmd)); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("发送认证失败: %v", err)
 	}
 
 	// 读取认证响应
-	headers, err = c.readHeaders()
+	headers, err = ReadMessage(reader)
 	if err != nil {
-		c.conn.Close()
-		return fmt.Errorf("读取认证响应失败: %v", err)
+		conn.Close()
+		return nil, nil, fmt.Errorf("读取认证响应失败: %v", err)
 	}
 
 	if !strings.Contains(headers["Reply-Text"], "+OK accepted") {
-		c.conn.Close()
-		return fmt.Errorf("认证失败: %s", headers["Reply-Text"])
+		conn.Close()
+		return nil, nil, fmt.Errorf("认证失败: %s", headers["Reply-Text"])
 	}
 
-	log.Println("认证成功，连接已建立")
-
-	// 启动事件读取循环
-	go c.readEventLoop()
-
-	return nil
+	return &eslConn{
+		Conn:    conn,
+		replies: make(chan map[string]string, 1),
+		done:    make(chan struct{}),
+	}, reader, nil
 }
 
-// Close 关闭连接
+// Close 关闭连接并停止重连
 func (c *ESLClient) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.running = false
-	if c.conn != nil {
-		return c.conn.Close()
+	if !c.closed {
+		c.closed = true
+		close(c.stop)
+	}
+	conn := c.conn
+	c.conn = nil
+	if conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
-// SubscribeEvents 订阅事件
-func (c *ESLClient) SubscribeEvents() error {
+// Connected 当前是否已连接
+func (c *ESLClient) Connected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn != nil
+}
+
+// OnDisconnect 注册连接意外断开时的回调，调用Close主动关闭时不触发；回调在读取循环中依次调用，不应阻塞
+func (c *ESLClient) OnDisconnect(callback func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onDisconnect = append(c.onDisconnect, callback)
+}
+
+// OnReconnect 注册重连成功后的回调，回调时已重新认证并恢复事件订阅
+func (c *ESLClient) OnReconnect(callback func()) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.onReconnect = append(c.onReconnect, callback)
+}
 
-	if c.conn == nil {
-		return fmt.Errorf("未连接")
+// SubscribeEvents 按EventSubscription订阅事件，并下发配置的事件过滤条件；重连后自动重新订阅
+// 需在注册完事件处理器后调用，之后注册的处理器的事件要到重连时才会订阅
+func (c *ESLClient) SubscribeEvents() error {
+	if !c.Connected() {
+		return ErrNotConnected
 	}
+	// 本次订阅失败时重连后仍会重新订阅
+	c.mu.Lock()
+	c.subscribed = true
+	c.mu.Unlock()
+	return c.subscribe()
+}
 
-	// 订阅所有事件
-	cmd := "event plain all\n\n"
-	if _, err := c.conn.Write([]byte(cmd)); err != nil {
-		return fmt.Errorf("订阅事件失败: %v", err)
+// subscribe 在当前连接上订阅事件并下发事件过滤条件
+func (c *ESLClient) subscribe() error {
+	subscription := c.EventSubscription()
+	if subscription == "" {
+		log.Println("没有需要订阅的FreeSWITCH事件")
+		return nil
 	}
 
-	// 读取订阅响应
-	headers, err := c.readHeaders()
-	if err != nil {
-		return fmt.Errorf("读取订阅响应失败: %v", err)
+	if err := c.command("event plain " + subscription); err != nil {
+		return fmt.Errorf("订阅失败: %v", err)
+	}
+	for _, filter := range c.config.Filters {
+		if err := c.command(fmt.Sprintf("filter %s %s", filter.Header, filter.Value)); err != nil {
+			return fmt.Errorf("设置事件过滤 %s=%s 失败: %v", filter.Header, filter.Value, err)
+		}
 	}
 
+	log.Printf("事件订阅成功: %s", subscription)
+	return nil
+}
+
+// command 发送ESL命令并检查回复
+func (c *ESLClient) command(cmd string) error {
+	headers, err := c.request(cmd)
+	if err != nil {
+		return err
+	}
 	if !strings.Contains(headers["Reply-Text"], "+OK") {
-		return fmt.Errorf("订阅失败: %s", headers["Reply-Text"])
+		return fmt.Errorf("%s", headers["Reply-Text"])
 	}
-
-	log.Println("事件订阅成功")
 	return nil
 }
 
-// RegisterHandler 注册事件处理器
+// request 发送ESL命令并等待读取循环转交的响应，等待期间连接断开时返回错误
+func (c *ESLClient) request(cmd string) (map[string]string, error) {
+	c.cmdMu.Lock()
+	defer c.cmdMu.Unlock()
+
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	if _, err := conn.Write([]byte(cmd + "\n\n")); err != nil {
+		return nil, fmt.Errorf("发送命令失败: %v", err)
+	}
+	select {
+	case headers := <-conn.replies:
+		return headers, nil
+	case <-conn.done:
+		return nil, fmt.Errorf("读取命令响应失败: 连接已断开")
+	}
+}
+
+// EventSubscription 返回要订阅的事件列表，如 "CHANNEL_ANSWER CHANNEL_HANGUP CUSTOM mod_audio_stream::connect"
+// 配置了Events时按配置订阅，否则订阅已注册处理器的事件；订阅CUSTOM时附带已注册的事件子类；没有需要订阅的事件时返回空
+func (c *ESLClient) EventSubscription() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make(map[string]bool)
+	if len(c.config.Events) > 0 {
+		for _, name := range c.config.Events {
+			name = strings.ToUpper(strings.TrimSpace(name))
+			if name == "ALL" {
+				return "all"
+			}
+			if name != "" {
+				names[name] = true
+			}
+		}
+		for name := range c.handlers {
+			if !names[name] {
+				log.Printf("警告: 已注册 %s 事件处理器，但该事件不在订阅配置中", name)
+			}
+		}
+	} else {
+		for name := range c.handlers {
+			names[name] = true
+		}
+	}
+
+	custom := names[EventCustom]
+	delete(names, EventCustom)
+	events := sortedKeys(names)
+	if custom {
+		// CUSTOM事件须在其后列出子类，未注册子类时订阅全部自定义事件
+		events = append(events, EventCustom)
+		events = append(events, sortedKeys(c.custom)...)
+	}
+	return strings.Join(events, " ")
+}
+
+// sortedKeys 返回排序后的键
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RegisterHandler 注册事件处理器，同一事件可注册多个处理器，按注册顺序调用
 func (c *ESLClient) RegisterHandler(eventName string, handler EventHandler) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.handlers[eventName] = handler
+	c.handlers[eventName] = append(c.handlers[eventName], handler)
 }
 
-// SendCommand 发送命令
-func (c *ESLClient) SendCommand(command string) (string, error) {
+// RegisterCustomHandler 注册CUSTOM事件处理器，并订阅指定的事件子类；处理器会收到所有已订阅子类的事件，需自行按Event-Subclass区分
+func (c *ESLClient) RegisterCustomHandler(handler EventHandler, subclasses ...string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for _, subclass := range subclasses {
+		c.custom[subclass] = true
+	}
+	c.handlers[EventCustom] = append(c.handlers[EventCustom], handler)
+}
+
+// EnableAudioStreams 启用通话音频流管理，音频流地址由streamURL生成；
+// 管理器接收mod_audio_stream事件以在异常时重启音频流，并在通道挂断时停止跟踪
+func (c *ESLClient) EnableAudioStreams(streamURL StreamURLFunc, config AudioStreamConfig) *AudioStreamManager {
+	manager := NewAudioStreamManager(c, streamURL, config)
+	c.mu.Lock()
+	c.streams = manager
+	c.mu.Unlock()
 
-	if c.conn == nil {
-		return "", fmt.Errorf("未连接")
+	c.RegisterCustomHandler(manager.HandleEvent, AudioStreamEventConnect, AudioStreamEventDisconnect, AudioStreamEventError)
+	c.RegisterHandler(EventChannelHangup, manager.HandleEvent)
+	return manager
+}
+
+// AudioStreams 返回音频流管理器，未启用时返回nil
+func (c *ESLClient) AudioStreams() *AudioStreamManager {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.streams
+}
+
+// StartAudioStream 启动通话音频流，需先调用EnableAudioStreams
+func (c *ESLClient) StartAudioStream(uuid string) error {
+	manager := c.AudioStreams()
+	if manager == nil {
+		return fmt.Errorf("音频流未启用")
+	}
+	return manager.Start(uuid)
+}
+
+// StopAudioStream 停止通话音频流，需先调用EnableAudioStreams
+func (c *ESLClient) StopAudioStream(uuid string) error {
+	manager := c.AudioStreams()
+	if manager == nil {
+		return fmt.Errorf("音频流未启用")
+	}
+	return manager.Stop(uuid)
+}
+
+// SendCommand 发送api命令，返回响应内容
+func (c *ESLClient) SendCommand(command string) (string, error) {
+	// api命令的结果在消息体中
+	headers, err := c.request("api " + command)
+	if err != nil {
+		return "", err
 	}
 
-	// 发送命令
-	cmd := fmt.Sprintf("api %s\n\n", command)
-	if _, err := c.conn.Write([]byte(cmd)); err != nil {
-		return "", fmt.Errorf("发送命令失败: %v", err)
+	if reply, ok := headers["Reply-Text"]; ok {
+		return reply, nil
 	}
+	return headers[BodyKey], nil
+}
+
+// maxBodySize 消息体长度上限，超过时视为协议错误，避免异常的Content-Length导致分配过大内存
+const maxBodySize = 16 << 20
 
-	// 读取响应
-	headers, err := c.readHeaders()
+// BodyKey 非事件消息（如api命令响应）的消息体在解析结果中的键
+const BodyKey = "_body"
+
+// ReadMessage 从ESL连接读取一条消息：头部以空行结束，带Content-Length时继续读取完整的消息体
+// text/event-plain 事件的消息体按“键: 值”解析并入头部，值经过URL编码，解析时解码；
+// 其他消息体及事件头部之后的附加内容保存在BodyKey中
+func ReadMessage(r *bufio.Reader) (map[string]string, error) {
+	headers, err := readHeaders(r)
 	if err != nil {
-		return "", fmt.Errorf("读取命令响应失败: %v", err)
+		return nil, err
+	}
+
+	lenStr, ok := headers["Content-Length"]
+	if !ok {
+		return headers, nil
+	}
+	contentLength, err := strconv.Atoi(lenStr)
+	if err != nil || contentLength < 0 || contentLength > maxBodySize {
+		return nil, fmt.Errorf("无效的Content-Length: %q", lenStr)
+	}
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("读取消息体失败: %v", err)
+	}
+
+	if headers["Content-Type"] != "text/event-plain" {
+		headers[BodyKey] = string(body)
+		return headers, nil
 	}
 
-	return headers["Reply-Text"], nil
+	// 事件头部与附加内容之间以空行分隔
+	text := string(body)
+	rest := ""
+	if idx := strings.Index(text, "\n\n"); idx != -1 {
+		text, rest = text[:idx], text[idx+2:]
+	}
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := parseHeader(line)
+		if !ok {
+			continue
+		}
+		if decoded, err := url.PathUnescape(value); err == nil {
+			value = decoded
+		}
+		headers[key] = value
+	}
+	if rest != "" {
+		headers[BodyKey] = rest
+	}
+	return headers, nil
 }
 
-// readHeaders 读取ESL头部
-func (c *ESLClient) readHeaders() (map[string]string, error) {
+// readHeaders 读取ESL头部，以空行结束
+func readHeaders(r *bufio.Reader) (map[string]string, error) {
 	headers := make(map[string]string)
 	for {
-		line, err := c.reader.ReadString('\n')
+		line, err := r.ReadString('\n')
 		if err != nil {
 			return nil, err
 		}
-		line = strings.TrimSpace(line)
-		if line == "" {
+		if strings.TrimSpace(line) == "" {
 			break
 		}
-		if idx := strings.Index(line, ": "); idx != -1 {
-			key := line[:idx]
-			value := line[idx+2:]
+		if key, value, ok := parseHeader(line); ok {
 			headers[key] = value
 		}
 	}
 	return headers, nil
 }
 
-// readEventLoop 读取事件循环
-func (c *ESLClient) readEventLoop() {
-	c.running = true
+// parseHeader 解析“键: 值”格式的一行，键为空或缺少分隔符时返回false
+func parseHeader(line string) (string, string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	idx := strings.Index(line, ": ")
+	if idx < 0 {
+		return "", "", false
+	}
+	key := strings.TrimSpace(line[:idx])
+	if key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(line[idx+2:]), true
+}
+
+// readEventLoop 读取事件循环，事件交给处理器，命令响应转交给等待的命令；
+// 连接意外断开时通知OnDisconnect回调并开始重连
+func (c *ESLClient) readEventLoop(conn *eslConn, reader *bufio.Reader) {
 	log.Println("开始事件读取循环")
 
-	for c.running {
-		// 读取事件头部
-		headers, err := c.readHeaders()
+	var err error
+	for {
+		var headers map[string]string
+		headers, err = ReadMessage(reader)
 		if err != nil {
-			log.Printf("读取事件头部失败: %v\n", err)
 			break
 		}
 
-		// 如果有Content-Length，读取事件体
-		if lenStr, ok := headers["Content-Length"]; ok {
-			var contentLength int
-			fmt.Sscanf(lenStr, "%d", &contentLength)
-			if contentLength > 0 {
-				body := make([]byte, contentLength)
-				_, err := c.reader.Read(body)
-				if err != nil {
-					log.Printf("读取事件体失败: %v\n", err)
-					break
-				}
-				// 将事件体解析为头部
-				for _, line := range strings.Split(string(body), "\n") {
-					line = strings.TrimSpace(line)
-					if line == "" {
-						continue
-					}
-					if idx := strings.Index(line, ": "); idx != -1 {
-						key := line[:idx]
-						value := line[idx+2:]
-						headers[key] = value
-					}
-				}
+		switch headers["Content-Type"] {
+		case "command/reply", "api/response":
+			select {
+			case conn.replies <- headers:
+			default:
+				log.Printf("丢弃无人等待的命令响应: %s", headers["Reply-Text"])
 			}
+		case "text/disconnect-notice":
+			log.Println("FreeSWITCH通知即将断开连接")
+		default:
+			// 处理事件
+			go c.handleEvent(headers)
 		}
+	}
+	close(conn.done)
+	conn.Close()
+
+	c.mu.Lock()
+	current := c.conn == conn
+	if current {
+		c.conn = nil
+	}
+	closed := c.closed
+	callbacks := append([]func(error){}, c.onDisconnect...)
+	c.mu.Unlock()
 
-		// 处理事件
-		go c.handleEvent(headers)
+	if closed || !current {
+		log.Println("事件读取循环结束")
+		return
 	}
+	log.Printf("警告: FreeSWITCH连接断开: %v", err)
+	for _, callback := range callbacks {
+		callback(err)
+	}
+	c.reconnect()
+}
+
+// reconnect 按指数退避重连，成功后重新订阅事件并通知OnReconnect回调，调用Close后停止
+func (c *ESLClient) reconnect() {
+	c.mu.RLock()
+	stop := c.stop
+	c.mu.RUnlock()
+
+	delay := c.config.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
 
-	log.Println("事件读取循环结束")
+		conn, reader, err := c.dial()
+		if err != nil {
+			delay = min(delay*2, c.config.MaxReconnectDelay)
+			log.Printf("第%d次重连FreeSWITCH失败: %v，%v后重试", attempt, err, delay)
+			continue
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conn = conn
+		subscribed := c.subscribed
+		callbacks := append([]func(){}, c.onReconnect...)
+		c.mu.Unlock()
+
+		go c.readEventLoop(conn, reader)
+		if subscribed {
+			if err := c.subscribe(); err != nil {
+				// 连接再次断开时读取循环会重新开始重连
+				log.Printf("警告: 重连后重新订阅事件失败: %v", err)
+			}
+		}
+		log.Printf("FreeSWITCH重连成功，共尝试%d次", attempt)
+		for _, callback := range callbacks {
+			callback()
+		}
+		return
+	}
 }
 
 // handleEvent 处理单个事件
@@ -246,14 +577,14 @@ func (c *ESLClient) handleEvent(headers map[string]string) {
 	// 如果有事件名称，调用对应的处理器
 	if eventName, ok := headers["Event-Name"]; ok {
 		c.mu.RLock()
-		handler, exists := c.handlers[eventName]
+		handlers := c.handlers[eventName]
 		c.mu.RUnlock()
 
-		if exists {
+		for _, handler := range handlers {
 			if err := handler(headers); err != nil {
 				log.Printf("事件处理失败: %v\n", err)
 			} else {
-				log.Printf("成功处理事件: %s\n", eventName)
+				eventLog.Printf("成功处理事件: %s\n", eventName)
 			}
 		}
 	}
@@ -0,0 +1,159 @@
+package freeswitch
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ESL事件名称
+const (
+	EventChannelCreate        = "CHANNEL_CREATE"
+	EventChannelProgress      = "CHANNEL_PROGRESS"
+	EventChannelProgressMedia = "CHANNEL_PROGRESS_MEDIA"
+	EventChannelAnswer        = "CHANNEL_ANSWER"
+	EventChannelHangup        = "CHANNEL_HANGUP"
+	EventDTMF                 = "DTMF"
+	EventCustom               = "CUSTOM"
+)
+
+// ChannelEvent 通道事件的公共字段
+type ChannelEvent struct {
+	UUID      string            // 通道UUID（Unique-ID）
+	Direction string            // 呼叫方向，inbound或outbound
+	Caller    string            // 主叫号码
+	Callee    string            // 被叫号码
+	Timestamp time.Time         // 事件发生时间，FreeSWITCH未提供时为零值
+	Headers   map[string]string // 原始事件头部
+}
+
+// ChannelCreate 通道创建事件
+type ChannelCreate struct {
+	ChannelEvent
+}
+
+// ChannelAnswer 通道应答事件
+type ChannelAnswer struct {
+	ChannelEvent
+}
+
+// ChannelHangup 通道挂断事件
+type ChannelHangup struct {
+	ChannelEvent
+	Cause string // 挂断原因，如NORMAL_CLEARING、USER_BUSY
+}
+
+// DTMF 按键事件
+type DTMF struct {
+	ChannelEvent
+	Digit    string // 按键，0-9、*、#、A-D
+	Duration int    // 按键时长，单位为采样点（8000Hz下8个采样点为1毫秒）
+	Source   string // 按键来源，如RTP、INBAND_AUDIO
+}
+
+// parseChannelEvent 解析通道事件的公共字段，事件名称不匹配或缺少通道UUID时返回错误
+func parseChannelEvent(headers map[string]string, name string) (ChannelEvent, error) {
+	if headers["Event-Name"] != name {
+		return ChannelEvent{}, fmt.Errorf("事件类型不匹配: 期望%s，实际%s", name, headers["Event-Name"])
+	}
+	event := ChannelEvent{
+		UUID:      headers["Unique-ID"],
+		Direction: headers["Call-Direction"],
+		Caller:    headers["Caller-Caller-ID-Number"],
+		Callee:    headers["Caller-Destination-Number"],
+		Headers:   headers,
+	}
+	if event.UUID == "" {
+		return ChannelEvent{}, fmt.Errorf("%s事件缺少Unique-ID", name)
+	}
+	// Event-Date-Timestamp 为微秒级Unix时间戳
+	if micros, err := strconv.ParseInt(headers["Event-Date-Timestamp"], 10, 64); err == nil {
+		event.Timestamp = time.UnixMicro(micros)
+	}
+	return event, nil
+}
+
+// ParseChannelCreate 解析通道创建事件
+func ParseChannelCreate(headers map[string]string) (ChannelCreate, error) {
+	event, err := parseChannelEvent(headers, EventChannelCreate)
+	return ChannelCreate{ChannelEvent: event}, err
+}
+
+// ParseChannelAnswer 解析通道应答事件
+func ParseChannelAnswer(headers map[string]string) (ChannelAnswer, error) {
+	event, err := parseChannelEvent(headers, EventChannelAnswer)
+	return ChannelAnswer{ChannelEvent: event}, err
+}
+
+// ParseChannelHangup 解析通道挂断事件
+func ParseChannelHangup(headers map[string]string) (ChannelHangup, error) {
+	event, err := parseChannelEvent(headers, EventChannelHangup)
+	if err != nil {
+		return ChannelHangup{}, err
+	}
+	return ChannelHangup{ChannelEvent: event, Cause: headers["Hangup-Cause"]}, nil
+}
+
+// ParseDTMF 解析按键事件，缺少按键时返回错误
+func ParseDTMF(headers map[string]string) (DTMF, error) {
+	event, err := parseChannelEvent(headers, EventDTMF)
+	if err != nil {
+		return DTMF{}, err
+	}
+	dtmf := DTMF{
+		ChannelEvent: event,
+		Digit:        headers["DTMF-Digit"],
+		Source:       headers["DTMF-Source"],
+	}
+	if dtmf.Digit == "" {
+		return DTMF{}, fmt.Errorf("DTMF事件缺少DTMF-Digit")
+	}
+	if duration, err := strconv.Atoi(headers["DTMF-Duration"]); err == nil {
+		dtmf.Duration = duration
+	}
+	return dtmf, nil
+}
+
+// OnChannelCreate 注册通道创建事件处理器，事件解析失败时不调用处理器并记录错误
+func (c *ESLClient) OnChannelCreate(handler func(ChannelCreate) error) {
+	c.RegisterHandler(EventChannelCreate, func(headers map[string]string) error {
+		event, err := ParseChannelCreate(headers)
+		if err != nil {
+			return err
+		}
+		return handler(event)
+	})
+}
+
+// OnChannelAnswer 注册通道应答事件处理器，事件解析失败时不调用处理器并记录错误
+func (c *ESLClient) OnChannelAnswer(handler func(ChannelAnswer) error) {
+	c.RegisterHandler(EventChannelAnswer, func(headers map[string]string) error {
+		event, err := ParseChannelAnswer(headers)
+		if err != nil {
+			return err
+		}
+		return handler(event)
+	})
+}
+
+// OnChannelHangup 注册通道挂断事件处理器，事件解析失败时不调用处理器并记录错误
+func (c *ESLClient) OnChannelHangup(handler func(ChannelHangup) error) {
+	c.RegisterHandler(EventChannelHangup, func(headers map[string]string) error {
+		event, err := ParseChannelHangup(headers)
+		if err != nil {
+			return err
+		}
+		return handler(event)
+	})
+}
+
+// OnDTMF 注册按键事件处理器，事件解析失败时不调用处理器并记录错误
+func (c *ESLClient) OnDTMF(handler func(DTMF) error) {
+	c.RegisterHandler(EventDTMF, func(headers map[string]string) error {
+		event, err := ParseDTMF(headers)
+		if err != nil {
+			return err
+		}
+		return handler(event)
+	})
+}
@@ -24,6 +24,27 @@ type FSWSClient struct {
 // FSEventHandler FreeSWITCH事件处理函数类型
 type FSEventHandler func(event map[string]interface{}) error
 
+// FSEventMessage FreeSWITCH WebSocket事件消息
+type FSEventMessage struct {
+	Type      string                 `json:"type" ws:"event"` // 消息类型
+	EventName string                 `json:"Event-Name"`      // 事件名称
+	Fields    map[string]interface{} `json:"-"`               // 完整事件字段
+}
+
+// UnmarshalJSON 解析事件消息，同时保留完整的事件字段
+func (m *FSEventMessage) UnmarshalJSON(data []byte) error {
+	type plain FSEventMessage
+	var msg plain
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &msg.Fields); err != nil {
+		return err
+	}
+	*m = FSEventMessage(msg)
+	return nil
+}
+
 // NewFSWSClient 创建新的FreeSWITCH WebSocket客户端
 func NewFSWSClient(config FSWSConfig) *FSWSClient {
 	wsConfig := ws.Config{
@@ -43,27 +64,22 @@ func NewFSWSClient(config FSWSConfig) *FSWSClient {
 	}
 
 	// 注册默认消息处理器
-	client.Client.RegisterHandler("event", func(message []byte) error {
-		// 解析事件
-		var event map[string]interface{}
-		if err := json.Unmarshal(message, &event); err != nil {
-			return fmt.Errorf("解析事件失败: %v", err)
-		}
-
-		// 获取事件名称
-		eventName, ok := event["Event-Name"].(string)
-		if !ok {
+	err := ws.RegisterTypedHandler(client.Client, func(event *FSEventMessage) error {
+		if event.EventName == "" {
 			return fmt.Errorf("事件名称无效")
 		}
 
 		// 调用对应的处理器
-		if handler, ok := client.handlers[eventName]; ok {
-			if err := handler(event); err != nil {
+		if handler, ok := client.handlers[event.EventName]; ok {
+			if err := handler(event.Fields); err != nil {
 				log.Printf("处理事件失败: %v", err)
 			}
 		}
 		return nil
 	})
+	if err != nil {
+		log.Printf("注册事件处理器失败: %v", err)
+	}
 
 	return client
 }
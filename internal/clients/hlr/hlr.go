@@ -0,0 +1,110 @@
+// Package hlr 提供拨打前的号码状态查询（HLR/运营商查询），通过HTTP对接第三方查询服务
+package hlr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// Config 号码状态查询配置
+type Config struct {
+	Enabled      bool              `yaml:"enabled"`       // 是否在拨打前查询
+	URL          string            `yaml:"url"`           // 查询接口地址，号码以number参数传递
+	APIKey       string            `yaml:"api_key"`       // 接口密钥，以Authorization: Bearer请求头传递
+	Timeout      time.Duration     `yaml:"timeout"`       // 单次查询超时时间
+	MaxAge       time.Duration     `yaml:"max_age"`       // 查询结果有效期，有效期内不重复查询
+	StatusField  string            `yaml:"status_field"`  // 响应中号码状态的字段名
+	CarrierField string            `yaml:"carrier_field"` // 响应中运营商的字段名
+	StatusMap    map[string]string `yaml:"status_map"`    // 服务商状态值到active/disconnected/unknown的映射，未映射的值视为unknown
+}
+
+// Client 号码状态查询接口
+type Client interface {
+	// Lookup 查询号码状态
+	Lookup(ctx context.Context, phone string) (models.NumberLookup, error)
+}
+
+// HTTPClient 通过HTTP接口查询号码状态
+// 请求: GET {url}?number=13800000000，响应: JSON对象，按配置的字段名读取状态和运营商
+type HTTPClient struct {
+	config Config
+	client *http.Client
+}
+
+// NewHTTPClient 创建HTTP号码状态查询客户端
+func NewHTTPClient(config Config) (*HTTPClient, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("号码状态查询地址不能为空")
+	}
+	if config.StatusField == "" {
+		config.StatusField = "status"
+	}
+	if config.CarrierField == "" {
+		config.CarrierField = "carrier"
+	}
+	return &HTTPClient{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Lookup 查询号码状态
+func (c *HTTPClient) Lookup(ctx context.Context, phone string) (models.NumberLookup, error) {
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return models.NumberLookup{}, fmt.Errorf("号码状态查询地址无效: %v", err)
+	}
+	query := u.Query()
+	query.Set("number", phone)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return models.NumberLookup{}, fmt.Errorf("创建请求失败: %v", err)
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return models.NumberLookup{}, fmt.Errorf("查询号码状态失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return models.NumberLookup{}, fmt.Errorf("查询号码状态失败: HTTP %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return models.NumberLookup{}, fmt.Errorf("解析号码状态失败: %v", err)
+	}
+
+	carrier, _ := result[c.config.CarrierField].(string)
+	return models.NumberLookup{
+		Status:    c.status(fmt.Sprint(result[c.config.StatusField])),
+		Carrier:   carrier,
+		CheckedAt: time.Now(),
+	}, nil
+}
+
+// status 将服务商的状态值映射为号码状态
+func (c *HTTPClient) status(value string) string {
+	if status, ok := c.config.StatusMap[value]; ok {
+		return status
+	}
+	switch value {
+	case models.NumberStatusActive, models.NumberStatusDisconnected:
+		return value
+	}
+	return models.NumberStatusUnknown
+}
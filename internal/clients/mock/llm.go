@@ -0,0 +1,188 @@
+// Package mock 提供用于压测和CI的模拟服务，无需依赖外部的大模型、语音服务
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/recorder"
+)
+
+// defaultReply 未配置任何回复时使用的默认回复
+const defaultReply = "好的，我明白了。"
+
+// LLMConfig 模拟大模型配置
+type LLMConfig struct {
+	Rules   []LLMRule     `yaml:"rules"`   // 回复规则，按顺序匹配，命中第一条
+	Default string        `yaml:"default"` // 没有规则命中时的回复
+	Latency time.Duration `yaml:"latency"` // 模拟生成耗时
+}
+
+// LLMRule 回复规则
+// Pattern为正则表达式，匹配提示词的最后一行（即本轮用户输入）；Response为text/template模板，
+// 可使用 {{.Input}} 本轮输入、{{.Prompt}} 完整提示词、{{index .Groups 1}} 正则分组
+type LLMRule struct {
+	Pattern  string `yaml:"pattern"`
+	Response string `yaml:"response"`
+}
+
+// compiledRule 编译后的回复规则
+type compiledRule struct {
+	pattern  *regexp.Regexp
+	response *template.Template
+}
+
+// templateData 回复模板参数
+type templateData struct {
+	Input  string
+	Prompt string
+	Groups []string
+}
+
+// LLMClient 模拟大模型客户端，接口与ollama.Client一致
+type LLMClient struct {
+	config   LLMConfig
+	rules    []compiledRule
+	fallback *template.Template
+	recorder recorder.Hook
+}
+
+// NewLLMClient 创建模拟大模型客户端
+func NewLLMClient(config LLMConfig) (*LLMClient, error) {
+	client := &LLMClient{config: config}
+
+	for i, rule := range config.Rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("第%d条模拟回复规则正则无效: %v", i+1, err)
+		}
+		response, err := template.New(fmt.Sprintf("rule%d", i)).Parse(rule.Response)
+		if err != nil {
+			return nil, fmt.Errorf("第%d条模拟回复规则模板无效: %v", i+1, err)
+		}
+		client.rules = append(client.rules, compiledRule{pattern: pattern, response: response})
+	}
+
+	fallback := config.Default
+	if fallback == "" {
+		fallback = defaultReply
+	}
+	tmpl, err := template.New("default").Parse(fallback)
+	if err != nil {
+		return nil, fmt.Errorf("默认模拟回复模板无效: %v", err)
+	}
+	client.fallback = tmpl
+
+	return client, nil
+}
+
+// SetRecorder 设置飞行记录仪
+func (c *LLMClient) SetRecorder(hook recorder.Hook) {
+	c.recorder = hook
+}
+
+// Generate 生成回复
+func (c *LLMClient) Generate(prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	return c.GenerateContext(context.Background(), prompt, options)
+}
+
+// GenerateContext 按规则生成回复
+func (c *LLMClient) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	start := time.Now()
+	c.record(ctx, recorder.KindRequest, map[string]interface{}{"prompt": prompt, "options": options})
+
+	if c.config.Latency > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.config.Latency):
+		}
+	}
+
+	reply, err := c.reply(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ollama.GenerateResponse{
+		Model:         "mock",
+		CreatedAt:     time.Now().Format(time.RFC3339),
+		Response:      reply,
+		Done:          true,
+		TotalDuration: time.Since(start).Nanoseconds(),
+		EvalCount:     len([]rune(reply)),
+	}
+	c.record(ctx, recorder.KindResponse, response)
+	return response, nil
+}
+
+// GenerateStream 按字逐段回调，模拟流式输出
+func (c *LLMClient) GenerateStream(prompt string, options ollama.Options, callback func(*ollama.GenerateResponse) error) error {
+	full, err := c.GenerateContext(context.Background(), prompt, options)
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(full.Response)
+	for i, r := range runes {
+		chunk := &ollama.GenerateResponse{
+			Model:     full.Model,
+			CreatedAt: full.CreatedAt,
+			Response:  string(r),
+			Done:      i == len(runes)-1,
+		}
+		if chunk.Done {
+			chunk.TotalDuration = full.TotalDuration
+			chunk.EvalCount = full.EvalCount
+		}
+		if err := callback(chunk); err != nil {
+			return fmt.Errorf("处理响应失败: %v", err)
+		}
+	}
+	if len(runes) == 0 {
+		return callback(full)
+	}
+	return nil
+}
+
+// reply 根据提示词匹配规则并渲染回复
+func (c *LLMClient) reply(prompt string) (string, error) {
+	data := templateData{Input: lastLine(prompt), Prompt: prompt}
+
+	tmpl := c.fallback
+	for _, rule := range c.rules {
+		if groups := rule.pattern.FindStringSubmatch(data.Input); groups != nil {
+			data.Groups = groups
+			tmpl = rule.response
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染模拟回复失败: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// record 记录模拟报文
+func (c *LLMClient) record(ctx context.Context, kind string, v interface{}) {
+	if c.recorder == nil {
+		return
+	}
+	payload, _ := json.Marshal(v)
+	c.recorder.Record(ctx, "mock", kind, payload)
+}
+
+// lastLine 获取最后一个非空行
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,95 @@
+package mock
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode"
+
+	"ai_dialer_mini/internal/clients/tts"
+)
+
+// 模拟语音合成模式
+const (
+	TTSModeTone    = "tone"    // 每个字一声提示音
+	TTSModeSilence = "silence" // 静音
+)
+
+// TTSConfig 模拟语音合成配置
+type TTSConfig struct {
+	Mode         string        `yaml:"mode"`          // 合成模式，tone或silence
+	CharDuration time.Duration `yaml:"char_duration"` // 每个字对应的音频时长
+	Frequency    float64       `yaml:"frequency"`     // 提示音基准频率(Hz)
+	SampleRate   int           `yaml:"sample_rate"`   // 默认采样率
+}
+
+// TTSClient 模拟语音合成客户端，输出时长与文本长度成正比的确定性音频
+type TTSClient struct {
+	config TTSConfig
+}
+
+// NewTTSClient 创建模拟语音合成客户端
+func NewTTSClient(config TTSConfig) (*TTSClient, error) {
+	if config.Mode == "" {
+		config.Mode = TTSModeTone
+	}
+	if config.Mode != TTSModeTone && config.Mode != TTSModeSilence {
+		return nil, fmt.Errorf("不支持的模拟语音合成模式: %s", config.Mode)
+	}
+	if config.CharDuration <= 0 {
+		config.CharDuration = 200 * time.Millisecond
+	}
+	if config.Frequency <= 0 {
+		config.Frequency = 440
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = 16000
+	}
+	return &TTSClient{config: config}, nil
+}
+
+// Synthesize 合成音频：每个非空白字符对应CharDuration时长，tone模式下前80%为提示音、后20%为静音
+// 提示音频率由字符决定，相同文本总是得到相同的音频
+func (c *TTSClient) Synthesize(ctx context.Context, text string, options tts.Options) (*tts.Audio, error) {
+	sampleRate := options.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = c.config.SampleRate
+	}
+	charDuration := c.config.CharDuration
+	if options.Speed > 0 {
+		charDuration = time.Duration(float64(charDuration) / options.Speed)
+	}
+	frequency := c.config.Frequency
+	if options.Pitch > 0 {
+		frequency *= options.Pitch
+	}
+	samplesPerChar := int(int64(sampleRate) * int64(charDuration) / int64(time.Second))
+	toneSamples := samplesPerChar * 4 / 5
+
+	var chars []rune
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			chars = append(chars, r)
+		}
+	}
+
+	pcm := make([]byte, len(chars)*samplesPerChar*2)
+	if c.config.Mode == TTSModeTone {
+		for i, r := range chars {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// 按字符在基准频率上做八度内的偏移，便于人耳区分
+			freq := frequency * math.Pow(2, float64(r%12)/12)
+			offset := i * samplesPerChar * 2
+			for n := 0; n < toneSamples; n++ {
+				v := int16(0.3 * math.MaxInt16 * math.Sin(2*math.Pi*freq*float64(n)/float64(sampleRate)))
+				binary.LittleEndian.PutUint16(pcm[offset+n*2:], uint16(v))
+			}
+		}
+	}
+
+	return &tts.Audio{PCM: pcm, SampleRate: sampleRate, Channels: 1}, nil
+}
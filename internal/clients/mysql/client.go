@@ -0,0 +1,34 @@
+// Package mysql 提供MySQL数据库连接封装
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	// 注册MySQL驱动
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Open 打开MySQL连接池并检查连通性
+func Open(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开MySQL连接失败: %v", err)
+	}
+
+	// 连接池参数
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接MySQL失败: %v", err)
+	}
+
+	return db, nil
+}
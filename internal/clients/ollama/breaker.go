@@ -0,0 +1,211 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/recorder"
+)
+
+// ErrCircuitOpen Ollama连续失败已熔断，且未配置兜底回复
+var ErrCircuitOpen = errors.New("Ollama服务不可用，已熔断")
+
+// 熔断默认值
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+	defaultProbeInterval    = 10 * time.Second
+)
+
+// BreakerConfig 熔断配置
+type BreakerConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Threshold     int           `yaml:"threshold"`      // 连续失败多少次（重试用尽后计一次）后熔断
+	Cooldown      time.Duration `yaml:"cooldown"`       // 熔断后经过该时间放行一个试探请求，成功即恢复
+	ProbeInterval time.Duration `yaml:"probe_interval"` // 熔断期间探测 /api/tags 的间隔，探测成功即恢复
+	Fallback      string        `yaml:"fallback"`       // 熔断期间的兜底回复，为空时直接返回错误
+}
+
+// Validate 校验熔断配置
+func (c BreakerConfig) Validate() error {
+	if c.Threshold < 0 {
+		return fmt.Errorf("threshold不能为负数")
+	}
+	if c.Cooldown < 0 {
+		return fmt.Errorf("cooldown不能为负数")
+	}
+	if c.ProbeInterval < 0 {
+		return fmt.Errorf("probe_interval不能为负数")
+	}
+	return nil
+}
+
+// Breaker 带熔断的Ollama客户端：连续失败达到阈值后不再请求Ollama，直接返回兜底回复，
+// 冷却时间过后放行一个试探请求，或后台探测 /api/tags 成功后恢复
+type Breaker struct {
+	client *Client
+	config BreakerConfig
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	since    time.Time // 熔断的时间
+	openedAt time.Time // 熔断或上次试探失败的时间，冷却时间从此时开始计算
+	trial    bool      // 是否有试探请求正在进行
+}
+
+// NewBreaker 为client创建熔断包装，未配置的参数使用默认值
+func NewBreaker(client *Client, config BreakerConfig) *Breaker {
+	if config.Threshold <= 0 {
+		config.Threshold = defaultBreakerThreshold
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaultBreakerCooldown
+	}
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = defaultProbeInterval
+	}
+	return &Breaker{client: client, config: config}
+}
+
+// SetRecorder 设置飞行记录仪
+func (b *Breaker) SetRecorder(hook recorder.Hook) {
+	b.client.SetRecorder(hook)
+}
+
+// Ping 探测Ollama服务是否可用
+func (b *Breaker) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx)
+}
+
+// Open 是否已熔断
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// GenerateContext 生成文本，熔断期间返回兜底回复
+func (b *Breaker) GenerateContext(ctx context.Context, prompt string, options Options) (*GenerateResponse, error) {
+	if !b.allow() {
+		if b.config.Fallback == "" {
+			return nil, ErrCircuitOpen
+		}
+		return &GenerateResponse{Model: b.client.model(options), Response: b.config.Fallback, Done: true, Fallback: true}, nil
+	}
+	response, err := b.client.GenerateContext(ctx, prompt, options)
+	b.report(ctx, err)
+	return response, err
+}
+
+// GenerateStream 流式生成文本，熔断期间以一个片段回调兜底回复
+func (b *Breaker) GenerateStream(prompt string, options Options, callback func(*GenerateResponse) error) error {
+	return b.GenerateStreamContext(context.Background(), prompt, options, callback)
+}
+
+// GenerateStreamContext 流式生成文本，ctx取消时中断请求；调用方中止（回调返回错误）不计为失败
+func (b *Breaker) GenerateStreamContext(ctx context.Context, prompt string, options Options, callback func(*GenerateResponse) error) error {
+	if !b.allow() {
+		if b.config.Fallback == "" {
+			return ErrCircuitOpen
+		}
+		return callback(&GenerateResponse{Model: b.client.model(options), Response: b.config.Fallback, Done: true, Fallback: true})
+	}
+	var callbackErr error
+	err := b.client.GenerateStreamContext(ctx, prompt, options, func(response *GenerateResponse) error {
+		callbackErr = callback(response)
+		return callbackErr
+	})
+	if callbackErr != nil {
+		b.release()
+		return err
+	}
+	b.report(ctx, err)
+	return err
+}
+
+// Run 熔断期间定期探测 /api/tags，探测成功时恢复，直到ctx取消
+func (b *Breaker) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !b.Open() {
+				continue
+			}
+			if err := b.client.Ping(ctx); err != nil {
+				log.Printf("Ollama服务探测失败: %v", err)
+				continue
+			}
+			b.close("探测成功")
+		}
+	}
+}
+
+// allow 是否放行请求：未熔断时放行；熔断后超过冷却时间且没有试探请求时放行一个试探请求
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.config.Cooldown {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// release 请求被调用方中止，不计入结果，允许下一个试探请求
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// report 记录请求结果：成功时恢复并清零失败次数，失败达到阈值时熔断；调用方取消不计为失败
+func (b *Breaker) report(ctx context.Context, err error) {
+	if err == nil {
+		b.close("请求成功")
+		return
+	}
+	if ctx.Err() != nil {
+		b.release()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.open {
+		// 试探请求失败，重新计算冷却时间
+		b.trial = false
+		b.openedAt = time.Now()
+		return
+	}
+	if b.failures >= b.config.Threshold {
+		log.Printf("警告: Ollama连续失败 %d 次，已熔断: %v", b.failures, err)
+		b.open = true
+		b.since = time.Now()
+		b.openedAt = b.since
+	}
+}
+
+// close 恢复请求
+func (b *Breaker) close(reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+	if b.open {
+		log.Printf("Ollama服务已恢复（%s），熔断时长: %v", reason, time.Since(b.since).Round(time.Second))
+		b.open = false
+	}
+}
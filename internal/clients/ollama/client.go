@@ -1,23 +1,121 @@
 package ollama
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand"
+	"net"
 	"net/http"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/recorder"
 )
 
 // Config Ollama客户端配置
 type Config struct {
-	Host  string // Ollama服务器地址（完整URL）
-	Model string // 使用的模型名称
+	Host                string        `yaml:"host"`                    // Ollama服务器地址（完整URL）
+	Model               string        `yaml:"model"`                   // 使用的模型名称
+	Timeout             time.Duration `yaml:"timeout"`                 // 请求超时时间，流式输出时为整个回复的超时时间，为0时不限制
+	DialTimeout         time.Duration `yaml:"dial_timeout"`            // 建立TCP连接的超时时间
+	MaxIdleConns        int           `yaml:"max_idle_conns"`          // 连接池保持的最大空闲连接数
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"` // 每个Ollama服务器保持的最大空闲连接数，应不小于并发通话数
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`       // 空闲连接的最长保留时间
+	MaxRetries          int           `yaml:"max_retries"`             // 连接失败或服务器返回500/502/503/504时的最大重试次数
+	RetryBackoff        time.Duration `yaml:"retry_backoff"`           // 首次重试前的等待时间，之后每次翻倍
+	RetryJitter         float64       `yaml:"retry_jitter"`            // 重试等待时间的随机浮动比例（0~1），避免多路通话同时重试
+	Breaker             BreakerConfig `yaml:"breaker"`                 // 熔断配置
+}
+
+// Validate 校验Ollama客户端配置
+func (c Config) Validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout不能为负数")
+	}
+	if c.DialTimeout < 0 {
+		return fmt.Errorf("dial_timeout不能为负数")
+	}
+	if c.MaxIdleConns < 0 {
+		return fmt.Errorf("max_idle_conns不能为负数")
+	}
+	if c.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("max_idle_conns_per_host不能为负数")
+	}
+	if c.IdleConnTimeout < 0 {
+		return fmt.Errorf("idle_conn_timeout不能为负数")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max_retries不能为负数")
+	}
+	if c.RetryBackoff < 0 {
+		return fmt.Errorf("retry_backoff不能为负数")
+	}
+	if c.RetryJitter < 0 || c.RetryJitter > 1 {
+		return fmt.Errorf("retry_jitter必须在0到1之间")
+	}
+	if err := c.Breaker.Validate(); err != nil {
+		return fmt.Errorf("breaker.%v", err)
+	}
+	return nil
+}
+
+// transportKey 连接池参数，参数相同的客户端共用同一个连接池
+type transportKey struct {
+	dialTimeout         time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+}
+
+// transports 按连接池参数共享的连接池，每个对话服务各自创建客户端时仍复用已建立的连接
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[transportKey]*http.Transport)
+)
+
+// sharedTransport 获取连接池参数对应的共享连接池，未配置的参数使用net/http的默认值
+func sharedTransport(config Config) *http.Transport {
+	key := transportKey{
+		dialTimeout:         config.DialTimeout,
+		maxIdleConns:        config.MaxIdleConns,
+		maxIdleConnsPerHost: config.MaxIdleConnsPerHost,
+		idleConnTimeout:     config.IdleConnTimeout,
+	}
+
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if transport, ok := transports[key]; ok {
+		return transport
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if key.dialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: key.dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	}
+	if key.maxIdleConns > 0 {
+		transport.MaxIdleConns = key.maxIdleConns
+	}
+	if key.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = key.maxIdleConnsPerHost
+	}
+	if key.idleConnTimeout > 0 {
+		transport.IdleConnTimeout = key.idleConnTimeout
+	}
+	transports[key] = transport
+	return transport
 }
 
 // Client Ollama客户端
 type Client struct {
-	config Config
-	client *http.Client
+	config   Config
+	client   *http.Client
+	recorder recorder.Hook
 }
 
 // GenerateRequest 生成请求参数
@@ -35,6 +133,7 @@ type Options struct {
 	TopP        float64 `json:"top_p,omitempty"`      // Top-p采样
 	TopK        int     `json:"top_k,omitempty"`      // Top-k采样
 	MaxTokens   int     `json:"max_tokens,omitempty"` // 最大生成token数
+	Model       string  `json:"-"`                    // 覆盖配置的模型名称（如租户指定的模型），为空时使用配置
 }
 
 // GenerateResponse 生成响应
@@ -49,21 +148,54 @@ type GenerateResponse struct {
 	PromptEvalCount   int       `json:"prompt_eval_count"`  // 提示词评估数量
 	EvalCount         int       `json:"eval_count"`         // 评估数量
 	EvalDuration      int64     `json:"eval_duration"`      // 评估耗时(纳秒)
+	Error             string    `json:"error,omitempty"`    // 生成过程中出错时的错误信息
+	Fallback          bool      `json:"-"`                  // 是否为熔断期间的兜底回复，不是大模型生成的
 }
 
-// NewClient 创建新的Ollama客户端
+// maxStreamLine 流式响应单行的最大长度，超过时中止读取，避免异常响应占用过多内存
+const maxStreamLine = 1024 * 1024
+
+// NewClient 创建新的Ollama客户端，连接池参数相同的客户端共用连接
 func NewClient(config Config) *Client {
 	return &Client{
 		config: config,
-		client: &http.Client{},
+		client: &http.Client{
+			Transport: sharedTransport(config),
+			Timeout:   config.Timeout,
+		},
+	}
+}
+
+// SetRecorder 设置飞行记录仪，记录原始请求和响应
+func (c *Client) SetRecorder(hook recorder.Hook) {
+	c.recorder = hook
+}
+
+// record 记录原始报文
+func (c *Client) record(ctx context.Context, kind string, payload []byte) {
+	if c.recorder != nil {
+		c.recorder.Record(ctx, "ollama", kind, payload)
 	}
 }
 
 // Generate 生成文本
 func (c *Client) Generate(prompt string, options Options) (*GenerateResponse, error) {
+	return c.GenerateContext(context.Background(), prompt, options)
+}
+
+// model 请求使用的模型名称，选项指定了模型时优先使用
+func (c *Client) model(options Options) string {
+	if options.Model != "" {
+		return options.Model
+	}
+	return c.config.Model
+}
+
+// GenerateContext 生成文本，ctx可携带飞行记录仪的通话标记
+func (c *Client) GenerateContext(ctx context.Context, prompt string, options Options) (*GenerateResponse, error) {
 	// 准备请求体
 	reqBody := GenerateRequest{
-		Model:   c.config.Model,
+		Model:   c.model(options),
 		Prompt:  prompt,
 		Stream:  false,
 		Options: options,
@@ -75,35 +207,29 @@ func (c *Client) Generate(prompt string, options Options) (*GenerateResponse, er
 		return nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	// 构建请求URL
-	url := fmt.Sprintf("%s/api/generate", c.config.Host)
-	
-	// 创建请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	c.record(ctx, recorder.KindRequest, jsonData)
 
 	// 发送请求
-	resp, err := c.client.Do(req)
+	resp, err := c.post(ctx, jsonData)
 	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	c.record(ctx, recorder.KindResponse, body)
+
 	// 检查响应状态码
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("服务器返回错误: %s", string(body))
 	}
 
 	// 解析响应
 	var response GenerateResponse
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&response); err != nil {
+	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
 
@@ -112,9 +238,14 @@ func (c *Client) Generate(prompt string, options Options) (*GenerateResponse, er
 
 // GenerateStream 流式生成文本
 func (c *Client) GenerateStream(prompt string, options Options, callback func(*GenerateResponse) error) error {
+	return c.GenerateStreamContext(context.Background(), prompt, options, callback)
+}
+
+// GenerateStreamContext 流式生成文本，ctx取消时中断请求
+func (c *Client) GenerateStreamContext(ctx context.Context, prompt string, options Options, callback func(*GenerateResponse) error) error {
 	// 准备请求体
 	reqBody := GenerateRequest{
-		Model:   c.config.Model,
+		Model:   c.model(options),
 		Prompt:  prompt,
 		Stream:  true,
 		Options: options,
@@ -126,22 +257,10 @@ func (c *Client) GenerateStream(prompt string, options Options, callback func(*G
 		return fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	// 构建请求URL
-	url := fmt.Sprintf("%s/api/generate", c.config.Host)
-
-	// 创建请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
-
 	// 发送请求
-	resp, err := c.client.Do(req)
+	resp, err := c.post(ctx, jsonData)
 	if err != nil {
-		return fmt.Errorf("发送请求失败: %v", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -151,24 +270,121 @@ func (c *Client) GenerateStream(prompt string, options Options, callback func(*G
 		return fmt.Errorf("服务器返回错误: %s", string(body))
 	}
 
-	// 创建解码器
-	decoder := json.NewDecoder(resp.Body)
+	// 逐行读取响应，跳过保活空行；网络分片到达的半行由scanner缓存到换行后再解析
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
 
-	// 逐行读取响应
-	for decoder.More() {
 		var response GenerateResponse
-		if err := decoder.Decode(&response); err != nil {
+		if err := json.Unmarshal(line, &response); err != nil {
 			return fmt.Errorf("解析响应失败: %v", err)
 		}
+		if response.Error != "" {
+			return fmt.Errorf("服务器返回错误: %s", response.Error)
+		}
 
 		if err := callback(&response); err != nil {
 			return fmt.Errorf("处理响应失败: %v", err)
 		}
 
 		if response.Done {
-			break
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("读取响应失败: 单行超过 %d 字节", maxStreamLine)
 		}
+		return fmt.Errorf("读取响应失败: %v", err)
 	}
+	return fmt.Errorf("读取响应失败: 响应流在生成完成前结束")
+}
 
+// post 发送生成请求，连接失败或服务器暂时不可用时按退避时间重试，ctx取消时停止重试
+func (c *Client) post(ctx context.Context, body []byte) (*http.Response, error) {
+	url := fmt.Sprintf("%s/api/generate", c.config.Host)
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("创建请求失败: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.client.Do(req)
+		var reason string
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return nil, fmt.Errorf("发送请求失败: %w", ctx.Err())
+		case err != nil:
+			reason = err.Error()
+		case transientStatus(resp.StatusCode):
+			reason = resp.Status
+		default:
+			return resp, nil
+		}
+
+		if attempt >= c.config.MaxRetries {
+			if err != nil {
+				return nil, fmt.Errorf("发送请求失败: %v", err)
+			}
+			return resp, nil
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		wait := c.jitter(backoff)
+		log.Printf("Ollama请求失败，%v 后第 %d 次重试: %s", wait, attempt+1, reason)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("发送请求失败: %w", ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// jitter 在退避时间上加减随机浮动，未配置浮动比例时原样返回
+func (c *Client) jitter(backoff time.Duration) time.Duration {
+	if c.config.RetryJitter <= 0 || backoff <= 0 {
+		return backoff
+	}
+	delta := (rand.Float64()*2 - 1) * c.config.RetryJitter * float64(backoff)
+	return backoff + time.Duration(delta)
+}
+
+// Ping 请求 /api/tags 探测Ollama服务是否可用
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/tags", c.config.Host), nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("服务器返回错误: %s %s", resp.Status, string(body))
+	}
+	io.Copy(io.Discard, resp.Body)
 	return nil
 }
+
+// transientStatus 是否为可重试的暂时性错误，如模型加载中、网关超时
+func transientStatus(code int) bool {
+	switch code {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,272 @@
+// Package openai 提供OpenAI兼容的对话补全客户端，可对接OpenAI、Azure OpenAI、DeepSeek等服务
+// 接口与ollama.Client一致，可作为对话服务的大模型后端
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/recorder"
+)
+
+// Config OpenAI兼容接口配置
+type Config struct {
+	BaseURL      string        `yaml:"base_url"`      // 接口地址，如 https://api.openai.com/v1、https://api.deepseek.com/v1；Azure为 https://{资源名}.openai.azure.com/openai/deployments/{部署名}
+	APIKey       string        `yaml:"api_key"`       // 接口密钥
+	Model        string        `yaml:"model"`         // 模型名称，Azure可留空（由部署决定）
+	APIVersion   string        `yaml:"api_version"`   // Azure OpenAI接口版本，如 2024-02-01；填写后按Azure方式鉴权
+	SystemPrompt string        `yaml:"system_prompt"` // 系统提示词，留空则不发送
+	Timeout      time.Duration `yaml:"timeout"`       // 请求超时时间，流式输出时为整个回复的超时时间
+}
+
+// Client OpenAI兼容接口客户端
+type Client struct {
+	config   Config
+	client   *http.Client
+	recorder recorder.Hook
+}
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`    // 角色: system/user/assistant
+	Content string `json:"content"` // 消息内容
+}
+
+// ChatRequest 对话补全请求
+type ChatRequest struct {
+	Model         string         `json:"model,omitempty"`          // 模型名称
+	Messages      []Message      `json:"messages"`                 // 对话消息
+	Temperature   float64        `json:"temperature,omitempty"`    // 温度参数
+	TopP          float64        `json:"top_p,omitempty"`          // Top-p采样
+	MaxTokens     int            `json:"max_tokens,omitempty"`     // 最大生成token数
+	Stream        bool           `json:"stream,omitempty"`         // 是否流式输出
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"` // 流式输出选项
+}
+
+// StreamOptions 流式输出选项
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"` // 在最后一个数据块中返回token用量
+}
+
+// Usage token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`     // 提示词token数
+	CompletionTokens int `json:"completion_tokens"` // 生成token数
+	TotalTokens      int `json:"total_tokens"`      // 总token数
+}
+
+// ChatResponse 对话补全响应，流式输出时每个数据块的choices中为delta
+type ChatResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Message      Message `json:"message"`
+		Delta        Message `json:"delta"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// NewClient 创建OpenAI兼容接口客户端
+func NewClient(config Config) *Client {
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &Client{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// SetRecorder 设置飞行记录仪，记录原始请求和响应
+func (c *Client) SetRecorder(hook recorder.Hook) {
+	c.recorder = hook
+}
+
+// record 记录原始报文
+func (c *Client) record(ctx context.Context, kind string, payload []byte) {
+	if c.recorder != nil {
+		c.recorder.Record(ctx, "openai", kind, payload)
+	}
+}
+
+// Generate 生成文本
+func (c *Client) Generate(prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	return c.GenerateContext(context.Background(), prompt, options)
+}
+
+// GenerateContext 生成文本，ctx可携带飞行记录仪的通话标记
+func (c *Client) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	start := time.Now()
+	resp, err := c.do(ctx, c.newRequest(prompt, options, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	c.record(ctx, recorder.KindResponse, body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("服务器返回错误: HTTP %d - %s", resp.StatusCode, string(body))
+	}
+
+	var chat ChatResponse
+	if err := json.Unmarshal(body, &chat); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("响应中没有生成结果")
+	}
+
+	response := &ollama.GenerateResponse{
+		Model:         chat.Model,
+		CreatedAt:     time.Unix(chat.Created, 0).Format(time.RFC3339),
+		Response:      chat.Choices[0].Message.Content,
+		Done:          true,
+		TotalDuration: time.Since(start).Nanoseconds(),
+	}
+	c.applyUsage(response, chat.Usage)
+	return response, nil
+}
+
+// GenerateStream 流式生成文本，按服务器推送的数据块回调，最后一次回调Done为true并带有token用量
+func (c *Client) GenerateStream(prompt string, options ollama.Options, callback func(*ollama.GenerateResponse) error) error {
+	ctx := context.Background()
+	start := time.Now()
+	resp, err := c.do(ctx, c.newRequest(prompt, options, true))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("服务器返回错误: HTTP %d - %s", resp.StatusCode, string(body))
+	}
+
+	// 服务器推送事件格式，每个数据块一行 "data: {...}"，以 "data: [DONE]" 结束
+	final := &ollama.GenerateResponse{Done: true}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk ChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+		final.Model = chunk.Model
+		final.CreatedAt = time.Unix(chunk.Created, 0).Format(time.RFC3339)
+		if chunk.Usage != nil {
+			c.applyUsage(final, chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		if err := callback(&ollama.GenerateResponse{
+			Model:     final.Model,
+			CreatedAt: final.CreatedAt,
+			Response:  chunk.Choices[0].Delta.Content,
+		}); err != nil {
+			return fmt.Errorf("处理响应失败: %v", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	final.TotalDuration = time.Since(start).Nanoseconds()
+	if err := callback(final); err != nil {
+		return fmt.Errorf("处理响应失败: %v", err)
+	}
+	return nil
+}
+
+// newRequest 构建对话补全请求，提示词作为一条用户消息发送
+func (c *Client) newRequest(prompt string, options ollama.Options, stream bool) ChatRequest {
+	var messages []Message
+	if c.config.SystemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: c.config.SystemPrompt})
+	}
+	messages = append(messages, Message{Role: "user", Content: prompt})
+
+	model := c.config.Model
+	if options.Model != "" {
+		model = options.Model
+	}
+	req := ChatRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   options.MaxTokens,
+		Stream:      stream,
+	}
+	if stream {
+		req.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	return req
+}
+
+// do 发送请求，Azure OpenAI使用api-key请求头和api-version参数，其他服务使用Bearer令牌
+func (c *Client) do(ctx context.Context, chat ChatRequest) (*http.Response, error) {
+	jsonData, err := json.Marshal(chat)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	url := c.config.BaseURL + "/chat/completions"
+	if c.config.APIVersion != "" {
+		url += "?api-version=" + c.config.APIVersion
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	c.record(ctx, recorder.KindRequest, jsonData)
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIVersion != "" {
+		req.Header.Set("api-key", c.config.APIKey)
+	} else if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	return resp, nil
+}
+
+// applyUsage 将token用量写入响应并记录日志，便于核算费用
+func (c *Client) applyUsage(response *ollama.GenerateResponse, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	response.PromptEvalCount = usage.PromptTokens
+	response.EvalCount = usage.CompletionTokens
+	log.Printf("大模型token用量: 模型=%s, 提示词=%d, 生成=%d, 合计=%d",
+		response.Model, usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+}
@@ -0,0 +1,198 @@
+// Package punctuation 对接本地部署的文本后处理服务，为没有标点的识别结果添加标点并切分句子
+// 讯飞电话模式（ptt=0）等不返回标点的识别结果经该服务处理后再交给大模型
+package punctuation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Config 文本后处理服务配置
+type Config struct {
+	Enabled     bool          `yaml:"enabled"`      // 是否启用
+	URL         string        `yaml:"url"`          // 服务地址
+	Timeout     time.Duration `yaml:"timeout"`      // 单次请求超时时间
+	BatchSize   int           `yaml:"batch_size"`   // 单次请求最多合并的文本数
+	BatchWindow time.Duration `yaml:"batch_window"` // 等待合并的最长时间，0表示不等待
+}
+
+// Result 后处理结果
+type Result struct {
+	Text      string   `json:"text"`      // 添加标点后的文本
+	Sentences []string `json:"sentences"` // 切分后的句子
+}
+
+// request 后处理请求
+type request struct {
+	Texts []string `json:"texts"`
+}
+
+// response 后处理响应，results与请求的texts一一对应
+type response struct {
+	Results []Result `json:"results"`
+}
+
+// call 等待合并发送的单条文本
+type call struct {
+	text   string
+	result chan callResult
+}
+
+// callResult 单条文本的处理结果
+type callResult struct {
+	result Result
+	err    error
+}
+
+// Client 文本后处理客户端，并发的单条请求在BatchWindow内合并为一次批量请求
+// 请求: POST {url} {"texts": ["..."]}，响应: {"results": [{"text": "...", "sentences": ["..."]}]}
+type Client struct {
+	config Config
+	client *http.Client
+
+	mu      sync.Mutex
+	pending []*call
+	timer   *time.Timer
+}
+
+// NewClient 创建文本后处理客户端
+func NewClient(config Config) (*Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("文本后处理服务地址不能为空")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	return &Client{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// HasPunctuation 文本是否已包含标点
+func HasPunctuation(text string) bool {
+	return strings.IndexFunc(text, unicode.IsPunct) >= 0
+}
+
+// Punctuate 为文本添加标点，文本为空或已有标点时原样返回
+func (c *Client) Punctuate(ctx context.Context, text string) (string, error) {
+	if strings.TrimSpace(text) == "" || HasPunctuation(text) {
+		return text, nil
+	}
+	result, err := c.Process(ctx, text)
+	if err != nil {
+		return text, err
+	}
+	return result.Text, nil
+}
+
+// Process 添加标点并切分句子，与其他并发请求合并发送
+func (c *Client) Process(ctx context.Context, text string) (Result, error) {
+	pending := &call{text: text, result: make(chan callResult, 1)}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, pending)
+	switch {
+	case len(c.pending) >= c.config.BatchSize || c.config.BatchWindow <= 0:
+		batch := c.takeLocked()
+		c.mu.Unlock()
+		go c.send(batch)
+	case len(c.pending) == 1:
+		c.timer = time.AfterFunc(c.config.BatchWindow, c.flush)
+		c.mu.Unlock()
+	default:
+		c.mu.Unlock()
+	}
+
+	select {
+	case r := <-pending.result:
+		return r.result, r.err
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+// flush 合并时间到期，发送当前积累的文本
+func (c *Client) flush() {
+	c.mu.Lock()
+	batch := c.takeLocked()
+	c.mu.Unlock()
+	c.send(batch)
+}
+
+// takeLocked 取出积累的文本，调用方需持有锁
+func (c *Client) takeLocked() []*call {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	batch := c.pending
+	c.pending = nil
+	return batch
+}
+
+// send 批量发送并分发结果
+func (c *Client) send(batch []*call) {
+	if len(batch) == 0 {
+		return
+	}
+	texts := make([]string, len(batch))
+	for i, pending := range batch {
+		texts[i] = pending.text
+	}
+
+	results, err := c.ProcessBatch(context.Background(), texts)
+	for i, pending := range batch {
+		if err != nil {
+			pending.result <- callResult{err: err}
+			continue
+		}
+		pending.result <- callResult{result: results[i]}
+	}
+}
+
+// ProcessBatch 批量添加标点并切分句子，结果与texts一一对应
+func (c *Client) ProcessBatch(ctx context.Context, texts []string) ([]Result, error) {
+	body, err := json.Marshal(request{Texts: texts})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求文本后处理服务失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("请求文本后处理服务失败: HTTP %d - %s", resp.StatusCode, string(data))
+	}
+
+	var result response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析文本后处理结果失败: %v", err)
+	}
+	if len(result.Results) != len(texts) {
+		return nil, fmt.Errorf("文本后处理结果数量不符: 请求%d条，返回%d条", len(texts), len(result.Results))
+	}
+	for i := range result.Results {
+		if result.Results[i].Text == "" {
+			result.Results[i].Text = texts[i]
+		}
+	}
+	return result.Results, nil
+}
@@ -0,0 +1,35 @@
+// Package redis 提供Redis连接封装
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Config Redis连接配置
+type Config struct {
+	Addr     string // 服务器地址，host:port
+	Password string // 密码
+	DB       int    // 数据库编号
+}
+
+// NewClient 创建Redis客户端并检查连通性
+func NewClient(config Config) (*goredis.Client, error) {
+	client := goredis.NewClient(&goredis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接Redis失败: %v", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,46 @@
+// Package sqlite 提供SQLite数据库连接封装，用于无需外部数据库的单机部署
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	// 注册纯Go实现的SQLite驱动，不需要cgo
+	_ "modernc.org/sqlite"
+)
+
+// options 连接参数：WAL日志允许读写并发；写事务以immediate方式开启，代替MySQL的行锁保证领取线索和发件箱消息互斥；
+// 锁等待最多5秒；时间按SQLite标准格式保存，可用julianday等日期函数计算
+const options = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate&_time_format=sqlite"
+
+// Open 打开SQLite数据库文件并检查可用性，文件所在目录不存在时自动创建
+func Open(path string) (*sql.DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建SQLite数据目录失败: %v", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+options)
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite数据库失败: %v", err)
+	}
+
+	// 单文件数据库同一时间只有一个写入者，连接数不宜过多
+	db.SetMaxOpenConns(4)
+	db.SetMaxIdleConns(4)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接SQLite数据库失败: %v", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,52 @@
+// Package storage 提供对象存储访问，支持本地目录和S3兼容存储（AWS S3、MinIO、阿里云OSS等）
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 存储驱动
+const (
+	DriverLocal = "local" // 本地目录
+	DriverS3    = "s3"    // S3兼容对象存储
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Config 对象存储配置
+type Config struct {
+	Driver    string `yaml:"driver"`     // 存储驱动，local或s3
+	Dir       string `yaml:"dir"`        // 本地存储目录
+	Endpoint  string `yaml:"endpoint"`   // S3服务地址，如 https://s3.amazonaws.com
+	Region    string `yaml:"region"`     // S3区域
+	Bucket    string `yaml:"bucket"`     // 存储桶
+	AccessKey string `yaml:"access_key"` // 访问密钥ID
+	SecretKey string `yaml:"secret_key"` // 访问密钥
+}
+
+// Store 对象存储接口
+type Store interface {
+	// Put 写入对象，已存在时覆盖
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+
+	// Get 读取对象，不存在时返回ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// New 根据配置创建对象存储
+func New(config Config) (Store, error) {
+	switch config.Driver {
+	case DriverLocal, "":
+		return NewLocalStore(config.Dir)
+	case DriverS3:
+		return NewS3Store(config)
+	default:
+		return nil, fmt.Errorf("不支持的存储驱动: %s", config.Driver)
+	}
+}
@@ -0,0 +1,78 @@
+// Package tts 定义语音合成服务接口及音频格式
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"regexp"
+	"time"
+)
+
+// Options 合成参数
+type Options struct {
+	Voice      string  `yaml:"voice"`       // 发音人
+	SampleRate int     `yaml:"sample_rate"` // 采样率，为0时使用服务默认值
+	Speed      float64 `yaml:"speed"`       // 语速倍率，为0时使用服务默认值
+	Pitch      float64 `yaml:"pitch"`       // 音调倍率，为0时使用服务默认值
+}
+
+// styleTagPattern 回复开头的语气标签，如“[apologetic]非常抱歉给您带来不便”
+var styleTagPattern = regexp.MustCompile(`^\s*\[([a-z_]+)\]\s*`)
+
+// SplitStyle 拆分回复开头的语气标签，没有标签时style为空
+// 话术脚本可要求大模型在回复前输出语气标签，由语音合成映射为对应的发音人和语速
+func SplitStyle(text string) (style, rest string) {
+	matches := styleTagPattern.FindStringSubmatch(text)
+	if matches == nil {
+		return "", text
+	}
+	return matches[1], text[len(matches[0]):]
+}
+
+// Provider 语音合成服务接口
+type Provider interface {
+	// Synthesize 将文本合成为音频
+	Synthesize(ctx context.Context, text string, options Options) (*Audio, error)
+}
+
+// Audio 合成结果，16位小端PCM
+type Audio struct {
+	PCM        []byte // PCM数据
+	SampleRate int    // 采样率
+	Channels   int    // 声道数
+}
+
+// Duration 获取音频时长
+func (a *Audio) Duration() time.Duration {
+	bytesPerSecond := a.SampleRate * a.Channels * 2
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(len(a.PCM)) * time.Second / time.Duration(bytesPerSecond)
+}
+
+// WAV 封装为WAV格式
+func (a *Audio) WAV() []byte {
+	var buf bytes.Buffer
+	dataSize := uint32(len(a.PCM))
+	byteRate := uint32(a.SampleRate * a.Channels * 2)
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))           // fmt块大小
+	binary.Write(&buf, binary.LittleEndian, uint16(1))            // PCM格式
+	binary.Write(&buf, binary.LittleEndian, uint16(a.Channels))   // 声道数
+	binary.Write(&buf, binary.LittleEndian, uint32(a.SampleRate)) // 采样率
+	binary.Write(&buf, binary.LittleEndian, byteRate)             // 字节率
+	binary.Write(&buf, binary.LittleEndian, uint16(a.Channels*2)) // 块对齐
+	binary.Write(&buf, binary.LittleEndian, uint16(16))           // 位深
+
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(a.PCM)
+	return buf.Bytes()
+}
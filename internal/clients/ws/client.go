@@ -1,4 +1,4 @@
-// Package ws 提供通用的WebSocket客户端实现
+// Package ws 提供通用的WebSocket客户端实现，讯飞、Whisper和FreeSWITCH等WebSocket客户端均基于此实现
 package ws
 
 import (
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -13,109 +14,174 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-// Client WebSocket客户端基类
+// Client WebSocket客户端基类：按消息类型分发收到的消息，连接异常断开后自动重连，
+// Close后可再次Connect
 type Client struct {
-	// WebSocket连接配置
-	url      string
-	headers  map[string]string
-	conn     *websocket.Conn
-	connLock sync.Mutex
+	config Config
 
-	// 重连控制
-	reconnectInterval time.Duration
-	maxRetries       int
-	currentRetries   int
-
-	// 心跳控制
-	heartbeatInterval time.Duration
-	heartbeatMessage []byte
-	lastPong        time.Time
-	heartbeatTimer  *time.Timer
+	// 连接状态
+	connLock sync.Mutex
+	writeMu  sync.Mutex // 串行化写操作，gorilla/websocket不支持并发写
+	conn     *websocket.Conn
+	ctx      context.Context // 本次Connect到Close之间有效，Close后接收循环、心跳和重连都停止
+	cancel   context.CancelFunc
+	lastPong time.Time
 
 	// 消息处理
-	handlers map[string]MessageHandler
-	ctx      context.Context
-	cancel   context.CancelFunc
+	handlersMu  sync.RWMutex
+	handlers    map[string]MessageHandler
+	fallback    MessageHandler
+	binary      MessageHandler
+	onReconnect []func()
 }
 
-// MessageHandler 消息处理函数类型
+// MessageHandler 消息处理函数类型，返回错误时断开连接并按重连策略重连
 type MessageHandler func(message []byte) error
 
 // Config WebSocket客户端配置
 type Config struct {
-	URL               string            // WebSocket服务器地址
-	Headers           map[string]string // 自定义请求头
-	ReconnectInterval time.Duration     // 重连间隔
-	MaxRetries        int              // 最大重试次数
-	HeartbeatInterval time.Duration    // 心跳间隔
-	HeartbeatMessage  []byte           // 心跳消息内容
+	URL               string                 // WebSocket服务器地址
+	URLFunc           func() (string, error) // 每次连接时生成服务器地址，用于每次握手都需重新签名的服务，设置后忽略URL
+	Headers           map[string]string      // 自定义请求头
+	ReconnectInterval time.Duration          // 重连间隔
+	MaxRetries        int                    // 最大重试次数
+	HeartbeatInterval time.Duration          // 心跳间隔
+	HeartbeatMessage  []byte                 // 心跳消息内容
+	Cookies           []*http.Cookie         // 握手时携带的Cookie
+	HandshakeTimeout  time.Duration          // 握手超时时间，默认10秒
+	TypeField         string                 // 消息类型字段名，默认"type"
 }
 
 // NewClient 创建新的WebSocket客户端
 func NewClient(config Config) *Client {
-	ctx, cancel := context.WithCancel(context.Background())
+	if config.TypeField == "" {
+		config.TypeField = "type"
+	}
+	if config.HandshakeTimeout <= 0 {
+		config.HandshakeTimeout = 10 * time.Second
+	}
 	return &Client{
-		url:               config.URL,
-		headers:           config.Headers,
-		reconnectInterval: config.ReconnectInterval,
-		maxRetries:        config.MaxRetries,
-		heartbeatInterval: config.HeartbeatInterval,
-		heartbeatMessage:  config.HeartbeatMessage,
-		handlers:          make(map[string]MessageHandler),
-		ctx:              ctx,
-		cancel:           cancel,
+		config:   config,
+		handlers: make(map[string]MessageHandler),
 	}
 }
 
 // Connect 连接到WebSocket服务器
 func (c *Client) Connect() error {
+	return c.ConnectContext(context.Background())
+}
+
+// ConnectContext 在给定上下文内连接到WebSocket服务器，ctx取消或超时会中止本次拨号；已连接时直接返回
+func (c *Client) ConnectContext(ctx context.Context) error {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	c.start(conn)
+	return nil
+}
+
+// Attach 使用调用方已建立的连接（如预先握手的连接），替换当前连接
+func (c *Client) Attach(conn *websocket.Conn) {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
 
-	log.Printf("正在连接WebSocket服务器: %s\n", c.url)
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.start(conn)
+}
+
+// Connected 当前是否已连接
+func (c *Client) Connected() bool {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+	return c.conn != nil
+}
 
-	// 解析URL
-	u, err := url.Parse(c.url)
+// dial 建立连接
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	address := c.config.URL
+	if c.config.URLFunc != nil {
+		var err error
+		if address, err = c.config.URLFunc(); err != nil {
+			return nil, fmt.Errorf("生成连接地址失败: %v", err)
+		}
+	}
+	u, err := url.Parse(address)
 	if err != nil {
-		return fmt.Errorf("解析URL失败: %v", err)
+		return nil, fmt.Errorf("解析URL失败: %v", err)
 	}
+	log.Printf("正在连接WebSocket服务器: %s://%s%s\n", u.Scheme, u.Host, u.Path)
 
-	// 建立连接
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+		HandshakeTimeout: c.config.HandshakeTimeout,
 	}
-	conn, _, err := dialer.Dial(u.String(), nil)
+	conn, resp, err := dialer.DialContext(ctx, u.String(), c.requestHeader())
 	if err != nil {
-		return fmt.Errorf("连接WebSocket失败: %v", err)
+		if resp != nil {
+			return nil, fmt.Errorf("连接WebSocket失败: HTTP %d - %v", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("连接WebSocket失败: %v", err)
 	}
+	log.Printf("已成功连接到WebSocket服务器: %s://%s%s\n", u.Scheme, u.Host, u.Path)
+	return conn, nil
+}
 
+// start 启用连接，启动心跳和消息接收循环，需持有connLock
+// 客户端已关闭或首次连接时开始新的生命周期
+func (c *Client) start(conn *websocket.Conn) {
+	if c.ctx == nil || c.ctx.Err() != nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+	}
 	c.conn = conn
-	c.currentRetries = 0
 	c.lastPong = time.Now()
 
-	// 设置Pong处理函数
-	c.conn.SetPongHandler(func(string) error {
+	conn.SetPongHandler(func(string) error {
+		c.connLock.Lock()
 		c.lastPong = time.Now()
+		c.connLock.Unlock()
 		return nil
 	})
 
-	// 启动心跳
-	c.startHeartbeat()
+	c.startHeartbeat(c.ctx, conn)
+	go c.receiveLoop(c.ctx, conn)
+}
 
-	// 启动消息接收循环
-	go c.receiveLoop()
+// requestHeader 构建握手请求头，包含自定义请求头和Cookie
+func (c *Client) requestHeader() http.Header {
+	header := http.Header{}
+	for key, value := range c.config.Headers {
+		header.Set(key, value)
+	}
 
-	log.Printf("已成功连接到WebSocket服务器: %s\n", c.url)
-	return nil
+	// 借助http.Request统一处理Cookie的格式化与合并
+	if len(c.config.Cookies) > 0 {
+		req := &http.Request{Header: header}
+		for _, cookie := range c.config.Cookies {
+			req.AddCookie(cookie)
+		}
+	}
+
+	return header
 }
 
-// Close 关闭WebSocket连接
+// Close 关闭WebSocket连接，停止接收循环、心跳和重连
 func (c *Client) Close() error {
 	c.connLock.Lock()
 	defer c.connLock.Unlock()
 
+	if c.cancel != nil {
+		c.cancel()
+	}
 	if c.conn != nil {
-		c.stopHeartbeat()
 		err := c.conn.Close()
 		c.conn = nil
 		return err
@@ -123,148 +189,218 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// RegisterHandler 注册消息处理器
+// RegisterHandler 注册消息处理器，按消息中TypeField字段的值分发
 func (c *Client) RegisterHandler(messageType string, handler MessageHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
 	c.handlers[messageType] = handler
 }
 
-// SendMessage 发送消息到服务器
-func (c *Client) SendMessage(message interface{}) error {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
+// SetDefaultHandler 设置默认消息处理器，处理不是JSON对象、没有类型字段或类型未注册的文本消息
+func (c *Client) SetDefaultHandler(handler MessageHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.fallback = handler
+}
 
-	if c.conn == nil {
-		return fmt.Errorf("WebSocket未连接")
-	}
+// SetBinaryHandler 设置二进制消息处理器，未设置时忽略二进制消息
+func (c *Client) SetBinaryHandler(handler MessageHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.binary = handler
+}
+
+// OnReconnect 注册重连成功后调用的函数，可用于恢复会话状态（如重新发送语法设置）
+func (c *Client) OnReconnect(fn func()) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.onReconnect = append(c.onReconnect, fn)
+}
 
+// SendMessage 将消息序列化为JSON后以文本消息发送
+func (c *Client) SendMessage(message interface{}) error {
 	data, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("消息序列化失败: %v", err)
 	}
+	return c.SendText(data)
+}
 
-	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
-		go c.handleConnectionError()
-		return fmt.Errorf("消息发送失败: %v", err)
+// SendText 发送文本消息
+func (c *Client) SendText(data []byte) error {
+	return c.write(websocket.TextMessage, data)
+}
+
+// SendBinary 发送二进制消息
+func (c *Client) SendBinary(data []byte) error {
+	return c.write(websocket.BinaryMessage, data)
+}
+
+// write 发送消息，发送失败时断开连接并按重连策略重连
+func (c *Client) write(messageType int, data []byte) error {
+	c.connLock.Lock()
+	conn := c.conn
+	c.connLock.Unlock()
+	if conn == nil {
+		return fmt.Errorf("WebSocket未连接")
 	}
 
+	c.writeMu.Lock()
+	err := conn.WriteMessage(messageType, data)
+	c.writeMu.Unlock()
+	if err != nil {
+		go c.handleConnectionError(conn)
+		return fmt.Errorf("消息发送失败: %v", err)
+	}
 	return nil
 }
 
 // startHeartbeat 启动心跳
-func (c *Client) startHeartbeat() {
-	if c.heartbeatInterval <= 0 || len(c.heartbeatMessage) == 0 {
+func (c *Client) startHeartbeat(ctx context.Context, conn *websocket.Conn) {
+	if c.config.HeartbeatInterval <= 0 || len(c.config.HeartbeatMessage) == 0 {
 		return
 	}
 
-	c.heartbeatTimer = time.NewTimer(c.heartbeatInterval)
 	go func() {
+		ticker := time.NewTicker(c.config.HeartbeatInterval)
+		defer ticker.Stop()
 		for {
 			select {
-			case <-c.ctx.Done():
+			case <-ctx.Done():
 				return
-			case <-c.heartbeatTimer.C:
-				if err := c.sendHeartbeat(); err != nil {
+			case <-ticker.C:
+				c.connLock.Lock()
+				current, lastPong := c.conn, c.lastPong
+				c.connLock.Unlock()
+				if current != conn {
+					return
+				}
+				c.writeMu.Lock()
+				err := conn.WriteMessage(websocket.PingMessage, c.config.HeartbeatMessage)
+				c.writeMu.Unlock()
+				if err != nil {
 					log.Printf("发送心跳失败: %v\n", err)
-					go c.handleConnectionError()
+					go c.handleConnectionError(conn)
 					return
 				}
 				// 检查上次收到Pong的时间
-				if time.Since(c.lastPong) > c.heartbeatInterval*2 {
+				if time.Since(lastPong) > c.config.HeartbeatInterval*2 {
 					log.Printf("心跳超时，准备重连\n")
-					go c.handleConnectionError()
+					go c.handleConnectionError(conn)
 					return
 				}
-				c.heartbeatTimer.Reset(c.heartbeatInterval)
 			}
 		}
 	}()
 }
 
-// stopHeartbeat 停止心跳
-func (c *Client) stopHeartbeat() {
-	if c.heartbeatTimer != nil {
-		c.heartbeatTimer.Stop()
-		c.heartbeatTimer = nil
-	}
-}
-
-// sendHeartbeat 发送心跳消息
-func (c *Client) sendHeartbeat() error {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
-
-	if c.conn == nil {
-		return fmt.Errorf("WebSocket未连接")
-	}
-
-	return c.conn.WriteMessage(websocket.PingMessage, c.heartbeatMessage)
-}
-
 // receiveLoop 接收消息循环
-func (c *Client) receiveLoop() {
+func (c *Client) receiveLoop(ctx context.Context, conn *websocket.Conn) {
 	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		default:
-			if err := c.receiveMessage(); err != nil {
-				log.Printf("接收消息失败: %v\n", err)
-				go c.handleConnectionError()
+		if err := c.receiveMessage(conn); err != nil {
+			if ctx.Err() != nil {
 				return
 			}
+			log.Printf("接收消息失败: %v\n", err)
+			go c.handleConnectionError(conn)
+			return
 		}
 	}
 }
 
-// receiveMessage 接收单条消息
-func (c *Client) receiveMessage() error {
-	_, message, err := c.conn.ReadMessage()
+// receiveMessage 接收单条消息并交给对应的处理器
+func (c *Client) receiveMessage(conn *websocket.Conn) error {
+	kind, message, err := conn.ReadMessage()
 	if err != nil {
 		return fmt.Errorf("读取消息失败: %v", err)
 	}
 
-	// 解析消息类型
-	var msg map[string]interface{}
-	if err := json.Unmarshal(message, &msg); err != nil {
-		return fmt.Errorf("解析消息失败: %v", err)
+	handler, err := c.route(kind, message)
+	if err != nil || handler == nil {
+		return err
+	}
+	if err := handler(message); err != nil {
+		return fmt.Errorf("处理消息失败: %v", err)
 	}
+	return nil
+}
 
-	// 根据消息类型调用对应的处理器
-	messageType, ok := msg["type"].(string)
-	if !ok {
-		return fmt.Errorf("消息类型无效")
+// route 按消息类型选择处理器，没有对应处理器时返回nil
+func (c *Client) route(kind int, message []byte) (MessageHandler, error) {
+	c.handlersMu.RLock()
+	defer c.handlersMu.RUnlock()
+
+	if kind == websocket.BinaryMessage {
+		return c.binary, nil
 	}
 
-	if handler, ok := c.handlers[messageType]; ok {
-		if err := handler(message); err != nil {
-			return fmt.Errorf("处理消息失败: %v", err)
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		if c.fallback != nil {
+			return c.fallback, nil
 		}
+		return nil, fmt.Errorf("解析消息失败: %v", err)
 	}
 
-	return nil
-}
-
-// handleConnectionError 处理连接错误
-func (c *Client) handleConnectionError() {
-	c.connLock.Lock()
-	defer c.connLock.Unlock()
+	var messageType string
+	if raw, ok := msg[c.config.TypeField]; ok {
+		if err := json.Unmarshal(raw, &messageType); err != nil && c.fallback == nil {
+			return nil, fmt.Errorf("消息类型无效")
+		}
+	} else if c.fallback == nil {
+		return nil, fmt.Errorf("消息类型无效")
+	}
 
-	if c.conn != nil {
-		c.stopHeartbeat()
-		c.conn.Close()
-		c.conn = nil
+	if handler, ok := c.handlers[messageType]; ok {
+		return handler, nil
 	}
+	return c.fallback, nil
+}
 
-	if c.currentRetries >= c.maxRetries {
-		log.Printf("重试次数超过最大限制，停止重连\n")
+// handleConnectionError 处理连接错误：关闭出错的连接，按重连间隔重试直到成功或达到最大重试次数
+// conn已不是当前连接（已被关闭或替换）时不处理
+func (c *Client) handleConnectionError(conn *websocket.Conn) {
+	c.connLock.Lock()
+	if c.conn != conn || c.ctx.Err() != nil {
+		c.connLock.Unlock()
 		return
 	}
+	conn.Close()
+	c.conn = nil
+	ctx := c.ctx
+	c.connLock.Unlock()
 
-	c.currentRetries++
-	time.Sleep(c.reconnectInterval)
+	for attempt := 1; attempt <= c.config.MaxRetries; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.config.ReconnectInterval):
+		}
 
-	log.Printf("正在尝试重新连接 (第 %d 次)\n", c.currentRetries)
-	if err := c.Connect(); err != nil {
-		log.Printf("重新连接失败: %v\n", err)
+		log.Printf("正在尝试重新连接 (第 %d 次)\n", attempt)
+		next, err := c.dial(ctx)
+		if err != nil {
+			log.Printf("重新连接失败: %v\n", err)
+			continue
+		}
+
+		c.connLock.Lock()
+		// 重连期间客户端已关闭或已建立其他连接
+		if ctx.Err() != nil || c.conn != nil {
+			c.connLock.Unlock()
+			next.Close()
+			return
+		}
+		c.start(next)
+		c.connLock.Unlock()
+
+		c.handlersMu.RLock()
+		hooks := c.onReconnect
+		c.handlersMu.RUnlock()
+		for _, fn := range hooks {
+			fn()
+		}
+		return
 	}
+	log.Printf("重试次数超过最大限制，停止重连\n")
 }
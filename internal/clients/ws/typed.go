@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypeTag 声明消息类型的结构体标签名
+//
+// 消息结构体中与客户端 TypeField 对应的字段通过该标签声明其路由的消息类型，例如：
+//
+//	type ResultMessage struct {
+//		Type string `json:"type" ws:"result"`
+//		Text string `json:"text"`
+//	}
+const TypeTag = "ws"
+
+// RegisterTypedHandler 注册类型化消息处理器
+// 消息类型从 T 的 ws 标签中读取，收到该类型的消息后自动反序列化为 T 再交给处理器，
+// 处理器内无需再做 map 取值和类型断言
+func RegisterTypedHandler[T any](c *Client, handler func(msg *T) error) error {
+	messageType, err := messageTypeOf[T](c.config.TypeField)
+	if err != nil {
+		return err
+	}
+
+	c.RegisterHandler(messageType, func(message []byte) error {
+		var msg T
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return fmt.Errorf("解析%s消息失败: %v", messageType, err)
+		}
+		return handler(&msg)
+	})
+	return nil
+}
+
+// messageTypeOf 从结构体标签中获取消息类型
+func messageTypeOf[T any](typeField string) (string, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("消息类型 %s 必须是结构体", t)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		messageType, ok := field.Tag.Lookup(TypeTag)
+		if !ok {
+			continue
+		}
+		if messageType == "" {
+			return "", fmt.Errorf("%s.%s 的 ws 标签为空", t, field.Name)
+		}
+
+		// 标签所在字段必须就是客户端用于分发的类型字段
+		if name := jsonFieldName(field); name != typeField {
+			return "", fmt.Errorf("%s.%s 的JSON字段名为 %q，与类型字段 %q 不一致", t, field.Name, name, typeField)
+		}
+		return messageType, nil
+	}
+
+	return "", fmt.Errorf("消息类型 %s 缺少 ws 标签", t)
+}
+
+// jsonFieldName 获取结构体字段序列化后的JSON字段名
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
@@ -1,21 +1,26 @@
 package xfyun
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/url"
-	"strings"
 	"sync"
 	"time"
 
+	"ai_dialer_mini/internal/clients/ws"
+	"ai_dialer_mini/internal/logger"
+	"ai_dialer_mini/internal/recorder"
 	"ai_dialer_mini/internal/models"
 	"github.com/gorilla/websocket"
 )
 
+// 高频日志采样器，每帧音频和每条中间结果都会触发
+var (
+	audioLog  = logger.Sampled("xfyun.audio")
+	resultLog = logger.Sampled("xfyun.result")
+)
+
 const (
 	STATUS_FIRST_FRAME    = 0
 	STATUS_CONTINUE_FRAME = 1
@@ -24,87 +29,89 @@ const (
 
 // Config 科大讯飞ASR配置
 type Config struct {
-	AppID             string
-	APIKey            string
-	APISecret         string
-	ServerURL         string
-	ReconnectInterval time.Duration
-	MaxRetries        int
-	SampleRate        int
+	AppID             string        `yaml:"app_id"`
+	APIKey            string        `yaml:"api_key"`
+	APISecret         string        `yaml:"api_secret"`
+	ServerURL         string        `yaml:"server_url"`
+	ReconnectInterval time.Duration `yaml:"reconnect_interval"`
+	MaxRetries        int           `yaml:"max_retries"`
+	SampleRate        int           `yaml:"sample_rate"`
+	NoPunctuation     bool          `yaml:"no_punctuation"` // 不返回标点（ptt=0），可配合本地文本后处理服务使用
+	Pool              PoolConfig    `yaml:"pool"`           // 连接预热池
+	Session           SessionConfig `yaml:"session"`        // 会话级连接保持
+	Resume            ResumeConfig  `yaml:"resume"`         // 识别中途断线续传
 }
 
-// WSClient WebSocket客户端
+// WSClient 讯飞听写WebSocket客户端，基于通用WebSocket客户端，每次连接（包括重连）都重新生成鉴权参数
 type WSClient struct {
-	config      Config
-	conn        *websocket.Conn
-	callback    func(string, bool) error
-	mu          sync.Mutex
-	retryCount  int
-	decoder     *Decoder
+	config    Config
+	client    *ws.Client
+	callback  func(string, bool) error
+	mu        sync.Mutex
+	decoder   *Decoder
+	recorder  recorder.Hook
+	recordCtx context.Context
 }
 
 // NewWSClient 创建新的WebSocket客户端
 func NewWSClient(config Config) *WSClient {
-	return &WSClient{
+	c := &WSClient{
 		config:  config,
 		decoder: &Decoder{},
 	}
+	c.client = ws.NewClient(ws.Config{
+		URLFunc: func() (string, error) {
+			params, err := authQuery(config.ServerURL, config.APIKey, config.APISecret)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s?%s", config.ServerURL, params), nil
+		},
+		ReconnectInterval: config.ReconnectInterval,
+		MaxRetries:        config.MaxRetries,
+		HandshakeTimeout:  5 * time.Second,
+	})
+	// 讯飞的响应没有消息类型字段，全部交给默认处理器
+	c.client.SetDefaultHandler(c.handleMessage)
+	c.client.OnReconnect(c.reset)
+	return c
 }
 
-// Connect 连接WebSocket服务器
+// Connect 连接WebSocket服务器，失败时按重连间隔重试，已连接时直接返回
 func (c *WSClient) Connect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.conn != nil {
+	if c.client.Connected() {
 		return nil
 	}
-
-	// 生成握手参数
-	handshakeParams := c.generateHandshakeParams()
-	if handshakeParams == "" {
-		return fmt.Errorf("生成握手参数失败")
-	}
-	
-	url := fmt.Sprintf("%s?%s", c.config.ServerURL, handshakeParams)
-	log.Printf("正在连接WebSocket服务器: %s", url)
-
-	// 建立连接
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 5 * time.Second,
-	}
-	conn, _, err := dialer.Dial(url, nil)
-	if err != nil {
-		c.retryCount++
-		if c.retryCount > c.config.MaxRetries {
-			return fmt.Errorf("连接失败，已达到最大重试次数: %v", err)
+	c.reset()
+	var err error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			log.Printf("连接失败，将在 %v 后重试: %v", c.config.ReconnectInterval, err)
+			time.Sleep(c.config.ReconnectInterval)
+		}
+		if err = c.client.Connect(); err == nil {
+			return nil
 		}
-		log.Printf("连接失败，将在 %v 后重试: %v", c.config.ReconnectInterval, err)
-		time.Sleep(c.config.ReconnectInterval)
-		return c.Connect()
 	}
+	return fmt.Errorf("连接失败，已达到最大重试次数: %v", err)
+}
 
-	log.Printf("WebSocket连接成功")
-	c.retryCount = 0
-	c.conn = conn
-
-	// 启动消息接收协程
-	go c.receiveMessages()
+// attach 使用已建立的连接，替换当前连接
+func (c *WSClient) attach(conn *websocket.Conn) {
+	c.reset()
+	c.client.Attach(conn)
+}
 
-	return nil
+// reset 新连接开始新的识别会话，清空之前的识别结果
+func (c *WSClient) reset() {
+	c.mu.Lock()
+	c.decoder = &Decoder{}
+	c.mu.Unlock()
 }
 
 // Close 关闭连接
 func (c *WSClient) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.conn != nil {
-		err := c.conn.Close()
-		c.conn = nil
-		return err
-	}
-	return nil
+	return c.client.Close()
 }
 
 // SetCallback 设置回调函数
@@ -114,188 +121,75 @@ func (c *WSClient) SetCallback(callback func(string, bool) error) {
 	c.mu.Unlock()
 }
 
-// SendAudio 发送音频数据
-func (c *WSClient) SendAudio(data []byte, status int) error {
+// SetRecorder 设置飞行记录仪，ctx标记报文所属的通话
+func (c *WSClient) SetRecorder(hook recorder.Hook, ctx context.Context) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.recorder = hook
+	c.recordCtx = ctx
+	c.mu.Unlock()
+}
 
-	if c.conn == nil {
-		if err := c.Connect(); err != nil {
-			return fmt.Errorf("重新连接失败: %v", err)
-		}
+// record 记录原始报文，需持有锁
+func (c *WSClient) record(kind string, payload []byte) {
+	if c.recorder != nil {
+		c.recorder.Record(c.recordCtx, "xfyun", kind, payload)
 	}
+}
 
-	// 将音频数据转换为Base64
-	base64Data := base64.StdEncoding.EncodeToString(data)
-
-	// 构建消息
-	frame := Frame{}
-	
-	// 只在第一帧时发送common和business信息
-	if status == STATUS_FIRST_FRAME {
-		frame.Common.AppID = c.config.AppID
-		frame.Business.Language = "zh_cn"
-		frame.Business.Domain = "iat"
-		frame.Business.Accent = "mandarin"
+// SendAudio 发送音频数据，未连接时先建立连接
+func (c *WSClient) SendAudio(data []byte, status int) error {
+	if err := c.Connect(); err != nil {
+		return fmt.Errorf("重新连接失败: %v", err)
 	}
 
-	frame.Data.Status = status
-	frame.Data.Format = "audio/L16;rate=16000"
-	frame.Data.Audio = base64Data
-
 	// 序列化消息
-	message, err := json.Marshal(frame)
+	message, err := json.Marshal(buildFrame(c.config, data, status))
 	if err != nil {
 		return fmt.Errorf("序列化消息失败: %v", err)
 	}
 
-	log.Printf("发送音频帧，状态: %d, 大小: %d 字节", status, len(data))
+	audioLog.Printf("发送音频帧，状态: %d, 大小: %d 字节", status, len(data))
+	c.mu.Lock()
+	c.record(recorder.KindRequest, message)
+	c.mu.Unlock()
 
-	// 发送消息
-	if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-		c.conn = nil // 连接可能已断开，标记为nil以便下次重连
+	if err := c.client.SendText(message); err != nil {
 		return fmt.Errorf("发送消息失败: %v", err)
 	}
-
 	return nil
 }
 
-// receiveMessages 接收消息
-func (c *WSClient) receiveMessages() {
-	for {
-		_, message, err := c.conn.ReadMessage()
-		if err != nil {
-			log.Printf("读取消息失败: %v", err)
-			c.handleError(err)
-			return
-		}
-
-		log.Printf("收到原始消息: %s", string(message))
-
-		var resp Response
-		if err := json.Unmarshal(message, &resp); err != nil {
-			log.Printf("解析消息失败: %v", err)
-			c.handleError(err)
-			continue
-		}
-
-		// 检查响应状态
-		if resp.Code != 0 {
-			log.Printf("服务器错误: %s", resp.Message)
-			c.handleError(fmt.Errorf("服务器错误: %s", resp.Message))
-			continue
-		}
-
-		// 解码结果
-		c.decoder.Decode(&resp.Data.Result)
-		text := c.decoder.String()
-		log.Printf("解析识别结果: %s, 状态: %d, pgs: %s", text, resp.Data.Status, resp.Data.Result.Pgs)
-
-		// 只有在pgs为"rpl"时才更新最终结果
-		if resp.Data.Result.Pgs == "rpl" {
-			if c.callback != nil {
-				isEnd := resp.Data.Status == STATUS_LAST_FRAME
-				if err := c.callback(text, isEnd); err != nil {
-					log.Printf("回调函数执行失败: %v", err)
-					c.handleError(err)
-				}
-			}
-		}
-	}
-}
-
-// handleError 处理错误
-func (c *WSClient) handleError(err error) {
+// handleMessage 处理讯飞返回的识别结果，返回错误时断开连接并重连
+func (c *WSClient) handleMessage(message []byte) error {
+	resultLog.Printf("收到原始消息: %s", string(message))
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-		log.Printf("WebSocket连接异常关闭: %v", err)
-	}
+	c.record(recorder.KindResponse, message)
+	decoder, callback := c.decoder, c.callback
+	c.mu.Unlock()
 
-	// 关闭连接
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
+	var resp Response
+	if err := json.Unmarshal(message, &resp); err != nil {
+		return fmt.Errorf("解析消息失败: %v", err)
 	}
 
-	// 尝试重连
-	if c.retryCount < c.config.MaxRetries {
-		c.retryCount++
-		go func() {
-			time.Sleep(c.config.ReconnectInterval)
-			if err := c.Connect(); err != nil {
-				log.Printf("重连失败: %v", err)
-			}
-		}()
+	// 检查响应状态
+	if resp.Code != 0 {
+		return newServerError(resp.Code, resp.Message, resp.Sid)
 	}
-}
-
-// buildAuthURL 构建鉴权URL
-func (c *WSClient) buildAuthURL() (string, error) {
-	baseURL := c.config.ServerURL
-	now := time.Now().UTC().Format(time.RFC1123)
-	now = strings.Replace(now, "UTC", "GMT", -1)
-
-	signString := fmt.Sprintf("host: %s\ndate: %s\nGET /v2/iat HTTP/1.1", strings.Split(baseURL, "://")[1], now)
-	signature := c.buildAuthorization(signString)
-
-	authorization := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("api_key=\"%s\", algorithm=\"hmac-sha256\", headers=\"host date request-line\", signature=\"%s\"",
-		c.config.APIKey, signature)))
-
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return "", err
-	}
-
-	q := u.Query()
-	q.Set("authorization", authorization)
-	q.Set("date", now)
-	q.Set("host", u.Host)
-	u.RawQuery = q.Encode()
-
-	return u.String(), nil
-}
-
-// buildAuthorization 构建鉴权信息
-func (c *WSClient) buildAuthorization(data string) string {
-	mac := hmac.New(sha256.New, []byte(c.config.APISecret))
-	mac.Write([]byte(data))
-	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
-}
 
-// generateHandshakeParams 生成握手参数
-func (c *WSClient) generateHandshakeParams() string {
-	// 生成RFC1123格式的日期
-	now := time.Now()
-	date := now.UTC().Format(time.RFC1123)
+	// 解码结果
+	decoder.Decode(&resp.Data.Result)
+	text := decoder.String()
+	resultLog.Printf("解析识别结果: %s, 状态: %d, pgs: %s", text, resp.Data.Status, resp.Data.Result.Pgs)
 
-	// 提取host
-	u, err := url.Parse(c.config.ServerURL)
-	if err != nil {
-		log.Printf("解析URL失败: %v", err)
-		return ""
+	// 只有在pgs为"rpl"时才更新最终结果
+	if resp.Data.Result.Pgs == "rpl" && callback != nil {
+		isEnd := resp.Data.Status == STATUS_LAST_FRAME
+		if err := callback(text, isEnd); err != nil {
+			return fmt.Errorf("回调函数执行失败: %v", err)
+		}
 	}
-	host := u.Host
-
-	// 签名原文
-	signString := fmt.Sprintf("host: %s\ndate: %s\nGET /v2/iat HTTP/1.1", host, date)
-
-	// 使用HMAC-SHA256计算签名
-	mac := hmac.New(sha256.New, []byte(c.config.APISecret))
-	mac.Write([]byte(signString))
-	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-
-	// 生成authorization
-	authString := fmt.Sprintf("api_key=\"%s\", algorithm=\"hmac-sha256\", headers=\"host date request-line\", signature=\"%s\"", c.config.APIKey, signature)
-
-	// 构建查询参数
-	params := url.Values{}
-	params.Set("authorization", base64.StdEncoding.EncodeToString([]byte(authString)))
-	params.Set("date", date)
-	params.Set("host", host)
-
-	return params.Encode()
+	return nil
 }
 
 // Frame WebSocket帧
@@ -307,6 +201,7 @@ type Frame struct {
 		Language string `json:"language"`
 		Domain   string `json:"domain"`
 		Accent   string `json:"accent"`
+		Ptt      *int   `json:"ptt,omitempty"`
 	} `json:"business"`
 	Data struct {
 		Status int    `json:"status"`
@@ -400,6 +295,9 @@ type ASRClient struct {
 	config    Config
 	wsClient  *WSClient
 	dialogSvc models.DialogService
+	recorder  recorder.Hook
+	pool      *ConnPool
+	sessions  *sessionConns
 }
 
 // NewASRClient 创建新的ASR客户端
@@ -408,14 +306,48 @@ func NewASRClient(config Config, dialogSvc models.DialogService) *ASRClient {
 		config:    config,
 		wsClient:  NewWSClient(config),
 		dialogSvc: dialogSvc,
+		sessions:  newSessionConns(config),
 	}
 }
 
+// SetRecorder 设置飞行记录仪，记录与讯飞交互的原始报文
+func (c *ASRClient) SetRecorder(hook recorder.Hook) {
+	c.recorder = hook
+}
+
+// SetPool 设置连接预热池，流式识别会话优先使用预热连接
+func (c *ASRClient) SetPool(pool *ConnPool) {
+	c.pool = pool
+}
+
+// Pool 连接预热池，未设置时返回nil
+func (c *ASRClient) Pool() *ConnPool {
+	return c.pool
+}
+
+// ReleaseSession 会话结束（如通话挂断），关闭为该会话保持的备用连接
+func (c *ASRClient) ReleaseSession(sessionID string) {
+	c.sessions.release(sessionID)
+}
+
+// Ping 建立一次独立的WebSocket连接后立即关闭，用于探测讯飞服务是否可用
+func (c *ASRClient) Ping(ctx context.Context) error {
+	probe := NewWSClient(c.config)
+	if err := probe.Connect(); err != nil {
+		return err
+	}
+	return probe.Close()
+}
+
 // ProcessAudio 处理音频数据并返回识别结果
 func (c *ASRClient) ProcessAudio(sessionID string, audioData []byte) (string, error) {
+	if sessionID == "" {
+		return "", models.ErrSessionIDRequired
+	}
 	if len(audioData) == 0 {
 		return "", fmt.Errorf("音频数据为空")
 	}
+	c.wsClient.SetRecorder(c.recorder, recorder.WithCall(context.Background(), sessionID))
 
 	log.Printf("开始处理音频数据，大小: %d 字节", len(audioData))
 
@@ -437,10 +369,15 @@ func (c *ASRClient) ProcessAudio(sessionID string, audioData []byte) (string, er
 		return nil
 	})
 
-	// 连接WebSocket服务器
-	log.Printf("连接WebSocket服务器: %s", c.wsClient.config.ServerURL)
-	if err := c.wsClient.Connect(); err != nil {
-		return "", fmt.Errorf("连接WebSocket服务器失败: %v", err)
+	// 连接WebSocket服务器，会话有备用连接时直接使用，识别结束后为下一段音频预先建立连接
+	defer c.sessions.prepare(sessionID)
+	if conn, ok := c.sessions.take(sessionID); ok {
+		c.wsClient.attach(conn)
+	} else {
+		log.Printf("连接WebSocket服务器: %s", c.config.ServerURL)
+		if err := c.wsClient.Connect(); err != nil {
+			return "", fmt.Errorf("连接WebSocket服务器失败: %v", err)
+		}
 	}
 	defer c.wsClient.Close()
 
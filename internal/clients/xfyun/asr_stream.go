@@ -0,0 +1,371 @@
+package xfyun
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamFrameSize 单帧音频的最大字节数，对应16k采样16位单声道40ms音频
+const streamFrameSize = 1280
+
+// streamFinalTimeout 发送最后一帧后等待最终识别结果的时间
+const streamFinalTimeout = 5 * time.Second
+
+// ErrStreamClosed 识别会话已结束，不能继续发送音频
+var ErrStreamClosed = errors.New("识别会话已结束")
+
+// StreamResult 流式识别结果
+type StreamResult = models.ASRResult
+
+// Stream 单个流式识别会话：Write按到达顺序发送PCM分片，识别结果从Results读取；
+// 讯飞检测到句尾静音或调用Close后返回最终结果，随后Results关闭。
+// 启用断线续传时连接中途断开后自动重连，调用方无需处理
+type Stream struct {
+	conn      *websocket.Conn // 只在receive中替换，替换时持有锁
+	config    Config
+	recorder  recorder.Hook
+	recordCtx context.Context
+	dial      func() (*websocket.Conn, error)
+
+	mu           sync.Mutex // 串行化写操作
+	started      bool       // 已发送第一帧
+	closed       bool       // 已调用Close
+	timedOut     bool       // 等待最终结果超时
+	sentAt       time.Time  // 最近一次发送音频帧的时间
+	latency      time.Duration
+	replay       *replayBuffer // 断线续传缓存的音频，未启用时为nil
+	reconnecting bool          // 正在重连，期间的音频只缓存不发送
+	attempts     int           // 已重连次数
+
+	results chan StreamResult
+	quit    chan struct{} // Close结束等待后关闭，不再投递识别结果
+	quitted sync.Once
+	done    chan struct{}
+	err     error
+}
+
+// OpenStream 为会话建立独立的讯飞连接，开始一次流式识别
+// 依次取用会话的备用连接和连接预热池中的连接，都没有时现场握手；启用会话连接保持时，识别结束后为下一句预先建立连接
+func (c *ASRClient) OpenStream(sessionID string) (*Stream, error) {
+	if sessionID == "" {
+		return nil, models.ErrSessionIDRequired
+	}
+	conn, ok := c.sessions.take(sessionID)
+	if !ok {
+		conn, ok = c.pool.Get()
+	}
+	if !ok {
+		var err error
+		if conn, err = dialASR(c.config); err != nil {
+			return nil, err
+		}
+	}
+
+	stream := &Stream{
+		conn:      conn,
+		config:    c.config,
+		recorder:  c.recorder,
+		recordCtx: recorder.WithCall(context.Background(), sessionID),
+		dial:      func() (*websocket.Conn, error) { return dialASR(c.config) },
+		results:   make(chan StreamResult, 16),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if c.config.Resume.Enabled {
+		stream.replay = newReplayBuffer(c.config.Resume.Buffer)
+	}
+	go stream.receive()
+	if c.sessions != nil {
+		go func() {
+			<-stream.done
+			c.sessions.prepare(sessionID)
+		}()
+	}
+	return stream, nil
+}
+
+// Results 识别结果通道，调用方需持续读取直至通道关闭
+func (s *Stream) Results() <-chan StreamResult {
+	return s.results
+}
+
+// Done 识别会话结束时关闭
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 等待识别会话结束，返回会话异常结束的原因，正常结束时返回nil
+func (s *Stream) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Latency 等待识别会话结束，返回最终结果相对最后一帧音频发送时间的延迟，未收到最终结果时返回0
+func (s *Stream) Latency() time.Duration {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latency
+}
+
+// Write 发送一段PCM音频，超过单帧大小时拆分为多帧连续发送
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	select {
+	case <-s.done:
+		return 0, ErrStreamClosed
+	default:
+	}
+
+	s.replay.write(p)
+	if s.reconnecting {
+		// 重连期间只缓存音频，连接恢复后一并发送
+		return len(p), nil
+	}
+	n, err := s.sendAudio(p)
+	if err != nil && s.resumable() {
+		// 连接已断开，关闭连接使receive开始重连，音频已缓存
+		s.reconnecting = true
+		s.conn.Close()
+		return len(p), nil
+	}
+	return n, err
+}
+
+// sendAudio 按单帧大小拆分发送音频，第一帧携带识别参数，需持有锁
+func (s *Stream) sendAudio(p []byte) (int, error) {
+	for i := 0; i < len(p); i += streamFrameSize {
+		end := i + streamFrameSize
+		if end > len(p) {
+			end = len(p)
+		}
+		status := STATUS_CONTINUE_FRAME
+		if !s.started {
+			status = STATUS_FIRST_FRAME
+		}
+		if err := s.send(p[i:end], status); err != nil {
+			return i, err
+		}
+		s.started = true
+	}
+	return len(p), nil
+}
+
+// Close 发送最后一帧并等待最终识别结果，超时后关闭连接
+func (s *Stream) Close() error {
+	s.mu.Lock()
+	var err error
+	wait := false
+	if !s.closed {
+		s.closed = true
+		select {
+		case <-s.done:
+		default:
+			// 会话未结束时发送最后一帧，等待讯飞返回最终结果；正在重连时由重连后发送
+			if s.reconnecting {
+				wait = true
+			} else if s.started {
+				err = s.send(nil, STATUS_LAST_FRAME)
+				wait = err == nil
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if wait {
+		select {
+		case <-s.done:
+		case <-time.After(streamFinalTimeout):
+			s.mu.Lock()
+			s.timedOut = true
+			s.mu.Unlock()
+		}
+	}
+	s.quitted.Do(func() { close(s.quit) })
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	conn.Close()
+	<-s.done
+	return err
+}
+
+// send 发送一帧音频，需持有锁
+func (s *Stream) send(data []byte, status int) error {
+	message, err := json.Marshal(buildFrame(s.config, data, status))
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %v", err)
+	}
+	audioLog.Printf("发送音频帧，状态: %d, 大小: %d 字节", status, len(data))
+	s.record(recorder.KindRequest, message)
+	if err := s.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		return fmt.Errorf("发送音频数据失败: %v", err)
+	}
+	s.sentAt = time.Now()
+	return nil
+}
+
+// receive 读取识别结果，收到最终结果或连接异常时结束会话
+func (s *Stream) receive() {
+	defer close(s.done)
+	defer close(s.results)
+
+	var decoder Decoder
+	// prefix 重连前已识别的文本，缓存未覆盖全部音频时新会话的结果接在其后
+	var prefix, text string
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if full, ok := s.resume(err); ok {
+				if !full {
+					prefix = text
+				}
+				decoder = Decoder{}
+				continue
+			}
+			s.mu.Lock()
+			closed, timedOut := s.closed, s.timedOut
+			s.mu.Unlock()
+			if timedOut {
+				s.err = fmt.Errorf("等待最终识别结果超时")
+			} else if !closed {
+				s.err = fmt.Errorf("读取识别结果失败: %v", err)
+			}
+			return
+		}
+		resultLog.Printf("收到原始消息: %s", string(message))
+		s.record(recorder.KindResponse, message)
+
+		var resp Response
+		if err := json.Unmarshal(message, &resp); err != nil {
+			s.err = fmt.Errorf("解析识别结果失败: %v", err)
+			return
+		}
+		if resp.Code != 0 {
+			s.err = newServerError(resp.Code, resp.Message, resp.Sid)
+			return
+		}
+
+		decoder.Decode(&resp.Data.Result)
+		text = mergeOverlap(prefix, decoder.String())
+		result := StreamResult{Text: text, IsFinal: resp.Data.Status == STATUS_LAST_FRAME}
+		if result.IsFinal {
+			s.mu.Lock()
+			s.latency = time.Since(s.sentAt)
+			s.mu.Unlock()
+		}
+		select {
+		case s.results <- result:
+		case <-s.quit:
+			return
+		}
+		if result.IsFinal {
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// resumable 是否还可以重连，需持有锁
+func (s *Stream) resumable() bool {
+	return s.replay != nil && s.attempts < s.config.Resume.MaxAttempts && !s.timedOut
+}
+
+// resume 连接中途断开后重新建立识别会话：重新握手，以第一帧参数重新发送缓存的音频，已调用Close时随后发送最后一帧。
+// 返回是否重连成功，以及缓存是否覆盖了本次识别会话的全部音频（覆盖时新会话的结果即完整文本）
+func (s *Stream) resume(cause error) (full, ok bool) {
+	for {
+		s.mu.Lock()
+		select {
+		case <-s.quit:
+			s.mu.Unlock()
+			return false, false
+		default:
+		}
+		if !s.resumable() || (s.closed && !s.started && !s.reconnecting) {
+			s.mu.Unlock()
+			return false, false
+		}
+		s.reconnecting = true
+		s.attempts++
+		attempt := s.attempts
+		s.mu.Unlock()
+
+		log.Printf("讯飞连接中断，重新建立识别会话（第%d次）: %v", attempt, cause)
+		conn, err := s.dial()
+		if err != nil {
+			cause = err
+			select {
+			case <-time.After(s.config.ReconnectInterval):
+				continue
+			case <-s.quit:
+				return false, false
+			}
+		}
+
+		s.mu.Lock()
+		s.conn = conn
+		s.reconnecting = false
+		s.started = false
+		full = !s.replay.trimmed
+		_, err = s.sendAudio(s.replay.data)
+		if err == nil && s.closed && s.started {
+			err = s.send(nil, STATUS_LAST_FRAME)
+		}
+		// 已调用Close且没有缓存的音频时讯飞不会返回结果，直接结束
+		empty := s.closed && !s.started
+		s.mu.Unlock()
+		if err != nil {
+			conn.Close()
+			cause = err
+			continue
+		}
+		if empty {
+			conn.Close()
+			return false, false
+		}
+		return full, true
+	}
+}
+
+// record 记录原始报文
+func (s *Stream) record(kind string, payload []byte) {
+	if s.recorder != nil {
+		s.recorder.Record(s.recordCtx, "xfyun", kind, payload)
+	}
+}
+
+// buildFrame 构建音频帧，只在第一帧携带common和business参数
+func buildFrame(config Config, data []byte, status int) Frame {
+	frame := Frame{}
+	if status == STATUS_FIRST_FRAME {
+		frame.Common.AppID = config.AppID
+		frame.Business.Language = "zh_cn"
+		frame.Business.Domain = "iat"
+		frame.Business.Accent = "mandarin"
+		if config.NoPunctuation {
+			ptt := 0
+			frame.Business.Ptt = &ptt
+		}
+	}
+	frame.Data.Status = status
+	frame.Data.Format = "audio/L16;rate=16000"
+	frame.Data.Audio = base64.StdEncoding.EncodeToString(data)
+	return frame
+}
@@ -0,0 +1,142 @@
+package xfyun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// 讯飞错误分类，用于指标标签和就绪检查
+const (
+	ErrorKindAuth           = "auth"            // 鉴权失败，需检查凭据、时钟或IP白名单
+	ErrorKindQuota          = "quota"           // 额度或并发超限，需扩容或限流
+	ErrorKindTimeout        = "timeout"         // 会话超时，通常是音频发送中断或过慢
+	ErrorKindInvalidRequest = "invalid_request" // 请求参数或音频格式错误，需修改配置或代码
+	ErrorKindEngine         = "engine"          // 讯飞引擎内部错误，通常重试即可
+	ErrorKindUnknown        = "unknown"         // 未收录的错误码
+)
+
+// 各分类的错误，可用errors.Is判断
+var (
+	ErrAuth           = errors.New("讯飞鉴权失败")
+	ErrQuota          = errors.New("讯飞服务额度不足")
+	ErrTimeout        = errors.New("讯飞会话超时")
+	ErrInvalidRequest = errors.New("讯飞请求参数错误")
+	ErrEngine         = errors.New("讯飞引擎错误")
+)
+
+// kindErrors 错误分类对应的错误
+var kindErrors = map[string]error{
+	ErrorKindAuth:           ErrAuth,
+	ErrorKindQuota:          ErrQuota,
+	ErrorKindTimeout:        ErrTimeout,
+	ErrorKindInvalidRequest: ErrInvalidRequest,
+	ErrorKindEngine:         ErrEngine,
+}
+
+// errorInfo 错误码说明和处理建议
+type errorInfo struct {
+	kind        string
+	description string
+	remediation string
+}
+
+// errorCodes 讯飞听写常见错误码，401/403为握手时的HTTP状态码
+var errorCodes = map[int]errorInfo{
+	401:   {ErrorKindAuth, "握手鉴权失败", "检查 asr.xfyun.api_key、api_secret 是否正确，以及是否与 app_id 属于同一应用"},
+	403:   {ErrorKindAuth, "握手被拒绝", "检查服务器时钟（签名时间与讯飞服务器相差不能超过5分钟）和控制台的IP白名单"},
+	10005: {ErrorKindAuth, "应用ID校验失败", "检查 asr.xfyun.app_id 是否正确"},
+	10006: {ErrorKindInvalidRequest, "缺少必要参数", "检查第一帧的common和business参数"},
+	10007: {ErrorKindInvalidRequest, "参数值非法", "检查音频格式参数，应为 audio/L16;rate=16000"},
+	10010: {ErrorKindQuota, "引擎授权不足", "在讯飞控制台检查服务量或并发路数，必要时扩容"},
+	10014: {ErrorKindTimeout, "会话超时", "检查音频是否持续发送，识别会话最长60秒"},
+	10019: {ErrorKindTimeout, "读取音频超时", "检查音频发送间隔，超过10秒未发送音频会被断开"},
+	10043: {ErrorKindInvalidRequest, "音频解码失败", "检查音频编码，应为16kHz 16位单声道PCM"},
+	10101: {ErrorKindEngine, "引擎会话已结束", "会话结束后不能继续发送音频，重新开启识别会话"},
+	10105: {ErrorKindAuth, "没有权限", "检查应用是否开通语音听写服务，以及 app_id 与 api_key 是否匹配"},
+	10106: {ErrorKindInvalidRequest, "无效参数", "检查business参数的取值"},
+	10107: {ErrorKindInvalidRequest, "非法参数值", "检查business参数的取值"},
+	10109: {ErrorKindInvalidRequest, "音频过长", "单次识别会话的音频不能超过60秒"},
+	10110: {ErrorKindQuota, "无授权许可", "在讯飞控制台检查服务是否过期或服务量是否用尽"},
+	10114: {ErrorKindTimeout, "会话超时", "检查音频是否持续发送，识别会话最长60秒"},
+	10139: {ErrorKindInvalidRequest, "参数错误", "检查请求参数"},
+	10160: {ErrorKindInvalidRequest, "请求数据格式非法", "检查请求是否为合法JSON"},
+	10161: {ErrorKindInvalidRequest, "音频base64解码失败", "检查音频是否正确base64编码"},
+	10163: {ErrorKindInvalidRequest, "缺少必传参数或参数不合法", "检查第一帧的common和business参数"},
+	10165: {ErrorKindEngine, "无效的会话句柄", "会话已失效，重新开启识别会话"},
+	10200: {ErrorKindTimeout, "读取数据超时", "检查网络和音频发送是否中断"},
+	10222: {ErrorKindEngine, "讯飞服务网络异常", "稍后重试，持续出现时联系讯飞技术支持"},
+	10313: {ErrorKindAuth, "应用ID为空", "配置 asr.xfyun.app_id"},
+	10317: {ErrorKindInvalidRequest, "版本非法", "检查接口地址 asr.xfyun.server_url"},
+	10700: {ErrorKindEngine, "引擎错误", "稍后重试，持续出现时联系讯飞技术支持"},
+	11200: {ErrorKindQuota, "功能未授权或服务量超限", "在讯飞控制台检查服务量和有效期，必要时购买服务量"},
+	11201: {ErrorKindQuota, "日流控超限", "当日调用次数已达上限，在讯飞控制台提升日流控或降低外呼速率"},
+}
+
+// ServerError 讯飞返回的错误，按错误码给出分类和处理建议
+type ServerError struct {
+	Code    int    // 讯飞错误码，握手失败时为HTTP状态码
+	Message string // 讯飞返回的错误信息
+	SID     string // 会话ID，联系讯飞技术支持时提供
+}
+
+// newServerError 创建讯飞错误
+func newServerError(code int, message, sid string) *ServerError {
+	return &ServerError{Code: code, Message: message, SID: sid}
+}
+
+// info 错误码说明，未收录的错误码返回unknown分类
+func (e *ServerError) info() errorInfo {
+	if info, ok := errorCodes[e.Code]; ok {
+		return info
+	}
+	return errorInfo{kind: ErrorKindUnknown, description: "未知错误", remediation: "查阅讯飞错误码文档，持续出现时联系讯飞技术支持"}
+}
+
+// Error 错误信息，包含错误码说明和处理建议
+func (e *ServerError) Error() string {
+	info := e.info()
+	msg := fmt.Sprintf("讯飞服务错误 %d（%s）: %s，建议: %s", e.Code, info.description, e.Message, info.remediation)
+	if e.SID != "" {
+		msg += "，sid: " + e.SID
+	}
+	return msg
+}
+
+// Unwrap 返回错误分类对应的错误，可用errors.Is(err, ErrAuth)等判断
+func (e *ServerError) Unwrap() error {
+	return kindErrors[e.info().kind]
+}
+
+// Kind 错误分类
+func (e *ServerError) Kind() string {
+	return e.info().kind
+}
+
+// ErrorCode 错误码
+func (e *ServerError) ErrorCode() string {
+	return strconv.Itoa(e.Code)
+}
+
+// Remediation 处理建议
+func (e *ServerError) Remediation() string {
+	return e.info().remediation
+}
+
+// handshakeError 握手被拒绝（HTTP 401/403）时按状态码返回讯飞错误，其他情况返回nil
+func handshakeError(resp *http.Response) error {
+	if resp == nil || (resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden) {
+		return nil
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	message := http.StatusText(resp.StatusCode)
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 4096)); err == nil && json.Unmarshal(data, &body) == nil && body.Message != "" {
+		message = body.Message
+	}
+	return newServerError(resp.StatusCode, message, "")
+}
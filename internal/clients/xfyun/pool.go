@@ -0,0 +1,216 @@
+package xfyun
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// PoolConfig 讯飞连接预热池配置
+type PoolConfig struct {
+	Enabled bool          `yaml:"enabled"` // 是否预先建立连接，识别会话开始时直接取用，省去握手时间
+	Size    int           `yaml:"size"`    // 保持的空闲连接数
+	TTL     time.Duration `yaml:"ttl"`     // 空闲连接的最长保留时间，讯飞在连接建立后一段时间内未收到音频会主动断开
+}
+
+// Validate 校验连接预热池配置
+func (c PoolConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Size <= 0 {
+		return fmt.Errorf("size: 启用连接预热时必须大于0")
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("ttl: 启用连接预热时必须大于0")
+	}
+	return nil
+}
+
+// PoolStats 连接预热池的命中情况
+type PoolStats struct {
+	Idle             int     `json:"idle"`               // 当前空闲连接数
+	Hits             int64   `json:"hits"`               // 取到预热连接的次数
+	Misses           int64   `json:"misses"`             // 没有可用连接、需要现场握手的次数
+	Expired          int64   `json:"expired"`            // 超过保留时间被关闭的连接数
+	DialFailures     int64   `json:"dial_failures"`      // 预热连接握手失败次数
+	HitRate          float64 `json:"hit_rate"`           // 命中率
+	AvgHandshakeMS   int64   `json:"avg_handshake_ms"`   // 预热连接的平均握手耗时（毫秒）
+	HandshakeSavedMS int64   `json:"handshake_saved_ms"` // 命中预热连接累计节省的握手耗时（毫秒）
+}
+
+// pooledConn 预热的空闲连接
+type pooledConn struct {
+	conn      *websocket.Conn
+	created   time.Time
+	handshake time.Duration
+}
+
+// ConnPool 讯飞连接预热池：后台保持一定数量刚建立的连接，超过保留时间的连接关闭后重新建立
+type ConnPool struct {
+	config PoolConfig
+	asr    Config
+
+	mu      sync.Mutex
+	idle    []pooledConn
+	stats   PoolStats
+	dialed  int64         // 预热成功的连接数
+	dialDur time.Duration // 预热连接握手总耗时
+	saved   time.Duration // 命中预热连接节省的握手总耗时
+	refill  chan struct{}
+}
+
+// NewConnPool 创建连接预热池，需调用Run开始预热
+func NewConnPool(config Config) *ConnPool {
+	return &ConnPool{
+		config: config.Pool,
+		asr:    config,
+		refill: make(chan struct{}, 1),
+	}
+}
+
+// dialASR 建立一条鉴权后的讯飞听写连接
+func dialASR(config Config) (*websocket.Conn, error) {
+	params, err := authQuery(config.ServerURL, config.APIKey, config.APISecret)
+	if err != nil {
+		return nil, err
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, resp, err := dialer.Dial(fmt.Sprintf("%s?%s", config.ServerURL, params), nil)
+	if err != nil {
+		if serr := handshakeError(resp); serr != nil {
+			return nil, fmt.Errorf("连接WebSocket服务器失败: %w", serr)
+		}
+		return nil, fmt.Errorf("连接WebSocket服务器失败: %v", err)
+	}
+	return conn, nil
+}
+
+// Run 保持空闲连接数，关闭过期连接，直到ctx取消；返回前关闭所有空闲连接
+func (p *ConnPool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.TTL / 4)
+	defer ticker.Stop()
+	defer p.closeAll()
+
+	for {
+		p.evict(time.Now())
+		p.fill(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.refill:
+		}
+	}
+}
+
+// Get 取出一条预热连接，没有可用连接或p为nil时返回false，调用方需自行建立连接
+func (p *ConnPool) Get() (*websocket.Conn, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	defer p.requestRefill()
+
+	now := time.Now()
+	for len(p.idle) > 0 {
+		// 取最新建立的连接，距离被讯飞断开的时间最长
+		last := len(p.idle) - 1
+		c := p.idle[last]
+		p.idle = p.idle[:last]
+		if now.Sub(c.created) >= p.config.TTL {
+			c.conn.Close()
+			p.stats.Expired++
+			continue
+		}
+		p.stats.Hits++
+		p.saved += c.handshake
+		return c.conn, true
+	}
+	p.stats.Misses++
+	return nil, false
+}
+
+// Stats 连接预热池的命中情况
+func (p *ConnPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Idle = len(p.idle)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	if p.dialed > 0 {
+		stats.AvgHandshakeMS = (p.dialDur / time.Duration(p.dialed)).Milliseconds()
+	}
+	stats.HandshakeSavedMS = p.saved.Milliseconds()
+	return stats
+}
+
+// requestRefill 通知后台补充连接
+func (p *ConnPool) requestRefill() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+// evict 关闭超过保留时间的空闲连接
+func (p *ConnPool) evict(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kept := p.idle[:0]
+	for _, c := range p.idle {
+		if now.Sub(c.created) >= p.config.TTL {
+			c.conn.Close()
+			p.stats.Expired++
+			continue
+		}
+		kept = append(kept, c)
+	}
+	p.idle = kept
+}
+
+// fill 补充空闲连接到配置的数量，握手失败时等待下一轮
+func (p *ConnPool) fill(ctx context.Context) {
+	for ctx.Err() == nil {
+		p.mu.Lock()
+		missing := p.config.Size - len(p.idle)
+		p.mu.Unlock()
+		if missing <= 0 {
+			return
+		}
+
+		start := time.Now()
+		conn, err := dialASR(p.asr)
+		handshake := time.Since(start)
+		p.mu.Lock()
+		if err != nil {
+			p.stats.DialFailures++
+			p.mu.Unlock()
+			log.Printf("预热讯飞连接失败: %v", err)
+			return
+		}
+		p.dialed++
+		p.dialDur += handshake
+		p.idle = append(p.idle, pooledConn{conn: conn, created: time.Now(), handshake: handshake})
+		p.mu.Unlock()
+	}
+}
+
+// closeAll 关闭所有空闲连接
+func (p *ConnPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.idle {
+		c.conn.Close()
+	}
+	p.idle = nil
+}
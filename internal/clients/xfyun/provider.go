@@ -0,0 +1,32 @@
+package xfyun
+
+import (
+	"ai_dialer_mini/internal/models"
+)
+
+// ProviderName 讯飞语音识别后端名称
+const ProviderName = "xfyun"
+
+// provider 以models.ASRProvider接口提供讯飞流式识别
+type provider struct {
+	*ASRClient
+}
+
+// Provider 返回实现models.ASRProvider接口的讯飞识别后端
+func (c *ASRClient) Provider() models.ASRProvider {
+	return provider{c}
+}
+
+// Name 后端名称
+func (p provider) Name() string {
+	return ProviderName
+}
+
+// OpenStream 为会话开始一次流式识别
+func (p provider) OpenStream(sessionID string) (models.ASRStream, error) {
+	stream, err := p.ASRClient.OpenStream(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
@@ -0,0 +1,76 @@
+package xfyun
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// bytesPerSecond 16k采样16位单声道音频每秒的字节数
+const bytesPerSecond = 16000 * 2
+
+// ResumeConfig 识别会话断线续传配置
+// 讯飞连接在识别中途断开时，重新握手并以第一帧参数开启新的识别会话，重新发送最近缓存的音频，识别结果接续之前的文本
+type ResumeConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Buffer      time.Duration `yaml:"buffer"`       // 缓存最近发送的音频时长，重连后重新发送
+	MaxAttempts int           `yaml:"max_attempts"` // 单次识别会话的最大重连次数
+}
+
+// Validate 校验断线续传配置
+func (c ResumeConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Buffer <= 0 || c.Buffer > 60*time.Second {
+		return fmt.Errorf("buffer: 必须在0到60秒之间")
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("max_attempts: 启用断线续传时必须大于0")
+	}
+	return nil
+}
+
+// replayBuffer 最近发送的音频，超过容量时丢弃最早的音频
+type replayBuffer struct {
+	data    []byte
+	limit   int
+	trimmed bool // 是否丢弃过音频，未丢弃时缓存即本次识别会话的全部音频
+}
+
+// newReplayBuffer 创建可缓存d时长音频的缓存，d不大于0时返回nil
+func newReplayBuffer(d time.Duration) *replayBuffer {
+	limit := int(d.Seconds()*bytesPerSecond) &^ 1
+	if limit <= 0 {
+		return nil
+	}
+	return &replayBuffer{limit: limit}
+}
+
+// write 缓存一段音频，丢弃的字节数保持为偶数，不拆开16位采样
+func (b *replayBuffer) write(p []byte) {
+	if b == nil {
+		return
+	}
+	b.data = append(b.data, p...)
+	if over := len(b.data) - b.limit; over > 0 {
+		over += over & 1
+		b.data = append(b.data[:0], b.data[over:]...)
+		b.trimmed = true
+	}
+}
+
+// mergeOverlap 拼接重连前后的识别文本，去掉重新发送的音频导致的重复部分：
+// 前文的结尾与新文本的开头有至少两个字相同时只保留一份，单字重复（如“好好”）不合并
+func mergeOverlap(prefix, text string) string {
+	if prefix == "" {
+		return text
+	}
+	head := []rune(text)
+	for k := len(head); k >= 2; k-- {
+		if strings.HasSuffix(prefix, string(head[:k])) {
+			return prefix + string(head[k:])
+		}
+	}
+	return prefix + text
+}
@@ -0,0 +1,172 @@
+package xfyun
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SessionConfig 会话级连接保持配置
+// 讯飞听写每次识别会话结束后由服务端断开连接，无法在同一连接上识别下一句；
+// 启用后每句识别结束即为同一会话预先建立下一条鉴权连接，同一通话的连续语句不必等待握手
+type SessionConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	IdleTimeout time.Duration `yaml:"idle_timeout"` // 备用连接的最长空闲时间，超过后以新的鉴权参数重新建立，应小于讯飞未收到音频时断开连接的时间
+	MaxIdle     time.Duration `yaml:"max_idle"`     // 会话最后一次识别后继续保持备用连接的时间，超过后释放
+}
+
+// Validate 校验会话级连接保持配置
+func (c SessionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.IdleTimeout <= 0 {
+		return fmt.Errorf("idle_timeout: 启用会话连接保持时必须大于0")
+	}
+	if c.MaxIdle < c.IdleTimeout {
+		return fmt.Errorf("max_idle: 不能小于idle_timeout")
+	}
+	return nil
+}
+
+// standby 会话的备用连接
+type standby struct {
+	conn     *websocket.Conn
+	created  time.Time
+	lastUsed time.Time // 会话最近一次开始识别的时间
+	dialing  bool
+	timer    *time.Timer
+}
+
+// sessionConns 按会话保持的备用连接，可在nil上调用，此时不保持连接
+type sessionConns struct {
+	config SessionConfig
+	asr    Config
+
+	mu       sync.Mutex
+	sessions map[string]*standby
+}
+
+// newSessionConns 创建会话级连接保持，未启用时返回nil
+func newSessionConns(config Config) *sessionConns {
+	if !config.Session.Enabled {
+		return nil
+	}
+	return &sessionConns{
+		config:   config.Session,
+		asr:      config,
+		sessions: make(map[string]*standby),
+	}
+}
+
+// take 取出会话的备用连接，没有可用的备用连接时返回false，调用方需自行建立连接
+func (s *sessionConns) take(sessionID string) (*websocket.Conn, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sb, ok := s.sessions[sessionID]
+	if !ok {
+		sb = &standby{}
+		s.sessions[sessionID] = sb
+	}
+	sb.lastUsed = now
+	if sb.conn == nil {
+		return nil, false
+	}
+	conn := sb.conn
+	sb.conn = nil
+	sb.timer.Stop()
+	if now.Sub(sb.created) >= s.config.IdleTimeout {
+		conn.Close()
+		return nil, false
+	}
+	return conn, true
+}
+
+// prepare 识别会话结束后为下一句预先建立连接，已释放的会话不再建立
+func (s *sessionConns) prepare(sessionID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sb, ok := s.sessions[sessionID]
+	if !ok || sb.conn != nil || sb.dialing {
+		return
+	}
+	sb.dialing = true
+	go s.dial(sessionID, sb)
+}
+
+// dial 建立备用连接，每次都重新生成鉴权参数；握手失败时释放会话，下一句现场握手
+func (s *sessionConns) dial(sessionID string, sb *standby) {
+	conn, err := dialASR(s.asr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sb.dialing = false
+	if s.sessions[sessionID] != sb {
+		// 建立期间会话已释放
+		if conn != nil {
+			conn.Close()
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("预先建立会话 %s 的讯飞连接失败: %v", sessionID, err)
+		delete(s.sessions, sessionID)
+		return
+	}
+	sb.conn = conn
+	sb.created = time.Now()
+	sb.timer = time.AfterFunc(s.config.IdleTimeout, func() { s.expire(sessionID, sb, conn) })
+}
+
+// expire 备用连接空闲超时：会话仍在保持期内时重新建立，否则释放会话
+func (s *sessionConns) expire(sessionID string, sb *standby, conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sb.conn != conn {
+		return
+	}
+	conn.Close()
+	sb.conn = nil
+	if s.sessions[sessionID] != sb {
+		return
+	}
+	if time.Since(sb.lastUsed) >= s.config.MaxIdle {
+		delete(s.sessions, sessionID)
+		return
+	}
+	sb.dialing = true
+	go s.dial(sessionID, sb)
+}
+
+// release 释放会话并关闭备用连接
+func (s *sessionConns) release(sessionID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sb, ok := s.sessions[sessionID]
+	if !ok {
+		return
+	}
+	delete(s.sessions, sessionID)
+	if sb.conn != nil {
+		sb.timer.Stop()
+		sb.conn.Close()
+		sb.conn = nil
+	}
+}
@@ -0,0 +1,189 @@
+package xfyun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"ai_dialer_mini/internal/clients/tts"
+
+	"github.com/gorilla/websocket"
+)
+
+// 讯飞在线语音合成默认参数
+const (
+	DefaultTTSServerURL = "wss://tts-api.xfyun.cn/v2/tts"
+	DefaultTTSVoice     = "xiaoyan"
+)
+
+// TTSConfig 科大讯飞在线语音合成配置，app_id/api_key/api_secret留空时使用asr.xfyun的配置
+type TTSConfig struct {
+	AppID      string        `yaml:"app_id"`
+	APIKey     string        `yaml:"api_key"`
+	APISecret  string        `yaml:"api_secret"`
+	ServerURL  string        `yaml:"server_url"`
+	Voice      string        `yaml:"voice"`       // 默认发音人
+	SampleRate int           `yaml:"sample_rate"` // 默认采样率，支持8000或16000
+	Timeout    time.Duration `yaml:"timeout"`     // 单次合成超时
+}
+
+// ttsRequest 合成请求帧，文本一次性发送
+type ttsRequest struct {
+	Common struct {
+		AppID string `json:"app_id"`
+	} `json:"common"`
+	Business struct {
+		Aue    string `json:"aue"`
+		Auf    string `json:"auf"`
+		Vcn    string `json:"vcn"`
+		Speed  int    `json:"speed"`
+		Pitch  int    `json:"pitch"`
+		Volume int    `json:"volume"`
+		Tte    string `json:"tte"`
+	} `json:"business"`
+	Data struct {
+		Status int    `json:"status"`
+		Text   string `json:"text"`
+	} `json:"data"`
+}
+
+// ttsResponse 合成结果帧，音频分多帧返回，status为2时结束
+type ttsResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Sid     string `json:"sid"`
+	Data    struct {
+		Audio  string `json:"audio"`
+		Status int    `json:"status"`
+	} `json:"data"`
+}
+
+// TTSClient 科大讯飞在线语音合成客户端，输出16位PCM
+type TTSClient struct {
+	config TTSConfig
+}
+
+// NewTTSClient 创建语音合成客户端
+func NewTTSClient(config TTSConfig) (*TTSClient, error) {
+	if config.AppID == "" || config.APIKey == "" || config.APISecret == "" {
+		return nil, fmt.Errorf("讯飞语音合成缺少app_id、api_key或api_secret")
+	}
+	if config.ServerURL == "" {
+		config.ServerURL = DefaultTTSServerURL
+	}
+	if config.Voice == "" {
+		config.Voice = DefaultTTSVoice
+	}
+	if config.SampleRate == 0 {
+		config.SampleRate = 16000
+	}
+	if config.SampleRate != 8000 && config.SampleRate != 16000 {
+		return nil, fmt.Errorf("讯飞语音合成不支持的采样率: %d", config.SampleRate)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &TTSClient{config: config}, nil
+}
+
+// Synthesize 合成音频，options中的发音人、采样率、语速和音调覆盖默认值
+func (c *TTSClient) Synthesize(ctx context.Context, text string, options tts.Options) (*tts.Audio, error) {
+	sampleRate := options.SampleRate
+	if sampleRate != 8000 && sampleRate != 16000 {
+		sampleRate = c.config.SampleRate
+	}
+	voice := options.Voice
+	if voice == "" {
+		voice = c.config.Voice
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	query, err := authQuery(c.config.ServerURL, c.config.APIKey, c.config.APISecret)
+	if err != nil {
+		return nil, err
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.DialContext(ctx, c.config.ServerURL+"?"+query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("连接讯飞语音合成失败: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+		conn.SetWriteDeadline(deadline)
+	}
+
+	var req ttsRequest
+	req.Common.AppID = c.config.AppID
+	req.Business.Aue = "raw"
+	req.Business.Auf = fmt.Sprintf("audio/L16;rate=%d", sampleRate)
+	req.Business.Vcn = voice
+	req.Business.Speed = scale(options.Speed)
+	req.Business.Pitch = scale(options.Pitch)
+	req.Business.Volume = 50
+	req.Business.Tte = "UTF8"
+	req.Data.Status = STATUS_LAST_FRAME
+	req.Data.Text = base64.StdEncoding.EncodeToString([]byte(text))
+	if err := conn.WriteJSON(req); err != nil {
+		return nil, fmt.Errorf("发送合成请求失败: %v", err)
+	}
+
+	audio := &tts.Audio{SampleRate: sampleRate, Channels: 1}
+	for {
+		var resp ttsResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			return nil, fmt.Errorf("读取合成结果失败: %v", err)
+		}
+		if resp.Code != 0 {
+			return nil, fmt.Errorf("讯飞语音合成失败: %d %s (sid: %s)", resp.Code, resp.Message, resp.Sid)
+		}
+		pcm, err := base64.StdEncoding.DecodeString(resp.Data.Audio)
+		if err != nil {
+			return nil, fmt.Errorf("解析合成音频失败: %v", err)
+		}
+		audio.PCM = append(audio.PCM, pcm...)
+		if resp.Data.Status == STATUS_LAST_FRAME {
+			return audio, nil
+		}
+	}
+}
+
+// scale 将倍率换算为讯飞的0~100取值，1.0对应50，为0时使用默认值50
+func scale(multiplier float64) int {
+	if multiplier <= 0 {
+		return 50
+	}
+	v := int(multiplier*50 + 0.5)
+	if v > 100 {
+		v = 100
+	}
+	return v
+}
+
+// authQuery 生成讯飞WebSocket接口的鉴权查询参数，签名覆盖host、date和请求行
+func authQuery(serverURL, apiKey, apiSecret string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("解析URL失败: %v", err)
+	}
+	date := time.Now().UTC().Format(time.RFC1123)
+
+	signString := fmt.Sprintf("host: %s\ndate: %s\nGET %s HTTP/1.1", u.Host, date, u.Path)
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(signString))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	authString := fmt.Sprintf("api_key=\"%s\", algorithm=\"hmac-sha256\", headers=\"host date request-line\", signature=\"%s\"", apiKey, signature)
+
+	params := url.Values{}
+	params.Set("authorization", base64.StdEncoding.EncodeToString([]byte(authString)))
+	params.Set("date", date)
+	params.Set("host", u.Host)
+	return params.Encode(), nil
+}
@@ -3,11 +3,60 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/audio/vad"
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/clients/hlr"
+	"ai_dialer_mini/internal/clients/mock"
 	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/clients/openai"
+	"ai_dialer_mini/internal/clients/punctuation"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
 	"ai_dialer_mini/internal/clients/xfyun"
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/logger"
+	"ai_dialer_mini/internal/middleware"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/services/assist"
+	"ai_dialer_mini/internal/services/campaign"
+	"ai_dialer_mini/internal/services/consent"
+	"ai_dialer_mini/internal/services/cron"
+	"ai_dialer_mini/internal/services/dataset"
+	"ai_dialer_mini/internal/services/deadair"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/fallback"
+	"ai_dialer_mini/internal/services/filler"
+	"ai_dialer_mini/internal/services/guardrail"
+	"ai_dialer_mini/internal/services/history"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/llmcache"
+	"ai_dialer_mini/internal/services/postprocess"
+	"ai_dialer_mini/internal/services/prompt"
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/qa"
+	"ai_dialer_mini/internal/services/redaction"
+	"ai_dialer_mini/internal/services/scripting"
+	"ai_dialer_mini/internal/services/sentiment"
+	"ai_dialer_mini/internal/services/session"
+	"ai_dialer_mini/internal/services/streamauth"
+	"ai_dialer_mini/internal/services/tap"
+	"ai_dialer_mini/internal/services/tenant"
+	"ai_dialer_mini/internal/services/tools"
+	"ai_dialer_mini/internal/services/turn"
+	"ai_dialer_mini/internal/services/wrapup"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,19 +65,144 @@ var globalConfig *Config
 
 // Config 应用程序配置结构
 type Config struct {
-	Server     ServerConfig     `yaml:"server"`
-	FreeSWITCH FreeSWITCHConfig `yaml:"freeswitch"`
-	XFYun      xfyun.Config    `yaml:"xfyun"`
-	Ollama     ollama.Config   `yaml:"ollama"`
-	WebSocket  WebSocketConfig  `yaml:"websocket"`
-	MySQL      MySQLConfig      `yaml:"mysql"`
-	Redis      RedisConfig      `yaml:"redis"`
+	Server      ServerConfig       `yaml:"server"`
+	FreeSWITCH  FreeSWITCHConfig   `yaml:"freeswitch"`
+	ASR         ASRConfig          `yaml:"asr"`
+	LLM         LLMConfig          `yaml:"llm"`
+	TTS         TTSConfig          `yaml:"tts"`
+	WebSocket   WebSocketConfig    `yaml:"websocket"`
+	Database    database.Config    `yaml:"database"`
+	MySQL       MySQLConfig        `yaml:"mysql"`
+	Redis       RedisConfig        `yaml:"redis"`
+	Session     session.Config     `yaml:"session"`
+	API         APIConfig          `yaml:"api"`
+	Admin       AdminConfig        `yaml:"admin"`
+	Webhook     WebhookConfig      `yaml:"webhook"`
+	Outbox      OutboxConfig       `yaml:"outbox"`
+	Logging     logger.Config      `yaml:"logging"`
+	Storage     storage.Config     `yaml:"storage"`
+	Recorder    recorder.Config    `yaml:"recorder"`
+	Recording   RecordingConfig    `yaml:"recording"`
+	PromptAudio promptaudio.Config `yaml:"prompt_audio"`
+	Transcript  TranscriptConfig   `yaml:"transcript"`
+	Redaction   redaction.Config   `yaml:"redaction"`
+	Encryption  encryption.Config  `yaml:"encryption"`
+	AudioTap    tap.Config         `yaml:"audio_tap"`
+	Assist      assist.Config      `yaml:"assist"`
+	Turn        turn.Config        `yaml:"turn"`
+	BargeIn     vad.Config         `yaml:"barge_in"`
+	DeadAir     deadair.Config     `yaml:"dead_air"`
+	Intent      intent.Config      `yaml:"intent"`
+	DTMF        dtmf.Config        `yaml:"dtmf"`
+	WrapUp      wrapup.Config      `yaml:"wrapup"`
+	HLR         hlr.Config         `yaml:"number_lookup"`
+	Routing     RoutingConfig      `yaml:"routing"`
+	AudioStream streamauth.Config  `yaml:"audio_stream"`
+	ASRFallback fallback.Config    `yaml:"asr_fallback"`
+	Campaign    campaign.Config    `yaml:"campaign"`
+	Inbound     inbound.Config     `yaml:"inbound"`
+	Tenancy     tenant.Config      `yaml:"tenancy"`
+	QA          qa.Config          `yaml:"qa"`
+	Dataset     dataset.Config     `yaml:"dataset"`
+	Cron        cron.Config        `yaml:"cron"`
+	Lifecycle   lifecycle.Config   `yaml:"lifecycle"`
+	Scripting   scripting.Config   `yaml:"scripting"`
+
+	// 已废弃的配置项，加载时映射到asr/llm下，保留以兼容旧配置文件
+	DeprecatedXFYun  *DeprecatedXFYun  `yaml:"xfyun"`
+	DeprecatedOllama *ollama.Config    `yaml:"ollama"`
+	DeprecatedDialog *DeprecatedDialog `yaml:"dialog"`
+}
+
+// 服务后端
+const (
+	ProviderXFYun   = "xfyun"   // 科大讯飞
+	ProviderWhisper = "whisper" // Whisper识别服务
+	ProviderOllama  = "ollama"  // Ollama
+	ProviderOpenAI  = "openai"  // OpenAI兼容接口（OpenAI、Azure OpenAI、DeepSeek等）
+	ProviderMock    = "mock"    // 模拟后端，用于压测和CI
+)
+
+// ASRConfig 语音识别配置
+type ASRConfig struct {
+	Provider    string             `yaml:"provider"`    // 默认语音识别后端，xfyun或whisper
+	XFYun       xfyun.Config       `yaml:"xfyun"`       // 科大讯飞语音听写配置
+	Whisper     asr.WhisperConfig  `yaml:"whisper"`     // Whisper识别服务配置，未配置server_url时不启用
+	Failover    asr.FailoverConfig `yaml:"failover"`    // 识别后端故障切换
+	Punctuation punctuation.Config `yaml:"punctuation"` // 本地文本后处理服务，为没有标点的识别结果添加标点
+	PostProcess postprocess.Config `yaml:"postprocess"` // 最终识别结果的数字规整、词语替换和屏蔽词过滤
+}
+
+// LLMConfig 大模型配置
+type LLMConfig struct {
+	Provider string         `yaml:"provider"` // 大模型后端，ollama、openai或mock
+	Ollama   ollama.Config  `yaml:"ollama"`   // Ollama配置
+	OpenAI   openai.Config  `yaml:"openai"`   // OpenAI兼容接口配置
+	Mock     mock.LLMConfig `yaml:"mock"`     // 模拟大模型配置
+
+	Options   models.GenerationOptions              `yaml:"options"`   // 默认生成参数
+	Campaigns map[string]models.GenerationOverrides `yaml:"campaigns"` // 按活动ID覆盖生成参数
+	Prompt    prompt.Config                         `yaml:"prompt"`    // 提示词模板、AI人设和业务知识
+	Guardrail guardrail.Config                      `yaml:"guardrail"` // 回复长度限制、不安全内容过滤和兜底话术
+	Cache     llmcache.Config                       `yaml:"cache"`     // 回复缓存，需要Redis
+	History   history.Config                        `yaml:"history"`   // 对话历史的token预算和较早历史的摘要
+	Tools     tools.Config                          `yaml:"tools"`     // 大模型可调用的工具
+}
+
+// Model 当前大模型后端及其默认模型名称，如 ollama:qwen:0.5b，用于区分不同模型生成的回复
+func (c LLMConfig) Model() string {
+	switch c.Provider {
+	case ProviderOpenAI:
+		return ProviderOpenAI + ":" + c.OpenAI.Model
+	case ProviderMock:
+		return ProviderMock
+	default:
+		return ProviderOllama + ":" + c.Ollama.Model
+	}
+}
+
+// DeprecatedXFYun 旧版xfyun配置段，已由asr.xfyun取代
+type DeprecatedXFYun struct {
+	AppID             string `yaml:"app_id"`
+	APIKey            string `yaml:"api_key"`
+	APISecret         string `yaml:"api_secret"`
+	ServerURL         string `yaml:"server_url"`
+	MaxRetries        int    `yaml:"max_retries"`
+	ReconnectInterval int    `yaml:"reconnect_interval"` // 重连间隔，单位秒
+}
+
+// DeprecatedDialog 旧版dialog配置段，已由llm取代
+type DeprecatedDialog struct {
+	Provider string          `yaml:"provider"`
+	Mock     *mock.LLMConfig `yaml:"mock"`
+}
+
+// TTSConfig 语音合成配置
+type TTSConfig struct {
+	Provider string          `yaml:"provider"` // 语音合成后端，留空不合成，xfyun为讯飞在线语音合成，mock为模拟音频
+	XFYun    xfyun.TTSConfig `yaml:"xfyun"`    // 讯飞在线语音合成配置
+	Mock     mock.TTSConfig  `yaml:"mock"`     // 模拟语音合成配置
+	Fillers  filler.Config   `yaml:"fillers"`  // 回复较慢时播放的应答语音
+	Playback PlaybackConfig  `yaml:"playback"` // 通过FreeSWITCH向通话播放AI语音
+
+	Styles       map[string]tts.Options `yaml:"styles"`        // 语气风格对应的合成参数，回复以[风格名]开头时使用
+	DefaultStyle string                 `yaml:"default_style"` // 回复没有语气标签时使用的风格
+	Campaigns    map[string]string      `yaml:"campaigns"`     // 按活动ID指定默认风格，优先级高于default_style
+}
+
+// PlaybackConfig 通话中AI语音播放配置
+type PlaybackConfig struct {
+	Enabled       bool   `yaml:"enabled"`        // 是否通过uuid_broadcast向通话播放AI语音
+	Dir           string `yaml:"dir"`            // 语音文件临时目录（与FreeSWITCH共享）
+	FreeSWITCHDir string `yaml:"freeswitch_dir"` // FreeSWITCH主机上的对应目录，留空则与dir相同
+	Leg           string `yaml:"leg"`            // 播放到哪一侧：aleg（音频流所在通道）、bleg 或 both
 }
 
 // ServerConfig HTTP服务器配置
 type ServerConfig struct {
 	Host string `yaml:"host"` // 服务器监听地址
 	Port int    `yaml:"port"` // 服务器监听端口
+	Role string `yaml:"role"` // 进程角色，逗号分隔的 api、media、worker，为空或all时运行全部；启动参数 --role 优先
 }
 
 // FreeSWITCHConfig FreeSWITCH连接配置
@@ -36,6 +210,50 @@ type FreeSWITCHConfig struct {
 	Host     string `yaml:"host"`     // FreeSWITCH主机地址
 	Port     int    `yaml:"port"`     // FreeSWITCH端口
 	Password string `yaml:"password"` // 认证密码
+	// Events 订阅的事件，为空时只订阅拨号器已注册处理的事件，包含all时订阅全部事件
+	Events []string `yaml:"events"`
+	// Filters 事件过滤，配置后只接收头部与任一条件匹配的事件，多个拨号器共用一台FreeSWITCH时可按Caller-Context等区分
+	Filters []freeswitch.EventFilter `yaml:"filters"`
+	// ReconnectDelay 连接断开后首次重连前的等待时间，之后每次失败翻倍
+	ReconnectDelay time.Duration `yaml:"reconnect_delay"`
+	// MaxReconnectDelay 重连等待时间的上限
+	MaxReconnectDelay time.Duration `yaml:"max_reconnect_delay"`
+}
+
+// RoutingConfig 外呼路由配置
+type RoutingConfig struct {
+	Gateways        []string      `yaml:"gateways"`         // 落地网关（FreeSWITCH sofia网关名），按优先级排列，为空时直接呼叫分机
+	Window          time.Duration `yaml:"window"`           // 接通率统计窗口
+	MinCalls        int           `yaml:"min_calls"`        // 统计窗口内呼叫次数达到该值才参与降级判断
+	MinASR          float64       `yaml:"min_asr"`          // 接通率低于该值的网关降级，排在其他网关之后
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // 统计刷新间隔
+}
+
+// RecordingConfig 录音归档配置
+type RecordingConfig struct {
+	Enabled       bool         `yaml:"enabled"`        // 是否在挂机后归档录音
+	Dir           string       `yaml:"dir"`            // FreeSWITCH录音目录（与FreeSWITCH共享），录音文件名为 {通话UUID}.wav
+	Prefix        string       `yaml:"prefix"`         // 对象存储中的键前缀
+	Filename      string       `yaml:"filename"`       // 归档文件名模板（不含扩展名），可用{uuid}、{date}、{time}、{caller}、{callee}，默认{uuid}
+	DeleteSource  bool         `yaml:"delete_source"`  // 归档成功后删除原始录音
+	Workers       int          `yaml:"workers"`        // 并行转码数
+	Codec         codec.Config `yaml:"codec"`          // 存储格式
+	Record        bool         `yaml:"record"`         // 通道应答时由拨号器通过uuid_record启动录音，关闭时由拨号计划自行录音
+	FreeSWITCHDir string       `yaml:"freeswitch_dir"` // FreeSWITCH主机上的录音目录，留空则与dir相同
+	Stereo        bool         `yaml:"stereo"`         // 双声道录音，左声道为主叫（A腿），右声道为被叫（B腿）
+	Transcribe    bool         `yaml:"transcribe"`     // 归档前离线转写，双声道录音按声道区分说话方
+	LeftSpeaker   string       `yaml:"left_speaker"`   // 左声道（单声道录音时为整段录音）的说话方
+	RightSpeaker  string       `yaml:"right_speaker"`  // 右声道的说话方
+
+	Consent consent.Config `yaml:"consent"` // 录音授权：客户拒绝录音时停止录音并在挂机后删除录音文件
+}
+
+// TranscriptConfig 通话转写配置
+type TranscriptConfig struct {
+	PinyinDict string `yaml:"pinyin_dict"` // 拼音字典文件（pinyin-data的pinyin.txt格式），配置后转写检索支持附加拼音
+	Live       bool   `yaml:"live"`        // 通话中实时保存转写（客户语音、AI回复、按键和接通挂断事件），通过 GET /api/v1/calls/:uuid/transcript 导出
+
+	Sentiment sentiment.Config `yaml:"sentiment"` // 实时保存的客户语音逐句标注情绪，通过 GET /api/v1/calls/:uuid/sentiment 汇总
 }
 
 // MySQLConfig MySQL配置
@@ -45,6 +263,44 @@ type MySQLConfig struct {
 	User     string `yaml:"user"`     // MySQL用户名
 	Password string `yaml:"password"` // MySQL密码
 	Database string `yaml:"database"` // 数据库名
+
+	AutoMigrate bool `yaml:"auto_migrate"` // 启动时自动执行待执行的数据库迁移
+}
+
+// DSN 生成MySQL驱动连接串
+func (c MySQLConfig) DSN() string {
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=true&loc=Local", c.User, c.Password, addr, c.Database)
+}
+
+// Addr 获取Redis服务器地址
+func (c RedisConfig) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// APIConfig REST API配置
+type APIConfig struct {
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"` // 幂等键有效期，期内重复提交返回首次结果
+	Tokens         []string      `yaml:"tokens"`          // 通话控制接口（通话中插入话术、补充上下文）和录音接口的令牌，未配置时不注册这些接口
+}
+
+// AdminConfig 运维管理接口配置
+type AdminConfig struct {
+	Tokens []string `yaml:"tokens"` // 运维管理接口令牌，未配置时不注册运维管理接口
+}
+
+// WebhookConfig 外部系统推送配置
+type WebhookConfig struct {
+	URL     string        `yaml:"url"`     // 通话事件Webhook地址，留空则不推送
+	CRMURL  string        `yaml:"crm_url"` // CRM推送地址，留空则不推送
+	Timeout time.Duration `yaml:"timeout"` // 单次投递超时时间
+}
+
+// OutboxConfig 发件箱投递配置
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // 轮询待投递消息的间隔
+	BatchSize    int           `yaml:"batch_size"`    // 每次轮询处理的消息数
+	MaxAttempts  int           `yaml:"max_attempts"`  // 最大投递次数，超过后标记为失败
 }
 
 // RedisConfig Redis配置
@@ -52,7 +308,9 @@ type RedisConfig struct {
 	Host     string `yaml:"host"`     // Redis主机地址
 	Port     int    `yaml:"port"`     // Redis端口
 	Password string `yaml:"password"` // Redis密码
-	DB       int    `yaml:"db"`      // Redis数据库编号
+	DB       int    `yaml:"db"`       // Redis数据库编号
+
+	EventChannel string `yaml:"event_channel"` // 跨实例广播通话实时事件的频道
 }
 
 // WebSocketConfig WebSocket配置
@@ -61,6 +319,25 @@ type WebSocketConfig struct {
 	WriteBufferSize int           `yaml:"write_buffer_size"` // 写缓冲区大小
 	PingPeriod      time.Duration `yaml:"ping_period"`       // 心跳间隔
 	PongWait        time.Duration `yaml:"pong_wait"`         // 等待Pong响应的超时时间
+	StreamReply     bool          `yaml:"stream_reply"`      // AI回复生成过程中以ai_delta消息逐段推送回复文本
+	MaxMessageRate  int           `yaml:"max_message_rate"`  // 每个连接每秒最多处理的消息数，超出的消息丢弃，0为不限制
+
+	AllowedOrigins     []string `yaml:"allowed_origins"`       // 允许建立WebSocket连接的网页来源，如 https://crm.example.com、https://*.example.com
+	DevAllowAllOrigins bool     `yaml:"dev_allow_all_origins"` // 允许所有来源，仅用于本地开发
+}
+
+// CheckOrigin 检查WebSocket握手请求的来源，没有Origin头的非浏览器客户端和同源请求总是允许
+func (c WebSocketConfig) CheckOrigin(r *http.Request) bool {
+	return middleware.AllowOrigin(r, c.AllowedOrigins, c.DevAllowAllOrigins)
+}
+
+// CheckWebSocketOrigin 按全局配置检查WebSocket握手请求的来源，供无法注入配置的升级器使用；
+// 配置未加载时只允许同源和非浏览器客户端
+func CheckWebSocketOrigin(r *http.Request) bool {
+	if globalConfig == nil {
+		return middleware.AllowOrigin(r, nil, false)
+	}
+	return globalConfig.WebSocket.CheckOrigin(r)
 }
 
 // GetConfig 获取全局配置实例
@@ -75,10 +352,27 @@ func Load(filename string) (*Config, error) {
 		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
+	if err := expandEnv(&doc); err != nil {
+		return nil, fmt.Errorf("替换配置文件中的环境变量失败: %v", err)
+	}
+	var config Config
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&config); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %v", err)
+		}
+	}
+
+	// 兼容旧版配置项
+	applyDeprecated(&config)
+
+	// 环境变量覆盖配置项，密钥等敏感配置应通过环境变量注入而不是写入配置文件
+	if err := applyEnvOverrides(&config); err != nil {
+		return nil, fmt.Errorf("应用环境变量覆盖失败: %v", err)
+	}
 
 	// 设置默认值
 	if config.WebSocket.ReadBufferSize == 0 {
@@ -93,6 +387,265 @@ func Load(filename string) (*Config, error) {
 	if config.WebSocket.PongWait == 0 {
 		config.WebSocket.PongWait = 60 * time.Second
 	}
+	if config.ASR.Provider == "" {
+		config.ASR.Provider = ProviderXFYun
+	}
+	if config.ASR.XFYun.Pool.Size == 0 {
+		config.ASR.XFYun.Pool.Size = 2
+	}
+	if config.ASR.XFYun.Pool.TTL == 0 {
+		config.ASR.XFYun.Pool.TTL = 5 * time.Second
+	}
+	if config.ASR.XFYun.Session.IdleTimeout == 0 {
+		config.ASR.XFYun.Session.IdleTimeout = 5 * time.Second
+	}
+	if config.ASR.XFYun.Session.MaxIdle == 0 {
+		config.ASR.XFYun.Session.MaxIdle = 60 * time.Second
+	}
+	if config.ASR.Failover.Threshold == 0 {
+		config.ASR.Failover.Threshold = 3
+	}
+	if config.ASR.Failover.Cooldown == 0 {
+		config.ASR.Failover.Cooldown = time.Minute
+	}
+	if config.ASR.XFYun.Resume.Buffer == 0 {
+		config.ASR.XFYun.Resume.Buffer = 3 * time.Second
+	}
+	if config.ASR.XFYun.Resume.MaxAttempts == 0 {
+		config.ASR.XFYun.Resume.MaxAttempts = 2
+	}
+	if config.ASR.Punctuation.Timeout == 0 {
+		config.ASR.Punctuation.Timeout = 2 * time.Second
+	}
+	if config.ASR.Punctuation.BatchSize == 0 {
+		config.ASR.Punctuation.BatchSize = 16
+	}
+	if config.ASR.Punctuation.BatchWindow == 0 {
+		config.ASR.Punctuation.BatchWindow = 20 * time.Millisecond
+	}
+	if config.Transcript.Sentiment.Provider == "" {
+		config.Transcript.Sentiment.Provider = sentiment.ProviderLexicon
+	}
+	if config.LLM.Provider == "" {
+		config.LLM.Provider = ProviderOllama
+	}
+	if config.LLM.Options == (models.GenerationOptions{}) {
+		config.LLM.Options = models.DefaultGenerationOptions
+	} else if config.LLM.Options.MaxTokens == 0 {
+		config.LLM.Options.MaxTokens = models.DefaultGenerationOptions.MaxTokens
+	}
+	if config.FreeSWITCH.ReconnectDelay == 0 {
+		config.FreeSWITCH.ReconnectDelay = time.Second
+	}
+	if config.FreeSWITCH.MaxReconnectDelay == 0 {
+		config.FreeSWITCH.MaxReconnectDelay = 30 * time.Second
+	}
+	if config.Database.Driver == "" {
+		config.Database.Driver = database.MySQL
+	}
+	if config.Database.Driver == database.SQLite && config.Database.SQLite.Path == "" {
+		config.Database.SQLite.Path = database.DefaultSQLitePath
+	}
+	if config.Storage.Driver == "" {
+		config.Storage.Driver = storage.DriverLocal
+	}
+	if config.Storage.Driver == storage.DriverLocal && config.Storage.Dir == "" {
+		config.Storage.Dir = "data/storage"
+	}
+	if config.TTS.Fillers.Threshold == 0 {
+		config.TTS.Fillers.Threshold = 1500 * time.Millisecond
+	}
+	if config.TTS.XFYun.AppID == "" && config.TTS.XFYun.APIKey == "" && config.TTS.XFYun.APISecret == "" {
+		config.TTS.XFYun.AppID = config.ASR.XFYun.AppID
+		config.TTS.XFYun.APIKey = config.ASR.XFYun.APIKey
+		config.TTS.XFYun.APISecret = config.ASR.XFYun.APISecret
+	}
+	if config.TTS.Playback.FreeSWITCHDir == "" {
+		config.TTS.Playback.FreeSWITCHDir = config.TTS.Playback.Dir
+	}
+	if config.TTS.Playback.Leg == "" {
+		config.TTS.Playback.Leg = "aleg"
+	}
+	if config.Turn.HoldTimeout == 0 {
+		config.Turn.HoldTimeout = time.Minute
+	}
+	if config.BargeIn.Threshold == 0 {
+		config.BargeIn.Threshold = 800
+	}
+	if config.BargeIn.MinSpeech == 0 {
+		config.BargeIn.MinSpeech = 200 * time.Millisecond
+	}
+	if config.BargeIn.MinSilence == 0 {
+		config.BargeIn.MinSilence = 500 * time.Millisecond
+	}
+	if config.DeadAir.Timeout == 0 {
+		config.DeadAir.Timeout = 90 * time.Second
+	}
+	if config.DeadAir.Threshold == 0 {
+		config.DeadAir.Threshold = 300
+	}
+	if config.DeadAir.Cause == "" {
+		config.DeadAir.Cause = deadair.DefaultCause
+	}
+	if config.Redis.EventChannel == "" {
+		config.Redis.EventChannel = "ai_dialer:events"
+	}
+	if config.Session.Store == "" {
+		config.Session.Store = session.StoreMemory
+	}
+	if config.Session.TTL == 0 {
+		config.Session.TTL = session.DefaultTTL
+	}
+	if config.Session.Prefix == "" {
+		config.Session.Prefix = "ai_dialer:session:"
+	}
+	if config.API.IdempotencyTTL == 0 {
+		config.API.IdempotencyTTL = 24 * time.Hour
+	}
+	if config.Webhook.Timeout == 0 {
+		config.Webhook.Timeout = 10 * time.Second
+	}
+	if config.Outbox.PollInterval == 0 {
+		config.Outbox.PollInterval = 2 * time.Second
+	}
+	if config.Outbox.BatchSize == 0 {
+		config.Outbox.BatchSize = 20
+	}
+	if config.Outbox.MaxAttempts == 0 {
+		config.Outbox.MaxAttempts = 10
+	}
+	if config.LLM.OpenAI.Timeout == 0 {
+		config.LLM.OpenAI.Timeout = 60 * time.Second
+	}
+	if config.LLM.Ollama.Timeout == 0 {
+		config.LLM.Ollama.Timeout = 60 * time.Second
+	}
+	if config.LLM.Ollama.DialTimeout == 0 {
+		config.LLM.Ollama.DialTimeout = 5 * time.Second
+	}
+	if config.LLM.Ollama.MaxIdleConns == 0 {
+		config.LLM.Ollama.MaxIdleConns = 100
+	}
+	if config.LLM.Ollama.MaxIdleConnsPerHost == 0 {
+		config.LLM.Ollama.MaxIdleConnsPerHost = 32
+	}
+	if config.LLM.Ollama.IdleConnTimeout == 0 {
+		config.LLM.Ollama.IdleConnTimeout = 90 * time.Second
+	}
+	if config.LLM.Ollama.MaxRetries == 0 {
+		config.LLM.Ollama.MaxRetries = 2
+	}
+	if config.LLM.Ollama.RetryBackoff == 0 {
+		config.LLM.Ollama.RetryBackoff = 200 * time.Millisecond
+	}
+	if config.Recording.Prefix == "" {
+		config.Recording.Prefix = "recordings"
+	}
+	if config.Recording.Filename == "" {
+		config.Recording.Filename = "{uuid}"
+	}
+	if config.Recording.Workers == 0 {
+		config.Recording.Workers = 2
+	}
+	if config.Recording.FreeSWITCHDir == "" {
+		config.Recording.FreeSWITCHDir = config.Recording.Dir
+	}
+	if config.Recording.LeftSpeaker == "" {
+		config.Recording.LeftSpeaker = models.SpeakerAgent
+	}
+	if config.Recording.RightSpeaker == "" {
+		config.Recording.RightSpeaker = models.SpeakerCustomer
+	}
+	if c := &config.Recording.Consent; c.GrantDigits == "" && c.RefuseDigits == "" && c.GrantPhrases == nil && c.RefusePhrases == nil {
+		c.GrantDigits = "1"
+		c.RefuseDigits = "2"
+		c.GrantPhrases = []string{"同意", "可以", "没问题", "好的"}
+		c.RefusePhrases = []string{"不同意", "不可以", "不要录", "别录", "拒绝"}
+	}
+	if config.Campaign.TickInterval == 0 {
+		config.Campaign.TickInterval = time.Second
+	}
+	if config.Campaign.BatchSize == 0 {
+		config.Campaign.BatchSize = 10
+	}
+	if config.Encryption.Tenant == "" {
+		config.Encryption.Tenant = "default"
+	}
+	if config.Campaign.QuietHours.Start == "" {
+		config.Campaign.QuietHours.Start = "09:00"
+	}
+	if config.Campaign.QuietHours.End == "" {
+		config.Campaign.QuietHours.End = "21:00"
+	}
+	if config.Campaign.QuietHours.DefaultCountryCode == "" {
+		config.Campaign.QuietHours.DefaultCountryCode = "86"
+	}
+	if config.Campaign.AutoThrottle.Window == 0 {
+		config.Campaign.AutoThrottle.Window = time.Minute
+	}
+	if config.Campaign.AutoThrottle.MinSamples == 0 {
+		config.Campaign.AutoThrottle.MinSamples = 20
+	}
+	if config.Campaign.AutoThrottle.Factor == 0 {
+		config.Campaign.AutoThrottle.Factor = 0.5
+	}
+	if config.Campaign.Preview.Timeout == 0 {
+		config.Campaign.Preview.Timeout = 2 * time.Minute
+	}
+	if config.Campaign.Preview.MaxPending == 0 {
+		config.Campaign.Preview.MaxPending = 5
+	}
+	if config.Campaign.Metrics.Interval == 0 {
+		config.Campaign.Metrics.Interval = 15 * time.Second
+	}
+	if config.Campaign.Metrics.AbandonThreshold == 0 {
+		config.Campaign.Metrics.AbandonThreshold = 5 * time.Second
+	}
+	if config.Routing.Window == 0 {
+		config.Routing.Window = time.Hour
+	}
+	if config.Routing.MinCalls == 0 {
+		config.Routing.MinCalls = 50
+	}
+	if config.Routing.RefreshInterval == 0 {
+		config.Routing.RefreshInterval = time.Minute
+	}
+	if config.HLR.Timeout == 0 {
+		config.HLR.Timeout = 5 * time.Second
+	}
+	if config.HLR.MaxAge == 0 {
+		config.HLR.MaxAge = 30 * 24 * time.Hour
+	}
+	if config.QA.Interval == 0 {
+		config.QA.Interval = time.Minute
+	}
+	if config.QA.Delay == 0 {
+		config.QA.Delay = 10 * time.Minute
+	}
+	if config.QA.Lookback == 0 {
+		config.QA.Lookback = 24 * time.Hour
+	}
+	if config.QA.BatchSize == 0 {
+		config.QA.BatchSize = 10
+	}
+	if config.QA.PassScore == 0 {
+		config.QA.PassScore = 60
+	}
+	if config.Dataset.Dir == "" {
+		config.Dataset.Dir = "data/datasets"
+	}
+	if config.Dataset.MaxDialogs == 0 {
+		config.Dataset.MaxDialogs = 10000
+	}
+	if config.Dataset.MinTurns == 0 {
+		config.Dataset.MinTurns = 2
+	}
+	if config.Dataset.Lookback == 0 {
+		config.Dataset.Lookback = 24 * time.Hour
+	}
+	if config.Lifecycle.GracePeriod == 0 {
+		config.Lifecycle.GracePeriod = 30 * time.Second
+	}
 
 	// 验证配置
 	if err := validateConfig(&config); err != nil {
@@ -111,6 +664,13 @@ func validateConfig(config *Config) error {
 	if config.Server.Port <= 0 {
 		return fmt.Errorf("服务器端口必须大于0")
 	}
+	if _, err := ParseRoles(config.Server.Role); err != nil {
+		return fmt.Errorf("server.role: %v", err)
+	}
+
+	if config.FreeSWITCH.ReconnectDelay < 0 || config.FreeSWITCH.MaxReconnectDelay < config.FreeSWITCH.ReconnectDelay {
+		return fmt.Errorf("freeswitch.max_reconnect_delay: 不能小于reconnect_delay")
+	}
 
 	// 验证WebSocket配置
 	if config.WebSocket.ReadBufferSize <= 0 {
@@ -119,6 +679,291 @@ func validateConfig(config *Config) error {
 	if config.WebSocket.WriteBufferSize <= 0 {
 		return fmt.Errorf("WebSocket写缓冲区大小必须大于0")
 	}
+	if config.WebSocket.MaxMessageRate < 0 {
+		return fmt.Errorf("websocket.max_message_rate: 不能为负数")
+	}
+	if err := middleware.ValidateOrigins(config.WebSocket.AllowedOrigins); err != nil {
+		return fmt.Errorf("websocket.allowed_origins: %v", err)
+	}
+
+	// 验证服务后端
+	switch config.ASR.Provider {
+	case ProviderXFYun:
+	case ProviderWhisper:
+		if config.ASR.Whisper.ServerURL == "" {
+			return fmt.Errorf("asr.whisper.server_url: 使用Whisper识别时必须配置服务地址")
+		}
+	default:
+		return fmt.Errorf("不支持的语音识别后端: %s", config.ASR.Provider)
+	}
+	if err := config.ASR.Whisper.Validate(); err != nil {
+		return fmt.Errorf("asr.whisper.%v", err)
+	}
+	if err := config.ASR.Failover.Validate(); err != nil {
+		return fmt.Errorf("asr.failover.%v", err)
+	}
+	if config.ASR.Failover.Enabled && config.ASR.Whisper.ServerURL == "" {
+		return fmt.Errorf("asr.failover: 故障切换需要同时配置讯飞和Whisper两个识别后端")
+	}
+	if err := config.ASR.XFYun.Pool.Validate(); err != nil {
+		return fmt.Errorf("asr.xfyun.pool.%v", err)
+	}
+	if err := config.ASR.XFYun.Session.Validate(); err != nil {
+		return fmt.Errorf("asr.xfyun.session.%v", err)
+	}
+	if err := config.ASR.XFYun.Resume.Validate(); err != nil {
+		return fmt.Errorf("asr.xfyun.resume.%v", err)
+	}
+	if config.ASR.Punctuation.Enabled && config.ASR.Punctuation.URL == "" {
+		return fmt.Errorf("asr.punctuation.url: 启用文本后处理时必须配置服务地址")
+	}
+	if err := config.ASR.PostProcess.Validate(); err != nil {
+		return fmt.Errorf("asr.postprocess.%v", err)
+	}
+	if err := config.LLM.Ollama.Validate(); err != nil {
+		return fmt.Errorf("llm.ollama.%v", err)
+	}
+	switch config.LLM.Provider {
+	case ProviderOllama, ProviderMock:
+	case ProviderOpenAI:
+		if config.LLM.OpenAI.BaseURL == "" {
+			return fmt.Errorf("llm.openai.base_url: 使用OpenAI兼容接口时必须配置接口地址")
+		}
+		if config.LLM.OpenAI.Model == "" && config.LLM.OpenAI.APIVersion == "" {
+			return fmt.Errorf("llm.openai.model: 使用OpenAI兼容接口时必须配置模型名称")
+		}
+	default:
+		return fmt.Errorf("不支持的大模型后端: %s", config.LLM.Provider)
+	}
+
+	switch config.TTS.Provider {
+	case "", ProviderXFYun, ProviderMock:
+	default:
+		return fmt.Errorf("不支持的语音合成后端: %s", config.TTS.Provider)
+	}
+	if config.TTS.Playback.Enabled && config.TTS.Playback.Dir == "" {
+		return fmt.Errorf("tts.playback.dir: 启用通话语音播放时必须配置语音文件目录")
+	}
+	switch config.TTS.Playback.Leg {
+	case "aleg", "bleg", "both":
+	default:
+		return fmt.Errorf("tts.playback.leg: 必须为aleg、bleg或both")
+	}
+
+	// 验证日志级别配置
+	if err := config.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging.%v", err)
+	}
+
+	// 验证数据库驱动配置
+	if err := config.Database.Validate(); err != nil {
+		return fmt.Errorf("database.%v", err)
+	}
+
+	// 验证会话存储配置
+	if err := config.Session.Validate(); err != nil {
+		return fmt.Errorf("session.%v", err)
+	}
+
+	// 验证打断检测配置
+	if config.BargeIn.Threshold < 0 || config.BargeIn.Threshold > 32768 {
+		return fmt.Errorf("barge_in.threshold: 必须在0到32768之间")
+	}
+	if err := config.DeadAir.Validate(); err != nil {
+		return fmt.Errorf("dead_air.%v", err)
+	}
+	if config.DeadAir.Enabled && len(config.Turn.HoldPhrases) > 0 && config.DeadAir.Timeout <= config.Turn.HoldTimeout {
+		return fmt.Errorf("dead_air.timeout: 必须大于turn.hold_timeout，否则客户要求稍等期间会被挂机")
+	}
+
+	// 验证通话监听配置
+	if config.AudioTap.Enabled && len(config.AudioTap.Tokens) == 0 {
+		return fmt.Errorf("audio_tap.tokens: 启用通话监听时必须配置质检坐席令牌")
+	}
+
+	// 验证坐席辅助配置
+	if err := config.Assist.Validate(); err != nil {
+		return fmt.Errorf("assist.%v", err)
+	}
+
+	// 验证录音归档配置
+	if config.Recording.Enabled && config.Recording.Dir == "" {
+		return fmt.Errorf("recording.dir: 启用录音归档时必须配置录音目录")
+	}
+	if !strings.Contains(config.Recording.Filename, "{uuid}") {
+		return fmt.Errorf("recording.filename: 必须包含{uuid}，避免不同通话的录音互相覆盖")
+	}
+	if err := config.Recording.Consent.Validate(); err != nil {
+		return fmt.Errorf("recording.consent.%v", err)
+	}
+	switch config.Recording.Codec.Format {
+	case "", codec.FormatWAV, codec.FormatFLAC, codec.FormatOpus:
+	default:
+		return fmt.Errorf("recording.codec.format: 不支持的录音格式 %s", config.Recording.Codec.Format)
+	}
+	for _, speaker := range []string{config.Recording.LeftSpeaker, config.Recording.RightSpeaker} {
+		switch speaker {
+		case models.SpeakerAgent, models.SpeakerCustomer, models.SpeakerAI:
+		default:
+			return fmt.Errorf("recording: 不支持的说话方 %s", speaker)
+		}
+	}
+
+	// 验证音频流配置
+	if config.AudioStream.Enabled && (config.AudioStream.BaseURL == "" || config.AudioStream.Secret == "") {
+		return fmt.Errorf("audio_stream: 启用音频流时必须配置base_url和secret")
+	}
+	if config.AudioStream.MaxRestarts < 0 {
+		return fmt.Errorf("audio_stream: max_restarts不能为负数")
+	}
+	if rate := config.AudioStream.SampleRate; rate != 0 && rate != 8000 && rate != 16000 {
+		return fmt.Errorf("audio_stream: sample_rate只支持8000或16000")
+	}
+	if _, err := codec.ParseEncoding(config.AudioStream.Codec); err != nil {
+		return fmt.Errorf("audio_stream.codec: %v", err)
+	}
+
+	// 验证语音识别降级配置
+	if err := config.ASRFallback.Validate(); err != nil {
+		return fmt.Errorf("asr_fallback.%v", err)
+	}
+
+	// 验证外呼路由配置
+	if config.Routing.MinASR < 0 || config.Routing.MinASR > 1 {
+		return fmt.Errorf("routing.min_asr: 必须在0到1之间")
+	}
+
+	// 验证号码状态查询配置
+	if config.HLR.Enabled && config.HLR.URL == "" {
+		return fmt.Errorf("number_lookup.url: 启用号码状态查询时必须配置查询地址")
+	}
+	for value, status := range config.HLR.StatusMap {
+		if status != models.NumberStatusActive && status != models.NumberStatusDisconnected && status != models.NumberStatusUnknown {
+			return fmt.Errorf("number_lookup.status_map.%s: 不支持的号码状态 %s", value, status)
+		}
+	}
+
+	if err := config.PromptAudio.Validate(); err != nil {
+		return fmt.Errorf("prompt_audio.%v", err)
+	}
+
+	if err := config.Redaction.Validate(); err != nil {
+		return fmt.Errorf("redaction.%v", err)
+	}
+
+	// 验证静态加密配置
+	if err := config.Encryption.Validate(); err != nil {
+		return fmt.Errorf("encryption.%v", err)
+	}
+	if config.Encryption.RekeySchedule != "" {
+		if _, err := cron.Parse(config.Encryption.RekeySchedule); err != nil {
+			return fmt.Errorf("encryption.rekey_schedule: %v", err)
+		}
+	}
+
+	// 验证外呼免打扰时段配置
+	if err := config.Campaign.QuietHours.Validate(); err != nil {
+		return fmt.Errorf("campaign.quiet_hours.%v", err)
+	}
+	if err := config.Campaign.AutoThrottle.Validate(); err != nil {
+		return fmt.Errorf("campaign.auto_throttle.%v", err)
+	}
+	if err := config.Campaign.Preview.Validate(); err != nil {
+		return fmt.Errorf("campaign.preview.%v", err)
+	}
+	if err := config.Campaign.Metrics.Validate(); err != nil {
+		return fmt.Errorf("campaign.metrics.%v", err)
+	}
+
+	// 验证客户情绪识别配置
+	if err := config.Transcript.Sentiment.Validate(); err != nil {
+		return fmt.Errorf("transcript.sentiment.%v", err)
+	}
+
+	// 验证自动质检配置
+	if err := config.QA.Validate(); err != nil {
+		return fmt.Errorf("qa.%v", err)
+	}
+
+	// 验证训练数据导出配置
+	if err := config.Dataset.Validate(); err != nil {
+		return fmt.Errorf("dataset.%v", err)
+	}
+	if config.Dataset.Schedule != "" {
+		if _, err := cron.Parse(config.Dataset.Schedule); err != nil {
+			return fmt.Errorf("dataset.schedule: %v", err)
+		}
+	}
+
+	// 验证定时任务配置
+	if err := config.Cron.Validate(); err != nil {
+		return fmt.Errorf("cron.%v", err)
+	}
+
+	// 验证进程生命周期配置
+	if err := config.Intent.Validate(); err != nil {
+		return fmt.Errorf("intent.%v", err)
+	}
+	if err := config.DTMF.Validate(); err != nil {
+		return fmt.Errorf("dtmf.%v", err)
+	}
+	if err := config.Lifecycle.Validate(); err != nil {
+		return fmt.Errorf("lifecycle.%v", err)
+	}
+
+	if err := config.Scripting.Validate(); err != nil {
+		return fmt.Errorf("scripting.%v", err)
+	}
+
+	// 验证挂机收尾动作
+	if _, err := wrapup.New(config.WrapUp, config.Webhook.CRMURL); err != nil {
+		return err
+	}
+
+	// 验证语音合成风格
+	if _, ok := config.TTS.Styles[config.TTS.DefaultStyle]; config.TTS.DefaultStyle != "" && !ok {
+		return fmt.Errorf("tts.default_style: 未定义的语气风格 %s", config.TTS.DefaultStyle)
+	}
+	for campaignID, style := range config.TTS.Campaigns {
+		if _, ok := config.TTS.Styles[style]; !ok {
+			return fmt.Errorf("tts.campaigns.%s: 未定义的语气风格 %s", campaignID, style)
+		}
+	}
+
+	// 验证大模型生成参数
+	if err := config.LLM.Options.Validate(); err != nil {
+		return fmt.Errorf("llm.options: %v", err)
+	}
+	if err := config.LLM.Prompt.Validate(); err != nil {
+		return fmt.Errorf("llm.prompt.%v", err)
+	}
+	if err := config.LLM.Guardrail.Validate(); err != nil {
+		return fmt.Errorf("llm.guardrail.%v", err)
+	}
+	if err := config.LLM.Cache.Validate(); err != nil {
+		return fmt.Errorf("llm.cache.%v", err)
+	}
+	if err := config.LLM.History.Validate(); err != nil {
+		return fmt.Errorf("llm.history.%v", err)
+	}
+	if err := config.LLM.Tools.Validate(); err != nil {
+		return fmt.Errorf("llm.tools.%v", err)
+	}
+	hasPersona := func(name string) bool {
+		_, ok := config.LLM.Prompt.Profiles[name]
+		return ok
+	}
+	if err := config.Inbound.Validate(hasPersona); err != nil {
+		return fmt.Errorf("inbound.%v", err)
+	}
+	if err := config.Tenancy.Validate(hasPersona); err != nil {
+		return fmt.Errorf("tenancy.%v", err)
+	}
+	for campaignID, overrides := range config.LLM.Campaigns {
+		if err := overrides.Apply(config.LLM.Options).Validate(); err != nil {
+			return fmt.Errorf("llm.campaigns.%s: %v", campaignID, err)
+		}
+	}
 
 	return nil
 }
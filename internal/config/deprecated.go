@@ -0,0 +1,48 @@
+package config
+
+import (
+	"log"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/clients/xfyun"
+)
+
+// applyDeprecated 将旧版顶层配置项（xfyun、ollama、dialog）映射到新的asr/llm配置段
+// 新旧配置同时存在时以新配置为准，每个旧配置项都会输出废弃警告
+func applyDeprecated(config *Config) {
+	if old := config.DeprecatedXFYun; old != nil {
+		if config.ASR.XFYun == (xfyun.Config{}) {
+			log.Println("警告: 配置项 xfyun 已废弃，请改用 asr.xfyun")
+			config.ASR.XFYun = xfyun.Config{
+				AppID:             old.AppID,
+				APIKey:            old.APIKey,
+				APISecret:         old.APISecret,
+				ServerURL:         old.ServerURL,
+				MaxRetries:        old.MaxRetries,
+				ReconnectInterval: time.Duration(old.ReconnectInterval) * time.Second,
+			}
+		} else {
+			log.Println("警告: 配置项 xfyun 已废弃，已被 asr.xfyun 覆盖")
+		}
+	}
+
+	if old := config.DeprecatedOllama; old != nil {
+		if config.LLM.Ollama == (ollama.Config{}) {
+			log.Println("警告: 配置项 ollama 已废弃，请改用 llm.ollama")
+			config.LLM.Ollama = *old
+		} else {
+			log.Println("警告: 配置项 ollama 已废弃，已被 llm.ollama 覆盖")
+		}
+	}
+
+	if old := config.DeprecatedDialog; old != nil {
+		log.Println("警告: 配置项 dialog 已废弃，请改用 llm.provider 和 llm.mock")
+		if config.LLM.Provider == "" {
+			config.LLM.Provider = old.Provider
+		}
+		if old.Mock != nil && len(config.LLM.Mock.Rules) == 0 && config.LLM.Mock.Default == "" {
+			config.LLM.Mock = *old.Mock
+		}
+	}
+}
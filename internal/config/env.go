@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀，如 AI_DIALER_ASR_XFYUN_API_KEY 覆盖 asr.xfyun.api_key
+const EnvPrefix = "AI_DIALER_"
+
+// envPattern 配置值中的环境变量引用：${NAME} 或 ${NAME:-默认值}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandEnv 替换配置值中的环境变量引用：${NAME} 要求变量已设置，${NAME:-默认值} 在变量未设置或为空时使用默认值。
+// 替换在YAML解析之后按值进行，变量内容中的引号、冒号等字符不会破坏配置格式；未加引号的值替换后重新推断类型，
+// 因此 port: ${PORT} 可以填写数字
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "${") {
+			return nil
+		}
+		var missing []string
+		node.Value = envPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			m := envPattern.FindStringSubmatch(ref)
+			if value := os.Getenv(m[1]); value != "" {
+				return value
+			}
+			if m[2] == "" {
+				missing = append(missing, m[1])
+			}
+			return m[3]
+		})
+		if len(missing) > 0 {
+			return fmt.Errorf("第%d行: 环境变量 %s 未设置", node.Line, strings.Join(missing, ", "))
+		}
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshalerType 自定义YAML解析的类型，按单个值覆盖
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// applyEnvOverrides 用 AI_DIALER_ 开头的环境变量覆盖配置项，变量名为配置路径的大写形式，层级之间用下划线连接，
+// 如 AI_DIALER_FREESWITCH_PASSWORD、AI_DIALER_SERVER_PORT；列表填写逗号分隔的值，map和结构体列表不支持覆盖
+func applyEnvOverrides(config *Config) error {
+	return overrideStruct(reflect.ValueOf(config).Elem(), strings.TrimSuffix(EnvPrefix, "_"))
+}
+
+// overrideStruct 逐个字段查找对应的环境变量，嵌套结构体递归处理
+func overrideStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "inline") {
+			if fv.Kind() == reflect.Struct {
+				if err := overrideStruct(fv, prefix); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := prefix + "_" + strings.ToUpper(name)
+		if fv.Kind() == reflect.Struct && !reflect.PointerTo(fv.Type()).Implements(unmarshalerType) {
+			if err := overrideStruct(fv, key); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setField(fv, value); err != nil {
+			return fmt.Errorf("环境变量 %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// setField 按字段类型解析环境变量的值
+func setField(fv reflect.Value, value string) error {
+	switch {
+	case reflect.PointerTo(fv.Type()).Implements(unmarshalerType):
+		// 自定义解析的类型按YAML值解析
+	case fv.Kind() == reflect.String:
+		fv.SetString(value)
+		return nil
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		items := reflect.MakeSlice(fv.Type(), 0, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(fv.Type().Elem()))
+			}
+		}
+		fv.Set(items)
+		return nil
+	case fv.Kind() == reflect.Map, fv.Kind() == reflect.Slice, fv.Kind() == reflect.Pointer:
+		return fmt.Errorf("不支持通过环境变量覆盖")
+	}
+	target := reflect.New(fv.Type())
+	if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return fmt.Errorf("无效的值 %q: %v", value, err)
+	}
+	fv.Set(target.Elem())
+	return nil
+}
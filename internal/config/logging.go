@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SaveLogLevels 将日志级别写回配置文件的logging.level和logging.levels，
+// 按节点修改以保留文件中的其他配置项和注释
+func SaveLogLevels(filename, level string, levels map[string]string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("配置文件格式无效")
+	}
+
+	logging := mappingValue(doc.Content[0], "logging")
+	setMappingValue(logging, "level", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: level})
+
+	names := make([]string, 0, len(levels))
+	for name := range levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	levelsNode := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, name := range names {
+		levelsNode.Content = append(levelsNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: levels[name]})
+	}
+	setMappingValue(logging, "levels", levelsNode)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("生成配置文件失败: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("生成配置文件失败: %v", err)
+	}
+
+	// 先写临时文件再替换，避免写入中断损坏配置文件
+	info, err := os.Stat(filename)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// mappingValue 返回映射节点中键对应的映射节点，不存在时追加
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.MappingNode {
+			return node.Content[i+1]
+		}
+	}
+	value := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setMappingValue(node, key, value)
+	return value
+}
+
+// setMappingValue 设置映射节点中键的值，保留原键上的注释
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			old := node.Content[i+1]
+			value.LineComment = old.LineComment
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 进程角色，同一程序按角色只运行对应的部分，以便分别扩容
+const (
+	RoleAPI    = "api"    // HTTP管理和查询接口：发起呼叫、外呼任务、转写、质检、录音下载等
+	RoleMedia  = "media"  // 通话媒体处理：FreeSWITCH事件、通话音频流、语音识别和合成的WebSocket
+	RoleWorker = "worker" // 后台任务：发件箱投递、外呼拨号、自动质检、定时任务
+	RoleAll    = "all"    // 运行全部角色
+)
+
+// allRoles 全部角色，按固定顺序输出
+var allRoles = []string{RoleAPI, RoleMedia, RoleWorker}
+
+// Roles 进程运行的角色集合
+type Roles map[string]bool
+
+// ParseRoles 解析逗号分隔的角色列表，如 "api,worker"；为空或为all时运行全部角色
+func ParseRoles(value string) (Roles, error) {
+	roles := make(Roles)
+	if strings.TrimSpace(value) == "" {
+		value = RoleAll
+	}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+		case RoleAll:
+			for _, role := range allRoles {
+				roles[role] = true
+			}
+		case RoleAPI, RoleMedia, RoleWorker:
+			roles[name] = true
+		default:
+			return nil, fmt.Errorf("未知的进程角色: %s，可选 api、media、worker、all", name)
+		}
+	}
+	return roles, nil
+}
+
+// Has 是否运行指定角色
+func (r Roles) Has(role string) bool {
+	return r[role]
+}
+
+// String 逗号分隔的角色列表
+func (r Roles) String() string {
+	var names []string
+	for _, role := range allRoles {
+		if r[role] {
+			names = append(names, role)
+		}
+	}
+	return strings.Join(names, ",")
+}
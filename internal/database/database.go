@@ -0,0 +1,82 @@
+// Package database 关系数据库驱动选择与SQL方言：默认使用MySQL，单机试点可改用SQLite，
+// 无需部署外部数据库。仓储、发件箱和迁移器按方言生成少量MySQL特有的语句
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect 数据库驱动及对应的SQL方言，零值按MySQL处理
+type Dialect string
+
+// 支持的数据库驱动
+const (
+	MySQL  Dialect = "mysql"  // MySQL，使用mysql配置段的连接参数
+	SQLite Dialect = "sqlite" // SQLite单文件数据库，适合单机部署，不支持多实例共享
+)
+
+// DefaultSQLitePath SQLite数据库文件的默认路径
+const DefaultSQLitePath = "data/ai_dialer.db"
+
+// Config 数据库驱动配置
+type Config struct {
+	Driver Dialect      `yaml:"driver"` // 数据库驱动，mysql或sqlite，默认mysql
+	SQLite SQLiteConfig `yaml:"sqlite"` // SQLite配置
+}
+
+// SQLiteConfig SQLite配置
+type SQLiteConfig struct {
+	Path string `yaml:"path"` // 数据库文件路径，目录不存在时自动创建
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	switch c.Driver {
+	case "", MySQL:
+	case SQLite:
+		if strings.TrimSpace(c.SQLite.Path) == "" {
+			return fmt.Errorf("sqlite.path: 不能为空")
+		}
+	default:
+		return fmt.Errorf("driver: 不支持的数据库驱动: %s", c.Driver)
+	}
+	return nil
+}
+
+// IsSQLite 是否为SQLite方言
+func (d Dialect) IsSQLite() bool {
+	return d == SQLite
+}
+
+// InsertIgnore 忽略唯一键冲突的插入语句开头
+func (d Dialect) InsertIgnore() string {
+	if d.IsSQLite() {
+		return "INSERT OR IGNORE"
+	}
+	return "INSERT IGNORE"
+}
+
+// ForUpdate 锁定查询结果行的后缀；SQLite的写事务本身互斥（以immediate方式开启），不需要行锁
+func (d Dialect) ForUpdate() string {
+	if d.IsSQLite() {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// ForUpdateSkipLocked 锁定查询结果行并跳过其他事务已锁定行的后缀，用于多实例领取任务
+func (d Dialect) ForUpdateSkipLocked() string {
+	if d.IsSQLite() {
+		return ""
+	}
+	return " FOR UPDATE SKIP LOCKED"
+}
+
+// MicrosecondsBetween 两个时间列之间相差的微秒数表达式；SQLite按文本保存时间，精度为毫秒
+func (d Dialect) MicrosecondsBetween(from, to string) string {
+	if d.IsSQLite() {
+		return fmt.Sprintf("CAST(ROUND((julianday(%s) - julianday(%s)) * 86400000) AS INTEGER) * 1000", to, from)
+	}
+	return fmt.Sprintf("TIMESTAMPDIFF(MICROSECOND, %s, %s)", from, to)
+}
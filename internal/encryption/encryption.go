@@ -0,0 +1,242 @@
+// Package encryption 通话转写和对话历史的静态加密：采用信封加密，每条数据使用随机生成的数据密钥以AES-256-GCM加密，
+// 数据密钥再用租户的主密钥加密后与密文一起保存；轮换主密钥后旧数据仍可解密，只需重新加密数据密钥即可改用新主密钥
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix 加密数据的前缀，不带前缀的数据视为加密启用前写入的明文
+const prefix = "enc:v1:"
+
+// 密钥长度
+const (
+	keySize   = 32 // AES-256
+	nonceSize = 12
+)
+
+var (
+	// ErrUnknownKey 数据使用的主密钥未配置
+	ErrUnknownKey = errors.New("未配置数据使用的主密钥")
+	// ErrMalformed 加密数据格式错误或已被篡改
+	ErrMalformed = errors.New("加密数据格式错误")
+)
+
+// Config 静态加密配置
+type Config struct {
+	Enabled       bool                  `yaml:"enabled"`
+	Tenant        string                `yaml:"tenant"`         // 本实例加密新数据使用的租户
+	Tenants       map[string]TenantKeys `yaml:"tenants"`        // 各租户的主密钥，解密时按数据记录的租户和密钥ID查找
+	RekeySchedule string                `yaml:"rekey_schedule"` // 用当前主密钥重新加密转写数据密钥的cron表达式，为空时不自动执行
+}
+
+// TenantKeys 租户的主密钥
+type TenantKeys struct {
+	Active string            `yaml:"active"` // 加密新数据使用的主密钥ID
+	Keys   map[string]string `yaml:"keys"`   // 主密钥ID与base64编码的32字节密钥，轮换后保留旧密钥用于解密
+}
+
+// Validate 校验静态加密配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := New(c)
+	return err
+}
+
+// Keyring 按租户管理的主密钥，可在nil上调用，此时不加密
+type Keyring struct {
+	tenant  string
+	active  string
+	tenants map[string]map[string]cipher.AEAD
+}
+
+// New 解析主密钥，当前租户或其启用的主密钥未配置时返回错误
+func New(config Config) (*Keyring, error) {
+	k := &Keyring{
+		tenant:  config.Tenant,
+		tenants: make(map[string]map[string]cipher.AEAD, len(config.Tenants)),
+	}
+	for tenant, keys := range config.Tenants {
+		if tenant == "" || strings.Contains(tenant, ":") {
+			return nil, fmt.Errorf("tenants.%s: 租户名称不能为空或包含冒号", tenant)
+		}
+		aeads := make(map[string]cipher.AEAD, len(keys.Keys))
+		for id, encoded := range keys.Keys {
+			if id == "" || strings.Contains(id, ":") {
+				return nil, fmt.Errorf("tenants.%s.keys.%s: 密钥ID不能为空或包含冒号", tenant, id)
+			}
+			key, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(key) != keySize {
+				return nil, fmt.Errorf("tenants.%s.keys.%s: 密钥应为base64编码的%d字节", tenant, id, keySize)
+			}
+			aead, err := newAEAD(key)
+			if err != nil {
+				return nil, fmt.Errorf("tenants.%s.keys.%s: %v", tenant, id, err)
+			}
+			aeads[id] = aead
+		}
+		if _, ok := aeads[keys.Active]; !ok {
+			return nil, fmt.Errorf("tenants.%s.active: 未配置主密钥 %s", tenant, keys.Active)
+		}
+		k.tenants[tenant] = aeads
+		if tenant == config.Tenant {
+			k.active = keys.Active
+		}
+	}
+	if _, ok := k.tenants[config.Tenant]; !ok {
+		return nil, fmt.Errorf("tenant: 未配置租户 %s 的主密钥", config.Tenant)
+	}
+	return k, nil
+}
+
+// newAEAD 创建AES-GCM
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密算法失败: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncrypted 数据是否为加密格式
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 用新生成的数据密钥加密明文，数据密钥用当前租户启用的主密钥加密
+// 格式为 enc:v1:租户:密钥ID:加密的数据密钥:加密的数据，未启用加密（nil）时原样返回
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %v", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	payload, err := seal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return k.wrap(dataKey, payload)
+}
+
+// wrap 用当前租户启用的主密钥加密数据密钥，租户和密钥ID作为附加数据防止替换
+func (k *Keyring) wrap(dataKey, payload []byte) (string, error) {
+	header := k.tenant + ":" + k.active
+	wrapped, err := seal(k.tenants[k.tenant][k.active], dataKey, []byte(header))
+	if err != nil {
+		return "", err
+	}
+	return prefix + header + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(payload), nil
+}
+
+// Decrypt 解密数据，不是加密格式的数据原样返回；未启用加密（nil）时遇到加密数据返回ErrUnknownKey
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	e, err := k.parse(value)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(e.dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, e.payload, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 用当前租户启用的主密钥重新加密数据密钥，数据本身不重新加密；明文数据加密后返回
+// changed为false表示数据已使用当前主密钥，无需更新
+func (k *Keyring) Rewrap(value string) (rewrapped string, changed bool, err error) {
+	if k == nil {
+		return value, false, nil
+	}
+	if !IsEncrypted(value) {
+		rewrapped, err = k.Encrypt(value)
+		return rewrapped, err == nil, err
+	}
+	e, err := k.parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if e.tenant == k.tenant && e.keyID == k.active {
+		return value, false, nil
+	}
+	rewrapped, err = k.wrap(e.dataKey, e.payload)
+	return rewrapped, err == nil, err
+}
+
+// envelope 解析后的加密数据
+type envelope struct {
+	tenant  string
+	keyID   string
+	dataKey []byte
+	payload []byte
+}
+
+// parse 解析加密数据并解密数据密钥
+func (k *Keyring) parse(value string) (*envelope, error) {
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 4 {
+		return nil, ErrMalformed
+	}
+	e := &envelope{tenant: parts[0], keyID: parts[1]}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if e.payload, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return nil, ErrMalformed
+	}
+
+	var master cipher.AEAD
+	if k != nil {
+		master = k.tenants[e.tenant][e.keyID]
+	}
+	if master == nil {
+		return nil, fmt.Errorf("%w: %s/%s", ErrUnknownKey, e.tenant, e.keyID)
+	}
+	if e.dataKey, err = open(master, wrapped, []byte(e.tenant+":"+e.keyID)); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// seal 加密，输出为随机nonce加密文
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open 解密seal的输出
+func open(aead cipher.AEAD, data, additional []byte) ([]byte, error) {
+	if len(data) < nonceSize {
+		return nil, ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, data[:nonceSize], data[nonceSize:], additional)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnnotationHandler 通话标签和备注HTTP处理器
+type AnnotationHandler struct {
+	annotationService *services.AnnotationService
+}
+
+// NewAnnotationHandler 创建通话标签和备注处理器
+func NewAnnotationHandler(annotationService *services.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{annotationService: annotationService}
+}
+
+// Annotate 为通话添加标签和带时间点的备注，通话进行中或结束后均可添加
+func (h *AnnotationHandler) Annotate(c *gin.Context) {
+	var req models.AnnotateCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+
+	annotations, err := h.annotationService.Annotate(c.Request.Context(), c.Param("uuid"), req)
+	if errors.Is(err, services.ErrInvalidAnnotation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("添加通话标签失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加通话标签失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, annotations)
+}
+
+// List 查询通话的全部标签和备注
+func (h *AnnotationHandler) List(c *gin.Context) {
+	annotations, err := h.annotationService.Get(c.Request.Context(), c.Param("uuid"))
+	if err != nil {
+		log.Printf("查询通话标签失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通话标签失败"})
+		return
+	}
+	c.JSON(http.StatusOK, annotations)
+}
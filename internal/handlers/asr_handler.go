@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"log"
-	"net/http"
 	"sync"
 
+	"ai_dialer_mini/internal/config"
 	"ai_dialer_mini/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,9 +12,7 @@ import (
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin: config.CheckWebSocketOrigin,
 }
 
 // ASRHandler WebSocket ASR 处理器
@@ -41,10 +39,15 @@ func (h *ASRHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// 生成会话ID
+	// 获取会话ID，客户端未提供时生成新的会话ID并在第一条消息中返回
 	sessionID := c.Query("session_id")
 	if sessionID == "" {
-		sessionID = "default" // 如果没有提供会话ID，使用默认值
+		sessionID = models.NewSessionID()
+	}
+	if err := conn.WriteJSON(models.NewSessionMessage(sessionID)); err != nil {
+		log.Printf("发送会话ID失败: %v", err)
+		conn.Close()
+		return
 	}
 
 	// 注册客户端
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ASRPoolHandler 讯飞连接预热池HTTP处理器
+type ASRPoolHandler struct {
+	pool *xfyun.ConnPool
+}
+
+// NewASRPoolHandler 创建讯飞连接预热池处理器
+func NewASRPoolHandler(pool *xfyun.ConnPool) *ASRPoolHandler {
+	return &ASRPoolHandler{pool: pool}
+}
+
+// Stats 查询连接预热池的空闲连接数、命中率和节省的握手耗时
+func (h *ASRPoolHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pool.Stats())
+}
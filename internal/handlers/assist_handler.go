@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services/assist"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// AssistHandler 坐席辅助处理器
+type AssistHandler struct {
+	hub      *assist.Hub
+	upgrader websocket.Upgrader
+}
+
+// NewAssistHandler 创建坐席辅助处理器
+func NewAssistHandler(hub *assist.Hub) *AssistHandler {
+	return &AssistHandler{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: config.CheckWebSocketOrigin,
+		},
+	}
+}
+
+// Subscribe 以WebSocket文本消息实时推送通话的坐席辅助消息：客户转写（transcript）、
+// 相关知识片段（knowledge）和建议话术（suggestion），只推送订阅之后产生的消息
+func (h *AssistHandler) Subscribe(c *gin.Context) {
+	callID := c.Param("uuid")
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("升级WebSocket连接失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	messages, cancel := h.hub.Subscribe(callID)
+	defer cancel()
+	log.Printf("坐席开始接收辅助信息: %s", callID)
+
+	// 读取循环只用于感知客户端断开
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			log.Printf("坐席停止接收辅助信息: %s", callID)
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ai_dialer_mini/internal/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheHandler 缓存统计HTTP处理器
+type CacheHandler struct{}
+
+// NewCacheHandler 创建缓存统计处理器
+func NewCacheHandler() *CacheHandler {
+	return &CacheHandler{}
+}
+
+// Stats 查询各进程内缓存的条目数、命中率和淘汰次数
+func (h *CacheHandler) Stats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"caches": cache.All()})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallDialog 通话中干预AI对话
+type CallDialog interface {
+	Say(ctx context.Context, callUUID, text string) error
+	InjectContext(callUUID, text string) error
+}
+
+// CallDialogHandler 通话中对话干预处理器，供坐席或外部系统让AI说出指定话术、补充背景信息
+type CallDialogHandler struct {
+	dialog CallDialog
+}
+
+// NewCallDialogHandler 创建通话中对话干预处理器
+func NewCallDialogHandler(dialog CallDialog) *CallDialogHandler {
+	return &CallDialogHandler{dialog: dialog}
+}
+
+// CallTextRequest 通话中对话干预请求
+type CallTextRequest struct {
+	Text string `json:"text" binding:"required"` // 让AI说出的话术或补充的背景信息
+}
+
+// Say 让AI在通话中说出指定话术
+func (h *CallDialogHandler) Say(c *gin.Context) {
+	var req CallTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+	if err := h.dialog.Say(c.Request.Context(), c.Param("uuid"), req.Text); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "spoken"})
+}
+
+// InjectContext 向通话的对话上下文补充背景信息，对之后的AI回复生效
+func (h *CallDialogHandler) InjectContext(c *gin.Context) {
+	var req CallTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+	if err := h.dialog.InjectContext(c.Param("uuid"), req.Text); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "injected"})
+}
+
+// fail 返回错误响应，通话未接入音频流时返回404
+func (h *CallDialogHandler) fail(c *gin.Context, err error) {
+	if errors.Is(err, ws.ErrCallNotStreaming) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/fallback"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallHandler 呼叫控制HTTP处理器
+type CallHandler struct {
+	callService  services.CallService
+	idempotency  *services.IdempotencyService
+	reachability *services.ReachabilityService
+	fallback     *fallback.Monitor
+	leads        fallback.LeadScheduler
+	lifecycle    *lifecycle.Manager
+}
+
+// NewCallHandler 创建呼叫控制处理器，idempotency为nil时不支持Idempotency-Key
+func NewCallHandler(callService services.CallService, idempotency *services.IdempotencyService) *CallHandler {
+	return &CallHandler{
+		callService: callService,
+		idempotency: idempotency,
+	}
+}
+
+// SetReachability 设置拨打前号码状态检查，空号或停机号码不再发起呼叫
+func (h *CallHandler) SetReachability(reachability *services.ReachabilityService) {
+	h.reachability = reachability
+}
+
+// SetASRFallback 设置语音识别降级策略，识别服务不可用时按活动暂停拨号、转人工或致歉后回拨；leads为nil时不安排回拨
+func (h *CallHandler) SetASRFallback(monitor *fallback.Monitor, leads fallback.LeadScheduler) {
+	h.fallback = monitor
+	h.leads = leads
+}
+
+// SetLifecycle 设置进程生命周期，排空期间拒绝发起新的呼叫
+func (h *CallHandler) SetLifecycle(manager *lifecycle.Manager) {
+	h.lifecycle = manager
+}
+
+// Originate 发起呼叫
+// 请求携带Idempotency-Key时，窗口期内的重复提交直接返回首次创建的呼叫，不会重复拨打客户
+func (h *CallHandler) Originate(c *gin.Context) {
+	if !h.lifecycle.Accepting() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务正在排空，不接受新的呼叫"})
+		return
+	}
+	var req models.OriginateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.Tenant != "" {
+		ctx = tenant.WithID(ctx, req.Tenant)
+	}
+	if req.ASR != "" {
+		ctx = services.WithASRProvider(ctx, req.ASR)
+	}
+	originate := func() (interface{}, error) {
+		if _, err := h.reachability.CheckNumber(ctx, req.To); err != nil {
+			return nil, err
+		}
+		if policy, degraded := h.fallback.Policy(req.CampaignID); degraded {
+			return h.originateFallback(ctx, req, policy)
+		}
+		callID, err := h.callService.InitiateCall(ctx, req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+		return models.CallInfo{
+			CallID:    callID,
+			From:      req.From,
+			To:        req.To,
+			CreatedAt: time.Now(),
+		}, nil
+	}
+
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		call, err := originate()
+		if errors.Is(err, services.ErrInvalidCallCommand) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrNumberUnreachable) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, fallback.ErrDialingPaused) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, call)
+		return
+	}
+
+	if h.idempotency == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "幂等存储不可用，无法处理Idempotency-Key"})
+		return
+	}
+
+	var call models.CallInfo
+	replayed, err := h.idempotency.Do(c.Request.Context(), key, requestFingerprint(req), &call, originate)
+	switch {
+	case errors.Is(err, services.ErrIdempotencyInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrIdempotencyMismatch), errors.Is(err, services.ErrNumberUnreachable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, fallback.ErrDialingPaused):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("发起呼叫失败: %v", err)
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, call)
+		return
+	}
+	c.JSON(http.StatusCreated, call)
+}
+
+// Hangup 挂断通话
+func (h *CallHandler) Hangup(c *gin.Context) {
+	callUUID := c.Param("uuid")
+	if err := h.callService.EndCall(c.Request.Context(), callUUID); err != nil {
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"call_id": callUUID})
+}
+
+// Transfer 将通话转接到拨号计划中的分机或号码
+func (h *CallHandler) Transfer(c *gin.Context) {
+	var req models.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	controller, ok := h.callService.(services.CallController)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "通话服务不支持转接"})
+		return
+	}
+
+	callUUID := c.Param("uuid")
+	if err := controller.TransferCall(c.Request.Context(), callUUID, req.Destination, req.Context); err != nil {
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"call_id": callUUID, "destination": req.Destination})
+}
+
+// SendDTMF 向通话发送按键
+func (h *CallHandler) SendDTMF(c *gin.Context) {
+	var req models.DTMFRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	controller, ok := h.callService.(services.CallController)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "通话服务不支持发送按键"})
+		return
+	}
+
+	callUUID := c.Param("uuid")
+	duration := time.Duration(req.DurationMS) * time.Millisecond
+	if err := controller.SendDTMF(c.Request.Context(), callUUID, req.Digits, duration); err != nil {
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"call_id": callUUID, "digits": req.Digits})
+}
+
+// SetDisposition 话后处理期间修改通话结果
+func (h *CallHandler) SetDisposition(c *gin.Context) {
+	var req models.DispositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	controller, ok := h.callService.(services.WrapUpController)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "通话服务不支持话后处理"})
+		return
+	}
+
+	callUUID := c.Param("uuid")
+	if err := controller.SetDisposition(c.Request.Context(), callUUID, req.Disposition); err != nil {
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"call_id": callUUID, "disposition": req.Disposition})
+}
+
+// CompleteWrapUp 提前结束话后处理
+func (h *CallHandler) CompleteWrapUp(c *gin.Context) {
+	controller, ok := h.callService.(services.WrapUpController)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "通话服务不支持话后处理"})
+		return
+	}
+
+	callUUID := c.Param("uuid")
+	if err := controller.CompleteWrapUp(c.Request.Context(), callUUID); err != nil {
+		c.JSON(callCommandStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"call_id": callUUID})
+}
+
+// callCommandStatus 将呼叫控制错误转换为HTTP状态码
+func callCommandStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidCallCommand):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotInWrapUp):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrCallNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSwitchUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// originateFallback 语音识别不可用时按降级策略呼叫，致歉挂断的线索按策略稍后回拨
+func (h *CallHandler) originateFallback(ctx context.Context, req models.OriginateRequest, policy fallback.Policy) (interface{}, error) {
+	caller, ok := h.callService.(services.FallbackCaller)
+	if policy.Action == fallback.ActionPause || !ok {
+		return nil, fallback.ErrDialingPaused
+	}
+
+	callID, err := caller.InitiateFallbackCall(ctx, "", req.To, policy)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Action == fallback.ActionApology && req.LeadID != nil && h.leads != nil {
+		next := policy.CallbackAt(time.Now())
+		if err := h.leads.UpdateStatus(ctx, *req.LeadID, models.LeadStatusQueued, &next); err != nil {
+			log.Printf("安排线索回拨失败: %v", err)
+		}
+	}
+	return models.CallInfo{
+		CallID:    callID,
+		From:      req.From,
+		To:        req.To,
+		CreatedAt: time.Now(),
+		Fallback:  policy.Action,
+	}, nil
+}
+
+// requestFingerprint 计算发起呼叫请求的指纹
+func requestFingerprint(req models.OriginateRequest) string {
+	sum := sha256.Sum256([]byte(req.From + "\n" + req.To))
+	return hex.EncodeToString(sum[:])
+}
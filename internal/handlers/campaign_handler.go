@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/campaign"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CampaignHandler 外呼任务HTTP处理器
+type CampaignHandler struct {
+	manager *campaign.Manager
+}
+
+// NewCampaignHandler 创建外呼任务处理器
+func NewCampaignHandler(manager *campaign.Manager) *CampaignHandler {
+	return &CampaignHandler{manager: manager}
+}
+
+// Create 创建外呼任务及拨打名单，创建后为草稿状态，需调用start启动
+func (h *CampaignHandler) Create(c *gin.Context) {
+	var req models.CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item := &models.Campaign{
+		Name:                 req.Name,
+		CallerID:             req.CallerID,
+		PacingPerMinute:      req.PacingPerMinute,
+		MaxAttempts:          req.MaxAttempts,
+		RetryIntervalSeconds: req.RetryIntervalSeconds,
+		WebhookURL:           req.WebhookURL,
+		DialMode:             req.DialMode,
+		AgentExtension:       req.AgentExtension,
+	}
+	if err := h.manager.Create(c.Request.Context(), item, models.ToLeads(req.Leads)); err != nil {
+		log.Printf("创建外呼任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建外呼任务失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+// List 查询外呼任务
+// 查询参数: status 任务状态，留空返回全部
+func (h *CampaignHandler) List(c *gin.Context) {
+	campaigns, err := h.manager.List(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		log.Printf("查询外呼任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询外呼任务失败"})
+		return
+	}
+	if campaigns == nil {
+		campaigns = []*models.Campaign{}
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// Get 查询外呼任务及各状态的线索数
+func (h *CampaignHandler) Get(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	item, err := h.manager.Get(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// AddLeads 向外呼任务追加线索
+func (h *CampaignHandler) AddLeads(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	var req models.AddLeadsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.manager.AddLeads(c.Request.Context(), id, models.ToLeads(req.Leads)); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"added": len(req.Leads)})
+}
+
+// Start 启动或恢复外呼任务
+func (h *CampaignHandler) Start(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	if err := h.manager.Start(c.Request.Context(), id); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "status": models.CampaignStatusRunning})
+}
+
+// Pause 暂停外呼任务
+func (h *CampaignHandler) Pause(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	if err := h.manager.Pause(c.Request.Context(), id); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "status": models.CampaignStatusPaused})
+}
+
+// Previews 查询预览拨号任务中等待坐席确认的线索，next_attempt_at为确认截止时间
+func (h *CampaignHandler) Previews(c *gin.Context) {
+	id, ok := campaignID(c)
+	if !ok {
+		return
+	}
+	leads, err := h.manager.Previews(c.Request.Context(), id)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	if leads == nil {
+		leads = []*models.Lead{}
+	}
+	c.JSON(http.StatusOK, gin.H{"leads": leads})
+}
+
+// Confirm 坐席确认预览线索并发起呼叫，被叫接通后桥接到坐席分机
+// 请求体（可选）: agent 坐席分机，留空使用任务的默认坐席分机
+func (h *CampaignHandler) Confirm(c *gin.Context) {
+	id, leadID, ok := campaignLeadID(c)
+	if !ok {
+		return
+	}
+	var req models.ConfirmLeadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	lead, err := h.manager.Confirm(c.Request.Context(), id, leadID, req.Agent)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, lead)
+}
+
+// Skip 坐席跳过预览线索，线索按重拨间隔重新排队
+func (h *CampaignHandler) Skip(c *gin.Context) {
+	id, leadID, ok := campaignLeadID(c)
+	if !ok {
+		return
+	}
+	lead, err := h.manager.Skip(c.Request.Context(), id, leadID)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, lead)
+}
+
+// writeError 按错误类型返回状态码
+func (h *CampaignHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "外呼任务不存在"})
+	case errors.Is(err, campaign.ErrInvalidTransition), errors.Is(err, campaign.ErrNotPreviewing):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, campaign.ErrNoAgent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, campaign.ErrAssistUnsupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		log.Printf("处理外呼任务请求失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理外呼任务请求失败"})
+	}
+}
+
+// campaignID 解析路径中的任务ID，无效时直接返回400
+func campaignID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务ID无效"})
+		return 0, false
+	}
+	return id, true
+}
+
+// campaignLeadID 解析路径中的任务ID和线索ID，无效时直接返回400
+func campaignLeadID(c *gin.Context) (int64, int64, bool) {
+	id, ok := campaignID(c)
+	if !ok {
+		return 0, 0, false
+	}
+	leadID, err := strconv.ParseInt(c.Param("lead_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "线索ID无效"})
+		return 0, 0, false
+	}
+	return id, leadID, true
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ai_dialer_mini/internal/services/cron"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CronHandler 定时任务HTTP处理器
+type CronHandler struct {
+	scheduler *cron.Scheduler
+}
+
+// NewCronHandler 创建定时任务处理器
+func NewCronHandler(scheduler *cron.Scheduler) *CronHandler {
+	return &CronHandler{scheduler: scheduler}
+}
+
+// List 查询所有定时任务的调度和最近一次执行情况
+func (h *CronHandler) List(c *gin.Context) {
+	jobs := h.scheduler.Jobs()
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+// Trigger 立即执行一次定时任务，任务在后台执行，结果通过List查询
+func (h *CronHandler) Trigger(c *gin.Context) {
+	err := h.scheduler.Trigger(c.Param("name"))
+	switch {
+	case errors.Is(err, cron.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, cron.ErrJobRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"status": "started"})
+	}
+}
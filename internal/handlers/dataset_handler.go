@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/dataset"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatasetHandler 训练数据导出HTTP处理器
+type DatasetHandler struct {
+	exporter *dataset.Exporter
+}
+
+// NewDatasetHandler 创建训练数据导出处理器
+func NewDatasetHandler(exporter *dataset.Exporter) *DatasetHandler {
+	return &DatasetHandler{exporter: exporter}
+}
+
+// Export 按条件导出脱敏后的对话到服务端导出目录，返回文件路径和导出数量
+// 请求体: from/to 挂机时间范围(RFC3339); campaign_id; dispositions 通话结果列表; limit
+func (h *DatasetHandler) Export(c *gin.Context) {
+	var query models.DatasetQuery
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+			return
+		}
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "起始时间必须早于截止时间"})
+		return
+	}
+
+	export, err := h.exporter.Export(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("导出训练数据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出训练数据失败"})
+		return
+	}
+	log.Printf("训练数据已导出: %s, 对话 %d 条, 跳过 %d 通", export.File, export.Dialogs, export.Skipped)
+	c.JSON(http.StatusOK, export)
+}
@@ -3,9 +3,9 @@ package handlers
 import (
 	"encoding/json"
 	"log"
-	"net/http"
 	"sync"
 
+	"ai_dialer_mini/internal/config"
 	"ai_dialer_mini/internal/clients/ollama"
 	"ai_dialer_mini/internal/clients/xfyun"
 	"ai_dialer_mini/internal/models"
@@ -50,9 +50,7 @@ func NewDialogHandler(asrConfig xfyun.Config, ollamaConfig ollama.Config) *Dialo
 		asrClient:    asrClient,
 		ollamaClient: ollamaClient,
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
+			CheckOrigin: config.CheckWebSocketOrigin,
 		},
 		sessions: make(map[string]*DialogSession),
 	}
@@ -67,10 +65,15 @@ func (h *DialogHandler) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// 创建新的会话
+	// 创建新的会话，客户端未提供会话ID时生成新的会话ID并在第一条消息中返回
 	sessionID := c.Query("session_id")
 	if sessionID == "" {
-		sessionID = "default"
+		sessionID = models.NewSessionID()
+	}
+	if err := ws.WriteJSON(models.NewSessionMessage(sessionID)); err != nil {
+		log.Printf("发送会话ID失败: %v", err)
+		ws.Close()
+		return
 	}
 
 	session := &DialogSession{
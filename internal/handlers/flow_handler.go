@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"ai_dialer_mini/internal/services/flow"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// maxFlowSize 通话流程请求体的最大字节数
+const maxFlowSize = 1 << 20
+
+// FlowHandler 通话流程HTTP处理器
+type FlowHandler struct {
+	linter *flow.Linter
+}
+
+// NewFlowHandler 创建通话流程处理器
+func NewFlowHandler(linter *flow.Linter) *FlowHandler {
+	return &FlowHandler{linter: linter}
+}
+
+// Validate 上线前检查通话流程
+// 请求体: YAML或JSON格式的通话流程，包含 prompt/inbound/intent/dtmf/turn，与config.yaml中的同名配置一致
+func (h *FlowHandler) Validate(c *gin.Context) {
+	scenario, ok := bindScenario(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.linter.Lint(c.Request.Context(), scenario))
+}
+
+// Export 将通话流程导出为流程图描述
+// 请求体同Validate；查询参数: format mermaid(默认)/graphviz/json，json返回节点和连线
+func (h *FlowHandler) Export(c *gin.Context) {
+	scenario, ok := bindScenario(c)
+	if !ok {
+		return
+	}
+	graph := flow.BuildGraph(scenario)
+	format := c.Query("format")
+	if format == "json" {
+		c.JSON(http.StatusOK, graph)
+		return
+	}
+	text, err := graph.Render(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+}
+
+// bindScenario 解析请求体中的通话流程，失败时已写入错误响应
+func bindScenario(c *gin.Context) (flow.Scenario, bool) {
+	var scenario flow.Scenario
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxFlowSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("读取请求失败: %v", err)})
+		return scenario, false
+	}
+	if len(body) > maxFlowSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("通话流程超过%d字节", maxFlowSize)})
+		return scenario, false
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求体不能为空"})
+		return scenario, false
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(body))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("解析通话流程失败: %v", err)})
+		return scenario, false
+	}
+	return scenario, true
+}
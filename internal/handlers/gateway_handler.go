@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GatewayHandler 网关统计HTTP处理器
+type GatewayHandler struct {
+	gatewayService *services.GatewayService
+}
+
+// NewGatewayHandler 创建网关统计处理器
+func NewGatewayHandler(gatewayService *services.GatewayService) *GatewayHandler {
+	return &GatewayHandler{gatewayService: gatewayService}
+}
+
+// Stats 查询各网关的接通率(ASR)和平均通话时长(ACD)
+// 查询参数: window 统计窗口，如 1h、24h，默认使用配置的统计窗口; tag 只统计带该标签的通话
+func (h *GatewayHandler) Stats(c *gin.Context) {
+	var window time.Duration
+	if value := c.Query("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window参数无效"})
+			return
+		}
+		window = d
+	}
+
+	stats, err := h.gatewayService.Stats(c.Request.Context(), window, c.Query("tag"))
+	if err != nil {
+		log.Printf("查询网关统计失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询网关统计失败"})
+		return
+	}
+	if stats == nil {
+		stats = []*models.GatewayStats{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gateways": stats,
+		"routing":  h.gatewayService.Gateways(),
+	})
+}
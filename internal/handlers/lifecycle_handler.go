@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ai_dialer_mini/internal/services/lifecycle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LifecycleHandler 进程生命周期HTTP处理器：就绪检查和排空
+type LifecycleHandler struct {
+	manager *lifecycle.Manager
+}
+
+// NewLifecycleHandler 创建进程生命周期处理器
+func NewLifecycleHandler(manager *lifecycle.Manager) *LifecycleHandler {
+	return &LifecycleHandler{manager: manager}
+}
+
+// Ready 就绪检查，启动完成前和排空期间返回503，供Kubernetes就绪探针使用
+func (h *LifecycleHandler) Ready(c *gin.Context) {
+	status := h.manager.Status()
+	if !h.manager.Ready() {
+		c.JSON(http.StatusServiceUnavailable, status)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// DrainStatus 查询排空进度
+func (h *LifecycleHandler) DrainStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.manager.Status())
+}
+
+// Drain 开始排空，不再接受新的呼叫和连接；立即返回，进度通过DrainStatus查询
+func (h *LifecycleHandler) Drain(c *gin.Context) {
+	h.manager.Drain()
+	c.JSON(http.StatusAccepted, h.manager.Status())
+}
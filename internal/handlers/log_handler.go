@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogLevelHandler 日志级别管理HTTP处理器
+type LogLevelHandler struct {
+	configFile string
+}
+
+// NewLogLevelHandler 创建日志级别管理处理器，configFile为空时不支持写回配置文件
+func NewLogLevelHandler(configFile string) *LogLevelHandler {
+	return &LogLevelHandler{configFile: configFile}
+}
+
+// LogLevelRequest 修改日志级别请求
+type LogLevelRequest struct {
+	Level      string            `json:"level"`      // 全局日志级别，为空时保持不变
+	Subsystems map[string]string `json:"subsystems"` // 按子系统覆盖的日志级别，值为空时恢复使用全局级别
+	Persist    bool              `json:"persist"`    // 是否写回配置文件，重启后仍然生效
+}
+
+// Get 查询当前日志级别
+func (h *LogLevelHandler) Get(c *gin.Context) {
+	level, subsystems := logger.Levels()
+	c.JSON(http.StatusOK, gin.H{"level": level, "subsystems": subsystems})
+}
+
+// Update 运行时修改全局和子系统日志级别，无需重启，可选写回配置文件
+func (h *LogLevelHandler) Update(c *gin.Context) {
+	var req LogLevelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效"})
+		return
+	}
+	if req.Persist && h.configFile == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未配置配置文件路径，无法写回"})
+		return
+	}
+	if err := logger.SetLevels(req.Level, req.Subsystems); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	level, subsystems := logger.Levels()
+	log.Printf("日志级别已修改: 全局 %s, 子系统 %v", level, subsystems)
+	if req.Persist {
+		if err := config.SaveLogLevels(h.configFile, level, subsystems); err != nil {
+			log.Printf("写回日志级别失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "日志级别已生效，但写回配置文件失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"level": level, "subsystems": subsystems, "persisted": req.Persist})
+}
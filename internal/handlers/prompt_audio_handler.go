@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ai_dialer_mini/internal/services/promptaudio"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromptAudioHandler 预录提示音HTTP处理器
+type PromptAudioHandler struct {
+	library *promptaudio.Library
+}
+
+// NewPromptAudioHandler 创建预录提示音处理器
+func NewPromptAudioHandler(library *promptaudio.Library) *PromptAudioHandler {
+	return &PromptAudioHandler{library: library}
+}
+
+// Upload 上传提示音
+// 表单字段: name 提示音名称; text 提示音的文字内容; description 用途说明(可选); file 16位PCM的WAV文件
+func (h *PromptAudioHandler) Upload(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.library.MaxSize()+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("缺少提示音文件: %v", err)})
+		return
+	}
+	if file.Size > h.library.MaxSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("提示音文件超过%d字节", h.library.MaxSize())})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("读取提示音文件失败: %v", err)})
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("读取提示音文件失败: %v", err)})
+		return
+	}
+
+	prompt, err := h.library.Upload(c.Request.Context(), promptaudio.Prompt{
+		Name:        c.PostForm("name"),
+		Text:        c.PostForm("text"),
+		Description: c.PostForm("description"),
+	}, data)
+	if errors.Is(err, promptaudio.ErrInvalidName) || errors.Is(err, promptaudio.ErrInvalidAudio) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("上传提示音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "上传提示音失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, prompt)
+}
+
+// List 查询所有提示音
+func (h *PromptAudioHandler) List(c *gin.Context) {
+	prompts, err := h.library.List(c.Request.Context())
+	if err != nil {
+		log.Printf("查询提示音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询提示音失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"prompts": prompts,
+		"count":   len(prompts),
+	})
+}
+
+// Get 查询提示音的元数据
+func (h *PromptAudioHandler) Get(c *gin.Context) {
+	prompt, err := h.library.Get(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, promptaudio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("查询提示音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询提示音失败"})
+		return
+	}
+	c.JSON(http.StatusOK, prompt)
+}
+
+// Download 下载转换后的提示音文件
+// 查询参数: rate 采样率，8000或16000(默认)
+func (h *PromptAudioHandler) Download(c *gin.Context) {
+	rate := 16000
+	if v := c.Query("rate"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || (n != 8000 && n != 16000) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate参数无效，只支持8000和16000"})
+			return
+		}
+		rate = n
+	}
+	audio, prompt, err := h.library.Load(c.Request.Context(), c.Param("name"), rate)
+	if errors.Is(err, promptaudio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("下载提示音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下载提示音失败"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s_%d.wav", prompt.Name, rate)))
+	c.Data(http.StatusOK, "audio/wav", audio.WAV())
+}
+
+// Delete 删除提示音
+func (h *PromptAudioHandler) Delete(c *gin.Context) {
+	err := h.library.Delete(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, promptaudio.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("删除提示音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除提示音失败"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/qa"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QAHandler 通话质检HTTP处理器
+type QAHandler struct {
+	evaluator *qa.Evaluator
+}
+
+// NewQAHandler 创建通话质检处理器
+func NewQAHandler(evaluator *qa.Evaluator) *QAHandler {
+	return &QAHandler{evaluator: evaluator}
+}
+
+// Evaluate 立即按评分表评估通话，覆盖之前的质检结果
+func (h *QAHandler) Evaluate(c *gin.Context) {
+	eval, err := h.evaluator.Evaluate(c.Request.Context(), c.Param("uuid"))
+	if errors.Is(err, qa.ErrNoTranscript) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("通话质检失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "通话质检失败"})
+		return
+	}
+	c.JSON(http.StatusOK, eval)
+}
+
+// Get 查询通话的质检结果
+func (h *QAHandler) Get(c *gin.Context) {
+	eval, err := h.evaluator.Get(c.Request.Context(), c.Param("uuid"))
+	if errors.Is(err, repositories.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通话尚未质检"})
+		return
+	}
+	if err != nil {
+		log.Printf("查询质检结果失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询质检结果失败"})
+		return
+	}
+	c.JSON(http.StatusOK, eval)
+}
+
+// Dashboard 汇总质检结果
+// 查询参数: from/to 评估时间范围(RFC3339或2006-01-02); campaign_id
+func (h *QAHandler) Dashboard(c *gin.Context) {
+	query, err := parseQADashboardQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dashboard, err := h.evaluator.Dashboard(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("汇总质检结果失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "汇总质检结果失败"})
+		return
+	}
+	c.JSON(http.StatusOK, dashboard)
+}
+
+// parseQADashboardQuery 解析质检汇总请求参数
+func parseQADashboardQuery(c *gin.Context) (models.QADashboardQuery, error) {
+	var q models.QADashboardQuery
+	var err error
+	if q.From, err = parseTimeParam(c.Query("from")); err != nil {
+		return q, fmt.Errorf("from参数无效: %v", err)
+	}
+	if q.To, err = parseTimeParam(c.Query("to")); err != nil {
+		return q, fmt.Errorf("to参数无效: %v", err)
+	}
+	if v := c.Query("campaign_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("campaign_id参数无效: %v", err)
+		}
+		q.CampaignID = &id
+	}
+	return q, nil
+}
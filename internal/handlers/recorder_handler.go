@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/recorder"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecorderHandler 飞行记录仪HTTP处理器
+type RecorderHandler struct {
+	recorder *recorder.Recorder
+}
+
+// NewRecorderHandler 创建飞行记录仪处理器
+func NewRecorderHandler(rec *recorder.Recorder) *RecorderHandler {
+	return &RecorderHandler{recorder: rec}
+}
+
+// Get 按通话UUID查询飞行记录
+func (h *RecorderHandler) Get(c *gin.Context) {
+	callUUID := c.Param("uuid")
+
+	entries, err := h.recorder.Get(c.Request.Context(), callUUID)
+	if errors.Is(err, recorder.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("读取飞行记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取飞行记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"call_uuid": callUUID,
+		"entries":   entries,
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecordingHandler 通话录音HTTP处理器
+type RecordingHandler struct {
+	recordingService *services.RecordingService
+}
+
+// NewRecordingHandler 创建通话录音处理器
+func NewRecordingHandler(recordingService *services.RecordingService) *RecordingHandler {
+	return &RecordingHandler{recordingService: recordingService}
+}
+
+// List 查询录音
+// 查询参数: from/to 挂断时间范围(RFC3339或2006-01-02); number 主叫或被叫号码; limit; offset
+func (h *RecordingHandler) List(c *gin.Context) {
+	query, err := parseRecordingQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	recordings, err := h.recordingService.List(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("查询录音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询录音失败"})
+		return
+	}
+	if recordings == nil {
+		recordings = []*models.Recording{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"recordings": recordings,
+		"count":      len(recordings),
+	})
+}
+
+// Get 查询通话录音的元数据
+func (h *RecordingHandler) Get(c *gin.Context) {
+	rec, err := h.recordingService.Get(c.Request.Context(), c.Param("uuid"))
+	if errors.Is(err, repositories.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通话没有录音"})
+		return
+	}
+	if err != nil {
+		log.Printf("查询录音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询录音失败"})
+		return
+	}
+	c.JSON(http.StatusOK, rec)
+}
+
+// Download 下载通话录音文件
+func (h *RecordingHandler) Download(c *gin.Context) {
+	rec, data, err := h.recordingService.Download(c.Request.Context(), c.Param("uuid"))
+	if errors.Is(err, repositories.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通话没有录音"})
+		return
+	}
+	if err != nil {
+		log.Printf("下载录音失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下载录音失败"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(rec.Key)))
+	c.Data(http.StatusOK, rec.ContentType, data)
+}
+
+// parseRecordingQuery 解析录音查询参数
+func parseRecordingQuery(c *gin.Context) (models.RecordingQuery, error) {
+	q := models.RecordingQuery{Number: c.Query("number")}
+	var err error
+	if q.From, err = parseTimeParam(c.Query("from")); err != nil {
+		return q, fmt.Errorf("from参数无效: %v", err)
+	}
+	if q.To, err = parseTimeParam(c.Query("to")); err != nil {
+		return q, fmt.Errorf("to参数无效: %v", err)
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("limit参数无效: %v", err)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("offset参数无效: %v", err)
+		}
+	}
+	return q, nil
+}
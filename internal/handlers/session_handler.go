@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler 对话会话HTTP处理器
+type SessionHandler struct {
+	dialogService *services.DialogService
+}
+
+// NewSessionHandler 创建对话会话处理器
+func NewSessionHandler(dialogService *services.DialogService) *SessionHandler {
+	return &SessionHandler{dialogService: dialogService}
+}
+
+// UpdateOptionsRequest 修改会话大模型参数请求
+type UpdateOptionsRequest struct {
+	CampaignID string                     `json:"campaign_id"` // 所属活动，留空则只使用全局配置
+	Prompt     string                     `json:"prompt"`      // 提示词配置名称，留空则使用活动或默认配置
+	Overrides  models.GenerationOverrides `json:"overrides"`   // 会话级覆盖项
+}
+
+// GetOptions 查询会话的大模型参数
+func (h *SessionHandler) GetOptions(c *gin.Context) {
+	options, err := h.dialogService.GetSessionOptions(c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// UpdateOptions 设置会话所属活动、提示词配置和大模型参数覆盖项，对之后的对话轮次生效
+func (h *SessionHandler) UpdateOptions(c *gin.Context) {
+	var req UpdateOptionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数无效: " + err.Error()})
+		return
+	}
+
+	options, err := h.dialogService.SetSessionOptions(c.Param("session_id"), req.CampaignID, req.Prompt, req.Overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, options)
+}
+
+// GetHistory 查询会话的对话历史，AI回复附带生成时实际使用的参数
+func (h *SessionHandler) GetHistory(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	history := h.dialogService.GetHistory(sessionID)
+	if history == nil {
+		history = []models.Message{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"history":    history,
+	})
+}
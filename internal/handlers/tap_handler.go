@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services/tap"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// TapFormat 音频格式消息，音频帧之前及采样率变化时发送
+type TapFormat struct {
+	Type       string `json:"type"`        // 固定为format
+	Leg        string `json:"leg"`         // 之后的音频帧所属通道
+	Encoding   string `json:"encoding"`    // 编码，固定为pcm_s16le
+	SampleRate int    `json:"sample_rate"` // 采样率
+	Channels   int    `json:"channels"`    // 声道数
+}
+
+// TapHandler 通话实时监听处理器
+type TapHandler struct {
+	tap      *tap.Tap
+	upgrader websocket.Upgrader
+}
+
+// NewTapHandler 创建通话实时监听处理器
+func NewTapHandler(t *tap.Tap) *TapHandler {
+	return &TapHandler{
+		tap: t,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: config.CheckWebSocketOrigin,
+		},
+	}
+}
+
+// Listen 以WebSocket二进制消息实时推送通话音频
+// 查询参数: leg 音频通道 customer/ai/mixed，默认mixed
+func (h *TapHandler) Listen(c *gin.Context) {
+	callID := c.Param("uuid")
+	leg := c.DefaultQuery("leg", tap.LegMixed)
+	if !tap.ValidLeg(leg) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leg 只能为 customer、ai 或 mixed"})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("升级WebSocket连接失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	frames, cancel := h.tap.Subscribe(callID, leg)
+	defer cancel()
+	log.Printf("质检坐席开始监听通话: %s, 通道: %s", callID, leg)
+
+	// 读取循环只用于感知客户端断开
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	var last TapFormat
+	for {
+		select {
+		case <-closed:
+			log.Printf("质检坐席停止监听通话: %s", callID)
+			return
+		case frame, ok := <-frames:
+			if !ok {
+				return
+			}
+			format := TapFormat{Type: "format", Leg: frame.Leg, Encoding: "pcm_s16le", SampleRate: frame.SampleRate, Channels: 1}
+			if format != last {
+				if err := conn.WriteJSON(format); err != nil {
+					return
+				}
+				last = format
+			}
+			if err := conn.WriteMessage(websocket.BinaryMessage, frame.PCM); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranscriptHandler 通话转写HTTP处理器
+type TranscriptHandler struct {
+	transcriptService *services.TranscriptService
+}
+
+// NewTranscriptHandler 创建通话转写处理器
+func NewTranscriptHandler(transcriptService *services.TranscriptService) *TranscriptHandler {
+	return &TranscriptHandler{transcriptService: transcriptService}
+}
+
+// Search 全文检索转写片段
+// 查询参数: q 检索短语; from/to 时间范围(RFC3339或2006-01-02); campaign_id; disposition; tag 通话标签;
+// romanize=pinyin 为中文片段附加拼音; limit; offset
+func (h *TranscriptHandler) Search(c *gin.Context) {
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hits, err := h.transcriptService.Search(c.Request.Context(), query)
+	if errors.Is(err, services.ErrInvalidSearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, repositories.ErrSearchEncrypted) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("检索通话转写失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "检索通话转写失败"})
+		return
+	}
+	if hits == nil {
+		hits = []*models.TranscriptHit{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": hits,
+		"count":   len(hits),
+	})
+}
+
+// Export 导出通话的完整转写（客户语音、AI回复、按键和通话事件），供质检和合规审查
+// 查询参数: format json（默认）或text; from/to 相对通话开始的时间范围，如 30s、2m; speaker 说话方，可逗号分隔多个
+func (h *TranscriptHandler) Export(c *gin.Context) {
+	filter, err := parseTranscriptFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format参数无效: 仅支持json和text"})
+		return
+	}
+
+	callUUID := c.Param("uuid")
+	transcripts, err := h.transcriptService.Export(c.Request.Context(), callUUID, filter)
+	if errors.Is(err, services.ErrInvalidSearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrTranscriptNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("导出通话转写失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出通话转写失败"})
+		return
+	}
+
+	if format == "text" {
+		c.String(http.StatusOK, services.FormatTranscriptText(transcripts))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"call_uuid":   callUUID,
+		"transcripts": transcripts,
+		"count":       len(transcripts),
+	})
+}
+
+// Sentiment 通话的客户情绪汇总，follow_up为true的通话建议优先安排人工跟进
+func (h *TranscriptHandler) Sentiment(c *gin.Context) {
+	summary, err := h.transcriptService.Sentiment(c.Request.Context(), c.Param("uuid"))
+	if errors.Is(err, services.ErrTranscriptNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("查询客户情绪失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询客户情绪失败"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// parseTranscriptFilter 解析导出转写的过滤参数
+func parseTranscriptFilter(c *gin.Context) (models.TranscriptFilter, error) {
+	var filter models.TranscriptFilter
+	for _, param := range []struct {
+		name string
+		dst  **time.Duration
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := c.Query(param.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return filter, fmt.Errorf("%s参数无效: 应为相对通话开始的时长，如 30s、2m", param.name)
+		}
+		*param.dst = &d
+	}
+	for _, v := range c.QueryArray("speaker") {
+		for _, speaker := range strings.Split(v, ",") {
+			if speaker = strings.TrimSpace(speaker); speaker != "" {
+				filter.Speakers = append(filter.Speakers, speaker)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// parseSearchQuery 解析检索请求参数
+func parseSearchQuery(c *gin.Context) (models.TranscriptSearchQuery, error) {
+	q := models.TranscriptSearchQuery{
+		Query:       c.Query("q"),
+		Disposition: c.Query("disposition"),
+		Tag:         c.Query("tag"),
+	}
+
+	var err error
+	if q.From, err = parseTimeParam(c.Query("from")); err != nil {
+		return q, fmt.Errorf("from参数无效: %v", err)
+	}
+	if q.To, err = parseTimeParam(c.Query("to")); err != nil {
+		return q, fmt.Errorf("to参数无效: %v", err)
+	}
+
+	if v := c.Query("campaign_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("campaign_id参数无效: %v", err)
+		}
+		q.CampaignID = &id
+	}
+	switch v := c.Query("romanize"); v {
+	case "":
+	case "pinyin":
+		q.Romanize = true
+	default:
+		return q, fmt.Errorf("romanize参数无效: 仅支持pinyin")
+	}
+	if v := c.Query("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("limit参数无效: %v", err)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if q.Offset, err = strconv.Atoi(v); err != nil {
+			return q, fmt.Errorf("offset参数无效: %v", err)
+		}
+	}
+	return q, nil
+}
+
+// parseTimeParam 解析时间参数，支持RFC3339和日期格式，空值返回nil
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
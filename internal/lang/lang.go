@@ -0,0 +1,45 @@
+// Package lang 转写文本的语种识别和拼音转写，供跨国质检团队审阅通话
+package lang
+
+import "unicode"
+
+// 语种代码，与ISO 639-1一致
+const (
+	Chinese  = "zh"
+	English  = "en"
+	Japanese = "ja"
+	Korean   = "ko"
+)
+
+// Detect 按文字种类识别文本的语种，无法判断时返回空字符串
+// 含假名时为日语，含谚文时为韩语，否则汉字数不少于英文单词数时为中文
+func Detect(text string) string {
+	var han, kana, hangul, words int
+	inWord := false
+	for _, r := range text {
+		isLatin := r < unicode.MaxASCII && unicode.IsLetter(r)
+		if isLatin && !inWord {
+			words++
+		}
+		inWord = isLatin
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+	}
+	switch {
+	case kana > 0:
+		return Japanese
+	case hangul > 0:
+		return Korean
+	case han > 0 && han >= words:
+		return Chinese
+	case words > 0:
+		return English
+	}
+	return ""
+}
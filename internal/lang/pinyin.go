@@ -0,0 +1,103 @@
+package lang
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pinyin 汉字拼音转写，拼音表由外部字典文件提供
+type Pinyin struct {
+	table map[rune]string
+}
+
+// LoadPinyin 从字典文件加载拼音表，格式与 pinyin-data 的 pinyin.txt 一致:
+//
+//	U+4E2D: zhōng,zhòng  # 中
+//
+// 多音字取第一个读音
+func LoadPinyin(path string) (*Pinyin, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开拼音字典失败: %v", err)
+	}
+	defer f.Close()
+	return ParsePinyin(f)
+}
+
+// ParsePinyin 解析拼音字典，忽略空行和#开头的注释行
+func ParsePinyin(r io.Reader) (*Pinyin, error) {
+	table := make(map[rune]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+
+		code, readings, ok := strings.Cut(text, ":")
+		if !ok || !strings.HasPrefix(code, "U+") {
+			return nil, fmt.Errorf("拼音字典第%d行格式错误: %s", line, text)
+		}
+		n, err := strconv.ParseUint(strings.TrimPrefix(code, "U+"), 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("拼音字典第%d行码位无效: %s", line, code)
+		}
+		reading, _, _ := strings.Cut(readings, ",")
+		if reading = strings.TrimSpace(reading); reading != "" {
+			table[rune(n)] = reading
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取拼音字典失败: %v", err)
+	}
+	if len(table) == 0 {
+		return nil, fmt.Errorf("拼音字典为空")
+	}
+	return &Pinyin{table: table}, nil
+}
+
+// Romanize 将文本中的汉字转写为拼音，音节之间以及音节与字母、数字之间以空格分隔，
+// 其他字符和字典中没有的汉字原样保留；可在nil上调用，此时返回空字符串
+func (p *Pinyin) Romanize(text string) string {
+	if p == nil {
+		return ""
+	}
+
+	// 上一个写入的内容：音节、字母或数字、其他字符
+	const (
+		prevOther = iota
+		prevSyllable
+		prevWord
+	)
+	var b strings.Builder
+	prev := prevOther
+	for _, r := range text {
+		reading, ok := p.table[r]
+		switch {
+		case ok:
+			if prev != prevOther {
+				b.WriteByte(' ')
+			}
+			b.WriteString(reading)
+			prev = prevSyllable
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if prev == prevSyllable {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			prev = prevWord
+		default:
+			b.WriteRune(r)
+			prev = prevOther
+		}
+	}
+	return b.String()
+}
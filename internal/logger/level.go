@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level 日志级别
+type Level int
+
+// 日志级别，低于子系统级别的日志不输出
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames 日志级别名称
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String 日志级别名称
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel 解析日志级别名称，空字符串为info
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return LevelInfo, nil
+	}
+	for level, n := range levelNames {
+		if n == name {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("不支持的日志级别: %s", name)
+}
+
+// Validate 验证日志级别配置
+func (c Config) Validate() error {
+	if _, err := ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("level: %v", err)
+	}
+	for subsystem, name := range c.Levels {
+		if _, err := ParseLevel(name); err != nil {
+			return fmt.Errorf("levels.%s: %v", subsystem, err)
+		}
+	}
+	return nil
+}
+
+// SetLevels 运行时修改日志级别，已创建的采样器立即生效；
+// level为空时保持全局级别不变，levels中值为空的子系统恢复使用全局级别
+func SetLevels(level string, levels map[string]string) error {
+	update := Config{Level: level, Levels: levels}
+	if err := update.Validate(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if level != "" {
+		config.Level = strings.ToLower(strings.TrimSpace(level))
+	}
+	merged := make(map[string]string, len(config.Levels)+len(levels))
+	for subsystem, name := range config.Levels {
+		merged[subsystem] = name
+	}
+	for subsystem, name := range levels {
+		if strings.TrimSpace(name) == "" {
+			delete(merged, subsystem)
+			continue
+		}
+		merged[subsystem] = strings.ToLower(strings.TrimSpace(name))
+	}
+	config.Levels = merged
+
+	for name, s := range samplers {
+		s.apply(lookup(name), levelOf(name))
+	}
+	return nil
+}
+
+// Levels 返回当前的全局日志级别和按子系统覆盖的日志级别
+func Levels() (string, map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	levels := make(map[string]string, len(config.Levels))
+	for subsystem, name := range config.Levels {
+		levels[subsystem] = name
+	}
+	return levelOf(DefaultSubsystem).String(), levels
+}
+
+// levelOf 查找子系统的日志级别，未单独配置时使用全局级别，调用方需持有mu
+func levelOf(subsystem string) Level {
+	if name, ok := config.Levels[subsystem]; ok {
+		if level, err := ParseLevel(name); err == nil {
+			return level
+		}
+	}
+	level, _ := ParseLevel(config.Level)
+	return level
+}
@@ -0,0 +1,158 @@
+// Package logger 提供高频日志的采样输出，避免音频帧等逐条日志刷屏，并支持按子系统设置和运行时调整日志级别
+package logger
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultSubsystem 未单独配置的子系统使用的采样配置键
+const DefaultSubsystem = "default"
+
+// Config 日志配置
+type Config struct {
+	Level    string                  `yaml:"level"`    // 全局日志级别：debug、info、warn、error，默认info
+	Levels   map[string]string       `yaml:"levels"`   // 按子系统覆盖日志级别，键为子系统名称
+	Sampling map[string]SampleConfig `yaml:"sampling"` // 按子系统配置采样，键为子系统名称，default对未配置的子系统生效
+}
+
+// SampleConfig 采样配置
+type SampleConfig struct {
+	Every    int           `yaml:"every"`    // 每N条记录一条，小于等于1表示全部记录
+	Interval time.Duration `yaml:"interval"` // 两条记录之间的最小间隔，0表示不限制
+}
+
+var (
+	mu       sync.Mutex
+	config   Config
+	samplers = make(map[string]*Sampler)
+)
+
+// Configure 设置采样和日志级别配置，已创建的采样器立即生效
+func Configure(cfg Config) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	config = cfg
+	for name, s := range samplers {
+		s.apply(lookup(name), levelOf(name))
+	}
+}
+
+// Sampled 获取子系统的采样器，同名子系统共享同一个采样器
+func Sampled(subsystem string) *Sampler {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if s, ok := samplers[subsystem]; ok {
+		return s
+	}
+	s := &Sampler{name: subsystem}
+	s.apply(lookup(subsystem), levelOf(subsystem))
+	samplers[subsystem] = s
+	return s
+}
+
+// lookup 查找子系统的采样配置，调用方需持有mu
+func lookup(subsystem string) SampleConfig {
+	if cfg, ok := config.Sampling[subsystem]; ok {
+		return cfg
+	}
+	return config.Sampling[DefaultSubsystem]
+}
+
+// Sampler 日志采样器
+// Printf按配置采样输出，并在输出时附带被省略的条数；Errorf始终输出；
+// 各方法按子系统的日志级别过滤，Debugf只在级别为debug时输出
+type Sampler struct {
+	name string
+
+	mu         sync.Mutex
+	level      Level
+	every      int
+	interval   time.Duration
+	count      int
+	suppressed int
+	last       time.Time
+}
+
+// apply 更新采样配置和日志级别
+func (s *Sampler) apply(cfg SampleConfig, level Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.level = level
+	s.every = cfg.Every
+	s.interval = cfg.Interval
+	s.count = 0
+}
+
+// Printf 采样输出info级别日志
+func (s *Sampler) Printf(format string, v ...interface{}) {
+	s.output(LevelInfo, format, v...)
+}
+
+// Debugf 采样输出debug级别日志，用于排查单个通话等场景时临时调低级别
+func (s *Sampler) Debugf(format string, v ...interface{}) {
+	s.output(LevelDebug, format, v...)
+}
+
+// Warnf 输出警告日志，不参与采样
+func (s *Sampler) Warnf(format string, v ...interface{}) {
+	if !s.Enabled(LevelWarn) {
+		return
+	}
+	log.Output(2, fmt.Sprintf(format, v...))
+}
+
+// Errorf 输出错误日志，不参与采样
+func (s *Sampler) Errorf(format string, v ...interface{}) {
+	log.Output(2, fmt.Sprintf(format, v...))
+}
+
+// Enabled 判断该级别的日志是否输出
+func (s *Sampler) Enabled(level Level) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return level >= s.level
+}
+
+// output 按级别过滤后采样输出日志
+func (s *Sampler) output(level Level, format string, v ...interface{}) {
+	if !s.Enabled(level) {
+		return
+	}
+	suppressed, ok := s.allow(time.Now())
+	if !ok {
+		return
+	}
+
+	msg := fmt.Sprintf(format, v...)
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (已省略%d条[%s]日志)", msg, suppressed, s.name)
+	}
+	log.Output(3, msg)
+}
+
+// allow 判断本条日志是否输出，输出时返回此前被省略的条数
+func (s *Sampler) allow(now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	if s.every > 1 && (s.count-1)%s.every != 0 {
+		s.suppressed++
+		return 0, false
+	}
+	if s.interval > 0 && !s.last.IsZero() && now.Sub(s.last) < s.interval {
+		s.suppressed++
+		return 0, false
+	}
+
+	suppressed := s.suppressed
+	s.suppressed = 0
+	s.last = now
+	return suppressed, true
+}
@@ -0,0 +1,224 @@
+// Package metrics 进程内运行指标，以Prometheus文本格式通过 /metrics 导出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentType Prometheus文本格式
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelSeparator 拼接标签值作为键时使用的分隔符，不会出现在正常的标签值中
+const labelSeparator = "\xff"
+
+// Collector 可导出的指标
+type Collector interface {
+	// Name 指标名称，同一注册表中不能重复
+	Name() string
+	// Write 以Prometheus文本格式写出指标
+	Write(w io.Writer) error
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// Default 默认注册表，NewCounterVec创建的指标注册在其中
+var Default = NewRegistry()
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// MustRegister 注册指标，名称重复时panic
+func (r *Registry) MustRegister(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[collector.Name()]; ok {
+		panic(fmt.Sprintf("指标重复注册: %s", collector.Name()))
+	}
+	r.collectors[collector.Name()] = collector
+}
+
+// Write 按名称顺序写出所有指标
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, collector := range r.collectors {
+		collectors = append(collectors, collector)
+	}
+	r.mu.RUnlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].Name() < collectors[j].Name() })
+
+	buf := bufio.NewWriter(w)
+	for _, collector := range collectors {
+		if err := collector.Write(buf); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// ServeHTTP 以Prometheus文本格式返回所有指标
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	if err := r.Write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// vec 按标签区分的指标值，计数器和仪表盘共用
+type vec struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newVec(name, help, typ string, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+}
+
+// Name 指标名称
+func (v *vec) Name() string {
+	return v.name
+}
+
+// Value 返回指定标签值的当前值
+func (v *vec) Value(values ...string) float64 {
+	key := v.key(values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[key]
+}
+
+// key 标签值拼接为键，数量与标签不一致时panic
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签值，实际 %d 个", v.name, len(v.labels), len(values)))
+	}
+	return strings.Join(values, labelSeparator)
+}
+
+// Write 以Prometheus文本格式写出指标
+func (v *vec) Write(w io.Writer) error {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, v.name+formatLabels(v.labels, strings.Split(key, labelSeparator))+" "+
+			strconv.FormatFloat(v.values[key], 'g', -1, 64))
+	}
+	v.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.typ); err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CounterVec 按标签区分的计数器
+type CounterVec struct {
+	*vec
+}
+
+// NewCounterVec 创建计数器并注册到默认注册表
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	Default.MustRegister(c)
+	return c
+}
+
+// Inc 计数加1，标签值按创建时的标签顺序传入
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add 计数增加delta，delta为负数时忽略
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		return
+	}
+	key := c.key(values)
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+// GaugeVec 按标签区分的仪表盘，值可增可减
+type GaugeVec struct {
+	*vec
+}
+
+// NewGaugeVec 创建仪表盘并注册到默认注册表
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels)}
+	Default.MustRegister(g)
+	return g
+}
+
+// Set 设置指定标签值的当前值
+func (g *GaugeVec) Set(value float64, values ...string) {
+	key := g.key(values)
+	g.mu.Lock()
+	g.values[key] = value
+	g.mu.Unlock()
+}
+
+// Delete 删除指定标签值，不再导出
+func (g *GaugeVec) Delete(values ...string) {
+	key := g.key(values)
+	g.mu.Lock()
+	delete(g.values, key)
+	g.mu.Unlock()
+}
+
+// formatLabels 格式化标签，如 {endpoint="asr",reason="timeout"}
+func formatLabels(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = label + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp 转义说明中的反斜杠和换行
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
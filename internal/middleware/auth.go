@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenAuth 令牌鉴权中间件，令牌通过 Authorization: Bearer 请求头传递
+// 浏览器WebSocket无法设置请求头，因此也接受 token 查询参数
+func TokenAuth(tokens []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
+
+		for _, allowed := range tokens {
+			if allowed != "" && subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+	}
+}
+
+// StreamVerifier 音频流签名地址校验
+type StreamVerifier interface {
+	Verify(callUUID string, query url.Values) error
+}
+
+// SignedStream 音频流签名校验中间件，在WebSocket升级前拒绝过期、与通话不符或重复使用的地址
+func SignedStream(verifier StreamVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		callUUID := c.Param("uuid")
+		if err := verifier.Verify(callUUID, c.Request.URL.Query()); err != nil {
+			log.Printf("拒绝音频流连接: %s, %v", callUUID, err)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ValidateOrigins 校验允许的来源列表，每项为 scheme://host[:port]，主机名可以 *. 开头匹配所有子域名
+func ValidateOrigins(patterns []string) error {
+	for _, pattern := range patterns {
+		u, err := url.Parse(pattern)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("无效的来源 %q，格式为 scheme://host[:port]", pattern)
+		}
+		host := strings.TrimPrefix(u.Host, "*.")
+		if host == "" || strings.Contains(host, "*") {
+			return fmt.Errorf("无效的来源 %q，通配符只能用于 *.example.com", pattern)
+		}
+	}
+	return nil
+}
+
+// AllowOrigin 检查WebSocket握手请求的来源：没有Origin头的请求（FreeSWITCH、服务端SDK等非浏览器客户端）
+// 和同源请求总是允许，其他来源须在允许列表中；allowAll为true时允许所有来源，仅用于本地开发
+func AllowOrigin(r *http.Request, allowed []string, allowAll bool) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || allowAll {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		log.Printf("拒绝WebSocket连接: 无效的来源 %s", origin)
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, pattern := range allowed {
+		if matchOrigin(pattern, u) {
+			return true
+		}
+	}
+	log.Printf("拒绝WebSocket连接: 来源 %s 不在允许列表中（websocket.allowed_origins）", origin)
+	return false
+}
+
+// matchOrigin 来源是否匹配允许的来源，协议和主机名不区分大小写，*.example.com 匹配子域名但不匹配 example.com 本身
+func matchOrigin(pattern string, origin *url.URL) bool {
+	p, err := url.Parse(pattern)
+	if err != nil || !strings.EqualFold(p.Scheme, origin.Scheme) {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(strings.ToLower(p.Host), "*"); ok {
+		return strings.HasSuffix(strings.ToLower(origin.Host), suffix)
+	}
+	return strings.EqualFold(p.Host, origin.Host)
+}
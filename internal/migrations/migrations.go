@@ -0,0 +1,345 @@
+// Package migrations 管理数据库表结构版本，SQL迁移脚本编译时嵌入二进制
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+)
+
+// sqlFiles 迁移脚本，命名格式为 {版本号}_{名称}.up.sql / {版本号}_{名称}.down.sql
+// sql目录下为MySQL脚本，sql/sqlite目录下为相同版本的SQLite脚本，两者的表结构保持一致
+//
+//go:embed sql/*.sql sql/sqlite/*.sql
+var sqlFiles embed.FS
+
+// lockName 迁移时使用的MySQL命名锁，防止多实例同时迁移
+const lockName = "ai_dialer_mini_migrate"
+
+// fileNamePattern 迁移脚本文件名格式
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration 单个迁移版本
+type Migration struct {
+	Version int    // 版本号
+	Name    string // 名称
+	Up      string // 升级脚本
+	Down    string // 回滚脚本
+}
+
+// Status 迁移状态
+type Status struct {
+	Current int         // 当前数据库版本
+	Latest  int         // 代码中的最新版本
+	Pending []Migration // 待执行的迁移
+}
+
+// Migrator 数据库迁移器
+type Migrator struct {
+	db         *sql.DB
+	dialect    database.Dialect
+	migrations []Migration
+}
+
+// New 创建MySQL数据库的迁移器
+func New(db *sql.DB) (*Migrator, error) {
+	return NewWithDialect(db, database.MySQL)
+}
+
+// NewWithDialect 创建指定方言的迁移器
+func NewWithDialect(db *sql.DB, dialect database.Dialect) (*Migrator, error) {
+	migrations, err := LoadDialect(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Load 加载内嵌的MySQL迁移脚本，按版本号升序返回
+func Load() ([]Migration, error) {
+	return LoadDialect(database.MySQL)
+}
+
+// LoadDialect 加载内嵌的指定方言的迁移脚本，按版本号升序返回
+func LoadDialect(dialect database.Dialect) ([]Migration, error) {
+	dir := "sql"
+	if dialect.IsSQLite() {
+		dir = "sql/sqlite"
+	}
+	entries, err := fs.ReadDir(sqlFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移脚本失败: %v", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := fileNamePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("迁移脚本文件名格式错误: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		content, err := sqlFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移脚本失败: %v", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("迁移版本 %d 存在多个名称: %s, %s", version, m.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少升级脚本", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Latest 获取最新的迁移版本号
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status 获取迁移状态
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	if err := m.ensureVersionTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.appliedVersions(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Latest: m.Latest()}
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			status.Current = migration.Version
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// Verify 检查数据库是否已迁移到最新版本
+func (m *Migrator) Verify(ctx context.Context) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("数据库版本 %d 落后于最新版本 %d，有 %d 个迁移待执行，请运行 `ai_dialer_mini migrate up`",
+			status.Current, status.Latest, len(status.Pending))
+	}
+	return nil
+}
+
+// Up 执行所有待执行的迁移，返回执行的数量
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+			log.Printf("执行数据库迁移: %d_%s", migration.Version, migration.Name)
+			if err := execScript(ctx, conn, migration.Up); err != nil {
+				return fmt.Errorf("执行迁移 %d_%s 失败: %v", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				migration.Version, migration.Name, time.Now()); err != nil {
+				return fmt.Errorf("记录迁移版本失败: %v", err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down 回滚最近的steps个迁移，返回回滚的数量
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := m.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("迁移 %d_%s 没有回滚脚本", migration.Version, migration.Name)
+			}
+			log.Printf("回滚数据库迁移: %d_%s", migration.Version, migration.Name)
+			if err := execScript(ctx, conn, migration.Down); err != nil {
+				return fmt.Errorf("回滚迁移 %d_%s 失败: %v", migration.Version, migration.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version); err != nil {
+				return fmt.Errorf("删除迁移版本记录失败: %v", err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// execer 可执行SQL的对象，*sql.DB和*sql.Conn均满足
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// withLock 获取命名锁后在同一连接上执行fn；SQLite只用于单实例部署，不加锁
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %v", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.IsSQLite() {
+		if err := m.ensureVersionTable(ctx, conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	}
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT GET_LOCK(?, 30)`, lockName).Scan(&locked); err != nil {
+		return fmt.Errorf("获取迁移锁失败: %v", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("获取迁移锁超时，可能有其他实例正在迁移")
+	}
+	defer conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, lockName)
+
+	if err := m.ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureVersionTable 创建版本记录表
+func (m *Migrator) ensureVersionTable(ctx context.Context, db execer) error {
+	ddl := `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(191) NOT NULL,
+		applied_at DATETIME(3) NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`
+	if m.dialect.IsSQLite() {
+		ddl = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(191) NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`
+	}
+	_, err := db.ExecContext(ctx, ddl)
+	if err != nil {
+		return fmt.Errorf("创建迁移版本表失败: %v", err)
+	}
+	return nil
+}
+
+// appliedVersions 查询已执行的迁移版本
+func (m *Migrator) appliedVersions(ctx context.Context, db execer) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("查询迁移版本失败: %v", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("读取迁移版本失败: %v", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// execScript 逐条执行脚本中的SQL语句
+// MySQL的DDL会隐式提交，无法放入事务，因此每个迁移脚本应尽量保持幂等（IF NOT EXISTS）
+func execScript(ctx context.Context, db execer, script string) error {
+	for _, stmt := range SplitStatements(script) {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitStatements 按行尾分号拆分SQL脚本，忽略空语句和整行注释
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			if stmt != "" {
+				statements = append(statements, stmt)
+			}
+			current.Reset()
+		}
+	}
+
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, stmt)
+	}
+	return statements
+}
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS outbox (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	idempotency_key VARCHAR(191) NOT NULL,
+	destination VARCHAR(32) NOT NULL,
+	target_url VARCHAR(512) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload JSON NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NULL,
+	next_attempt_at DATETIME(3) NOT NULL,
+	created_at DATETIME(3) NOT NULL,
+	delivered_at DATETIME(3) NULL,
+	UNIQUE KEY uk_outbox_idempotency (idempotency_key),
+	KEY idx_outbox_pending (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS cdr (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	caller VARCHAR(64) NOT NULL DEFAULT '',
+	callee VARCHAR(64) NOT NULL DEFAULT '',
+	hangup_cause VARCHAR(64) NOT NULL DEFAULT '',
+	start_time DATETIME(3) NULL,
+	answer_time DATETIME(3) NULL,
+	end_time DATETIME(3) NULL,
+	billsec INT NOT NULL DEFAULT 0,
+	created_at DATETIME(3) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
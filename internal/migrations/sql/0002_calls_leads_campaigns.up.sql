@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(128) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'draft',
+	caller_id VARCHAR(64) NOT NULL DEFAULT '',
+	pacing_per_minute INT NOT NULL DEFAULT 10,
+	max_attempts INT NOT NULL DEFAULT 3,
+	retry_interval_seconds INT NOT NULL DEFAULT 3600,
+	created_at DATETIME(3) NOT NULL,
+	updated_at DATETIME(3) NOT NULL,
+	KEY idx_campaigns_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS leads (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	campaign_id BIGINT NOT NULL,
+	phone VARCHAR(64) NOT NULL,
+	name VARCHAR(128) NOT NULL DEFAULT '',
+	status VARCHAR(32) NOT NULL DEFAULT 'queued',
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME(3) NULL,
+	last_call_uuid VARCHAR(64) NOT NULL DEFAULT '',
+	data JSON NULL,
+	created_at DATETIME(3) NOT NULL,
+	updated_at DATETIME(3) NOT NULL,
+	KEY idx_leads_dial (campaign_id, status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS calls (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	campaign_id BIGINT NULL,
+	lead_id BIGINT NULL,
+	direction VARCHAR(16) NOT NULL DEFAULT 'outbound',
+	caller VARCHAR(64) NOT NULL DEFAULT '',
+	callee VARCHAR(64) NOT NULL DEFAULT '',
+	status VARCHAR(32) NOT NULL DEFAULT 'created',
+	disposition VARCHAR(64) NOT NULL DEFAULT '',
+	started_at DATETIME(3) NULL,
+	answered_at DATETIME(3) NULL,
+	ended_at DATETIME(3) NULL,
+	created_at DATETIME(3) NOT NULL,
+	updated_at DATETIME(3) NOT NULL,
+	KEY idx_calls_campaign (campaign_id, created_at),
+	KEY idx_calls_lead (lead_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS transcripts (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	call_uuid VARCHAR(64) NOT NULL,
+	speaker VARCHAR(16) NOT NULL,
+	text TEXT NOT NULL,
+	start_ms INT NOT NULL DEFAULT 0,
+	end_ms INT NOT NULL DEFAULT 0,
+	created_at DATETIME(3) NOT NULL,
+	KEY idx_transcripts_call (call_uuid, start_ms)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	actor VARCHAR(128) NOT NULL DEFAULT '',
+	action VARCHAR(64) NOT NULL,
+	target VARCHAR(191) NOT NULL DEFAULT '',
+	detail JSON NULL,
+	created_at DATETIME(3) NOT NULL,
+	KEY idx_audit_target (target, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1 @@
+ALTER TABLE transcripts DROP INDEX ft_transcripts_text;
@@ -0,0 +1,2 @@
+-- 转写全文索引，使用ngram分词器支持中文短语检索
+ALTER TABLE transcripts ADD FULLTEXT INDEX ft_transcripts_text (text) WITH PARSER ngram;
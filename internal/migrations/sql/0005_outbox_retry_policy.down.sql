@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+	DROP COLUMN backoff_seconds,
+	DROP COLUMN max_attempts;
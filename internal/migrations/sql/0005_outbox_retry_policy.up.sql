@@ -0,0 +1,4 @@
+-- 单条消息的重试策略，为0时使用发件箱全局配置
+ALTER TABLE outbox
+	ADD COLUMN max_attempts INT NOT NULL DEFAULT 0 AFTER attempts,
+	ADD COLUMN backoff_seconds INT NOT NULL DEFAULT 0 AFTER max_attempts;
@@ -0,0 +1,4 @@
+ALTER TABLE leads
+	DROP COLUMN looked_up_at,
+	DROP COLUMN lookup_carrier,
+	DROP COLUMN lookup_status;
@@ -0,0 +1,5 @@
+-- 拨打前号码状态查询（HLR/运营商查询）结果
+ALTER TABLE leads
+	ADD COLUMN lookup_status VARCHAR(16) NOT NULL DEFAULT '' AFTER last_call_uuid,
+	ADD COLUMN lookup_carrier VARCHAR(64) NOT NULL DEFAULT '' AFTER lookup_status,
+	ADD COLUMN looked_up_at DATETIME(3) NULL AFTER lookup_carrier;
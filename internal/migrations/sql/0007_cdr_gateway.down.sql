@@ -0,0 +1,3 @@
+ALTER TABLE cdr
+	DROP KEY idx_cdr_gateway,
+	DROP COLUMN gateway;
@@ -0,0 +1,4 @@
+-- 记录外呼落地网关，用于按网关统计接通率和平均通话时长
+ALTER TABLE cdr
+	ADD COLUMN gateway VARCHAR(64) NOT NULL DEFAULT '' AFTER callee,
+	ADD KEY idx_cdr_gateway (gateway, start_time);
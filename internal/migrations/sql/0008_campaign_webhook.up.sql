@@ -0,0 +1,3 @@
+-- 外呼任务的线索状态变化Webhook地址
+ALTER TABLE campaigns
+	ADD COLUMN webhook_url VARCHAR(512) NOT NULL DEFAULT '' AFTER retry_interval_seconds;
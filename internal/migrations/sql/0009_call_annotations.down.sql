@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS call_notes;
+DROP TABLE IF EXISTS call_tags;
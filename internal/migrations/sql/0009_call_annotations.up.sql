@@ -0,0 +1,19 @@
+-- 通话标签和备注，质检人员在通话中或通话结束后添加，按通话UUID与详单关联
+CREATE TABLE IF NOT EXISTS call_tags (
+	call_uuid VARCHAR(64) NOT NULL,
+	tag VARCHAR(64) NOT NULL,
+	author VARCHAR(128) NOT NULL DEFAULT '',
+	created_at DATETIME(3) NOT NULL,
+	PRIMARY KEY (call_uuid, tag),
+	KEY idx_call_tags_tag (tag)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS call_notes (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	call_uuid VARCHAR(64) NOT NULL,
+	author VARCHAR(128) NOT NULL DEFAULT '',
+	note TEXT NOT NULL,
+	offset_ms INT NULL,
+	created_at DATETIME(3) NOT NULL,
+	KEY idx_call_notes_call (call_uuid, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS qa_scores;
+DROP TABLE IF EXISTS qa_evaluations;
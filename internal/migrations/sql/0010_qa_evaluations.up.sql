@@ -0,0 +1,20 @@
+-- 自动质检结果，每通电话一条评估记录，按评分表逐项保存得分
+CREATE TABLE IF NOT EXISTS qa_evaluations (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	status VARCHAR(16) NOT NULL,
+	total_score DECIMAL(5,2) NULL,
+	passed TINYINT(1) NOT NULL DEFAULT 0,
+	error TEXT NULL,
+	evaluated_at DATETIME(3) NOT NULL,
+	KEY idx_qa_evaluations_time (evaluated_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS qa_scores (
+	call_uuid VARCHAR(64) NOT NULL,
+	criterion VARCHAR(64) NOT NULL,
+	score INT NULL,
+	passed TINYINT(1) NOT NULL DEFAULT 0,
+	reason TEXT NOT NULL,
+	PRIMARY KEY (call_uuid, criterion),
+	KEY idx_qa_scores_criterion (criterion)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
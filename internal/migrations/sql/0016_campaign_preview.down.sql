@@ -0,0 +1,3 @@
+ALTER TABLE campaigns
+	DROP COLUMN agent_extension,
+	DROP COLUMN dial_mode;
@@ -0,0 +1,4 @@
+-- 外呼任务的拨号方式及预览拨号的默认坐席分机
+ALTER TABLE campaigns
+	ADD COLUMN dial_mode VARCHAR(16) NOT NULL DEFAULT 'auto' AFTER webhook_url,
+	ADD COLUMN agent_extension VARCHAR(32) NOT NULL DEFAULT '' AFTER dial_mode;
@@ -0,0 +1 @@
+ALTER TABLE cdr DROP COLUMN acw_sec;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	idempotency_key VARCHAR(191) NOT NULL,
+	destination VARCHAR(32) NOT NULL,
+	target_url VARCHAR(512) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL,
+	delivered_at DATETIME NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uk_outbox_idempotency ON outbox (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS cdr (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	caller VARCHAR(64) NOT NULL DEFAULT '',
+	callee VARCHAR(64) NOT NULL DEFAULT '',
+	hangup_cause VARCHAR(64) NOT NULL DEFAULT '',
+	start_time DATETIME NULL,
+	answer_time DATETIME NULL,
+	end_time DATETIME NULL,
+	billsec INT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
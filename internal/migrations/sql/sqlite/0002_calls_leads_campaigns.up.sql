@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS campaigns (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(128) NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'draft',
+	caller_id VARCHAR(64) NOT NULL DEFAULT '',
+	pacing_per_minute INT NOT NULL DEFAULT 10,
+	max_attempts INT NOT NULL DEFAULT 3,
+	retry_interval_seconds INT NOT NULL DEFAULT 3600,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns (status);
+
+CREATE TABLE IF NOT EXISTS leads (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	campaign_id BIGINT NOT NULL,
+	phone VARCHAR(64) NOT NULL,
+	name VARCHAR(128) NOT NULL DEFAULT '',
+	status VARCHAR(32) NOT NULL DEFAULT 'queued',
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NULL,
+	last_call_uuid VARCHAR(64) NOT NULL DEFAULT '',
+	data TEXT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_leads_dial ON leads (campaign_id, status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS calls (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	campaign_id BIGINT NULL,
+	lead_id BIGINT NULL,
+	direction VARCHAR(16) NOT NULL DEFAULT 'outbound',
+	caller VARCHAR(64) NOT NULL DEFAULT '',
+	callee VARCHAR(64) NOT NULL DEFAULT '',
+	status VARCHAR(32) NOT NULL DEFAULT 'created',
+	disposition VARCHAR(64) NOT NULL DEFAULT '',
+	started_at DATETIME NULL,
+	answered_at DATETIME NULL,
+	ended_at DATETIME NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_calls_campaign ON calls (campaign_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_calls_lead ON calls (lead_id);
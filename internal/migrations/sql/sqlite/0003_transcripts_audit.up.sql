@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS transcripts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	call_uuid VARCHAR(64) NOT NULL,
+	speaker VARCHAR(16) NOT NULL,
+	text TEXT NOT NULL,
+	start_ms INT NOT NULL DEFAULT 0,
+	end_ms INT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_transcripts_call ON transcripts (call_uuid, start_ms);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor VARCHAR(128) NOT NULL DEFAULT '',
+	action VARCHAR(64) NOT NULL,
+	target VARCHAR(191) NOT NULL DEFAULT '',
+	detail TEXT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_log (target, created_at);
@@ -0,0 +1 @@
+-- 升级脚本没有变更表结构，无需回滚
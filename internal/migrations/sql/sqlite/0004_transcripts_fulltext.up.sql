@@ -0,0 +1 @@
+-- SQLite没有ngram分词的全文索引，转写检索按子串匹配，本版本无需变更表结构
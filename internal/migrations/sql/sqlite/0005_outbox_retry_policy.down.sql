@@ -0,0 +1,2 @@
+ALTER TABLE outbox DROP COLUMN backoff_seconds;
+ALTER TABLE outbox DROP COLUMN max_attempts;
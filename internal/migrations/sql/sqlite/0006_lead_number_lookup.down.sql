@@ -0,0 +1,3 @@
+ALTER TABLE leads DROP COLUMN looked_up_at;
+ALTER TABLE leads DROP COLUMN lookup_carrier;
+ALTER TABLE leads DROP COLUMN lookup_status;
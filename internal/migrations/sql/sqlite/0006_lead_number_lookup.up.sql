@@ -0,0 +1,4 @@
+-- 拨打前号码状态查询（HLR/运营商查询）结果
+ALTER TABLE leads ADD COLUMN lookup_status VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE leads ADD COLUMN lookup_carrier VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE leads ADD COLUMN looked_up_at DATETIME NULL;
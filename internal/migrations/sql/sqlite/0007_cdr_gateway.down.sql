@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_cdr_gateway;
+ALTER TABLE cdr DROP COLUMN gateway;
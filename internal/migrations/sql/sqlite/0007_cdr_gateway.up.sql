@@ -0,0 +1,3 @@
+-- 记录外呼落地网关，用于按网关统计接通率和平均通话时长
+ALTER TABLE cdr ADD COLUMN gateway VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_cdr_gateway ON cdr (gateway, start_time);
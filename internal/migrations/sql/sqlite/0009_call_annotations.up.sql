@@ -0,0 +1,19 @@
+-- 通话标签和备注，质检人员在通话中或通话结束后添加，按通话UUID与详单关联
+CREATE TABLE IF NOT EXISTS call_tags (
+	call_uuid VARCHAR(64) NOT NULL,
+	tag VARCHAR(64) NOT NULL,
+	author VARCHAR(128) NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (call_uuid, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_call_tags_tag ON call_tags (tag);
+
+CREATE TABLE IF NOT EXISTS call_notes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	call_uuid VARCHAR(64) NOT NULL,
+	author VARCHAR(128) NOT NULL DEFAULT '',
+	note TEXT NOT NULL,
+	offset_ms INT NULL,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_call_notes_call ON call_notes (call_uuid, created_at);
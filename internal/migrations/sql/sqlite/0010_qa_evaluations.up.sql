@@ -0,0 +1,20 @@
+-- 自动质检结果，每通电话一条评估记录，按评分表逐项保存得分
+CREATE TABLE IF NOT EXISTS qa_evaluations (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	status VARCHAR(16) NOT NULL,
+	total_score DECIMAL(5,2) NULL,
+	passed BOOLEAN NOT NULL DEFAULT 0,
+	error TEXT NULL,
+	evaluated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_qa_evaluations_time ON qa_evaluations (evaluated_at);
+
+CREATE TABLE IF NOT EXISTS qa_scores (
+	call_uuid VARCHAR(64) NOT NULL,
+	criterion VARCHAR(64) NOT NULL,
+	score INT NULL,
+	passed BOOLEAN NOT NULL DEFAULT 0,
+	reason TEXT NOT NULL,
+	PRIMARY KEY (call_uuid, criterion)
+);
+CREATE INDEX IF NOT EXISTS idx_qa_scores_criterion ON qa_scores (criterion);
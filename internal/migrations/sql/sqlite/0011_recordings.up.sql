@@ -0,0 +1,18 @@
+-- 通话录音索引，录音文件和元数据文件保存在对象存储中，这里记录位置和通话信息供查询
+CREATE TABLE IF NOT EXISTS recordings (
+	call_uuid VARCHAR(64) PRIMARY KEY,
+	object_key VARCHAR(512) NOT NULL,
+	content_type VARCHAR(64) NOT NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	channels INT NOT NULL DEFAULT 0,
+	duration_ms INT NOT NULL DEFAULT 0,
+	caller VARCHAR(64) NOT NULL DEFAULT '',
+	callee VARCHAR(64) NOT NULL DEFAULT '',
+	started_at DATETIME NULL,
+	answered_at DATETIME NULL,
+	ended_at DATETIME NULL,
+	archived_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_recordings_ended ON recordings (ended_at);
+CREATE INDEX IF NOT EXISTS idx_recordings_caller ON recordings (caller);
+CREATE INDEX IF NOT EXISTS idx_recordings_callee ON recordings (callee);
@@ -0,0 +1,2 @@
+-- 转写片段识别的语种，如 zh、en，无法判断时为空
+ALTER TABLE transcripts ADD COLUMN language VARCHAR(16) NOT NULL DEFAULT '';
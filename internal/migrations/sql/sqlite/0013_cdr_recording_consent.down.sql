@@ -0,0 +1 @@
+ALTER TABLE cdr DROP COLUMN recording_consent;
@@ -0,0 +1,2 @@
+-- 录音授权结果：granted同意、refused拒绝，未确认时为空
+ALTER TABLE cdr ADD COLUMN recording_consent VARCHAR(16) NOT NULL DEFAULT '';
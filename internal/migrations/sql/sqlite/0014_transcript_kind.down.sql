@@ -0,0 +1 @@
+ALTER TABLE transcripts DROP COLUMN kind;
@@ -0,0 +1,2 @@
+-- 转写条目类型：speech语音识别或AI回复、dtmf客户按键、event通话事件
+ALTER TABLE transcripts ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'speech';
@@ -0,0 +1,2 @@
+-- 客户语音的情绪：positive、neutral、negative，未启用情绪识别时为空
+ALTER TABLE transcripts ADD COLUMN sentiment VARCHAR(16) NOT NULL DEFAULT '';
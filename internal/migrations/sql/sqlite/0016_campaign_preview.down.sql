@@ -0,0 +1,2 @@
+ALTER TABLE campaigns DROP COLUMN agent_extension;
+ALTER TABLE campaigns DROP COLUMN dial_mode;
@@ -0,0 +1,2 @@
+-- 挂机后的话后处理时长（秒），话后处理结束后写入
+ALTER TABLE cdr ADD COLUMN acw_sec INT NOT NULL DEFAULT 0;
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// MaxTagLength 标签最大长度（字符）
+const MaxTagLength = 64
+
+// CallNote 通话备注
+type CallNote struct {
+	ID        int64     `json:"id"`                  // 备注ID
+	CallUUID  string    `json:"call_uuid"`           // 通话UUID
+	Author    string    `json:"author,omitempty"`    // 添加人
+	Note      string    `json:"note"`                // 备注内容
+	OffsetMs  *int      `json:"offset_ms,omitempty"` // 备注对应的通话时间点（相对通话开始，毫秒），为空表示针对整通电话
+	CreatedAt time.Time `json:"created_at"`          // 添加时间
+}
+
+// CallTag 通话标签
+type CallTag struct {
+	Tag       string    `json:"tag"`              // 标签
+	Author    string    `json:"author,omitempty"` // 添加人
+	CreatedAt time.Time `json:"created_at"`       // 添加时间
+}
+
+// AnnotateCallRequest 为通话添加标签和备注的请求，标签和备注至少填写一项
+type AnnotateCallRequest struct {
+	Tags     []string `json:"tags"`                // 标签，重复添加同一标签会被忽略
+	Note     string   `json:"note"`                // 备注内容
+	OffsetMs *int     `json:"offset_ms,omitempty"` // 备注对应的通话时间点（毫秒）
+	Author   string   `json:"author"`              // 添加人
+}
+
+// CallAnnotations 通话的全部标签和备注
+type CallAnnotations struct {
+	CallUUID string      `json:"call_uuid"`
+	Tags     []*CallTag  `json:"tags"`
+	Notes    []*CallNote `json:"notes"`
+}
@@ -1,5 +1,10 @@
 package models
 
+import (
+	"context"
+	"time"
+)
+
 // ASRService ASR服务接口
 type ASRService interface {
 	// ProcessAudio 处理音频数据并返回识别结果
@@ -11,3 +16,35 @@ type ASRService interface {
 	// ClearDialogHistory 清除对话历史
 	ClearDialogHistory(sessionID string)
 }
+
+// ASRResult 流式识别结果
+type ASRResult struct {
+	Text    string // 本次识别会话到目前为止的完整文本，动态修正的结果会替换之前的中间结果
+	IsFinal bool   // 识别会话结束，Text为最终结果
+}
+
+// ASRStream 单次流式识别会话：Write按到达顺序发送16kHz 16位PCM分片，识别结果从Results读取，
+// 后端检测到句尾或调用Close后返回最终结果，随后Results关闭
+type ASRStream interface {
+	Write(p []byte) (int, error)
+	Close() error
+	Results() <-chan ASRResult
+	// Done 识别会话结束时关闭
+	Done() <-chan struct{}
+	// Err 等待识别会话结束，返回异常结束的原因
+	Err() error
+	// Latency 等待识别会话结束，返回最终结果相对最后一帧音频的延迟，未收到最终结果时返回0
+	Latency() time.Duration
+}
+
+// ASRProvider 流式语音识别后端
+type ASRProvider interface {
+	// Name 后端名称，如 xfyun、whisper
+	Name() string
+	// OpenStream 为会话开始一次流式识别
+	OpenStream(sessionID string) (ASRStream, error)
+	// ReleaseSession 会话结束时释放为会话保留的资源
+	ReleaseSession(sessionID string)
+	// Ping 探测后端是否可用
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// OriginateRequest 发起呼叫请求
+type OriginateRequest struct {
+	From string `json:"from" binding:"required"` // 主叫号码/分机
+	To   string `json:"to" binding:"required"`   // 被叫号码/分机
+
+	CampaignID *int64 `json:"campaign_id,omitempty"` // 所属外呼任务，用于选择语音识别不可用时的降级策略
+	LeadID     *int64 `json:"lead_id,omitempty"`     // 关联线索，降级致歉后据此安排回拨
+	Tenant     string `json:"tenant,omitempty"`      // 所属租户，使用租户的外显号码，通话的AI对话使用租户的凭据、模型和人设
+	ASR        string `json:"asr,omitempty"`         // 通话使用的语音识别后端（xfyun、whisper），为空时使用默认后端
+}
+
+// TransferRequest 通话转接请求
+type TransferRequest struct {
+	Destination string `json:"destination" binding:"required"` // 转接目标分机或号码
+	Context     string `json:"context,omitempty"`              // 拨号计划上下文，默认default
+}
+
+// DTMFRequest 发送按键请求
+type DTMFRequest struct {
+	Digits     string `json:"digits" binding:"required"` // 按键序列，支持0-9、*、#、A-D，w/W表示停顿
+	DurationMS int    `json:"duration_ms,omitempty"`     // 每个按键的时长（毫秒），默认使用FreeSWITCH的设置
+}
+
+// DispositionRequest 修改通话结果请求，仅在话后处理期间允许
+type DispositionRequest struct {
+	Disposition string `json:"disposition" binding:"required"` // 通话结果，如 interested、callback
+}
+
+// CallInfo 呼叫信息
+type CallInfo struct {
+	CallID    string    `json:"call_id"`            // FreeSWITCH通话UUID
+	From      string    `json:"from"`               // 主叫号码/分机
+	To        string    `json:"to"`                 // 被叫号码/分机
+	CreatedAt time.Time `json:"created_at"`         // 发起时间
+	Fallback  string    `json:"fallback,omitempty"` // 语音识别不可用时执行的降级动作
+}
+
+// 通话状态
+const (
+	CallStatusCreated  = "created"  // 已创建
+	CallStatusRinging  = "ringing"  // 振铃中
+	CallStatusAnswered = "answered" // 已接通
+	CallStatusEnded    = "ended"    // 已结束
+)
+
+// Call 通话记录
+type Call struct {
+	CallUUID    string     `json:"call_uuid"`             // 通话UUID
+	CampaignID  *int64     `json:"campaign_id,omitempty"` // 所属外呼任务，手动呼叫为空
+	LeadID      *int64     `json:"lead_id,omitempty"`     // 关联线索，手动呼叫为空
+	Direction   string     `json:"direction"`             // 呼叫方向，outbound或inbound
+	Caller      string     `json:"caller"`                // 主叫号码
+	Callee      string     `json:"callee"`                // 被叫号码
+	Status      string     `json:"status"`                // 通话状态
+	Disposition string     `json:"disposition"`           // 通话结果
+	StartedAt   *time.Time `json:"started_at,omitempty"`  // 开始时间
+	AnsweredAt  *time.Time `json:"answered_at,omitempty"` // 应答时间
+	EndedAt     *time.Time `json:"ended_at,omitempty"`    // 结束时间
+	CreatedAt   time.Time  `json:"created_at"`            // 创建时间
+	UpdatedAt   time.Time  `json:"updated_at"`            // 更新时间
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 外呼任务状态
+const (
+	CampaignStatusDraft     = "draft"     // 草稿，尚未启动
+	CampaignStatusRunning   = "running"   // 拨打中
+	CampaignStatusPaused    = "paused"    // 已暂停
+	CampaignStatusCompleted = "completed" // 线索已全部拨打完成
+)
+
+// 外呼任务拨号方式
+const (
+	DialModeAuto    = "auto"    // 自动拨号，被叫接通后由AI坐席接听
+	DialModePreview = "preview" // 预览拨号，坐席确认线索后才发起呼叫，接通后由坐席通话，AI只推送建议话术
+)
+
+// Campaign 外呼任务
+type Campaign struct {
+	ID                   int64          `json:"id"`                     // 任务ID
+	Name                 string         `json:"name"`                   // 任务名称
+	Status               string         `json:"status"`                 // 任务状态
+	CallerID             string         `json:"caller_id"`              // 外显号码
+	PacingPerMinute      int            `json:"pacing_per_minute"`      // 每分钟最多发起的呼叫数
+	MaxAttempts          int            `json:"max_attempts"`           // 每条线索最多拨打次数
+	RetryIntervalSeconds int            `json:"retry_interval_seconds"` // 未接通时重拨间隔（秒）
+	WebhookURL           string         `json:"webhook_url,omitempty"`  // 线索状态变化Webhook地址，留空则不推送
+	DialMode             string         `json:"dial_mode"`              // 拨号方式，auto或preview
+	AgentExtension       string         `json:"agent_extension"`        // 预览拨号时坐席确认未指定分机所用的默认坐席分机
+	Leads                map[string]int `json:"leads,omitempty"`        // 各状态的线索数
+	CreatedAt            time.Time      `json:"created_at"`             // 创建时间
+	UpdatedAt            time.Time      `json:"updated_at"`             // 更新时间
+}
+
+// RetryInterval 重拨间隔
+func (c *Campaign) RetryInterval() time.Duration {
+	return time.Duration(c.RetryIntervalSeconds) * time.Second
+}
+
+// CampaignStats 外呼任务的通话统计
+type CampaignStats struct {
+	Attempts    int // 发起的呼叫数
+	Connects    int // 被叫接通的呼叫数
+	Abandoned   int // 接通后很快被挂断的呼叫数
+	Conversions int // 通话结果为转化的呼叫数
+}
+
+// CreateCampaignRequest 创建外呼任务请求
+type CreateCampaignRequest struct {
+	Name                 string      `json:"name" binding:"required"` // 任务名称
+	CallerID             string      `json:"caller_id"`               // 外显号码
+	PacingPerMinute      int         `json:"pacing_per_minute"`       // 每分钟最多发起的呼叫数，默认10
+	MaxAttempts          int         `json:"max_attempts"`            // 每条线索最多拨打次数，默认3
+	RetryIntervalSeconds int         `json:"retry_interval_seconds"`  // 未接通时重拨间隔（秒），默认3600
+	WebhookURL           string      `json:"webhook_url"`             // 线索状态变化Webhook地址，留空则不推送
+	DialMode             string      `json:"dial_mode"`               // 拨号方式，auto（默认）或preview
+	AgentExtension       string      `json:"agent_extension"`         // 预览拨号的默认坐席分机
+	Leads                []LeadInput `json:"leads"`                   // 拨打名单
+}
+
+// AddLeadsRequest 追加线索请求
+type AddLeadsRequest struct {
+	Leads []LeadInput `json:"leads" binding:"required"` // 拨打名单
+}
+
+// LeadInput 名单中的一条线索
+type LeadInput struct {
+	Phone string          `json:"phone" binding:"required"` // 电话号码
+	Name  string          `json:"name"`                     // 姓名
+	Data  json.RawMessage `json:"data,omitempty"`           // 自定义数据
+}
+
+// ToLeads 转换为线索
+func ToLeads(inputs []LeadInput) []*Lead {
+	leads := make([]*Lead, len(inputs))
+	for i, in := range inputs {
+		leads[i] = &Lead{Phone: in.Phone, Name: in.Name, Data: in.Data}
+	}
+	return leads
+}
+
+// ConfirmLeadRequest 坐席确认预览线索请求
+type ConfirmLeadRequest struct {
+	Agent string `json:"agent"` // 接听的坐席分机，留空使用任务的默认坐席分机
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// CDR 通话详单
+type CDR struct {
+	CallUUID    string     `json:"call_uuid"`             // 通话UUID
+	Caller      string     `json:"caller"`                // 主叫号码
+	Callee      string     `json:"callee"`                // 被叫号码
+	Gateway     string     `json:"gateway,omitempty"`     // 外呼落地网关，分机互拨为空
+	HangupCause string     `json:"hangup_cause"`          // 挂断原因
+	StartTime   time.Time  `json:"start_time"`            // 通道创建时间
+	AnswerTime  *time.Time `json:"answer_time,omitempty"` // 应答时间，未接通为空
+	EndTime     time.Time  `json:"end_time"`              // 挂断时间
+	BillSec     int        `json:"billsec"`               // 计费时长（秒）
+
+	RecordingConsent string `json:"recording_consent,omitempty"` // 录音授权结果：granted同意、refused拒绝，未确认时为空
+	ACWSec           int    `json:"acw_sec,omitempty"`           // 话后处理时长（秒），话后处理结束后写入
+}
+
+// GatewayStats 网关接通统计
+type GatewayStats struct {
+	Gateway  string  `json:"gateway"`  // 网关名称
+	Calls    int     `json:"calls"`    // 呼叫次数
+	Answered int     `json:"answered"` // 接通次数
+	BillSec  int     `json:"billsec"`  // 接通通话总时长（秒）
+	ASR      float64 `json:"asr"`      // 应答占用比（接通次数/呼叫次数）
+	ACD      float64 `json:"acd"`      // 平均通话时长（秒）
+	Degraded bool    `json:"degraded"` // 接通率过低，路由时排在最后
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// DatasetQuery 训练数据导出条件
+type DatasetQuery struct {
+	From         *time.Time `json:"from,omitempty"`        // 挂机时间起（含）
+	To           *time.Time `json:"to,omitempty"`          // 挂机时间止（不含）
+	CampaignID   *int64     `json:"campaign_id,omitempty"` // 外呼任务
+	Dispositions []string   `json:"dispositions"`          // 通话结果，为空时导出所有结果的通话
+	Limit        int        `json:"limit"`                 // 最多导出的通话数
+}
+
+// DatasetExport 训练数据导出结果
+type DatasetExport struct {
+	File      string    `json:"file"`       // 导出文件路径
+	Dialogs   int       `json:"dialogs"`    // 导出的对话数
+	Skipped   int       `json:"skipped"`    // 轮数不足未导出的通话数
+	CreatedAt time.Time `json:"created_at"` // 导出时间
+}
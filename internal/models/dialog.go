@@ -1,9 +1,95 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+)
+
 // Message 对话消息
 type Message struct {
-	Role    string `json:"role"`     // 消息角色：user/assistant
-	Content string `json:"content"`  // 消息内容
+	Role    string             `json:"role"`              // 消息角色：user/assistant/system/tool
+	Content string             `json:"content"`           // 消息内容
+	Options *GenerationOptions `json:"options,omitempty"` // 生成该回复时实际使用的大模型参数，仅assistant消息有
+}
+
+// 对话消息角色
+const (
+	RoleUser      = "user"      // 客户
+	RoleAssistant = "assistant" // AI
+	RoleSystem    = "system"    // 通话中由坐席或外部系统补充的背景信息
+	RoleTool      = "tool"      // 大模型调用工具的执行结果
+)
+
+// GenerationOptions 大模型生成参数
+type GenerationOptions struct {
+	Temperature float64 `json:"temperature" yaml:"temperature"` // 温度参数
+	TopP        float64 `json:"top_p" yaml:"top_p"`             // Top-p采样，0表示使用模型默认值
+	TopK        int     `json:"top_k" yaml:"top_k"`             // Top-k采样，0表示使用模型默认值
+	MaxTokens   int     `json:"max_tokens" yaml:"max_tokens"`   // 最大生成token数
+}
+
+// 生成参数的安全范围，超出范围的参数容易导致回复失控或单轮耗时过长
+const (
+	MaxTemperature = 1.5
+	MaxTopK        = 100
+	MaxMaxTokens   = 4096
+)
+
+// DefaultGenerationOptions 未配置时使用的生成参数
+var DefaultGenerationOptions = GenerationOptions{Temperature: 0.7, MaxTokens: 2048}
+
+// ErrInvalidGenerationOptions 生成参数超出安全范围
+var ErrInvalidGenerationOptions = errors.New("大模型参数无效")
+
+// Validate 检查生成参数是否在安全范围内
+func (o GenerationOptions) Validate() error {
+	if o.Temperature < 0 || o.Temperature > MaxTemperature {
+		return fmt.Errorf("%w: temperature 必须在 0 到 %.1f 之间", ErrInvalidGenerationOptions, MaxTemperature)
+	}
+	if o.TopP < 0 || o.TopP > 1 {
+		return fmt.Errorf("%w: top_p 必须在 0 到 1 之间", ErrInvalidGenerationOptions)
+	}
+	if o.TopK < 0 || o.TopK > MaxTopK {
+		return fmt.Errorf("%w: top_k 必须在 0 到 %d 之间", ErrInvalidGenerationOptions, MaxTopK)
+	}
+	if o.MaxTokens < 1 || o.MaxTokens > MaxMaxTokens {
+		return fmt.Errorf("%w: max_tokens 必须在 1 到 %d 之间", ErrInvalidGenerationOptions, MaxMaxTokens)
+	}
+	return nil
+}
+
+// GenerationOverrides 生成参数覆盖项，未设置的字段沿用上一级（全局、活动）配置
+type GenerationOverrides struct {
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature"`
+	TopP        *float64 `json:"top_p,omitempty" yaml:"top_p"`
+	TopK        *int     `json:"top_k,omitempty" yaml:"top_k"`
+	MaxTokens   *int     `json:"max_tokens,omitempty" yaml:"max_tokens"`
+}
+
+// Apply 将覆盖项应用到生成参数上，返回新的生成参数
+func (o GenerationOverrides) Apply(base GenerationOptions) GenerationOptions {
+	if o.Temperature != nil {
+		base.Temperature = *o.Temperature
+	}
+	if o.TopP != nil {
+		base.TopP = *o.TopP
+	}
+	if o.TopK != nil {
+		base.TopK = *o.TopK
+	}
+	if o.MaxTokens != nil {
+		base.MaxTokens = *o.MaxTokens
+	}
+	return base
+}
+
+// SessionOptions 会话级大模型参数设置
+type SessionOptions struct {
+	CampaignID string              `json:"campaign_id,omitempty"` // 所属活动，使用活动配置的参数
+	Prompt     string              `json:"prompt,omitempty"`      // 会话指定的提示词配置名称，留空时使用活动或默认配置
+	Overrides  GenerationOverrides `json:"overrides"`             // 会话级覆盖项，优先级高于活动配置
+	Model      string              `json:"model,omitempty"`       // 会话指定的大模型名称，为空时使用全局配置
+	Effective  GenerationOptions   `json:"effective"`             // 合并后实际生效的参数
 }
 
 // DialogResponse WebSocket响应消息
@@ -24,3 +110,20 @@ type DialogService interface {
 	// ClearHistory 清除对话历史
 	ClearHistory(sessionID string)
 }
+
+// StreamingDialogService 支持流式生成回复的对话服务
+type StreamingDialogService interface {
+	DialogService
+
+	// ProcessMessageStream 处理用户消息，每生成一个完整句子回调一次，返回完整回复
+	ProcessMessageStream(sessionID string, text string, onSentence func(sentence string) error) (string, error)
+}
+
+// TokenStreamingDialogService 支持逐段推送回复文本的对话服务
+type TokenStreamingDialogService interface {
+	StreamingDialogService
+
+	// ProcessMessageTokens 处理用户消息，每生成一个文本片段回调onToken，每凑成一个完整句子回调onSentence，
+	// 回调为nil时不调用，返回完整回复
+	ProcessMessageTokens(sessionID string, text string, onToken, onSentence func(text string) error) (string, error)
+}
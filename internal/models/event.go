@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// 通话实时事件类型
+const (
+	EventTypeTranscript   = "transcript"    // 语音识别结果
+	EventTypeDialog       = "dialog"        // AI回复
+	EventTypeSuggestion   = "suggestion"    // 坐席通话中AI给坐席的建议话术，不播放
+	EventTypeCallCreated  = "call_created"  // 通道创建
+	EventTypeCallAnswered = "call_answered" // 通道应答
+	EventTypeCallHangup   = "call_hangup"   // 通道挂断
+
+	EventTypeCampaignThrottle = "campaign_throttle" // 外呼自动限速状态变化，Text为原因
+	EventTypeSwitchConnection = "switch_connection" // FreeSWITCH连接断开或恢复，Text为状态
+)
+
+// CallEvent 通话实时事件，推送给监控面板
+type CallEvent struct {
+	Type      string    `json:"type"`             // 事件类型
+	SessionID string    `json:"session_id"`       // 会话ID
+	Speaker   string    `json:"speaker"`          // 说话方
+	Text      string    `json:"text"`             // 文本内容
+	IsFinal   bool      `json:"is_final"`         // 是否为最终结果
+	Caller    string    `json:"caller,omitempty"` // 主叫号码，仅通话状态事件
+	Callee    string    `json:"callee,omitempty"` // 被叫号码，仅通话状态事件
+	Cause     string    `json:"cause,omitempty"`  // 挂断原因，仅挂断事件
+	Instance  string    `json:"instance"`         // 产生事件的服务实例
+	Timestamp time.Time `json:"timestamp"`        // 事件时间
+}
+
+// EventPublisher 通话实时事件发布接口
+type EventPublisher interface {
+	// Publish 发布事件
+	Publish(ctx context.Context, event *CallEvent) error
+}
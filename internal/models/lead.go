@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 线索状态
+const (
+	LeadStatusQueued      = "queued"      // 待拨打
+	LeadStatusPreview     = "preview"     // 预览拨号任务中等待坐席确认，next_attempt_at为确认截止时间
+	LeadStatusDialing     = "dialing"     // 拨打中
+	LeadStatusCompleted   = "completed"   // 已完成
+	LeadStatusFailed      = "failed"      // 超过最大拨打次数
+	LeadStatusUnreachable = "unreachable" // 拨打前查询为空号或停机
+)
+
+// 线索状态变化的Webhook事件类型
+const (
+	LeadEventQueued         = "lead.queued"          // 线索加入拨打名单
+	LeadEventPreview        = "lead.preview"         // 预览拨号任务的线索等待坐席确认
+	LeadEventDialing        = "lead.dialing"         // 开始拨打
+	LeadEventRetryScheduled = "lead.retry_scheduled" // 未接通，已安排重拨
+	LeadEventExhausted      = "lead.exhausted"       // 达到最大拨打次数仍未接通
+	LeadEventUnreachable    = "lead.unreachable"     // 拨打前查询为空号或停机，不再拨打
+	LeadEventAnswered       = "lead.answered"        // 被叫接通，线索完成；接通不代表成交，成交结果由业务系统根据通话结果判断
+)
+
+// LeadEvent 线索状态变化事件，推送到外呼任务配置的Webhook地址
+type LeadEvent struct {
+	Event         string          `json:"event"`                     // 事件类型
+	CampaignID    int64           `json:"campaign_id"`               // 所属外呼任务
+	LeadID        int64           `json:"lead_id"`                   // 线索ID
+	Phone         string          `json:"phone"`                     // 电话号码
+	Name          string          `json:"name"`                      // 姓名
+	Status        string          `json:"status"`                    // 变化后的线索状态
+	Attempts      int             `json:"attempts"`                  // 已拨打次数
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // 下次拨打时间
+	CallUUID      string          `json:"call_uuid,omitempty"`       // 最近一次通话UUID
+	Data          json.RawMessage `json:"data,omitempty"`            // 自定义数据
+	OccurredAt    time.Time       `json:"occurred_at"`               // 发生时间
+}
+
+// Lead 外呼线索
+type Lead struct {
+	ID            int64           `json:"id"`                        // 线索ID
+	CampaignID    int64           `json:"campaign_id"`               // 所属外呼任务
+	Phone         string          `json:"phone"`                     // 电话号码
+	Name          string          `json:"name"`                      // 姓名
+	Status        string          `json:"status"`                    // 线索状态
+	Attempts      int             `json:"attempts"`                  // 已拨打次数
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // 下次拨打时间
+	LastCallUUID  string          `json:"last_call_uuid"`            // 最近一次通话UUID
+	Lookup        *NumberLookup   `json:"lookup,omitempty"`          // 最近一次号码状态查询结果，未查询过为空
+	Data          json.RawMessage `json:"data,omitempty"`            // 自定义数据
+	CreatedAt     time.Time       `json:"created_at"`                // 创建时间
+	UpdatedAt     time.Time       `json:"updated_at"`                // 更新时间
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 号码状态
+const (
+	NumberStatusActive       = "active"       // 正常
+	NumberStatusDisconnected = "disconnected" // 空号、停机或销号
+	NumberStatusUnknown      = "unknown"      // 无法确定
+)
+
+// NumberLookup 号码状态查询结果
+type NumberLookup struct {
+	Status    string    `json:"status"`     // 号码状态
+	Carrier   string    `json:"carrier"`    // 运营商
+	CheckedAt time.Time `json:"checked_at"` // 查询时间
+}
+
+// Reachable 号码是否可能接通，无法确定时视为可接通
+func (l NumberLookup) Reachable() bool {
+	return l.Status != NumberStatusDisconnected
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// 质检评估状态
+const (
+	QAStatusDone   = "done"   // 评估完成
+	QAStatusFailed = "failed" // 大模型评分失败，可手动重新评估
+)
+
+// QAScore 单个评分项的得分
+type QAScore struct {
+	Criterion string `json:"criterion"` // 评分项
+	Score     *int   `json:"score"`     // 得分（0-100），为空表示本通电话不适用，如客户没有提出异议
+	Passed    bool   `json:"passed"`    // 得分达到及格分
+	Reason    string `json:"reason"`    // 评分理由
+}
+
+// QAEvaluation 通话质检结果
+type QAEvaluation struct {
+	CallUUID    string     `json:"call_uuid"`             // 通话UUID
+	Status      string     `json:"status"`                // 评估状态
+	TotalScore  *float64   `json:"total_score,omitempty"` // 适用评分项按权重计算的总分
+	Passed      bool       `json:"passed"`                // 总分达到及格分且没有不及格的评分项
+	Error       string     `json:"error,omitempty"`       // 评估失败原因
+	Scores      []*QAScore `json:"scores"`                // 各评分项得分
+	EvaluatedAt time.Time  `json:"evaluated_at"`          // 评估时间
+}
+
+// QADashboardQuery 质检汇总条件
+type QADashboardQuery struct {
+	From       *time.Time // 评估时间起（含）
+	To         *time.Time // 评估时间止（不含）
+	CampaignID *int64     // 外呼任务
+}
+
+// QACriterionStats 单个评分项的汇总
+type QACriterionStats struct {
+	Criterion string  `json:"criterion"` // 评分项
+	Evaluated int     `json:"evaluated"` // 适用该评分项的通话数
+	AvgScore  float64 `json:"avg_score"` // 平均得分
+	PassRate  float64 `json:"pass_rate"` // 及格率
+}
+
+// QADashboard 质检汇总
+type QADashboard struct {
+	Evaluated int                 `json:"evaluated"` // 完成评估的通话数
+	Failed    int                 `json:"failed"`    // 评估失败的通话数
+	AvgScore  float64             `json:"avg_score"` // 平均总分
+	PassRate  float64             `json:"pass_rate"` // 及格率
+	Criteria  []*QACriterionStats `json:"criteria"`  // 各评分项汇总
+}
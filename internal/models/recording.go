@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Recording 通话录音，录音文件和同名的JSON元数据文件保存在对象存储中
+type Recording struct {
+	CallUUID    string     `json:"call_uuid"`             // 通话UUID
+	Key         string     `json:"key"`                   // 录音在对象存储中的键
+	ContentType string     `json:"content_type"`          // 录音格式的MIME类型
+	Size        int64      `json:"size"`                  // 录音文件大小（字节）
+	Channels    int        `json:"channels"`              // 声道数，无法解析原始录音时为0
+	DurationMs  int        `json:"duration_ms"`           // 录音时长（毫秒），无法解析原始录音时为0
+	Caller      string     `json:"caller"`                // 主叫号码
+	Callee      string     `json:"callee"`                // 被叫号码
+	StartedAt   *time.Time `json:"started_at,omitempty"`  // 通道创建时间
+	AnsweredAt  *time.Time `json:"answered_at,omitempty"` // 应答时间
+	EndedAt     *time.Time `json:"ended_at,omitempty"`    // 挂断时间
+	ArchivedAt  time.Time  `json:"archived_at"`           // 归档时间
+}
+
+// RecordingQuery 录音查询条件
+type RecordingQuery struct {
+	From   *time.Time // 挂断时间起（含）
+	To     *time.Time // 挂断时间止（不含）
+	Number string     // 主叫或被叫号码
+	Limit  int        // 返回条数
+	Offset int        // 偏移量
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// MessageTypeSession 建立WebSocket连接后服务端发送的第一条消息类型
+const MessageTypeSession = "session"
+
+// ErrSessionIDRequired 缺少会话ID
+var ErrSessionIDRequired = errors.New("缺少会话ID")
+
+// SessionMessage 会话建立消息，客户端后续请求需携带其中的会话ID
+type SessionMessage struct {
+	Type      string `json:"type"`       // 消息类型，固定为session
+	SessionID string `json:"session_id"` // 会话ID
+}
+
+// NewSessionID 生成UUIDv7格式的会话ID，按创建时间有序，便于日志和数据库按时间检索
+func NewSessionID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// NewSessionMessage 创建会话建立消息
+func NewSessionMessage(sessionID string) SessionMessage {
+	return SessionMessage{Type: MessageTypeSession, SessionID: sessionID}
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// 说话方
+const (
+	SpeakerCustomer = "customer" // 客户
+	SpeakerAI       = "ai"       // AI
+	SpeakerAgent    = "agent"    // 人工坐席
+	SpeakerSystem   = "system"   // 系统，用于通话事件
+)
+
+// 转写条目类型
+const (
+	TranscriptKindSpeech = "speech" // 客户语音识别结果、AI回复或坐席语音
+	TranscriptKindDTMF   = "dtmf"   // 客户按键
+	TranscriptKindEvent  = "event"  // 通话事件，如接通、挂断
+)
+
+// 客户情绪
+const (
+	SentimentPositive = "positive" // 正面
+	SentimentNeutral  = "neutral"  // 中性
+	SentimentNegative = "negative" // 负面
+)
+
+// Transcript 通话转写片段
+type Transcript struct {
+	ID        int64     `json:"id"`                  // 片段ID
+	CallUUID  string    `json:"call_uuid"`           // 通话UUID
+	Speaker   string    `json:"speaker"`             // 说话方
+	Kind      string    `json:"kind,omitempty"`      // 条目类型，为空时为speech
+	Text      string    `json:"text"`                // 文本内容
+	StartMs   int       `json:"start_ms"`            // 相对通话开始的起始时间（毫秒）
+	EndMs     int       `json:"end_ms"`              // 相对通话开始的结束时间（毫秒）
+	Language  string    `json:"language,omitempty"`  // 识别的语种，如 zh、en
+	Sentiment string    `json:"sentiment,omitempty"` // 客户语音的情绪，未启用情绪识别时为空
+	Romanized string    `json:"romanized,omitempty"` // 中文的拼音转写，只在查询时按需生成，不存储
+	CreatedAt time.Time `json:"created_at"`          // 创建时间
+}
+
+// TranscriptSearchQuery 转写检索条件
+type TranscriptSearchQuery struct {
+	Query       string     // 检索短语
+	From        *time.Time // 起始时间（含）
+	To          *time.Time // 截止时间（不含）
+	CampaignID  *int64     // 外呼任务
+	Disposition string     // 通话结果
+	Tag         string     // 通话标签
+	Romanize    bool       // 是否为中文片段附加拼音转写
+	Limit       int        // 返回条数
+	Offset      int        // 偏移量
+}
+
+// TranscriptHit 转写检索结果
+type TranscriptHit struct {
+	Transcript
+	CampaignID  *int64  `json:"campaign_id,omitempty"` // 所属外呼任务
+	Disposition string  `json:"disposition"`           // 通话结果
+	Score       float64 `json:"score"`                 // 相关度
+}
+
+// TranscriptFilter 导出通话转写的过滤条件
+type TranscriptFilter struct {
+	From     *time.Duration // 相对通话开始的起始时间（含）
+	To       *time.Duration // 相对通话开始的截止时间（不含）
+	Speakers []string       // 说话方，为空时不过滤
+}
+
+// SentimentSummary 通话的客户情绪汇总，用于安排人工跟进的优先级
+type SentimentSummary struct {
+	CallUUID   string  `json:"call_uuid"`  // 通话UUID
+	Utterances int     `json:"utterances"` // 标注了情绪的客户语句数
+	Positive   int     `json:"positive"`   // 正面语句数
+	Neutral    int     `json:"neutral"`    // 中性语句数
+	Negative   int     `json:"negative"`   // 负面语句数
+	Score      float64 `json:"score"`      // 情绪得分（-1到1），(正面数-负面数)/语句数，越低越需要优先跟进
+	Overall    string  `json:"overall"`    // 整体情绪，得分为正为正面、为负为负面
+	Last       string  `json:"last"`       // 最后一句的情绪，反映挂机前客户的状态
+	FollowUp   bool    `json:"follow_up"`  // 建议人工跟进：负面语句占比不低于30%或最后一句为负面
+}
@@ -2,8 +2,10 @@ package models
 
 // WhisperRequest mod_whisper 请求结构
 type WhisperRequest struct {
-	Grammar string `json:"grammar,omitempty"`
-	Data    struct {
+	Grammar  string `json:"grammar,omitempty"`
+	Model    string `json:"model,omitempty"`    // 识别模型，只在第一帧携带
+	Language string `json:"language,omitempty"` // 识别语言，只在第一帧携带
+	Data     struct {
 		Status   int    `json:"status"`
 		Format   string `json:"format"`
 		Audio    string `json:"audio"`
@@ -13,8 +15,9 @@ type WhisperRequest struct {
 
 // WhisperResponse mod_whisper 响应结构
 type WhisperResponse struct {
-	Type       string  `json:"type"`
+	Type       string  `json:"type" ws:"result"`
 	Text       string  `json:"text,omitempty"`
 	Confidence float64 `json:"confidence,omitempty"`
 	Error      string  `json:"error,omitempty"`
+	Final      bool    `json:"final,omitempty"` // 服务端检测到句尾，Text为该句的最终结果
 }
@@ -0,0 +1,307 @@
+// Package recorder 实现第三方服务（ASR/LLM）原始请求与响应的"飞行记录仪"
+// 按通话抽样记录，脱敏后写入对象存储，用于离线排查服务商侧问题
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/clients/storage"
+)
+
+// 记录方向
+const (
+	KindRequest  = "request"  // 发往服务商的请求
+	KindResponse = "response" // 服务商返回的响应
+)
+
+// ErrNotFound 通话没有记录
+var ErrNotFound = errors.New("通话没有飞行记录")
+
+// segmentRetention 已写入存储且没有新记录的通话缓冲保留时间，期间新记录继续写入后续分段
+const segmentRetention = 24 * time.Hour
+
+// Config 飞行记录仪配置
+type Config struct {
+	Enabled     bool          `yaml:"enabled"`      // 是否启用
+	SampleRate  float64       `yaml:"sample_rate"`  // 抽样比例，0~1
+	MaxEntries  int           `yaml:"max_entries"`  // 单个通话最多记录条数，超出部分丢弃
+	IdleTimeout time.Duration `yaml:"idle_timeout"` // 通话无新记录超过该时间后自动写入存储
+	Prefix      string        `yaml:"prefix"`       // 对象键前缀
+}
+
+// Hook 供第三方服务客户端调用的记录接口
+type Hook interface {
+	// Record 记录一条原始报文，ctx中没有通话UUID或通话未被抽中时忽略
+	Record(ctx context.Context, provider, kind string, payload []byte)
+}
+
+// Entry 一条记录
+type Entry struct {
+	Time     time.Time       `json:"time"`     // 记录时间
+	Provider string          `json:"provider"` // 服务商，如xfyun、ollama
+	Kind     string          `json:"kind"`     // 请求或响应
+	Payload  json.RawMessage `json:"payload"`  // 脱敏后的报文，非JSON报文以字符串保存
+}
+
+// callBuffer 单个通话的记录缓冲，空闲写入后保留分段序号，后续记录写入下一个分段
+type callBuffer struct {
+	entries  []Entry
+	total    int // 已记录条数，含已写入存储的分段
+	dropped  int
+	segments int // 已写入存储的分段数
+	updated  time.Time
+}
+
+// Recorder 飞行记录仪
+type Recorder struct {
+	store  storage.Store
+	config Config
+
+	mu    sync.Mutex
+	calls map[string]*callBuffer
+}
+
+// New 创建飞行记录仪
+func New(store storage.Store, config Config) *Recorder {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 2000
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = 5 * time.Minute
+	}
+	if config.Prefix == "" {
+		config.Prefix = "flight-recorder"
+	}
+	return &Recorder{
+		store:  store,
+		config: config,
+		calls:  make(map[string]*callBuffer),
+	}
+}
+
+// Sampled 判断通话是否被抽中，同一通话在所有实例上的结果一致
+func (r *Recorder) Sampled(callUUID string) bool {
+	if r == nil || !r.config.Enabled || callUUID == "" {
+		return false
+	}
+	if r.config.SampleRate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(callUUID))
+	return float64(h.Sum32()%10000) < r.config.SampleRate*10000
+}
+
+// Record 记录一条原始报文
+func (r *Recorder) Record(ctx context.Context, provider, kind string, payload []byte) {
+	callUUID := CallFromContext(ctx)
+	if !r.Sampled(callUUID) {
+		return
+	}
+
+	entry := Entry{
+		Time:     time.Now(),
+		Provider: provider,
+		Kind:     kind,
+		Payload:  toJSON(Redact(payload)),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buf, ok := r.calls[callUUID]
+	if !ok {
+		buf = &callBuffer{}
+		r.calls[callUUID] = buf
+	}
+	buf.updated = entry.Time
+	if buf.total >= r.config.MaxEntries {
+		buf.dropped++
+		return
+	}
+	buf.entries = append(buf.entries, entry)
+	buf.total++
+}
+
+// Flush 将通话剩余的记录写入对象存储并释放缓冲，通话结束时调用
+func (r *Recorder) Flush(ctx context.Context, callUUID string) error {
+	if r == nil {
+		return nil
+	}
+	return r.flush(ctx, callUUID, true)
+}
+
+// flush 将通话缓冲中的记录写入一个新分段，final为true时释放缓冲
+// 每个分段使用独立的对象键，空闲写入后通话继续产生的记录不会覆盖之前的分段
+func (r *Recorder) flush(ctx context.Context, callUUID string, final bool) error {
+	r.mu.Lock()
+	buf, ok := r.calls[callUUID]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	if final {
+		delete(r.calls, callUUID)
+	}
+	entries, dropped := buf.entries, buf.dropped
+	buf.entries, buf.dropped = nil, 0
+	if len(entries) > 0 {
+		buf.segments++
+	}
+	seq := buf.segments
+	r.mu.Unlock()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("序列化飞行记录失败: %v", err)
+		}
+	}
+	if dropped > 0 {
+		log.Printf("通话 %s 的飞行记录超过上限，丢弃了 %d 条", callUUID, dropped)
+	}
+
+	if err := r.store.Put(ctx, r.segmentKey(callUUID, seq), data.Bytes(), "application/x-ndjson"); err != nil {
+		return fmt.Errorf("保存飞行记录失败: %v", err)
+	}
+	return nil
+}
+
+// Get 按分段顺序读取通话的飞行记录
+func (r *Recorder) Get(ctx context.Context, callUUID string) ([]Entry, error) {
+	var entries []Entry
+	for seq := 1; ; seq++ {
+		data, err := r.store.Get(ctx, r.segmentKey(callUUID, seq))
+		if errors.Is(err, storage.ErrNotFound) && seq == 1 {
+			// 兼容按通话写入单个对象的旧记录
+			data, err = r.store.Get(ctx, r.legacyKey(callUUID))
+			if errors.Is(err, storage.ErrNotFound) {
+				return nil, ErrNotFound
+			}
+			if err != nil {
+				return nil, err
+			}
+			return decodeEntries(data)
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		segment, err := decodeEntries(data)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, segment...)
+	}
+}
+
+// decodeEntries 解析ndjson格式的记录
+func decodeEntries(data []byte) ([]Entry, error) {
+	var entries []Entry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry Entry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("解析飞行记录失败: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Run 定期将空闲通话的记录写入存储，防止未正常结束的通话记录一直留在内存
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.flushIdle(ctx, now.Add(-r.config.IdleTimeout))
+		}
+	}
+}
+
+// FlushAll 写入所有通话的记录，服务关闭时调用
+func (r *Recorder) FlushAll(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.flushIdle(ctx, time.Time{})
+}
+
+// flushIdle 写入最后记录时间早于before的通话，before为零值时写入全部通话并释放缓冲
+// 空闲写入的通话保留缓冲中的分段序号，长时间没有新记录时才释放
+func (r *Recorder) flushIdle(ctx context.Context, before time.Time) {
+	final := before.IsZero()
+	r.mu.Lock()
+	var idle []string
+	for callUUID, buf := range r.calls {
+		switch {
+		case final || (len(buf.entries) > 0 && buf.updated.Before(before)):
+			idle = append(idle, callUUID)
+		case len(buf.entries) == 0 && buf.updated.Before(before.Add(-segmentRetention)):
+			delete(r.calls, callUUID)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, callUUID := range idle {
+		if err := r.flush(ctx, callUUID, final); err != nil {
+			log.Printf("写入飞行记录失败: %s, %v", callUUID, err)
+		}
+	}
+}
+
+// segmentKey 获取通话记录分段的对象键，分段序号从1开始
+func (r *Recorder) segmentKey(callUUID string, seq int) string {
+	return fmt.Sprintf("%s/%s/%d.ndjson", r.config.Prefix, callUUID, seq)
+}
+
+// legacyKey 旧版本按通话写入单个对象的键
+func (r *Recorder) legacyKey(callUUID string) string {
+	return fmt.Sprintf("%s/%s.ndjson", r.config.Prefix, callUUID)
+}
+
+// toJSON 报文为合法JSON时原样保存，否则保存为JSON字符串
+func toJSON(payload []byte) json.RawMessage {
+	if json.Valid(payload) {
+		return json.RawMessage(payload)
+	}
+	data, _ := json.Marshal(string(payload))
+	return data
+}
+
+// callKey ctx中保存通话UUID的键
+type callKey struct{}
+
+// WithCall 在ctx中标记当前通话，客户端据此将报文归属到通话
+func WithCall(ctx context.Context, callUUID string) context.Context {
+	return context.WithValue(ctx, callKey{}, callUUID)
+}
+
+// CallFromContext 获取ctx中的通话UUID
+func CallFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	callUUID, _ := ctx.Value(callKey{}).(string)
+	return callUUID
+}
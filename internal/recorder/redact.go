@@ -0,0 +1,23 @@
+package recorder
+
+import "regexp"
+
+// 脱敏规则
+var (
+	// secretFieldPattern JSON中的密钥类字段
+	secretFieldPattern = regexp.MustCompile(`(?i)("(?:api_?key|api_?secret|app_?id|authorization|access_?key|secret(?:_?key)?|token|access_token|password)"\s*:\s*)"[^"]*"`)
+
+	// secretQueryPattern URL查询参数中的签名和密钥
+	secretQueryPattern = regexp.MustCompile(`(?i)([?&](?:authorization|signature|api_?key|token|access_token)=)[^&"\s]+`)
+
+	// audioFieldPattern JSON中的Base64音频数据，体积大且无排查价值
+	audioFieldPattern = regexp.MustCompile(`("audio"\s*:\s*)"[^"]*"`)
+)
+
+// Redact 对报文脱敏：隐藏密钥、签名，省略音频数据
+func Redact(payload []byte) []byte {
+	payload = secretFieldPattern.ReplaceAll(payload, []byte(`$1"***"`))
+	payload = secretQueryPattern.ReplaceAll(payload, []byte(`$1***`))
+	payload = audioFieldPattern.ReplaceAll(payload, []byte(`$1"<音频已省略>"`))
+	return payload
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/models"
+)
+
+// AnnotationRepo 通话标签和备注仓储
+type AnnotationRepo struct {
+	db      DBTX
+	dialect database.Dialect
+}
+
+// NewAnnotationRepo 创建通话标签和备注仓储
+func NewAnnotationRepo(db DBTX) *AnnotationRepo {
+	return &AnnotationRepo{db: db}
+}
+
+// AddTag 为通话添加标签，已存在的标签保持原添加人和时间
+func (r *AnnotationRepo) AddTag(ctx context.Context, callUUID string, tag *models.CallTag) error {
+	tag.CreatedAt = time.Now()
+	_, err := r.db.ExecContext(ctx,
+		r.dialect.InsertIgnore()+` INTO call_tags (call_uuid, tag, author, created_at) VALUES (?, ?, ?, ?)`,
+		callUUID, tag.Tag, tag.Author, tag.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("添加通话标签失败: %v", err)
+	}
+	return nil
+}
+
+// AddNote 为通话添加备注，成功后回填ID
+func (r *AnnotationRepo) AddNote(ctx context.Context, note *models.CallNote) error {
+	note.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO call_notes (call_uuid, author, note, offset_ms, created_at) VALUES (?, ?, ?, ?, ?)`,
+		note.CallUUID, note.Author, note.Note, note.OffsetMs, note.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("添加通话备注失败: %v", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取备注ID失败: %v", err)
+	}
+	note.ID = id
+	return nil
+}
+
+// ListTags 查询通话的标签，按添加时间顺序
+func (r *AnnotationRepo) ListTags(ctx context.Context, callUUID string) ([]*models.CallTag, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT tag, author, created_at FROM call_tags WHERE call_uuid = ? ORDER BY created_at, tag`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询通话标签失败: %v", err)
+	}
+	defer rows.Close()
+
+	var tags []*models.CallTag
+	for rows.Next() {
+		var tag models.CallTag
+		if err := rows.Scan(&tag.Tag, &tag.Author, &tag.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取通话标签失败: %v", err)
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取通话标签失败: %v", err)
+	}
+	return tags, nil
+}
+
+// ListNotes 查询通话的备注，按添加时间顺序
+func (r *AnnotationRepo) ListNotes(ctx context.Context, callUUID string) ([]*models.CallNote, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, call_uuid, author, note, offset_ms, created_at FROM call_notes
+		 WHERE call_uuid = ? ORDER BY created_at, id`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询通话备注失败: %v", err)
+	}
+	defer rows.Close()
+
+	var notes []*models.CallNote
+	for rows.Next() {
+		var (
+			note     models.CallNote
+			offsetMs sql.NullInt64
+		)
+		if err := rows.Scan(&note.ID, &note.CallUUID, &note.Author, &note.Note, &offsetMs, &note.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取通话备注失败: %v", err)
+		}
+		if offsetMs.Valid {
+			ms := int(offsetMs.Int64)
+			note.OffsetMs = &ms
+		}
+		notes = append(notes, &note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取通话备注失败: %v", err)
+	}
+	return notes, nil
+}
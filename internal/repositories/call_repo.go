@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/models"
+)
+
+// callColumns 通话表查询列
+const callColumns = `call_uuid, campaign_id, lead_id, direction, caller, callee, status, disposition,
+	started_at, answered_at, ended_at, created_at, updated_at`
+
+// CallRepo 通话记录仓储
+type CallRepo struct {
+	db      DBTX
+	dialect database.Dialect
+}
+
+// NewCallRepo 创建通话记录仓储
+func NewCallRepo(db DBTX) *CallRepo {
+	return &CallRepo{db: db}
+}
+
+// Create 创建通话记录
+func (r *CallRepo) Create(ctx context.Context, call *models.Call) error {
+	now := time.Now()
+	if call.Status == "" {
+		call.Status = models.CallStatusCreated
+	}
+	if call.Direction == "" {
+		call.Direction = "outbound"
+	}
+	call.CreatedAt = now
+	call.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO calls (call_uuid, campaign_id, lead_id, direction, caller, callee, status, disposition, started_at, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		call.CallUUID, call.CampaignID, call.LeadID, call.Direction, call.Caller, call.Callee,
+		call.Status, call.Disposition, call.StartedAt, call.CreatedAt, call.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("创建通话记录失败: %v", err)
+	}
+	return nil
+}
+
+// Get 按UUID查询通话记录，不存在时返回ErrNotFound
+func (r *CallRepo) Get(ctx context.Context, callUUID string) (*models.Call, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+callColumns+` FROM calls WHERE call_uuid = ?`, callUUID)
+	call, err := scanCall(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询通话记录失败: %v", err)
+	}
+	return call, nil
+}
+
+// ListByLead 查询线索的全部通话记录，按创建时间升序
+func (r *CallRepo) ListByLead(ctx context.Context, leadID int64) ([]*models.Call, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+callColumns+` FROM calls WHERE lead_id = ? ORDER BY created_at`, leadID)
+	if err != nil {
+		return nil, fmt.Errorf("查询通话记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	var calls []*models.Call
+	for rows.Next() {
+		call, err := scanCall(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取通话记录失败: %v", err)
+		}
+		calls = append(calls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取通话记录失败: %v", err)
+	}
+	return calls, nil
+}
+
+// MarkAnswered 标记通话已接通
+func (r *CallRepo) MarkAnswered(ctx context.Context, callUUID string, at time.Time) error {
+	return r.update(ctx,
+		`UPDATE calls SET status = ?, answered_at = ?, updated_at = ? WHERE call_uuid = ?`,
+		models.CallStatusAnswered, at, time.Now(), callUUID)
+}
+
+// MarkEnded 标记通话已结束并记录通话结果
+func (r *CallRepo) MarkEnded(ctx context.Context, callUUID string, at time.Time, disposition string) error {
+	return r.update(ctx,
+		`UPDATE calls SET status = ?, ended_at = ?, disposition = ?, updated_at = ? WHERE call_uuid = ?`,
+		models.CallStatusEnded, at, disposition, time.Now(), callUUID)
+}
+
+// SetDisposition 修改通话结果
+func (r *CallRepo) SetDisposition(ctx context.Context, callUUID, disposition string) error {
+	return r.update(ctx,
+		`UPDATE calls SET disposition = ?, updated_at = ? WHERE call_uuid = ?`,
+		disposition, time.Now(), callUUID)
+}
+
+// update 执行更新语句，没有匹配的记录时返回ErrNotFound
+func (r *CallRepo) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("更新通话记录失败: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListForDataset 查询已结束且有转写的通话，用于导出训练数据，可按挂机时间、外呼任务、通话结果过滤，按挂机时间顺序
+func (r *CallRepo) ListForDataset(ctx context.Context, q models.DatasetQuery) ([]*models.Call, error) {
+	var (
+		where = []string{"status = ?", "EXISTS (SELECT 1 FROM transcripts t WHERE t.call_uuid = calls.call_uuid)"}
+		args  = []interface{}{models.CallStatusEnded}
+	)
+	if q.From != nil {
+		where = append(where, "ended_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "ended_at < ?")
+		args = append(args, *q.To)
+	}
+	if q.CampaignID != nil {
+		where = append(where, "campaign_id = ?")
+		args = append(args, *q.CampaignID)
+	}
+	if len(q.Dispositions) > 0 {
+		where = append(where, "disposition IN (?"+strings.Repeat(", ?", len(q.Dispositions)-1)+")")
+		for _, d := range q.Dispositions {
+			args = append(args, d)
+		}
+	}
+	args = append(args, q.Limit)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+callColumns+` FROM calls WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY ended_at, call_uuid LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询待导出通话失败: %v", err)
+	}
+	defer rows.Close()
+
+	var calls []*models.Call
+	for rows.Next() {
+		call, err := scanCall(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取待导出通话失败: %v", err)
+		}
+		calls = append(calls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取待导出通话失败: %v", err)
+	}
+	return calls, nil
+}
+
+// CampaignStats 统计外呼任务的通话：接通后不足abandon即挂断的计为放弃，通话结果属于conversions的计为转化
+func (r *CallRepo) CampaignStats(ctx context.Context, campaignID int64, abandon time.Duration, conversions []string) (models.CampaignStats, error) {
+	converted, args := "0", []interface{}{abandon.Microseconds()}
+	if len(conversions) > 0 {
+		converted = "disposition IN (?" + strings.Repeat(", ?", len(conversions)-1) + ")"
+		for _, d := range conversions {
+			args = append(args, d)
+		}
+	}
+	args = append(args, campaignID)
+
+	var stats models.CampaignStats
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(answered_at),
+		 COALESCE(SUM(answered_at IS NOT NULL AND ended_at IS NOT NULL AND `+r.dialect.MicrosecondsBetween("answered_at", "ended_at")+` < ?), 0),
+		 COALESCE(SUM(`+converted+`), 0)
+		 FROM calls WHERE campaign_id = ?`, args...).
+		Scan(&stats.Attempts, &stats.Connects, &stats.Abandoned, &stats.Conversions)
+	if err != nil {
+		return stats, fmt.Errorf("统计外呼任务通话失败: %v", err)
+	}
+	return stats, nil
+}
+
+// rowScanner *sql.Row与*sql.Rows的公共扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCall 读取一行通话记录
+func scanCall(row rowScanner) (*models.Call, error) {
+	var (
+		call                           models.Call
+		campaignID, leadID             sql.NullInt64
+		startedAt, answeredAt, endedAt sql.NullTime
+	)
+	err := row.Scan(&call.CallUUID, &campaignID, &leadID, &call.Direction, &call.Caller, &call.Callee,
+		&call.Status, &call.Disposition, &startedAt, &answeredAt, &endedAt, &call.CreatedAt, &call.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	call.CampaignID = int64Ptr(campaignID)
+	call.LeadID = int64Ptr(leadID)
+	call.StartedAt = timePtr(startedAt)
+	call.AnsweredAt = timePtr(answeredAt)
+	call.EndedAt = timePtr(endedAt)
+	return &call, nil
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// campaignColumns 外呼任务表查询列
+const campaignColumns = `id, name, status, caller_id, pacing_per_minute, max_attempts, retry_interval_seconds, webhook_url, dial_mode, agent_extension, created_at, updated_at`
+
+// CampaignRepo 外呼任务仓储
+type CampaignRepo struct {
+	db DBTX
+}
+
+// NewCampaignRepo 创建外呼任务仓储
+func NewCampaignRepo(db DBTX) *CampaignRepo {
+	return &CampaignRepo{db: db}
+}
+
+// Create 创建外呼任务，成功后回填ID
+func (r *CampaignRepo) Create(ctx context.Context, campaign *models.Campaign) error {
+	now := time.Now()
+	if campaign.Status == "" {
+		campaign.Status = models.CampaignStatusDraft
+	}
+	if campaign.DialMode == "" {
+		campaign.DialMode = models.DialModeAuto
+	}
+	campaign.CreatedAt = now
+	campaign.UpdatedAt = now
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO campaigns (name, status, caller_id, pacing_per_minute, max_attempts, retry_interval_seconds, webhook_url, dial_mode, agent_extension, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		campaign.Name, campaign.Status, campaign.CallerID, campaign.PacingPerMinute, campaign.MaxAttempts,
+		campaign.RetryIntervalSeconds, campaign.WebhookURL, campaign.DialMode, campaign.AgentExtension, now, now)
+	if err != nil {
+		return fmt.Errorf("创建外呼任务失败: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取外呼任务ID失败: %v", err)
+	}
+	campaign.ID = id
+	return nil
+}
+
+// Get 按ID查询外呼任务，不存在时返回ErrNotFound
+func (r *CampaignRepo) Get(ctx context.Context, id int64) (*models.Campaign, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+campaignColumns+` FROM campaigns WHERE id = ?`, id)
+	campaign, err := scanCampaign(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询外呼任务失败: %v", err)
+	}
+	return campaign, nil
+}
+
+// List 查询外呼任务，status为空时返回全部，按ID升序
+func (r *CampaignRepo) List(ctx context.Context, status string) ([]*models.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaigns`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询外呼任务失败: %v", err)
+	}
+	defer rows.Close()
+
+	var campaigns []*models.Campaign
+	for rows.Next() {
+		campaign, err := scanCampaign(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取外呼任务失败: %v", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取外呼任务失败: %v", err)
+	}
+	return campaigns, nil
+}
+
+// UpdateStatus 更新外呼任务状态，from非空时只在当前状态为from之一时更新，否则返回ErrNotFound
+func (r *CampaignRepo) UpdateStatus(ctx context.Context, id int64, status string, from ...string) error {
+	query := `UPDATE campaigns SET status = ?, updated_at = ? WHERE id = ?`
+	args := []interface{}{status, time.Now(), id}
+	if len(from) > 0 {
+		query += ` AND status IN (?` + strings.Repeat(", ?", len(from)-1) + `)`
+		for _, s := range from {
+			args = append(args, s)
+		}
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("更新外呼任务失败: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanCampaign 读取一行外呼任务
+func scanCampaign(row rowScanner) (*models.Campaign, error) {
+	var c models.Campaign
+	err := row.Scan(&c.ID, &c.Name, &c.Status, &c.CallerID, &c.PacingPerMinute, &c.MaxAttempts,
+		&c.RetryIntervalSeconds, &c.WebhookURL, &c.DialMode, &c.AgentExtension, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
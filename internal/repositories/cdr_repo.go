@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/models"
+)
+
+// CDRRepo 通话详单仓储
+type CDRRepo struct {
+	db      DBTX
+	dialect database.Dialect
+}
+
+// NewCDRRepo 创建通话详单仓储
+func NewCDRRepo(db DBTX) *CDRRepo {
+	return &CDRRepo{db: db}
+}
+
+// Insert 写入通话详单，同一通话重复写入会被忽略
+func (r *CDRRepo) Insert(ctx context.Context, cdr models.CDR) error {
+	_, err := r.db.ExecContext(ctx,
+		r.dialect.InsertIgnore()+` INTO cdr (call_uuid, caller, callee, gateway, hangup_cause, start_time, answer_time, end_time, billsec, recording_consent, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		cdr.CallUUID, cdr.Caller, cdr.Callee, cdr.Gateway, cdr.HangupCause, cdr.StartTime, cdr.AnswerTime, cdr.EndTime, cdr.BillSec,
+		cdr.RecordingConsent, time.Now())
+	if err != nil {
+		return fmt.Errorf("写入通话详单失败: %v", err)
+	}
+	return nil
+}
+
+// SetACW 记录话后处理时长，详单不存在时返回ErrNotFound
+func (r *CDRRepo) SetACW(ctx context.Context, callUUID string, acwSec int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE cdr SET acw_sec = ? WHERE call_uuid = ?`, acwSec, callUUID)
+	if err != nil {
+		return fmt.Errorf("更新话后处理时长失败: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GatewayStats 按网关汇总指定时间之后开始的外呼，不含未经网关的分机互拨；tag不为空时只统计带该标签的通话
+func (r *CDRRepo) GatewayStats(ctx context.Context, since time.Time, tag string) ([]*models.GatewayStats, error) {
+	query := `SELECT gateway, COUNT(*), COUNT(answer_time), COALESCE(SUM(billsec), 0) FROM cdr
+		 WHERE gateway <> '' AND start_time >= ?`
+	args := []interface{}{since}
+	if tag != "" {
+		query += ` AND EXISTS (SELECT 1 FROM call_tags ct WHERE ct.call_uuid = cdr.call_uuid AND ct.tag = ?)`
+		args = append(args, tag)
+	}
+	rows, err := r.db.QueryContext(ctx, query+` GROUP BY gateway ORDER BY gateway`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("统计网关接通率失败: %v", err)
+	}
+	defer rows.Close()
+
+	var stats []*models.GatewayStats
+	for rows.Next() {
+		var s models.GatewayStats
+		if err := rows.Scan(&s.Gateway, &s.Calls, &s.Answered, &s.BillSec); err != nil {
+			return nil, fmt.Errorf("读取网关统计失败: %v", err)
+		}
+		if s.Calls > 0 {
+			s.ASR = float64(s.Answered) / float64(s.Calls)
+		}
+		if s.Answered > 0 {
+			s.ACD = float64(s.BillSec) / float64(s.Answered)
+		}
+		stats = append(stats, &s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/models"
+)
+
+// leadColumns 线索表查询列
+const leadColumns = `id, campaign_id, phone, name, status, attempts, next_attempt_at, last_call_uuid,
+	lookup_status, lookup_carrier, looked_up_at, data, created_at, updated_at`
+
+// LeadRepo 外呼线索仓储
+type LeadRepo struct {
+	db      DBTX
+	dialect database.Dialect
+}
+
+// NewLeadRepo 创建外呼线索仓储
+func NewLeadRepo(db DBTX) *LeadRepo {
+	return &LeadRepo{db: db}
+}
+
+// Create 创建线索，成功后回填ID
+func (r *LeadRepo) Create(ctx context.Context, lead *models.Lead) error {
+	now := time.Now()
+	if lead.Status == "" {
+		lead.Status = models.LeadStatusQueued
+	}
+	lead.CreatedAt = now
+	lead.UpdatedAt = now
+
+	var data interface{}
+	if len(lead.Data) > 0 {
+		data = []byte(lead.Data)
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO leads (campaign_id, phone, name, status, attempts, next_attempt_at, data, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		lead.CampaignID, lead.Phone, lead.Name, lead.Status, lead.Attempts, lead.NextAttemptAt, data, now, now)
+	if err != nil {
+		return fmt.Errorf("创建线索失败: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取线索ID失败: %v", err)
+	}
+	lead.ID = id
+	return nil
+}
+
+// Get 按ID查询线索，不存在时返回ErrNotFound
+func (r *LeadRepo) Get(ctx context.Context, id int64) (*models.Lead, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+leadColumns+` FROM leads WHERE id = ?`, id)
+	lead, err := scanLead(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询线索失败: %v", err)
+	}
+	return lead, nil
+}
+
+// GetForUpdate 按ID查询并锁定线索，必须在事务中调用，不存在时返回ErrNotFound
+func (r *LeadRepo) GetForUpdate(ctx context.Context, id int64) (*models.Lead, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+leadColumns+` FROM leads WHERE id = ?`+r.dialect.ForUpdate(), id)
+	lead, err := scanLead(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询线索失败: %v", err)
+	}
+	return lead, nil
+}
+
+// ClaimDue 锁定并返回外呼任务中到期待拨打的线索
+// 必须在事务中调用，行锁在事务结束前有效，其他实例会跳过已锁定的线索
+func (r *LeadRepo) ClaimDue(ctx context.Context, campaignID int64, now time.Time, limit int) ([]*models.Lead, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+leadColumns+` FROM leads
+		 WHERE campaign_id = ? AND status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+		 ORDER BY id LIMIT ?`+r.dialect.ForUpdateSkipLocked(),
+		campaignID, models.LeadStatusQueued, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询待拨打线索失败: %v", err)
+	}
+	defer rows.Close()
+
+	var leads []*models.Lead
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取线索失败: %v", err)
+		}
+		leads = append(leads, lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取线索失败: %v", err)
+	}
+	return leads, nil
+}
+
+// ListByStatus 查询外呼任务中指定状态的线索，按ID升序
+func (r *LeadRepo) ListByStatus(ctx context.Context, campaignID int64, status string) ([]*models.Lead, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+leadColumns+` FROM leads WHERE campaign_id = ? AND status = ? ORDER BY id`,
+		campaignID, status)
+	if err != nil {
+		return nil, fmt.Errorf("查询线索失败: %v", err)
+	}
+	defer rows.Close()
+
+	var leads []*models.Lead
+	for rows.Next() {
+		lead, err := scanLead(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取线索失败: %v", err)
+		}
+		leads = append(leads, lead)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取线索失败: %v", err)
+	}
+	return leads, nil
+}
+
+// ReleaseExpiredPreviews 将外呼任务中超过确认截止时间的预览线索重新排队，返回重新排队的线索数
+func (r *LeadRepo) ReleaseExpiredPreviews(ctx context.Context, campaignID int64, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE leads SET status = ?, next_attempt_at = NULL, updated_at = ?
+		 WHERE campaign_id = ? AND status = ? AND next_attempt_at <= ?`,
+		models.LeadStatusQueued, now, campaignID, models.LeadStatusPreview, now)
+	if err != nil {
+		return 0, fmt.Errorf("释放过期预览线索失败: %v", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("释放过期预览线索失败: %v", err)
+	}
+	return n, nil
+}
+
+// RecordAttempt 记录一次拨打，拨打次数加一
+func (r *LeadRepo) RecordAttempt(ctx context.Context, id int64, callUUID string) error {
+	return r.update(ctx,
+		`UPDATE leads SET status = ?, attempts = attempts + 1, last_call_uuid = ?, updated_at = ? WHERE id = ?`,
+		models.LeadStatusDialing, callUUID, time.Now(), id)
+}
+
+// UpdateStatus 更新线索状态及下次拨打时间，nextAttemptAt为nil表示不再安排拨打
+func (r *LeadRepo) UpdateStatus(ctx context.Context, id int64, status string, nextAttemptAt *time.Time) error {
+	return r.update(ctx,
+		`UPDATE leads SET status = ?, next_attempt_at = ?, updated_at = ? WHERE id = ?`,
+		status, nextAttemptAt, time.Now(), id)
+}
+
+// RecordLookup 记录号码状态查询结果
+func (r *LeadRepo) RecordLookup(ctx context.Context, id int64, lookup models.NumberLookup) error {
+	return r.update(ctx,
+		`UPDATE leads SET lookup_status = ?, lookup_carrier = ?, looked_up_at = ?, updated_at = ? WHERE id = ?`,
+		lookup.Status, lookup.Carrier, lookup.CheckedAt, time.Now(), id)
+}
+
+// CountByStatus 统计外呼任务中各状态的线索数
+func (r *LeadRepo) CountByStatus(ctx context.Context, campaignID int64) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT status, COUNT(*) FROM leads WHERE campaign_id = ? GROUP BY status`, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("统计线索失败: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("读取线索统计失败: %v", err)
+		}
+		counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取线索统计失败: %v", err)
+	}
+	return counts, nil
+}
+
+// update 执行更新语句，没有匹配的记录时返回ErrNotFound
+func (r *LeadRepo) update(ctx context.Context, query string, args ...interface{}) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("更新线索失败: %v", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// scanLead 读取一行线索
+func scanLead(row rowScanner) (*models.Lead, error) {
+	var (
+		lead          models.Lead
+		nextAttemptAt sql.NullTime
+		lookup        models.NumberLookup
+		lookedUpAt    sql.NullTime
+		data          []byte
+	)
+	err := row.Scan(&lead.ID, &lead.CampaignID, &lead.Phone, &lead.Name, &lead.Status, &lead.Attempts,
+		&nextAttemptAt, &lead.LastCallUUID, &lookup.Status, &lookup.Carrier, &lookedUpAt,
+		&data, &lead.CreatedAt, &lead.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	lead.NextAttemptAt = timePtr(nextAttemptAt)
+	if lookedUpAt.Valid {
+		lookup.CheckedAt = lookedUpAt.Time
+		lead.Lookup = &lookup
+	}
+	if len(data) > 0 {
+		lead.Data = data
+	}
+	return &lead, nil
+}
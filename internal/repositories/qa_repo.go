@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// QARepo 通话质检结果仓储
+type QARepo struct {
+	db DBTX
+}
+
+// NewQARepo 创建通话质检结果仓储
+func NewQARepo(db DBTX) *QARepo {
+	return &QARepo{db: db}
+}
+
+// Pending 查询在[since, until]之间挂机、已接通且有转写但尚未质检的通话，按挂机时间顺序
+func (r *QARepo) Pending(ctx context.Context, since, until time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT d.call_uuid FROM cdr d
+		 WHERE d.end_time >= ? AND d.end_time <= ? AND d.billsec > 0
+		   AND EXISTS (SELECT 1 FROM transcripts t WHERE t.call_uuid = d.call_uuid)
+		   AND NOT EXISTS (SELECT 1 FROM qa_evaluations e WHERE e.call_uuid = d.call_uuid)
+		 ORDER BY d.end_time LIMIT ?`, since, until, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询待质检通话失败: %v", err)
+	}
+	defer rows.Close()
+
+	var uuids []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, fmt.Errorf("读取待质检通话失败: %v", err)
+		}
+		uuids = append(uuids, uuid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取待质检通话失败: %v", err)
+	}
+	return uuids, nil
+}
+
+// Save 保存质检结果，覆盖该通话之前的结果，需在事务中调用
+func (r *QARepo) Save(ctx context.Context, eval *models.QAEvaluation) error {
+	_, err := r.db.ExecContext(ctx,
+		`REPLACE INTO qa_evaluations (call_uuid, status, total_score, passed, error, evaluated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		eval.CallUUID, eval.Status, eval.TotalScore, eval.Passed, nullString(eval.Error), eval.EvaluatedAt)
+	if err != nil {
+		return fmt.Errorf("保存质检结果失败: %v", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM qa_scores WHERE call_uuid = ?`, eval.CallUUID); err != nil {
+		return fmt.Errorf("保存质检得分失败: %v", err)
+	}
+	for _, score := range eval.Scores {
+		_, err := r.db.ExecContext(ctx,
+			`INSERT INTO qa_scores (call_uuid, criterion, score, passed, reason) VALUES (?, ?, ?, ?, ?)`,
+			eval.CallUUID, score.Criterion, score.Score, score.Passed, score.Reason)
+		if err != nil {
+			return fmt.Errorf("保存质检得分失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// Get 查询通话的质检结果
+func (r *QARepo) Get(ctx context.Context, callUUID string) (*models.QAEvaluation, error) {
+	var (
+		eval       models.QAEvaluation
+		totalScore sql.NullFloat64
+		errText    sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx,
+		`SELECT call_uuid, status, total_score, passed, error, evaluated_at FROM qa_evaluations WHERE call_uuid = ?`, callUUID).
+		Scan(&eval.CallUUID, &eval.Status, &totalScore, &eval.Passed, &errText, &eval.EvaluatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询质检结果失败: %v", err)
+	}
+	if totalScore.Valid {
+		eval.TotalScore = &totalScore.Float64
+	}
+	eval.Error = errText.String
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT criterion, score, passed, reason FROM qa_scores WHERE call_uuid = ? ORDER BY criterion`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询质检得分失败: %v", err)
+	}
+	defer rows.Close()
+
+	eval.Scores = []*models.QAScore{}
+	for rows.Next() {
+		var (
+			score models.QAScore
+			value sql.NullInt64
+		)
+		if err := rows.Scan(&score.Criterion, &value, &score.Passed, &score.Reason); err != nil {
+			return nil, fmt.Errorf("读取质检得分失败: %v", err)
+		}
+		if value.Valid {
+			v := int(value.Int64)
+			score.Score = &v
+		}
+		eval.Scores = append(eval.Scores, &score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取质检得分失败: %v", err)
+	}
+	return &eval, nil
+}
+
+// Dashboard 汇总质检结果，可按评估时间和外呼任务过滤
+func (r *QARepo) Dashboard(ctx context.Context, q models.QADashboardQuery) (*models.QADashboard, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.From != nil {
+		where = append(where, "e.evaluated_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "e.evaluated_at < ?")
+		args = append(args, *q.To)
+	}
+	if q.CampaignID != nil {
+		where = append(where, "c.campaign_id = ?")
+		args = append(args, *q.CampaignID)
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var dashboard models.QADashboard
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(e.status = 'done'), 0), COALESCE(SUM(e.status = 'failed'), 0),
+		        COALESCE(AVG(e.total_score), 0), COALESCE(AVG(CASE WHEN e.status = 'done' THEN e.passed END), 0)
+		 FROM qa_evaluations e LEFT JOIN calls c ON c.call_uuid = e.call_uuid`+filter, args...).
+		Scan(&dashboard.Evaluated, &dashboard.Failed, &dashboard.AvgScore, &dashboard.PassRate)
+	if err != nil {
+		return nil, fmt.Errorf("汇总质检结果失败: %v", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT s.criterion, COUNT(s.score), COALESCE(AVG(s.score), 0), COALESCE(AVG(CASE WHEN s.score IS NOT NULL THEN s.passed END), 0)
+		 FROM qa_scores s JOIN qa_evaluations e ON e.call_uuid = s.call_uuid
+		 LEFT JOIN calls c ON c.call_uuid = s.call_uuid`+filter+`
+		 GROUP BY s.criterion ORDER BY s.criterion`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("汇总质检得分失败: %v", err)
+	}
+	defer rows.Close()
+
+	dashboard.Criteria = []*models.QACriterionStats{}
+	for rows.Next() {
+		var stats models.QACriterionStats
+		if err := rows.Scan(&stats.Criterion, &stats.Evaluated, &stats.AvgScore, &stats.PassRate); err != nil {
+			return nil, fmt.Errorf("读取质检汇总失败: %v", err)
+		}
+		dashboard.Criteria = append(dashboard.Criteria, &stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取质检汇总失败: %v", err)
+	}
+	return &dashboard, nil
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// recordingColumns 录音表查询列
+const recordingColumns = `call_uuid, object_key, content_type, size, channels, duration_ms, caller, callee,
+	started_at, answered_at, ended_at, archived_at`
+
+// RecordingRepo 通话录音索引仓储
+type RecordingRepo struct {
+	db DBTX
+}
+
+// NewRecordingRepo 创建通话录音索引仓储
+func NewRecordingRepo(db DBTX) *RecordingRepo {
+	return &RecordingRepo{db: db}
+}
+
+// Save 保存录音索引，同一通话重新归档时覆盖
+func (r *RecordingRepo) Save(ctx context.Context, rec *models.Recording) error {
+	_, err := r.db.ExecContext(ctx,
+		`REPLACE INTO recordings (`+recordingColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.CallUUID, rec.Key, rec.ContentType, rec.Size, rec.Channels, rec.DurationMs, rec.Caller, rec.Callee,
+		rec.StartedAt, rec.AnsweredAt, rec.EndedAt, rec.ArchivedAt)
+	if err != nil {
+		return fmt.Errorf("保存录音索引失败: %v", err)
+	}
+	return nil
+}
+
+// Get 查询通话的录音，不存在时返回ErrNotFound
+func (r *RecordingRepo) Get(ctx context.Context, callUUID string) (*models.Recording, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+recordingColumns+` FROM recordings WHERE call_uuid = ?`, callUUID)
+	rec, err := scanRecording(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询录音失败: %v", err)
+	}
+	return rec, nil
+}
+
+// List 按挂断时间和号码查询录音，按挂断时间倒序
+func (r *RecordingRepo) List(ctx context.Context, q models.RecordingQuery) ([]*models.Recording, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if q.From != nil {
+		where = append(where, "ended_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "ended_at < ?")
+		args = append(args, *q.To)
+	}
+	if q.Number != "" {
+		where = append(where, "(caller = ? OR callee = ?)")
+		args = append(args, q.Number, q.Number)
+	}
+	query := `SELECT ` + recordingColumns + ` FROM recordings`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query+` ORDER BY ended_at DESC, call_uuid LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询录音失败: %v", err)
+	}
+	defer rows.Close()
+
+	var recordings []*models.Recording
+	for rows.Next() {
+		rec, err := scanRecording(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取录音失败: %v", err)
+		}
+		recordings = append(recordings, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取录音失败: %v", err)
+	}
+	return recordings, nil
+}
+
+// scanRecording 读取一行录音索引
+func scanRecording(row rowScanner) (*models.Recording, error) {
+	var (
+		rec                            models.Recording
+		startedAt, answeredAt, endedAt sql.NullTime
+	)
+	err := row.Scan(&rec.CallUUID, &rec.Key, &rec.ContentType, &rec.Size, &rec.Channels, &rec.DurationMs,
+		&rec.Caller, &rec.Callee, &startedAt, &answeredAt, &endedAt, &rec.ArchivedAt)
+	if err != nil {
+		return nil, err
+	}
+	rec.StartedAt = timePtr(startedAt)
+	rec.AnsweredAt = timePtr(answeredAt)
+	rec.EndedAt = timePtr(endedAt)
+	return &rec, nil
+}
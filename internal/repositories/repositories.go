@@ -0,0 +1,158 @@
+// Package repositories 封装数据库访问，服务层通过仓储读写数据，不直接拼写SQL
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/encryption"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("记录不存在")
+
+// DBTX 仓储使用的数据库句柄，*sql.DB和*sql.Tx均满足
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Repositories 共享同一个数据库句柄的一组仓储
+type Repositories struct {
+	Campaigns   *CampaignRepo
+	Calls       *CallRepo
+	Leads       *LeadRepo
+	Transcripts *TranscriptRepo
+	CDRs        *CDRRepo
+	Annotations *AnnotationRepo
+	QA          *QARepo
+	Recordings  *RecordingRepo
+}
+
+// newRepositories 基于数据库句柄创建仓储，keyring不为nil时转写文本加密保存，redactor不为nil时转写文本脱敏后保存，
+// dialect决定MySQL特有语句的写法
+func newRepositories(db DBTX, keyring *encryption.Keyring, redactor Redactor, dialect database.Dialect) *Repositories {
+	repos := &Repositories{
+		Campaigns:   NewCampaignRepo(db),
+		Calls:       NewCallRepo(db),
+		Leads:       NewLeadRepo(db),
+		Transcripts: NewTranscriptRepo(db),
+		CDRs:        NewCDRRepo(db),
+		Annotations: NewAnnotationRepo(db),
+		QA:          NewQARepo(db),
+		Recordings:  NewRecordingRepo(db),
+	}
+	repos.Transcripts.keyring = keyring
+	repos.Transcripts.redactor = redactor
+	repos.setDialect(dialect)
+	return repos
+}
+
+// setDialect 设置需要区分方言的仓储的SQL方言
+func (r *Repositories) setDialect(dialect database.Dialect) {
+	r.Calls.dialect = dialect
+	r.Leads.dialect = dialect
+	r.Transcripts.dialect = dialect
+	r.CDRs.dialect = dialect
+	r.Annotations.dialect = dialect
+}
+
+// Store 仓储入口，直接使用其中的仓储时每条语句自动提交，需要事务时使用Transaction
+type Store struct {
+	*Repositories
+	db       *sql.DB
+	keyring  *encryption.Keyring
+	redactor Redactor
+	dialect  database.Dialect
+}
+
+// NewStore 创建仓储入口，默认使用MySQL方言
+func NewStore(db *sql.DB) *Store {
+	return &Store{
+		Repositories: newRepositories(db, nil, nil, database.MySQL),
+		db:           db,
+		dialect:      database.MySQL,
+	}
+}
+
+// SetKeyring 设置静态加密主密钥，设置后写入的转写文本加密保存，读取时透明解密
+func (s *Store) SetKeyring(keyring *encryption.Keyring) {
+	s.keyring = keyring
+	s.Transcripts.keyring = keyring
+}
+
+// SetRedactor 设置转写脱敏，设置后写入的转写文本先按规则隐藏敏感号码再加密保存，已保存的转写不受影响
+func (s *Store) SetRedactor(redactor Redactor) {
+	s.redactor = redactor
+	s.Transcripts.redactor = redactor
+}
+
+// SetDialect 设置数据库方言，使用SQLite时调用
+func (s *Store) SetDialect(dialect database.Dialect) {
+	s.dialect = dialect
+	s.Repositories.setDialect(dialect)
+}
+
+// UnitOfWork 工作单元，其中的仓储共享同一个事务
+type UnitOfWork struct {
+	*Repositories
+	tx *sql.Tx
+}
+
+// Tx 获取底层事务，供发件箱等需要参与同一事务的组件使用
+func (u *UnitOfWork) Tx() *sql.Tx {
+	return u.tx
+}
+
+// Transaction 在事务中执行fn，fn返回错误或panic时回滚，否则提交
+func (s *Store) Transaction(ctx context.Context, fn func(uow *UnitOfWork) error) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&UnitOfWork{Repositories: newRepositories(tx, s.keyring, s.redactor, s.dialect), tx: tx}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%v (回滚事务失败: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// timePtr 将可空时间转换为指针，NULL返回nil
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// int64Ptr 将可空整数转换为指针，NULL返回nil
+func int64Ptr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+// nullString 将空字符串转换为NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/models"
+)
+
+// ErrSearchEncrypted 转写文本加密保存时无法全文检索
+var ErrSearchEncrypted = errors.New("通话转写已加密保存，不支持全文检索")
+
+// Redactor 转写文本脱敏
+type Redactor interface {
+	Redact(text string) string
+}
+
+// TranscriptRepo 通话转写仓储，设置了脱敏规则时文本脱敏后保存，设置了主密钥时文本加密保存，读取时透明解密
+type TranscriptRepo struct {
+	db       DBTX
+	keyring  *encryption.Keyring
+	redactor Redactor
+	dialect  database.Dialect
+}
+
+// NewTranscriptRepo 创建通话转写仓储
+func NewTranscriptRepo(db DBTX) *TranscriptRepo {
+	return &TranscriptRepo{db: db}
+}
+
+// Append 追加一条转写片段，成功后回填ID；设置了脱敏规则时t.Text替换为脱敏后的文本
+func (r *TranscriptRepo) Append(ctx context.Context, t *models.Transcript) error {
+	if r.redactor != nil {
+		t.Text = r.redactor.Redact(t.Text)
+	}
+	text, err := r.keyring.Encrypt(t.Text)
+	if err != nil {
+		return fmt.Errorf("加密通话转写失败: %v", err)
+	}
+	if t.Kind == "" {
+		t.Kind = models.TranscriptKindSpeech
+	}
+	t.CreatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO transcripts (call_uuid, speaker, kind, text, start_ms, end_ms, language, sentiment, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.CallUUID, t.Speaker, t.Kind, text, t.StartMs, t.EndMs, t.Language, t.Sentiment, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("写入通话转写失败: %v", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取转写ID失败: %v", err)
+	}
+	t.ID = id
+	return nil
+}
+
+// ListByCall 查询通话的全部语音转写片段，按时间顺序；不含按键和通话事件，供质检和数据集使用
+func (r *TranscriptRepo) ListByCall(ctx context.Context, callUUID string) ([]*models.Transcript, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, call_uuid, speaker, text, start_ms, end_ms, language, created_at FROM transcripts
+		 WHERE call_uuid = ? AND kind = 'speech' ORDER BY start_ms, id`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询通话转写失败: %v", err)
+	}
+	defer rows.Close()
+
+	var transcripts []*models.Transcript
+	for rows.Next() {
+		var t models.Transcript
+		if err := rows.Scan(&t.ID, &t.CallUUID, &t.Speaker, &t.Text, &t.StartMs, &t.EndMs, &t.Language, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取通话转写失败: %v", err)
+		}
+		if t.Text, err = r.keyring.Decrypt(t.Text); err != nil {
+			return nil, fmt.Errorf("解密通话转写 %d 失败: %v", t.ID, err)
+		}
+		transcripts = append(transcripts, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取通话转写失败: %v", err)
+	}
+	return transcripts, nil
+}
+
+// Timeline 查询通话的全部转写条目，包括语音、按键和通话事件，按时间顺序
+func (r *TranscriptRepo) Timeline(ctx context.Context, callUUID string) ([]*models.Transcript, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, call_uuid, speaker, kind, text, start_ms, end_ms, language, sentiment, created_at FROM transcripts
+		 WHERE call_uuid = ? ORDER BY start_ms, id`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询通话转写失败: %v", err)
+	}
+	defer rows.Close()
+
+	var transcripts []*models.Transcript
+	for rows.Next() {
+		var t models.Transcript
+		if err := rows.Scan(&t.ID, &t.CallUUID, &t.Speaker, &t.Kind, &t.Text, &t.StartMs, &t.EndMs, &t.Language, &t.Sentiment, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取通话转写失败: %v", err)
+		}
+		if t.Text, err = r.keyring.Decrypt(t.Text); err != nil {
+			return nil, fmt.Errorf("解密通话转写 %d 失败: %v", t.ID, err)
+		}
+		transcripts = append(transcripts, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取通话转写失败: %v", err)
+	}
+	return transcripts, nil
+}
+
+// SetSentiment 更新转写片段的情绪标签
+func (r *TranscriptRepo) SetSentiment(ctx context.Context, id int64, sentiment string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE transcripts SET sentiment = ? WHERE id = ?`, sentiment, id); err != nil {
+		return fmt.Errorf("更新转写情绪失败: %v", err)
+	}
+	return nil
+}
+
+// Sentiments 查询通话中已标注情绪的客户语句的情绪，按时间顺序
+func (r *TranscriptRepo) Sentiments(ctx context.Context, callUUID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT sentiment FROM transcripts
+		 WHERE call_uuid = ? AND speaker = 'customer' AND kind = 'speech' AND sentiment <> '' ORDER BY start_ms, id`, callUUID)
+	if err != nil {
+		return nil, fmt.Errorf("查询客户情绪失败: %v", err)
+	}
+	defer rows.Close()
+
+	var sentiments []string
+	for rows.Next() {
+		var sentiment string
+		if err := rows.Scan(&sentiment); err != nil {
+			return nil, fmt.Errorf("读取客户情绪失败: %v", err)
+		}
+		sentiments = append(sentiments, sentiment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取客户情绪失败: %v", err)
+	}
+	return sentiments, nil
+}
+
+// Search 全文检索转写片段，可按时间、外呼任务、通话结果、通话标签过滤，结果按相关度降序
+// 文本加密保存时返回ErrSearchEncrypted；SQLite没有ngram全文索引，按子串匹配，相关度均为1，结果按ID倒序
+func (r *TranscriptRepo) Search(ctx context.Context, q models.TranscriptSearchQuery) ([]*models.TranscriptHit, error) {
+	if r.keyring != nil {
+		return nil, ErrSearchEncrypted
+	}
+	var (
+		phrase = `"` + strings.ReplaceAll(q.Query, `"`, " ") + `"`
+		score  = "MATCH(t.text) AGAINST(? IN BOOLEAN MODE)"
+		where  = []string{score}
+		args   = []interface{}{phrase, phrase}
+	)
+	if r.dialect.IsSQLite() {
+		score = "1.0"
+		where = []string{`t.text LIKE ? ESCAPE '\'`}
+		args = []interface{}{"%" + likeEscaper.Replace(q.Query) + "%"}
+	}
+	if q.From != nil {
+		where = append(where, "t.created_at >= ?")
+		args = append(args, *q.From)
+	}
+	if q.To != nil {
+		where = append(where, "t.created_at < ?")
+		args = append(args, *q.To)
+	}
+	if q.CampaignID != nil {
+		where = append(where, "c.campaign_id = ?")
+		args = append(args, *q.CampaignID)
+	}
+	if q.Disposition != "" {
+		where = append(where, "c.disposition = ?")
+		args = append(args, q.Disposition)
+	}
+	if q.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM call_tags ct WHERE ct.call_uuid = t.call_uuid AND ct.tag = ?)")
+		args = append(args, q.Tag)
+	}
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.id, t.call_uuid, t.speaker, t.text, t.start_ms, t.end_ms, t.language, t.created_at,
+		        c.campaign_id, COALESCE(c.disposition, ''), `+score+` AS score
+		 FROM transcripts t LEFT JOIN calls c ON c.call_uuid = t.call_uuid
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY score DESC, t.id DESC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("检索通话转写失败: %v", err)
+	}
+	defer rows.Close()
+
+	var hits []*models.TranscriptHit
+	for rows.Next() {
+		var (
+			hit        models.TranscriptHit
+			campaignID sql.NullInt64
+		)
+		err := rows.Scan(&hit.ID, &hit.CallUUID, &hit.Speaker, &hit.Text, &hit.StartMs, &hit.EndMs, &hit.Language, &hit.CreatedAt,
+			&campaignID, &hit.Disposition, &hit.Score)
+		if err != nil {
+			return nil, fmt.Errorf("读取检索结果失败: %v", err)
+		}
+		hit.CampaignID = int64Ptr(campaignID)
+		hits = append(hits, &hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取检索结果失败: %v", err)
+	}
+	return hits, nil
+}
+
+// likeEscaper 转义LIKE模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Rewrap 用当前主密钥重新加密id大于afterID的最多limit条转写的数据密钥，加密启用前写入的明文同时加密
+// 返回本批最后一条转写的ID和更新的条数，没有更多转写时lastID为0
+func (r *TranscriptRepo) Rewrap(ctx context.Context, afterID int64, limit int) (lastID int64, updated int, err error) {
+	if r.keyring == nil {
+		return 0, 0, nil
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, text FROM transcripts WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("查询通话转写失败: %v", err)
+	}
+	type row struct {
+		id   int64
+		text string
+	}
+	var batch []row
+	for rows.Next() {
+		var item row
+		if err := rows.Scan(&item.id, &item.text); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("读取通话转写失败: %v", err)
+		}
+		batch = append(batch, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("读取通话转写失败: %v", err)
+	}
+
+	for _, row := range batch {
+		lastID = row.id
+		text, changed, err := r.keyring.Rewrap(row.text)
+		if err != nil {
+			return 0, updated, fmt.Errorf("重新加密通话转写 %d 失败: %v", row.id, err)
+		}
+		if !changed {
+			continue
+		}
+		// 以原文本为条件，避免覆盖并发写入的内容
+		result, err := r.db.ExecContext(ctx,
+			`UPDATE transcripts SET text = ? WHERE id = ? AND text = ?`, text, row.id, row.text)
+		if err != nil {
+			return 0, updated, fmt.Errorf("更新通话转写 %d 失败: %v", row.id, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			updated++
+		}
+	}
+	return lastID, updated, nil
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminRoutes 注册运维管理路由，仅允许持有运维令牌的请求访问
+func RegisterAdminRoutes(r *gin.Engine, logLevelHandler *handlers.LogLevelHandler, tokens []string) {
+	admin := r.Group("/api/v1/admin", middleware.TokenAuth(tokens))
+	admin.GET("/loglevel", logLevelHandler.Get)
+	admin.PUT("/loglevel", logLevelHandler.Update)
+}
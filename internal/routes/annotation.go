@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAnnotationRoutes 注册通话标签和备注路由，仅允许持有接口令牌的请求访问
+func RegisterAnnotationRoutes(r *gin.Engine, annotationHandler *handlers.AnnotationHandler, tokens []string) {
+	calls := r.Group("/api/v1/calls/:uuid", middleware.TokenAuth(tokens))
+	calls.POST("/tags", annotationHandler.Annotate)
+	calls.GET("/tags", annotationHandler.List)
+}
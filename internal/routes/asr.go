@@ -1,7 +1,11 @@
 package routes
 
 import (
+	"net/http"
+
 	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
 	"ai_dialer_mini/internal/models"
 	"ai_dialer_mini/internal/services"
 	"ai_dialer_mini/internal/services/ws"
@@ -25,4 +29,15 @@ func RegisterASRRoutes(r *gin.Engine, wsService models.WSService) {
 	r.GET("/ws", func(c *gin.Context) {
 		wsService.HandleConnection(c)
 	})
+
+	// 注册浏览器麦克风音频流路由（供 /demo 演示页面使用）
+	if handler, ok := wsService.(http.Handler); ok {
+		r.GET("/ws/mic", gin.WrapH(handler))
+	}
+}
+
+// RegisterASRPoolRoutes 注册讯飞连接预热池运维路由，仅允许持有运维令牌的请求访问
+func RegisterASRPoolRoutes(r *gin.Engine, poolHandler *handlers.ASRPoolHandler, tokens []string) {
+	admin := r.Group("/api/v1/admin", middleware.TokenAuth(tokens))
+	admin.GET("/asr/pool", poolHandler.Stats)
 }
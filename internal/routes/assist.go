@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAssistRoutes 注册坐席辅助路由，仅允许持有令牌的坐席访问
+func RegisterAssistRoutes(r *gin.Engine, assistHandler *handlers.AssistHandler, tokens []string) {
+	r.GET("/ws/calls/:uuid/assist", middleware.TokenAuth(tokens), assistHandler.Subscribe)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCacheRoutes 注册缓存统计路由
+func RegisterCacheRoutes(r *gin.Engine, cacheHandler *handlers.CacheHandler) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/caches/stats", cacheHandler.Stats)
+}
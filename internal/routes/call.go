@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCallRoutes 注册呼叫控制路由
+func RegisterCallRoutes(r *gin.Engine, callHandler *handlers.CallHandler) {
+	v1 := r.Group("/api/v1")
+	v1.POST("/calls", callHandler.Originate)
+}
+
+// RegisterCallControlRoutes 注册通话中控制路由（挂断、转接、发送按键）及话后处理路由，仅允许持有接口令牌的请求访问
+func RegisterCallControlRoutes(r *gin.Engine, callHandler *handlers.CallHandler, tokens []string) {
+	calls := r.Group("/api/v1/calls/:uuid", middleware.TokenAuth(tokens))
+	calls.DELETE("", callHandler.Hangup)
+	calls.POST("/transfer", callHandler.Transfer)
+	calls.POST("/dtmf", callHandler.SendDTMF)
+	calls.PUT("/disposition", callHandler.SetDisposition)
+	calls.POST("/wrapup", callHandler.CompleteWrapUp)
+}
+
+// RegisterCallDialogRoutes 注册通话中对话干预路由，仅允许持有通话控制令牌的请求访问
+func RegisterCallDialogRoutes(r *gin.Engine, dialogHandler *handlers.CallDialogHandler, tokens []string) {
+	calls := r.Group("/api/v1/calls/:uuid", middleware.TokenAuth(tokens))
+	calls.POST("/say", dialogHandler.Say)
+	calls.POST("/inject-context", dialogHandler.InjectContext)
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCampaignRoutes 注册外呼任务路由，上传的号码会被自动拨打，仅允许持有接口令牌的请求访问
+func RegisterCampaignRoutes(r *gin.Engine, campaignHandler *handlers.CampaignHandler, tokens []string) {
+	campaigns := r.Group("/api/v1/campaigns", middleware.TokenAuth(tokens))
+	campaigns.POST("", campaignHandler.Create)
+	campaigns.GET("", campaignHandler.List)
+	campaigns.GET("/:id", campaignHandler.Get)
+	campaigns.POST("/:id/leads", campaignHandler.AddLeads)
+	campaigns.POST("/:id/start", campaignHandler.Start)
+	campaigns.POST("/:id/pause", campaignHandler.Pause)
+}
+
+// RegisterCampaignPreviewRoutes 注册预览拨号路由，确认后向指定坐席分机发起桥接外呼，仅允许持有接口令牌的请求访问
+func RegisterCampaignPreviewRoutes(r *gin.Engine, campaignHandler *handlers.CampaignHandler, tokens []string) {
+	campaigns := r.Group("/api/v1/campaigns/:id", middleware.TokenAuth(tokens))
+	campaigns.GET("/previews", campaignHandler.Previews)
+	campaigns.POST("/leads/:lead_id/confirm", campaignHandler.Confirm)
+	campaigns.POST("/leads/:lead_id/skip", campaignHandler.Skip)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCronRoutes 注册定时任务路由，仅允许持有运维令牌的请求访问
+func RegisterCronRoutes(r *gin.Engine, cronHandler *handlers.CronHandler, tokens []string) {
+	jobs := r.Group("/api/v1/admin/cron/jobs", middleware.TokenAuth(tokens))
+	jobs.GET("", cronHandler.List)
+	jobs.POST("/:name/run", cronHandler.Trigger)
+}
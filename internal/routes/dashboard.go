@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDashboardRoutes 注册监控面板路由，面板通过该WebSocket接收所有实例的通话实时事件
+func RegisterDashboardRoutes(r *gin.Engine, hub *services.WSService) {
+	r.GET("/ws/dashboard", func(c *gin.Context) {
+		hub.HandleConnection(c.Writer, c.Request)
+	})
+
+	// 只读的通话事件订阅，如 /ws/events?types=call_answered,call_hangup&session_id=<通话UUID>
+	r.GET("/ws/events", func(c *gin.Context) {
+		hub.HandleEvents(c.Writer, c.Request)
+	})
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDatasetRoutes 注册训练数据导出路由，导出内容为通话对话，仅允许持有运维令牌的请求访问
+func RegisterDatasetRoutes(r *gin.Engine, datasetHandler *handlers.DatasetHandler, tokens []string) {
+	datasets := r.Group("/api/v1/admin/datasets", middleware.TokenAuth(tokens))
+	datasets.POST("/export", datasetHandler.Export)
+}
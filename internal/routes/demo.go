@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterDemoRoutes 注册演示网页客户端路由
+func RegisterDemoRoutes(r *gin.Engine) {
+	// 演示页面：浏览器采集麦克风音频并推送到 /ws/mic
+	r.StaticFS("/demo", web.FileSystem())
+}
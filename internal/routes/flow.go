@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterFlowRoutes 注册通话流程路由，仅允许持有接口令牌的请求访问
+func RegisterFlowRoutes(r *gin.Engine, flowHandler *handlers.FlowHandler, tokens []string) {
+	flows := r.Group("/api/v1/flows", middleware.TokenAuth(tokens))
+	flows.POST("/validate", flowHandler.Validate)
+	flows.POST("/export", flowHandler.Export)
+}
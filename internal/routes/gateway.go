@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterGatewayRoutes 注册网关统计路由
+func RegisterGatewayRoutes(r *gin.Engine, gatewayHandler *handlers.GatewayHandler) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/gateways/stats", gatewayHandler.Stats)
+}
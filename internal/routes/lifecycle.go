@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterLifecycleRoutes 注册就绪检查和排空路由，排空接口仅允许持有运维令牌的请求访问
+func RegisterLifecycleRoutes(r *gin.Engine, lifecycleHandler *handlers.LifecycleHandler, tokens []string) {
+	r.GET("/ready", lifecycleHandler.Ready)
+
+	if len(tokens) > 0 {
+		admin := r.Group("/api/v1/admin", middleware.TokenAuth(tokens))
+		admin.GET("/drain", lifecycleHandler.DrainStatus)
+		admin.POST("/drain", lifecycleHandler.Drain)
+	}
+}
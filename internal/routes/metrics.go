@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterMetricsRoutes 注册Prometheus指标路由，所有进程角色都提供
+func RegisterMetricsRoutes(r *gin.Engine) {
+	r.GET("/metrics", gin.WrapH(metrics.Default))
+}
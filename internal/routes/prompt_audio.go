@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterPromptAudioRoutes 注册预录提示音路由，上传和删除会改变通话中播放的内容，仅允许持有接口令牌的请求访问
+func RegisterPromptAudioRoutes(r *gin.Engine, promptAudioHandler *handlers.PromptAudioHandler, tokens []string) {
+	prompts := r.Group("/api/v1/prompts/audio", middleware.TokenAuth(tokens))
+	prompts.POST("", promptAudioHandler.Upload)
+	prompts.GET("", promptAudioHandler.List)
+	prompts.GET("/:name", promptAudioHandler.Get)
+	prompts.GET("/:name/audio", promptAudioHandler.Download)
+	prompts.DELETE("/:name", promptAudioHandler.Delete)
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterQARoutes 注册通话质检路由，仅允许持有接口令牌的请求访问
+func RegisterQARoutes(r *gin.Engine, qaHandler *handlers.QAHandler, tokens []string) {
+	v1 := r.Group("/api/v1", middleware.TokenAuth(tokens))
+	v1.POST("/calls/:uuid/qa", qaHandler.Evaluate)
+	v1.GET("/calls/:uuid/qa", qaHandler.Get)
+	v1.GET("/qa/dashboard", qaHandler.Dashboard)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRecorderRoutes 注册飞行记录仪路由
+func RegisterRecorderRoutes(r *gin.Engine, recorderHandler *handlers.RecorderHandler) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/calls/:uuid/flight-record", recorderHandler.Get)
+}
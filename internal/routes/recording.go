@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRecordingRoutes 注册通话录音路由，录音包含客户语音，仅允许持有接口令牌的请求访问
+func RegisterRecordingRoutes(r *gin.Engine, recordingHandler *handlers.RecordingHandler, tokens []string) {
+	recordings := r.Group("/api/v1/recordings", middleware.TokenAuth(tokens))
+	recordings.GET("", recordingHandler.List)
+	recordings.GET("/:uuid", recordingHandler.Get)
+	recordings.GET("/:uuid/audio", recordingHandler.Download)
+}
@@ -12,16 +12,24 @@ import (
 // RegisterRoutes 注册所有路由
 func RegisterRoutes(r *gin.Engine, wsService models.WSService, asrConfig xfyun.Config, ollamaConfig ollama.Config) {
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"time":   time.Now().Format(time.RFC3339),
-		})
-	})
+	RegisterHealthRoutes(r)
 
 	// 注册ASR路由
 	RegisterASRRoutes(r, wsService)
 
 	// 注册对话路由
 	RegisterDialogRoutes(r, asrConfig, ollamaConfig)
+
+	// 注册演示页面路由
+	RegisterDemoRoutes(r)
+}
+
+// RegisterHealthRoutes 注册健康检查路由，所有进程角色都提供
+func RegisterHealthRoutes(r *gin.Engine) {
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status": "ok",
+			"time":   time.Now().Format(time.RFC3339),
+		})
+	})
 }
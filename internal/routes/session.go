@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSessionRoutes 注册对话会话路由
+func RegisterSessionRoutes(r *gin.Engine, sessionHandler *handlers.SessionHandler) {
+	v1 := r.Group("/api/v1")
+	v1.GET("/sessions/:session_id/options", sessionHandler.GetOptions)
+	v1.PUT("/sessions/:session_id/options", sessionHandler.UpdateOptions)
+	v1.GET("/sessions/:session_id/history", sessionHandler.GetHistory)
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"net/http"
+
+	"ai_dialer_mini/internal/middleware"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterStreamRoutes 注册FreeSWITCH通话音频流路由，连接须使用通话应答时生成的签名地址
+// 音频按通话UUID作为会话ID交给语音识别服务处理，AI语音同时在该通话中播放
+func RegisterStreamRoutes(r *gin.Engine, handler http.Handler, verifier middleware.StreamVerifier) {
+	r.GET("/ws/calls/:uuid/stream", countRejected, middleware.SignedStream(verifier), func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		query.Set("session_id", c.Param("uuid"))
+		c.Request.URL.RawQuery = query.Encode()
+		handler.ServeHTTP(c.Writer, ws.WithCall(c.Request, c.Param("uuid")))
+	})
+}
+
+// countRejected 统计签名校验失败而拒绝的音频流连接
+func countRejected(c *gin.Context) {
+	c.Next()
+	if c.IsAborted() && c.Writer.Status() == http.StatusForbidden {
+		ws.RecordDisconnect(ws.EndpointCallStream, ws.DisconnectAuthFailure)
+	}
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTapRoutes 注册通话实时监听路由，仅允许持有令牌的质检坐席访问
+func RegisterTapRoutes(r *gin.Engine, tapHandler *handlers.TapHandler, tokens []string) {
+	r.GET("/ws/calls/:uuid/tap", middleware.TokenAuth(tokens), tapHandler.Listen)
+}
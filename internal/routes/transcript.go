@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTranscriptRoutes 注册通话转写路由，转写读取时已解密，仅允许持有接口令牌的请求访问
+func RegisterTranscriptRoutes(r *gin.Engine, transcriptHandler *handlers.TranscriptHandler, tokens []string) {
+	v1 := r.Group("/api/v1", middleware.TokenAuth(tokens))
+	v1.GET("/transcripts/search", transcriptHandler.Search)
+	v1.GET("/calls/:uuid/transcript", transcriptHandler.Export)
+	v1.GET("/calls/:uuid/sentiment", transcriptHandler.Sentiment)
+}
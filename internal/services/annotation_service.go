@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// ErrInvalidAnnotation 标签或备注无效
+var ErrInvalidAnnotation = errors.New("通话标签或备注无效")
+
+// AnnotationService 通话标签和备注服务，质检人员可在通话进行中或结束后添加，
+// 标签可用于转写检索和网关统计的过滤
+type AnnotationService struct {
+	store *repositories.Store
+}
+
+// NewAnnotationService 创建通话标签和备注服务
+func NewAnnotationService(store *repositories.Store) *AnnotationService {
+	return &AnnotationService{store: store}
+}
+
+// Annotate 为通话添加标签和备注，标签去掉首尾空白并转为小写后去重
+func (s *AnnotationService) Annotate(ctx context.Context, callUUID string, req models.AnnotateCallRequest) (*models.CallAnnotations, error) {
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return nil, err
+	}
+	note := strings.TrimSpace(req.Note)
+	if len(tags) == 0 && note == "" {
+		return nil, fmt.Errorf("%w: 标签和备注至少填写一项", ErrInvalidAnnotation)
+	}
+	if req.OffsetMs != nil && *req.OffsetMs < 0 {
+		return nil, fmt.Errorf("%w: offset_ms不能为负数", ErrInvalidAnnotation)
+	}
+
+	err = s.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		for _, tag := range tags {
+			if err := uow.Annotations.AddTag(ctx, callUUID, &models.CallTag{Tag: tag, Author: req.Author}); err != nil {
+				return err
+			}
+		}
+		if note == "" {
+			return nil
+		}
+		return uow.Annotations.AddNote(ctx, &models.CallNote{
+			CallUUID: callUUID,
+			Author:   req.Author,
+			Note:     note,
+			OffsetMs: req.OffsetMs,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, callUUID)
+}
+
+// Get 查询通话的全部标签和备注
+func (s *AnnotationService) Get(ctx context.Context, callUUID string) (*models.CallAnnotations, error) {
+	tags, err := s.store.Annotations.ListTags(ctx, callUUID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.store.Annotations.ListNotes(ctx, callUUID)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []*models.CallTag{}
+	}
+	if notes == nil {
+		notes = []*models.CallNote{}
+	}
+	return &models.CallAnnotations{CallUUID: callUUID, Tags: tags, Notes: notes}, nil
+}
+
+// normalizeTags 规范化标签，忽略空标签，超长时返回错误
+func normalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	var tags []string
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > models.MaxTagLength {
+			return nil, fmt.Errorf("%w: 标签不能超过%d个字符", ErrInvalidAnnotation, models.MaxTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
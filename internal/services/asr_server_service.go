@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"sync"
 
+	"ai_dialer_mini/internal/config"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -31,9 +33,7 @@ type ASRServerService struct {
 func NewASRServerService() *ASRServerService {
 	return &ASRServerService{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true
-			},
+			CheckOrigin: config.CheckWebSocketOrigin,
 		},
 		grammars: make(map[*websocket.Conn]string),
 	}
@@ -17,7 +17,7 @@ type ASRService struct {
 // NewASRService 创建新的ASR服务实例
 func NewASRService(cfg *config.Config, dialogSvc models.DialogService) *ASRService {
 	// 创建ASR客户端
-	client := xfyun.NewASRClient(cfg.XFYun, dialogSvc)
+	client := xfyun.NewASRClient(cfg.ASR.XFYun, dialogSvc)
 
 	return &ASRService{
 		client:    client,
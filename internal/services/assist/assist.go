@@ -0,0 +1,200 @@
+// Package assist 坐席辅助：通话桥接到人工坐席后继续识别客户语音，通过专用WebSocket通道
+// 为坐席实时推送客户转写、大模型生成的建议话术和相关业务知识片段，不合成语音
+package assist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// 推送给坐席的消息类型
+const (
+	MessageTranscript = "transcript" // 客户说的一句话
+	MessageSuggestion = "suggestion" // 大模型生成的建议话术
+	MessageKnowledge  = "knowledge"  // 与客户这句话相关的业务知识片段
+)
+
+// 默认值
+const (
+	defaultBuffer      = 32
+	defaultMaxSnippets = 3
+)
+
+// minScore 知识片段与客户语音至少共有的二字词数，低于该值视为不相关
+const minScore = 2
+
+// Config 坐席辅助配置
+type Config struct {
+	Enabled     bool     `yaml:"enabled"`
+	Tokens      []string `yaml:"tokens"`       // 允许订阅的坐席令牌
+	Knowledge   []string `yaml:"knowledge"`    // 业务知识片段，留空使用默认提示词配置的业务知识
+	MaxSnippets int      `yaml:"max_snippets"` // 每句客户语音最多推送的知识片段数
+	Buffer      int      `yaml:"buffer"`       // 每个坐席连接缓存的消息数，坐席处理不过来时丢弃新消息
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Tokens) == 0 {
+		return fmt.Errorf("tokens: 启用坐席辅助时必须配置坐席令牌")
+	}
+	for i, snippet := range c.Knowledge {
+		if strings.TrimSpace(snippet) == "" {
+			return fmt.Errorf("knowledge[%d]: 知识片段不能为空", i)
+		}
+	}
+	if c.MaxSnippets < 0 {
+		return fmt.Errorf("max_snippets: 不能为负数")
+	}
+	if c.Buffer < 0 {
+		return fmt.Errorf("buffer: 不能为负数")
+	}
+	return nil
+}
+
+// Message 推送给坐席的一条消息
+type Message struct {
+	Type     string    `json:"type"`               // 消息类型
+	CallUUID string    `json:"call_uuid"`          // 通话UUID
+	Text     string    `json:"text,omitempty"`     // 客户转写或建议话术
+	Snippets []string  `json:"snippets,omitempty"` // 知识片段，按相关度排列
+	At       time.Time `json:"at"`                 // 产生时间
+}
+
+// snippet 预先切分好二字词的知识片段
+type snippet struct {
+	text    string
+	bigrams map[string]struct{}
+}
+
+// Hub 按通话分发坐席辅助消息；方法可在nil上调用，此时不推送
+type Hub struct {
+	buffer      int
+	maxSnippets int
+	knowledge   []snippet
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan Message]struct{}
+}
+
+// New 创建坐席辅助，未启用时返回nil；knowledge为配置中未填写知识片段时使用的默认知识
+func New(config Config, knowledge []string) *Hub {
+	if !config.Enabled {
+		return nil
+	}
+	if config.Buffer <= 0 {
+		config.Buffer = defaultBuffer
+	}
+	if config.MaxSnippets <= 0 {
+		config.MaxSnippets = defaultMaxSnippets
+	}
+	if len(config.Knowledge) > 0 {
+		knowledge = config.Knowledge
+	}
+	h := &Hub{
+		buffer:      config.Buffer,
+		maxSnippets: config.MaxSnippets,
+		subscribers: make(map[string]map[chan Message]struct{}),
+	}
+	for _, text := range knowledge {
+		h.knowledge = append(h.knowledge, snippet{text: text, bigrams: bigrams(text)})
+	}
+	return h
+}
+
+// Subscribe 订阅通话的坐席辅助消息，调用返回的cancel取消订阅
+func (h *Hub) Subscribe(callUUID string) (<-chan Message, func()) {
+	ch := make(chan Message, h.buffer)
+
+	h.mu.Lock()
+	if h.subscribers[callUUID] == nil {
+		h.subscribers[callUUID] = make(map[chan Message]struct{})
+	}
+	h.subscribers[callUUID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[callUUID], ch)
+			if len(h.subscribers[callUUID]) == 0 {
+				delete(h.subscribers, callUUID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// Publish 向订阅通话的坐席推送消息，At为空时填写当前时间；坐席缓存已满时丢弃，不阻塞识别流程
+func (h *Hub) Publish(msg Message) {
+	if h == nil {
+		return
+	}
+	if msg.At.IsZero() {
+		msg.At = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers[msg.CallUUID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// Knowledge 按与客户语音共有的二字词数选出相关的知识片段，相关度相同时按配置顺序
+func (h *Hub) Knowledge(text string) []string {
+	if h == nil || len(h.knowledge) == 0 {
+		return nil
+	}
+	words := bigrams(text)
+	type scored struct {
+		text  string
+		score int
+	}
+	var matches []scored
+	for _, s := range h.knowledge {
+		score := 0
+		for word := range words {
+			if _, ok := s.bigrams[word]; ok {
+				score++
+			}
+		}
+		if score >= minScore {
+			matches = append(matches, scored{text: s.text, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	var snippets []string
+	for i := 0; i < len(matches) && i < h.maxSnippets; i++ {
+		snippets = append(snippets, matches[i].text)
+	}
+	return snippets
+}
+
+// bigrams 文本中相邻两个字组成的词，忽略标点和空白，英文转为小写
+func bigrams(text string) map[string]struct{} {
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	words := make(map[string]struct{})
+	for i := 0; i+1 < len(runes); i++ {
+		words[string(runes[i:i+2])] = struct{}{}
+	}
+	return words
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"regexp"
+)
+
+// ASRVariable 指定通话语音识别后端的通道变量，通话音频流接入时按该变量选择后端（如 whisper）
+const ASRVariable = "ai_dialer_asr"
+
+// asrProviderPattern 语音识别后端名称格式，名称会写入originate命令
+var asrProviderPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// asrContextKey 上下文中语音识别后端的键
+type asrContextKey struct{}
+
+// WithASRProvider 在上下文中记录通话使用的语音识别后端，发起呼叫时据此设置通道变量
+func WithASRProvider(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, asrContextKey{}, name)
+}
+
+// ASRProviderFromContext 读取上下文中的语音识别后端，未设置时返回空
+func ASRProviderFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(asrContextKey{}).(string)
+	return name
+}
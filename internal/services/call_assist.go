@@ -0,0 +1,27 @@
+package services
+
+// AssistVariable 标记坐席通话的通道变量，值为true时通话由坐席接听，音频流接入后AI只推送建议话术，不播放语音
+const AssistVariable = "ai_dialer_assist"
+
+// SetAssist 将通话标记为坐席通话，通话不存在时返回false
+func (m *CallSessionManager) SetAssist(uuid string) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	session.assist = true
+	session.mu.Unlock()
+	return true
+}
+
+// CallAssisted 通话是否为坐席通话，通话不存在时返回false
+func (m *CallSessionManager) CallAssisted(uuid string) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.assist
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCallCommand 呼叫控制参数无效
+	ErrInvalidCallCommand = errors.New("呼叫控制参数无效")
+	// ErrCallNotFound 通话不存在或已挂断
+	ErrCallNotFound = errors.New("通话不存在或已挂断")
+	// ErrSwitchUnavailable FreeSWITCH连接不可用
+	ErrSwitchUnavailable = errors.New("FreeSWITCH不可用")
+	// ErrCommandRejected FreeSWITCH拒绝执行命令
+	ErrCommandRejected = errors.New("FreeSWITCH命令执行失败")
+)
+
+// CallController 支持通话中控制的通话服务
+type CallController interface {
+	// TransferCall 将通话转接到拨号计划中的目标，dialplanContext为空时使用default
+	TransferCall(ctx context.Context, callUUID, destination, dialplanContext string) error
+
+	// SendDTMF 向通话发送按键，duration为每个按键的时长，为0时使用FreeSWITCH默认值
+	SendDTMF(ctx context.Context, callUUID, digits string, duration time.Duration) error
+}
+
+// 呼叫控制参数格式，参数直接拼接到ESL命令中，不允许空白和命令分隔符
+var (
+	callUUIDPattern = regexp.MustCompile(`^[0-9A-Za-z-]{1,64}$`)
+	numberPattern   = regexp.MustCompile(`^\+?[0-9A-Za-z_.*#-]{1,64}$`)
+	contextPattern  = regexp.MustCompile(`^[0-9A-Za-z_.-]{1,64}$`)
+	digitsPattern   = regexp.MustCompile(`^[0-9*#A-Da-dwW]{1,64}$`)
+	variablePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	causePattern    = regexp.MustCompile(`^[A-Z_]{1,64}$`)
+)
+
+// maxDTMFDuration 按键时长上限
+const maxDTMFDuration = 5 * time.Second
+
+// validateCallUUID 校验通话UUID
+func validateCallUUID(callUUID string) error {
+	if !callUUIDPattern.MatchString(callUUID) {
+		return fmt.Errorf("%w: 通话UUID格式错误", ErrInvalidCallCommand)
+	}
+	return nil
+}
+
+// validateNumber 校验号码或分机，name用于错误信息
+func validateNumber(name, number string) error {
+	if !numberPattern.MatchString(number) {
+		return fmt.Errorf("%w: %s格式错误", ErrInvalidCallCommand, name)
+	}
+	return nil
+}
+
+// TransferCall 实现通话转接
+func (s *CallServiceImpl) TransferCall(ctx context.Context, callUUID, destination, dialplanContext string) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	if err := validateNumber("转接目标", destination); err != nil {
+		return err
+	}
+	if dialplanContext == "" {
+		dialplanContext = "default"
+	}
+	if !contextPattern.MatchString(dialplanContext) {
+		return fmt.Errorf("%w: 拨号计划上下文格式错误", ErrInvalidCallCommand)
+	}
+
+	resp, err := s.execute(fmt.Sprintf("uuid_transfer %s %s XML %s", callUUID, destination, dialplanContext))
+	if err != nil {
+		return fmt.Errorf("转接通话失败: %w", err)
+	}
+	log.Printf("转接通话响应: %s", resp)
+	return nil
+}
+
+// SendDTMF 实现发送按键
+func (s *CallServiceImpl) SendDTMF(ctx context.Context, callUUID, digits string, duration time.Duration) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	if !digitsPattern.MatchString(digits) {
+		return fmt.Errorf("%w: 按键只能为0-9、*、#、A-D，w/W表示停顿", ErrInvalidCallCommand)
+	}
+	if duration < 0 || duration > maxDTMFDuration {
+		return fmt.Errorf("%w: 按键时长超出范围", ErrInvalidCallCommand)
+	}
+
+	data := digits
+	if duration > 0 {
+		data = fmt.Sprintf("%s@%d", digits, duration.Milliseconds())
+	}
+	resp, err := s.execute(fmt.Sprintf("uuid_send_dtmf %s %s", callUUID, data))
+	if err != nil {
+		return fmt.Errorf("发送按键失败: %w", err)
+	}
+	log.Printf("发送按键响应: %s", resp)
+	return nil
+}
+
+// HangupCall 以指定的挂断原因结束通话，原因写入详单的hangup_cause，如 MEDIA_TIMEOUT
+func (s *CallServiceImpl) HangupCall(ctx context.Context, callUUID, cause string) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	if !causePattern.MatchString(cause) {
+		return fmt.Errorf("%w: 挂断原因格式错误", ErrInvalidCallCommand)
+	}
+
+	resp, err := s.execute(fmt.Sprintf("uuid_kill %s %s", callUUID, cause))
+	if err != nil {
+		return fmt.Errorf("结束呼叫失败: %w", err)
+	}
+	log.Printf("结束呼叫响应: %s", resp)
+	return nil
+}
+
+// SetVariable 设置通话的通道变量，变量值不能包含换行
+func (s *CallServiceImpl) SetVariable(ctx context.Context, callUUID, name, value string) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	if !variablePattern.MatchString(name) {
+		return fmt.Errorf("%w: 变量名格式错误", ErrInvalidCallCommand)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: 变量值不能包含换行", ErrInvalidCallCommand)
+	}
+
+	if _, err := s.execute(fmt.Sprintf("uuid_setvar %s %s %s", callUUID, name, value)); err != nil {
+		return fmt.Errorf("设置通道变量失败: %w", err)
+	}
+	return nil
+}
+
+// execute 执行FreeSWITCH命令，将连接错误和"-ERR"响应转换为对应的错误
+func (s *CallServiceImpl) execute(cmd string) (string, error) {
+	resp, err := s.fsClient.SendCommand(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSwitchUnavailable, err)
+	}
+	resp = strings.TrimSpace(resp)
+	if strings.HasPrefix(resp, "-ERR") {
+		reason := strings.TrimSpace(strings.TrimPrefix(resp, "-ERR"))
+		if strings.Contains(strings.ToLower(reason), "no such channel") {
+			return "", fmt.Errorf("%w: %s", ErrCallNotFound, reason)
+		}
+		return "", fmt.Errorf("%w: %s", ErrCommandRejected, reason)
+	}
+	return resp, nil
+}
@@ -4,31 +4,60 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/consent"
+	"ai_dialer_mini/internal/services/fallback"
+	"ai_dialer_mini/internal/services/hooks"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/tenant"
 )
 
 // CallService FreeSWITCH 通话服务接口
 type CallService interface {
-	// InitiateCall 发起呼叫
+	// InitiateCall 发起呼叫，返回通话UUID
 	InitiateCall(ctx context.Context, fromNumber, toNumber string) (string, error)
-	
+
 	// EndCall 结束呼叫
 	EndCall(ctx context.Context, callID string) error
-	
+
 	// HandleCallEvent 处理通话事件
 	HandleCallEvent(ctx context.Context, eventType string, eventData map[string]string) error
 }
 
+// GatewayRouter 外呼网关路由，返回按优先级排列的落地网关
+type GatewayRouter interface {
+	Gateways() []string
+}
+
+// FallbackCaller 支持语音识别降级外呼的通话服务
+type FallbackCaller interface {
+	InitiateFallbackCall(ctx context.Context, callUUID, toNumber string, policy fallback.Policy) (string, error)
+}
+
 // CallServiceImpl FreeSWITCH 通话服务实现
 type CallServiceImpl struct {
-	fsClient *freeswitch.ESLClient
+	fsClient    *freeswitch.ESLClient
+	cdrService  *CDRService
+	router      GatewayRouter
+	recordings  *RecordingArchiver
+	sessions    *CallSessionManager
+	events      models.EventPublisher
+	consent     *consent.Detector
+	inbound     *inbound.Router
+	hooks       *hooks.Dispatcher
+	tenants     *tenant.Registry
+	transcripts *TranscriptRecorder
 }
 
 // NewCallService 创建新的通话服务实例
-func NewCallService(fsClient *freeswitch.ESLClient) CallService {
+func NewCallService(fsClient *freeswitch.ESLClient) *CallServiceImpl {
 	service := &CallServiceImpl{
 		fsClient: fsClient,
+		sessions: NewCallSessionManager(),
 	}
 
 	// 注册事件处理器
@@ -36,6 +65,14 @@ func NewCallService(fsClient *freeswitch.ESLClient) CallService {
 		return service.HandleCallEvent(context.Background(), "CHANNEL_CREATE", headers)
 	})
 
+	fsClient.RegisterHandler("CHANNEL_PROGRESS", func(headers map[string]string) error {
+		return service.HandleCallEvent(context.Background(), "CHANNEL_PROGRESS", headers)
+	})
+
+	fsClient.RegisterHandler("CHANNEL_PROGRESS_MEDIA", func(headers map[string]string) error {
+		return service.HandleCallEvent(context.Background(), "CHANNEL_PROGRESS_MEDIA", headers)
+	})
+
 	fsClient.RegisterHandler("CHANNEL_ANSWER", func(headers map[string]string) error {
 		return service.HandleCallEvent(context.Background(), "CHANNEL_ANSWER", headers)
 	})
@@ -44,33 +81,333 @@ func NewCallService(fsClient *freeswitch.ESLClient) CallService {
 		return service.HandleCallEvent(context.Background(), "CHANNEL_HANGUP", headers)
 	})
 
+	fsClient.OnDTMF(service.handleDTMF)
+
 	return service
 }
 
+// SetCDRService 设置通话详单服务，设置后挂断事件会写入详单
+func (s *CallServiceImpl) SetCDRService(cdrService *CDRService) {
+	s.cdrService = cdrService
+}
+
+// SetGatewayRouter 设置外呼网关路由，设置后被叫经落地网关呼出，前一个网关失败时依次尝试下一个
+func (s *CallServiceImpl) SetGatewayRouter(router GatewayRouter) {
+	s.router = router
+}
+
+// Sessions 返回通话会话管理器，通话状态由本服务收到的ESL事件驱动
+func (s *CallServiceImpl) Sessions() *CallSessionManager {
+	return s.sessions
+}
+
+// SetRecordingArchiver 设置录音归档任务，设置后通道挂断时归档该通道的录音；
+// 配置了recording.record时，主叫通道应答后由拨号器启动录音
+func (s *CallServiceImpl) SetRecordingArchiver(archiver *RecordingArchiver) {
+	s.recordings = archiver
+}
+
+// SetEventPublisher 设置通话实时事件发布，设置后通道创建、应答和挂断时推送给监控面板
+func (s *CallServiceImpl) SetEventPublisher(events models.EventPublisher) {
+	s.events = events
+}
+
+// SetConsent 设置录音授权判断，设置后根据客户按键和识别文本记录录音授权，客户拒绝时停止录音，挂机后删除录音文件
+func (s *CallServiceImpl) SetConsent(detector *consent.Detector) {
+	s.consent = detector
+}
+
+// SetTranscripts 设置实时转写保存，设置后通道应答、挂断和客户按键记入通话转写
+func (s *CallServiceImpl) SetTranscripts(recorder *TranscriptRecorder) {
+	s.transcripts = recorder
+}
+
+// handleDTMF 处理客户按键：按键菜单等待按键时交给菜单，否则用于判断录音授权
+func (s *CallServiceImpl) handleDTMF(event freeswitch.DTMF) error {
+	log.Printf("客户按键 - UUID: %s, 按键: %s", event.UUID, event.Digit)
+	now := time.Now()
+	s.transcripts.Record(context.Background(), event.UUID, models.SpeakerCustomer, models.TranscriptKindDTMF, event.Digit, now, now)
+	if s.sessions.HandleDTMF(event.UUID, event.Digit) {
+		return nil
+	}
+	if s.consent == nil {
+		return nil
+	}
+	return s.RecordConsent(event.UUID, s.consent.FromDigit(event.Digit), consent.SourceDTMF)
+}
+
+// SetInbound 设置呼入路由，设置后匹配路由的呼入通道创建时自动应答，应答后与外呼通话一样启动音频流，
+// 音频流接入时按路由选用AI人设并播放问候语；拨号计划应对这些号码执行park，由本服务接管通道
+func (s *CallServiceImpl) SetInbound(router *inbound.Router) {
+	s.inbound = router
+}
+
+// SetHooks 设置会话事件钩子，设置后通道应答和挂断时调用
+func (s *CallServiceImpl) SetHooks(d *hooks.Dispatcher) {
+	s.hooks = d
+}
+
+// SetTenants 设置租户配置，设置后发起的呼叫按请求的租户设置通道变量和外显号码，通道创建时记录通道变量指定的租户
+func (s *CallServiceImpl) SetTenants(tenants *tenant.Registry) {
+	s.tenants = tenants
+}
+
+// channelVars 返回上下文指定的租户和语音识别后端对应的originate通道变量，以及租户的外显号码；租户未配置时返回错误
+func (s *CallServiceImpl) channelVars(ctx context.Context) (vars, callerID string, err error) {
+	var list []string
+	if id := tenant.IDFromContext(ctx); id != "" {
+		t, ok := s.tenants.Get(id)
+		if !ok {
+			return "", "", fmt.Errorf("%w: 未配置的租户 %s", ErrInvalidCallCommand, id)
+		}
+		list = append(list, s.tenants.Variable()+"="+id)
+		callerID = t.CallerID
+	}
+	if name := ASRProviderFromContext(ctx); name != "" {
+		if !asrProviderPattern.MatchString(name) {
+			return "", "", fmt.Errorf("%w: 无效的语音识别后端 %s", ErrInvalidCallCommand, name)
+		}
+		list = append(list, ASRVariable+"="+name)
+	}
+	return strings.Join(list, ","), callerID, nil
+}
+
+// answerInbound 呼入通道匹配路由时登记到通话会话并应答
+func (s *CallServiceImpl) answerInbound(uuid string, headers map[string]string) error {
+	route, ok := s.inbound.Match(headers)
+	if !ok || s.sessions == nil || !s.sessions.SetInbound(uuid, route) {
+		return nil
+	}
+	log.Printf("呼入通话 - UUID: %s, 主叫: %s, 被叫: %s, 人设: %s",
+		uuid, headers["Caller-Caller-ID-Number"], headers["Caller-Destination-Number"], route.Persona)
+
+	resp, err := s.fsClient.SendCommand(fmt.Sprintf("uuid_answer %s", uuid))
+	if err != nil {
+		return fmt.Errorf("应答呼入通话失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("应答呼入通话失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// DetectConsent 根据客户的识别文本判断录音授权，通话已确认授权后不再根据说话内容改变
+func (s *CallServiceImpl) DetectConsent(callUUID, text string) {
+	if s.consent == nil || s.sessions.Consent(callUUID) != consent.StatusPending {
+		return
+	}
+	if err := s.RecordConsent(callUUID, s.consent.FromText(text), consent.SourceSpeech); err != nil {
+		log.Printf("记录录音授权失败 - UUID: %s: %v", callUUID, err)
+	}
+}
+
+// RecordConsent 记录通话的录音授权结果，客户拒绝时立即停止通话录音；拒绝后不再改变
+func (s *CallServiceImpl) RecordConsent(callUUID string, status consent.Status, source string) error {
+	if !s.sessions.SetConsent(callUUID, status) {
+		return nil
+	}
+	log.Printf("录音授权 - UUID: %s, 结果: %s, 来源: %s", callUUID, status, source)
+	if status != consent.StatusRefused {
+		return nil
+	}
+
+	resp, err := s.fsClient.SendCommand(fmt.Sprintf("uuid_record %s stop all", callUUID))
+	if err != nil {
+		return fmt.Errorf("停止录音失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		// 通话未在录音，如由拨号计划录音或尚未开始录音，挂机后仍会删除录音文件
+		log.Printf("停止录音 - UUID: %s: %s", callUUID, strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// publishCallEvent 发布通话状态事件
+func (s *CallServiceImpl) publishCallEvent(ctx context.Context, eventType string, headers map[string]string) {
+	if s.events == nil {
+		return
+	}
+	event := &models.CallEvent{
+		Type:      eventType,
+		SessionID: headers["Unique-ID"],
+		Caller:    headers["Caller-Caller-ID-Number"],
+		Callee:    headers["Caller-Destination-Number"],
+		Cause:     headers["Hangup-Cause"],
+		IsFinal:   true,
+	}
+	if err := s.events.Publish(ctx, event); err != nil {
+		log.Printf("发布通话事件失败: %v", err)
+	}
+}
+
 // InitiateCall 实现发起呼叫
 func (s *CallServiceImpl) InitiateCall(ctx context.Context, fromNumber, toNumber string) (string, error) {
-	// 构建originate命令
-	cmd := fmt.Sprintf("originate user/%s &bridge(user/%s)", fromNumber, toNumber)
-	
+	if err := validateNumber("主叫号码", fromNumber); err != nil {
+		return "", err
+	}
+	if err := validateNumber("被叫号码", toNumber); err != nil {
+		return "", err
+	}
+
+	chanVars, callerID, err := s.channelVars(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// 构建originate命令，指定租户或语音识别后端时主被叫通道都设置对应的通道变量，被叫通道使用租户的外显号码
+	cmd := fmt.Sprintf("originate user/%s &bridge(%s)", fromNumber, s.dialString(toNumber))
+	if chanVars != "" {
+		legVars := chanVars
+		if callerID != "" {
+			legVars += ",origination_caller_id_number=" + callerID
+		}
+		cmd = fmt.Sprintf("originate {%s}user/%s &bridge({%s}%s)", chanVars, fromNumber, legVars, s.dialString(toNumber))
+	}
+
 	// 发送命令
 	resp, err := s.fsClient.SendCommand(cmd)
 	if err != nil {
-		return "", fmt.Errorf("发起呼叫失败: %v", err)
+		return "", fmt.Errorf("发起呼叫失败: %w: %v", ErrSwitchUnavailable, err)
 	}
 
 	log.Printf("发起呼叫响应: %s", resp)
-	return resp, nil
+	return parseOriginateResponse(resp)
+}
+
+// InitiateOutboundCall 先呼叫被叫，被叫接通后桥接到本地分机；callerID非空时作为外显号码
+// 与InitiateCall不同，A腿为被叫，挂断详单的应答时间即被叫接通时间
+// callUUID非空时作为通话UUID（origination_uuid），调用方可在发起呼叫前登记通话
+func (s *CallServiceImpl) InitiateOutboundCall(ctx context.Context, callUUID, callerID, toNumber, extension string) (string, error) {
+	return s.originateBridge(ctx, callUUID, callerID, toNumber, "user/"+extension, "")
+}
+
+// InitiateAssistedCall 坐席确认预览线索后呼叫被叫，被叫接通后桥接到坐席分机；
+// 两条腿都带AssistVariable通道变量，音频流接入后AI只作为坐席助手推送建议话术
+func (s *CallServiceImpl) InitiateAssistedCall(ctx context.Context, callUUID, callerID, toNumber, agent string) (string, error) {
+	assist := AssistVariable + "=true"
+	return s.originateBridge(ctx, callUUID, callerID, toNumber, "{"+assist+"}user/"+agent, assist)
+}
+
+// originateBridge 呼叫被叫，接通后桥接到target；extraVars非空时追加到被叫通道变量
+func (s *CallServiceImpl) originateBridge(ctx context.Context, callUUID, callerID, toNumber, target, extraVars string) (string, error) {
+	chanVars, tenantCallerID, err := s.channelVars(ctx)
+	if err != nil {
+		return "", err
+	}
+	vars := "ignore_early_media=true"
+	if callUUID != "" {
+		vars += ",origination_uuid=" + callUUID
+	}
+	if callerID == "" {
+		callerID = tenantCallerID
+	}
+	if callerID != "" {
+		vars += ",origination_caller_id_number=" + callerID
+	}
+	if chanVars != "" {
+		vars += "," + chanVars
+	}
+	if extraVars != "" {
+		vars += "," + extraVars
+	}
+	cmd := fmt.Sprintf("originate {%s}%s &bridge(%s)", vars, s.dialString(toNumber), target)
+
+	resp, err := s.fsClient.SendCommand(cmd)
+	if err != nil {
+		return "", fmt.Errorf("发起呼叫失败: %v", err)
+	}
+	log.Printf("外呼任务呼叫响应: %s", resp)
+	return parseOriginateResponse(resp)
+}
+
+// InitiateFallbackCall 语音识别不可用时按降级策略呼叫被叫：接通后转人工队列，或播放致歉语音后挂断
+// callUUID非空时作为通话UUID（origination_uuid）
+func (s *CallServiceImpl) InitiateFallbackCall(ctx context.Context, callUUID, toNumber string, policy fallback.Policy) (string, error) {
+	var app string
+	switch policy.Action {
+	case fallback.ActionHuman:
+		app = fmt.Sprintf("&transfer(%s XML default)", policy.Queue)
+	case fallback.ActionApology:
+		app = fmt.Sprintf("&playback(%s)", policy.Message)
+	default:
+		return "", fmt.Errorf("不支持的降级动作: %s", policy.Action)
+	}
+
+	dial := s.dialString(toNumber)
+	if callUUID != "" {
+		dial = "{origination_uuid=" + callUUID + "}" + dial
+	}
+	resp, err := s.fsClient.SendCommand(fmt.Sprintf("originate %s %s", dial, app))
+	if err != nil {
+		return "", fmt.Errorf("发起呼叫失败: %v", err)
+	}
+	log.Printf("降级呼叫响应(%s): %s", policy.Action, resp)
+	return parseOriginateResponse(resp)
+}
+
+// dialString 构建被叫呼叫串，未配置网关时呼叫本地分机，多个网关以|连接按顺序失败转移
+func (s *CallServiceImpl) dialString(toNumber string) string {
+	if s.router == nil {
+		return "user/" + toNumber
+	}
+	gateways := s.router.Gateways()
+	if len(gateways) == 0 {
+		return "user/" + toNumber
+	}
+
+	legs := make([]string, len(gateways))
+	for i, gw := range gateways {
+		legs[i] = fmt.Sprintf("sofia/gateway/%s/%s", gw, toNumber)
+	}
+	return strings.Join(legs, "|")
+}
+
+// startRecording 对主叫通道启动录音，双声道录音时左声道为主叫、右声道为被叫
+func (s *CallServiceImpl) startRecording(uuid string) error {
+	cfg := s.recordings.config
+	vars := []string{"record_sample_rate 16000"}
+	if cfg.Stereo {
+		vars = append(vars, "RECORD_STEREO true")
+	}
+	for _, v := range vars {
+		if _, err := s.fsClient.SendCommand(fmt.Sprintf("uuid_setvar %s %s", uuid, v)); err != nil {
+			return fmt.Errorf("设置录音参数失败: %v", err)
+		}
+	}
+
+	path := strings.TrimSuffix(cfg.FreeSWITCHDir, "/") + "/" + uuid + ".wav"
+	resp, err := s.fsClient.SendCommand(fmt.Sprintf("uuid_record %s start %s", uuid, path))
+	if err != nil {
+		return fmt.Errorf("启动录音失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("启动录音失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// parseOriginateResponse 解析originate命令响应（"+OK <uuid>"或"-ERR <原因>"）
+func parseOriginateResponse(resp string) (string, error) {
+	resp = strings.TrimSpace(resp)
+	if strings.HasPrefix(resp, "-ERR") {
+		return "", fmt.Errorf("发起呼叫失败: %s", strings.TrimSpace(strings.TrimPrefix(resp, "-ERR")))
+	}
+	return strings.TrimSpace(strings.TrimPrefix(resp, "+OK")), nil
 }
 
 // EndCall 实现结束呼叫
 func (s *CallServiceImpl) EndCall(ctx context.Context, callID string) error {
+	if err := validateCallUUID(callID); err != nil {
+		return err
+	}
+
 	// 构建hangup命令
 	cmd := fmt.Sprintf("uuid_kill %s", callID)
-	
+
 	// 发送命令
-	resp, err := s.fsClient.SendCommand(cmd)
+	resp, err := s.execute(cmd)
 	if err != nil {
-		return fmt.Errorf("结束呼叫失败: %v", err)
+		return fmt.Errorf("结束呼叫失败: %w", err)
 	}
 
 	log.Printf("结束呼叫响应: %s", resp)
@@ -83,14 +420,83 @@ func (s *CallServiceImpl) HandleCallEvent(ctx context.Context, eventType string,
 	channelName := headers["Channel-Name"]
 	uuid := headers["Unique-ID"]
 
+	// 挂断时会话随之移除，先取出录音授权结果写入详单
+	var recordingConsent consent.Status
+	if eventType == "CHANNEL_HANGUP" && s.sessions != nil {
+		recordingConsent = s.sessions.Consent(uuid)
+	}
+
+	// 推进通话状态机，挂断时统一释放通话资源
+	if s.sessions != nil {
+		if err := s.sessions.HandleEvent(eventType, uuid); err != nil {
+			log.Printf("通话状态更新失败 - UUID: %s: %v", uuid, err)
+		}
+	}
+
 	switch eventType {
 	case "CHANNEL_CREATE":
 		log.Printf("新通道创建 - UUID: %s, 通道: %s", uuid, channelName)
+		s.publishCallEvent(ctx, models.EventTypeCallCreated, headers)
+		if id := s.tenants.FromHeaders(headers); id != "" && s.sessions != nil {
+			s.sessions.SetTenant(uuid, id)
+		}
+		if name := headers["variable_"+ASRVariable]; name != "" && s.sessions != nil {
+			s.sessions.SetASRProvider(uuid, name)
+		}
+		if headers["variable_"+AssistVariable] == "true" && s.sessions != nil {
+			s.sessions.SetAssist(uuid)
+		}
+		if err := s.answerInbound(uuid, headers); err != nil {
+			return err
+		}
+	case "CHANNEL_PROGRESS", "CHANNEL_PROGRESS_MEDIA":
+		log.Printf("通道振铃 - UUID: %s, 通道: %s", uuid, channelName)
 	case "CHANNEL_ANSWER":
 		log.Printf("通道应答 - UUID: %s, 通道: %s", uuid, channelName)
+		s.publishCallEvent(ctx, models.EventTypeCallAnswered, headers)
+		now := time.Now()
+		s.transcripts.Start(uuid, now)
+		s.transcripts.Record(ctx, uuid, models.SpeakerSystem, models.TranscriptKindEvent, "通话接通", now, now)
+		s.hooks.CallAnswered(ctx, hooks.Call{
+			UUID:      uuid,
+			Caller:    headers["Caller-Caller-ID-Number"],
+			Callee:    headers["Caller-Destination-Number"],
+			Direction: headers["Call-Direction"],
+			Headers:   headers,
+		})
+		// FreeSWITCH客户端启用音频流后，通道音频推送到按配置生成的签名地址
+		if s.fsClient.AudioStreams() != nil {
+			if err := s.fsClient.StartAudioStream(uuid); err != nil {
+				return err
+			}
+		}
+		// 被叫通道（Other-Type为originator）的音频已在主叫通道录音的右声道中
+		if s.recordings != nil && s.recordings.config.Record && headers["Other-Type"] != "originator" {
+			if err := s.startRecording(uuid); err != nil {
+				return err
+			}
+		}
 	case "CHANNEL_HANGUP":
 		hangupCause := headers["Hangup-Cause"]
 		log.Printf("通道挂断 - UUID: %s, 通道: %s, 原因: %s", uuid, channelName, hangupCause)
+		s.publishCallEvent(ctx, models.EventTypeCallHangup, headers)
+		now := time.Now()
+		s.transcripts.Record(ctx, uuid, models.SpeakerSystem, models.TranscriptKindEvent, "通话挂断: "+hangupCause, now, now)
+		s.transcripts.Finish(uuid)
+
+		cdr := CDRFromHeaders(headers)
+		cdr.RecordingConsent = string(recordingConsent)
+		s.hooks.Hangup(ctx, cdr)
+		if s.recordings != nil {
+			s.recordings.Enqueue(cdr)
+		}
+
+		// 写入通话详单
+		if s.cdrService != nil {
+			if err := s.cdrService.Record(ctx, cdr); err != nil {
+				return fmt.Errorf("保存通话详单失败: %v", err)
+			}
+		}
 	}
 
 	return nil
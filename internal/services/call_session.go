@@ -0,0 +1,521 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/services/consent"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/inbound"
+)
+
+// CallState 通话状态
+type CallState string
+
+// 通话状态，按ESL事件依次推进：Created → Ringing → Answered → Talking → Hangup，
+// 配置了话后处理时长时挂断后先进入WrapUp，话后处理结束后进入Hangup
+const (
+	CallStateCreated  CallState = "created"  // 通道已创建
+	CallStateRinging  CallState = "ringing"  // 被叫振铃或收到早期媒体
+	CallStateAnswered CallState = "answered" // 通道已应答，等待音频流接入
+	CallStateTalking  CallState = "talking"  // 音频流已接入，AI与客户对话中
+	CallStateWrapUp   CallState = "wrapup"   // 通道已挂断，通话资源已释放，话后处理中
+	CallStateHangup   CallState = "hangup"   // 通话已结束，会话已移除
+)
+
+// ErrInvalidCallTransition 通话状态不允许的转换
+var ErrInvalidCallTransition = errors.New("通话状态转换无效")
+
+// callTransitions 各状态允许转换到的状态，未振铃直接应答（如呼入）的通话可跳过Ringing
+var callTransitions = map[CallState][]CallState{
+	CallStateCreated:  {CallStateRinging, CallStateAnswered, CallStateWrapUp, CallStateHangup},
+	CallStateRinging:  {CallStateAnswered, CallStateWrapUp, CallStateHangup},
+	CallStateAnswered: {CallStateTalking, CallStateWrapUp, CallStateHangup},
+	CallStateTalking:  {CallStateWrapUp, CallStateHangup},
+	CallStateWrapUp:   {CallStateHangup},
+}
+
+// callStateOrder 通话状态的先后顺序
+var callStateOrder = map[CallState]int{
+	CallStateCreated:  0,
+	CallStateRinging:  1,
+	CallStateAnswered: 2,
+	CallStateTalking:  3,
+	CallStateWrapUp:   4,
+	CallStateHangup:   5,
+}
+
+// callEventStates ESL事件对应的通话状态
+var callEventStates = map[string]CallState{
+	"CHANNEL_CREATE":         CallStateCreated,
+	"CHANNEL_PROGRESS":       CallStateRinging,
+	"CHANNEL_PROGRESS_MEDIA": CallStateRinging,
+	"CHANNEL_ANSWER":         CallStateAnswered,
+	"CHANNEL_HANGUP":         CallStateHangup,
+}
+
+// endedRetention 挂断的通话UUID保留时间，期间乱序到达的事件不会重新创建会话
+const endedRetention = time.Minute
+
+// PlaybackStopper 停止通话中的语音播放
+type PlaybackStopper interface {
+	Stop(callUUID string) error
+}
+
+// CallSessionInfo 通话会话快照
+type CallSessionInfo struct {
+	UUID        string     `json:"uuid"`
+	State       CallState  `json:"state"`
+	SessionID   string     `json:"session_id,omitempty"` // 音频流连接的识别和对话会话ID
+	CreatedAt   time.Time  `json:"created_at"`
+	AnsweredAt  *time.Time `json:"answered_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`    // 挂断时间
+	Disposition string     `json:"disposition,omitempty"` // 话后处理期间主管修改的通话结果
+
+	RecordingConsent consent.Status `json:"recording_consent,omitempty"` // 录音授权结果，未确认时为空
+	Inbound          bool           `json:"inbound,omitempty"`           // 是否为接入AI的呼入通话
+	Persona          string         `json:"persona,omitempty"`           // 呼入通话按被叫号码选用的提示词配置
+	Tenant           string         `json:"tenant,omitempty"`            // 通道变量指定的租户，未指定时为空
+	ASRProvider      string         `json:"asr_provider,omitempty"`      // 通道变量指定的语音识别后端，未指定时使用默认后端
+	Assist           bool           `json:"assist,omitempty"`            // 是否为坐席通话，AI只推送建议话术
+	DTMF             string         `json:"dtmf,omitempty"`              // 通话中客户的全部按键
+	DTMFMenu         string         `json:"dtmf_menu,omitempty"`         // 正在等待按键的菜单
+	DTMFInputs       []dtmf.Result  `json:"dtmf_inputs,omitempty"`       // 已结束的按键菜单输入
+}
+
+// CallSession 单个通话的会话，持有通话期间创建的资源（音频流连接上的识别会话、对话上下文、语音播放），
+// 挂断时统一释放，避免资源散落在各个goroutine中
+type CallSession struct {
+	mu          sync.Mutex
+	uuid        string
+	state       CallState
+	sessionID   string
+	createdAt   time.Time
+	answeredAt  time.Time
+	endedAt     time.Time
+	closeASR    func() // 关闭音频流连接，连接关闭时识别会话、对话轮次和进行中的回复随之结束
+	streamSeq   int    // 音频流连接序号，音频流重启后旧连接注销时不影响新连接
+	playback    PlaybackStopper
+	consent     consent.Status // 录音授权结果
+	inbound     *inbound.Route // 呼入通话的路由，外呼通话为nil
+	tenant      string         // 通道变量指定的租户
+	asr         string         // 通道变量指定的语音识别后端
+	assist      bool           // 通道变量标记的坐席通话
+	greeted     bool           // 呼入问候语是否已取出，音频流重启后不再重复问候
+	dtmf        string         // 客户的全部按键
+	menu        *dtmf.Collector
+	inputs      []dtmf.Result // 已结束的按键菜单输入
+	disposition string        // 话后处理期间修改的通话结果
+	wrapUpTimer *time.Timer   // 话后处理超时结束的定时器
+}
+
+// newCallSession 创建处于Created状态的通话会话
+func newCallSession(uuid string, playback PlaybackStopper) *CallSession {
+	return &CallSession{uuid: uuid, state: CallStateCreated, createdAt: time.Now(), playback: playback}
+}
+
+// UUID 通话UUID
+func (c *CallSession) UUID() string {
+	return c.uuid
+}
+
+// State 当前通话状态
+func (c *CallSession) State() CallState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Info 返回通话会话快照
+func (c *CallSession) Info() CallSessionInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info := CallSessionInfo{UUID: c.uuid, State: c.state, SessionID: c.sessionID, CreatedAt: c.createdAt, RecordingConsent: c.consent}
+	if c.inbound != nil {
+		info.Inbound = true
+		info.Persona = c.inbound.Persona
+	}
+	info.Tenant = c.tenant
+	info.ASRProvider = c.asr
+	info.Assist = c.assist
+	info.DTMF = c.dtmf
+	if c.menu != nil && !c.menu.Closed() {
+		info.DTMFMenu = c.menu.Name()
+	}
+	info.DTMFInputs = append([]dtmf.Result(nil), c.inputs...)
+	info.Disposition = c.disposition
+	if !c.answeredAt.IsZero() {
+		answeredAt := c.answeredAt
+		info.AnsweredAt = &answeredAt
+	}
+	if !c.endedAt.IsZero() {
+		endedAt := c.endedAt
+		info.EndedAt = &endedAt
+	}
+	return info
+}
+
+// transition 转换到目标状态，需持有锁；
+// ESL事件在各自的goroutine中处理，可能乱序到达，已处于目标状态或目标状态落后于当前状态时不做处理
+func (c *CallSession) transition(to CallState) error {
+	if callStateOrder[to] <= callStateOrder[c.state] {
+		return nil
+	}
+	for _, allowed := range callTransitions[c.state] {
+		if allowed == to {
+			c.state = to
+			switch to {
+			case CallStateAnswered:
+				c.answeredAt = time.Now()
+			case CallStateWrapUp:
+				c.endedAt = time.Now()
+			case CallStateHangup:
+				// 经过话后处理的通话保留挂断时间
+				if c.endedAt.IsZero() {
+					c.endedAt = time.Now()
+				}
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidCallTransition, c.state, to)
+}
+
+// teardown 释放通话资源：关闭按键菜单，停止通话中的播放并关闭音频流连接
+func (c *CallSession) teardown() {
+	c.mu.Lock()
+	closeASR, playback, menu := c.closeASR, c.playback, c.menu
+	c.closeASR, c.menu = nil, nil
+	c.mu.Unlock()
+
+	if menu != nil {
+		menu.Close()
+	}
+
+	if playback != nil {
+		if err := playback.Stop(c.uuid); err != nil {
+			log.Printf("停止通话播放失败 - UUID: %s: %v", c.uuid, err)
+		}
+	}
+	if closeASR != nil {
+		closeASR()
+	}
+}
+
+// CallSessionManager 按ESL事件驱动通话会话状态机，并在挂断时释放通话资源
+type CallSessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*CallSession
+	ended    map[string]time.Time // 最近挂断的通话及挂断时间
+	playback PlaybackStopper
+	menus    dtmf.Config
+	onInput  func(callUUID string, result dtmf.Result)
+	wrapUp   time.Duration // 话后处理时长，为0时挂断即移除会话
+	onWrapUp func(callUUID string, acw time.Duration, disposition string)
+}
+
+// NewCallSessionManager 创建通话会话管理器
+func NewCallSessionManager() *CallSessionManager {
+	return &CallSessionManager{sessions: make(map[string]*CallSession), ended: make(map[string]time.Time)}
+}
+
+// SetPlayback 设置通话语音播放，设置后挂断时停止该通话中的播放
+func (m *CallSessionManager) SetPlayback(playback PlaybackStopper) {
+	m.mu.Lock()
+	m.playback = playback
+	m.mu.Unlock()
+}
+
+// SetMenus 设置按键菜单，onInput在菜单输入结束（按键完成或超时）时调用，不经过语音识别交给对话
+func (m *CallSessionManager) SetMenus(menus dtmf.Config, onInput func(callUUID string, result dtmf.Result)) {
+	m.mu.Lock()
+	m.menus, m.onInput = menus, onInput
+	m.mu.Unlock()
+}
+
+// Get 返回通话会话
+func (m *CallSessionManager) Get(uuid string) (*CallSession, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[uuid]
+	return session, ok
+}
+
+// List 返回所有进行中的通话会话快照
+func (m *CallSessionManager) List() []CallSessionInfo {
+	m.mu.Lock()
+	sessions := make([]*CallSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		sessions = append(sessions, session)
+	}
+	m.mu.Unlock()
+
+	infos := make([]CallSessionInfo, len(sessions))
+	for i, session := range sessions {
+		infos[i] = session.Info()
+	}
+	return infos
+}
+
+// Count 返回进行中的通话数
+func (m *CallSessionManager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// session 返回通话会话，不存在时创建（如服务在通话中途启动），通话已挂断时返回nil
+func (m *CallSessionManager) session(uuid string) *CallSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ended := m.ended[uuid]; ended {
+		return nil
+	}
+	session, ok := m.sessions[uuid]
+	if !ok {
+		session = newCallSession(uuid, m.playback)
+		m.sessions[uuid] = session
+	}
+	return session
+}
+
+// HandleEvent 按ESL事件推进通话状态，挂断时释放资源并移除会话；配置了话后处理时长时挂断后会话进入话后处理，到时移除
+func (m *CallSessionManager) HandleEvent(eventName, uuid string) error {
+	to, ok := callEventStates[eventName]
+	if !ok || uuid == "" {
+		return nil
+	}
+	m.mu.Lock()
+	wrapUp := m.wrapUp
+	m.mu.Unlock()
+	if to == CallStateHangup && wrapUp > 0 {
+		to = CallStateWrapUp
+	}
+
+	session := m.session(uuid)
+	if session == nil {
+		return nil
+	}
+	session.mu.Lock()
+	from := session.state
+	err := session.transition(to)
+	changed := session.state != from
+	session.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		return nil
+	}
+	switch to {
+	case CallStateWrapUp:
+		session.teardown()
+		m.startWrapUp(session, wrapUp)
+	case CallStateHangup:
+		m.remove(uuid)
+		session.teardown()
+	}
+	return nil
+}
+
+// remove 移除已结束的通话会话，并记录结束时间以忽略之后乱序到达的事件
+func (m *CallSessionManager) remove(uuid string) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, uuid)
+	for id, endedAt := range m.ended {
+		if now.Sub(endedAt) > endedRetention {
+			delete(m.ended, id)
+		}
+	}
+	m.ended[uuid] = now
+}
+
+// SetConsent 记录通话的录音授权结果，客户拒绝后不再改变；返回结果是否有变化，通话不存在时返回false
+func (m *CallSessionManager) SetConsent(uuid string, status consent.Status) bool {
+	session, ok := m.Get(uuid)
+	if !ok || status == consent.StatusPending {
+		return false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.consent == status || session.consent == consent.StatusRefused {
+		return false
+	}
+	session.consent = status
+	return true
+}
+
+// Consent 返回通话的录音授权结果，通话不存在或尚未确认时返回StatusPending
+func (m *CallSessionManager) Consent(uuid string) consent.Status {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return consent.StatusPending
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.consent
+}
+
+// SetInbound 标记通话为接入AI的呼入通话并记录路由，通话不存在时返回false
+func (m *CallSessionManager) SetInbound(uuid string, route inbound.Route) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	session.inbound = &route
+	session.mu.Unlock()
+	return true
+}
+
+// InboundRoute 返回呼入通话的路由，供音频流接入时设置AI人设；
+// 问候语只在第一次调用时返回，音频流重启后重新接入时为空。通话不存在或不是呼入通话时返回false
+func (m *CallSessionManager) InboundRoute(uuid string) (inbound.Route, bool) {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return inbound.Route{}, false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.inbound == nil {
+		return inbound.Route{}, false
+	}
+	route := *session.inbound
+	if session.greeted {
+		route.Greeting = ""
+	}
+	session.greeted = true
+	return route, true
+}
+
+// SetTenant 记录通话所属的租户，通话不存在时返回false
+func (m *CallSessionManager) SetTenant(uuid, tenantID string) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	session.tenant = tenantID
+	session.mu.Unlock()
+	return true
+}
+
+// CallTenant 返回通话所属的租户，供音频流接入时选用租户的凭据、模型和人设；通话不存在或未指定租户时返回空
+func (m *CallSessionManager) CallTenant(uuid string) string {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return ""
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.tenant
+}
+
+// SetASRProvider 记录通话指定的语音识别后端，通话不存在时返回false
+func (m *CallSessionManager) SetASRProvider(uuid, name string) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	session.asr = name
+	session.mu.Unlock()
+	return true
+}
+
+// CallASRProvider 返回通话指定的语音识别后端，通话不存在或未指定时返回空
+func (m *CallSessionManager) CallASRProvider(uuid string) string {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return ""
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.asr
+}
+
+// OpenMenu 为通话打开按键菜单，已打开的菜单被替换；菜单在按键完成、超时或通话挂断时关闭
+func (m *CallSessionManager) OpenMenu(uuid, name string) error {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return fmt.Errorf("通话不存在: %s", uuid)
+	}
+	m.mu.Lock()
+	menus, onInput := m.menus, m.onInput
+	m.mu.Unlock()
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.state == CallStateHangup {
+		return fmt.Errorf("通话已挂断: %s", uuid)
+	}
+	menu, err := menus.Open(name, func(result dtmf.Result) {
+		session.mu.Lock()
+		session.inputs = append(session.inputs, result)
+		session.mu.Unlock()
+		log.Printf("按键菜单结束 - UUID: %s, 菜单: %s, 按键: %q, 超时: %v", uuid, result.Menu, result.Digits, result.TimedOut)
+		if onInput != nil {
+			onInput(uuid, result)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if session.menu != nil {
+		session.menu.Close()
+	}
+	session.menu = menu
+	return nil
+}
+
+// HandleDTMF 记录客户按键并交给正在等待按键的菜单，返回按键是否被菜单接收；通话不存在时返回false
+func (m *CallSessionManager) HandleDTMF(uuid, digit string) bool {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return false
+	}
+	session.mu.Lock()
+	session.dtmf += digit
+	menu := session.menu
+	session.mu.Unlock()
+	// 菜单结束时回调会获取会话锁，按键在锁外交给菜单
+	return menu != nil && menu.Add(digit)
+}
+
+// AttachStream 通话音频流连接建立，登记连接的会话ID和关闭函数，通话进入Talking状态；
+// 音频流重启时新连接替换旧连接，返回的函数在连接结束时调用，注销该连接
+func (m *CallSessionManager) AttachStream(callUUID, sessionID string, closeStream func()) (func(), error) {
+	m.mu.Lock()
+	session, ok := m.sessions[callUUID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("通话不存在: %s", callUUID)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.state == CallStateWrapUp {
+		return nil, fmt.Errorf("通话已挂断: %s", callUUID)
+	}
+	if err := session.transition(CallStateTalking); err != nil {
+		return nil, err
+	}
+	session.sessionID = sessionID
+	session.closeASR = closeStream
+	session.streamSeq++
+	seq := session.streamSeq
+
+	return func() {
+		session.mu.Lock()
+		if session.streamSeq == seq {
+			session.closeASR = nil
+		}
+		session.mu.Unlock()
+	}, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"ai_dialer_mini/internal/repositories"
+)
+
+// ErrNotInWrapUp 通话不在话后处理中（未挂断、话后处理已结束或未配置话后处理时长）
+var ErrNotInWrapUp = errors.New("通话不在话后处理中")
+
+// maxDispositionLength 通话结果的最大字数，与calls.disposition列宽一致
+const maxDispositionLength = 64
+
+// WrapUpController 支持话后处理的通话服务
+type WrapUpController interface {
+	// SetDisposition 话后处理期间修改通话结果
+	SetDisposition(ctx context.Context, callUUID, disposition string) error
+
+	// CompleteWrapUp 提前结束话后处理
+	CompleteWrapUp(ctx context.Context, callUUID string) error
+}
+
+// SetWrapUp 设置话后处理时长，onEnd在话后处理结束（到时或提前结束）时调用，acw为挂断到结束的时长
+func (m *CallSessionManager) SetWrapUp(duration time.Duration, onEnd func(callUUID string, acw time.Duration, disposition string)) {
+	m.mu.Lock()
+	m.wrapUp, m.onWrapUp = duration, onEnd
+	m.mu.Unlock()
+}
+
+// startWrapUp 通话挂断后开始话后处理，到时自动结束
+func (m *CallSessionManager) startWrapUp(session *CallSession, duration time.Duration) {
+	uuid := session.uuid
+	timer := time.AfterFunc(duration, func() {
+		if err := m.EndWrapUp(uuid); err == nil {
+			log.Printf("话后处理到时结束 - UUID: %s", uuid)
+		}
+	})
+	session.mu.Lock()
+	session.wrapUpTimer = timer
+	session.mu.Unlock()
+}
+
+// EndWrapUp 结束话后处理并移除会话，通话不在话后处理中时返回ErrNotInWrapUp
+func (m *CallSessionManager) EndWrapUp(uuid string) error {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return ErrNotInWrapUp
+	}
+	session.mu.Lock()
+	if session.state != CallStateWrapUp {
+		session.mu.Unlock()
+		return ErrNotInWrapUp
+	}
+	if err := session.transition(CallStateHangup); err != nil {
+		session.mu.Unlock()
+		return err
+	}
+	if session.wrapUpTimer != nil {
+		session.wrapUpTimer.Stop()
+	}
+	acw, disposition := time.Since(session.endedAt), session.disposition
+	session.mu.Unlock()
+
+	m.remove(uuid)
+	m.mu.Lock()
+	onEnd := m.onWrapUp
+	m.mu.Unlock()
+	if onEnd != nil {
+		onEnd(uuid, acw, disposition)
+	}
+	return nil
+}
+
+// SetDisposition 话后处理期间记录修改后的通话结果，通话不在话后处理中时返回ErrNotInWrapUp
+func (m *CallSessionManager) SetDisposition(uuid, disposition string) error {
+	session, ok := m.Get(uuid)
+	if !ok {
+		return ErrNotInWrapUp
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.state != CallStateWrapUp {
+		return ErrNotInWrapUp
+	}
+	session.disposition = disposition
+	return nil
+}
+
+// SetWrapUp 设置话后处理时长：挂机后通话会话保留该时长，期间挂机收尾任务照常执行，主管可修改通话结果，
+// 结束后话后处理时长写入通话详单；为0时挂机即结束
+func (s *CallServiceImpl) SetWrapUp(duration time.Duration) {
+	s.sessions.SetWrapUp(duration, s.finishWrapUp)
+}
+
+// finishWrapUp 话后处理结束，记录话后处理时长
+func (s *CallServiceImpl) finishWrapUp(callUUID string, acw time.Duration, disposition string) {
+	log.Printf("话后处理结束 - UUID: %s, 时长: %v, 通话结果: %s", callUUID, acw.Round(time.Second), disposition)
+	if s.cdrService == nil {
+		return
+	}
+	if err := s.cdrService.RecordACW(context.Background(), callUUID, acw); err != nil {
+		log.Printf("记录话后处理时长失败 - UUID: %s: %v", callUUID, err)
+	}
+}
+
+// SetDisposition 话后处理期间修改通话结果，同时更新通话记录
+func (s *CallServiceImpl) SetDisposition(ctx context.Context, callUUID, disposition string) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	if disposition == "" || utf8.RuneCountInString(disposition) > maxDispositionLength {
+		return fmt.Errorf("%w: 通话结果不能为空且不能超过%d个字", ErrInvalidCallCommand, maxDispositionLength)
+	}
+	if err := s.sessions.SetDisposition(callUUID, disposition); err != nil {
+		return err
+	}
+	if s.cdrService == nil {
+		return nil
+	}
+	// 未登记的通话（如分机直拨）没有通话记录，只保留在会话中
+	err := s.cdrService.UpdateDisposition(ctx, callUUID, disposition)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return fmt.Errorf("更新通话结果失败: %v", err)
+	}
+	return nil
+}
+
+// CompleteWrapUp 提前结束话后处理，如坐席完成小结后置为空闲
+func (s *CallServiceImpl) CompleteWrapUp(ctx context.Context, callUUID string) error {
+	if err := validateCallUUID(callUUID); err != nil {
+		return err
+	}
+	return s.sessions.EndWrapUp(callUUID)
+}
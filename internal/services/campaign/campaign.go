@@ -0,0 +1,767 @@
+// Package campaign 外呼任务管理：任务与线索存放在MySQL，后台按任务的拨打速率领取到期线索发起呼叫，
+// 挂机后根据详单更新线索结果并按重试策略安排重拨；线索状态变化时推送到任务配置的Webhook地址
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/dnd"
+	"ai_dialer_mini/internal/services/fallback"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/outbox"
+	"ai_dialer_mini/internal/services/throttle"
+)
+
+// ErrInvalidTransition 外呼任务当前状态不允许该操作
+var ErrInvalidTransition = errors.New("外呼任务当前状态不允许该操作")
+
+// 预览拨号的错误
+var (
+	ErrNotPreviewing     = errors.New("线索不在等待坐席确认状态")
+	ErrNoAgent           = errors.New("未指定坐席分机，任务也未配置默认坐席分机")
+	ErrAssistUnsupported = errors.New("拨号器不支持坐席预览外呼")
+)
+
+// agentPattern 坐席分机格式，分机会写入originate命令
+var agentPattern = regexp.MustCompile(`^[0-9A-Za-z_.@-]{1,64}$`)
+
+// Config 外呼任务配置
+type Config struct {
+	Extension    string          `yaml:"extension"`     // 被叫接通后桥接的本地分机（AI坐席），拨号计划中应在该分机启动音频流
+	TickInterval time.Duration   `yaml:"tick_interval"` // 调度间隔
+	BatchSize    int             `yaml:"batch_size"`    // 单个任务每次调度最多领取的线索数
+	QuietHours   dnd.Config      `yaml:"quiet_hours"`   // 按被叫当地时间限制拨打时段
+	AutoThrottle throttle.Config `yaml:"auto_throttle"` // 语音识别或大模型指标异常时自动降低拨打速率
+	Preview      PreviewConfig   `yaml:"preview"`       // 预览拨号
+	Metrics      MetricsConfig   `yaml:"metrics"`       // 任务指标
+}
+
+// PreviewConfig 预览拨号配置：线索先展示给坐席，坐席确认后才发起呼叫
+type PreviewConfig struct {
+	Timeout    time.Duration `yaml:"timeout"`     // 坐席确认超时时间，超时未确认的线索重新排队
+	MaxPending int           `yaml:"max_pending"` // 单个任务同时等待坐席确认的最大线索数
+}
+
+// Validate 校验配置
+func (c PreviewConfig) Validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout: 不能为负数")
+	}
+	if c.MaxPending < 0 {
+		return fmt.Errorf("max_pending: 不能为负数")
+	}
+	return nil
+}
+
+// Dialer 外呼拨号，先呼叫被叫，接通后桥接到本地分机；通话使用调用方预先生成的callUUID
+type Dialer interface {
+	InitiateOutboundCall(ctx context.Context, callUUID, callerID, toNumber, extension string) (string, error)
+}
+
+// fallbackDialer 支持语音识别降级外呼的拨号器
+type fallbackDialer interface {
+	InitiateFallbackCall(ctx context.Context, callUUID, toNumber string, policy fallback.Policy) (string, error)
+}
+
+// assistDialer 支持坐席预览外呼的拨号器，被叫接通后桥接到坐席分机，AI只作为助手推送建议话术
+type assistDialer interface {
+	InitiateAssistedCall(ctx context.Context, callUUID, callerID, toNumber, agent string) (string, error)
+}
+
+// ReachabilityChecker 拨打前号码状态检查，号码为空号或停机时将线索标记为unreachable并返回错误
+type ReachabilityChecker interface {
+	CheckLead(ctx context.Context, lead *models.Lead) error
+}
+
+// Manager 外呼任务管理
+type Manager struct {
+	store        *repositories.Store
+	dialer       Dialer
+	config       Config
+	fallback     *fallback.Monitor
+	outbox       *outbox.Outbox
+	reachability ReachabilityChecker
+	lifecycle    *lifecycle.Manager
+	quietHours   *dnd.Checker
+	throttle     *throttle.Governor
+
+	mu       sync.Mutex
+	buckets  map[int64]*bucket
+	exported map[int64]struct{} // 已导出指标的任务
+}
+
+// New 创建外呼任务管理，dialer为nil时只能管理任务，不会发起呼叫
+func New(store *repositories.Store, dialer Dialer, config Config) *Manager {
+	if config.TickInterval <= 0 {
+		config.TickInterval = time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+	if config.Preview.Timeout <= 0 {
+		config.Preview.Timeout = 2 * time.Minute
+	}
+	if config.Preview.MaxPending <= 0 {
+		config.Preview.MaxPending = 5
+	}
+	if config.Metrics.Interval <= 0 {
+		config.Metrics.Interval = 15 * time.Second
+	}
+	if config.Metrics.AbandonThreshold <= 0 {
+		config.Metrics.AbandonThreshold = 5 * time.Second
+	}
+	return &Manager{
+		store:    store,
+		dialer:   dialer,
+		config:   config,
+		buckets:  make(map[int64]*bucket),
+		exported: make(map[int64]struct{}),
+	}
+}
+
+// SetASRFallback 设置语音识别降级策略，识别服务不可用时按任务的策略暂停拨号、转人工或播放致歉语音
+func (m *Manager) SetASRFallback(monitor *fallback.Monitor) {
+	m.fallback = monitor
+}
+
+// SetOutbox 设置发件箱，设置后线索状态变化时在同一事务中写入任务Webhook推送消息
+func (m *Manager) SetOutbox(ob *outbox.Outbox) {
+	m.outbox = ob
+}
+
+// SetReachability 设置号码状态检查，设置后领取的线索在发起呼叫前检查号码，跳过空号和停机号码
+func (m *Manager) SetReachability(checker ReachabilityChecker) {
+	m.reachability = checker
+}
+
+// SetLifecycle 设置进程生命周期，排空期间不再领取线索发起呼叫
+func (m *Manager) SetLifecycle(manager *lifecycle.Manager) {
+	m.lifecycle = manager
+}
+
+// SetQuietHours 设置免打扰时段检查，设置后被叫当地时间不在允许时段内的线索推迟到允许拨打时再领取
+func (m *Manager) SetQuietHours(checker *dnd.Checker) {
+	m.quietHours = checker
+}
+
+// SetThrottle 设置自动限速，设置后语音识别或大模型指标超过阈值期间按比例降低所有任务的拨打速率
+func (m *Manager) SetThrottle(governor *throttle.Governor) {
+	m.throttle = governor
+}
+
+// Create 创建外呼任务及其线索，未填写的拨打参数使用默认值
+func (m *Manager) Create(ctx context.Context, campaign *models.Campaign, leads []*models.Lead) error {
+	if campaign.Name == "" {
+		return fmt.Errorf("外呼任务名称不能为空")
+	}
+	if campaign.WebhookURL != "" {
+		if u, err := url.Parse(campaign.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("外呼任务Webhook地址无效: %s", campaign.WebhookURL)
+		}
+	}
+	switch campaign.DialMode {
+	case "":
+		campaign.DialMode = models.DialModeAuto
+	case models.DialModeAuto, models.DialModePreview:
+	default:
+		return fmt.Errorf("不支持的拨号方式: %s", campaign.DialMode)
+	}
+	if campaign.AgentExtension != "" && !agentPattern.MatchString(campaign.AgentExtension) {
+		return fmt.Errorf("坐席分机格式无效: %s", campaign.AgentExtension)
+	}
+	if campaign.PacingPerMinute <= 0 {
+		campaign.PacingPerMinute = 10
+	}
+	if campaign.MaxAttempts <= 0 {
+		campaign.MaxAttempts = 3
+	}
+	if campaign.RetryIntervalSeconds <= 0 {
+		campaign.RetryIntervalSeconds = 3600
+	}
+	campaign.Status = models.CampaignStatusDraft
+
+	return m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		if err := uow.Campaigns.Create(ctx, campaign); err != nil {
+			return err
+		}
+		return m.createLeads(ctx, uow, campaign, leads)
+	})
+}
+
+// AddLeads 向外呼任务追加线索，已完成的任务重新进入暂停状态等待启动
+func (m *Manager) AddLeads(ctx context.Context, campaignID int64, leads []*models.Lead) error {
+	return m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		campaign, err := uow.Campaigns.Get(ctx, campaignID)
+		if err != nil {
+			return err
+		}
+		if err := m.createLeads(ctx, uow, campaign, leads); err != nil {
+			return err
+		}
+		if campaign.Status == models.CampaignStatusCompleted {
+			return uow.Campaigns.UpdateStatus(ctx, campaignID, models.CampaignStatusPaused)
+		}
+		return nil
+	})
+}
+
+// createLeads 批量创建线索
+func (m *Manager) createLeads(ctx context.Context, uow *repositories.UnitOfWork, campaign *models.Campaign, leads []*models.Lead) error {
+	for _, lead := range leads {
+		if lead.Phone == "" {
+			return fmt.Errorf("线索电话号码不能为空")
+		}
+		lead.CampaignID = campaign.ID
+		lead.Status = models.LeadStatusQueued
+		if err := uow.Leads.Create(ctx, lead); err != nil {
+			return err
+		}
+		if err := m.notifyLead(ctx, uow, campaign, lead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLead 在事务中更新线索状态并推送状态变化
+func (m *Manager) updateLead(ctx context.Context, uow *repositories.UnitOfWork, campaign *models.Campaign, lead *models.Lead, status string, next *time.Time) error {
+	if err := uow.Leads.UpdateStatus(ctx, lead.ID, status, next); err != nil {
+		return err
+	}
+	lead.Status = status
+	lead.NextAttemptAt = next
+	return m.notifyLead(ctx, uow, campaign, lead)
+}
+
+// notifyLead 将线索当前状态写入发件箱，推送到任务的Webhook地址；未设置发件箱或任务未配置webhook_url时不推送
+// 幂等键包含拨打次数，同一次拨打的同一状态只推送一次
+func (m *Manager) notifyLead(ctx context.Context, uow *repositories.UnitOfWork, campaign *models.Campaign, lead *models.Lead) error {
+	if m.outbox == nil || campaign.WebhookURL == "" {
+		return nil
+	}
+	event := leadEvent(lead)
+	if event == "" {
+		return nil
+	}
+	return m.outbox.Enqueue(ctx, uow.Tx(), outbox.Message{
+		IdempotencyKey: fmt.Sprintf("%s:%s:%d:%d", outbox.DestinationWebhook, event, lead.ID, lead.Attempts),
+		Destination:    outbox.DestinationWebhook,
+		TargetURL:      campaign.WebhookURL,
+		EventType:      event,
+		Payload: models.LeadEvent{
+			Event:         event,
+			CampaignID:    campaign.ID,
+			LeadID:        lead.ID,
+			Phone:         lead.Phone,
+			Name:          lead.Name,
+			Status:        lead.Status,
+			Attempts:      lead.Attempts,
+			NextAttemptAt: lead.NextAttemptAt,
+			CallUUID:      lead.LastCallUUID,
+			Data:          lead.Data,
+			OccurredAt:    time.Now(),
+		},
+	})
+}
+
+// leadEvent 线索状态对应的Webhook事件类型，已拨打过又重新排队的线索为安排重拨
+func leadEvent(lead *models.Lead) string {
+	switch lead.Status {
+	case models.LeadStatusQueued:
+		if lead.Attempts > 0 {
+			return models.LeadEventRetryScheduled
+		}
+		return models.LeadEventQueued
+	case models.LeadStatusPreview:
+		return models.LeadEventPreview
+	case models.LeadStatusDialing:
+		return models.LeadEventDialing
+	case models.LeadStatusCompleted:
+		return models.LeadEventAnswered
+	case models.LeadStatusFailed:
+		return models.LeadEventExhausted
+	case models.LeadStatusUnreachable:
+		return models.LeadEventUnreachable
+	}
+	return ""
+}
+
+// Get 查询外呼任务及各状态的线索数
+func (m *Manager) Get(ctx context.Context, id int64) (*models.Campaign, error) {
+	campaign, err := m.store.Campaigns.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	campaign.Leads, err = m.store.Leads.CountByStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// List 查询外呼任务，status为空时返回全部
+func (m *Manager) List(ctx context.Context, status string) ([]*models.Campaign, error) {
+	return m.store.Campaigns.List(ctx, status)
+}
+
+// Start 启动或恢复外呼任务
+func (m *Manager) Start(ctx context.Context, id int64) error {
+	return m.transition(ctx, id, models.CampaignStatusRunning, models.CampaignStatusDraft, models.CampaignStatusPaused)
+}
+
+// Pause 暂停外呼任务，已发起的呼叫不受影响
+func (m *Manager) Pause(ctx context.Context, id int64) error {
+	return m.transition(ctx, id, models.CampaignStatusPaused, models.CampaignStatusRunning)
+}
+
+// transition 切换任务状态，当前状态不在from中时返回ErrInvalidTransition
+func (m *Manager) transition(ctx context.Context, id int64, to string, from ...string) error {
+	err := m.store.Campaigns.UpdateStatus(ctx, id, to, from...)
+	if !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	// 区分任务不存在与状态不符
+	if _, getErr := m.store.Campaigns.Get(ctx, id); getErr != nil {
+		return getErr
+	}
+	return ErrInvalidTransition
+}
+
+// Run 按调度间隔驱动拨打中的任务，直到ctx取消
+func (m *Manager) Run(ctx context.Context) {
+	if m.dialer == nil {
+		return
+	}
+	if m.config.Extension == "" {
+		log.Println("警告: 未配置campaign.extension，外呼任务不会发起呼叫")
+		return
+	}
+	ticker := time.NewTicker(m.config.TickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := m.Tick(ctx, now); err != nil {
+				log.Printf("外呼任务调度失败: %v", err)
+			}
+		}
+	}
+}
+
+// Tick 执行一次调度：为每个拨打中的任务按拨打速率领取到期线索并发起呼叫
+func (m *Manager) Tick(ctx context.Context, now time.Time) error {
+	if !m.lifecycle.Accepting() {
+		return nil
+	}
+	campaigns, err := m.store.Campaigns.List(ctx, models.CampaignStatusRunning)
+	if err != nil {
+		return err
+	}
+	factor := m.throttle.Factor(now)
+	for _, campaign := range campaigns {
+		if err := m.dialCampaign(ctx, campaign, now, factor); err != nil {
+			log.Printf("外呼任务 %d 调度失败: %v", campaign.ID, err)
+		}
+	}
+	return nil
+}
+
+// dialCampaign 为单个任务领取线索并发起呼叫，factor为自动限速的拨打速率系数
+func (m *Manager) dialCampaign(ctx context.Context, campaign *models.Campaign, now time.Time, factor float64) error {
+	if campaign.DialMode == models.DialModePreview {
+		return m.previewCampaign(ctx, campaign, now, factor)
+	}
+	policy, degraded := m.fallback.Policy(&campaign.ID)
+	if degraded && policy.Action == fallback.ActionPause {
+		return nil
+	}
+
+	limit := m.limit(campaign, now, factor, m.config.BatchSize)
+	if limit == 0 {
+		return nil
+	}
+	leads, err := m.claim(ctx, campaign, now, limit, models.LeadStatusDialing, nil)
+	if err != nil {
+		return err
+	}
+	if len(leads) == 0 {
+		return m.completeIfDone(ctx, campaign.ID)
+	}
+	for _, lead := range leads {
+		m.dial(ctx, campaign, lead, policy, degraded, "")
+	}
+	return nil
+}
+
+// previewCampaign 预览拨号任务：超时未确认的线索重新排队，再按拨打速率领取线索等待坐席确认，不直接发起呼叫
+func (m *Manager) previewCampaign(ctx context.Context, campaign *models.Campaign, now time.Time, factor float64) error {
+	// 超时重新排队的线索不计拨打次数也不推送状态变化，下次领取时重新推送等待确认
+	released, err := m.store.Leads.ReleaseExpiredPreviews(ctx, campaign.ID, now)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		log.Printf("外呼任务 %d 有 %d 条线索超时未确认，已重新排队", campaign.ID, released)
+	}
+
+	counts, err := m.store.Leads.CountByStatus(ctx, campaign.ID)
+	if err != nil {
+		return err
+	}
+	pending := m.config.Preview.MaxPending - counts[models.LeadStatusPreview]
+	if pending <= 0 {
+		return nil
+	}
+	limit := m.limit(campaign, now, factor, min(pending, m.config.BatchSize))
+	if limit == 0 {
+		return nil
+	}
+	expires := now.Add(m.config.Preview.Timeout)
+	leads, err := m.claim(ctx, campaign, now, limit, models.LeadStatusPreview, &expires)
+	if err != nil {
+		return err
+	}
+	if len(leads) == 0 {
+		return m.completeIfDone(ctx, campaign.ID)
+	}
+	log.Printf("外呼任务 %d 新增 %d 条线索等待坐席确认", campaign.ID, len(leads))
+	return nil
+}
+
+// limit 按拨打速率从令牌桶取出本次可领取的线索数，最多为n
+func (m *Manager) limit(campaign *models.Campaign, now time.Time, factor float64, n int) int {
+	limit := m.bucket(campaign.ID).take(now, pacing(campaign.PacingPerMinute, factor))
+	if limit > n {
+		limit = n
+	}
+	return limit
+}
+
+// claim 领取最多limit条到期线索并更新为status，未用完的额度退还令牌桶
+func (m *Manager) claim(ctx context.Context, campaign *models.Campaign, now time.Time, limit int, status string, next *time.Time) ([]*models.Lead, error) {
+	var leads []*models.Lead
+	err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		claimed, err := uow.Leads.ClaimDue(ctx, campaign.ID, now, limit)
+		if err != nil {
+			return err
+		}
+		leads = leads[:0]
+		for _, lead := range claimed {
+			// 被叫当地处于免打扰时段的线索保持待拨打，推迟到允许拨打的时间，不计拨打次数也不推送状态变化
+			if next := m.quietHours.Next(lead.Phone, now); next.After(now) {
+				if err := uow.Leads.UpdateStatus(ctx, lead.ID, models.LeadStatusQueued, &next); err != nil {
+					return err
+				}
+				log.Printf("线索 %d 号码 %s 当地处于免打扰时段，推迟到 %s 拨打", lead.ID, lead.Phone, next.Format(time.RFC3339))
+				continue
+			}
+			if err := m.updateLead(ctx, uow, campaign, lead, status, next); err != nil {
+				return err
+			}
+			leads = append(leads, lead)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.bucket(campaign.ID).refund(limit - len(leads))
+	return leads, nil
+}
+
+// Previews 查询预览拨号任务中等待坐席确认的线索，next_attempt_at为确认截止时间
+func (m *Manager) Previews(ctx context.Context, campaignID int64) ([]*models.Lead, error) {
+	if _, err := m.store.Campaigns.Get(ctx, campaignID); err != nil {
+		return nil, err
+	}
+	return m.store.Leads.ListByStatus(ctx, campaignID, models.LeadStatusPreview)
+}
+
+// Confirm 坐席确认预览线索后发起呼叫，被叫接通后桥接到坐席分机，AI作为助手只推送建议话术；
+// agent为空时使用任务的默认坐席分机。返回更新后的线索，last_call_uuid为本次通话UUID
+func (m *Manager) Confirm(ctx context.Context, campaignID, leadID int64, agent string) (*models.Lead, error) {
+	if _, ok := m.dialer.(assistDialer); !ok {
+		return nil, ErrAssistUnsupported
+	}
+	if !m.lifecycle.Accepting() {
+		return nil, fmt.Errorf("服务正在排空，不再发起呼叫")
+	}
+	var campaign *models.Campaign
+	var lead *models.Lead
+	err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		var err error
+		if campaign, lead, err = m.lockPreview(ctx, uow, campaignID, leadID); err != nil {
+			return err
+		}
+		if agent == "" {
+			agent = campaign.AgentExtension
+		}
+		if agent == "" {
+			return ErrNoAgent
+		}
+		if !agentPattern.MatchString(agent) {
+			return fmt.Errorf("坐席分机格式无效: %s", agent)
+		}
+		return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusDialing, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.dial(ctx, campaign, lead, fallback.Policy{}, false, agent); err != nil {
+		return nil, err
+	}
+	return lead, nil
+}
+
+// Skip 坐席跳过预览线索，线索按重拨间隔重新排队，不计拨打次数
+func (m *Manager) Skip(ctx context.Context, campaignID, leadID int64) (*models.Lead, error) {
+	var lead *models.Lead
+	err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		campaign, locked, err := m.lockPreview(ctx, uow, campaignID, leadID)
+		if err != nil {
+			return err
+		}
+		lead = locked
+		next := time.Now().Add(campaign.RetryInterval())
+		return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusQueued, &next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lead, nil
+}
+
+// lockPreview 在事务中锁定外呼任务中等待坐席确认的线索，线索不属于该任务时返回ErrNotFound
+func (m *Manager) lockPreview(ctx context.Context, uow *repositories.UnitOfWork, campaignID, leadID int64) (*models.Campaign, *models.Lead, error) {
+	campaign, err := uow.Campaigns.Get(ctx, campaignID)
+	if err != nil {
+		return nil, nil, err
+	}
+	lead, err := uow.Leads.GetForUpdate(ctx, leadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lead.CampaignID != campaignID {
+		return nil, nil, repositories.ErrNotFound
+	}
+	if lead.Status != models.LeadStatusPreview {
+		return nil, nil, ErrNotPreviewing
+	}
+	return campaign, lead, nil
+}
+
+// dial 检查号码状态并登记通话后呼叫一条线索，发起失败时按重试策略安排重拨并返回错误
+// 通话和拨打次数在发起呼叫前登记，被叫很快挂断时挂机详单也能找到该通话并更新线索结果
+// agent非空时为坐席确认的预览线索，被叫接通后桥接到该坐席分机
+func (m *Manager) dial(ctx context.Context, campaign *models.Campaign, lead *models.Lead, policy fallback.Policy, degraded bool, agent string) error {
+	if m.reachability != nil {
+		if err := m.reachability.CheckLead(ctx, lead); err != nil {
+			if lead.Status == models.LeadStatusUnreachable {
+				log.Printf("线索 %d 号码 %s 为空号或已停机，跳过拨打", lead.ID, lead.Phone)
+				return fmt.Errorf("号码为空号或已停机: %s", lead.Phone)
+			}
+			log.Printf("警告: 线索 %d 号码状态检查失败，继续拨打: %v", lead.ID, err)
+		}
+	}
+
+	callUUID := models.NewSessionID()
+	startedAt := time.Now()
+	call := &models.Call{
+		CallUUID:   callUUID,
+		CampaignID: &campaign.ID,
+		LeadID:     &lead.ID,
+		Caller:     campaign.CallerID,
+		Callee:     lead.Phone,
+		StartedAt:  &startedAt,
+	}
+	err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		if err := uow.Calls.Create(ctx, call); err != nil {
+			return err
+		}
+		return uow.Leads.RecordAttempt(ctx, lead.ID, callUUID)
+	})
+	if err != nil {
+		// 未登记的通话无法关联挂机结果，不发起呼叫，线索重新排队等待下次调度
+		log.Printf("登记外呼任务通话失败，线索 %d 重新排队: %v", lead.ID, err)
+		if err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+			return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusQueued, nil)
+		}); err != nil {
+			log.Printf("线索重新排队失败: %v", err)
+		}
+		return fmt.Errorf("登记外呼任务通话失败: %v", err)
+	}
+	lead.Attempts++
+	lead.LastCallUUID = callUUID
+
+	if ad, ok := m.dialer.(assistDialer); agent != "" && ok {
+		_, err = ad.InitiateAssistedCall(ctx, callUUID, campaign.CallerID, lead.Phone, agent)
+	} else if fd, ok := m.dialer.(fallbackDialer); degraded && ok {
+		_, err = fd.InitiateFallbackCall(ctx, callUUID, lead.Phone, policy)
+	} else {
+		_, err = m.dialer.InitiateOutboundCall(ctx, callUUID, campaign.CallerID, lead.Phone, m.config.Extension)
+	}
+	if err != nil {
+		log.Printf("外呼任务 %d 呼叫 %s 失败: %v", campaign.ID, lead.Phone, err)
+		status, next := nextStatus(campaign, lead, time.Now())
+		if err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+			if err := uow.Calls.MarkEnded(ctx, callUUID, time.Now(), "ORIGINATE_FAILED"); err != nil {
+				return err
+			}
+			return m.updateLead(ctx, uow, campaign, lead, status, next)
+		}); err != nil {
+			log.Printf("更新线索状态失败: %v", err)
+		}
+		return err
+	}
+
+	if degraded && policy.Action == fallback.ActionApology {
+		next := policy.CallbackAt(startedAt)
+		if err := m.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+			return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusQueued, &next)
+		}); err != nil {
+			log.Printf("安排线索回拨失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// completeIfDone 没有待拨打、等待确认和拨打中的线索时将任务标记为已完成
+func (m *Manager) completeIfDone(ctx context.Context, campaignID int64) error {
+	counts, err := m.store.Leads.CountByStatus(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	if counts[models.LeadStatusQueued] > 0 || counts[models.LeadStatusPreview] > 0 || counts[models.LeadStatusDialing] > 0 {
+		return nil
+	}
+	err = m.store.Campaigns.UpdateStatus(ctx, campaignID, models.CampaignStatusCompleted, models.CampaignStatusRunning)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	if err == nil {
+		log.Printf("外呼任务 %d 已完成", campaignID)
+	}
+	return nil
+}
+
+// RecordResult 根据挂机详单更新线索结果：被叫接通为已完成，未接通时按重试策略重拨或标记失败
+// 在详单事务中调用，非外呼任务的通话直接忽略
+func (m *Manager) RecordResult(ctx context.Context, uow *repositories.UnitOfWork, cdr models.CDR) error {
+	call, err := uow.Calls.Get(ctx, cdr.CallUUID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if call.CampaignID == nil || call.LeadID == nil {
+		return nil
+	}
+
+	lead, err := uow.Leads.Get(ctx, *call.LeadID)
+	if err != nil {
+		return err
+	}
+	// 致歉挂断等已重新排队的线索保持原安排
+	if lead.Status != models.LeadStatusDialing || lead.LastCallUUID != cdr.CallUUID {
+		return nil
+	}
+
+	campaign, err := uow.Campaigns.Get(ctx, *call.CampaignID)
+	if err != nil {
+		return err
+	}
+	if cdr.AnswerTime != nil {
+		return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusCompleted, nil)
+	}
+	status, next := nextStatus(campaign, lead, cdr.EndTime)
+	return m.updateLead(ctx, uow, campaign, lead, status, next)
+}
+
+// MarkUnreachable 在事务中将空号或停机的线索标记为unreachable并推送状态变化，作为号码状态检查的处理函数
+func (m *Manager) MarkUnreachable(ctx context.Context, uow *repositories.UnitOfWork, lead *models.Lead) error {
+	campaign, err := uow.Campaigns.Get(ctx, lead.CampaignID)
+	if err != nil {
+		return err
+	}
+	return m.updateLead(ctx, uow, campaign, lead, models.LeadStatusUnreachable, nil)
+}
+
+// nextStatus 未接通的线索：未达最大拨打次数时按重拨间隔重新排队，否则标记失败
+func nextStatus(campaign *models.Campaign, lead *models.Lead, now time.Time) (string, *time.Time) {
+	if lead.Attempts >= campaign.MaxAttempts {
+		return models.LeadStatusFailed, nil
+	}
+	next := now.Add(campaign.RetryInterval())
+	return models.LeadStatusQueued, &next
+}
+
+// pacing 按限速系数调整任务的每分钟拨打数，至少为1
+func pacing(perMinute int, factor float64) int {
+	if factor >= 1 {
+		return perMinute
+	}
+	return max(1, int(math.Ceil(float64(perMinute)*factor)))
+}
+
+// bucket 获取任务的拨打速率令牌桶
+func (m *Manager) bucket(campaignID int64) *bucket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[campaignID]
+	if !ok {
+		b = &bucket{}
+		m.buckets[campaignID] = b
+	}
+	return b
+}
+
+// bucket 拨打速率令牌桶，按每分钟速率匀速补充，最多积攒一分钟的额度
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// take 按速率补充后取出全部整数令牌
+func (b *bucket) take(now time.Time, perMinute int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	capacity := float64(perMinute)
+	if b.last.IsZero() {
+		b.tokens = 1
+	} else if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Minutes() * capacity
+	}
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	n := int(b.tokens)
+	b.tokens -= float64(n)
+	return n
+}
+
+// refund 退还未用完的令牌
+func (b *bucket) refund(n int) {
+	if n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += float64(n)
+}
@@ -0,0 +1,125 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/models"
+)
+
+// 外呼任务指标，按任务ID区分；数值从数据库统计，多个实例导出相同的值
+var (
+	leadsRemaining = metrics.NewGaugeVec("ai_dialer_campaign_leads_remaining",
+		"外呼任务剩余线索数（待拨打、等待坐席确认和拨打中）", "campaign_id")
+	attempts = metrics.NewGaugeVec("ai_dialer_campaign_attempts",
+		"外呼任务已发起的呼叫数", "campaign_id")
+	connects = metrics.NewGaugeVec("ai_dialer_campaign_connects",
+		"外呼任务被叫接通的呼叫数", "campaign_id")
+	conversions = metrics.NewGaugeVec("ai_dialer_campaign_conversions",
+		"外呼任务通话结果为转化的呼叫数", "campaign_id")
+	abandonRate = metrics.NewGaugeVec("ai_dialer_campaign_abandon_rate",
+		"外呼任务放弃率：接通后很快被挂断的呼叫占接通呼叫的比例", "campaign_id")
+)
+
+// MetricsConfig 外呼任务指标配置：定时统计拨打中和已暂停任务的进度，以Prometheus格式通过 /metrics 导出
+type MetricsConfig struct {
+	Interval               time.Duration `yaml:"interval"`                // 统计间隔
+	AbandonThreshold       time.Duration `yaml:"abandon_threshold"`       // 接通后不足该时长即挂断的呼叫计为放弃
+	ConversionDispositions []string      `yaml:"conversion_dispositions"` // 计为转化的通话结果，为空时不统计转化
+}
+
+// Validate 校验配置
+func (c MetricsConfig) Validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval: 不能为负数")
+	}
+	if c.AbandonThreshold < 0 {
+		return fmt.Errorf("abandon_threshold: 不能为负数")
+	}
+	for i, disposition := range c.ConversionDispositions {
+		if disposition == "" {
+			return fmt.Errorf("conversion_dispositions[%d]: 通话结果不能为空", i)
+		}
+	}
+	return nil
+}
+
+// RunMetrics 按统计间隔刷新任务指标，直到ctx取消
+func (m *Manager) RunMetrics(ctx context.Context) {
+	if err := m.RefreshMetrics(ctx); err != nil {
+		log.Printf("统计外呼任务指标失败: %v", err)
+	}
+	ticker := time.NewTicker(m.config.Metrics.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.RefreshMetrics(ctx); err != nil {
+				log.Printf("统计外呼任务指标失败: %v", err)
+			}
+		}
+	}
+}
+
+// RefreshMetrics 统计拨打中和已暂停任务的线索和通话，更新任务指标；不再处于这两种状态的任务不再导出
+func (m *Manager) RefreshMetrics(ctx context.Context) error {
+	var campaigns []*models.Campaign
+	for _, status := range []string{models.CampaignStatusRunning, models.CampaignStatusPaused} {
+		list, err := m.store.Campaigns.List(ctx, status)
+		if err != nil {
+			return err
+		}
+		campaigns = append(campaigns, list...)
+	}
+
+	active := make(map[int64]struct{}, len(campaigns))
+	for _, campaign := range campaigns {
+		if err := m.refreshCampaign(ctx, campaign.ID); err != nil {
+			log.Printf("统计外呼任务 %d 指标失败: %v", campaign.ID, err)
+		}
+		active[campaign.ID] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id := range m.exported {
+		if _, ok := active[id]; !ok {
+			label := strconv.FormatInt(id, 10)
+			for _, gauge := range []*metrics.GaugeVec{leadsRemaining, attempts, connects, conversions, abandonRate} {
+				gauge.Delete(label)
+			}
+		}
+	}
+	m.exported = active
+	return nil
+}
+
+// refreshCampaign 更新单个任务的指标
+func (m *Manager) refreshCampaign(ctx context.Context, campaignID int64) error {
+	counts, err := m.store.Leads.CountByStatus(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+	stats, err := m.store.Calls.CampaignStats(ctx, campaignID, m.config.Metrics.AbandonThreshold, m.config.Metrics.ConversionDispositions)
+	if err != nil {
+		return err
+	}
+
+	label := strconv.FormatInt(campaignID, 10)
+	leadsRemaining.Set(float64(counts[models.LeadStatusQueued]+counts[models.LeadStatusPreview]+counts[models.LeadStatusDialing]), label)
+	attempts.Set(float64(stats.Attempts), label)
+	connects.Set(float64(stats.Connects), label)
+	conversions.Set(float64(stats.Conversions), label)
+	rate := 0.0
+	if stats.Connects > 0 {
+		rate = float64(stats.Abandoned) / float64(stats.Connects)
+	}
+	abandonRate.Set(rate, label)
+	return nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/outbox"
+	"ai_dialer_mini/internal/services/wrapup"
+)
+
+// CDRService 通话详单服务，详单与对外推送消息在同一事务内写入
+type CDRService struct {
+	store   *repositories.Store
+	outbox  *outbox.Outbox
+	webhook config.WebhookConfig
+	wrapup  *wrapup.Executor
+	leads   LeadResultRecorder
+}
+
+// LeadResultRecorder 挂机后更新外呼线索结果
+type LeadResultRecorder interface {
+	RecordResult(ctx context.Context, uow *repositories.UnitOfWork, cdr models.CDR) error
+}
+
+// NewCDRService 创建通话详单服务
+func NewCDRService(store *repositories.Store, ob *outbox.Outbox, webhook config.WebhookConfig) *CDRService {
+	return &CDRService{
+		store:   store,
+		outbox:  ob,
+		webhook: webhook,
+	}
+}
+
+// SetWrapUp 设置挂机收尾动作执行器，收尾动作与详单在同一事务中写入
+func (s *CDRService) SetWrapUp(executor *wrapup.Executor) {
+	s.wrapup = executor
+}
+
+// SetLeadResults 设置外呼线索结果更新，线索结果与详单在同一事务中写入，先于收尾动作执行
+func (s *CDRService) SetLeadResults(recorder LeadResultRecorder) {
+	s.leads = recorder
+}
+
+// Record 保存通话详单并更新通话记录，同时在同一事务中写入Webhook和CRM推送消息
+func (s *CDRService) Record(ctx context.Context, cdr models.CDR) error {
+	return s.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		if err := uow.CDRs.Insert(ctx, cdr); err != nil {
+			return err
+		}
+
+		// 未登记的通话（如分机直拨）没有通话记录，忽略即可
+		err := uow.Calls.MarkEnded(ctx, cdr.CallUUID, cdr.EndTime, cdr.HangupCause)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+
+		// 详单与推送消息同时提交，进程在通话中途崩溃也不会丢失事件
+		messages := []outbox.Message{
+			{
+				IdempotencyKey: fmt.Sprintf("%s:call.completed:%s", outbox.DestinationWebhook, cdr.CallUUID),
+				Destination:    outbox.DestinationWebhook,
+				TargetURL:      s.webhook.URL,
+				EventType:      "call.completed",
+				Payload:        cdr,
+			},
+			{
+				IdempotencyKey: fmt.Sprintf("%s:call.completed:%s", outbox.DestinationCRM, cdr.CallUUID),
+				Destination:    outbox.DestinationCRM,
+				TargetURL:      s.webhook.CRMURL,
+				EventType:      "call.completed",
+				Payload:        cdr,
+			},
+		}
+		for _, msg := range messages {
+			if err := s.outbox.Enqueue(ctx, uow.Tx(), msg); err != nil {
+				return err
+			}
+		}
+
+		if s.leads != nil {
+			if err := s.leads.RecordResult(ctx, uow, cdr); err != nil {
+				return err
+			}
+		}
+		if s.wrapup != nil {
+			return s.wrapup.Run(ctx, uow, s.outbox, cdr)
+		}
+		return nil
+	})
+}
+
+// RecordACW 话后处理结束后将话后处理时长写入通话详单
+func (s *CDRService) RecordACW(ctx context.Context, callUUID string, acw time.Duration) error {
+	return s.store.CDRs.SetACW(ctx, callUUID, int(acw.Seconds()))
+}
+
+// UpdateDisposition 修改通话记录中的通话结果
+func (s *CDRService) UpdateDisposition(ctx context.Context, callUUID, disposition string) error {
+	return s.store.Calls.SetDisposition(ctx, callUUID, disposition)
+}
+
+// CDRFromHeaders 从FreeSWITCH挂断事件头构建通话详单
+func CDRFromHeaders(headers map[string]string) models.CDR {
+	cdr := models.CDR{
+		CallUUID:    headers["Unique-ID"],
+		Caller:      headers["Caller-Caller-ID-Number"],
+		Callee:      headers["Caller-Destination-Number"],
+		Gateway:     headers["variable_sip_gateway_name"],
+		HangupCause: headers["Hangup-Cause"],
+		StartTime:   eventTime(headers["Caller-Channel-Created-Time"]),
+		EndTime:     eventTime(headers["Caller-Channel-Hangup-Time"]),
+	}
+	if cdr.EndTime.IsZero() {
+		cdr.EndTime = time.Now()
+	}
+
+	if answered := eventTime(headers["Caller-Channel-Answered-Time"]); !answered.IsZero() {
+		cdr.AnswerTime = &answered
+		cdr.BillSec = int(cdr.EndTime.Sub(answered).Seconds())
+	}
+	return cdr
+}
+
+// eventTime 解析FreeSWITCH事件中的微秒时间戳，值为0或无效时返回零值
+func eventTime(value string) time.Time {
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(usec)
+}
@@ -0,0 +1,126 @@
+// Package consent 录音授权：根据客户的按键或识别文本判断客户是否同意通话录音，
+// 客户拒绝时由通话服务停止录音并在挂机后删除录音文件，授权结果写入通话详单
+package consent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Status 录音授权状态
+type Status string
+
+// 录音授权状态
+const (
+	StatusPending Status = ""        // 尚未确认
+	StatusGranted Status = "granted" // 客户同意录音
+	StatusRefused Status = "refused" // 客户拒绝录音
+)
+
+// 授权来源
+const (
+	SourceDTMF   = "dtmf"   // 客户按键
+	SourceSpeech = "speech" // 语音识别的客户回答
+)
+
+// dtmfDigits 合法的按键
+const dtmfDigits = "0123456789*#ABCD"
+
+// Config 录音授权配置
+type Config struct {
+	Enabled       bool     `yaml:"enabled"`
+	GrantDigits   string   `yaml:"grant_digits"`   // 表示同意录音的按键，如 "1"
+	RefuseDigits  string   `yaml:"refuse_digits"`  // 表示拒绝录音的按键，如 "2"
+	GrantPhrases  []string `yaml:"grant_phrases"`  // 表示同意的说法，识别文本包含任一说法时视为同意
+	RefusePhrases []string `yaml:"refuse_phrases"` // 表示拒绝的说法，优先于同意的说法匹配，如“不同意”包含“同意”
+}
+
+// Validate 校验录音授权配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RefuseDigits == "" && len(c.RefusePhrases) == 0 {
+		return fmt.Errorf("refuse_digits: 启用录音授权时至少配置一种拒绝方式")
+	}
+	for name, digits := range map[string]string{"grant_digits": c.GrantDigits, "refuse_digits": c.RefuseDigits} {
+		for _, digit := range digits {
+			if !strings.ContainsRune(dtmfDigits, digit) {
+				return fmt.Errorf("%s: 无效的按键 %q", name, digit)
+			}
+		}
+	}
+	for _, digit := range c.GrantDigits {
+		if strings.ContainsRune(c.RefuseDigits, digit) {
+			return fmt.Errorf("refuse_digits: 按键 %q 不能同时表示同意和拒绝", digit)
+		}
+	}
+	for name, phrases := range map[string][]string{"grant_phrases": c.GrantPhrases, "refuse_phrases": c.RefusePhrases} {
+		for _, phrase := range phrases {
+			if normalize(phrase) == "" {
+				return fmt.Errorf("%s: 说法不能为空", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Detector 根据按键或识别文本判断录音授权，可在nil上调用，此时不做判断
+type Detector struct {
+	config Config
+}
+
+// New 创建录音授权判断，未启用时返回nil
+func New(config Config) *Detector {
+	if !config.Enabled {
+		return nil
+	}
+	return &Detector{config: config}
+}
+
+// FromDigit 按客户按键判断，不是授权按键时返回StatusPending
+func (d *Detector) FromDigit(digit string) Status {
+	if d == nil || len(digit) != 1 {
+		return StatusPending
+	}
+	switch {
+	case strings.Contains(d.config.RefuseDigits, digit):
+		return StatusRefused
+	case strings.Contains(d.config.GrantDigits, digit):
+		return StatusGranted
+	}
+	return StatusPending
+}
+
+// FromText 按识别文本判断，忽略空白和标点；先匹配拒绝的说法，都不匹配时返回StatusPending
+func (d *Detector) FromText(text string) Status {
+	if d == nil {
+		return StatusPending
+	}
+	text = normalize(text)
+	if text == "" {
+		return StatusPending
+	}
+	for _, phrase := range d.config.RefusePhrases {
+		if strings.Contains(text, normalize(phrase)) {
+			return StatusRefused
+		}
+	}
+	for _, phrase := range d.config.GrantPhrases {
+		if strings.Contains(text, normalize(phrase)) {
+			return StatusGranted
+		}
+	}
+	return StatusPending
+}
+
+// normalize 去除空白和标点并转为小写
+func normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
@@ -0,0 +1,257 @@
+// Package cron 进程内的定时任务调度：按cron表达式执行注册的任务，同一任务上次未结束时跳过本次执行，
+// 并记录每个任务最近一次的执行情况供运维接口查询
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound 定时任务不存在
+	ErrJobNotFound = errors.New("定时任务不存在")
+	// ErrJobRunning 定时任务正在执行
+	ErrJobRunning = errors.New("定时任务正在执行")
+)
+
+// Config 定时任务配置
+type Config struct {
+	Timezone string `yaml:"timezone"` // 解析调度表达式使用的时区，如 Asia/Shanghai，为空时使用本地时区
+}
+
+// Validate 校验定时任务配置
+func (c Config) Validate() error {
+	if _, err := c.Location(); err != nil {
+		return fmt.Errorf("timezone: %v", err)
+	}
+	return nil
+}
+
+// Location 调度使用的时区
+func (c Config) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// Job 定时任务，ctx在调度器停止时取消
+type Job func(ctx context.Context) error
+
+// JobStatus 定时任务的执行情况
+type JobStatus struct {
+	Name      string     `json:"name"`                 // 任务名称
+	Spec      string     `json:"spec"`                 // 调度表达式
+	Running   bool       `json:"running"`              // 是否正在执行
+	NextRun   *time.Time `json:"next_run,omitempty"`   // 下一次执行时间
+	LastStart *time.Time `json:"last_start,omitempty"` // 最近一次开始时间
+	LastEnd   *time.Time `json:"last_end,omitempty"`   // 最近一次结束时间
+	LastError string     `json:"last_error,omitempty"` // 最近一次执行的错误，成功时为空
+	Runs      int        `json:"runs"`                 // 执行次数
+	Failures  int        `json:"failures"`             // 失败次数
+	Skipped   int        `json:"skipped"`              // 到期时上次执行尚未结束而跳过的次数
+}
+
+// entry 注册的定时任务
+type entry struct {
+	schedule Schedule
+	job      Job
+	status   JobStatus
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	location *time.Location
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	ctx     context.Context // Run开始后的调度上下文，手动触发的任务同样使用
+	wake    chan struct{}
+	running sync.WaitGroup
+}
+
+// New 创建定时任务调度器，location为nil时使用本地时区
+func New(location *time.Location) *Scheduler {
+	if location == nil {
+		location = time.Local
+	}
+	return &Scheduler{
+		location: location,
+		entries:  make(map[string]*entry),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register 注册定时任务，名称不能重复；可在Run之前或之后调用
+func (s *Scheduler) Register(name, spec string, job Job) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("定时任务 %s 的调度表达式无效: %v", name, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[name]; ok {
+		return fmt.Errorf("定时任务 %s 重复注册", name)
+	}
+	e := &entry{schedule: schedule, job: job, status: JobStatus{Name: name, Spec: spec}}
+	e.setNext(time.Now().In(s.location))
+	s.entries[name] = e
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run 按调度执行到期的任务，直到ctx取消；返回前等待执行中的任务结束
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	defer s.running.Wait()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			s.runDue(ctx, time.Now().In(s.location))
+		case <-s.wake:
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(s.untilNext())
+	}
+}
+
+// untilNext 距离最早到期的任务的时间
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	now := time.Now()
+	for _, e := range s.entries {
+		if e.status.NextRun == nil {
+			continue
+		}
+		if d := e.status.NextRun.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// runDue 执行所有到期的任务，上次执行未结束的任务跳过本次
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, e := range s.entries {
+		if e.status.NextRun == nil || now.Before(*e.status.NextRun) {
+			continue
+		}
+		e.setNext(now)
+		if e.status.Running {
+			e.status.Skipped++
+			log.Printf("定时任务 %s 上次执行尚未结束，跳过本次执行", name)
+			continue
+		}
+		s.start(ctx, e)
+	}
+}
+
+// Trigger 立即执行一次任务，不影响下一次调度时间；需在Run开始后调用
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if e.status.Running {
+		return ErrJobRunning
+	}
+	if s.ctx == nil {
+		return fmt.Errorf("定时任务调度器未启动")
+	}
+	s.start(s.ctx, e)
+	return nil
+}
+
+// start 在后台执行任务，调用方持有锁
+func (s *Scheduler) start(ctx context.Context, e *entry) {
+	started := time.Now()
+	e.status.Running = true
+	e.status.LastStart = &started
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+		err := runJob(ctx, e.job)
+		ended := time.Now()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.status.Running = false
+		e.status.LastEnd = &ended
+		e.status.Runs++
+		e.status.LastError = ""
+		if err != nil {
+			e.status.Failures++
+			e.status.LastError = err.Error()
+			log.Printf("定时任务 %s 执行失败: %v", e.status.Name, err)
+		}
+	}()
+}
+
+// runJob 执行任务，任务panic时作为错误返回，不影响其他任务
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("任务panic: %v", p)
+		}
+	}()
+	return job(ctx)
+}
+
+// setNext 计算下一次执行时间，调用方持有锁
+func (e *entry) setNext(now time.Time) {
+	next := e.schedule.Next(now)
+	if next.IsZero() {
+		e.status.NextRun = nil
+		return
+	}
+	e.status.NextRun = &next
+}
+
+// Jobs 所有定时任务的执行情况，按名称排序
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(s.entries))
+	for _, e := range s.entries {
+		jobs = append(jobs, e.status)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
@@ -0,0 +1,205 @@
+// Package dataset 训练数据导出：将通话转写脱敏后按对话格式写成JSONL，用于微调后续的对话模型
+package dataset
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// 对话消息角色，与OpenAI微调数据格式一致
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Config 训练数据导出配置
+type Config struct {
+	Dir          string `yaml:"dir"`           // 导出文件目录
+	SystemPrompt string `yaml:"system_prompt"` // 每条样本开头的system消息，为空时不添加
+	MaxDialogs   int    `yaml:"max_dialogs"`   // 单次最多导出的通话数
+	MinTurns     int    `yaml:"min_turns"`     // 客户发言少于该轮数的通话不导出
+
+	// 定时导出：按schedule定期导出最近lookback内挂机的通话
+	Schedule     string        `yaml:"schedule"`     // cron表达式，如 "0 3 * * *"，为空时不定时导出
+	Lookback     time.Duration `yaml:"lookback"`     // 每次定时导出覆盖的挂机时间范围
+	Dispositions []string      `yaml:"dispositions"` // 定时导出的通话结果，为空时导出所有结果的通话
+}
+
+// Validate 校验训练数据导出配置
+func (c Config) Validate() error {
+	if c.MaxDialogs < 0 {
+		return fmt.Errorf("max_dialogs不能为负数")
+	}
+	if c.MinTurns < 0 {
+		return fmt.Errorf("min_turns不能为负数")
+	}
+	if c.Schedule != "" && c.Lookback <= 0 {
+		return fmt.Errorf("lookback: 定时导出时必须大于0")
+	}
+	return nil
+}
+
+// Message 对话消息
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Metadata 样本的附加信息，不含可识别客户的内容
+type Metadata struct {
+	Disposition string `json:"disposition"`           // 通话结果
+	CampaignID  *int64 `json:"campaign_id,omitempty"` // 外呼任务
+}
+
+// Sample 一通电话对应的一条训练样本，写成JSONL中的一行
+type Sample struct {
+	Messages []Message `json:"messages"`
+	Metadata Metadata  `json:"metadata"`
+}
+
+// Exporter 训练数据导出
+type Exporter struct {
+	store  *repositories.Store
+	config Config
+}
+
+// New 创建训练数据导出
+func New(store *repositories.Store, config Config) *Exporter {
+	if config.Dir == "" {
+		config.Dir = "data/datasets"
+	}
+	if config.MaxDialogs <= 0 {
+		config.MaxDialogs = 10000
+	}
+	return &Exporter{store: store, config: config}
+}
+
+// Export 按条件导出训练数据到导出目录下的新文件，写完后才以.jsonl文件名出现，避免读到不完整的文件
+func (e *Exporter) Export(ctx context.Context, q models.DatasetQuery) (*models.DatasetExport, error) {
+	if err := os.MkdirAll(e.config.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建导出目录失败: %v", err)
+	}
+	now := time.Now()
+	path := filepath.Join(e.config.Dir, "dialogs-"+now.Format("20060102-150405")+".jsonl")
+	tmp, err := os.CreateTemp(e.config.Dir, ".dialogs-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	dialogs, skipped, err := e.WriteTo(ctx, q, tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("写入导出文件失败: %v", closeErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("保存导出文件失败: %v", err)
+	}
+	return &models.DatasetExport{File: path, Dialogs: dialogs, Skipped: skipped, CreatedAt: now}, nil
+}
+
+// ExportScheduled 定时导出最近lookback内挂机的通话，作为定时任务执行
+func (e *Exporter) ExportScheduled(ctx context.Context) error {
+	to := time.Now()
+	from := to.Add(-e.config.Lookback)
+	export, err := e.Export(ctx, models.DatasetQuery{From: &from, To: &to, Dispositions: e.config.Dispositions})
+	if err != nil {
+		return err
+	}
+	log.Printf("训练数据已定时导出: %s, 对话 %d 条, 跳过 %d 通", export.File, export.Dialogs, export.Skipped)
+	return nil
+}
+
+// WriteTo 按条件将脱敏后的对话逐行写入w，返回导出和跳过的通话数
+func (e *Exporter) WriteTo(ctx context.Context, q models.DatasetQuery, w io.Writer) (dialogs, skipped int, err error) {
+	if q.Limit <= 0 || q.Limit > e.config.MaxDialogs {
+		q.Limit = e.config.MaxDialogs
+	}
+	calls, err := e.store.Calls.ListForDataset(ctx, q)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	for _, call := range calls {
+		if err := ctx.Err(); err != nil {
+			return dialogs, skipped, err
+		}
+		transcripts, err := e.store.Transcripts.ListByCall(ctx, call.CallUUID)
+		if err != nil {
+			return dialogs, skipped, err
+		}
+		sample, ok := e.sample(call, transcripts)
+		if !ok {
+			skipped++
+			continue
+		}
+		if err := enc.Encode(sample); err != nil {
+			return dialogs, skipped, fmt.Errorf("写入导出文件失败: %v", err)
+		}
+		dialogs++
+	}
+	if err := buf.Flush(); err != nil {
+		return dialogs, skipped, fmt.Errorf("写入导出文件失败: %v", err)
+	}
+	return dialogs, skipped, nil
+}
+
+// sample 将一通电话的转写转换为脱敏后的对话样本：客户为user，AI和人工坐席为assistant，
+// 同一方连续的发言合并为一条；客户最后的发言没有回复，不作为样本内容
+func (e *Exporter) sample(call *models.Call, transcripts []*models.Transcript) (*Sample, bool) {
+	var messages []Message
+	for _, t := range transcripts {
+		text := strings.TrimSpace(Scrub(t.Text, call.Caller, call.Callee))
+		if text == "" {
+			continue
+		}
+		role := RoleAssistant
+		if t.Speaker == models.SpeakerCustomer {
+			role = RoleUser
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content += " " + text
+			continue
+		}
+		messages = append(messages, Message{Role: role, Content: text})
+	}
+	for len(messages) > 0 && messages[len(messages)-1].Role == RoleUser {
+		messages = messages[:len(messages)-1]
+	}
+
+	userTurns := 0
+	for _, m := range messages {
+		if m.Role == RoleUser {
+			userTurns++
+		}
+	}
+	// 合并后双方轮流发言，客户发言轮数即对话轮数
+	if userTurns == 0 || userTurns < e.config.MinTurns {
+		return nil, false
+	}
+
+	if e.config.SystemPrompt != "" {
+		messages = append([]Message{{Role: RoleSystem, Content: e.config.SystemPrompt}}, messages...)
+	}
+	return &Sample{
+		Messages: messages,
+		Metadata: Metadata{Disposition: call.Disposition, CampaignID: call.CampaignID},
+	}, true
+}
@@ -0,0 +1,39 @@
+package dataset
+
+import (
+	"regexp"
+	"strings"
+)
+
+// scrubRule 脱敏规则，匹配的内容替换为占位符
+type scrubRule struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// scrubRules 按顺序执行的脱敏规则，范围更具体的规则在前
+var scrubRules = []scrubRule{
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), "[邮箱]"},
+	{regexp.MustCompile(`\b\d{6}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`), "[身份证号]"},
+	{regexp.MustCompile(`(?:\+?86[- ]?)?\b1[3-9]\d[- ]?\d{4}[- ]?\d{4}\b`), "[手机号]"},
+	{regexp.MustCompile(`\b0\d{2,3}-?\d{7,8}\b`), "[电话]"},
+	{regexp.MustCompile(`\b\d{16,19}\b`), "[银行卡号]"},
+	// 其余较长的数字串可能是账号、订单号、验证码，一律隐藏
+	{regexp.MustCompile(`\d{6,}`), "[数字]"},
+	// 识别结果中逐字读出的号码
+	{regexp.MustCompile(`[零〇一二三四五六七八九幺两]{6,}`), "[数字]"},
+}
+
+// Scrub 隐藏文本中的个人信息：邮箱、身份证号、手机和固定电话、银行卡号及其他长数字串；
+// known为该通话已知的敏感值（如主被叫号码），出现时一并隐藏
+func Scrub(text string, known ...string) string {
+	for _, value := range known {
+		if len(value) >= 5 {
+			text = strings.ReplaceAll(text, value, "[电话]")
+		}
+	}
+	for _, rule := range scrubRules {
+		text = rule.pattern.ReplaceAllString(text, rule.placeholder)
+	}
+	return text
+}
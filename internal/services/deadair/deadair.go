@@ -0,0 +1,188 @@
+// Package deadair 静音看门狗：通话中长时间既没有客户语音能量也没有AI语音播放时，判定为僵尸通话，
+// 记录诊断快照后挂机，避免无人说话的通话持续占用语音识别额度
+package deadair
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+)
+
+// DefaultCause 静音超时挂机的默认挂断原因
+const DefaultCause = "MEDIA_TIMEOUT"
+
+// causePattern FreeSWITCH挂断原因格式
+var causePattern = regexp.MustCompile(`^[A-Z_]{1,64}$`)
+
+// Config 静音看门狗配置
+type Config struct {
+	Enabled   bool          `yaml:"enabled"`
+	Timeout   time.Duration `yaml:"timeout"`   // 最长静音时长，超过后挂机
+	Threshold float64       `yaml:"threshold"` // 语音能量阈值（RMS，0~32768），低于该值的音频视为静音
+	Cause     string        `yaml:"cause"`     // 挂机使用的FreeSWITCH挂断原因，写入详单的hangup_cause
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout: 必须大于0")
+	}
+	if c.Threshold < 0 || c.Threshold > 32768 {
+		return fmt.Errorf("threshold: 必须在0到32768之间")
+	}
+	if c.Cause != "" && !causePattern.MatchString(c.Cause) {
+		return fmt.Errorf("cause: 无效的挂断原因 %s，应为大写字母和下划线，如 MEDIA_TIMEOUT", c.Cause)
+	}
+	return nil
+}
+
+// Snapshot 静音超时时的诊断快照
+type Snapshot struct {
+	CallUUID      string     `json:"call_uuid"`
+	SessionID     string     `json:"session_id"`
+	Cause         string     `json:"cause"`
+	SilenceMs     int64      `json:"silence_ms"`     // 静音时长
+	ConnectedMs   int64      `json:"connected_ms"`   // 音频流连接时长
+	AudioBytes    int64      `json:"audio_bytes"`    // 收到的客户音频字节数（16kHz 16位PCM）
+	VoicedChunks  int64      `json:"voiced_chunks"`  // 能量超过阈值的音频分片数
+	LastLevel     float64    `json:"last_level"`     // 最后一个音频分片的能量
+	LastVoice     *time.Time `json:"last_voice"`     // 最后一次检测到客户语音的时间，没有时为空
+	LastPlayback  *time.Time `json:"last_playback"`  // AI语音最后播放结束的时间，没有时为空
+	AudioReceived bool       `json:"audio_received"` // 是否收到过音频，false通常表示音频流未建立或FreeSWITCH未推送音频
+}
+
+// Watchdog 单个连接的静音看门狗，可在nil上调用，此时不做处理
+type Watchdog struct {
+	config    Config
+	callUUID  string
+	sessionID string
+	onTimeout func(Snapshot)
+
+	mu           sync.Mutex
+	timer        *time.Timer
+	closed       bool
+	connectedAt  time.Time
+	activeUntil  time.Time // 最后一次活动（客户语音或AI语音播放结束）的时间
+	lastVoice    time.Time
+	lastPlayback time.Time
+	audioBytes   int64
+	voicedChunks int64
+	lastLevel    float64
+}
+
+// New 创建静音看门狗并开始计时，未启用时返回nil；超时后调用一次onTimeout
+func New(config Config, callUUID, sessionID string, onTimeout func(Snapshot)) *Watchdog {
+	if !config.Enabled {
+		return nil
+	}
+	if config.Cause == "" {
+		config.Cause = DefaultCause
+	}
+	now := time.Now()
+	w := &Watchdog{
+		config:      config,
+		callUUID:    callUUID,
+		sessionID:   sessionID,
+		onTimeout:   onTimeout,
+		connectedAt: now,
+		activeUntil: now,
+	}
+	w.timer = time.AfterFunc(config.Timeout, w.check)
+	return w
+}
+
+// Audio 输入一段客户音频（16位PCM），能量超过阈值时重新计时
+func (w *Watchdog) Audio(data []byte) {
+	if w == nil || len(data) == 0 {
+		return
+	}
+	level := pcm.RMS(data)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.audioBytes += int64(len(data))
+	w.lastLevel = level
+	if level >= w.config.Threshold {
+		now := time.Now()
+		w.voicedChunks++
+		w.lastVoice = now
+		w.extend(now)
+	}
+}
+
+// Playing 记录AI开始播放一段时长为d的语音，播放结束后才开始计算静音
+func (w *Watchdog) Playing(d time.Duration) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	start := time.Now()
+	if w.lastPlayback.After(start) {
+		start = w.lastPlayback
+	}
+	w.lastPlayback = start.Add(d)
+	w.extend(w.lastPlayback)
+}
+
+// Close 停止计时
+func (w *Watchdog) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.timer.Stop()
+}
+
+// extend 延后最后活动时间，需持有mu；计时器在到期时按最新的活动时间重新计算，不必每次重置
+func (w *Watchdog) extend(t time.Time) {
+	if t.After(w.activeUntil) {
+		w.activeUntil = t
+	}
+}
+
+// check 计时器到期时检查静音时长，期间有活动则按最后活动时间重新计时
+func (w *Watchdog) check() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if remaining := w.activeUntil.Add(w.config.Timeout).Sub(now); remaining > 0 {
+		w.timer.Reset(remaining)
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	snapshot := Snapshot{
+		CallUUID:      w.callUUID,
+		SessionID:     w.sessionID,
+		Cause:         w.config.Cause,
+		SilenceMs:     now.Sub(w.activeUntil).Milliseconds(),
+		ConnectedMs:   now.Sub(w.connectedAt).Milliseconds(),
+		AudioBytes:    w.audioBytes,
+		VoicedChunks:  w.voicedChunks,
+		LastLevel:     w.lastLevel,
+		LastVoice:     timePtr(w.lastVoice),
+		LastPlayback:  timePtr(w.lastPlayback),
+		AudioReceived: w.audioBytes > 0,
+	}
+	w.mu.Unlock()
+	w.onTimeout(snapshot)
+}
+
+// timePtr 零值时间返回nil
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -1,126 +1,543 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"ai_dialer_mini/internal/clients/mock"
 	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/clients/openai"
 	"ai_dialer_mini/internal/config"
 	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/services/guardrail"
+	"ai_dialer_mini/internal/services/history"
+	"ai_dialer_mini/internal/services/llmcache"
+	"ai_dialer_mini/internal/services/prompt"
+	"ai_dialer_mini/internal/services/session"
+	"ai_dialer_mini/internal/services/throttle"
+	"ai_dialer_mini/internal/services/tools"
 )
 
-// DialogContext 对话上下文
-type DialogContext struct {
-	SessionID     string
-	History      []models.Message
-	LastActivity time.Time
-	mu           sync.RWMutex
+// sessionLock 会话锁，同一会话的请求在本实例内串行执行，无人持有时释放
+type sessionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// LLMClient 大模型客户端接口，由ollama.Client、ollama.Breaker、openai.Client和mock.LLMClient实现
+type LLMClient interface {
+	GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error)
+	GenerateStream(prompt string, options ollama.Options, callback func(*ollama.GenerateResponse) error) error
+	SetRecorder(hook recorder.Hook)
 }
 
 // DialogService 处理对话服务
 type DialogService struct {
-	ollamaClient *ollama.Client
-	sessions     map[string]*DialogContext
-	mu           sync.RWMutex
+	llmClient LLMClient
+	store     session.Store
+	locks     map[string]*sessionLock
+	mu        sync.Mutex
+
+	options   models.GenerationOptions              // 默认生成参数
+	campaigns map[string]models.GenerationOverrides // 按活动覆盖的生成参数
+	prompts   *prompt.Builder                       // 提示词模板
+	observer  LatencyObserver                       // 大模型耗时和错误统计，为nil时不统计
+	guardrail *guardrail.Filter                     // 回复过滤，为nil时不过滤
+	cache     *llmcache.Cache                       // 回复缓存，为nil时不缓存
+	history   *history.Window                       // 历史窗口，为nil时不限制提示词长度
+	tools     *tools.Registry                       // 可供大模型调用的工具，为nil时不启用工具调用
+	actions   map[string]tools.Action               // 工具要求执行、尚未被取走的通话动作，按会话ID
 }
 
-// NewDialogService 创建新的对话服务
+// LatencyObserver 记录外部服务调用的耗时和结果
+type LatencyObserver interface {
+	Observe(component string, latency time.Duration, err error)
+}
+
+// NewDialogService 创建新的对话服务，按llm.provider选择大模型后端
 func NewDialogService(cfg *config.Config) *DialogService {
-	ollamaConfig := ollama.Config{
-		Host:  cfg.Ollama.Host,
-		Model: cfg.Ollama.Model,
+	s := NewDialogServiceWithClient(newLLMClient(cfg))
+	s.store = session.NewMemoryStore(cfg.Session.TTL)
+	if cfg.LLM.Options != (models.GenerationOptions{}) {
+		s.options = cfg.LLM.Options
+	}
+	s.campaigns = cfg.LLM.Campaigns
+	if prompts, err := prompt.New(cfg.LLM.Prompt); err != nil {
+		log.Printf("警告: 提示词模板配置无效，使用默认模板: %v", err)
+	} else {
+		s.prompts = prompts
 	}
+	if filter, err := guardrail.New(cfg.LLM.Guardrail); err != nil {
+		log.Printf("警告: 回复过滤配置无效，不过滤大模型回复: %v", err)
+	} else {
+		s.guardrail = filter
+	}
+	s.history = history.New(cfg.LLM.History, s.llmClient)
+	if registry, err := tools.New(cfg.LLM.Tools); err != nil {
+		log.Printf("警告: 工具调用配置无效，不启用工具调用: %v", err)
+	} else if registry != nil {
+		s.tools = registry
+		log.Printf("工具调用已启用，可用工具: %s", strings.Join(registry.Names(), ", "))
+	}
+	return s
+}
+
+// NewDialogServiceWithClient 使用指定的大模型客户端创建对话服务
+func NewDialogServiceWithClient(client LLMClient) *DialogService {
+	// 空配置只使用内置的默认模板，不会解析失败
+	prompts, _ := prompt.New(prompt.Config{})
 	return &DialogService{
-		ollamaClient: ollama.NewClient(ollamaConfig),
-		sessions:     make(map[string]*DialogContext),
+		llmClient: client,
+		store:     session.NewMemoryStore(0),
+		locks:     make(map[string]*sessionLock),
+		actions:   make(map[string]tools.Action),
+		options:   models.DefaultGenerationOptions,
+		prompts:   prompts,
+	}
+}
+
+// newLLMClient 根据配置创建大模型客户端，模拟后端配置无效时回退到Ollama
+func newLLMClient(cfg *config.Config) LLMClient {
+	if cfg.LLM.Provider == config.ProviderOpenAI {
+		log.Printf("对话服务使用OpenAI兼容接口: %s", cfg.LLM.OpenAI.BaseURL)
+		return openai.NewClient(cfg.LLM.OpenAI)
+	}
+	if cfg.LLM.Provider == config.ProviderMock {
+		client, err := mock.NewLLMClient(cfg.LLM.Mock)
+		if err == nil {
+			log.Println("对话服务使用模拟大模型")
+			return client
+		}
+		log.Printf("警告: 模拟大模型配置无效，改用Ollama: %v", err)
+	}
+
+	client := ollama.NewClient(cfg.LLM.Ollama)
+	if cfg.LLM.Ollama.Breaker.Enabled {
+		return ollama.NewBreaker(client, cfg.LLM.Ollama.Breaker)
+	}
+	return client
+}
+
+// LLMClient 返回对话服务使用的大模型客户端，供质检等离线任务复用
+func (s *DialogService) LLMClient() LLMClient {
+	return s.llmClient
+}
+
+// SetRecorder 设置飞行记录仪，记录发往大模型的原始请求和响应
+func (s *DialogService) SetRecorder(hook recorder.Hook) {
+	s.llmClient.SetRecorder(hook)
+}
+
+// SetSessionStore 设置会话存储，默认使用进程内存储
+func (s *DialogService) SetSessionStore(store session.Store) {
+	s.store = store
+}
+
+// SetLatencyObserver 设置大模型调用统计，用于外呼自动限速
+func (s *DialogService) SetLatencyObserver(observer LatencyObserver) {
+	s.observer = observer
+}
+
+// SetGuardrail 设置回复过滤，filter为nil时不过滤
+func (s *DialogService) SetGuardrail(filter *guardrail.Filter) {
+	s.guardrail = filter
+}
+
+// SetCache 设置大模型回复缓存，提示词、模型和生成参数都相同时直接使用缓存的回复；cache为nil时不缓存
+func (s *DialogService) SetCache(cache *llmcache.Cache) {
+	s.cache = cache
+}
+
+// SetHistoryWindow 设置对话历史窗口，提示词超出token预算时摘要或丢弃较早的历史；window为nil时不限制
+func (s *DialogService) SetHistoryWindow(window *history.Window) {
+	s.history = window
+}
+
+// observe 记录一次大模型调用
+func (s *DialogService) observe(start time.Time, err error) {
+	if s.observer != nil {
+		s.observer.Observe(throttle.ComponentLLM, time.Since(start), err)
 	}
 }
 
-// getOrCreateSession 获取或创建会话
-func (s *DialogService) getOrCreateSession(sessionID string) *DialogContext {
+// lock 锁定会话，返回解锁函数
+func (s *DialogService) lock(sessionID string) func() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	l, ok := s.locks[sessionID]
+	if !ok {
+		l = &sessionLock{}
+		s.locks[sessionID] = l
+	}
+	l.refs++
+	s.mu.Unlock()
 
-	if ctx, exists := s.sessions[sessionID]; exists {
-		ctx.LastActivity = time.Now()
-		return ctx
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		s.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(s.locks, sessionID)
+		}
+		s.mu.Unlock()
 	}
+}
+
+// update 锁定并读取会话，调用fn修改后写回
+func (s *DialogService) update(sessionID string, fn func(sess *session.Session) error) error {
+	unlock := s.lock(sessionID)
+	defer unlock()
 
-	ctx := &DialogContext{
-		SessionID:    sessionID,
-		History:     make([]models.Message, 0),
-		LastActivity: time.Now(),
+	ctx := context.Background()
+	sess, err := s.store.Load(ctx, sessionID)
+	if err != nil {
+		return err
 	}
-	s.sessions[sessionID] = ctx
-	return ctx
+	fnErr := fn(sess)
+	if err := s.store.Save(ctx, sessionID, sess); err != nil {
+		return err
+	}
+	return fnErr
 }
 
 // ProcessMessage 处理用户消息
 func (s *DialogService) ProcessMessage(sessionID string, text string) (string, error) {
-	ctx := s.getOrCreateSession(sessionID)
-	ctx.mu.Lock()
-	defer ctx.mu.Unlock()
+	return s.turn(sessionID, text, func(prompt string, options ollama.Options) (string, error) {
+		ctx := recorder.WithCall(context.Background(), sessionID)
+		// 缓存未经过滤的回复，修改过滤规则后缓存的回复同样按新规则过滤
+		reply, ok := s.cache.Get(ctx, prompt, options)
+		if !ok {
+			start := time.Now()
+			response, err := s.llmClient.GenerateContext(ctx, prompt, options)
+			s.observe(start, err)
+			if err != nil {
+				return "", err
+			}
+			if !response.Fallback {
+				s.cache.Set(ctx, prompt, options, response.Response)
+			}
+			reply = response.Response
+		}
+		// 工具调用不经过回复过滤，由turn执行
+		if _, ok := s.tools.Parse(reply); ok {
+			return reply, nil
+		}
+		return s.guardrail.Apply(reply), nil
+	})
+}
 
-	// 添加用户消息到历史记录
-	userMsg := models.Message{
-		Role:    "user",
-		Content: text,
-	}
-	ctx.History = append(ctx.History, userMsg)
+// ProcessMessageStream 以流式方式处理用户消息，大模型每生成一个完整句子就回调onSentence，
+// 调用方可在后续内容生成的同时开始合成和播放第一句，返回完整回复
+func (s *DialogService) ProcessMessageStream(sessionID string, text string, onSentence func(sentence string) error) (string, error) {
+	return s.ProcessMessageTokens(sessionID, text, nil, onSentence)
+}
 
-	// 构建提示词
-	prompt := s.buildPromptFromHistory(ctx.History)
+// ProcessMessageTokens 以流式方式处理用户消息，大模型每生成一个文本片段就回调onToken，每凑成一个完整句子回调onSentence，
+// 客户端可边生成边显示回复文本，语音合成可逐句进行；回调为nil时不调用，返回完整回复
+// 配置了回复过滤时按句过滤后再回调，onToken收到的是过滤后的整句，未经过滤的片段不会发出；
+// 启用工具调用时，以“{”或“`”开头的回复可能是工具调用，生成完成前不回调
+func (s *DialogService) ProcessMessageTokens(sessionID string, text string, onToken, onSentence func(text string) error) (string, error) {
+	emit := func(sentence string) error {
+		if onSentence == nil {
+			return nil
+		}
+		return onSentence(sentence)
+	}
+	return s.turn(sessionID, text, func(prompt string, options ollama.Options) (string, error) {
+		var splitter SentenceSplitter
+		var reply strings.Builder
+		var callbackErr error
+		var fallback bool      // 是否收到熔断期间的兜底回复
+		held := s.tools != nil // 回复开头可能是工具调用，暂不输出
+		guard := s.guardrail.Stream()
+		// output 输出一句过滤后的回复
+		output := func(sentence string) error {
+			if s.guardrail != nil {
+				if sentence = guard.Write(sentence); sentence == "" {
+					return nil
+				}
+				if onToken != nil {
+					if err := onToken(sentence); err != nil {
+						return err
+					}
+				}
+			}
+			return emit(sentence)
+		}
+		// write 输出一段回复文本
+		write := func(text string) error {
+			if onToken != nil && s.guardrail == nil {
+				if callbackErr = onToken(text); callbackErr != nil {
+					return callbackErr
+				}
+			}
+			for _, sentence := range splitter.Write(text) {
+				if callbackErr = output(sentence); callbackErr != nil {
+					return callbackErr
+				}
+			}
+			return nil
+		}
+		// chunk 处理大模型生成的一个文本片段
+		chunk := func(response *ollama.GenerateResponse) error {
+			if response.Response == "" {
+				return nil
+			}
+			fallback = fallback || response.Fallback
+			reply.WriteString(response.Response)
+			if held {
+				if s.tools.MaybeCall(reply.String()) {
+					return nil
+				}
+				held = false
+				return write(reply.String())
+			}
+			return write(response.Response)
+		}
+		ctx := context.Background()
+		var err error
+		if cached, ok := s.cache.Get(ctx, prompt, options); ok {
+			// 命中缓存时整条回复作为一个片段输出
+			err = chunk(&ollama.GenerateResponse{Response: cached, Done: true})
+		} else {
+			start := time.Now()
+			err = s.llmClient.GenerateStream(prompt, options, chunk)
+			// 调用方中止（如客户打断）不计为大模型错误
+			if callbackErr == nil {
+				s.observe(start, err)
+			}
+			if err == nil && !fallback {
+				s.cache.Set(ctx, prompt, options, reply.String())
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		if held {
+			// 工具调用不输出，由turn执行；不是工具调用时输出暂缓的回复
+			if _, ok := s.tools.Parse(reply.String()); ok {
+				return reply.String(), nil
+			}
+			if err := write(reply.String()); err != nil {
+				return "", err
+			}
+		}
+		if rest := splitter.Flush(); rest != "" {
+			if err := output(rest); err != nil {
+				return "", err
+			}
+		}
+		if s.guardrail == nil {
+			return reply.String(), nil
+		}
+		if rest := guard.Close(); rest != "" {
+			if onToken != nil {
+				if err := onToken(rest); err != nil {
+					return "", err
+				}
+			}
+			if err := emit(rest); err != nil {
+				return "", err
+			}
+		}
+		return guard.Reply(), nil
+	})
+}
 
-	// 调用Ollama生成回复
-	options := ollama.Options{
-		Temperature: 0.7,
-		MaxTokens:   2048,
+// turn 执行一轮对话：记录用户消息，按会话参数调用generate生成回复，并记录到历史
+// 生成失败时用户消息仍保留在历史中，本轮工具要求的通话动作被丢弃
+func (s *DialogService) turn(sessionID, text string, generate func(prompt string, options ollama.Options) (string, error)) (string, error) {
+	if sessionID == "" {
+		return "", models.ErrSessionIDRequired
 	}
-	response, err := s.ollamaClient.Generate(prompt, options)
+
+	var reply string
+	err := s.update(sessionID, func(sess *session.Session) error {
+		// 添加用户消息到历史记录
+		userMsg := models.Message{
+			Role:    "user",
+			Content: text,
+		}
+		sess.History = append(sess.History, userMsg)
+
+		// 提示词中人设、业务知识等固定部分占用的token数
+		reserved := 0
+		if s.history != nil {
+			base, err := s.prompts.Build(sess.Prompt, sess.CampaignID, nil)
+			if err != nil {
+				return err
+			}
+			reserved = history.EstimateTokens(s.tools.Prompt() + base)
+		}
+
+		options := s.resolveOptions(sess.CampaignID, sess.Overrides)
+		// 大模型调用工具时执行工具并把结果写入历史，再次生成，直到得到给客户的回复
+		for round := 0; ; round++ {
+			// 超出token预算时摘要或丢弃较早的历史，处理结果写回会话，历史不随通话轮数无限增长；
+			// 每次生成前都检查，工具结果写入历史后同样不超出预算
+			if s.history != nil {
+				sess.History = s.history.Fit(recorder.WithCall(context.Background(), sessionID), sess.History, reserved)
+			}
+
+			// 按会话或活动选用的模板构建提示词，启用工具调用时在开头写入工具说明
+			text, err := s.prompts.Build(sess.Prompt, sess.CampaignID, sess.History)
+			if err != nil {
+				return err
+			}
+
+			// 调用大模型生成回复
+			reply, err = generate(s.tools.Prompt()+text, ollama.Options{
+				Temperature: options.Temperature,
+				TopP:        options.TopP,
+				TopK:        options.TopK,
+				MaxTokens:   options.MaxTokens,
+				Model:       sess.Model,
+			})
+			if err != nil {
+				return err
+			}
+			call, ok := s.tools.Parse(reply)
+			if !ok {
+				break
+			}
+			if round >= s.tools.MaxRounds() {
+				return fmt.Errorf("大模型连续调用工具超过%d次", s.tools.MaxRounds())
+			}
+			s.callTool(sessionID, sess, call, reply)
+		}
+
+		// 添加助手回复到历史记录，同时记录实际使用的参数以便复现
+		assistantMsg := models.Message{
+			Role:    "assistant",
+			Content: reply,
+			Options: &options,
+		}
+		sess.History = append(sess.History, assistantMsg)
+		return nil
+	})
 	if err != nil {
+		// 本轮没有回复可播放，丢弃工具要求的通话动作，避免下一轮误执行
+		s.mu.Lock()
+		delete(s.actions, sessionID)
+		s.mu.Unlock()
 		return "", err
 	}
+	return reply, nil
+}
 
-	// 添加助手回复到历史记录
-	assistantMsg := models.Message{
-		Role:    "assistant",
-		Content: response.Response,
+// resolveOptions 按 全局配置 -> 活动配置 -> 会话覆盖项 的顺序合并生成参数
+func (s *DialogService) resolveOptions(campaignID string, overrides models.GenerationOverrides) models.GenerationOptions {
+	options := s.options
+	if campaignID != "" {
+		options = s.campaigns[campaignID].Apply(options)
 	}
-	ctx.History = append(ctx.History, assistantMsg)
+	return overrides.Apply(options)
+}
 
-	return response.Response, nil
+// GetSessionOptions 获取会话的生成参数设置
+func (s *DialogService) GetSessionOptions(sessionID string) (*models.SessionOptions, error) {
+	if sessionID == "" {
+		return nil, models.ErrSessionIDRequired
+	}
+	sess, err := s.store.Load(context.Background(), sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionOptions{
+		CampaignID: sess.CampaignID,
+		Prompt:     sess.Prompt,
+		Overrides:  sess.Overrides,
+		Model:      sess.Model,
+		Effective:  s.resolveOptions(sess.CampaignID, sess.Overrides),
+	}, nil
 }
 
-// buildPromptFromHistory 从历史记录构建提示词
-func (s *DialogService) buildPromptFromHistory(history []models.Message) string {
-	var prompt string
-	for _, msg := range history {
-		switch msg.Role {
-		case "user":
-			prompt += "用户: " + msg.Content + "\n"
-		case "assistant":
-			prompt += "助手: " + msg.Content + "\n"
-		}
+// SetSessionOptions 设置会话所属活动、提示词配置和生成参数覆盖项，
+// 合并后的参数超出安全范围或提示词配置未定义时不做修改
+func (s *DialogService) SetSessionOptions(sessionID, campaignID, profile string, overrides models.GenerationOverrides) (*models.SessionOptions, error) {
+	if sessionID == "" {
+		return nil, models.ErrSessionIDRequired
+	}
+
+	effective := s.resolveOptions(campaignID, overrides)
+	if err := effective.Validate(); err != nil {
+		return nil, err
+	}
+	if profile != "" && !s.prompts.Has(profile) {
+		return nil, fmt.Errorf("%w: %s", prompt.ErrUnknownProfile, profile)
+	}
+
+	err := s.update(sessionID, func(sess *session.Session) error {
+		sess.CampaignID = campaignID
+		sess.Prompt = profile
+		sess.Overrides = overrides
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return prompt
+	return &models.SessionOptions{
+		CampaignID: campaignID,
+		Prompt:     profile,
+		Overrides:  overrides,
+		Effective:  effective,
+	}, nil
 }
 
-// GetHistory 获取对话历史
-func (s *DialogService) GetHistory(sessionID string) []models.Message {
-	ctx := s.getOrCreateSession(sessionID)
-	ctx.mu.RLock()
-	defer ctx.mu.RUnlock()
+// SetSessionModel 设置会话使用的大模型，为空时恢复使用全局配置的模型
+func (s *DialogService) SetSessionModel(sessionID, model string) error {
+	if sessionID == "" {
+		return models.ErrSessionIDRequired
+	}
+	return s.update(sessionID, func(sess *session.Session) error {
+		sess.Model = model
+		return nil
+	})
+}
 
-	history := make([]models.Message, len(ctx.History))
-	copy(history, ctx.History)
-	return history
+// AppendMessage 向会话历史追加一条消息，不调用大模型；
+// 用于记录坐席要求AI说出的话术（assistant）或通话中补充的背景信息（system），之后的对话轮次可以看到
+func (s *DialogService) AppendMessage(sessionID string, msg models.Message) error {
+	if sessionID == "" {
+		return models.ErrSessionIDRequired
+	}
+	return s.update(sessionID, func(sess *session.Session) error {
+		sess.History = append(sess.History, msg)
+		return nil
+	})
 }
 
-// ClearHistory 清除对话历史
-func (s *DialogService) ClearHistory(sessionID string) {
-	ctx := s.getOrCreateSession(sessionID)
-	ctx.mu.Lock()
-	defer ctx.mu.Unlock()
+// GetHistory 获取对话历史，读取会话失败时返回nil
+func (s *DialogService) GetHistory(sessionID string) []models.Message {
+	if sessionID == "" {
+		return nil
+	}
+	sess, err := s.store.Load(context.Background(), sessionID)
+	if err != nil {
+		log.Printf("读取会话 %s 失败: %v", sessionID, err)
+		return nil
+	}
+	return sess.History
+}
 
-	ctx.History = make([]models.Message, 0)
+// ClearHistory 清除对话历史，保留会话的生成参数设置
+func (s *DialogService) ClearHistory(sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	err := s.update(sessionID, func(sess *session.Session) error {
+		sess.History = nil
+		return nil
+	})
+	if err != nil {
+		log.Printf("清除会话 %s 历史失败: %v", sessionID, err)
+	}
+	s.mu.Lock()
+	delete(s.actions, sessionID)
+	s.mu.Unlock()
 }
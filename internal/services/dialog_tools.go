@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/services/session"
+	"ai_dialer_mini/internal/services/tools"
+)
+
+// SetTools 设置可供大模型调用的工具，registry为nil时不启用工具调用
+func (s *DialogService) SetTools(registry *tools.Registry) {
+	s.tools = registry
+}
+
+// BindCall 将对话会话关联到通话，工具调用可据此转接通话或把通话UUID推送给业务系统
+func (s *DialogService) BindCall(sessionID, callUUID string) error {
+	if sessionID == "" {
+		return models.ErrSessionIDRequired
+	}
+	return s.update(sessionID, func(sess *session.Session) error {
+		sess.CallUUID = callUUID
+		return nil
+	})
+}
+
+// TakeToolAction 取出本轮对话中工具要求执行的通话动作（如转人工），调用方应在AI回复播放完后执行；没有时返回false
+func (s *DialogService) TakeToolAction(sessionID string) (tools.Action, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	action, ok := s.actions[sessionID]
+	delete(s.actions, sessionID)
+	return action, ok
+}
+
+// callTool 执行大模型发起的工具调用，调用和结果都写入会话历史；执行失败时把错误作为结果，由大模型向客户说明
+func (s *DialogService) callTool(sessionID string, sess *session.Session, call tools.Call, reply string) {
+	sess.History = append(sess.History, models.Message{Role: models.RoleAssistant, Content: strings.TrimSpace(reply)})
+
+	ctx := recorder.WithCall(context.Background(), sessionID)
+	result, err := s.tools.Execute(ctx, tools.Invocation{
+		Tool:       call.Tool,
+		SessionID:  sessionID,
+		CallUUID:   sess.CallUUID,
+		CampaignID: sess.CampaignID,
+		Arguments:  call.Arguments,
+	})
+	content := result.Content
+	if err != nil {
+		log.Printf("执行工具 %s 失败 - 会话: %s: %v", call.Tool, sessionID, err)
+		content = "调用失败: " + err.Error()
+	} else {
+		log.Printf("已执行工具 %s - 会话: %s", call.Tool, sessionID)
+		if result.Action != nil {
+			s.mu.Lock()
+			s.actions[sessionID] = *result.Action
+			s.mu.Unlock()
+		}
+	}
+	sess.History = append(sess.History, models.Message{Role: models.RoleTool, Content: call.Tool + ": " + content})
+}
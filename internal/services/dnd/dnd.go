@@ -0,0 +1,246 @@
+// Package dnd 外呼免打扰时段：按被叫号码前缀（国家码、国内长途区号、手机号段）推断被叫所在时区，
+// 被叫当地时间不在允许拨打的时段内时推迟拨打，避免服务器时区与被叫时区不同时深夜打扰客户
+package dnd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // 系统未安装时区数据时使用内置数据
+)
+
+// Config 免打扰时段配置
+type Config struct {
+	Enabled            bool                `yaml:"enabled"`
+	Start              string              `yaml:"start"`                // 被叫当地允许拨打的开始时间，如"09:00"
+	End                string              `yaml:"end"`                  // 被叫当地允许拨打的结束时间（不含），如"21:00"
+	DefaultCountryCode string              `yaml:"default_country_code"` // 不带国家码的号码所属国家码，如"86"
+	DefaultTimezone    string              `yaml:"default_timezone"`     // 无法按前缀识别时使用的时区，留空使用服务器时区
+	Prefixes           map[string][]string `yaml:"prefixes"`             // 号码前缀（含国家码，不带+）对应的时区，优先于内置表
+}
+
+// Validate 校验免打扰时段配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := New(c)
+	return err
+}
+
+// builtin 内置的号码前缀与时区，前缀含国家码；跨多个时区的国家列出主要时区，
+// 被叫当地时间须在所有时区都处于允许时段内才拨打
+var builtin = map[string][]string{
+	"1":   {"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles"},
+	"7":   {"Europe/Moscow", "Asia/Yekaterinburg", "Asia/Novosibirsk", "Asia/Vladivostok"},
+	"33":  {"Europe/Paris"},
+	"34":  {"Europe/Madrid"},
+	"39":  {"Europe/Rome"},
+	"44":  {"Europe/London"},
+	"49":  {"Europe/Berlin"},
+	"55":  {"America/Sao_Paulo"},
+	"60":  {"Asia/Kuala_Lumpur"},
+	"61":  {"Australia/Perth", "Australia/Adelaide", "Australia/Sydney"},
+	"62":  {"Asia/Jakarta", "Asia/Makassar", "Asia/Jayapura"},
+	"63":  {"Asia/Manila"},
+	"64":  {"Pacific/Auckland"},
+	"65":  {"Asia/Singapore"},
+	"66":  {"Asia/Bangkok"},
+	"81":  {"Asia/Tokyo"},
+	"82":  {"Asia/Seoul"},
+	"84":  {"Asia/Ho_Chi_Minh"},
+	"86":  {"Asia/Shanghai"},
+	"91":  {"Asia/Kolkata"},
+	"852": {"Asia/Hong_Kong"},
+	"853": {"Asia/Macau"},
+	"886": {"Asia/Taipei"},
+	"971": {"Asia/Dubai"},
+}
+
+// xinjiangAreaCodes 新疆固话长途区号（不含0），当地同时使用北京时间和新疆时间，两者都须处于允许时段
+// 手机号段按省份划分，需要时通过prefixes配置，如 "861399910": ["Asia/Shanghai", "Asia/Urumqi"]
+var xinjiangAreaCodes = []string{
+	"990", "991", "992", "993", "994", "995", "996", "997", "998", "999",
+	"901", "902", "903", "906", "908", "909",
+}
+
+func init() {
+	for _, code := range xinjiangAreaCodes {
+		builtin["86"+code] = []string{"Asia/Shanghai", "Asia/Urumqi"}
+	}
+}
+
+// Checker 按被叫号码判断当前是否允许拨打
+type Checker struct {
+	start       int // 当地允许拨打的开始时间，自零点起的分钟数
+	end         int
+	countryCode string
+	fallback    []*time.Location
+	prefixes    map[string][]*time.Location
+	maxPrefix   int
+}
+
+// New 创建免打扰时段检查，时间格式或时区名称错误时返回错误
+func New(config Config) (*Checker, error) {
+	start, err := parseClock(config.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %v", err)
+	}
+	end, err := parseClock(config.End)
+	if err != nil {
+		return nil, fmt.Errorf("end: %v", err)
+	}
+	if start >= end {
+		return nil, fmt.Errorf("end: 结束时间必须晚于开始时间")
+	}
+	if !isDigits(config.DefaultCountryCode) {
+		return nil, fmt.Errorf("default_country_code: 国家码只能包含数字: %s", config.DefaultCountryCode)
+	}
+
+	c := &Checker{
+		start:       start,
+		end:         end,
+		countryCode: config.DefaultCountryCode,
+		fallback:    []*time.Location{time.Local},
+		prefixes:    make(map[string][]*time.Location, len(builtin)+len(config.Prefixes)),
+	}
+	if config.DefaultTimezone != "" {
+		loc, err := time.LoadLocation(config.DefaultTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("default_timezone: 未知时区: %s", config.DefaultTimezone)
+		}
+		c.fallback = []*time.Location{loc}
+	}
+	for prefix, names := range builtin {
+		if err := c.addPrefix(prefix, names); err != nil {
+			return nil, err
+		}
+	}
+	for prefix, names := range config.Prefixes {
+		if !isDigits(prefix) || len(names) == 0 {
+			return nil, fmt.Errorf("prefixes.%s: 前缀只能包含数字且至少指定一个时区", prefix)
+		}
+		if err := c.addPrefix(prefix, names); err != nil {
+			return nil, fmt.Errorf("prefixes.%s: %v", prefix, err)
+		}
+	}
+	return c, nil
+}
+
+// addPrefix 登记号码前缀对应的时区
+func (c *Checker) addPrefix(prefix string, names []string) error {
+	locs := make([]*time.Location, 0, len(names))
+	for _, name := range names {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return fmt.Errorf("未知时区: %s", name)
+		}
+		locs = append(locs, loc)
+	}
+	c.prefixes[prefix] = locs
+	if len(prefix) > c.maxPrefix {
+		c.maxPrefix = len(prefix)
+	}
+	return nil
+}
+
+// parseClock 解析"HH:MM"格式的时间，返回自零点起的分钟数，允许"24:00"表示当天结束
+func parseClock(s string) (int, error) {
+	h, m, ok := strings.Cut(s, ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("时间格式应为HH:MM: %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// isDigits 字符串非空且只包含数字
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize 将号码转换为带国家码的纯数字形式：+或00开头的号码已含国家码，
+// 其他号码去掉长途字冠0后加上默认国家码
+func (c *Checker) normalize(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(phone), "+"):
+		return digits
+	case strings.HasPrefix(digits, "00"):
+		return digits[2:]
+	}
+	return c.countryCode + strings.TrimPrefix(digits, "0")
+}
+
+// Locations 按最长前缀匹配被叫号码所在的时区，无法识别时返回默认时区
+func (c *Checker) Locations(phone string) []*time.Location {
+	digits := c.normalize(phone)
+	for n := min(len(digits), c.maxPrefix); n > 0; n-- {
+		if locs, ok := c.prefixes[digits[:n]]; ok {
+			return locs
+		}
+	}
+	return c.fallback
+}
+
+// Next 返回被叫当地最早允许拨打的时间，当前允许拨打时返回now；可在nil上调用，此时不限制拨打
+// 号码对应多个时区且允许时段没有交集时按第一个时区计算
+func (c *Checker) Next(phone string, now time.Time) time.Time {
+	if c == nil {
+		return now
+	}
+	locs := c.Locations(phone)
+	if next, ok := c.next(locs, now); ok {
+		return next
+	}
+	next, _ := c.next(locs[:1], now)
+	return next
+}
+
+// next 逐个时区推迟到允许时段，直到所有时区都处于允许时段内
+func (c *Checker) next(locs []*time.Location, now time.Time) (time.Time, bool) {
+	t := now
+	for i := 0; i < 8*len(locs); i++ {
+		moved := false
+		for _, loc := range locs {
+			if start, ok := c.nextStart(t, loc); !ok {
+				t = start
+				moved = true
+			}
+		}
+		if !moved {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nextStart 判断t是否处于loc当地的允许时段，不在时返回之后最近的允许时段开始时间
+func (c *Checker) nextStart(t time.Time, loc *time.Location) (time.Time, bool) {
+	local := t.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	if minutes >= c.start && minutes < c.end {
+		return t, true
+	}
+	day := local.Day()
+	if minutes >= c.end {
+		day++
+	}
+	return time.Date(local.Year(), local.Month(), day, 0, c.start, 0, 0, loc), false
+}
@@ -0,0 +1,208 @@
+// Package dtmf 通话按键菜单：AI回复中提示“确认请按1”等说法时打开配置的菜单，
+// 收集客户按键后作为客户输入交给对话服务，不经过语音识别；超时和按键间隔超时由菜单自行计时
+package dtmf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认超时
+const (
+	defaultTimeout           = 5 * time.Second // 菜单打开后等待第一个按键的时间
+	defaultInterDigitTimeout = 3 * time.Second // 多位按键时等待下一个按键的时间
+)
+
+// digits 合法的按键
+const digits = "0123456789*#ABCD"
+
+// Option 菜单选项
+type Option struct {
+	Input string `yaml:"input"` // 交给对话服务的客户输入，如“确认办理”
+}
+
+// Menu 按键菜单
+type Menu struct {
+	Triggers     []string          `yaml:"triggers"`      // AI回复包含其中任一说法时打开菜单，如“请按1”
+	Options      map[string]Option `yaml:"options"`       // 按键串对应的选项，未匹配的按键以“客户按键X”交给对话服务
+	MaxDigits    int               `yaml:"max_digits"`    // 最多收集的按键数，达到后立即结束，为0时为1
+	Terminator   string            `yaml:"terminator"`    // 提前结束输入的按键，如 #，不计入按键串
+	TimeoutInput string            `yaml:"timeout_input"` // 超时未按键时交给对话服务的输入，为空时只关闭菜单
+}
+
+// Config 按键菜单配置
+type Config struct {
+	Enabled           bool            `yaml:"enabled"`
+	Timeout           time.Duration   `yaml:"dtmf_timeout"`        // 菜单打开后等待第一个按键的时间
+	InterDigitTimeout time.Duration   `yaml:"inter_digit_timeout"` // 已按键但未达到max_digits时等待下一个按键的时间，超时后以已收集的按键结束
+	Menus             map[string]Menu `yaml:"menus"`               // 按名称定义的菜单
+}
+
+// Validate 校验按键菜单配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("dtmf_timeout: 不能为负数")
+	}
+	if c.InterDigitTimeout < 0 {
+		return fmt.Errorf("inter_digit_timeout: 不能为负数")
+	}
+	for name, menu := range c.Menus {
+		if menu.MaxDigits < 0 {
+			return fmt.Errorf("menus.%s.max_digits: 不能为负数", name)
+		}
+		if len(menu.Terminator) > 1 || (menu.Terminator != "" && !strings.Contains(digits, menu.Terminator)) {
+			return fmt.Errorf("menus.%s.terminator: 无效的按键 %q", name, menu.Terminator)
+		}
+		for key := range menu.Options {
+			if key == "" || strings.Trim(key, digits) != "" {
+				return fmt.Errorf("menus.%s.options: 无效的按键 %q", name, key)
+			}
+		}
+		for _, trigger := range menu.Triggers {
+			if strings.TrimSpace(trigger) == "" {
+				return fmt.Errorf("menus.%s.triggers: 说法不能为空", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Match 按AI回复选择要打开的菜单，多个菜单匹配时按名称顺序取第一个
+func (c Config) Match(reply string) (string, bool) {
+	if !c.Enabled || reply == "" {
+		return "", false
+	}
+	names := make([]string, 0, len(c.Menus))
+	for name := range c.Menus {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, trigger := range c.Menus[name].Triggers {
+			if strings.Contains(reply, trigger) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Result 菜单输入结果
+type Result struct {
+	Menu     string `json:"menu"`
+	Digits   string `json:"digits,omitempty"`    // 收集到的按键串，不含结束键
+	Input    string `json:"input,omitempty"`     // 交给对话服务的客户输入，超时且未配置timeout_input时为空
+	TimedOut bool   `json:"timed_out,omitempty"` // 是否超时未按键，已按键后等待下一个按键超时不算
+}
+
+// Collector 一次打开的菜单，按键和超时计时都在其中；done在输入结束时调用一次，调用Close后不再调用
+type Collector struct {
+	name       string
+	menu       Menu
+	interDigit time.Duration
+	done       func(Result)
+
+	mu     sync.Mutex
+	digits strings.Builder
+	timer  *time.Timer
+	closed bool
+}
+
+// Open 打开指定名称的菜单并开始等待第一个按键
+func (c Config) Open(name string, done func(Result)) (*Collector, error) {
+	menu, ok := c.Menus[name]
+	if !ok {
+		return nil, fmt.Errorf("未定义的按键菜单: %s", name)
+	}
+	if menu.MaxDigits <= 0 {
+		menu.MaxDigits = 1
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	collector := &Collector{name: name, menu: menu, interDigit: c.InterDigitTimeout, done: done}
+	if collector.interDigit <= 0 {
+		collector.interDigit = defaultInterDigitTimeout
+	}
+	collector.mu.Lock()
+	collector.timer = time.AfterFunc(timeout, collector.expire)
+	collector.mu.Unlock()
+	return collector, nil
+}
+
+// Name 菜单名称
+func (c *Collector) Name() string {
+	return c.name
+}
+
+// Add 收到一个按键，返回菜单是否仍在收集按键（按键是否被菜单接收）
+func (c *Collector) Add(digit string) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.timer.Stop()
+	if digit != c.menu.Terminator {
+		c.digits.WriteString(digit)
+	}
+	if digit == c.menu.Terminator || c.digits.Len() >= c.menu.MaxDigits {
+		result := c.finish(false)
+		c.mu.Unlock()
+		c.done(result)
+		return true
+	}
+	c.timer = time.AfterFunc(c.interDigit, c.expire)
+	c.mu.Unlock()
+	return true
+}
+
+// expire 等待按键超时：未按键时按超时结束，已有按键时以已收集的按键结束
+func (c *Collector) expire() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	result := c.finish(true)
+	c.mu.Unlock()
+	c.done(result)
+}
+
+// finish 结束收集并生成结果，需持有锁；只按了结束键时与超时一样使用timeout_input
+func (c *Collector) finish(expired bool) Result {
+	c.closed = true
+	result := Result{Menu: c.name, Digits: c.digits.String()}
+	switch {
+	case result.Digits == "":
+		result.TimedOut = expired
+		result.Input = c.menu.TimeoutInput
+	case c.menu.Options[result.Digits].Input != "":
+		result.Input = c.menu.Options[result.Digits].Input
+	default:
+		result.Input = "客户按键" + result.Digits
+	}
+	return result
+}
+
+// Close 关闭菜单，停止计时，不再回调
+func (c *Collector) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	c.timer.Stop()
+}
+
+// Closed 菜单是否已结束
+func (c *Collector) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// EventBridge 通话实时事件桥接，通过Redis发布订阅在多个实例间转发事件
+// 每个实例只把事件发布到Redis，再由订阅方统一推送给本实例的监控连接，避免重复推送
+// 未配置Redis时退化为直接推送给本实例的监控连接
+type EventBridge struct {
+	client   *goredis.Client
+	channel  string
+	hub      *WSService
+	instance string
+}
+
+// NewEventBridge 创建事件桥接，client为nil时仅在本实例内推送
+func NewEventBridge(client *goredis.Client, channel string, hub *WSService) *EventBridge {
+	host, _ := os.Hostname()
+	return &EventBridge{
+		client:   client,
+		channel:  channel,
+		hub:      hub,
+		instance: fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Publish 发布通话实时事件
+func (b *EventBridge) Publish(ctx context.Context, event *models.CallEvent) error {
+	if event.Instance == "" {
+		event.Instance = b.instance
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化通话事件失败: %v", err)
+	}
+
+	if b.client == nil {
+		b.hub.Broadcast(data)
+		return nil
+	}
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return fmt.Errorf("发布通话事件失败: %v", err)
+	}
+	return nil
+}
+
+// Run 订阅Redis频道并推送给本实例的监控连接，直到ctx取消
+// 连接断开后由go-redis自动重新订阅
+func (b *EventBridge) Run(ctx context.Context) {
+	if b.client == nil {
+		return
+	}
+
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		log.Printf("订阅通话事件频道失败: %v", err)
+		return
+	}
+	log.Printf("已订阅通话事件频道: %s", b.channel)
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			b.hub.Broadcast([]byte(msg.Payload))
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package fallback 语音识别不可用时的降级策略：按活动暂停拨号、转人工队列或播放致歉语音后安排回拨
+package fallback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 降级动作
+const (
+	ActionContinue = "continue" // 照常拨打
+	ActionPause    = "pause"    // 暂停拨号
+	ActionHuman    = "human"    // 接通后直接转人工队列
+	ActionApology  = "apology"  // 接通后播放致歉语音并挂断，稍后回拨
+)
+
+// DefaultCallbackDelay 未配置回拨间隔时的默认值
+const DefaultCallbackDelay = time.Hour
+
+// ErrDialingPaused 语音识别不可用，该活动暂停拨号
+var ErrDialingPaused = errors.New("语音识别服务不可用，已暂停拨号")
+
+// Policy 降级策略
+type Policy struct {
+	Action        string        `yaml:"action"`         // 降级动作
+	Queue         string        `yaml:"queue"`          // 人工队列在拨号计划中的分机号，action为human时使用
+	Message       string        `yaml:"message"`        // 致歉语音文件（FreeSWITCH主机上的路径），action为apology时使用
+	CallbackDelay time.Duration `yaml:"callback_delay"` // 致歉后多久回拨线索
+}
+
+// Config 降级配置
+type Config struct {
+	Enabled          bool              `yaml:"enabled"`
+	FailureThreshold int               `yaml:"failure_threshold"` // 连续失败多少次判定为不可用
+	ProbeInterval    time.Duration     `yaml:"probe_interval"`    // 不可用期间探测恢复的间隔
+	Default          Policy            `yaml:"default"`           // 默认策略
+	Campaigns        map[string]Policy `yaml:"campaigns"`         // 按活动ID覆盖默认策略
+}
+
+// Validate 校验降级配置
+func (c Config) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %v", err)
+	}
+	for id, policy := range c.Campaigns {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("campaigns.%s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// CallbackAt 致歉后回拨线索的时间
+func (p Policy) CallbackAt(now time.Time) time.Time {
+	if p.CallbackDelay <= 0 {
+		return now.Add(DefaultCallbackDelay)
+	}
+	return now.Add(p.CallbackDelay)
+}
+
+// LeadScheduler 按时间重新安排线索拨打
+type LeadScheduler interface {
+	UpdateStatus(ctx context.Context, id int64, status string, nextAttemptAt *time.Time) error
+}
+
+// validate 校验单条策略
+func (p Policy) validate() error {
+	switch p.Action {
+	case "", ActionContinue, ActionPause:
+	case ActionHuman:
+		if p.Queue == "" {
+			return fmt.Errorf("转人工时必须配置queue")
+		}
+	case ActionApology:
+		if p.Message == "" {
+			return fmt.Errorf("播放致歉语音时必须配置message")
+		}
+	default:
+		return fmt.Errorf("不支持的降级动作 %s", p.Action)
+	}
+	return nil
+}
+
+// Probe 探测语音识别服务是否恢复
+type Probe func(ctx context.Context) error
+
+// Monitor 跟踪语音识别服务可用性，连续失败达到阈值时判定为不可用，
+// 不可用期间定期探测，探测成功或有识别成功时恢复
+type Monitor struct {
+	config Config
+	probe  Probe
+
+	mu       sync.Mutex
+	failures int
+	down     bool
+	since    time.Time
+}
+
+// NewMonitor 创建可用性监控，probe为nil时只依据识别结果恢复
+func NewMonitor(config Config, probe Probe) *Monitor {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = 30 * time.Second
+	}
+	return &Monitor{config: config, probe: probe}
+}
+
+// ReportSuccess 记录一次识别成功
+func (m *Monitor) ReportSuccess() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = 0
+	if m.down {
+		log.Printf("语音识别服务已恢复，不可用时长: %v", time.Since(m.since).Round(time.Second))
+		m.down = false
+	}
+}
+
+// ReportFailure 记录一次识别失败
+func (m *Monitor) ReportFailure(err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	if !m.down && m.failures >= m.config.FailureThreshold {
+		log.Printf("警告: 语音识别连续失败 %d 次，进入降级模式: %v", m.failures, err)
+		m.down = true
+		m.since = time.Now()
+	}
+}
+
+// Available 语音识别服务是否可用
+func (m *Monitor) Available() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.down
+}
+
+// Policy 获取活动当前应执行的降级策略，服务可用或策略为照常拨打时degraded为false
+func (m *Monitor) Policy(campaignID *int64) (policy Policy, degraded bool) {
+	if m.Available() {
+		return Policy{}, false
+	}
+	policy = m.config.Default
+	if campaignID != nil {
+		if p, ok := m.config.Campaigns[strconv.FormatInt(*campaignID, 10)]; ok {
+			policy = p
+		}
+	}
+	if policy.Action == "" || policy.Action == ActionContinue {
+		return policy, false
+	}
+	return policy, true
+}
+
+// Run 不可用期间定期探测，直到ctx取消
+func (m *Monitor) Run(ctx context.Context) {
+	if m.probe == nil {
+		return
+	}
+	ticker := time.NewTicker(m.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.Available() {
+				continue
+			}
+			if err := m.probe(ctx); err != nil {
+				log.Printf("语音识别服务探测失败: %v", err)
+				continue
+			}
+			m.ReportSuccess()
+		}
+	}
+}
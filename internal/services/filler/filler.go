@@ -0,0 +1,126 @@
+// Package filler 在AI回复生成较慢时播放“嗯”“好的，我帮您查一下”等应答语音，避免通话中出现长时间静默
+package filler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/clients/tts"
+)
+
+// latencyWeight 估算回复耗时时最新一轮的权重
+const latencyWeight = 0.3
+
+// Config 应答语音配置
+type Config struct {
+	Enabled   bool          `yaml:"enabled"`   // 是否启用
+	Phrases   []string      `yaml:"phrases"`   // 应答语，启动时预先合成，轮流播放
+	Threshold time.Duration `yaml:"threshold"` // 回复耗时超过该值时播放应答语音
+}
+
+// Pool 预合成的应答语音池，并根据最近几轮的回复耗时预测本轮是否需要播放
+type Pool struct {
+	clips     []*tts.Audio
+	threshold time.Duration
+
+	mu        sync.Mutex
+	next      int
+	predicted time.Duration // 按指数加权平均估算的回复耗时
+}
+
+// New 预先合成所有应答语
+func New(ctx context.Context, provider tts.Provider, config Config) (*Pool, error) {
+	if len(config.Phrases) == 0 {
+		return nil, fmt.Errorf("未配置应答语")
+	}
+	if config.Threshold <= 0 {
+		return nil, fmt.Errorf("应答语音触发阈值必须大于0")
+	}
+
+	pool := &Pool{threshold: config.Threshold}
+	for _, phrase := range config.Phrases {
+		audio, err := provider.Synthesize(ctx, phrase, tts.Options{})
+		if err != nil {
+			return nil, fmt.Errorf("合成应答语音 %q 失败: %v", phrase, err)
+		}
+		pool.clips = append(pool.clips, audio)
+	}
+	return pool, nil
+}
+
+// Turn 一轮回复的应答语音计时
+type Turn struct {
+	pool  *Pool
+	start time.Time
+	timer *time.Timer
+	once  sync.Once
+}
+
+// Start 开始一轮回复。预测本轮耗时超过阈值时立即触发，否则在耗时达到阈值时触发
+// 可在nil上调用，此时从不触发
+func (p *Pool) Start() *Turn {
+	turn := &Turn{pool: p, start: time.Now()}
+	if p == nil {
+		return turn
+	}
+
+	delay := p.threshold
+	if p.Predicted() >= p.threshold {
+		delay = 0
+	}
+	turn.timer = time.NewTimer(delay)
+	return turn
+}
+
+// C 返回应答语音的触发通道，从不触发时返回nil
+func (t *Turn) C() <-chan time.Time {
+	if t.timer == nil {
+		return nil
+	}
+	return t.timer.C
+}
+
+// Clip 获取本次要播放的应答语音
+func (t *Turn) Clip() *tts.Audio {
+	return t.pool.nextClip()
+}
+
+// Responded 第一段回复语音已就绪，停止计时并记录本轮耗时，重复调用只记录第一次
+func (t *Turn) Responded() {
+	if t.timer == nil {
+		return
+	}
+	t.once.Do(func() {
+		t.timer.Stop()
+		t.pool.observe(time.Since(t.start))
+	})
+}
+
+// Predicted 获取预测的回复耗时
+func (p *Pool) Predicted() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.predicted
+}
+
+// observe 记录一轮的回复耗时
+func (p *Pool) observe(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.predicted == 0 {
+		p.predicted = latency
+		return
+	}
+	p.predicted = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(p.predicted))
+}
+
+// nextClip 轮流取出应答语音
+func (p *Pool) nextClip() *tts.Audio {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clip := p.clips[p.next]
+	p.next = (p.next + 1) % len(p.clips)
+	return clip
+}
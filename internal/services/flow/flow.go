@@ -0,0 +1,284 @@
+// Package flow 通话流程检查：通话流程由提示词配置（AI人设）、呼入路由、意图规则、按键菜单和轮次提示语共同组成，
+// 上线前检查其中不可达的分支、缺失的预录提示音、模板中未定义的变量和无效的跳转，返回结构化的诊断结果
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/prompt"
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/turn"
+)
+
+// 诊断级别
+const (
+	SeverityError   = "error"   // 流程无法按预期执行，不应上线
+	SeverityWarning = "warning" // 流程可以执行，但部分分支不会生效或会降级
+	SeverityInfo    = "info"    // 提示信息
+)
+
+// 诊断类型
+const (
+	CodeInvalid           = "invalid"            // 配置校验失败
+	CodeUnreachable       = "unreachable"        // 分支永远不会被执行
+	CodeMissingPrompt     = "missing_prompt"     // 引用的预录提示音不存在
+	CodeUndefinedVariable = "undefined_variable" // 提示词模板引用了未定义的变量
+	CodeInvalidTransition = "invalid_transition" // 跳转目标无效，如按键菜单中无法输入的按键串
+)
+
+// Scenario 通话流程，各部分与config.yaml中的同名配置一致，可直接复制配置（YAML或JSON）进行检查
+type Scenario struct {
+	Prompt  prompt.Config  `yaml:"prompt"`  // 对应 llm.prompt
+	Inbound inbound.Config `yaml:"inbound"` // 对应 inbound
+	Intent  intent.Config  `yaml:"intent"`  // 对应 intent
+	DTMF    dtmf.Config    `yaml:"dtmf"`    // 对应 dtmf
+	Turn    turn.Config    `yaml:"turn"`    // 对应 turn
+}
+
+// Diagnostic 一条诊断结果
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path"` // 问题所在的配置路径，如 dtmf.menus.confirm.options.12
+	Message  string `json:"message"`
+}
+
+// Report 检查结果，没有error级别的诊断时Valid为true
+type Report struct {
+	Valid       bool         `json:"valid"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// Linter 通话流程检查
+type Linter struct {
+	prompts *promptaudio.Library
+}
+
+// NewLinter 创建通话流程检查，prompts为nil时视为未启用预录提示音
+func NewLinter(prompts *promptaudio.Library) *Linter {
+	return &Linter{prompts: prompts}
+}
+
+// lint 一次检查的诊断结果
+type lint struct {
+	diagnostics []Diagnostic
+}
+
+// add 添加诊断结果
+func (l *lint) add(severity, code, path, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Severity: severity, Code: code, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Lint 检查通话流程，诊断结果按级别和路径排序
+func (lt *Linter) Lint(ctx context.Context, scenario Scenario) Report {
+	l := &lint{}
+	lt.checkSections(l, scenario)
+	lt.checkTemplates(l, scenario.Prompt)
+	lt.checkIntents(l, scenario.Intent)
+	lt.checkMenus(l, scenario)
+	lt.checkPrompts(ctx, l, scenario)
+
+	rank := map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		a, b := l.diagnostics[i], l.diagnostics[j]
+		if rank[a.Severity] != rank[b.Severity] {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		return a.Path < b.Path
+	})
+	report := Report{Valid: true, Diagnostics: l.diagnostics}
+	if report.Diagnostics == nil {
+		report.Diagnostics = []Diagnostic{}
+	}
+	for _, d := range report.Diagnostics {
+		if d.Severity == SeverityError {
+			report.Valid = false
+		}
+	}
+	return report
+}
+
+// checkSections 按服务启动时的规则校验各部分配置，启用的部分才校验
+func (lt *Linter) checkSections(l *lint, scenario Scenario) {
+	if err := scenario.Prompt.Validate(); err != nil {
+		l.add(SeverityError, CodeInvalid, "prompt", "%v", err)
+	}
+	hasPersona := func(name string) bool {
+		_, ok := scenario.Prompt.Profiles[name]
+		return ok
+	}
+	if err := scenario.Inbound.Validate(hasPersona); err != nil {
+		l.add(SeverityError, CodeInvalidTransition, "inbound", "%v", err)
+	}
+	if err := scenario.Intent.Validate(); err != nil {
+		l.add(SeverityError, CodeInvalid, "intent", "%v", err)
+	}
+	if err := scenario.DTMF.Validate(); err != nil {
+		l.add(SeverityError, CodeInvalid, "dtmf", "%v", err)
+	}
+}
+
+// checkTemplates 用示例数据渲染每个提示词模板，模板引用了未定义的字段时渲染失败；模板语法错误已由配置校验报告
+func (lt *Linter) checkTemplates(l *lint, config prompt.Config) {
+	builder, err := prompt.New(config)
+	if err != nil {
+		return
+	}
+	history := []models.Message{
+		{Role: models.RoleAssistant, Content: "您好"},
+		{Role: models.RoleUser, Content: "你好"},
+	}
+	names := []string{""}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	for _, name := range names {
+		if _, err := builder.Build(name, "", history); err != nil {
+			path := "prompt.template"
+			if name != "" {
+				path = "prompt.profiles." + name + ".template"
+			}
+			l.add(SeverityError, CodeUndefinedVariable, path, "%v（可用变量: .Persona .Knowledge .History .Input）", err)
+		}
+	}
+}
+
+// checkIntents 意图规则按顺序匹配，关键词本身就会先命中前面的规则时，该关键词不可达
+func (lt *Linter) checkIntents(l *lint, config intent.Config) {
+	if !config.Enabled {
+		return
+	}
+	for i, rule := range config.Rules {
+		if i == 0 {
+			continue
+		}
+		earlier, err := intent.New(intent.Config{Enabled: true, Rules: config.Rules[:i]})
+		if err != nil {
+			return
+		}
+		for _, keyword := range rule.Keywords {
+			if match, ok := earlier.Match(keyword); ok {
+				l.add(SeverityWarning, CodeUnreachable, fmt.Sprintf("intent.rules[%d].keywords", i),
+					"关键词“%s”总是先命中规则 %s（关键词“%s”）", keyword, match.Rule, match.Keyword)
+			}
+		}
+	}
+}
+
+// checkMenus 检查按键菜单的打开条件和选项能否输入
+func (lt *Linter) checkMenus(l *lint, scenario Scenario) {
+	config := scenario.DTMF
+	if !config.Enabled {
+		return
+	}
+	lines := scriptedLines(scenario)
+	for name, menu := range config.Menus {
+		path := "dtmf.menus." + name
+		if len(menu.Triggers) == 0 {
+			l.add(SeverityWarning, CodeUnreachable, path+".triggers", "菜单没有触发说法，不会被打开")
+		} else if !triggeredBy(menu, lines) {
+			l.add(SeverityInfo, CodeUnreachable, path+".triggers", "固定话术中没有触发说法，菜单只在大模型回复包含触发说法时打开")
+		}
+		maxDigits := menu.MaxDigits
+		if maxDigits <= 0 {
+			maxDigits = 1
+		}
+		for key, option := range menu.Options {
+			optionPath := path + ".options." + key
+			if len(key) > maxDigits {
+				l.add(SeverityError, CodeInvalidTransition, optionPath, "按键串超过max_digits（%d），客户无法输入", maxDigits)
+			} else if menu.Terminator != "" && strings.Contains(key, menu.Terminator) {
+				l.add(SeverityError, CodeInvalidTransition, optionPath, "按键串包含结束键 %s，客户无法输入", menu.Terminator)
+			}
+			if strings.TrimSpace(option.Input) == "" {
+				l.add(SeverityWarning, CodeInvalidTransition, optionPath+".input", "未配置客户输入，按键后以“客户按键%s”交给对话服务", key)
+			}
+		}
+	}
+}
+
+// checkPrompts 检查固定话术中引用的预录提示音是否存在
+func (lt *Linter) checkPrompts(ctx context.Context, l *lint, scenario Scenario) {
+	for _, line := range scriptedLines(scenario) {
+		name, fallback, ok := promptaudio.ParseRef(line.text)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			l.add(SeverityError, CodeMissingPrompt, line.path, "提示音引用缺少名称")
+			continue
+		}
+		var reason string
+		if lt.prompts == nil {
+			reason = "未启用预录提示音"
+		} else if _, err := lt.prompts.Get(ctx, name); errors.Is(err, promptaudio.ErrNotFound) {
+			reason = fmt.Sprintf("提示音 %s 不存在", name)
+		} else if err != nil {
+			l.add(SeverityWarning, CodeMissingPrompt, line.path, "无法检查提示音 %s: %v", name, err)
+			continue
+		} else {
+			continue
+		}
+		if fallback == "" {
+			l.add(SeverityError, CodeMissingPrompt, line.path, "%s，且没有备用文本，话术不会播放", reason)
+		} else {
+			l.add(SeverityWarning, CodeMissingPrompt, line.path, "%s，将合成备用文本", reason)
+		}
+	}
+}
+
+// line 一句固定话术
+type line struct {
+	path string
+	text string
+}
+
+// scriptedLines 流程中的固定话术：呼入问候语、意图回复和轮次提示语
+func scriptedLines(scenario Scenario) []line {
+	var lines []line
+	if scenario.Inbound.Enabled {
+		for number, route := range scenario.Inbound.Numbers {
+			if route.Greeting != "" {
+				lines = append(lines, line{path: "inbound.numbers." + number + ".greeting", text: route.Greeting})
+			}
+		}
+	}
+	if scenario.Intent.Enabled {
+		for i, rule := range scenario.Intent.Rules {
+			if rule.Reply != "" {
+				lines = append(lines, line{path: fmt.Sprintf("intent.rules[%d].reply", i), text: rule.Reply})
+			}
+		}
+	}
+	if scenario.Turn.HoldReply != "" {
+		lines = append(lines, line{path: "turn.hold_reply", text: scenario.Turn.HoldReply})
+	}
+	if scenario.Turn.ResumeReply != "" {
+		lines = append(lines, line{path: "turn.resume_reply", text: scenario.Turn.ResumeReply})
+	}
+	return lines
+}
+
+// triggeredBy 固定话术中是否有打开菜单的说法，引用提示音的话术按备用文本判断
+func triggeredBy(menu dtmf.Menu, lines []line) bool {
+	for _, line := range lines {
+		text := line.text
+		if _, fallback, ok := promptaudio.ParseRef(text); ok {
+			text = fallback
+		}
+		for _, trigger := range menu.Triggers {
+			if trigger != "" && strings.Contains(text, trigger) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package flow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/promptaudio"
+)
+
+// 导出格式
+const (
+	FormatMermaid  = "mermaid"  // Mermaid流程图
+	FormatGraphviz = "graphviz" // Graphviz DOT
+)
+
+// 节点类型，决定导出时的形状
+const (
+	NodeStart  = "start"  // 通话入口
+	NodeDialog = "dialog" // 大模型对话
+	NodeSay    = "say"    // 固定话术
+	NodeMenu   = "menu"   // 按键菜单
+	NodeEnd    = "end"    // 挂机或转人工
+)
+
+// maxLabelRunes 节点和连线标签的最大字数，超出部分以省略号代替
+const maxLabelRunes = 24
+
+// Node 流程图节点
+type Node struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// Edge 流程图连线
+type Edge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// Graph 通话流程图
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// graphBuilder 按键值去重分配节点ID
+type graphBuilder struct {
+	graph Graph
+	ids   map[string]string
+}
+
+// node 返回键对应的节点ID，首次出现时添加节点
+func (b *graphBuilder) node(key, kind, label string) string {
+	if id, ok := b.ids[key]; ok {
+		return id
+	}
+	id := fmt.Sprintf("n%d", len(b.graph.Nodes))
+	b.ids[key] = id
+	b.graph.Nodes = append(b.graph.Nodes, Node{ID: id, Kind: kind, Label: truncate(label)})
+	return id
+}
+
+// edge 添加连线
+func (b *graphBuilder) edge(from, to, label string) {
+	b.graph.Edges = append(b.graph.Edges, Edge{From: from, To: to, Label: truncate(label)})
+}
+
+// BuildGraph 按通话流程生成流程图：呼入入口和外呼入口进入大模型对话，意图规则、按键菜单和暂停提示语
+// 是对话中的分支，未启用的部分不出现在图中；节点顺序固定，同一流程多次导出的结果相同
+func BuildGraph(scenario Scenario) Graph {
+	b := &graphBuilder{ids: make(map[string]string)}
+	dialog := b.node("dialog", NodeDialog, "AI对话")
+
+	outbound := b.node("start:outbound", NodeStart, "外呼接通")
+	b.edge(outbound, dialog, personaLabel(""))
+	if scenario.Inbound.Enabled {
+		for _, number := range sortedKeys(scenario.Inbound.Numbers) {
+			route := scenario.Inbound.Numbers[number]
+			label := "呼入 " + number
+			if number == "*" {
+				label = "呼入 其他号码"
+			}
+			start := b.node("start:inbound:"+number, NodeStart, label)
+			if route.Greeting != "" {
+				greeting := b.node("inbound:"+number+":greeting", NodeSay, spokenLabel(route.Greeting))
+				b.edge(start, greeting, "自动应答")
+				start = greeting
+			}
+			b.edge(start, dialog, personaLabel(route.Persona))
+		}
+	}
+
+	if scenario.Intent.Enabled {
+		for i, rule := range scenario.Intent.Rules {
+			target := dialog
+			switch rule.Action {
+			case intent.ActionHangup:
+				target = b.node("end:hangup", NodeEnd, "挂机")
+			case intent.ActionTransfer:
+				target = b.node("end:transfer:"+rule.Destination, NodeEnd, "转人工 "+rule.Destination)
+			}
+			label := "意图 " + rule.Name + ": " + strings.Join(rule.Keywords, "/")
+			if rule.Reply == "" {
+				b.edge(dialog, target, label)
+				continue
+			}
+			reply := b.node(fmt.Sprintf("intent:%d", i), NodeSay, spokenLabel(rule.Reply))
+			b.edge(dialog, reply, label)
+			b.edge(reply, target, "")
+		}
+	}
+
+	if scenario.DTMF.Enabled {
+		for _, name := range sortedKeys(scenario.DTMF.Menus) {
+			b.menu(dialog, name, scenario.DTMF.Menus[name])
+		}
+	}
+
+	if len(scenario.Turn.HoldPhrases) > 0 {
+		hold := b.node("turn:hold", NodeSay, spokenLabel(scenario.Turn.HoldReply))
+		b.edge(dialog, hold, "客户: "+strings.Join(scenario.Turn.HoldPhrases, "/"))
+		b.edge(hold, dialog, "客户再次开口")
+		if scenario.Turn.ResumeReply != "" {
+			resume := b.node("turn:resume", NodeSay, spokenLabel(scenario.Turn.ResumeReply))
+			b.edge(hold, resume, "暂停超时")
+			b.edge(resume, dialog, "")
+		}
+	}
+	return b.graph
+}
+
+// menu 添加按键菜单及其选项
+func (b *graphBuilder) menu(dialog, name string, menu dtmf.Menu) {
+	id := b.node("menu:"+name, NodeMenu, "按键菜单 "+name)
+	b.edge(dialog, id, "AI: "+strings.Join(menu.Triggers, "/"))
+	for _, key := range sortedKeys(menu.Options) {
+		input := menu.Options[key].Input
+		if input == "" {
+			input = "客户按键" + key
+		}
+		b.edge(id, dialog, "按"+key+" → "+input)
+	}
+	if menu.TimeoutInput != "" {
+		b.edge(id, dialog, "超时 → "+menu.TimeoutInput)
+	} else {
+		b.edge(id, dialog, "超时")
+	}
+}
+
+// Mermaid 导出为Mermaid流程图
+func (g Graph) Mermaid() string {
+	var out strings.Builder
+	out.WriteString("flowchart TD\n")
+	for _, node := range g.Nodes {
+		label := mermaidEscape(node.Label)
+		switch node.Kind {
+		case NodeStart:
+			fmt.Fprintf(&out, "    %s([\"%s\"])\n", node.ID, label)
+		case NodeSay:
+			fmt.Fprintf(&out, "    %s(\"%s\")\n", node.ID, label)
+		case NodeMenu:
+			fmt.Fprintf(&out, "    %s{\"%s\"}\n", node.ID, label)
+		case NodeEnd:
+			fmt.Fprintf(&out, "    %s((\"%s\"))\n", node.ID, label)
+		default:
+			fmt.Fprintf(&out, "    %s[\"%s\"]\n", node.ID, label)
+		}
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&out, "    %s --> %s\n", edge.From, edge.To)
+		} else {
+			fmt.Fprintf(&out, "    %s -->|\"%s\"| %s\n", edge.From, mermaidEscape(edge.Label), edge.To)
+		}
+	}
+	return out.String()
+}
+
+// Graphviz 导出为Graphviz DOT
+func (g Graph) Graphviz() string {
+	shapes := map[string]string{
+		NodeStart:  "shape=oval",
+		NodeDialog: "shape=box",
+		NodeSay:    "shape=box, style=rounded",
+		NodeMenu:   "shape=diamond",
+		NodeEnd:    "shape=doublecircle",
+	}
+	var out strings.Builder
+	out.WriteString("digraph flow {\n    rankdir=TB;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&out, "    %s [label=\"%s\", %s];\n", node.ID, dotEscape(node.Label), shapes[node.Kind])
+	}
+	for _, edge := range g.Edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&out, "    %s -> %s;\n", edge.From, edge.To)
+		} else {
+			fmt.Fprintf(&out, "    %s -> %s [label=\"%s\"];\n", edge.From, edge.To, dotEscape(edge.Label))
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// Render 按格式导出流程图
+func (g Graph) Render(format string) (string, error) {
+	switch format {
+	case FormatMermaid, "":
+		return g.Mermaid(), nil
+	case FormatGraphviz, "dot":
+		return g.Graphviz(), nil
+	}
+	return "", fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// personaLabel 进入对话的连线标签
+func personaLabel(persona string) string {
+	if persona == "" {
+		return "默认人设"
+	}
+	return "人设 " + persona
+}
+
+// spokenLabel 固定话术节点的标签，引用提示音的话术标明提示音名称
+func spokenLabel(text string) string {
+	if name, fallback, ok := promptaudio.ParseRef(text); ok {
+		if fallback == "" {
+			return "提示音 " + name
+		}
+		return "提示音 " + name + ": " + fallback
+	}
+	return "AI: " + text
+}
+
+// truncate 截断过长的标签
+func truncate(text string) string {
+	runes := []rune(text)
+	if len(runes) <= maxLabelRunes {
+		return text
+	}
+	return string(runes[:maxLabelRunes]) + "…"
+}
+
+// mermaidEscape 转义Mermaid标签中的引号
+func mermaidEscape(text string) string {
+	return strings.ReplaceAll(text, `"`, "#quot;")
+}
+
+// dotEscape 转义DOT标签中的反斜杠和引号
+func dotEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+}
+
+// sortedKeys 按字典序返回map的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// GatewayService 网关接通统计与路由排序
+// 定期从通话详单汇总各网关的接通率(ASR)和平均通话时长(ACD)，接通率骤降的网关在路由时排到最后
+type GatewayService struct {
+	store  *repositories.Store
+	config config.RoutingConfig
+
+	mu       sync.RWMutex
+	degraded map[string]bool
+}
+
+// NewGatewayService 创建网关统计服务，store为nil时只按配置顺序路由
+func NewGatewayService(store *repositories.Store, cfg config.RoutingConfig) *GatewayService {
+	return &GatewayService{
+		store:    store,
+		config:   cfg,
+		degraded: make(map[string]bool),
+	}
+}
+
+// Stats 统计window时间内各网关的接通情况，window为0时使用配置的统计窗口，tag不为空时只统计带该标签的通话
+// 已配置但窗口内没有呼叫的网关也会列出
+func (s *GatewayService) Stats(ctx context.Context, window time.Duration, tag string) ([]*models.GatewayStats, error) {
+	if window <= 0 {
+		window = s.config.Window
+	}
+	stats, err := s.store.CDRs.GatewayStats(ctx, time.Now().Add(-window), tag)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(stats))
+	for _, st := range stats {
+		seen[st.Gateway] = true
+		st.Degraded = s.isDegraded(st)
+	}
+	for _, gw := range s.config.Gateways {
+		if !seen[gw] {
+			stats = append(stats, &models.GatewayStats{Gateway: gw})
+		}
+	}
+	return stats, nil
+}
+
+// Refresh 重新统计并更新降级网关
+func (s *GatewayService) Refresh(ctx context.Context) error {
+	stats, err := s.Stats(ctx, s.config.Window, "")
+	if err != nil {
+		return err
+	}
+
+	degraded := make(map[string]bool)
+	for _, st := range stats {
+		if st.Degraded {
+			degraded[st.Gateway] = true
+		}
+	}
+
+	s.mu.Lock()
+	for gw := range degraded {
+		if !s.degraded[gw] {
+			log.Printf("警告: 网关接通率过低，已降级: %s", gw)
+		}
+	}
+	for gw := range s.degraded {
+		if !degraded[gw] {
+			log.Printf("网关接通率已恢复: %s", gw)
+		}
+	}
+	s.degraded = degraded
+	s.mu.Unlock()
+	return nil
+}
+
+// Run 定期刷新网关统计，直到ctx取消
+func (s *GatewayService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			log.Printf("刷新网关统计失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Gateways 按路由优先级返回网关：保持配置顺序，降级网关排在最后
+func (s *GatewayService) Gateways() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ordered := make([]string, 0, len(s.config.Gateways))
+	var degraded []string
+	for _, gw := range s.config.Gateways {
+		if s.degraded[gw] {
+			degraded = append(degraded, gw)
+			continue
+		}
+		ordered = append(ordered, gw)
+	}
+	return append(ordered, degraded...)
+}
+
+// isDegraded 判断网关接通率是否过低，呼叫次数不足时不判断
+func (s *GatewayService) isDegraded(st *models.GatewayStats) bool {
+	return s.config.MinASR > 0 && st.Calls >= s.config.MinCalls && st.ASR < s.config.MinASR
+}
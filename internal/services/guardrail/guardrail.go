@@ -0,0 +1,220 @@
+// Package guardrail 大模型回复过滤：限制回复长度，按正则和关键词去除不安全内容，
+// 回复不合格时改用预设的兜底话术
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"ai_dialer_mini/internal/metrics"
+)
+
+// 处理方式
+const (
+	ActionStrip    = "strip"    // 去除命中的内容，去除后为空时使用兜底话术
+	ActionFallback = "fallback" // 整条回复替换为兜底话术
+)
+
+// 过滤原因，用于指标标签
+const (
+	ReasonLength  = "length"  // 超过最大长度，已截断
+	ReasonPattern = "pattern" // 命中正则
+	ReasonKeyword = "keyword" // 命中关键词
+	ReasonEmpty   = "empty"   // 回复为空
+)
+
+// filtered 被过滤的回复数
+var filtered = metrics.NewCounterVec("ai_dialer_llm_filtered_total",
+	"被过滤的大模型回复数，按过滤原因和处理方式统计", "reason", "action")
+
+// Config 大模型回复过滤配置，max_length、patterns、keywords都未配置时不过滤
+type Config struct {
+	MaxLength int      `yaml:"max_length"` // 回复最大字数，超过时在句末截断，0为不限制
+	Patterns  []string `yaml:"patterns"`   // 不安全内容的正则表达式
+	Keywords  []string `yaml:"keywords"`   // 不安全内容的关键词，不区分大小写
+	Action    string   `yaml:"action"`     // 命中正则或关键词时的处理方式: strip（默认，去除命中内容）或 fallback（改用兜底话术）
+	Fallback  string   `yaml:"fallback"`   // 兜底话术，回复为空或不合格时使用
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if c.MaxLength < 0 {
+		return fmt.Errorf("max_length: 不能为负数")
+	}
+	for i, pattern := range c.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("patterns[%d]: 正则表达式无效: %v", i, err)
+		}
+	}
+	for i, keyword := range c.Keywords {
+		if keyword == "" {
+			return fmt.Errorf("keywords[%d]: 关键词不能为空", i)
+		}
+	}
+	switch c.Action {
+	case "", ActionStrip:
+	case ActionFallback:
+		if c.Fallback == "" {
+			return fmt.Errorf("fallback: 处理方式为fallback时必须配置兜底话术")
+		}
+	default:
+		return fmt.Errorf("action: 不支持的处理方式 %s", c.Action)
+	}
+	return nil
+}
+
+// rule 一条内容规则
+type rule struct {
+	reason string
+	re     *regexp.Regexp
+}
+
+// Filter 大模型回复过滤器，可并发使用
+type Filter struct {
+	maxLength int
+	rules     []rule
+	action    string
+	fallback  string
+}
+
+// New 按配置创建过滤器，没有配置任何规则时返回nil
+func New(config Config) (*Filter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MaxLength == 0 && len(config.Patterns) == 0 && len(config.Keywords) == 0 {
+		return nil, nil
+	}
+	f := &Filter{
+		maxLength: config.MaxLength,
+		action:    config.Action,
+		fallback:  config.Fallback,
+	}
+	if f.action == "" {
+		f.action = ActionStrip
+	}
+	for _, pattern := range config.Patterns {
+		f.rules = append(f.rules, rule{reason: ReasonPattern, re: regexp.MustCompile(pattern)})
+	}
+	for _, keyword := range config.Keywords {
+		f.rules = append(f.rules, rule{reason: ReasonKeyword, re: regexp.MustCompile("(?i)" + regexp.QuoteMeta(keyword))})
+	}
+	return f, nil
+}
+
+// Apply 过滤一条完整回复，返回过滤后的回复；f为nil时原样返回
+func (f *Filter) Apply(reply string) string {
+	if f == nil {
+		return reply
+	}
+	stream := f.Stream()
+	stream.Write(reply)
+	stream.Close()
+	return stream.Reply()
+}
+
+// check 去除命中规则的内容，返回去除后的文本和命中的原因
+func (f *Filter) check(text string) (string, string) {
+	reason := ""
+	for _, r := range f.rules {
+		if !r.re.MatchString(text) {
+			continue
+		}
+		if reason == "" {
+			reason = r.reason
+		}
+		text = r.re.ReplaceAllString(text, "")
+	}
+	return text, reason
+}
+
+// Stream 流式回复的过滤状态，逐句调用Write，回复结束后调用Close
+type Stream struct {
+	filter  *Filter
+	length  int  // 已输出的字数
+	stopped bool // 已截断或已改用兜底话术，后续内容全部丢弃
+	reply   strings.Builder
+}
+
+// Stream 创建一次回复的过滤状态；f为nil时不过滤
+func (f *Filter) Stream() *Stream {
+	return &Stream{filter: f}
+}
+
+// Write 过滤一句回复，返回可以输出的文本，可能为空
+func (s *Stream) Write(sentence string) string {
+	f := s.filter
+	if f == nil {
+		s.reply.WriteString(sentence)
+		return sentence
+	}
+	if s.stopped {
+		return ""
+	}
+
+	text, reason := f.check(sentence)
+	if reason != "" {
+		if f.action == ActionFallback {
+			filtered.Inc(reason, ActionFallback)
+			s.stopped = true
+			// 已经输出的内容无法撤回，只丢弃后续内容
+			if s.length > 0 {
+				return ""
+			}
+			return s.output(f.fallback)
+		}
+		filtered.Inc(reason, ActionStrip)
+	}
+
+	if f.maxLength > 0 && s.length+utf8.RuneCountInString(text) > f.maxLength {
+		filtered.Inc(ReasonLength, ActionStrip)
+		s.stopped = true
+		text = truncate(text, f.maxLength-s.length)
+	}
+	return s.output(text)
+}
+
+// Close 结束回复，整条回复为空时返回兜底话术，否则返回空字符串
+func (s *Stream) Close() string {
+	if s.filter == nil || strings.TrimSpace(s.reply.String()) != "" {
+		return ""
+	}
+	if s.filter.fallback == "" {
+		return ""
+	}
+	filtered.Inc(ReasonEmpty, ActionFallback)
+	s.reply.Reset()
+	return s.output(s.filter.fallback)
+}
+
+// Reply 过滤后的完整回复，用于写入对话历史
+func (s *Stream) Reply() string {
+	return s.reply.String()
+}
+
+// output 记录已输出的文本
+func (s *Stream) output(text string) string {
+	s.length += utf8.RuneCountInString(text)
+	s.reply.WriteString(text)
+	return text
+}
+
+// truncate 截取前n个字，尽量在最后一个标点处截断，避免半句话
+func truncate(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	runes = runes[:n]
+	for i := len(runes) - 1; i > 0; i-- {
+		if strings.ContainsRune("。！？；，.!?;,", runes[i]) {
+			return string(runes[:i+1])
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,225 @@
+// Package history 对话历史窗口：按近似token数估算提示词长度，超出预算时把较早的历史
+// 摘要为一条背景信息（可使用更小更快的模型），摘要失败或未启用摘要时直接丢弃，使提示词不随通话轮数无限增长
+package history
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/models"
+)
+
+// SummaryPrefix 历史摘要消息的内容前缀，摘要以背景信息消息的形式保留在历史开头
+const SummaryPrefix = "此前对话摘要："
+
+// 默认值
+const (
+	DefaultKeepRecent       = 2
+	DefaultSummaryMaxTokens = 200
+	DefaultSummaryTimeout   = 10 * time.Second
+)
+
+// messageOverhead 每条历史消息在提示词中的角色称呼和换行的近似token数
+const messageOverhead = 2
+
+// truncations 历史超出预算的处理次数
+var truncations = metrics.NewCounterVec("ai_dialer_llm_history_truncated_total",
+	"对话历史超出token预算的处理次数，按摘要（summarized）和丢弃（dropped）统计", "action")
+
+// speakers 摘要请求中历史消息角色的称呼
+var speakers = map[string]string{
+	models.RoleUser:      "客户",
+	models.RoleAssistant: "客服",
+	models.RoleSystem:    "背景信息",
+	models.RoleTool:      "工具结果",
+}
+
+// Config 对话历史窗口配置
+type Config struct {
+	MaxTokens  int           `yaml:"max_tokens"`  // 提示词的近似token预算，超出时处理较早的历史，为0时不限制
+	KeepRecent int           `yaml:"keep_recent"` // 始终完整保留的最近消息条数，含本轮用户消息
+	Summarize  SummaryConfig `yaml:"summarize"`   // 较早历史的摘要
+}
+
+// SummaryConfig 历史摘要配置
+type SummaryConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Model     string        `yaml:"model"`      // 摘要使用的模型，留空使用对话模型
+	MaxTokens int           `yaml:"max_tokens"` // 摘要的最大token数，从预算中预留
+	Timeout   time.Duration `yaml:"timeout"`    // 摘要请求超时时间，超时时丢弃较早的历史
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens: 不能为负数")
+	}
+	if c.KeepRecent < 0 {
+		return fmt.Errorf("keep_recent: 不能为负数")
+	}
+	if c.Summarize.MaxTokens < 0 {
+		return fmt.Errorf("summarize.max_tokens: 不能为负数")
+	}
+	if c.Summarize.Timeout < 0 {
+		return fmt.Errorf("summarize.timeout: 不能为负数")
+	}
+	if c.MaxTokens > 0 && c.Summarize.Enabled && c.Summarize.MaxTokens >= c.MaxTokens {
+		return fmt.Errorf("summarize.max_tokens: 必须小于max_tokens")
+	}
+	return nil
+}
+
+// Generator 生成摘要的大模型客户端
+type Generator interface {
+	GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error)
+}
+
+// Window 对话历史窗口；方法可在nil上调用，此时不截断
+type Window struct {
+	config    Config
+	generator Generator
+}
+
+// New 创建对话历史窗口，未配置预算时返回nil；generator为nil时不摘要
+func New(config Config, generator Generator) *Window {
+	if config.MaxTokens <= 0 {
+		return nil
+	}
+	if config.KeepRecent <= 0 {
+		config.KeepRecent = DefaultKeepRecent
+	}
+	if config.Summarize.MaxTokens <= 0 {
+		config.Summarize.MaxTokens = DefaultSummaryMaxTokens
+	}
+	if config.Summarize.Timeout <= 0 {
+		config.Summarize.Timeout = DefaultSummaryTimeout
+	}
+	if generator == nil {
+		config.Summarize.Enabled = false
+	}
+	return &Window{config: config, generator: generator}
+}
+
+// Fit 使历史加上提示词中人设、业务知识等固定部分（reserved个token）不超过预算：
+// 从最近的消息向前保留，放不下的较早消息摘要为一条背景信息或直接丢弃；
+// 最近keep_recent条消息即使超出预算也保留。未超出预算时原样返回
+func (w *Window) Fit(ctx context.Context, messages []models.Message, reserved int) []models.Message {
+	if w == nil {
+		return messages
+	}
+	budget := w.config.MaxTokens - reserved
+	if Tokens(messages) <= budget {
+		return messages
+	}
+	if w.config.Summarize.Enabled {
+		budget -= w.config.Summarize.MaxTokens + messageOverhead
+	}
+
+	split, used := len(messages), 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		n := EstimateTokens(messages[i].Content) + messageOverhead
+		if len(messages)-i > w.config.KeepRecent && used+n > budget {
+			break
+		}
+		used += n
+		split = i
+	}
+	if split == 0 {
+		return messages
+	}
+
+	recent := messages[split:]
+	if w.config.Summarize.Enabled {
+		summary, err := w.summarize(ctx, messages[:split])
+		if err == nil {
+			truncations.Inc("summarized")
+			return append([]models.Message{{Role: models.RoleSystem, Content: SummaryPrefix + summary}}, recent...)
+		}
+		log.Printf("警告: 摘要对话历史失败，丢弃较早的 %d 条消息: %v", split, err)
+	}
+	truncations.Inc("dropped")
+	return append([]models.Message(nil), recent...)
+}
+
+// summarize 调用大模型把较早的消息（可能包含上一次的摘要）概括为一段话
+func (w *Window) summarize(ctx context.Context, messages []models.Message) (string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "请用不超过%d字概括以下通话记录的要点，包括客户诉求、已确认的信息和已答复的问题，只输出摘要：\n",
+		w.config.Summarize.MaxTokens)
+	for _, msg := range messages {
+		if speaker, ok := speakers[msg.Role]; ok {
+			fmt.Fprintf(&prompt, "%s: %s\n", speaker, strings.TrimPrefix(msg.Content, SummaryPrefix))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.config.Summarize.Timeout)
+	defer cancel()
+	response, err := w.generator.GenerateContext(ctx, prompt.String(), ollama.Options{
+		MaxTokens: w.config.Summarize.MaxTokens,
+		Model:     w.config.Summarize.Model,
+	})
+	if err != nil {
+		return "", err
+	}
+	// 熔断期间的兜底回复不是摘要
+	if response.Fallback {
+		return "", ollama.ErrCircuitOpen
+	}
+	summary := strings.TrimSpace(response.Response)
+	if summary == "" {
+		return "", fmt.Errorf("大模型返回空摘要")
+	}
+	// Ollama不认max_tokens选项，摘要可能超出预留的token数，按估算截断
+	return truncateTokens(summary, w.config.Summarize.MaxTokens), nil
+}
+
+// truncateTokens 截断文本使估算的token数不超过max
+func truncateTokens(text string, max int) string {
+	if EstimateTokens(text) <= max {
+		return text
+	}
+	cjk, other := 0, 0
+	for i, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsSpace(r):
+		default:
+			other++
+		}
+		if cjk+(other+3)/4 > max {
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return text
+}
+
+// Tokens 估算消息在提示词中的token数
+func Tokens(messages []models.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += EstimateTokens(msg.Content) + messageOverhead
+	}
+	return total
+}
+
+// EstimateTokens 估算文本的token数：中日韩文字每字约1个token，其他非空白字符每4个约1个token
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsSpace(r):
+		default:
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -0,0 +1,153 @@
+// Package hooks 会话事件钩子：集成方实现Hooks接口并在编译时注册，即可在通话接通、客户说完一句话、
+// AI完成一轮回复和挂机时执行自定义逻辑（写入业务系统、打标签、触发工单等），无需修改服务层代码。
+//
+// 注册方式：在本模块内新增文件（如 cmd/hooks_crm.go），在init中调用Register：
+//
+//	type crmHooks struct{ hooks.Base }
+//
+//	func (crmHooks) OnHangup(ctx context.Context, cdr models.CDR) { ... }
+//
+//	func init() { hooks.Register(crmHooks{}) }
+//
+// 钩子在事件处理的goroutine中按注册顺序同步调用，耗时操作应自行启动goroutine；钩子中的panic会被捕获并记录日志
+package hooks
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// Call 已接通的通话
+type Call struct {
+	UUID      string
+	Caller    string            // 主叫号码
+	Callee    string            // 被叫号码
+	Direction string            // 通道方向：outbound外呼，inbound呼入
+	Headers   map[string]string // 应答事件的全部ESL事件头，可读取自定义通道变量（variable_*）
+}
+
+// Transcript 客户一句话的最终识别结果
+type Transcript struct {
+	CallUUID  string // 通话UUID，非通话音频流的连接为空
+	SessionID string // 识别和对话会话ID
+	Text      string
+}
+
+// Turn 一轮对话
+type Turn struct {
+	CallUUID  string        // 通话UUID，非通话音频流的连接为空
+	SessionID string        // 识别和对话会话ID
+	Input     string        // 客户的话或按键菜单输入
+	Reply     string        // AI回复
+	Intent    string        // 命中的意图名称，由大模型回复时为空
+	Latency   time.Duration // 从收到客户输入到回复完成的耗时
+}
+
+// Hooks 会话事件钩子
+type Hooks interface {
+	// OnCallAnswered 通话接通
+	OnCallAnswered(ctx context.Context, call Call)
+	// OnTranscriptFinal 客户说完一句话
+	OnTranscriptFinal(ctx context.Context, transcript Transcript)
+	// OnDialogTurn AI完成一轮回复，被打断或失败的回复不调用
+	OnDialogTurn(ctx context.Context, turn Turn)
+	// OnHangup 通道挂断，cdr为写入详单的内容
+	OnHangup(ctx context.Context, cdr models.CDR)
+}
+
+// Base 钩子的空实现，嵌入后只需实现关心的事件
+type Base struct{}
+
+// OnCallAnswered 不做处理
+func (Base) OnCallAnswered(context.Context, Call) {}
+
+// OnTranscriptFinal 不做处理
+func (Base) OnTranscriptFinal(context.Context, Transcript) {}
+
+// OnDialogTurn 不做处理
+func (Base) OnDialogTurn(context.Context, Turn) {}
+
+// OnHangup 不做处理
+func (Base) OnHangup(context.Context, models.CDR) {}
+
+// registry 编译时注册的钩子
+var registry struct {
+	mu    sync.Mutex
+	hooks []Hooks
+}
+
+// Register 注册钩子，应在init中调用；服务启动时由Default取出所有已注册的钩子
+func Register(h Hooks) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.hooks = append(registry.hooks, h)
+}
+
+// Dispatcher 依次调用钩子，可在nil上调用，此时不做处理
+type Dispatcher struct {
+	hooks []Hooks
+}
+
+// New 创建钩子调度，没有钩子时返回nil
+func New(hooks ...Hooks) *Dispatcher {
+	if len(hooks) == 0 {
+		return nil
+	}
+	return &Dispatcher{hooks: hooks}
+}
+
+// Default 使用所有已注册的钩子创建调度，没有注册钩子时返回nil
+func Default() *Dispatcher {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	return New(append([]Hooks(nil), registry.hooks...)...)
+}
+
+// Len 钩子数量
+func (d *Dispatcher) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.hooks)
+}
+
+// CallAnswered 通知通话接通
+func (d *Dispatcher) CallAnswered(ctx context.Context, call Call) {
+	d.each("OnCallAnswered", func(h Hooks) { h.OnCallAnswered(ctx, call) })
+}
+
+// TranscriptFinal 通知客户说完一句话
+func (d *Dispatcher) TranscriptFinal(ctx context.Context, transcript Transcript) {
+	d.each("OnTranscriptFinal", func(h Hooks) { h.OnTranscriptFinal(ctx, transcript) })
+}
+
+// DialogTurn 通知AI完成一轮回复
+func (d *Dispatcher) DialogTurn(ctx context.Context, turn Turn) {
+	d.each("OnDialogTurn", func(h Hooks) { h.OnDialogTurn(ctx, turn) })
+}
+
+// Hangup 通知通道挂断
+func (d *Dispatcher) Hangup(ctx context.Context, cdr models.CDR) {
+	d.each("OnHangup", func(h Hooks) { h.OnHangup(ctx, cdr) })
+}
+
+// each 按注册顺序调用钩子，一个钩子panic不影响其他钩子
+func (d *Dispatcher) each(event string, call func(h Hooks)) {
+	if d == nil {
+		return
+	}
+	for _, h := range d.hooks {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("会话钩子 %T.%s 发生panic: %v", h, event, err)
+				}
+			}()
+			call(h)
+		}()
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrIdempotencyInProgress 相同幂等键的请求仍在处理中
+	ErrIdempotencyInProgress = errors.New("相同幂等键的请求正在处理中")
+	// ErrIdempotencyMismatch 幂等键已被用于内容不同的请求
+	ErrIdempotencyMismatch = errors.New("幂等键已被用于不同的请求")
+)
+
+// idempotencyLockTTL 处理中记录的有效期，进程在处理期间退出时幂等键在该时间后释放，不会占用整个窗口期
+const idempotencyLockTTL = time.Minute
+
+// idempotencyCleanupTimeout 请求被取消后释放幂等键的超时时间
+const idempotencyCleanupTimeout = 5 * time.Second
+
+// idempotencyRecord Redis中保存的幂等记录
+type idempotencyRecord struct {
+	Fingerprint string          `json:"fingerprint"`      // 请求指纹，用于识别同一幂等键下的不同请求
+	Done        bool            `json:"done"`             // 首次请求是否已完成
+	Result      json.RawMessage `json:"result,omitempty"` // 首次请求的结果
+}
+
+// IdempotencyService 基于Redis的幂等请求服务
+type IdempotencyService struct {
+	client *goredis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+// NewIdempotencyService 创建幂等请求服务，ttl为幂等窗口期
+func NewIdempotencyService(client *goredis.Client, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		client: client,
+		ttl:    ttl,
+		prefix: "idempotency:",
+	}
+}
+
+// Do 以幂等方式执行fn
+// 窗口期内首次出现的key会执行fn并缓存结果；之后相同key的请求不再执行fn，
+// 直接把首次结果解码到out并返回replayed=true。fn失败时释放key，允许客户端重试
+// 处理期间key只短暂锁定，保存首次结果时才延长到完整窗口期
+func (s *IdempotencyService) Do(ctx context.Context, key, fingerprint string, out interface{}, fn func() (interface{}, error)) (bool, error) {
+	redisKey := s.prefix + key
+
+	pending, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return false, fmt.Errorf("序列化幂等记录失败: %v", err)
+	}
+
+	lockTTL := idempotencyLockTTL
+	if s.ttl < lockTTL {
+		lockTTL = s.ttl
+	}
+	acquired, err := s.client.SetNX(ctx, redisKey, pending, lockTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("写入幂等记录失败: %v", err)
+	}
+	if !acquired {
+		return true, s.replay(ctx, redisKey, fingerprint, out)
+	}
+
+	// 客户端断开后ctx被取消，仍需释放key或保存已产生的结果
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), idempotencyCleanupTimeout)
+	defer cancel()
+
+	result, err := fn()
+	if err != nil {
+		if delErr := s.client.Del(cleanupCtx, redisKey).Err(); delErr != nil {
+			return false, fmt.Errorf("%v (释放幂等键失败: %v)", err, delErr)
+		}
+		return false, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return false, fmt.Errorf("序列化请求结果失败: %v", err)
+	}
+	done, err := json.Marshal(idempotencyRecord{Fingerprint: fingerprint, Done: true, Result: data})
+	if err != nil {
+		return false, fmt.Errorf("序列化幂等记录失败: %v", err)
+	}
+	if err := s.client.Set(cleanupCtx, redisKey, done, s.ttl).Err(); err != nil {
+		return false, fmt.Errorf("保存请求结果失败: %v", err)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, fmt.Errorf("解析请求结果失败: %v", err)
+	}
+	return false, nil
+}
+
+// replay 读取首次请求的结果
+func (s *IdempotencyService) replay(ctx context.Context, redisKey, fingerprint string, out interface{}) error {
+	data, err := s.client.Get(ctx, redisKey).Bytes()
+	if err == goredis.Nil {
+		// 首次请求失败后刚释放了key
+		return ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return fmt.Errorf("读取幂等记录失败: %v", err)
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("解析幂等记录失败: %v", err)
+	}
+	if record.Fingerprint != fingerprint {
+		return ErrIdempotencyMismatch
+	}
+	if !record.Done {
+		return ErrIdempotencyInProgress
+	}
+
+	if err := json.Unmarshal(record.Result, out); err != nil {
+		return fmt.Errorf("解析请求结果失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package inbound 呼入通话入口：来自指定SIP配置的呼入通道创建后自动应答，
+// 按被叫号码选用AI人设（提示词配置）和开场问候语，应答后由音频流接入AI对话
+package inbound
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wildcard 未单独配置的被叫号码使用的路由
+const Wildcard = "*"
+
+// directionInbound FreeSWITCH呼入通道的Call-Direction
+const directionInbound = "inbound"
+
+// Route 被叫号码的呼入路由
+type Route struct {
+	Persona  string `yaml:"persona"`  // 提示词配置名称（llm.prompt.profiles），为空时使用默认人设
+	Greeting string `yaml:"greeting"` // 音频流接入后AI先说的问候语，为空时等待客户先开口
+}
+
+// Config 呼入配置
+type Config struct {
+	Enabled  bool             `yaml:"enabled"`
+	Profiles []string         `yaml:"profiles"` // 接受呼入的sofia配置名，如 external，为空时接受所有呼入通道
+	Numbers  map[string]Route `yaml:"numbers"`  // 按被叫号码配置路由，* 匹配其他号码；不匹配的呼入通道交由拨号计划处理
+}
+
+// Validate 校验呼入配置，hasPersona判断提示词配置是否存在，未启用时不校验
+func (c Config) Validate(hasPersona func(name string) bool) error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Numbers) == 0 {
+		return fmt.Errorf("numbers: 启用呼入时至少配置一个被叫号码")
+	}
+	for number, route := range c.Numbers {
+		if strings.TrimSpace(number) == "" {
+			return fmt.Errorf("numbers: 被叫号码不能为空")
+		}
+		if route.Persona != "" && !hasPersona(route.Persona) {
+			return fmt.Errorf("numbers.%s.persona: 未定义的提示词配置: %s", number, route.Persona)
+		}
+	}
+	return nil
+}
+
+// Router 判断通道是否为需要接入AI的呼入通话，并按被叫号码选择路由；可在nil上调用，此时不接入任何呼入
+type Router struct {
+	profiles map[string]bool
+	numbers  map[string]Route
+}
+
+// New 创建呼入路由，未启用时返回nil
+func New(config Config) *Router {
+	if !config.Enabled {
+		return nil
+	}
+	r := &Router{numbers: config.Numbers}
+	if len(config.Profiles) > 0 {
+		r.profiles = make(map[string]bool, len(config.Profiles))
+		for _, profile := range config.Profiles {
+			r.profiles[profile] = true
+		}
+	}
+	return r
+}
+
+// Match 按通道事件头部匹配呼入路由：通道须为呼入方向、来自配置的sofia配置，
+// 被叫号码（Caller-Destination-Number）已配置或配置了*时返回路由
+func (r *Router) Match(headers map[string]string) (Route, bool) {
+	if r == nil || headers["Call-Direction"] != directionInbound {
+		return Route{}, false
+	}
+	if r.profiles != nil && !r.profiles[headers["variable_sofia_profile_name"]] {
+		return Route{}, false
+	}
+	if route, ok := r.numbers[headers["Caller-Destination-Number"]]; ok {
+		return route, true
+	}
+	route, ok := r.numbers[Wildcard]
+	return route, ok
+}
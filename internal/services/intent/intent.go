@@ -0,0 +1,135 @@
+// Package intent 在调用大模型前按关键词识别客户意图，如“不需要”“转人工”、语音信箱提示音，
+// 命中后直接回复固定话术并挂机或转人工，常见的固定流程不必等待大模型生成
+package intent
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 命中意图后执行的动作
+const (
+	ActionReply    = "reply"    // 只回复固定话术，对话继续
+	ActionHangup   = "hangup"   // 回复话术（如有）后挂机
+	ActionTransfer = "transfer" // 回复话术（如有）后转接到人工坐席
+)
+
+// Rule 意图规则
+type Rule struct {
+	Name        string   `yaml:"name"`        // 意图名称，用于日志
+	Keywords    []string `yaml:"keywords"`    // 识别结果包含其中任一关键词时命中，忽略标点和空格
+	MaxRunes    int      `yaml:"max_runes"`   // 识别结果超过该字数时不匹配，避免长句中的关键词误判，为0时不限制
+	Action      string   `yaml:"action"`      // 命中后的动作: reply/hangup/transfer
+	Reply       string   `yaml:"reply"`       // 回复话术，挂机和转人工时可留空
+	Destination string   `yaml:"destination"` // 转人工的目标号码或分机，动作为transfer时必填
+}
+
+// Config 意图识别配置
+type Config struct {
+	Enabled         bool   `yaml:"enabled"`          // 是否启用
+	Rules           []Rule `yaml:"rules"`            // 意图规则，按顺序匹配，先命中的生效
+	DialplanContext string `yaml:"dialplan_context"` // 转人工使用的拨号计划上下文，留空使用FreeSWITCH默认值
+}
+
+// Validate 校验意图识别配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	for i, rule := range c.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rules[%d].name: 不能为空", i)
+		}
+		if len(rule.Keywords) == 0 {
+			return fmt.Errorf("rules[%d].keywords: 至少配置一个关键词", i)
+		}
+		if rule.MaxRunes < 0 {
+			return fmt.Errorf("rules[%d].max_runes: 不能为负数", i)
+		}
+		switch rule.Action {
+		case ActionReply:
+			if rule.Reply == "" {
+				return fmt.Errorf("rules[%d].reply: 动作为reply时必须配置回复话术", i)
+			}
+		case ActionHangup:
+		case ActionTransfer:
+			if rule.Destination == "" {
+				return fmt.Errorf("rules[%d].destination: 动作为transfer时必须配置转接目标", i)
+			}
+		default:
+			return fmt.Errorf("rules[%d].action: 不支持的动作: %s", i, rule.Action)
+		}
+	}
+	return nil
+}
+
+// Match 命中的意图
+type Match struct {
+	Rule        string // 意图名称
+	Keyword     string // 命中的关键词
+	Action      string // 执行的动作
+	Reply       string // 回复话术
+	Destination string // 转人工的目标
+}
+
+// Engine 意图识别，方法可在nil上调用，此时从不命中
+type Engine struct {
+	config Config
+}
+
+// New 创建意图识别
+func New(config Config) (*Engine, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{config: config}, nil
+}
+
+// DialplanContext 转人工使用的拨号计划上下文
+func (e *Engine) DialplanContext() string {
+	if e == nil {
+		return ""
+	}
+	return e.config.DialplanContext
+}
+
+// Match 按规则顺序匹配识别结果，返回第一个命中的意图
+func (e *Engine) Match(text string) (*Match, bool) {
+	if e == nil {
+		return nil, false
+	}
+	normalized := normalize(text)
+	if normalized == "" {
+		return nil, false
+	}
+	runes := utf8.RuneCountInString(normalized)
+	for _, rule := range e.config.Rules {
+		if rule.MaxRunes > 0 && runes > rule.MaxRunes {
+			continue
+		}
+		for _, keyword := range rule.Keywords {
+			if keyword = normalize(keyword); keyword != "" && strings.Contains(normalized, keyword) {
+				return &Match{
+					Rule:        rule.Name,
+					Keyword:     keyword,
+					Action:      rule.Action,
+					Reply:       rule.Reply,
+					Destination: rule.Destination,
+				}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// normalize 去掉标点和空格，英文转为小写
+func normalize(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, text)
+}
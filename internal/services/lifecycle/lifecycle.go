@@ -0,0 +1,203 @@
+// Package lifecycle 进程生命周期：启动完成前和排空期间就绪检查返回未就绪，排空时不再接受新的呼叫和
+// WebSocket连接，等待进行中的通话和连接结束（最长为宽限期）后再退出，配合Kubernetes的preStop和就绪探针滚动发布
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// 进程生命周期状态
+const (
+	StateStarting = "starting" // 启动中，就绪检查返回未就绪
+	StateReady    = "ready"    // 正常服务
+	StateDraining = "draining" // 排空中，不接受新的呼叫和连接，等待进行中的结束
+	StateDrained  = "drained"  // 进行中的呼叫和连接已全部结束，可以退出
+)
+
+// pollInterval 排空期间检查进行中数量的间隔
+const pollInterval = 200 * time.Millisecond
+
+// Config 进程生命周期配置
+type Config struct {
+	GracePeriod time.Duration `yaml:"grace_period"` // 排空时等待进行中的通话结束的最长时间，应小于Pod的terminationGracePeriodSeconds
+}
+
+// Validate 校验进程生命周期配置
+func (c Config) Validate() error {
+	if c.GracePeriod < 0 {
+		return fmt.Errorf("grace_period不能为负数")
+	}
+	return nil
+}
+
+// Counter 返回进行中的数量，如进行中的通话数、WebSocket连接数
+type Counter func() int
+
+// 外部依赖状态
+const (
+	DependencyOK    = "ok"    // 最近一次调用成功，或尚未调用
+	DependencyError = "error" // 最近一次调用失败
+)
+
+// Dependency 外部依赖（如语音识别服务）的最近状态，供就绪检查展示排障信息，不影响是否就绪
+type Dependency struct {
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`       // 最近一次失败的错误信息
+	Kind        string     `json:"kind,omitempty"`        // 错误分类，如 auth、quota、timeout
+	Code        string     `json:"code,omitempty"`        // 服务方错误码
+	Remediation string     `json:"remediation,omitempty"` // 处理建议
+	Failures    int        `json:"failures,omitempty"`    // 连续失败次数
+	Since       *time.Time `json:"since,omitempty"`       // 最近一次失败的时间
+}
+
+// DependencyCheck 返回外部依赖的最近状态
+type DependencyCheck func() Dependency
+
+// Status 生命周期状态和排空进度
+type Status struct {
+	State        string         `json:"state"`
+	Active       map[string]int `json:"active"`                  // 各类进行中的数量
+	Total        int            `json:"total"`                   // 进行中的总数
+	DrainStarted *time.Time     `json:"drain_started,omitempty"` // 开始排空的时间
+	Deadline     *time.Time     `json:"deadline,omitempty"`      // 排空的最晚结束时间，超过后不再等待
+
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"` // 外部依赖的最近状态
+}
+
+// Manager 进程生命周期管理，方法可在nil上调用，此时始终就绪并接受新的请求
+type Manager struct {
+	config Config
+
+	mu           sync.Mutex
+	state        string
+	counters     map[string]Counter
+	dependencies map[string]DependencyCheck
+	drainStarted time.Time
+}
+
+// New 创建进程生命周期管理，初始为启动中
+func New(config Config) *Manager {
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = 30 * time.Second
+	}
+	return &Manager{
+		config:       config,
+		state:        StateStarting,
+		counters:     make(map[string]Counter),
+		dependencies: make(map[string]DependencyCheck),
+	}
+}
+
+// Track 登记一类进行中的工作，排空时等待其数量归零
+func (m *Manager) Track(name string, counter Counter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name] = counter
+}
+
+// Depend 登记一个外部依赖，就绪检查中展示其最近状态
+func (m *Manager) Depend(name string, check DependencyCheck) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dependencies[name] = check
+}
+
+// MarkReady 启动完成，就绪检查开始返回就绪；已开始排空时不改变状态
+func (m *Manager) MarkReady() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == StateStarting {
+		m.state = StateReady
+	}
+}
+
+// Ready 是否就绪，启动中和排空后返回false，使负载均衡不再转发新的请求
+func (m *Manager) Ready() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state == StateReady
+}
+
+// Accepting 是否接受新的呼叫和连接，开始排空后返回false
+func (m *Manager) Accepting() bool {
+	if m == nil {
+		return true
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state == StateStarting || m.state == StateReady
+}
+
+// Drain 开始排空，已在排空时不重复开始；返回是否本次开始排空
+func (m *Manager) Drain() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == StateDraining || m.state == StateDrained {
+		return false
+	}
+	m.state = StateDraining
+	m.drainStarted = time.Now()
+	log.Printf("开始排空，不再接受新的呼叫和连接，最长等待 %v", m.config.GracePeriod)
+	return true
+}
+
+// Wait 开始排空并等待进行中的呼叫和连接全部结束，超过宽限期时返回错误，ctx取消时返回ctx的错误
+func (m *Manager) Wait(ctx context.Context) error {
+	m.Drain()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		status := m.Status()
+		if status.State == StateDrained {
+			return nil
+		}
+		if time.Now().After(*status.Deadline) {
+			return fmt.Errorf("排空超时，仍有 %d 个进行中: %v", status.Total, status.Active)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Status 当前状态和各类进行中的数量
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := Status{Active: make(map[string]int, len(m.counters))}
+	for name, counter := range m.counters {
+		n := counter()
+		status.Active[name] = n
+		status.Total += n
+	}
+
+	if len(m.dependencies) > 0 {
+		status.Dependencies = make(map[string]Dependency, len(m.dependencies))
+		for name, check := range m.dependencies {
+			status.Dependencies[name] = check()
+		}
+	}
+
+	if m.state == StateDraining && status.Total == 0 {
+		m.state = StateDrained
+		log.Printf("排空完成，耗时 %v", time.Since(m.drainStarted).Round(time.Millisecond))
+	}
+	status.State = m.state
+	if !m.drainStarted.IsZero() {
+		started := m.drainStarted
+		deadline := started.Add(m.config.GracePeriod)
+		status.DrainStarted = &started
+		status.Deadline = &deadline
+	}
+	return status
+}
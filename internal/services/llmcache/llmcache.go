@@ -0,0 +1,153 @@
+// Package llmcache 大模型回复缓存：按规整后的提示词、模型和生成参数缓存回复，
+// 相同的问候语和常见问题直接返回缓存的回复，不再请求大模型；缓存保存在Redis中，多实例共享
+package llmcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/metrics"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 默认值
+const (
+	DefaultTTL        = time.Hour
+	DefaultMaxEntries = 10000
+	DefaultPrefix     = "ai_dialer:llm_cache:"
+)
+
+// lookups 缓存查询次数
+var lookups = metrics.NewCounterVec("ai_dialer_llm_cache_total",
+	"大模型回复缓存查询次数，按命中（hit）和未命中（miss）统计", "result")
+
+// Config 大模型回复缓存配置，需要Redis
+type Config struct {
+	Enabled    bool          `yaml:"enabled"`
+	TTL        time.Duration `yaml:"ttl"`         // 缓存有效期
+	MaxEntries int           `yaml:"max_entries"` // 最多缓存的回复数，超过时淘汰最早写入的回复
+	Prefix     string        `yaml:"prefix"`      // Redis键前缀
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf("ttl: 不能为负数")
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("max_entries: 不能为负数")
+	}
+	return nil
+}
+
+// Cache 大模型回复缓存；方法可在nil上调用，此时总是未命中
+type Cache struct {
+	client     *goredis.Client
+	ttl        time.Duration
+	maxEntries int
+	prefix     string
+	model      string // 默认模型名称，生成参数未指定模型时计入缓存键
+}
+
+// New 创建大模型回复缓存，model为默认模型名称，未配置的参数使用默认值
+func New(client *goredis.Client, config Config, model string) *Cache {
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMaxEntries
+	}
+	if config.Prefix == "" {
+		config.Prefix = DefaultPrefix
+	}
+	return &Cache{client: client, ttl: config.TTL, maxEntries: config.MaxEntries, prefix: config.Prefix, model: model}
+}
+
+// Get 查询缓存的回复，Redis出错时视为未命中
+func (c *Cache) Get(ctx context.Context, prompt string, options ollama.Options) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	reply, err := c.client.Get(ctx, c.key(prompt, options)).Result()
+	if err != nil {
+		if err != goredis.Nil {
+			log.Printf("警告: 读取大模型回复缓存失败: %v", err)
+		}
+		lookups.Inc("miss")
+		return "", false
+	}
+	lookups.Inc("hit")
+	return reply, true
+}
+
+// Set 缓存回复，缓存数超过上限时淘汰最早写入的回复；空回复不缓存
+func (c *Cache) Set(ctx context.Context, prompt string, options ollama.Options, reply string) {
+	if c == nil || strings.TrimSpace(reply) == "" {
+		return
+	}
+	if err := c.set(ctx, c.key(prompt, options), reply); err != nil {
+		log.Printf("警告: 写入大模型回复缓存失败: %v", err)
+	}
+}
+
+// set 写入回复并在索引中登记写入时间，清理索引中已过期的键，超出上限时删除最早写入的键
+func (c *Cache) set(ctx context.Context, key, reply string) error {
+	index := c.prefix + "index"
+	now := time.Now()
+	_, err := c.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, key, reply, c.ttl)
+		pipe.ZAdd(ctx, index, goredis.Z{Score: float64(now.UnixNano()), Member: key})
+		pipe.ZRemRangeByScore(ctx, index, "-inf", strconv.FormatInt(now.Add(-c.ttl).UnixNano(), 10))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	count, err := c.client.ZCard(ctx, index).Result()
+	if err != nil || count <= int64(c.maxEntries) {
+		return err
+	}
+	evicted, err := c.client.ZPopMin(ctx, index, count-int64(c.maxEntries)).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(evicted))
+	for _, z := range evicted {
+		keys = append(keys, z.Member.(string))
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
+
+// key 缓存键：规整后的提示词、模型名称和影响回复内容的生成参数的摘要
+func (c *Cache) key(prompt string, options ollama.Options) string {
+	model := options.Model
+	if model == "" {
+		model = c.model
+	}
+	data, _ := json.Marshal(struct {
+		Prompt      string  `json:"prompt"`
+		Model       string  `json:"model"`
+		Temperature float64 `json:"temperature"`
+		TopP        float64 `json:"top_p"`
+		TopK        int     `json:"top_k"`
+		MaxTokens   int     `json:"max_tokens"`
+	}{Normalize(prompt), model, options.Temperature, options.TopP, options.TopK, options.MaxTokens})
+	sum := sha256.Sum256(data)
+	return c.prefix + hex.EncodeToString(sum[:])
+}
+
+// Normalize 规整提示词：合并连续空白、去除首尾空白、英文转为小写，
+// 只有空白或大小写不同的提示词使用同一条缓存
+func Normalize(prompt string) string {
+	return strings.ToLower(strings.Join(strings.Fields(prompt), " "))
+}
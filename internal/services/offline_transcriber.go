@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/audio/resample"
+	"ai_dialer_mini/internal/lang"
+	"ai_dialer_mini/internal/models"
+)
+
+// asrSampleRate 离线转写送入ASR的采样率
+const asrSampleRate = 16000
+
+// Transcriber 离线语音识别，xfyun.ASRClient 实现该接口
+type Transcriber interface {
+	ProcessAudio(sessionID string, audioData []byte) (string, error)
+}
+
+// TranscriptStore 转写存储
+type TranscriptStore interface {
+	Append(ctx context.Context, t *models.Transcript) error
+}
+
+// OfflineTranscriber 挂机后对录音做离线转写，双声道录音按声道区分说话方
+type OfflineTranscriber struct {
+	asr      Transcriber
+	store    TranscriptStore
+	speakers []string
+	segment  pcm.SegmentConfig
+}
+
+// NewOfflineTranscriber 创建离线转写任务，speakers 依次为左、右声道的说话方，单声道录音只使用第一个
+func NewOfflineTranscriber(asr Transcriber, store TranscriptStore, speakers ...string) *OfflineTranscriber {
+	return &OfflineTranscriber{
+		asr:      asr,
+		store:    store,
+		speakers: speakers,
+		segment:  pcm.DefaultSegmentConfig,
+	}
+}
+
+// Transcribe 转写一通电话的WAV录音，返回写入的转写片段（按起始时间排序）
+func (t *OfflineTranscriber) Transcribe(ctx context.Context, callUUID string, wav []byte) ([]*models.Transcript, error) {
+	audio, err := pcm.DecodeWAV(wav)
+	if err != nil {
+		return nil, fmt.Errorf("解析录音失败: %v", err)
+	}
+	if audio.SampleRate <= 0 {
+		return nil, fmt.Errorf("录音采样率无效: %d", audio.SampleRate)
+	}
+
+	channels := audio.Channels
+	if channels > len(t.speakers) {
+		channels = len(t.speakers)
+	}
+
+	var transcripts []*models.Transcript
+	for ch := 0; ch < channels; ch++ {
+		// 8kHz等电话录音先转换为ASR的采样率
+		channel, err := resample.Convert(audio.Channel(ch), audio.SampleRate, asrSampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("录音重采样失败: %v", err)
+		}
+		for i, seg := range pcm.Split(channel, asrSampleRate, t.segment) {
+			sessionID := fmt.Sprintf("%s-%d-%d", callUUID, ch, i)
+			text, err := t.asr.ProcessAudio(sessionID, seg.PCM)
+			if err != nil {
+				log.Printf("离线转写片段失败: %s, %v", sessionID, err)
+				continue
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			transcripts = append(transcripts, &models.Transcript{
+				CallUUID: callUUID,
+				Speaker:  t.speakers[ch],
+				Text:     text,
+				StartMs:  int(seg.Start / time.Millisecond),
+				EndMs:    int(seg.End / time.Millisecond),
+				Language: lang.Detect(text),
+			})
+		}
+	}
+
+	sort.SliceStable(transcripts, func(i, j int) bool {
+		return transcripts[i].StartMs < transcripts[j].StartMs
+	})
+	for _, tr := range transcripts {
+		if err := t.store.Append(ctx, tr); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("离线转写完成: %s, 声道数: %d, 片段数: %d", callUUID, audio.Channels, len(transcripts))
+	return transcripts, nil
+}
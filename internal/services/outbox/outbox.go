@@ -0,0 +1,279 @@
+// Package outbox 实现发件箱模式：外部推送与业务数据在同一事务中落库，由后台任务可靠投递
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"ai_dialer_mini/internal/database"
+)
+
+// 投递目标
+const (
+	DestinationWebhook = "webhook" // 通话事件Webhook
+	DestinationCRM     = "crm"     // CRM系统
+	DestinationSMS     = "sms"     // 短信网关
+)
+
+// 消息状态
+const (
+	StatusPending   = "pending"   // 待投递
+	StatusDelivered = "delivered" // 已投递
+	StatusFailed    = "failed"    // 超过最大次数，投递失败
+)
+
+// claimLease 消息被某个实例领取后的租约时间，实例崩溃后租约过期会被重新投递
+const claimLease = time.Minute
+
+// maxBackoff 重试退避的上限
+const maxBackoff = 10 * time.Minute
+
+// Message 待投递的消息
+type Message struct {
+	IdempotencyKey string      // 幂等键，相同键的消息只会入库一次，投递时作为请求头发送
+	Destination    string      // 投递目标，webhook或crm
+	TargetURL      string      // 投递地址
+	EventType      string      // 事件类型
+	Payload        interface{} // 消息内容，序列化为JSON
+
+	MaxAttempts int           // 最大投递次数，为0时使用全局配置
+	Backoff     time.Duration // 首次重试间隔，之后每次翻倍，为0时使用默认退避策略
+}
+
+// Config 发件箱配置
+type Config struct {
+	PollInterval time.Duration // 轮询间隔
+	BatchSize    int           // 每批处理的消息数
+	MaxAttempts  int           // 最大投递次数
+	Timeout      time.Duration // 单次HTTP投递超时
+}
+
+// Outbox 发件箱
+type Outbox struct {
+	db      *sql.DB
+	dialect database.Dialect
+	config  Config
+	client  *http.Client
+}
+
+// New 创建发件箱
+func New(db *sql.DB, config Config) *Outbox {
+	return &Outbox{
+		db:     db,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// SetDialect 设置数据库方言，使用SQLite时调用
+func (o *Outbox) SetDialect(dialect database.Dialect) {
+	o.dialect = dialect
+}
+
+// Enqueue 在调用方的事务中写入待投递消息，事务提交后消息才对投递任务可见
+// 相同幂等键的消息重复写入会被忽略
+func (o *Outbox) Enqueue(ctx context.Context, tx *sql.Tx, msg Message) error {
+	if msg.TargetURL == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("序列化发件箱消息失败: %v", err)
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx,
+		o.dialect.InsertIgnore()+` INTO outbox (idempotency_key, destination, target_url, event_type, payload, status, max_attempts, backoff_seconds, next_attempt_at, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		msg.IdempotencyKey, msg.Destination, msg.TargetURL, msg.EventType, payload, StatusPending,
+		msg.MaxAttempts, int(msg.Backoff/time.Second), now, now)
+	if err != nil {
+		return fmt.Errorf("写入发件箱失败: %v", err)
+	}
+	return nil
+}
+
+// Run 运行投递任务，直到ctx取消
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("发件箱投递任务已启动")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("发件箱投递任务已停止")
+			return
+		case <-ticker.C:
+			if err := o.deliverBatch(ctx); err != nil {
+				log.Printf("发件箱投递失败: %v", err)
+			}
+		}
+	}
+}
+
+// record 从数据库读取的发件箱记录
+type record struct {
+	id             int64
+	idempotencyKey string
+	targetURL      string
+	eventType      string
+	payload        []byte
+	attempts       int
+	maxAttempts    int
+	backoffSeconds int
+}
+
+// deliverBatch 领取并投递一批到期消息
+func (o *Outbox) deliverBatch(ctx context.Context) error {
+	records, err := o.claim(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		if ctx.Err() != nil {
+			return nil
+		}
+		deliverErr := o.deliver(ctx, rec)
+		if err := o.markResult(ctx, rec, deliverErr); err != nil {
+			log.Printf("更新发件箱消息状态失败: id=%d, %v", rec.id, err)
+		}
+	}
+	return nil
+}
+
+// claim 以行锁领取到期消息，并将其下次投递时间推迟一个租约周期，避免多实例重复投递
+func (o *Outbox) claim(ctx context.Context) ([]record, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, idempotency_key, target_url, event_type, payload, attempts, max_attempts, backoff_seconds FROM outbox
+		 WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?`+o.dialect.ForUpdateSkipLocked(),
+		StatusPending, time.Now(), o.config.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("查询发件箱失败: %v", err)
+	}
+
+	var records []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.id, &rec.idempotencyKey, &rec.targetURL, &rec.eventType, &rec.payload, &rec.attempts, &rec.maxAttempts, &rec.backoffSeconds); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取发件箱记录失败: %v", err)
+		}
+		records = append(records, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取发件箱记录失败: %v", err)
+	}
+
+	leaseUntil := time.Now().Add(claimLease)
+	for _, rec := range records {
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox SET next_attempt_at = ? WHERE id = ?`, leaseUntil, rec.id); err != nil {
+			return nil, fmt.Errorf("领取发件箱消息失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return records, nil
+}
+
+// deliver 通过HTTP投递单条消息，接收方可依据Idempotency-Key去重
+func (o *Outbox) deliver(ctx context.Context, rec record) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rec.targetURL, bytes.NewReader(rec.payload))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", rec.idempotencyKey)
+	req.Header.Set("X-Event-Type", rec.eventType)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("服务器返回错误: HTTP %d - %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// markResult 记录投递结果，失败时按消息自身或全局的重试策略安排重试
+func (o *Outbox) markResult(ctx context.Context, rec record, deliverErr error) error {
+	now := time.Now()
+	if deliverErr == nil {
+		_, err := o.db.ExecContext(ctx,
+			`UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = NULL, delivered_at = ? WHERE id = ?`,
+			StatusDelivered, now, rec.id)
+		return err
+	}
+
+	attempts := rec.attempts + 1
+	maxAttempts := o.config.MaxAttempts
+	if rec.maxAttempts > 0 {
+		maxAttempts = rec.maxAttempts
+	}
+	delay := Backoff(attempts)
+	if rec.backoffSeconds > 0 {
+		delay = RetryDelay(time.Duration(rec.backoffSeconds)*time.Second, attempts)
+	}
+
+	status := StatusPending
+	if attempts >= maxAttempts {
+		status = StatusFailed
+		log.Printf("发件箱消息投递失败次数已达上限: id=%d, key=%s, %v", rec.id, rec.idempotencyKey, deliverErr)
+	} else {
+		log.Printf("发件箱消息投递失败，稍后重试: id=%d, 第%d次, %v", rec.id, attempts, deliverErr)
+	}
+
+	_, err := o.db.ExecContext(ctx,
+		`UPDATE outbox SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?`,
+		status, attempts, deliverErr.Error(), now.Add(delay), rec.id)
+	return err
+}
+
+// Backoff 计算第n次失败后的重试间隔：2^n 秒，最长10分钟
+func Backoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return maxBackoff
+	}
+	delay := time.Duration(1<<uint(attempts)) * time.Second
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// RetryDelay 计算第n次失败后的重试间隔：base * 2^(n-1)，最长10分钟
+func RetryDelay(base time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := base
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
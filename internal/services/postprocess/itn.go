@@ -0,0 +1,130 @@
+package postprocess
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// minDigitRun 不带单位的中文数字逐位转换的最短长度，避免转换“一一”“三三两两”等词语中的数字，电话号码、验证码通常不少于3位
+const minDigitRun = 3
+
+// chineseDigits 中文数字，幺为读电话号码时的1
+var chineseDigits = map[rune]int64{
+	'零': 0, '〇': 0, '一': 1, '幺': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// chineseUnits 中文数字单位
+var chineseUnits = map[rune]int64{
+	'十': 10, '百': 100, '千': 1000, '万': 10000, '亿': 100000000,
+}
+
+// ITN 数字规整（逆文本标准化）：带单位的中文数字转为数值，如“一千五百”“两千三”转为“1500”“2300”；
+// 不带单位且不少于3位的中文数字逐位转换，如“幺三八零零”转为“13800”。
+// 单个数字和以单位开头的词语（如“一下”“十分”“千万”）不转换
+type ITN struct{}
+
+// Process 转换文本中的中文数字
+func (ITN) Process(ctx context.Context, text string) (string, error) {
+	runes := []rune(text)
+	var b strings.Builder
+	for i := 0; i < len(runes); {
+		if _, ok := chineseDigits[runes[i]]; !ok && runes[i] != '十' {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		hasUnit := false
+		for j < len(runes) {
+			if _, ok := chineseDigits[runes[j]]; ok {
+				j++
+			} else if _, ok := chineseUnits[runes[j]]; ok {
+				hasUnit = true
+				j++
+			} else {
+				break
+			}
+		}
+		b.WriteString(convertNumber(runes[i:j], hasUnit))
+		i = j
+	}
+	return b.String(), nil
+}
+
+// convertNumber 转换一段连续的中文数字，不能转换时原样返回
+func convertNumber(run []rune, hasUnit bool) string {
+	if !hasUnit {
+		if len(run) < minDigitRun {
+			return string(run)
+		}
+		digits := make([]byte, len(run))
+		for i, r := range run {
+			digits[i] = byte('0' + chineseDigits[r])
+		}
+		return string(digits)
+	}
+	if len(run) < 2 {
+		return string(run)
+	}
+	if n, ok := parseNumber(run); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	return string(run)
+}
+
+// parseNumber 解析带单位的中文数字，支持“两千三”“一万五”等省略末位单位的口语说法
+func parseNumber(run []rune) (int64, bool) {
+	var total, section int64
+	digit := int64(-1) // 尚未乘以单位的数字
+	var lastUnit int64 // 最近的单位，“零”之后为0
+	for i, r := range run {
+		if d, ok := chineseDigits[r]; ok {
+			if digit >= 0 {
+				return 0, false
+			}
+			if d == 0 {
+				lastUnit = 0
+				continue
+			}
+			digit = d
+			continue
+		}
+		u := chineseUnits[r]
+		switch {
+		case u < 10000:
+			if digit < 0 {
+				// 只有开头的“十”可以省略“一”，如“十五”
+				if u != 10 || i != 0 {
+					return 0, false
+				}
+				digit = 1
+			}
+			section += digit * u
+		default:
+			if digit > 0 {
+				section += digit
+			}
+			if section == 0 && total == 0 {
+				return 0, false
+			}
+			if u == 100000000 {
+				total = (total + section) * u
+			} else {
+				total += section * u
+			}
+			section = 0
+		}
+		digit = -1
+		lastUnit = u
+	}
+	if digit > 0 {
+		// 紧跟在单位后的末位数字省略了下一级单位，如“两千三”为2300、“一万五”为15000；有“零”时为个位，如“一千零三”
+		if lastUnit > 10 {
+			digit *= lastUnit / 10
+		}
+		section += digit
+	}
+	return total + section, true
+}
@@ -0,0 +1,94 @@
+// Package postprocess 识别结果文本后处理：最终识别结果在交给对话之前依次经过数字规整（ITN）、标点恢复、
+// 词语替换和屏蔽词过滤，各步骤实现Processor接口，可自由组合
+package postprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Processor 文本后处理步骤
+type Processor interface {
+	Process(ctx context.Context, text string) (string, error)
+}
+
+// Func 函数形式的处理步骤
+type Func func(ctx context.Context, text string) (string, error)
+
+// Process 调用函数
+func (f Func) Process(ctx context.Context, text string) (string, error) {
+	return f(ctx, text)
+}
+
+// Chain 按顺序执行的处理步骤，某一步失败时跳过该步骤继续执行后续步骤
+type Chain []Processor
+
+// Process 依次执行各步骤，返回处理后的文本和各失败步骤的错误
+func (c Chain) Process(ctx context.Context, text string) (string, error) {
+	var errs []error
+	for _, p := range c {
+		out, err := p.Process(ctx, text)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		text = out
+	}
+	return text, errors.Join(errs...)
+}
+
+// Punctuator 标点恢复服务，如punctuation.Client
+type Punctuator interface {
+	Punctuate(ctx context.Context, text string) (string, error)
+}
+
+// Punctuation 使用标点恢复服务的处理步骤
+func Punctuation(p Punctuator) Processor {
+	return Func(func(ctx context.Context, text string) (string, error) {
+		out, err := p.Punctuate(ctx, text)
+		if err != nil {
+			return "", fmt.Errorf("添加标点失败: %v", err)
+		}
+		return out, nil
+	})
+}
+
+// Config 识别结果后处理配置，标点恢复由 asr.punctuation 配置
+type Config struct {
+	ITN          bool              `yaml:"itn"`          // 将中文数字转为阿拉伯数字，如“一千五百块”转为“1500块”、“幺三八”转为“138”
+	Replacements map[string]string `yaml:"replacements"` // 词语替换，用于纠正常见的识别错误，如“智能外乎”替换为“智能外呼”
+	Censor       []string          `yaml:"censor"`       // 屏蔽词，逐字替换为mask
+	Mask         string            `yaml:"mask"`         // 屏蔽词的替换字符，默认*
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	for from := range c.Replacements {
+		if from == "" {
+			return fmt.Errorf("replacements: 被替换的词语不能为空")
+		}
+	}
+	for i, word := range c.Censor {
+		if word == "" {
+			return fmt.Errorf("censor[%d]: 屏蔽词不能为空", i)
+		}
+	}
+	return nil
+}
+
+// New 按配置创建处理链：数字规整、标点恢复、词语替换和屏蔽词过滤；punctuator为nil时不恢复标点，
+// 没有任何步骤时返回nil
+func New(config Config, punctuator Punctuator) Chain {
+	var chain Chain
+	if config.ITN {
+		chain = append(chain, ITN{})
+	}
+	if punctuator != nil {
+		chain = append(chain, Punctuation(punctuator))
+	}
+	if r := NewReplacer(config.Replacements, config.Censor, config.Mask); r != nil {
+		chain = append(chain, r)
+	}
+	return chain
+}
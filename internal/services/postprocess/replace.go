@@ -0,0 +1,54 @@
+package postprocess
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMask 屏蔽词的默认替换字符
+const defaultMask = "*"
+
+// Replacer 词语替换和屏蔽词过滤，较长的词语优先匹配
+type Replacer struct {
+	replacer *strings.Replacer
+}
+
+// NewReplacer 创建词语替换，屏蔽词逐字替换为mask（为空时使用*）；没有需要替换的词语时返回nil
+func NewReplacer(replacements map[string]string, censor []string, mask string) *Replacer {
+	if len(replacements) == 0 && len(censor) == 0 {
+		return nil
+	}
+	if mask == "" {
+		mask = defaultMask
+	}
+	pairs := make(map[string]string, len(replacements)+len(censor))
+	for from, to := range replacements {
+		pairs[from] = to
+	}
+	for _, word := range censor {
+		pairs[word] = strings.Repeat(mask, utf8.RuneCountInString(word))
+	}
+	words := make([]string, 0, len(pairs))
+	for from := range pairs {
+		words = append(words, from)
+	}
+	// strings.Replacer在同一位置按参数顺序匹配，较长的词语在前，避免被其前缀抢先替换
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	oldnew := make([]string, 0, 2*len(words))
+	for _, from := range words {
+		oldnew = append(oldnew, from, pairs[from])
+	}
+	return &Replacer{replacer: strings.NewReplacer(oldnew...)}
+}
+
+// Process 替换词语
+func (r *Replacer) Process(ctx context.Context, text string) (string, error) {
+	return r.replacer.Replace(text), nil
+}
@@ -0,0 +1,184 @@
+// Package prompt 对话提示词模板：按活动或会话选用不同的AI人设、业务知识和历史窗口，
+// 模板使用Go text/template语法，运营人员修改配置即可调整话术，无需重新编译
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// DefaultTemplate 默认提示词模板，未配置人设和业务知识时与按“用户:/助手:”逐条拼接历史的结果一致
+const DefaultTemplate = `{{if .Persona}}{{.Persona}}
+{{end}}{{range .Knowledge}}业务知识: {{.}}
+{{end}}{{range .History}}{{speaker .Role}}: {{.Content}}
+{{end}}`
+
+// ErrUnknownProfile 未定义的提示词配置
+var ErrUnknownProfile = errors.New("未定义的提示词配置")
+
+// speakers 历史消息角色在提示词中的称呼，其他角色的消息不写入提示词
+var speakers = map[string]string{
+	models.RoleUser:      "用户",
+	models.RoleAssistant: "助手",
+	models.RoleSystem:    "背景信息",
+	models.RoleTool:      "工具结果",
+}
+
+// Profile 提示词配置，命名配置中未设置的字段沿用默认配置
+type Profile struct {
+	Persona       string   `yaml:"persona"`        // AI人设，如“你是XX银行的信用卡客服小美，说话简洁礼貌”
+	Knowledge     []string `yaml:"knowledge"`      // 业务知识片段，如套餐价格、办理条件
+	HistoryWindow int      `yaml:"history_window"` // 提示词中保留的最近历史消息条数，为0时保留全部
+	Template      string   `yaml:"template"`       // 提示词模板，可用 .Persona .Knowledge .History .Input 和 speaker 函数
+}
+
+// Config 提示词配置
+type Config struct {
+	Profile   `yaml:",inline"`   // 默认配置
+	Profiles  map[string]Profile `yaml:"profiles"`  // 命名的提示词配置，活动和会话按名称选用
+	Campaigns map[string]string  `yaml:"campaigns"` // 按活动ID指定提示词配置名称
+}
+
+// Validate 校验提示词配置，模板语法错误和引用未定义的配置名称时返回错误
+func (c Config) Validate() error {
+	_, err := New(c)
+	return err
+}
+
+// Data 渲染提示词模板的数据
+type Data struct {
+	Persona   string           // AI人设
+	Knowledge []string         // 业务知识片段
+	History   []models.Message // 历史窗口内的消息，包含本轮用户消息
+	Input     string           // 本轮用户消息
+}
+
+// compiled 解析后的提示词配置
+type compiled struct {
+	profile  Profile
+	template *template.Template
+}
+
+// Builder 按配置构建提示词
+type Builder struct {
+	defaults  compiled
+	profiles  map[string]compiled
+	campaigns map[string]string
+}
+
+// New 解析所有提示词模板
+func New(config Config) (*Builder, error) {
+	defaults, err := compile("", config.Profile)
+	if err != nil {
+		return nil, err
+	}
+	b := &Builder{
+		defaults:  defaults,
+		profiles:  make(map[string]compiled, len(config.Profiles)),
+		campaigns: config.Campaigns,
+	}
+	for name, profile := range config.Profiles {
+		c, err := compile(name, merge(config.Profile, profile))
+		if err != nil {
+			return nil, err
+		}
+		b.profiles[name] = c
+	}
+	for campaignID, name := range config.Campaigns {
+		if _, ok := b.profiles[name]; !ok {
+			return nil, fmt.Errorf("campaigns.%s: %w: %s", campaignID, ErrUnknownProfile, name)
+		}
+	}
+	return b, nil
+}
+
+// merge 命名配置未设置的字段沿用默认配置
+func merge(base, profile Profile) Profile {
+	if profile.Persona == "" {
+		profile.Persona = base.Persona
+	}
+	if profile.Knowledge == nil {
+		profile.Knowledge = base.Knowledge
+	}
+	if profile.HistoryWindow == 0 {
+		profile.HistoryWindow = base.HistoryWindow
+	}
+	if profile.Template == "" {
+		profile.Template = base.Template
+	}
+	return profile
+}
+
+// compile 解析提示词模板，未配置模板时使用默认模板
+func compile(name string, profile Profile) (compiled, error) {
+	var prefix string
+	if name != "" {
+		prefix = "profiles." + name + "."
+	}
+	if profile.HistoryWindow < 0 {
+		return compiled{}, fmt.Errorf("%shistory_window: 不能为负数", prefix)
+	}
+	text := profile.Template
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New(prefix + "template").
+		Funcs(template.FuncMap{"speaker": func(role string) string { return speakers[role] }}).
+		Parse(text)
+	if err != nil {
+		return compiled{}, fmt.Errorf("%stemplate: 模板语法错误: %v", prefix, err)
+	}
+	return compiled{profile: profile, template: tmpl}, nil
+}
+
+// Has 是否定义了指定名称的提示词配置
+func (b *Builder) Has(name string) bool {
+	_, ok := b.profiles[name]
+	return ok
+}
+
+// Build 构建提示词：依次使用会话指定的配置、活动对应的配置和默认配置，
+// history的最后一条为本轮用户消息
+func (b *Builder) Build(profileName, campaignID string, history []models.Message) (string, error) {
+	c := b.resolve(profileName, campaignID)
+
+	var messages []models.Message
+	for _, msg := range history {
+		if _, ok := speakers[msg.Role]; ok {
+			messages = append(messages, msg)
+		}
+	}
+	if n := c.profile.HistoryWindow; n > 0 && len(messages) > n {
+		messages = messages[len(messages)-n:]
+	}
+
+	data := Data{
+		Persona:   c.profile.Persona,
+		Knowledge: c.profile.Knowledge,
+		History:   messages,
+	}
+	if n := len(history); n > 0 && history[n-1].Role == models.RoleUser {
+		data.Input = history[n-1].Content
+	}
+
+	var prompt strings.Builder
+	if err := c.template.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("渲染提示词失败: %v", err)
+	}
+	return prompt.String(), nil
+}
+
+// resolve 选择提示词配置
+func (b *Builder) resolve(profileName, campaignID string) compiled {
+	if c, ok := b.profiles[profileName]; ok {
+		return c
+	}
+	if c, ok := b.profiles[b.campaigns[campaignID]]; ok {
+		return c
+	}
+	return b.defaults
+}
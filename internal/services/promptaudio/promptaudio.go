@@ -0,0 +1,312 @@
+// Package promptaudio 预录提示音：运营人员上传问候语、法律声明等录音，上传时校验格式并转换为8kHz和16kHz两份，
+// 话术中以 audio:名称 引用时播放提示音而不是语音合成
+package promptaudio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/audio/resample"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
+)
+
+// RefPrefix 话术中引用提示音的前缀，如 "audio:greeting" 或 "audio:greeting|您好，这里是XX银行"，
+// 竖线后的文本在提示音不可用时改用语音合成
+const RefPrefix = "audio:"
+
+// SampleRates 提示音保存的采样率
+var SampleRates = []int{8000, 16000}
+
+// 默认配置
+const (
+	defaultPrefix      = "prompts"
+	defaultMaxSize     = 10 << 20 // 10MB
+	defaultMaxDuration = 2 * time.Minute
+)
+
+// 错误
+var (
+	ErrNotFound     = errors.New("提示音不存在")
+	ErrInvalidName  = errors.New("提示音名称无效，只能包含小写字母、数字、下划线和连字符，长度1~64")
+	ErrInvalidAudio = errors.New("提示音格式无效")
+)
+
+// namePattern 提示音名称
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// Config 提示音配置，提示音保存在storage配置的对象存储中
+type Config struct {
+	Enabled     bool          `yaml:"enabled"`
+	Prefix      string        `yaml:"prefix"`       // 对象键前缀，默认prompts
+	MaxSize     int64         `yaml:"max_size"`     // 上传文件的最大字节数
+	MaxDuration time.Duration `yaml:"max_duration"` // 提示音的最长时长
+}
+
+// Validate 校验提示音配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxSize < 0 {
+		return fmt.Errorf("max_size: 不能为负数")
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("max_duration: 不能为负数")
+	}
+	if strings.Trim(c.Prefix, "/") != c.Prefix {
+		return fmt.Errorf("prefix: 不能以/开头或结尾")
+	}
+	return nil
+}
+
+// Prompt 提示音元数据
+type Prompt struct {
+	Name        string    `json:"name"`
+	Text        string    `json:"text"`                  // 提示音的文字内容，播放后记入对话历史
+	Description string    `json:"description,omitempty"` // 用途说明
+	DurationMs  int64     `json:"duration_ms"`
+	SampleRate  int       `json:"sample_rate"` // 上传文件的原始采样率
+	Channels    int       `json:"channels"`    // 上传文件的原始声道数，多声道时只保留第一个声道
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Library 提示音库，元数据以索引对象保存，提示音音频按采样率分别保存并在进程内缓存
+type Library struct {
+	store  storage.Store
+	config Config
+
+	mu     sync.Mutex // 串行化索引的读改写
+	cacheM sync.RWMutex
+	cache  map[string]cachedAudio // 名称/采样率 -> 音频
+}
+
+// cachedAudio 缓存的提示音音频，上传时间与索引不一致时说明提示音已被其他进程替换，需重新读取
+type cachedAudio struct {
+	audio     *tts.Audio
+	createdAt time.Time
+}
+
+// New 创建提示音库
+func New(store storage.Store, config Config) *Library {
+	if config.Prefix == "" {
+		config.Prefix = defaultPrefix
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultMaxSize
+	}
+	if config.MaxDuration <= 0 {
+		config.MaxDuration = defaultMaxDuration
+	}
+	return &Library{store: store, config: config, cache: make(map[string]cachedAudio)}
+}
+
+// MaxSize 上传文件的最大字节数
+func (l *Library) MaxSize() int64 {
+	return l.config.MaxSize
+}
+
+// ParseRef 解析话术中的提示音引用，返回提示音名称和提示音不可用时改用语音合成的文本
+func ParseRef(text string) (name, fallback string, ok bool) {
+	if !strings.HasPrefix(text, RefPrefix) {
+		return "", "", false
+	}
+	name, fallback, _ = strings.Cut(strings.TrimPrefix(text, RefPrefix), "|")
+	return strings.TrimSpace(name), strings.TrimSpace(fallback), true
+}
+
+// Upload 上传提示音：只接受16位PCM的WAV文件，转换为单声道8kHz和16kHz后保存，同名提示音被替换
+func (l *Library) Upload(ctx context.Context, prompt Prompt, data []byte) (*Prompt, error) {
+	if !namePattern.MatchString(prompt.Name) {
+		return nil, ErrInvalidName
+	}
+	if strings.TrimSpace(prompt.Text) == "" {
+		return nil, fmt.Errorf("%w: 需填写提示音的文字内容", ErrInvalidAudio)
+	}
+	if int64(len(data)) > l.config.MaxSize {
+		return nil, fmt.Errorf("%w: 文件超过%d字节", ErrInvalidAudio, l.config.MaxSize)
+	}
+	wav, err := pcm.DecodeWAV(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudio, err)
+	}
+	if wav.SampleRate < 8000 || wav.SampleRate > 48000 || wav.Channels < 1 {
+		return nil, fmt.Errorf("%w: 不支持的采样率%d或声道数%d", ErrInvalidAudio, wav.SampleRate, wav.Channels)
+	}
+	duration := wav.Duration()
+	if duration == 0 {
+		return nil, fmt.Errorf("%w: 音频为空", ErrInvalidAudio)
+	}
+	if duration > l.config.MaxDuration {
+		return nil, fmt.Errorf("%w: 时长%v超过%v", ErrInvalidAudio, duration.Round(time.Second), l.config.MaxDuration)
+	}
+
+	prompt.DurationMs = duration.Milliseconds()
+	prompt.SampleRate = wav.SampleRate
+	prompt.Channels = wav.Channels
+	prompt.CreatedAt = time.Now()
+	mono := wav.Channel(0)
+	for _, rate := range SampleRates {
+		converted, err := resample.Convert(mono, wav.SampleRate, rate)
+		if err != nil {
+			return nil, fmt.Errorf("转换提示音采样率失败: %v", err)
+		}
+		audio := &tts.Audio{PCM: converted, SampleRate: rate, Channels: 1}
+		if err := l.store.Put(ctx, l.audioKey(prompt.Name, rate), audio.WAV(), "audio/wav"); err != nil {
+			return nil, fmt.Errorf("保存提示音失败: %v", err)
+		}
+		l.cacheM.Lock()
+		l.cache[cacheKey(prompt.Name, rate)] = cachedAudio{audio: audio, createdAt: prompt.CreatedAt}
+		l.cacheM.Unlock()
+	}
+
+	err = l.updateIndex(ctx, func(index map[string]Prompt) {
+		index[prompt.Name] = prompt
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// List 返回所有提示音，按名称排序
+func (l *Library) List(ctx context.Context) ([]Prompt, error) {
+	index, err := l.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prompts := make([]Prompt, 0, len(index))
+	for _, prompt := range index {
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts, nil
+}
+
+// Get 返回提示音元数据，不存在时返回ErrNotFound
+func (l *Library) Get(ctx context.Context, name string) (*Prompt, error) {
+	index, err := l.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prompt, ok := index[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return &prompt, nil
+}
+
+// Load 读取提示音及指定采样率的音频，sampleRate不是8000时使用16kHz的音频；音频在进程内缓存，提示音被替换后重新读取
+func (l *Library) Load(ctx context.Context, name string, sampleRate int) (*tts.Audio, *Prompt, error) {
+	prompt, err := l.Get(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sampleRate != 8000 {
+		sampleRate = 16000
+	}
+
+	l.cacheM.RLock()
+	cached, ok := l.cache[cacheKey(name, sampleRate)]
+	l.cacheM.RUnlock()
+	if ok && cached.createdAt.Equal(prompt.CreatedAt) {
+		return cached.audio, prompt, nil
+	}
+
+	data, err := l.store.Get(ctx, l.audioKey(name, sampleRate))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取提示音失败: %v", err)
+	}
+	wav, err := pcm.DecodeWAV(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析提示音失败: %v", err)
+	}
+	audio := &tts.Audio{PCM: wav.PCM, SampleRate: wav.SampleRate, Channels: wav.Channels}
+	l.cacheM.Lock()
+	l.cache[cacheKey(name, sampleRate)] = cachedAudio{audio: audio, createdAt: prompt.CreatedAt}
+	l.cacheM.Unlock()
+	return audio, prompt, nil
+}
+
+// Delete 删除提示音，不存在时返回ErrNotFound
+func (l *Library) Delete(ctx context.Context, name string) error {
+	var found bool
+	err := l.updateIndex(ctx, func(index map[string]Prompt) {
+		_, found = index[name]
+		delete(index, name)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	l.cacheM.Lock()
+	for _, rate := range SampleRates {
+		delete(l.cache, cacheKey(name, rate))
+	}
+	l.cacheM.Unlock()
+	for _, rate := range SampleRates {
+		if err := l.store.Delete(ctx, l.audioKey(name, rate)); err != nil {
+			return fmt.Errorf("删除提示音文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// loadIndex 读取提示音索引，索引不存在时返回空索引
+func (l *Library) loadIndex(ctx context.Context) (map[string]Prompt, error) {
+	index := make(map[string]Prompt)
+	data, err := l.store.Get(ctx, l.config.Prefix+"/index.json")
+	if errors.Is(err, storage.ErrNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取提示音索引失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("解析提示音索引失败: %v", err)
+	}
+	return index, nil
+}
+
+// updateIndex 修改并保存提示音索引
+func (l *Library) updateIndex(ctx context.Context, fn func(index map[string]Prompt)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	index, err := l.loadIndex(ctx)
+	if err != nil {
+		return err
+	}
+	fn(index)
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("序列化提示音索引失败: %v", err)
+	}
+	if err := l.store.Put(ctx, l.config.Prefix+"/index.json", data, "application/json"); err != nil {
+		return fmt.Errorf("保存提示音索引失败: %v", err)
+	}
+	return nil
+}
+
+// audioKey 提示音音频的对象键
+func (l *Library) audioKey(name string, sampleRate int) string {
+	return fmt.Sprintf("%s/%s/%d.wav", l.config.Prefix, name, sampleRate)
+}
+
+// cacheKey 缓存键
+func cacheKey(name string, sampleRate int) string {
+	return fmt.Sprintf("%s/%d", name, sampleRate)
+}
@@ -0,0 +1,268 @@
+// Package qa 通话自动质检：按评分表让大模型为完成的通话转写逐项打分，保存各项得分并提供汇总
+package qa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// ErrNoTranscript 通话没有转写，无法质检
+var ErrNoTranscript = errors.New("通话没有转写")
+
+// maxScore 单项满分
+const maxScore = 100
+
+// Criterion 评分项
+type Criterion struct {
+	Key         string  `yaml:"key"`         // 评分项标识，保存在质检结果中
+	Description string  `yaml:"description"` // 评分标准，写入评分提示词
+	Weight      float64 `yaml:"weight"`      // 计算总分时的权重，为0时按1计
+}
+
+// DefaultScorecard 未配置评分表时使用的评分项
+var DefaultScorecard = []Criterion{
+	{Key: "greeting", Description: "开场是否礼貌问候，并说明来电身份和目的"},
+	{Key: "identity_verified", Description: "沟通具体业务前是否核实了客户身份"},
+	{Key: "objection_handled", Description: "客户提出异议或拒绝时是否耐心回应并给出合理解释；客户没有提出异议时不适用"},
+}
+
+// Config 自动质检配置
+type Config struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval"`   // 扫描待质检通话的间隔
+	Delay     time.Duration `yaml:"delay"`      // 挂机后等待多久再质检，留出离线转写的时间
+	Lookback  time.Duration `yaml:"lookback"`   // 只自动质检这段时间内挂机的通话
+	BatchSize int           `yaml:"batch_size"` // 每次扫描最多质检的通话数
+	PassScore int           `yaml:"pass_score"` // 及格分（0-100），总分和各评分项均按此判断
+	Scorecard []Criterion   `yaml:"scorecard"`  // 评分表，为空时使用DefaultScorecard
+}
+
+// Validate 校验质检配置
+func (c Config) Validate() error {
+	if c.PassScore < 0 || c.PassScore > maxScore {
+		return fmt.Errorf("pass_score: 必须在0到%d之间", maxScore)
+	}
+	seen := make(map[string]bool, len(c.Scorecard))
+	for i, criterion := range c.Scorecard {
+		if criterion.Key == "" || criterion.Description == "" {
+			return fmt.Errorf("scorecard[%d]: 必须配置key和description", i)
+		}
+		if seen[criterion.Key] {
+			return fmt.Errorf("scorecard[%d]: 评分项 %s 重复", i, criterion.Key)
+		}
+		seen[criterion.Key] = true
+		if criterion.Weight < 0 {
+			return fmt.Errorf("scorecard[%d]: weight不能为负数", i)
+		}
+	}
+	return nil
+}
+
+// Generator 大模型文本生成，services.LLMClient满足该接口
+type Generator interface {
+	GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error)
+}
+
+// Evaluator 通话质检，定期为挂机的通话评分，也可手动重新评估
+type Evaluator struct {
+	store     *repositories.Store
+	llm       Generator
+	config    Config
+	scorecard []Criterion
+}
+
+// New 创建通话质检
+func New(store *repositories.Store, llm Generator, config Config) *Evaluator {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 10
+	}
+	scorecard := config.Scorecard
+	if len(scorecard) == 0 {
+		scorecard = DefaultScorecard
+	}
+	return &Evaluator{store: store, llm: llm, config: config, scorecard: scorecard}
+}
+
+// Run 定期质检挂机超过等待时间的通话，直到ctx取消
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.evaluatePending(ctx); err != nil {
+			log.Printf("自动质检失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluatePending 质检一批待质检的通话
+func (e *Evaluator) evaluatePending(ctx context.Context) error {
+	until := time.Now().Add(-e.config.Delay)
+	uuids, err := e.store.QA.Pending(ctx, until.Add(-e.config.Lookback), until, e.config.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, uuid := range uuids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		eval, err := e.Evaluate(ctx, uuid)
+		if err != nil {
+			log.Printf("通话质检失败 - UUID: %s: %v", uuid, err)
+			continue
+		}
+		if eval.Status == models.QAStatusFailed {
+			log.Printf("通话质检评分失败 - UUID: %s: %s", uuid, eval.Error)
+		}
+	}
+	return nil
+}
+
+// Evaluate 按评分表评估通话并保存结果，覆盖之前的结果；
+// 大模型评分失败时保存为失败状态，不再自动重试，可手动重新评估
+func (e *Evaluator) Evaluate(ctx context.Context, callUUID string) (*models.QAEvaluation, error) {
+	transcripts, err := e.store.Transcripts.ListByCall(ctx, callUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(transcripts) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoTranscript, callUUID)
+	}
+
+	eval := &models.QAEvaluation{CallUUID: callUUID, Status: models.QAStatusDone, Scores: []*models.QAScore{}}
+	if err := e.grade(ctx, transcripts, eval); err != nil {
+		eval.Status = models.QAStatusFailed
+		eval.Error = err.Error()
+		eval.Scores = []*models.QAScore{}
+		eval.TotalScore = nil
+		eval.Passed = false
+	}
+	eval.EvaluatedAt = time.Now()
+
+	err = e.store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		return uow.QA.Save(ctx, eval)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eval, nil
+}
+
+// Get 查询通话的质检结果
+func (e *Evaluator) Get(ctx context.Context, callUUID string) (*models.QAEvaluation, error) {
+	return e.store.QA.Get(ctx, callUUID)
+}
+
+// Dashboard 汇总质检结果
+func (e *Evaluator) Dashboard(ctx context.Context, q models.QADashboardQuery) (*models.QADashboard, error) {
+	return e.store.QA.Dashboard(ctx, q)
+}
+
+// grade 调用大模型逐项评分，计算总分
+func (e *Evaluator) grade(ctx context.Context, transcripts []*models.Transcript, eval *models.QAEvaluation) error {
+	resp, err := e.llm.GenerateContext(ctx, e.prompt(transcripts), ollama.Options{Temperature: 0.1, MaxTokens: 1024})
+	if err != nil {
+		return fmt.Errorf("大模型评分失败: %v", err)
+	}
+	grades, err := parseGrades(resp.Response)
+	if err != nil {
+		return err
+	}
+
+	var weighted, weights float64
+	allPassed := true
+	for _, criterion := range e.scorecard {
+		g, ok := grades[criterion.Key]
+		if !ok {
+			return fmt.Errorf("评分结果缺少评分项: %s", criterion.Key)
+		}
+		score := &models.QAScore{Criterion: criterion.Key, Score: g.Score, Reason: g.Reason}
+		if g.Score != nil {
+			if *g.Score < 0 || *g.Score > maxScore {
+				return fmt.Errorf("评分项 %s 的得分超出范围: %d", criterion.Key, *g.Score)
+			}
+			score.Passed = *g.Score >= e.config.PassScore
+			allPassed = allPassed && score.Passed
+
+			weight := criterion.Weight
+			if weight == 0 {
+				weight = 1
+			}
+			weighted += weight * float64(*g.Score)
+			weights += weight
+		}
+		eval.Scores = append(eval.Scores, score)
+	}
+
+	// 所有评分项都不适用时没有总分，视为及格
+	eval.Passed = allPassed
+	if weights > 0 {
+		total := weighted / weights
+		eval.TotalScore = &total
+		eval.Passed = allPassed && total >= float64(e.config.PassScore)
+	}
+	return nil
+}
+
+// speakerNames 转写说话方在评分提示词中的称呼
+var speakerNames = map[string]string{
+	models.SpeakerCustomer: "客户",
+	models.SpeakerAI:       "AI客服",
+	models.SpeakerAgent:    "人工坐席",
+}
+
+// prompt 构建评分提示词，要求大模型只返回JSON
+func (e *Evaluator) prompt(transcripts []*models.Transcript) string {
+	var b strings.Builder
+	b.WriteString("你是呼叫中心的质检员，请根据评分标准为下面这通电话中客服一方的表现逐项打分。\n\n评分标准:\n")
+	for _, criterion := range e.scorecard {
+		fmt.Fprintf(&b, "- %s: %s\n", criterion.Key, criterion.Description)
+	}
+	b.WriteString("\n通话记录:\n")
+	for _, t := range transcripts {
+		name, ok := speakerNames[t.Speaker]
+		if !ok {
+			name = t.Speaker
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, t.Text)
+	}
+	fmt.Fprintf(&b, "\n每项得分为0到%d的整数，评分标准说明不适用且本通电话确实不涉及时得分为null。"+
+		"只返回JSON，不要输出其他内容，格式为: {\"评分项\": {\"score\": 得分, \"reason\": \"简要理由\"}}\n", maxScore)
+	return b.String()
+}
+
+// llmGrade 大模型返回的单项评分
+type llmGrade struct {
+	Score  *int   `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// parseGrades 从大模型回复中解析评分JSON，容忍前后多余的说明文字和代码块标记
+func parseGrades(text string) (map[string]llmGrade, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("评分结果不是JSON: %s", text)
+	}
+	var grades map[string]llmGrade
+	if err := json.Unmarshal([]byte(text[start:end+1]), &grades); err != nil {
+		return nil, fmt.Errorf("解析评分结果失败: %v", err)
+	}
+	return grades, nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ai_dialer_mini/internal/clients/hlr"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// ErrNumberUnreachable 号码为空号或已停机
+var ErrNumberUnreachable = errors.New("号码为空号或已停机")
+
+// ReachabilityService 拨打前号码状态检查，过滤空号和停机号码，减少无效拨打
+// 查询服务异常时放行，不影响正常外呼
+type ReachabilityService struct {
+	client        hlr.Client
+	store         *repositories.Store
+	maxAge        time.Duration
+	onUnreachable UnreachableHandler
+}
+
+// UnreachableHandler 在记录查询结果的事务中将线索标记为unreachable，
+// 外呼任务通过它同时推送线索状态变化
+type UnreachableHandler func(ctx context.Context, uow *repositories.UnitOfWork, lead *models.Lead) error
+
+// NewReachabilityService 创建号码状态检查服务，store为nil时不记录查询结果
+func NewReachabilityService(client hlr.Client, store *repositories.Store, maxAge time.Duration) *ReachabilityService {
+	return &ReachabilityService{
+		client: client,
+		store:  store,
+		maxAge: maxAge,
+	}
+}
+
+// SetUnreachableHandler 设置线索标记为unreachable的处理，未设置时只更新线索状态
+func (s *ReachabilityService) SetUnreachableHandler(handler UnreachableHandler) {
+	s.onUnreachable = handler
+}
+
+// CheckNumber 检查号码，空号或停机时返回ErrNumberUnreachable
+// 服务未启用（nil）或查询失败时直接放行，此时返回结果的CheckedAt为零值
+func (s *ReachabilityService) CheckNumber(ctx context.Context, phone string) (models.NumberLookup, error) {
+	if s == nil {
+		return models.NumberLookup{Status: models.NumberStatusUnknown}, nil
+	}
+
+	lookup, err := s.client.Lookup(ctx, phone)
+	if err != nil {
+		log.Printf("警告: 号码状态查询失败，按可接通处理: %s, %v", phone, err)
+		return models.NumberLookup{Status: models.NumberStatusUnknown}, nil
+	}
+	if !lookup.Reachable() {
+		return lookup, ErrNumberUnreachable
+	}
+	return lookup, nil
+}
+
+// CheckLead 检查线索号码并记录查询结果，有效期内的结果不重复查询
+// 空号或停机时将线索标记为unreachable，不再安排拨打，并返回ErrNumberUnreachable
+func (s *ReachabilityService) CheckLead(ctx context.Context, lead *models.Lead) error {
+	if s == nil {
+		return nil
+	}
+
+	if lead.Lookup != nil && s.maxAge > 0 && time.Since(lead.Lookup.CheckedAt) < s.maxAge {
+		if lead.Lookup.Reachable() {
+			return nil
+		}
+		// 已领取待拨打的线索也要标记，否则会停留在拨打中状态
+		if lead.Status != models.LeadStatusUnreachable && s.store != nil {
+			if err := s.transaction(ctx, lead, func(uow *repositories.UnitOfWork) error {
+				return s.markUnreachable(ctx, uow, lead)
+			}); err != nil {
+				return fmt.Errorf("记录号码状态失败: %v", err)
+			}
+		}
+		return ErrNumberUnreachable
+	}
+
+	lookup, checkErr := s.CheckNumber(ctx, lead.Phone)
+	if lookup.CheckedAt.IsZero() || s.store == nil {
+		return checkErr
+	}
+
+	err := s.transaction(ctx, lead, func(uow *repositories.UnitOfWork) error {
+		if err := uow.Leads.RecordLookup(ctx, lead.ID, lookup); err != nil {
+			return err
+		}
+		if checkErr == nil {
+			return nil
+		}
+		return s.markUnreachable(ctx, uow, lead)
+	})
+	if err != nil {
+		return fmt.Errorf("记录号码状态失败: %v", err)
+	}
+	lead.Lookup = &lookup
+	return checkErr
+}
+
+// transaction 在事务中更新线索，事务失败时恢复线索状态，调用方据此判断线索是否已移出拨打队列
+func (s *ReachabilityService) transaction(ctx context.Context, lead *models.Lead, fn func(uow *repositories.UnitOfWork) error) error {
+	status, next := lead.Status, lead.NextAttemptAt
+	if err := s.store.Transaction(ctx, fn); err != nil {
+		lead.Status, lead.NextAttemptAt = status, next
+		return err
+	}
+	return nil
+}
+
+// markUnreachable 将线索标记为unreachable，不再安排拨打
+func (s *ReachabilityService) markUnreachable(ctx context.Context, uow *repositories.UnitOfWork, lead *models.Lead) error {
+	if s.onUnreachable != nil {
+		return s.onUnreachable(ctx, uow, lead)
+	}
+	if err := uow.Leads.UpdateStatus(ctx, lead.ID, models.LeadStatusUnreachable, nil); err != nil {
+		return err
+	}
+	lead.Status = models.LeadStatusUnreachable
+	lead.NextAttemptAt = nil
+	return nil
+}
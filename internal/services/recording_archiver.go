@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/consent"
+)
+
+// recordingQueueSize 待归档录音队列长度
+const recordingQueueSize = 256
+
+// defaultRecordingFilename 默认的录音文件名模板
+const defaultRecordingFilename = "{uuid}"
+
+// RecordingIndex 录音索引，repositories.RecordingRepo 实现该接口
+type RecordingIndex interface {
+	Save(ctx context.Context, rec *models.Recording) error
+}
+
+// RecordingArchiver 录音归档任务，通话挂断后将FreeSWITCH写出的WAV录音转码为配置的格式并存入对象存储，
+// 同时写入记录主被叫号码和通话时间的JSON元数据文件
+type RecordingArchiver struct {
+	store  storage.Store
+	codec  codec.Codec
+	config config.RecordingConfig
+	queue  chan models.CDR
+
+	transcriber *OfflineTranscriber
+	index       RecordingIndex
+}
+
+// NewRecordingArchiver 创建录音归档任务
+func NewRecordingArchiver(store storage.Store, c codec.Codec, cfg config.RecordingConfig) *RecordingArchiver {
+	return &RecordingArchiver{
+		store:  store,
+		codec:  c,
+		config: cfg,
+		queue:  make(chan models.CDR, recordingQueueSize),
+	}
+}
+
+// SetIndex 设置录音索引，设置后归档的录音可通过录音接口查询和下载
+func (a *RecordingArchiver) SetIndex(index RecordingIndex) {
+	a.index = index
+}
+
+// SetTranscriber 设置离线转写任务，设置后归档前先转写录音，转写失败不影响归档
+func (a *RecordingArchiver) SetTranscriber(transcriber *OfflineTranscriber) {
+	a.transcriber = transcriber
+}
+
+// Enqueue 加入待归档队列，cdr为挂断时的通话信息；队列已满时丢弃并记录日志，录音文件保留在原目录
+func (a *RecordingArchiver) Enqueue(cdr models.CDR) {
+	select {
+	case a.queue <- cdr:
+	default:
+		log.Printf("警告: 录音归档队列已满，跳过: %s", cdr.CallUUID)
+	}
+}
+
+// Run 启动归档工作协程，直到ctx取消
+func (a *RecordingArchiver) Run(ctx context.Context) {
+	workers := a.config.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case cdr := <-a.queue:
+					if err := a.Archive(ctx, cdr); err != nil {
+						log.Printf("归档录音失败: %s, %v", cdr.CallUUID, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Archive 归档一通电话的录音和元数据，没有录音文件时直接返回；客户拒绝录音的通话不归档并删除原始录音
+func (a *RecordingArchiver) Archive(ctx context.Context, cdr models.CDR) error {
+	callUUID := cdr.CallUUID
+	source := filepath.Join(a.config.Dir, callUUID+".wav")
+	if cdr.RecordingConsent == string(consent.StatusRefused) {
+		if err := os.Remove(source); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("删除拒绝授权的录音失败: %v", err)
+		}
+		log.Printf("客户拒绝录音，录音不归档: %s", callUUID)
+		return nil
+	}
+	wav, err := os.ReadFile(source)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取录音失败: %v", err)
+	}
+
+	if a.transcriber != nil {
+		if _, err := a.transcriber.Transcribe(ctx, callUUID, wav); err != nil {
+			log.Printf("离线转写失败: %s, %v", callUUID, err)
+		}
+	}
+
+	data, err := a.codec.Encode(ctx, wav)
+	if err != nil {
+		return err
+	}
+
+	key := a.Key(cdr)
+	if err := a.store.Put(ctx, key, data, a.codec.ContentType()); err != nil {
+		return fmt.Errorf("保存录音失败: %v", err)
+	}
+	log.Printf("录音已归档: %s (%d -> %d 字节)", key, len(wav), len(data))
+
+	rec := newRecording(cdr, key, a.codec.ContentType(), int64(len(data)), wav)
+	meta, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("生成录音元数据失败: %v", err)
+	}
+	if err := a.store.Put(ctx, MetadataKey(key), meta, "application/json"); err != nil {
+		return fmt.Errorf("保存录音元数据失败: %v", err)
+	}
+	if a.index != nil {
+		if err := a.index.Save(ctx, rec); err != nil {
+			return err
+		}
+	}
+
+	if a.config.DeleteSource {
+		if err := os.Remove(source); err != nil {
+			log.Printf("删除原始录音失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// newRecording 根据通话信息和原始录音生成录音元数据
+func newRecording(cdr models.CDR, key, contentType string, size int64, wav []byte) *models.Recording {
+	rec := &models.Recording{
+		CallUUID:    cdr.CallUUID,
+		Key:         key,
+		ContentType: contentType,
+		Size:        size,
+		Caller:      cdr.Caller,
+		Callee:      cdr.Callee,
+		AnsweredAt:  cdr.AnswerTime,
+		ArchivedAt:  time.Now(),
+	}
+	if !cdr.StartTime.IsZero() {
+		rec.StartedAt = &cdr.StartTime
+	}
+	if !cdr.EndTime.IsZero() {
+		rec.EndedAt = &cdr.EndTime
+	}
+	if audio, err := pcm.DecodeWAV(wav); err == nil {
+		rec.Channels = audio.Channels
+		rec.DurationMs = int(audio.Duration() / time.Millisecond)
+	}
+	return rec
+}
+
+// Key 录音在对象存储中的键，文件名按recording.filename模板生成
+func (a *RecordingArchiver) Key(cdr models.CDR) string {
+	template := a.config.Filename
+	if template == "" {
+		template = defaultRecordingFilename
+	}
+	at := cdr.StartTime
+	if at.IsZero() {
+		at = time.Now()
+	}
+	name := strings.NewReplacer(
+		"{uuid}", cdr.CallUUID,
+		"{date}", at.Format("2006-01-02"),
+		"{time}", at.Format("150405"),
+		"{caller}", safeFilename(cdr.Caller),
+		"{callee}", safeFilename(cdr.Callee),
+	).Replace(template)
+	return a.config.Prefix + "/" + name + "." + a.codec.Extension()
+}
+
+// MetadataKey 录音元数据文件在对象存储中的键，与录音同名
+func MetadataKey(key string) string {
+	return strings.TrimSuffix(key, filepath.Ext(key)) + ".json"
+}
+
+// safeFilename 将号码中不能用于文件名的字符替换为下划线
+func safeFilename(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '+' || r == '-' || r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// 录音查询分页限制
+const (
+	defaultRecordingLimit = 20
+	maxRecordingLimit     = 100
+)
+
+// RecordingService 通话录音查询和下载，录音由RecordingArchiver归档并登记索引
+type RecordingService struct {
+	store   *repositories.Store
+	objects storage.Store
+}
+
+// NewRecordingService 创建通话录音服务
+func NewRecordingService(store *repositories.Store, objects storage.Store) *RecordingService {
+	return &RecordingService{store: store, objects: objects}
+}
+
+// List 按挂断时间和号码查询录音
+func (s *RecordingService) List(ctx context.Context, q models.RecordingQuery) ([]*models.Recording, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultRecordingLimit
+	}
+	if q.Limit > maxRecordingLimit {
+		q.Limit = maxRecordingLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return s.store.Recordings.List(ctx, q)
+}
+
+// Get 查询通话的录音，不存在时返回repositories.ErrNotFound
+func (s *RecordingService) Get(ctx context.Context, callUUID string) (*models.Recording, error) {
+	return s.store.Recordings.Get(ctx, callUUID)
+}
+
+// Download 读取通话的录音文件
+func (s *RecordingService) Download(ctx context.Context, callUUID string) (*models.Recording, []byte, error) {
+	rec, err := s.store.Recordings.Get(ctx, callUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := s.objects.Get(ctx, rec.Key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取录音文件失败: %v", err)
+	}
+	return rec, data, nil
+}
@@ -0,0 +1,157 @@
+// Package redaction 通话转写脱敏：转写写入数据库前按规则隐藏银行卡号、身份证号等敏感号码，
+// 原始文本只保留在实时对话的会话存储中，随会话过期或通话结束清除
+package redaction
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 默认规则名称
+const (
+	RuleBankCard = "bank_card" // 银行卡号：13~19位数字，允许以空格或-分组，需通过Luhn校验
+	RuleIDCard   = "id_card"   // 居民身份证号：18位，含出生日期，末位可为X
+)
+
+// maskChar 隐藏号码使用的字符
+const maskChar = '*'
+
+// DefaultRules 未配置规则时使用的规则，身份证号在前，避免18位身份证号按银行卡号保留末4位
+var DefaultRules = []Rule{
+	{Name: RuleIDCard, Pattern: `\b\d{6}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`},
+	{Name: RuleBankCard, Pattern: `\b\d{4}(?:[ -]?\d){9,15}\b`, KeepLast: 4, Luhn: true},
+}
+
+// Rule 脱敏规则
+type Rule struct {
+	Name        string `yaml:"name"`
+	Pattern     string `yaml:"pattern"`     // 正则表达式（RE2语法）
+	Replacement string `yaml:"replacement"` // 替换文本，可用$1引用分组；为空时将匹配内容中的数字和字母逐个替换为*
+	KeepLast    int    `yaml:"keep_last"`   // 逐个替换时保留末尾的数字和字母个数，便于客服核对
+	Luhn        bool   `yaml:"luhn"`        // 只隐藏通过Luhn校验的数字串，避免误伤订单号、金额等
+}
+
+// Config 转写脱敏配置
+type Config struct {
+	Enabled   bool   `yaml:"enabled"`
+	Rules     []Rule `yaml:"rules"`      // 按顺序执行的脱敏规则，为空时使用DefaultRules
+	PurgeLive bool   `yaml:"purge_live"` // 通话音频流结束后立即清除会话中的原始对话历史，不等待会话过期
+}
+
+// Validate 校验转写脱敏配置，未启用时不校验
+func (c Config) Validate() error {
+	_, err := New(c)
+	return err
+}
+
+// Redactor 文本脱敏
+type Redactor interface {
+	Redact(text string) string
+}
+
+// compiledRule 解析后的脱敏规则
+type compiledRule struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// Rules 按配置的规则脱敏，可在nil上调用，此时不做处理
+type Rules struct {
+	rules []compiledRule
+}
+
+// New 解析脱敏规则，未启用时返回nil
+func New(config Config) (*Rules, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	rules := config.Rules
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	r := &Rules{rules: make([]compiledRule, 0, len(rules))}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i)
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("rules.%s.pattern: 不能为空", name)
+		}
+		if rule.KeepLast < 0 {
+			return nil, fmt.Errorf("rules.%s.keep_last: 不能为负数", name)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rules.%s.pattern: 正则表达式无效: %v", name, err)
+		}
+		r.rules = append(r.rules, compiledRule{Rule: rule, pattern: pattern})
+	}
+	return r, nil
+}
+
+// Redact 按顺序执行脱敏规则
+func (r *Rules) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, rule := range r.rules {
+		text = rule.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.Luhn && !luhnValid(match) {
+				return match
+			}
+			if rule.Replacement != "" {
+				return rule.pattern.ReplaceAllString(match, rule.Replacement)
+			}
+			return mask(match, rule.KeepLast)
+		})
+	}
+	return text
+}
+
+// mask 将数字和字母替换为*，保留末尾keepLast个，分隔符保持不变
+func mask(text string, keepLast int) string {
+	runes := []rune(text)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if !isAlnum(runes[i]) {
+			continue
+		}
+		if keepLast > 0 {
+			keepLast--
+			continue
+		}
+		runes[i] = maskChar
+	}
+	return string(runes)
+}
+
+// isAlnum 是否为ASCII数字或字母
+func isAlnum(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// luhnValid 数字串（忽略分隔符）是否通过Luhn校验
+func luhnValid(text string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, text)
+	if len(digits) < 2 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
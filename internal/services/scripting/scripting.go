@@ -0,0 +1,405 @@
+// Package scripting Lua脚本钩子：无法重新编译程序的用户可以编写Lua脚本，在通话接通、客户说完一句话、
+// AI完成一轮回复和挂机时执行简单的定制逻辑。脚本中定义以下全局函数即可，未定义的事件不执行：
+//
+//	function on_call_answered(call) end  -- call: uuid caller callee direction headers
+//	function on_transcript(t) end        -- t: call_uuid session_id text
+//	function on_dialog_turn(turn) end    -- turn: call_uuid session_id input reply intent latency_ms
+//	function on_hangup(cdr) end          -- cdr: call_uuid caller callee gateway hangup_cause billsec answered recording_consent
+//
+// 脚本运行在沙箱中，只能使用base（不含dofile/loadfile/load/require）、string、table、math库和dialer接口：
+//
+//	dialer.log(...)                       -- 输出日志
+//	dialer.set_var(name, value)           -- 设置当前通话的通道变量
+//	dialer.say(text)                      -- 让AI在当前通话中说出话术，text可为 audio:名称|备用文本 播放预录提示音
+//	dialer.http_post(url, body)           -- 以JSON推送body（table），返回状态码和响应内容，失败时返回nil和错误信息；
+//	                                      -- 只能访问 scripting.webhook_hosts 中的主机
+//
+// 每次事件在新的Lua环境中执行，全局变量不会保留到下一次事件；单次执行超过timeout时中止
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/hooks"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// 脚本中的事件函数名
+const (
+	FuncCallAnswered = "on_call_answered"
+	FuncTranscript   = "on_transcript"
+	FuncDialogTurn   = "on_dialog_turn"
+	FuncHangup       = "on_hangup"
+)
+
+// DefaultTimeout 单次执行的默认超时
+const DefaultTimeout = 2 * time.Second
+
+// maxResponseSize http_post返回的响应内容上限
+const maxResponseSize = 64 << 10
+
+// variablePattern 通道变量名格式
+var variablePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Config 脚本钩子配置
+type Config struct {
+	Enabled      bool          `yaml:"enabled"`
+	Files        []string      `yaml:"files"`         // Lua脚本文件，按顺序执行
+	Timeout      time.Duration `yaml:"timeout"`       // 单次事件执行超时，包括http_post的耗时，默认2s
+	WebhookHosts []string      `yaml:"webhook_hosts"` // http_post允许访问的主机（host或host:port），为空时不允许发起请求
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Files) == 0 {
+		return fmt.Errorf("files: 启用脚本钩子时必须配置脚本文件")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout: 不能为负数")
+	}
+	for _, host := range c.WebhookHosts {
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			return fmt.Errorf("webhook_hosts: 无效的主机 %q", host)
+		}
+	}
+	return nil
+}
+
+// VariableSetter 设置通道变量，由通话服务实现
+type VariableSetter interface {
+	SetVariable(ctx context.Context, callUUID, name, value string) error
+}
+
+// Speaker 让AI在通话中说出话术，由音频流服务实现
+type Speaker interface {
+	Say(ctx context.Context, callUUID, text string) error
+}
+
+// script 编译好的脚本
+type script struct {
+	name  string
+	proto *lua.FunctionProto
+}
+
+// Engine 脚本钩子，实现hooks.Hooks
+type Engine struct {
+	config  Config
+	scripts []script
+	client  *http.Client
+
+	mu        sync.RWMutex
+	variables VariableSetter
+	speaker   Speaker
+}
+
+var _ hooks.Hooks = (*Engine)(nil)
+
+// New 读取并编译脚本，脚本语法错误时返回错误
+func New(config Config) (*Engine, error) {
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+	e := &Engine{config: config, client: &http.Client{Timeout: config.Timeout}}
+	for _, file := range config.Files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取脚本失败: %v", err)
+		}
+		s, err := compile(filepath.Base(file), string(source))
+		if err != nil {
+			return nil, err
+		}
+		e.scripts = append(e.scripts, s)
+	}
+	return e, nil
+}
+
+// compile 编译脚本，name用于错误信息和日志
+func compile(name, source string) (script, error) {
+	chunk, err := parse.Parse(strings.NewReader(source), name)
+	if err != nil {
+		return script{}, fmt.Errorf("脚本 %s 语法错误: %v", name, err)
+	}
+	proto, err := lua.Compile(chunk, name)
+	if err != nil {
+		return script{}, fmt.Errorf("编译脚本 %s 失败: %v", name, err)
+	}
+	return script{name: name, proto: proto}, nil
+}
+
+// SetVariables 设置通道变量的实现，未设置时dialer.set_var返回错误
+func (e *Engine) SetVariables(v VariableSetter) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.variables = v
+}
+
+// SetSpeaker 设置播放话术的实现，未设置时dialer.say返回错误
+func (e *Engine) SetSpeaker(s Speaker) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.speaker = s
+}
+
+// Len 已加载的脚本数量
+func (e *Engine) Len() int {
+	return len(e.scripts)
+}
+
+// OnCallAnswered 执行脚本的on_call_answered
+func (e *Engine) OnCallAnswered(ctx context.Context, call hooks.Call) {
+	e.run(ctx, FuncCallAnswered, call.UUID, func(L *lua.LState) lua.LValue {
+		headers := L.NewTable()
+		for k, v := range call.Headers {
+			headers.RawSetString(k, lua.LString(v))
+		}
+		t := L.NewTable()
+		t.RawSetString("uuid", lua.LString(call.UUID))
+		t.RawSetString("caller", lua.LString(call.Caller))
+		t.RawSetString("callee", lua.LString(call.Callee))
+		t.RawSetString("direction", lua.LString(call.Direction))
+		t.RawSetString("headers", headers)
+		return t
+	})
+}
+
+// OnTranscriptFinal 执行脚本的on_transcript
+func (e *Engine) OnTranscriptFinal(ctx context.Context, transcript hooks.Transcript) {
+	e.run(ctx, FuncTranscript, transcript.CallUUID, func(L *lua.LState) lua.LValue {
+		t := L.NewTable()
+		t.RawSetString("call_uuid", lua.LString(transcript.CallUUID))
+		t.RawSetString("session_id", lua.LString(transcript.SessionID))
+		t.RawSetString("text", lua.LString(transcript.Text))
+		return t
+	})
+}
+
+// OnDialogTurn 执行脚本的on_dialog_turn
+func (e *Engine) OnDialogTurn(ctx context.Context, turn hooks.Turn) {
+	e.run(ctx, FuncDialogTurn, turn.CallUUID, func(L *lua.LState) lua.LValue {
+		t := L.NewTable()
+		t.RawSetString("call_uuid", lua.LString(turn.CallUUID))
+		t.RawSetString("session_id", lua.LString(turn.SessionID))
+		t.RawSetString("input", lua.LString(turn.Input))
+		t.RawSetString("reply", lua.LString(turn.Reply))
+		t.RawSetString("intent", lua.LString(turn.Intent))
+		t.RawSetString("latency_ms", lua.LNumber(turn.Latency.Milliseconds()))
+		return t
+	})
+}
+
+// OnHangup 执行脚本的on_hangup
+func (e *Engine) OnHangup(ctx context.Context, cdr models.CDR) {
+	e.run(ctx, FuncHangup, cdr.CallUUID, func(L *lua.LState) lua.LValue {
+		t := L.NewTable()
+		t.RawSetString("call_uuid", lua.LString(cdr.CallUUID))
+		t.RawSetString("caller", lua.LString(cdr.Caller))
+		t.RawSetString("callee", lua.LString(cdr.Callee))
+		t.RawSetString("gateway", lua.LString(cdr.Gateway))
+		t.RawSetString("hangup_cause", lua.LString(cdr.HangupCause))
+		t.RawSetString("billsec", lua.LNumber(cdr.BillSec))
+		t.RawSetString("answered", lua.LBool(cdr.AnswerTime != nil))
+		t.RawSetString("recording_consent", lua.LString(cdr.RecordingConsent))
+		return t
+	})
+}
+
+// run 依次在每个脚本中执行事件函数，脚本错误只记录日志
+func (e *Engine) run(ctx context.Context, fn, callUUID string, arg func(L *lua.LState) lua.LValue) {
+	for _, s := range e.scripts {
+		if err := e.call(ctx, s, fn, callUUID, arg); err != nil {
+			log.Printf("执行脚本 %s 的 %s 失败: %v", s.name, fn, err)
+		}
+	}
+}
+
+// call 在新的沙箱环境中加载脚本并调用事件函数，脚本未定义该函数时不做处理
+func (e *Engine) call(ctx context.Context, s script, fn, callUUID string, arg func(L *lua.LState) lua.LValue) error {
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	L := newSandbox()
+	defer L.Close()
+	L.SetContext(ctx)
+	L.SetGlobal("dialer", e.api(L, ctx, callUUID))
+
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		return err
+	}
+	handler, ok := L.GetGlobal(fn).(*lua.LFunction)
+	if !ok {
+		return nil
+	}
+	return L.CallByParam(lua.P{Fn: handler, NRet: 0, Protect: true}, arg(L))
+}
+
+// newSandbox 创建只加载安全标准库的Lua环境，移除可以读取文件或加载代码的函数
+func newSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: 120, RegistryMaxSize: 1 << 16})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+// api 创建当前事件的dialer接口，set_var和say作用于事件所属的通话
+func (e *Engine) api(L *lua.LState, ctx context.Context, callUUID string) *lua.LTable {
+	e.mu.RLock()
+	variables, speaker := e.variables, e.speaker
+	e.mu.RUnlock()
+
+	api := L.NewTable()
+	L.SetFuncs(api, map[string]lua.LGFunction{
+		"log": func(L *lua.LState) int {
+			parts := make([]string, 0, L.GetTop())
+			for i := 1; i <= L.GetTop(); i++ {
+				parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+			}
+			log.Printf("脚本日志 [%s]: %s", callUUID, strings.Join(parts, " "))
+			return 0
+		},
+		"set_var": func(L *lua.LState) int {
+			name, value := L.CheckString(1), L.CheckString(2)
+			switch {
+			case callUUID == "":
+				L.RaiseError("set_var: 事件不属于通话")
+			case variables == nil:
+				L.RaiseError("set_var: 未连接FreeSWITCH")
+			case !variablePattern.MatchString(name):
+				L.RaiseError("set_var: 无效的变量名 %q", name)
+			}
+			if err := variables.SetVariable(ctx, callUUID, name, value); err != nil {
+				L.RaiseError("set_var: %v", err)
+			}
+			return 0
+		},
+		"say": func(L *lua.LState) int {
+			text := L.CheckString(1)
+			switch {
+			case callUUID == "":
+				L.RaiseError("say: 事件不属于通话")
+			case speaker == nil:
+				L.RaiseError("say: 未启用通话音频流")
+			}
+			// 钩子在识别和回复的处理流程中同步调用，话术在脚本返回后异步播放，避免阻塞当前事件
+			go func() {
+				if err := speaker.Say(context.Background(), callUUID, text); err != nil {
+					log.Printf("脚本播放话术失败: %v", err)
+				}
+			}()
+			return 0
+		},
+		"http_post": func(L *lua.LState) int {
+			target, body := L.CheckString(1), L.OptTable(2, L.NewTable())
+			status, resp, err := e.post(ctx, target, toGo(body))
+			if err != nil {
+				L.Push(lua.LNil)
+				L.Push(lua.LString(err.Error()))
+				return 2
+			}
+			L.Push(lua.LNumber(status))
+			L.Push(lua.LString(resp))
+			return 2
+		},
+	})
+	return api
+}
+
+// post 以JSON推送数据，只允许访问配置的主机
+func (e *Engine) post(ctx context.Context, target string, body interface{}) (int, string, error) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0, "", fmt.Errorf("无效的地址: %s", target)
+	}
+	if !e.allowedHost(u.Host) {
+		return 0, "", fmt.Errorf("主机 %s 不在 scripting.webhook_hosts 中", u.Host)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, "", fmt.Errorf("编码请求失败: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, "", fmt.Errorf("读取响应失败: %v", err)
+	}
+	return resp.StatusCode, string(content), nil
+}
+
+// allowedHost 主机是否在允许列表中，不区分大小写
+func (e *Engine) allowedHost(host string) bool {
+	for _, allowed := range e.config.WebhookHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// toGo 将Lua值转换为可编码为JSON的Go值，键为连续整数1..n的table转换为数组
+func toGo(v lua.LValue) interface{} {
+	switch v := v.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LString:
+		return string(v)
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 {
+			items := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				items = append(items, toGo(v.RawGetInt(i)))
+			}
+			return items
+		}
+		m := make(map[string]interface{})
+		v.ForEach(func(key, value lua.LValue) {
+			m[key.String()] = toGo(value)
+		})
+		return m
+	}
+	return nil
+}
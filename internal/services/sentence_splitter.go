@@ -0,0 +1,47 @@
+package services
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// sentenceEnds 句末标点，遇到即切分
+const sentenceEnds = "。！？!?；;…\n"
+
+// clauseEnds 分句标点，当前句子较长时遇到即切分，避免长句迟迟无法开始合成
+const clauseEnds = "，,、：:"
+
+// longClauseRunes 超过该字数的句子遇到分句标点即切分
+const longClauseRunes = 20
+
+// SentenceSplitter 将大模型流式输出的文本片段按标点切分为完整句子，供逐句合成语音
+type SentenceSplitter struct {
+	buf strings.Builder
+}
+
+// Write 追加文本片段，返回本次凑成的完整句子
+func (s *SentenceSplitter) Write(chunk string) []string {
+	var sentences []string
+	for _, r := range chunk {
+		s.buf.WriteRune(r)
+		if strings.ContainsRune(sentenceEnds, r) ||
+			(strings.ContainsRune(clauseEnds, r) && utf8.RuneCountInString(s.buf.String()) >= longClauseRunes) {
+			if sentence := s.take(); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+		}
+	}
+	return sentences
+}
+
+// Flush 返回缓冲区中剩余的未以标点结尾的文本
+func (s *SentenceSplitter) Flush() string {
+	return s.take()
+}
+
+// take 取出并清空缓冲区
+func (s *SentenceSplitter) take() string {
+	sentence := strings.TrimSpace(s.buf.String())
+	s.buf.Reset()
+	return sentence
+}
@@ -0,0 +1,196 @@
+// Package sentiment 客户情绪识别：按情感词典为每句客户语音打上正面、中性或负面标签，
+// 可选调用大模型重新分类，结果随转写保存，用于按通话汇总情绪并安排人工跟进
+package sentiment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+)
+
+// 分类方式
+const (
+	ProviderLexicon = "lexicon" // 只使用情感词典
+	ProviderLLM     = "llm"     // 先按情感词典标注，再调用大模型重新分类
+)
+
+// defaultTimeout 大模型分类的默认超时时间
+const defaultTimeout = 5 * time.Second
+
+// negations 否定词，紧挨在情感词之前时反转正面词的情绪，如“不满意”
+const negations = "不没别未"
+
+// defaultPositive 默认正面词
+var defaultPositive = []string{
+	"好的", "可以", "谢谢", "感谢", "满意", "不错", "挺好", "太好了", "喜欢", "没问题",
+	"方便", "愿意", "有兴趣", "感兴趣", "划算", "优惠", "放心", "麻烦你了", "辛苦",
+}
+
+// defaultNegative 默认负面词
+var defaultNegative = []string{
+	"不需要", "不要", "别打", "别再打", "骚扰", "烦", "生气", "投诉", "举报", "垃圾",
+	"骗子", "诈骗", "滚", "太差", "失望", "太贵", "没用", "浪费时间", "恶心", "忽悠",
+}
+
+// Config 客户情绪识别配置
+type Config struct {
+	Enabled  bool          `yaml:"enabled"`
+	Provider string        `yaml:"provider"` // lexicon（默认）或 llm
+	Positive []string      `yaml:"positive"` // 追加的正面词
+	Negative []string      `yaml:"negative"` // 追加的负面词
+	Timeout  time.Duration `yaml:"timeout"`  // 大模型分类的超时时间，超时保留词典标注的结果
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	switch c.Provider {
+	case "", ProviderLexicon, ProviderLLM:
+	default:
+		return fmt.Errorf("provider: 不支持的分类方式 %s", c.Provider)
+	}
+	for i, word := range c.Positive {
+		if word == "" {
+			return fmt.Errorf("positive[%d]: 情感词不能为空", i)
+		}
+	}
+	for i, word := range c.Negative {
+		if word == "" {
+			return fmt.Errorf("negative[%d]: 情感词不能为空", i)
+		}
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout: 不能为负数")
+	}
+	return nil
+}
+
+// Generator 大模型文本生成，services.LLMClient满足该接口
+type Generator interface {
+	GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error)
+}
+
+// word 情感词
+type word struct {
+	text     string
+	positive bool
+}
+
+// Analyzer 客户情绪识别，可并发使用；方法可在nil上调用，此时不识别
+type Analyzer struct {
+	words   []word    // 情感词，较长的在前
+	llm     Generator // 为nil时只使用情感词典
+	timeout time.Duration
+}
+
+// New 按配置创建情绪识别，未启用时返回nil；分类方式为llm但llm为nil时只使用情感词典
+func New(config Config, llm Generator) *Analyzer {
+	if !config.Enabled {
+		return nil
+	}
+	a := &Analyzer{timeout: config.Timeout}
+	if a.timeout <= 0 {
+		a.timeout = defaultTimeout
+	}
+	if config.Provider == ProviderLLM {
+		a.llm = llm
+	}
+	for _, text := range append(append([]string{}, defaultPositive...), config.Positive...) {
+		a.words = append(a.words, word{text: text, positive: true})
+	}
+	for _, text := range append(append([]string{}, defaultNegative...), config.Negative...) {
+		a.words = append(a.words, word{text: text})
+	}
+	// 较长的词优先匹配，如“不需要”先于“需要”、“没问题”先于否定词判断
+	sort.SliceStable(a.words, func(i, j int) bool {
+		return len(a.words[i].text) > len(a.words[j].text)
+	})
+	return a
+}
+
+// Lexicon 按情感词典判断情绪：正面词多于负面词为正面，反之为负面，持平或没有情感词为中性；
+// 正面词前紧挨否定词时计为负面，如“不满意”“不可以”
+func (a *Analyzer) Lexicon(text string) string {
+	if a == nil || text == "" {
+		return ""
+	}
+	text = strings.ToLower(text)
+	score := 0
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		w, ok := a.match(string(runes[i:]))
+		if !ok {
+			i++
+			continue
+		}
+		negated := i > 0 && strings.ContainsRune(negations, runes[i-1])
+		switch {
+		case w.positive && negated:
+			score--
+		case w.positive:
+			score++
+		case !negated:
+			score--
+		}
+		i += len([]rune(w.text))
+	}
+	switch {
+	case score > 0:
+		return models.SentimentPositive
+	case score < 0:
+		return models.SentimentNegative
+	}
+	return models.SentimentNeutral
+}
+
+// match 匹配text开头的情感词
+func (a *Analyzer) match(text string) (word, bool) {
+	for _, w := range a.words {
+		if strings.HasPrefix(text, w.text) {
+			return w, true
+		}
+	}
+	return word{}, false
+}
+
+// UsesLLM 是否调用大模型重新分类
+func (a *Analyzer) UsesLLM() bool {
+	return a != nil && a.llm != nil
+}
+
+// Classify 调用大模型判断情绪，超时时间由timeout配置
+func (a *Analyzer) Classify(ctx context.Context, text string) (string, error) {
+	if !a.UsesLLM() {
+		return "", fmt.Errorf("未配置大模型情绪分类")
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	prompt := "判断客户在电话中说的这句话的情绪，只回答 positive、neutral 或 negative 其中一个词，不要解释。\n客户: " + text
+	resp, err := a.llm.GenerateContext(ctx, prompt, ollama.Options{Temperature: 0.1, MaxTokens: 8})
+	if err != nil {
+		return "", fmt.Errorf("大模型情绪分类失败: %v", err)
+	}
+	return parseLabel(resp.Response)
+}
+
+// parseLabel 解析大模型回答的情绪标签，兼容中文回答
+func parseLabel(answer string) (string, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, label := range []struct{ label, zh string }{
+		{models.SentimentNegative, "负面"},
+		{models.SentimentPositive, "正面"},
+		{models.SentimentNeutral, "中性"},
+	} {
+		if strings.Contains(answer, label.label) || strings.Contains(answer, label.zh) {
+			return label.label, nil
+		}
+	}
+	return "", fmt.Errorf("无法解析情绪分类结果: %q", answer)
+}
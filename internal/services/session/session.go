@@ -0,0 +1,212 @@
+// Package session 保存对话会话状态（历史消息、所属活动、生成参数覆盖项），
+// 支持进程内存储和Redis存储，Redis存储可在重启后恢复并在多实例间共享
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/models"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// 会话存储类型
+const (
+	StoreMemory = "memory" // 进程内存储，重启后丢失
+	StoreRedis  = "redis"  // Redis存储
+)
+
+// DefaultTTL 默认会话有效期
+const DefaultTTL = 2 * time.Hour
+
+// Config 会话存储配置
+type Config struct {
+	Store  string        `yaml:"store"`  // 存储类型：memory 或 redis
+	TTL    time.Duration `yaml:"ttl"`    // 会话自最后一次写入起的有效期，过期后自动清除
+	Prefix string        `yaml:"prefix"` // Redis键前缀
+}
+
+// Validate 验证会话存储配置
+func (c Config) Validate() error {
+	switch c.Store {
+	case "", StoreMemory, StoreRedis:
+	default:
+		return fmt.Errorf("store: 不支持的会话存储 %s", c.Store)
+	}
+	if c.TTL < 0 {
+		return fmt.Errorf("ttl: 不能为负数")
+	}
+	return nil
+}
+
+// Session 会话状态
+type Session struct {
+	History    []models.Message           `json:"history"`
+	CampaignID string                     `json:"campaign_id,omitempty"` // 所属活动
+	Prompt     string                     `json:"prompt,omitempty"`      // 会话指定的提示词配置名称，优先级高于活动配置
+	Overrides  models.GenerationOverrides `json:"overrides"`             // 会话级生成参数覆盖项
+	Model      string                     `json:"model,omitempty"`       // 会话指定的大模型名称（如租户配置的模型），为空时使用全局配置
+	CallUUID   string                     `json:"call_uuid,omitempty"`   // 会话关联的通话UUID，供工具调用使用
+}
+
+// Store 会话存储，Load在会话不存在或已过期时返回空会话
+type Store interface {
+	Load(ctx context.Context, id string) (*Session, error)
+	Save(ctx context.Context, id string, s *Session) error
+	Delete(ctx context.Context, id string) error
+}
+
+// memoryEntry 进程内存储的会话及过期时间
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemoryStore 进程内会话存储，会话序列化后保存，避免调用方修改已保存的状态
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryStore 创建进程内会话存储，ttl为0时使用DefaultTTL
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &MemoryStore{ttl: ttl, sessions: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+// Load 读取会话
+func (m *MemoryStore) Load(ctx context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	entry, ok := m.sessions[id]
+	if ok && time.Now().After(entry.expires) {
+		delete(m.sessions, id)
+		ok = false
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return &Session{}, nil
+	}
+	return decode(entry.data)
+}
+
+// Save 保存会话并刷新有效期，顺带清理过期会话
+func (m *MemoryStore) Save(ctx context.Context, id string, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %v", err)
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = memoryEntry{data: data, expires: now.Add(m.ttl)}
+	if now.Sub(m.lastSweep) >= m.ttl {
+		for key, entry := range m.sessions {
+			if now.After(entry.expires) {
+				delete(m.sessions, key)
+			}
+		}
+		m.lastSweep = now
+	}
+	return nil
+}
+
+// Delete 删除会话
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	return nil
+}
+
+// Len 当前保存的会话数（含尚未清理的过期会话）
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.sessions)
+}
+
+// RedisStore Redis会话存储，会话以JSON保存，每次写入刷新有效期
+type RedisStore struct {
+	client  *goredis.Client
+	ttl     time.Duration
+	prefix  string
+	keyring *encryption.Keyring
+}
+
+// NewRedisStore 创建Redis会话存储
+func NewRedisStore(client *goredis.Client, cfg Config) *RedisStore {
+	s := &RedisStore{client: client, ttl: cfg.TTL, prefix: cfg.Prefix}
+	if s.ttl <= 0 {
+		s.ttl = DefaultTTL
+	}
+	if s.prefix == "" {
+		s.prefix = "ai_dialer:session:"
+	}
+	return s
+}
+
+// SetKeyring 设置静态加密主密钥，设置后会话（含对话历史）加密保存，读取时透明解密
+func (r *RedisStore) SetKeyring(keyring *encryption.Keyring) {
+	r.keyring = keyring
+}
+
+// Load 读取会话
+func (r *RedisStore) Load(ctx context.Context, id string) (*Session, error) {
+	data, err := r.client.Get(ctx, r.prefix+id).Bytes()
+	if err == goredis.Nil {
+		return &Session{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取会话失败: %v", err)
+	}
+	plaintext, err := r.keyring.Decrypt(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("解密会话失败: %v", err)
+	}
+	return decode([]byte(plaintext))
+}
+
+// Save 保存会话并刷新有效期
+func (r *RedisStore) Save(ctx context.Context, id string, s *Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("序列化会话失败: %v", err)
+	}
+	value, err := r.keyring.Encrypt(string(data))
+	if err != nil {
+		return fmt.Errorf("加密会话失败: %v", err)
+	}
+	if err := r.client.Set(ctx, r.prefix+id, value, r.ttl).Err(); err != nil {
+		return fmt.Errorf("保存会话失败: %v", err)
+	}
+	return nil
+}
+
+// Delete 删除会话
+func (r *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := r.client.Del(ctx, r.prefix+id).Err(); err != nil {
+		return fmt.Errorf("删除会话失败: %v", err)
+	}
+	return nil
+}
+
+// decode 反序列化会话
+func decode(data []byte) (*Session, error) {
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("解析会话失败: %v", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/config"
+)
+
+// playbackCleanupDelay 播放结束后保留语音文件的时长，避免FreeSWITCH尚未读完就被删除
+const playbackCleanupDelay = 30 * time.Second
+
+// CommandSender 发送FreeSWITCH API命令
+type CommandSender interface {
+	SendCommand(cmd string) (string, error)
+}
+
+var _ CommandSender = (*freeswitch.ESLClient)(nil)
+
+// SpeechPlayback 将AI语音写成WAV文件并通过uuid_broadcast在通话中播放
+type SpeechPlayback struct {
+	fs     CommandSender
+	config config.PlaybackConfig
+	seq    atomic.Uint64
+}
+
+// NewSpeechPlayback 创建通话语音播放
+func NewSpeechPlayback(fs CommandSender, config config.PlaybackConfig) (*SpeechPlayback, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("tts.playback.dir: 未配置语音文件目录")
+	}
+	if config.FreeSWITCHDir == "" {
+		config.FreeSWITCHDir = config.Dir
+	}
+	if config.Leg == "" {
+		config.Leg = "aleg"
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建语音文件目录失败: %v", err)
+	}
+	return &SpeechPlayback{fs: fs, config: config}, nil
+}
+
+// Play 在通话中播放一段语音，多段语音由FreeSWITCH按顺序排队播放
+func (p *SpeechPlayback) Play(callUUID string, audio *tts.Audio) error {
+	name := fmt.Sprintf("%s-%d.wav", callUUID, p.seq.Add(1))
+	local := filepath.Join(p.config.Dir, name)
+	if err := os.WriteFile(local, audio.WAV(), 0o644); err != nil {
+		return fmt.Errorf("写入语音文件失败: %v", err)
+	}
+	time.AfterFunc(audio.Duration()+playbackCleanupDelay, func() {
+		if err := os.Remove(local); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除语音文件失败: %v", err)
+		}
+	})
+
+	remote := strings.TrimSuffix(p.config.FreeSWITCHDir, "/") + "/" + name
+	resp, err := p.fs.SendCommand(fmt.Sprintf("uuid_broadcast %s %s %s", callUUID, remote, p.config.Leg))
+	if err != nil {
+		return fmt.Errorf("播放语音失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("播放语音失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
+
+// Stop 停止通话中正在播放和排队的语音，客户打断AI说话时调用
+func (p *SpeechPlayback) Stop(callUUID string) error {
+	resp, err := p.fs.SendCommand(fmt.Sprintf("uuid_break %s all", callUUID))
+	if err != nil {
+		return fmt.Errorf("停止播放失败: %v", err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(resp), "-ERR") {
+		return fmt.Errorf("停止播放失败: %s", strings.TrimSpace(resp))
+	}
+	return nil
+}
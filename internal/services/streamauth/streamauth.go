@@ -0,0 +1,127 @@
+// Package streamauth 为FreeSWITCH推送到拨号器的音频流生成短期签名地址，并在WebSocket升级时校验
+// 签名覆盖通话UUID、过期时间和一次性随机数，地址过期、与通话不符或被重复使用时拒绝连接
+package streamauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTTL 签名地址默认有效期
+const defaultTTL = 30 * time.Second
+
+// 校验错误
+var (
+	ErrInvalidSignature = errors.New("音频流签名无效")
+	ErrExpired          = errors.New("音频流地址已过期")
+	ErrReplayed         = errors.New("音频流地址已被使用")
+)
+
+// Config 音频流签名配置
+type Config struct {
+	Enabled      bool          `yaml:"enabled"`       // 是否在通话应答后启动音频流
+	BaseURL      string        `yaml:"base_url"`      // FreeSWITCH可访问的拨号器地址，如 ws://10.0.0.5:8080
+	Secret       string        `yaml:"secret"`        // HMAC签名密钥
+	TTL          time.Duration `yaml:"ttl"`           // 签名地址有效期，只需覆盖FreeSWITCH发起连接的时间
+	MaxRestarts  int           `yaml:"max_restarts"`  // mod_audio_stream报告异常后单个通话最多重新启动的次数
+	RestartDelay time.Duration `yaml:"restart_delay"` // 重新启动前的等待时间
+	SampleRate   int           `yaml:"sample_rate"`   // FreeSWITCH推送音频的采样率，8000或16000，非16000时服务端重采样后再识别
+	Codec        string        `yaml:"codec"`         // FreeSWITCH推送音频的编码，pcmu或pcma时服务端解码为16位PCM，为空时为L16
+}
+
+// Signer 生成和校验音频流签名地址
+type Signer struct {
+	baseURL string
+	secret  []byte
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // 已使用的随机数及其过期时间
+}
+
+// New 创建签名器，未配置密钥时返回错误，避免签发任何人都能伪造的地址
+func New(config Config) (*Signer, error) {
+	if config.Secret == "" {
+		return nil, fmt.Errorf("音频流签名密钥不能为空")
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultTTL
+	}
+	return &Signer{
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		secret:  []byte(config.Secret),
+		ttl:     config.TTL,
+		nonces:  make(map[string]time.Time),
+	}, nil
+}
+
+// Path 通话音频流的路径
+func Path(callUUID string) string {
+	return "/ws/calls/" + url.PathEscape(callUUID) + "/stream"
+}
+
+// Sign 生成通话音频流的签名地址
+func (s *Signer) Sign(callUUID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	nonce := hex.EncodeToString(buf)
+	expires := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("nonce", nonce)
+	query.Set("sig", s.signature(callUUID, expires, nonce))
+	return s.baseURL + Path(callUUID) + "?" + query.Encode(), nil
+}
+
+// Verify 校验签名地址的查询参数，校验通过后随机数即失效，同一地址不能再次使用
+func (s *Signer) Verify(callUUID string, query url.Values) error {
+	expires, nonce, sig := query.Get("expires"), query.Get("nonce"), query.Get("sig")
+	if expires == "" || nonce == "" || sig == "" {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(callUUID, expires, nonce))) {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expiresAt := time.Unix(unix, 0)
+	now := time.Now()
+	if !now.Before(expiresAt) {
+		return ErrExpired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n, exp := range s.nonces {
+		if !now.Before(exp) {
+			delete(s.nonces, n)
+		}
+	}
+	if _, used := s.nonces[nonce]; used {
+		return ErrReplayed
+	}
+	s.nonces[nonce] = expiresAt
+	return nil
+}
+
+// signature 计算签名
+func (s *Signer) signature(callUUID, expires, nonce string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(callUUID + "\n" + expires + "\n" + nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,113 @@
+// Package tap 将通话音频实时复制给质检坐席监听，音频来自服务端接收和发送的音频流，不依赖FreeSWITCH监听
+package tap
+
+import (
+	"sync"
+)
+
+// 音频通道
+const (
+	LegCustomer = "customer" // 客户说话的音频
+	LegAI       = "ai"       // AI回复的合成语音
+	LegMixed    = "mixed"    // 两路音频按到达顺序合并，通话为一问一答时基本不会重叠，因此不做混音叠加
+)
+
+// defaultBuffer 每个监听者缓存的音频帧数
+const defaultBuffer = 64
+
+// Config 通话监听配置
+type Config struct {
+	Enabled bool     `yaml:"enabled"` // 是否启用
+	Tokens  []string `yaml:"tokens"`  // 允许监听的质检坐席令牌
+	Buffer  int      `yaml:"buffer"`  // 每个监听者缓存的音频帧数，监听者处理不过来时丢弃新帧
+}
+
+// Frame 一帧音频，16位小端PCM单声道
+type Frame struct {
+	Leg        string // 音频通道，customer或ai
+	SampleRate int    // 采样率
+	PCM        []byte // PCM数据
+}
+
+// listener 监听者
+type listener struct {
+	leg    string
+	frames chan Frame
+}
+
+// Tap 按通话分发音频帧
+type Tap struct {
+	buffer int
+
+	mu        sync.Mutex
+	listeners map[string]map[*listener]struct{}
+}
+
+// New 创建通话监听
+func New(config Config) *Tap {
+	if config.Buffer <= 0 {
+		config.Buffer = defaultBuffer
+	}
+	return &Tap{
+		buffer:    config.Buffer,
+		listeners: make(map[string]map[*listener]struct{}),
+	}
+}
+
+// ValidLeg 检查音频通道名称是否有效
+func ValidLeg(leg string) bool {
+	return leg == LegCustomer || leg == LegAI || leg == LegMixed
+}
+
+// Subscribe 订阅通话的音频，leg为mixed时接收两路音频；调用返回的cancel取消订阅
+func (t *Tap) Subscribe(callID, leg string) (<-chan Frame, func()) {
+	l := &listener{leg: leg, frames: make(chan Frame, t.buffer)}
+
+	t.mu.Lock()
+	if t.listeners[callID] == nil {
+		t.listeners[callID] = make(map[*listener]struct{})
+	}
+	t.listeners[callID][l] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.listeners[callID], l)
+			if len(t.listeners[callID]) == 0 {
+				delete(t.listeners, callID)
+			}
+			t.mu.Unlock()
+			close(l.frames)
+		})
+	}
+	return l.frames, cancel
+}
+
+// Publish 分发一帧音频，没有监听者时直接返回；可在nil上调用
+// 监听者缓存已满时丢弃该帧，不阻塞音频处理流程
+func (t *Tap) Publish(callID, leg string, sampleRate int, pcm []byte) {
+	if t == nil || len(pcm) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	listeners := t.listeners[callID]
+	if len(listeners) == 0 {
+		return
+	}
+
+	frame := Frame{Leg: leg, SampleRate: sampleRate, PCM: append([]byte(nil), pcm...)}
+	for l := range listeners {
+		if l.leg != LegMixed && l.leg != leg {
+			continue
+		}
+		select {
+		case l.frames <- frame:
+		default:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package tenant 多租户：一套部署服务多条业务线，每个租户可使用独立的讯飞凭据、大模型、AI人设和外显号码。
+// 租户通过WebSocket连接的tenant查询参数或FreeSWITCH通道变量（默认 ai_dialer_tenant）传递，未指定租户时使用全局配置
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// DefaultVariable 传递租户ID的默认通道变量
+const DefaultVariable = "ai_dialer_tenant"
+
+// idPattern 租户ID格式，租户ID会写入ESL命令和通道变量，不允许空白和分隔符
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// callerIDPattern 外显号码格式
+var callerIDPattern = regexp.MustCompile(`^\+?[0-9]{1,32}$`)
+
+// Credentials 讯飞凭据
+type Credentials struct {
+	AppID     string `yaml:"app_id"`
+	APIKey    string `yaml:"api_key"`
+	APISecret string `yaml:"api_secret"`
+}
+
+// Empty 是否未配置凭据
+func (c Credentials) Empty() bool {
+	return c.AppID == "" && c.APIKey == "" && c.APISecret == ""
+}
+
+// Tenant 租户配置，未配置的项使用全局配置
+type Tenant struct {
+	XFYun    Credentials `yaml:"xfyun"`     // 语音识别使用的讯飞凭据，应通过 ${NAME} 引用环境变量
+	Model    string      `yaml:"model"`     // 大模型名称，覆盖 llm.ollama.model / llm.openai.model
+	Persona  string      `yaml:"persona"`   // 提示词配置名称（llm.prompt.profiles），呼入路由配置了人设时以呼入路由为准
+	CallerID string      `yaml:"caller_id"` // 外呼外显号码，呼叫请求未指定外显号码时使用
+}
+
+// Config 多租户配置
+type Config struct {
+	Variable string            `yaml:"variable"` // 传递租户ID的通道变量名，默认 ai_dialer_tenant
+	Tenants  map[string]Tenant `yaml:"tenants"`  // 按租户ID配置
+}
+
+// Validate 校验租户配置，hasPersona判断提示词配置是否存在
+func (c Config) Validate(hasPersona func(name string) bool) error {
+	if c.Variable != "" && !idPattern.MatchString(c.Variable) {
+		return fmt.Errorf("variable: 无效的通道变量名 %s", c.Variable)
+	}
+	for id, t := range c.Tenants {
+		if !idPattern.MatchString(id) {
+			return fmt.Errorf("tenants.%s: 租户ID只能包含字母、数字、下划线和连字符", id)
+		}
+		if !t.XFYun.Empty() && (t.XFYun.AppID == "" || t.XFYun.APIKey == "" || t.XFYun.APISecret == "") {
+			return fmt.Errorf("tenants.%s.xfyun: app_id、api_key、api_secret须同时配置", id)
+		}
+		if t.Persona != "" && !hasPersona(t.Persona) {
+			return fmt.Errorf("tenants.%s.persona: 未定义的提示词配置: %s", id, t.Persona)
+		}
+		if t.CallerID != "" && !callerIDPattern.MatchString(t.CallerID) {
+			return fmt.Errorf("tenants.%s.caller_id: 无效的外显号码 %s", id, t.CallerID)
+		}
+	}
+	return nil
+}
+
+// Registry 租户查询，可在nil上调用，此时没有任何租户
+type Registry struct {
+	variable string
+	tenants  map[string]Tenant
+}
+
+// New 创建租户查询，没有配置租户时返回nil
+func New(config Config) *Registry {
+	if len(config.Tenants) == 0 {
+		return nil
+	}
+	variable := config.Variable
+	if variable == "" {
+		variable = DefaultVariable
+	}
+	return &Registry{variable: variable, tenants: config.Tenants}
+}
+
+// Get 按租户ID查询租户
+func (r *Registry) Get(id string) (Tenant, bool) {
+	if r == nil || id == "" {
+		return Tenant{}, false
+	}
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// Variable 传递租户ID的通道变量名
+func (r *Registry) Variable() string {
+	if r == nil {
+		return DefaultVariable
+	}
+	return r.variable
+}
+
+// FromHeaders 从ESL事件头部读取通道的租户ID，未设置通道变量或租户未配置时返回空
+func (r *Registry) FromHeaders(headers map[string]string) string {
+	id := headers["variable_"+r.Variable()]
+	if _, ok := r.Get(id); !ok {
+		return ""
+	}
+	return id
+}
+
+// contextKey 上下文中租户ID的键
+type contextKey struct{}
+
+// WithID 在上下文中记录租户ID，发起呼叫时据此设置通道变量和外显号码
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// IDFromContext 读取上下文中的租户ID，未设置时返回空
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
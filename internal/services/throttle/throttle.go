@@ -0,0 +1,224 @@
+// Package throttle 外呼自动限速：统计最近一段时间语音识别和大模型的P95延迟与错误率，
+// 超过阈值时按比例降低外呼任务的拨打速率，避免新接通的通话继续加重负载、拖慢进行中的对话；指标恢复后恢复原速率
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+)
+
+// 统计的组件
+const (
+	ComponentASR = "asr" // 语音识别：最后一帧音频发送后到收到最终结果的延迟
+	ComponentLLM = "llm" // 大模型：单轮回复的生成耗时
+)
+
+// Thresholds 单个组件的健康阈值，为0的项不检查
+type Thresholds struct {
+	P95       time.Duration `yaml:"p95"`        // P95延迟上限
+	ErrorRate float64       `yaml:"error_rate"` // 错误率上限，0-1
+}
+
+// Config 自动限速配置
+type Config struct {
+	Enabled    bool          `yaml:"enabled"`
+	Window     time.Duration `yaml:"window"`      // 统计窗口
+	MinSamples int           `yaml:"min_samples"` // 窗口内样本数少于该值时不改变限速状态
+	Factor     float64       `yaml:"factor"`      // 限速期间拨打速率乘以该系数，0-1
+	ASR        Thresholds    `yaml:"asr"`
+	LLM        Thresholds    `yaml:"llm"`
+}
+
+// Validate 校验自动限速配置，未启用时不校验
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("window: 启用自动限速时必须大于0")
+	}
+	if c.MinSamples <= 0 {
+		return fmt.Errorf("min_samples: 启用自动限速时必须大于0")
+	}
+	if c.Factor <= 0 || c.Factor >= 1 {
+		return fmt.Errorf("factor: 必须在0和1之间")
+	}
+	for name, t := range map[string]Thresholds{ComponentASR: c.ASR, ComponentLLM: c.LLM} {
+		if t.P95 < 0 || t.ErrorRate < 0 || t.ErrorRate > 1 {
+			return fmt.Errorf("%s: p95不能为负数，error_rate必须在0和1之间", name)
+		}
+	}
+	return nil
+}
+
+// sample 一次调用的结果
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// metrics 组件在统计窗口内的指标
+type metrics struct {
+	samples   int
+	p95       time.Duration
+	errorRate float64
+}
+
+// Governor 根据语音识别和大模型的健康指标决定外呼速率系数，可在nil上调用，此时不限速
+type Governor struct {
+	config    Config
+	publisher models.EventPublisher
+
+	mu        sync.Mutex
+	samples   map[string][]sample
+	throttled bool
+}
+
+// New 创建自动限速
+func New(config Config) *Governor {
+	return &Governor{
+		config:  config,
+		samples: make(map[string][]sample),
+	}
+}
+
+// SetPublisher 设置事件发布，限速状态变化时向监控面板推送告警
+func (g *Governor) SetPublisher(publisher models.EventPublisher) {
+	g.publisher = publisher
+}
+
+// Observe 记录一次调用的耗时和结果
+func (g *Governor) Observe(component string, latency time.Duration, err error) {
+	if g == nil {
+		return
+	}
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.samples[component] = append(g.prune(component, now), sample{at: now, latency: latency, failed: err != nil})
+}
+
+// Factor 返回当前的拨打速率系数：健康时为1，限速期间为配置的factor
+// 每次调用按now重新统计窗口内的指标，限速状态变化时记录日志并推送告警
+func (g *Governor) Factor(now time.Time) float64 {
+	if g == nil {
+		return 1
+	}
+	g.mu.Lock()
+	changed, reason := g.evaluate(now)
+	throttled := g.throttled
+	g.mu.Unlock()
+
+	if changed {
+		g.alert(throttled, reason)
+	}
+	if throttled {
+		return g.config.Factor
+	}
+	return 1
+}
+
+// evaluate 清理窗口外的样本并判断是否需要切换限速状态，需持有锁
+// 任一组件超过阈值时进入限速；所有组件的样本都足够且均未超过阈值时恢复
+func (g *Governor) evaluate(now time.Time) (bool, string) {
+	var violations, healthy []string
+	sufficient := true
+	for _, component := range []string{ComponentASR, ComponentLLM} {
+		m := g.metrics(component, now)
+		if m.samples < g.config.MinSamples {
+			sufficient = false
+			continue
+		}
+		summary := fmt.Sprintf("%s P95 %v 错误率 %.1f%%", component, m.p95.Round(time.Millisecond), m.errorRate*100)
+		if g.exceeds(component, m) {
+			violations = append(violations, summary)
+		} else {
+			healthy = append(healthy, summary)
+		}
+	}
+
+	switch {
+	case !g.throttled && len(violations) > 0:
+		g.throttled = true
+		return true, strings.Join(violations, "，")
+	case g.throttled && len(violations) == 0 && sufficient:
+		g.throttled = false
+		return true, strings.Join(healthy, "，")
+	}
+	return false, ""
+}
+
+// prune 清理组件窗口外的样本，需持有锁
+func (g *Governor) prune(component string, now time.Time) []sample {
+	samples := g.samples[component]
+	cutoff := now.Add(-g.config.Window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	samples = samples[i:]
+	g.samples[component] = samples
+	return samples
+}
+
+// metrics 统计组件在窗口内的指标，P95只统计成功的调用，需持有锁
+func (g *Governor) metrics(component string, now time.Time) metrics {
+	samples := g.prune(component, now)
+	m := metrics{samples: len(samples)}
+	if len(samples) == 0 {
+		return m
+	}
+	latencies := make([]time.Duration, 0, len(samples))
+	failures := 0
+	for _, s := range samples {
+		if s.failed {
+			failures++
+			continue
+		}
+		latencies = append(latencies, s.latency)
+	}
+	m.errorRate = float64(failures) / float64(len(samples))
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		m.p95 = latencies[(len(latencies)*95+99)/100-1]
+	}
+	return m
+}
+
+// exceeds 指标是否超过组件的阈值
+func (g *Governor) exceeds(component string, m metrics) bool {
+	t := g.config.ASR
+	if component == ComponentLLM {
+		t = g.config.LLM
+	}
+	return (t.P95 > 0 && m.p95 > t.P95) || (t.ErrorRate > 0 && m.errorRate > t.ErrorRate)
+}
+
+// alert 记录限速状态变化并推送到监控面板
+func (g *Governor) alert(throttled bool, reason string) {
+	var text string
+	if throttled {
+		text = fmt.Sprintf("外呼自动限速: 拨打速率降至 %.0f%%，%s", g.config.Factor*100, reason)
+		log.Printf("警告: %s", text)
+	} else {
+		text = fmt.Sprintf("外呼自动限速解除: 恢复原拨打速率，%s", reason)
+		log.Println(text)
+	}
+	if g.publisher == nil {
+		return
+	}
+	if err := g.publisher.Publish(context.Background(), &models.CallEvent{
+		Type: models.EventTypeCampaignThrottle,
+		Text: text,
+	}); err != nil {
+		log.Printf("推送外呼限速告警失败: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TransferToolName 内置转人工工具名
+const TransferToolName = "transfer_to_agent"
+
+// maxResponseSize Webhook工具响应的最大读取字节数
+const maxResponseSize = 16 << 10
+
+// TransferConfig 内置转人工工具配置，目标为空时不提供该工具
+type TransferConfig struct {
+	Destination     string `yaml:"destination"`      // 转人工的目标号码或分机
+	DialplanContext string `yaml:"dialplan_context"` // 拨号计划上下文，留空使用FreeSWITCH默认值
+}
+
+// TransferToAgent 转人工工具：客户要求人工服务或问题超出AI处理范围时调用，AI回复播放完后转接
+func TransferToAgent(config TransferConfig) Tool {
+	return Tool{
+		Name:        TransferToolName,
+		Description: "客户要求人工服务或问题无法解答时，把通话转接到人工坐席",
+		Parameters:  []Parameter{{Name: "reason", Description: "转人工的原因"}},
+		Handler: func(ctx context.Context, inv Invocation) (Result, error) {
+			if inv.CallUUID == "" {
+				return Result{}, fmt.Errorf("当前对话未关联通话，无法转接")
+			}
+			return Result{
+				Content: "已安排转接人工坐席，请告知客户稍等，回复结束后自动转接",
+				Action:  &Action{Type: ActionTransfer, Destination: config.Destination, DialplanContext: config.DialplanContext},
+			}, nil
+		},
+	}
+}
+
+// WebhookConfig Webhook工具配置：调用时以JSON把工具名、会话、通话和参数推送到url，
+// 响应中的result字段（响应不是JSON时为整个响应内容）作为工具结果
+type WebhookConfig struct {
+	Name        string      `yaml:"name"`        // 工具名，如 lookup_order、schedule_callback
+	Description string      `yaml:"description"` // 用途说明，写入提示词
+	Parameters  []Parameter `yaml:"parameters"`  // 参数
+	URL         string      `yaml:"url"`         // 业务系统接口地址
+}
+
+// Validate 校验配置
+func (c WebhookConfig) Validate() error {
+	if !namePattern.MatchString(c.Name) {
+		return fmt.Errorf("name: 只能包含小写字母、数字和下划线")
+	}
+	if c.Description == "" {
+		return fmt.Errorf("description: 不能为空")
+	}
+	for i, p := range c.Parameters {
+		if !namePattern.MatchString(p.Name) {
+			return fmt.Errorf("parameters[%d].name: 只能包含小写字母、数字和下划线", i)
+		}
+	}
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url: 必须是http或https地址")
+	}
+	return nil
+}
+
+// webhookRequest Webhook工具推送的内容
+type webhookRequest struct {
+	Tool       string            `json:"tool"`
+	SessionID  string            `json:"session_id"`
+	CallUUID   string            `json:"call_uuid,omitempty"`
+	CampaignID string            `json:"campaign_id,omitempty"`
+	Arguments  map[string]string `json:"arguments"`
+}
+
+// Webhook 把调用推送到业务系统的工具，非2xx响应视为执行失败
+func Webhook(config WebhookConfig) Tool {
+	return Tool{
+		Name:        config.Name,
+		Description: config.Description,
+		Parameters:  config.Parameters,
+		Handler: func(ctx context.Context, inv Invocation) (Result, error) {
+			data, err := json.Marshal(webhookRequest{
+				Tool:       inv.Tool,
+				SessionID:  inv.SessionID,
+				CallUUID:   inv.CallUUID,
+				CampaignID: inv.CampaignID,
+				Arguments:  inv.Arguments,
+			})
+			if err != nil {
+				return Result{}, fmt.Errorf("编码请求失败: %v", err)
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(data))
+			if err != nil {
+				return Result{}, err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return Result{}, fmt.Errorf("请求失败: %v", err)
+			}
+			defer resp.Body.Close()
+			content, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+			if err != nil {
+				return Result{}, fmt.Errorf("读取响应失败: %v", err)
+			}
+			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+				return Result{}, fmt.Errorf("业务系统返回状态码 %d", resp.StatusCode)
+			}
+			var body struct {
+				Result json.RawMessage `json:"result"`
+			}
+			if json.Unmarshal(content, &body) == nil && len(body.Result) > 0 {
+				var text string
+				if json.Unmarshal(body.Result, &text) == nil {
+					return Result{Content: text}, nil
+				}
+				return Result{Content: string(body.Result)}, nil
+			}
+			return Result{Content: strings.TrimSpace(string(content))}, nil
+		},
+	}
+}
@@ -0,0 +1,354 @@
+// Package tools 对话工具调用：提示词中列出可用工具，大模型需要执行操作（查询订单、预约回电、转人工等）时
+// 只回复一个JSON对象 {"tool": "工具名", "arguments": {"参数": "值"}}，对话服务执行对应的处理函数后把结果
+// 写入对话历史，再次请求大模型生成给客户的回复。
+//
+// 工具来源：内置的转人工工具、配置的Webhook工具（把调用推送到业务系统并以响应作为结果），
+// 以及集成方在编译时注册的Go处理函数：
+//
+//	func init() {
+//		tools.Register(tools.Tool{
+//			Name:        "lookup_order",
+//			Description: "按订单号查询订单状态",
+//			Parameters:  []tools.Parameter{{Name: "order_id", Description: "订单号", Required: true}},
+//			Handler: func(ctx context.Context, inv tools.Invocation) (tools.Result, error) { ... },
+//		})
+//	}
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/metrics"
+)
+
+// 默认值
+const (
+	DefaultMaxRounds = 3
+	DefaultTimeout   = 5 * time.Second
+)
+
+// 工具要求执行的通话动作，在AI回复播放完后执行，取值与intent包的动作相同
+const (
+	ActionHangup   = "hangup"   // 挂机
+	ActionTransfer = "transfer" // 转接到人工坐席
+)
+
+// ErrUnknownTool 未定义的工具
+var ErrUnknownTool = errors.New("未定义的工具")
+
+// namePattern 工具名和参数名格式
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// calls 工具调用次数
+var calls = metrics.NewCounterVec("ai_dialer_llm_tool_calls_total",
+	"大模型发起的工具调用次数，按工具和结果（ok/error）统计", "tool", "result")
+
+// Parameter 工具参数
+type Parameter struct {
+	Name        string `yaml:"name"`        // 参数名
+	Description string `yaml:"description"` // 参数说明，写入提示词
+	Required    bool   `yaml:"required"`    // 是否必填
+}
+
+// Invocation 一次工具调用
+type Invocation struct {
+	Tool       string            // 工具名
+	SessionID  string            // 对话会话ID
+	CallUUID   string            // 对话关联的通话UUID，非通话对话为空
+	CampaignID string            // 对话所属活动
+	Arguments  map[string]string // 大模型填写的参数，非字符串的值转换为文本
+}
+
+// Action 通话动作
+type Action struct {
+	Type            string // 动作类型: hangup/transfer
+	Destination     string // 转人工的目标号码或分机
+	DialplanContext string // 转人工使用的拨号计划上下文，留空使用FreeSWITCH默认值
+}
+
+// Result 工具执行结果
+type Result struct {
+	Content string  // 写入对话历史供大模型参考的结果
+	Action  *Action // 需要在AI回复播放完后执行的通话动作，为nil时不执行
+}
+
+// Handler 工具处理函数
+type Handler func(ctx context.Context, inv Invocation) (Result, error)
+
+// Tool 可供大模型调用的工具
+type Tool struct {
+	Name        string      // 工具名
+	Description string      // 用途说明，写入提示词
+	Parameters  []Parameter // 参数
+	Handler     Handler     // 处理函数
+}
+
+// validate 校验工具定义
+func (t Tool) validate() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("工具名只能包含小写字母、数字和下划线: %q", t.Name)
+	}
+	if t.Description == "" {
+		return fmt.Errorf("工具 %s 缺少用途说明", t.Name)
+	}
+	for _, p := range t.Parameters {
+		if !namePattern.MatchString(p.Name) {
+			return fmt.Errorf("工具 %s 的参数名只能包含小写字母、数字和下划线: %q", t.Name, p.Name)
+		}
+	}
+	if t.Handler == nil {
+		return fmt.Errorf("工具 %s 缺少处理函数", t.Name)
+	}
+	return nil
+}
+
+// Config 工具调用配置
+type Config struct {
+	Enabled   bool            `yaml:"enabled"`
+	MaxRounds int             `yaml:"max_rounds"` // 每轮对话最多连续调用工具的次数
+	Timeout   time.Duration   `yaml:"timeout"`    // 单次工具执行超时时间
+	Transfer  TransferConfig  `yaml:"transfer"`   // 内置转人工工具
+	Webhooks  []WebhookConfig `yaml:"webhooks"`   // Webhook工具
+}
+
+// Validate 校验配置
+func (c Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.MaxRounds < 0 {
+		return fmt.Errorf("max_rounds: 不能为负数")
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout: 不能为负数")
+	}
+	names := make(map[string]bool)
+	if c.Transfer.Destination != "" {
+		names[TransferToolName] = true
+	}
+	for i, webhook := range c.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return fmt.Errorf("webhooks[%d].%v", i, err)
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("webhooks[%d].name: 工具名重复: %s", i, webhook.Name)
+		}
+		names[webhook.Name] = true
+	}
+	return nil
+}
+
+// registry 编译时注册的工具
+var registry struct {
+	mu    sync.Mutex
+	tools []Tool
+}
+
+// Register 注册Go实现的工具，应在init中调用；启用工具调用时由New加入工具集
+func Register(tool Tool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.tools = append(registry.tools, tool)
+}
+
+// Registry 工具集；方法可在nil上调用，此时没有可用工具
+type Registry struct {
+	config Config
+	tools  map[string]Tool
+	prompt string // 写在提示词开头的工具说明
+}
+
+// New 按配置创建工具集，包含内置转人工工具、Webhook工具和编译时注册的工具；未启用时返回nil
+func New(config Config) (*Registry, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	if config.MaxRounds <= 0 {
+		config.MaxRounds = DefaultMaxRounds
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	r := &Registry{config: config, tools: make(map[string]Tool)}
+	if config.Transfer.Destination != "" {
+		if err := r.Add(TransferToAgent(config.Transfer)); err != nil {
+			return nil, err
+		}
+	}
+	for _, webhook := range config.Webhooks {
+		if err := r.Add(Webhook(webhook)); err != nil {
+			return nil, err
+		}
+	}
+	registry.mu.Lock()
+	registered := append([]Tool(nil), registry.tools...)
+	registry.mu.Unlock()
+	for _, tool := range registered {
+		if err := r.Add(tool); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add 加入工具，工具名重复或定义无效时返回错误
+func (r *Registry) Add(tool Tool) error {
+	if err := tool.validate(); err != nil {
+		return err
+	}
+	if _, ok := r.tools[tool.Name]; ok {
+		return fmt.Errorf("工具名重复: %s", tool.Name)
+	}
+	r.tools[tool.Name] = tool
+	r.prompt = r.describe()
+	return nil
+}
+
+// Names 按名称排序的工具名
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MaxRounds 每轮对话最多连续调用工具的次数，没有工具时为0
+func (r *Registry) MaxRounds() int {
+	if r == nil {
+		return 0
+	}
+	return r.config.MaxRounds
+}
+
+// Prompt 写在提示词开头的工具说明和调用格式，没有工具时为空
+func (r *Registry) Prompt() string {
+	if r == nil {
+		return ""
+	}
+	return r.prompt
+}
+
+// describe 生成工具说明
+func (r *Registry) describe() string {
+	var b strings.Builder
+	b.WriteString("可用工具（需要查询信息或执行操作时，只回复一行JSON：{\"tool\": \"工具名\", \"arguments\": {\"参数名\": \"值\"}}，" +
+		"不要附加其他文字；收到工具结果后再回复客户；不需要工具时直接回复客户）：\n")
+	for _, name := range r.Names() {
+		tool := r.tools[name]
+		fmt.Fprintf(&b, "- %s: %s", tool.Name, tool.Description)
+		if len(tool.Parameters) > 0 {
+			params := make([]string, len(tool.Parameters))
+			for i, p := range tool.Parameters {
+				params[i] = p.Name
+				if p.Description != "" {
+					params[i] += "（" + p.Description + "）"
+				}
+				if p.Required {
+					params[i] += "必填"
+				}
+			}
+			b.WriteString("；参数: " + strings.Join(params, "，"))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Call 从大模型回复中解析出的工具调用
+type Call struct {
+	Tool      string
+	Arguments map[string]string
+}
+
+// Parse 解析大模型回复，回复是工具调用JSON（允许包在```代码块中）时返回调用；没有工具时总是返回false
+func (r *Registry) Parse(reply string) (Call, bool) {
+	if r == nil {
+		return Call{}, false
+	}
+	text := strings.TrimSpace(reply)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "```"), "```")
+		text = strings.TrimSpace(strings.TrimPrefix(text, "json"))
+	}
+	if !strings.HasPrefix(text, "{") || !strings.HasSuffix(text, "}") {
+		return Call{}, false
+	}
+	var raw struct {
+		Tool      string                 `json:"tool"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil || raw.Tool == "" {
+		return Call{}, false
+	}
+	call := Call{Tool: raw.Tool, Arguments: make(map[string]string, len(raw.Arguments))}
+	for name, value := range raw.Arguments {
+		if s, ok := value.(string); ok {
+			call.Arguments[name] = s
+		} else if data, err := json.Marshal(value); err == nil {
+			call.Arguments[name] = string(data)
+		}
+	}
+	return call, true
+}
+
+// MaybeCall 流式回复的开头是否可能是工具调用，可能时应暂缓输出直到生成完成；没有工具时总是返回false
+func (r *Registry) MaybeCall(prefix string) bool {
+	if r == nil {
+		return false
+	}
+	text := strings.TrimSpace(prefix)
+	return text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "`")
+}
+
+// Execute 执行工具调用，必填参数缺失或工具未定义时返回错误，超过timeout时取消
+func (r *Registry) Execute(ctx context.Context, inv Invocation) (Result, error) {
+	if r == nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownTool, inv.Tool)
+	}
+	tool, ok := r.tools[inv.Tool]
+	if !ok {
+		calls.Inc(inv.Tool, "error")
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownTool, inv.Tool)
+	}
+	for _, p := range tool.Parameters {
+		if p.Required && strings.TrimSpace(inv.Arguments[p.Name]) == "" {
+			calls.Inc(inv.Tool, "error")
+			return Result{}, fmt.Errorf("缺少必填参数: %s", p.Name)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+	result, err := r.run(ctx, tool, inv)
+	if err != nil {
+		calls.Inc(inv.Tool, "error")
+		return Result{}, err
+	}
+	calls.Inc(inv.Tool, "ok")
+	return result, nil
+}
+
+// run 调用处理函数，处理函数panic时返回错误
+func (r *Registry) run(ctx context.Context, tool Tool, inv Invocation) (result Result, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("工具 %s 发生panic: %v", tool.Name, p)
+			err = fmt.Errorf("工具执行异常")
+		}
+	}()
+	return tool.Handler(ctx, inv)
+}
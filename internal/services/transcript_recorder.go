@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/lang"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/sentiment"
+)
+
+// 实时转写保存的限制
+const (
+	transcriptWriteTimeout = 5 * time.Second // 单条转写写入数据库的超时时间
+	maxCallDuration        = 12 * time.Hour  // 通话开始时间的最长保留时间，未收到挂断事件的通话超过后清除
+)
+
+// TranscriptRecorder 通话中实时保存转写：客户语音识别结果、AI回复、客户按键和通话接通、挂断事件，
+// 时间为相对通话接通的偏移；本实例未收到接通事件的通话（如音频流接入其他实例）以第一条转写的时间为起点。
+// 方法可在nil上调用，此时不保存
+type TranscriptRecorder struct {
+	store     TranscriptStore
+	sentiment *sentiment.Analyzer // 客户情绪识别，为nil时不识别
+
+	mu     sync.Mutex
+	starts map[string]time.Time // 按通话UUID记录的通话开始时间
+}
+
+// NewTranscriptRecorder 创建实时转写保存
+func NewTranscriptRecorder(store TranscriptStore) *TranscriptRecorder {
+	return &TranscriptRecorder{store: store, starts: make(map[string]time.Time)}
+}
+
+// Start 记录通话接通时间，之后的转写按该时间计算偏移
+func (r *TranscriptRecorder) Start(callUUID string, at time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for uuid, start := range r.starts {
+		if at.Sub(start) > maxCallDuration {
+			delete(r.starts, uuid)
+		}
+	}
+	r.starts[callUUID] = at
+}
+
+// SetSentiment 设置客户情绪识别：客户语音按情感词典标注后保存，配置了大模型分类时在后台重新分类并更新
+func (r *TranscriptRecorder) SetSentiment(analyzer *sentiment.Analyzer) {
+	r.sentiment = analyzer
+}
+
+// sentimentStore 可更新转写情绪的存储，如repositories.TranscriptRepo
+type sentimentStore interface {
+	SetSentiment(ctx context.Context, id int64, sentiment string) error
+}
+
+// Finish 通话结束，清除通话开始时间
+func (r *TranscriptRecorder) Finish(callUUID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.starts, callUUID)
+}
+
+// Record 保存一条转写，start和end为条目的起止时间；写入失败时记录日志，不影响通话
+func (r *TranscriptRecorder) Record(ctx context.Context, callUUID, speaker, kind, text string, start, end time.Time) {
+	if r == nil || callUUID == "" || text == "" {
+		return
+	}
+	origin := r.origin(callUUID, start)
+	t := &models.Transcript{
+		CallUUID: callUUID,
+		Speaker:  speaker,
+		Kind:     kind,
+		Text:     text,
+		StartMs:  offsetMs(origin, start),
+		EndMs:    offsetMs(origin, end),
+	}
+	if kind == models.TranscriptKindSpeech {
+		t.Language = lang.Detect(text)
+	}
+	customer := speaker == models.SpeakerCustomer && kind == models.TranscriptKindSpeech
+	if customer {
+		t.Sentiment = r.sentiment.Lexicon(text)
+	}
+	ctx = context.WithoutCancel(ctx)
+	writeCtx, cancel := context.WithTimeout(ctx, transcriptWriteTimeout)
+	defer cancel()
+	if err := r.store.Append(writeCtx, t); err != nil {
+		log.Printf("保存通话转写失败 - UUID: %s: %v", callUUID, err)
+		return
+	}
+	// 大模型分类较慢，在后台进行，不影响AI回复
+	if store, ok := r.store.(sentimentStore); ok && customer && r.sentiment.UsesLLM() {
+		go r.classify(ctx, store, t)
+	}
+}
+
+// classify 调用大模型重新判断客户语句的情绪，与词典标注不同时更新；分类失败时保留词典标注的结果
+func (r *TranscriptRecorder) classify(ctx context.Context, store sentimentStore, t *models.Transcript) {
+	label, err := r.sentiment.Classify(ctx, t.Text)
+	if err != nil {
+		log.Printf("客户情绪分类失败 - UUID: %s: %v", t.CallUUID, err)
+		return
+	}
+	if label == t.Sentiment {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, transcriptWriteTimeout)
+	defer cancel()
+	if err := store.SetSentiment(ctx, t.ID, label); err != nil {
+		log.Printf("保存客户情绪失败 - UUID: %s: %v", t.CallUUID, err)
+	}
+}
+
+// origin 通话开始时间，未记录时以at为开始时间
+func (r *TranscriptRecorder) origin(callUUID string, at time.Time) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	start, ok := r.starts[callUUID]
+	if !ok {
+		start = at
+		r.starts[callUUID] = start
+	}
+	return start
+}
+
+// offsetMs 相对通话开始的毫秒数，早于通话开始时为0
+func offsetMs(origin, t time.Time) int {
+	if t.Before(origin) {
+		return 0
+	}
+	return int(t.Sub(origin) / time.Millisecond)
+}
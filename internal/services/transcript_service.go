@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ai_dialer_mini/internal/lang"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+)
+
+// 转写检索分页限制
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// rekeyBatchSize 重新加密转写时每批处理的条数
+const rekeyBatchSize = 500
+
+// minSearchRunes 检索短语的最少字数，与MySQL ngram分词器默认的ngram_token_size一致
+const minSearchRunes = 2
+
+// ErrInvalidSearchQuery 检索条件无效
+var ErrInvalidSearchQuery = errors.New("检索条件无效")
+
+// ErrTranscriptNotFound 通话没有转写
+var ErrTranscriptNotFound = errors.New("通话没有转写")
+
+// speakerLabels 纯文本导出时说话方的显示名称
+var speakerLabels = map[string]string{
+	models.SpeakerCustomer: "客户",
+	models.SpeakerAI:       "AI",
+	models.SpeakerAgent:    "坐席",
+	models.SpeakerSystem:   "系统",
+}
+
+// TranscriptService 通话转写服务
+type TranscriptService struct {
+	store  *repositories.Store
+	pinyin *lang.Pinyin
+}
+
+// NewTranscriptService 创建通话转写服务
+func NewTranscriptService(store *repositories.Store) *TranscriptService {
+	return &TranscriptService{store: store}
+}
+
+// SetPinyin 设置拼音转写，未设置时不支持为检索结果附加拼音
+func (s *TranscriptService) SetPinyin(pinyin *lang.Pinyin) {
+	s.pinyin = pinyin
+}
+
+// Search 检索包含指定短语的转写片段，供质检人员查找通话
+func (s *TranscriptService) Search(ctx context.Context, q models.TranscriptSearchQuery) ([]*models.TranscriptHit, error) {
+	q.Query = strings.TrimSpace(q.Query)
+	if utf8.RuneCountInString(q.Query) < minSearchRunes {
+		return nil, fmt.Errorf("%w: 检索短语至少需要%d个字", ErrInvalidSearchQuery, minSearchRunes)
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, fmt.Errorf("%w: 起始时间必须早于截止时间", ErrInvalidSearchQuery)
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > maxSearchLimit {
+		q.Limit = maxSearchLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Romanize && s.pinyin == nil {
+		return nil, fmt.Errorf("%w: 未配置拼音字典，不支持拼音转写", ErrInvalidSearchQuery)
+	}
+
+	hits, err := s.store.Transcripts.Search(ctx, q)
+	if err != nil || !q.Romanize {
+		return hits, err
+	}
+	for _, hit := range hits {
+		s.romanize(&hit.Transcript)
+	}
+	return hits, nil
+}
+
+// Export 导出通话的完整转写，包括语音、按键和通话事件，按时间范围和说话方过滤；通话没有转写时返回ErrTranscriptNotFound
+func (s *TranscriptService) Export(ctx context.Context, callUUID string, filter models.TranscriptFilter) ([]*models.Transcript, error) {
+	if filter.From != nil && filter.To != nil && *filter.From >= *filter.To {
+		return nil, fmt.Errorf("%w: 起始时间必须早于截止时间", ErrInvalidSearchQuery)
+	}
+	for _, speaker := range filter.Speakers {
+		if _, ok := speakerLabels[speaker]; !ok {
+			return nil, fmt.Errorf("%w: 未知的说话方 %s", ErrInvalidSearchQuery, speaker)
+		}
+	}
+	all, err := s.store.Transcripts.Timeline(ctx, callUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrTranscriptNotFound, callUUID)
+	}
+
+	transcripts := make([]*models.Transcript, 0, len(all))
+	for _, t := range all {
+		start := time.Duration(t.StartMs) * time.Millisecond
+		if filter.From != nil && start < *filter.From {
+			continue
+		}
+		if filter.To != nil && start >= *filter.To {
+			continue
+		}
+		if len(filter.Speakers) > 0 && !containsString(filter.Speakers, t.Speaker) {
+			continue
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, nil
+}
+
+// followUpNegativeRatio 负面语句占比达到该值时建议人工跟进
+const followUpNegativeRatio = 0.3
+
+// Sentiment 汇总通话中客户语句的情绪；通话没有标注情绪的客户语句时返回ErrTranscriptNotFound
+func (s *TranscriptService) Sentiment(ctx context.Context, callUUID string) (*models.SentimentSummary, error) {
+	sentiments, err := s.store.Transcripts.Sentiments(ctx, callUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sentiments) == 0 {
+		return nil, fmt.Errorf("%w: %s 没有标注情绪的客户语句", ErrTranscriptNotFound, callUUID)
+	}
+	return SummarizeSentiment(callUUID, sentiments), nil
+}
+
+// SummarizeSentiment 按时间顺序的客户语句情绪计算汇总
+func SummarizeSentiment(callUUID string, sentiments []string) *models.SentimentSummary {
+	summary := &models.SentimentSummary{CallUUID: callUUID, Utterances: len(sentiments), Overall: models.SentimentNeutral}
+	for _, sentiment := range sentiments {
+		switch sentiment {
+		case models.SentimentPositive:
+			summary.Positive++
+		case models.SentimentNegative:
+			summary.Negative++
+		default:
+			summary.Neutral++
+		}
+	}
+	if len(sentiments) == 0 {
+		return summary
+	}
+	summary.Last = sentiments[len(sentiments)-1]
+	summary.Score = float64(summary.Positive-summary.Negative) / float64(summary.Utterances)
+	switch {
+	case summary.Score > 0:
+		summary.Overall = models.SentimentPositive
+	case summary.Score < 0:
+		summary.Overall = models.SentimentNegative
+	}
+	summary.FollowUp = summary.Last == models.SentimentNegative ||
+		float64(summary.Negative)/float64(summary.Utterances) >= followUpNegativeRatio
+	return summary
+}
+
+// FormatTranscriptText 将转写格式化为纯文本，每条一行，如“[01:02.345] 客户: 你好”，按键标注为“客户(按键)”
+func FormatTranscriptText(transcripts []*models.Transcript) string {
+	var b strings.Builder
+	for _, t := range transcripts {
+		label := speakerLabels[t.Speaker]
+		if label == "" {
+			label = t.Speaker
+		}
+		if t.Kind == models.TranscriptKindDTMF {
+			label += "(按键)"
+		}
+		offset := time.Duration(t.StartMs) * time.Millisecond
+		fmt.Fprintf(&b, "[%02d:%02d.%03d] %s: %s\n",
+			int(offset/time.Minute), int(offset%time.Minute/time.Second), int(offset%time.Second/time.Millisecond), label, t.Text)
+	}
+	return b.String()
+}
+
+// containsString 切片是否包含s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// romanize 为中文转写片段附加拼音，未识别语种的历史片段按文本重新识别
+func (s *TranscriptService) romanize(t *models.Transcript) {
+	language := t.Language
+	if language == "" {
+		language = lang.Detect(t.Text)
+	}
+	if language == lang.Chinese {
+		t.Romanized = s.pinyin.Romanize(t.Text)
+	}
+}
+
+// Rekey 用当前主密钥重新加密全部转写的数据密钥，并加密启用加密前写入的明文；
+// 主密钥轮换后执行，完成后即可从配置中移除旧密钥。未启用加密时不做处理
+func (s *TranscriptService) Rekey(ctx context.Context) error {
+	var afterID int64
+	total := 0
+	for {
+		lastID, updated, err := s.store.Transcripts.Rewrap(ctx, afterID, rekeyBatchSize)
+		total += updated
+		if err != nil {
+			return err
+		}
+		if lastID == 0 {
+			break
+		}
+		afterID = lastID
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if total > 0 {
+		log.Printf("通话转写重新加密完成，更新 %d 条", total)
+	}
+	return nil
+}
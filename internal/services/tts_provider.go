@@ -0,0 +1,32 @@
+package services
+
+import (
+	"fmt"
+
+	"ai_dialer_mini/internal/clients/mock"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/clients/xfyun"
+	"ai_dialer_mini/internal/config"
+)
+
+// NewTTSProvider 根据tts.provider创建语音合成服务，未配置时返回nil
+func NewTTSProvider(cfg *config.Config) (tts.Provider, error) {
+	switch cfg.TTS.Provider {
+	case "":
+		return nil, nil
+	case config.ProviderXFYun:
+		client, err := xfyun.NewTTSClient(cfg.TTS.XFYun)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case config.ProviderMock:
+		client, err := mock.NewTTSClient(cfg.TTS.Mock)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("不支持的语音合成后端: %s", cfg.TTS.Provider)
+	}
+}
@@ -0,0 +1,147 @@
+// Package turn 管理对话轮次状态，处理客户要求“稍等”时的暂停与恢复
+package turn
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxHoldRunes 超过该字数的识别结果不视为暂停请求，避免“等一下，我想问问价格”之类的句子被误判
+const maxHoldRunes = 10
+
+// State 对话状态
+type State string
+
+// 对话状态
+const (
+	StateActive State = "active" // 正常对话
+	StateHeld   State = "held"   // 客户要求稍等，暂停回复，只保持识别
+)
+
+// Action 收到识别结果后应执行的动作
+type Action int
+
+// 收到识别结果后应执行的动作
+const (
+	ActionReply  Action = iota // 正常生成回复
+	ActionHold                 // 进入暂停，回复暂停提示语
+	ActionResume               // 客户重新开口，恢复对话并正常生成回复
+	ActionIgnore               // 暂停中，不回复
+)
+
+// Config 轮次管理配置
+type Config struct {
+	HoldPhrases []string      `yaml:"hold_phrases"` // 触发暂停的短语，为空时不启用暂停
+	HoldReply   string        `yaml:"hold_reply"`   // 进入暂停时的提示语
+	ResumeReply string        `yaml:"resume_reply"` // 暂停超时自动恢复时的提示语，为空则静默恢复
+	HoldTimeout time.Duration `yaml:"hold_timeout"` // 暂停超时时间
+}
+
+// Manager 单个会话的轮次状态
+type Manager struct {
+	config    Config
+	onTimeout func()
+
+	mu     sync.Mutex
+	state  State
+	timer  *time.Timer
+	closed bool
+}
+
+// New 创建轮次管理，暂停超时自动恢复时调用onTimeout
+func New(config Config, onTimeout func()) *Manager {
+	return &Manager{config: config, onTimeout: onTimeout, state: StateActive}
+}
+
+// State 获取当前状态
+func (m *Manager) State() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// OnTranscript 根据客户的识别结果切换状态，返回应执行的动作
+func (m *Manager) OnTranscript(text string) Action {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	isHold := m.isHoldRequest(text)
+	switch {
+	case m.state == StateActive && isHold:
+		m.state = StateHeld
+		m.startTimer()
+		return ActionHold
+	case m.state == StateHeld && isHold:
+		m.startTimer()
+		return ActionIgnore
+	case m.state == StateHeld:
+		m.state = StateActive
+		m.stopTimer()
+		return ActionResume
+	default:
+		return ActionReply
+	}
+}
+
+// Close 停止计时，会话结束时调用
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.stopTimer()
+}
+
+// isHoldRequest 判断识别结果是否为暂停请求
+func (m *Manager) isHoldRequest(text string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+	if normalized == "" || utf8.RuneCountInString(normalized) > maxHoldRunes {
+		return false
+	}
+	for _, phrase := range m.config.HoldPhrases {
+		if phrase != "" && strings.Contains(normalized, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// startTimer 重新开始暂停计时，调用方需持有m.mu
+func (m *Manager) startTimer() {
+	m.stopTimer()
+	if m.config.HoldTimeout <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(m.config.HoldTimeout, func() {
+		m.mu.Lock()
+		if m.closed || m.timer != timer || m.state != StateHeld {
+			m.mu.Unlock()
+			return
+		}
+		m.state = StateActive
+		m.timer = nil
+		m.mu.Unlock()
+
+		if m.onTimeout != nil {
+			m.onTimeout()
+		}
+	})
+	m.timer = timer
+}
+
+// stopTimer 停止暂停计时，调用方需持有m.mu
+func (m *Manager) stopTimer() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+}
@@ -0,0 +1,253 @@
+// Package wrapup 挂机后按活动配置执行收尾动作（推送Webhook、创建CRM任务、安排回拨、发送短信）
+// 动作与通话详单在同一事务中写入发件箱，由发件箱投递任务按各动作的重试策略执行
+package wrapup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"text/template"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/outbox"
+)
+
+// 动作类型
+const (
+	ActionWebhook  = "webhook"  // 推送Webhook
+	ActionCRMTask  = "crm_task" // 创建CRM任务
+	ActionCallback = "callback" // 安排回拨
+	ActionSMS      = "sms"      // 发送短信
+)
+
+// Action 收尾动作
+type Action struct {
+	Type         string        `yaml:"type"`         // 动作类型
+	Name         string        `yaml:"name"`         // 动作名称，用于区分同类型的多个动作，为空时使用类型
+	URL          string        `yaml:"url"`          // Webhook地址、短信网关地址或CRM地址（CRM为空时使用webhook.crm_url）
+	To           string        `yaml:"to"`           // 短信接收号码模板，为空时发给线索号码，没有线索时发给被叫号码
+	Template     string        `yaml:"template"`     // 短信内容或CRM任务标题模板，可使用 .CDR .Call .Lead
+	Delay        time.Duration `yaml:"delay"`        // 回拨延迟，从挂机时间算起
+	Dispositions []string      `yaml:"dispositions"` // 仅在这些挂断原因下执行，为空时总是执行
+	MaxAttempts  int           `yaml:"max_attempts"` // 最大投递次数，为0时使用发件箱全局配置
+	Backoff      time.Duration `yaml:"backoff"`      // 首次重试间隔，之后每次翻倍，为0时使用发件箱默认策略
+}
+
+// Config 收尾动作配置
+type Config struct {
+	Duration  time.Duration       `yaml:"duration"`  // 话后处理时长，挂机后保留通话会话，期间主管可修改通话结果，为0时挂机即结束
+	Default   []Action            `yaml:"default"`   // 未单独配置的活动及手动呼叫使用的动作
+	Campaigns map[string][]Action `yaml:"campaigns"` // 按活动ID配置的动作，配置后不再执行默认动作
+}
+
+// Data 模板数据及推送内容
+type Data struct {
+	Action string       `json:"action"`         // 动作名称
+	CDR    models.CDR   `json:"cdr"`            // 通话详单
+	Call   *models.Call `json:"call,omitempty"` // 通话记录，未登记的通话为空
+	Lead   *models.Lead `json:"lead,omitempty"` // 关联线索，手动呼叫为空
+}
+
+// compiledAction 解析好模板的动作
+type compiledAction struct {
+	Action
+	to       *template.Template
+	template *template.Template
+}
+
+// Executor 收尾动作执行器
+type Executor struct {
+	crmURL    string
+	defaults  []compiledAction
+	campaigns map[string][]compiledAction
+}
+
+// New 创建收尾动作执行器，检查动作配置并解析模板
+func New(config Config, crmURL string) (*Executor, error) {
+	if config.Duration < 0 {
+		return nil, fmt.Errorf("wrapup.duration: 不能为负数")
+	}
+	e := &Executor{crmURL: crmURL, campaigns: make(map[string][]compiledAction)}
+
+	var err error
+	if e.defaults, err = compile(config.Default, crmURL); err != nil {
+		return nil, fmt.Errorf("wrapup.default: %v", err)
+	}
+	for campaignID, actions := range config.Campaigns {
+		if e.campaigns[campaignID], err = compile(actions, crmURL); err != nil {
+			return nil, fmt.Errorf("wrapup.campaigns.%s: %v", campaignID, err)
+		}
+	}
+	return e, nil
+}
+
+// compile 检查动作配置并解析模板
+func compile(actions []Action, crmURL string) ([]compiledAction, error) {
+	names := make(map[string]bool)
+	compiled := make([]compiledAction, 0, len(actions))
+	for i, action := range actions {
+		if action.Name == "" {
+			action.Name = action.Type
+		}
+		if names[action.Name] {
+			return nil, fmt.Errorf("动作名称重复: %s", action.Name)
+		}
+		names[action.Name] = true
+
+		switch action.Type {
+		case ActionWebhook, ActionSMS:
+			if action.URL == "" {
+				return nil, fmt.Errorf("第%d个动作(%s)缺少url", i+1, action.Type)
+			}
+		case ActionCRMTask:
+			if action.URL == "" && crmURL == "" {
+				return nil, fmt.Errorf("第%d个动作(%s)缺少url，且未配置webhook.crm_url", i+1, action.Type)
+			}
+		case ActionCallback:
+			if action.Delay <= 0 {
+				return nil, fmt.Errorf("第%d个动作(%s)的delay必须大于0", i+1, action.Type)
+			}
+		default:
+			return nil, fmt.Errorf("第%d个动作类型不支持: %s", i+1, action.Type)
+		}
+
+		c := compiledAction{Action: action}
+		var err error
+		if c.to, err = template.New("to").Parse(action.To); err != nil {
+			return nil, fmt.Errorf("第%d个动作的to模板无效: %v", i+1, err)
+		}
+		if c.template, err = template.New("template").Parse(action.Template); err != nil {
+			return nil, fmt.Errorf("第%d个动作的template模板无效: %v", i+1, err)
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Run 在详单事务中执行收尾动作：推送类动作写入发件箱，回拨直接更新线索
+func (e *Executor) Run(ctx context.Context, uow *repositories.UnitOfWork, ob *outbox.Outbox, cdr models.CDR) error {
+	data := Data{CDR: cdr}
+
+	call, err := uow.Calls.Get(ctx, cdr.CallUUID)
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	data.Call = call
+	if call != nil && call.LeadID != nil {
+		lead, err := uow.Leads.Get(ctx, *call.LeadID)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return err
+		}
+		data.Lead = lead
+	}
+
+	actions := e.defaults
+	if call != nil && call.CampaignID != nil {
+		if campaignActions, ok := e.campaigns[strconv.FormatInt(*call.CampaignID, 10)]; ok {
+			actions = campaignActions
+		}
+	}
+
+	for _, action := range actions {
+		if !action.matches(cdr.HangupCause) {
+			continue
+		}
+		data.Action = action.Name
+		if err := e.run(ctx, uow, ob, action, data); err != nil {
+			return fmt.Errorf("执行收尾动作 %s 失败: %v", action.Name, err)
+		}
+	}
+	return nil
+}
+
+// run 执行单个动作
+func (e *Executor) run(ctx context.Context, uow *repositories.UnitOfWork, ob *outbox.Outbox, action compiledAction, data Data) error {
+	msg := outbox.Message{
+		IdempotencyKey: fmt.Sprintf("wrapup:%s:%s", action.Name, data.CDR.CallUUID),
+		TargetURL:      action.URL,
+		MaxAttempts:    action.MaxAttempts,
+		Backoff:        action.Backoff,
+	}
+
+	switch action.Type {
+	case ActionWebhook:
+		msg.Destination = outbox.DestinationWebhook
+		msg.EventType = "call.wrapup"
+		msg.Payload = data
+
+	case ActionCRMTask:
+		title, err := render(action.template, data)
+		if err != nil {
+			return err
+		}
+		if msg.TargetURL == "" {
+			msg.TargetURL = e.crmURL
+		}
+		msg.Destination = outbox.DestinationCRM
+		msg.EventType = "crm.task.create"
+		msg.Payload = map[string]interface{}{
+			"title": title,
+			"call":  data,
+		}
+
+	case ActionSMS:
+		text, err := render(action.template, data)
+		if err != nil {
+			return err
+		}
+		to, err := render(action.to, data)
+		if err != nil {
+			return err
+		}
+		if to == "" && data.Lead != nil {
+			to = data.Lead.Phone
+		}
+		if to == "" {
+			to = data.CDR.Callee
+		}
+		msg.Destination = outbox.DestinationSMS
+		msg.EventType = "sms.send"
+		msg.Payload = map[string]string{
+			"to":        to,
+			"text":      text,
+			"call_uuid": data.CDR.CallUUID,
+		}
+
+	case ActionCallback:
+		if data.Lead == nil {
+			log.Printf("通话没有关联线索，跳过回拨: %s", data.CDR.CallUUID)
+			return nil
+		}
+		next := data.CDR.EndTime.Add(action.Delay)
+		return uow.Leads.UpdateStatus(ctx, data.Lead.ID, models.LeadStatusQueued, &next)
+	}
+
+	return ob.Enqueue(ctx, uow.Tx(), msg)
+}
+
+// matches 判断挂断原因是否满足动作的执行条件
+func (a compiledAction) matches(disposition string) bool {
+	if len(a.Dispositions) == 0 {
+		return true
+	}
+	for _, d := range a.Dispositions {
+		if d == disposition {
+			return true
+		}
+	}
+	return false
+}
+
+// render 渲染模板
+func render(tmpl *template.Template, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染模板失败: %v", err)
+	}
+	return buf.String(), nil
+}
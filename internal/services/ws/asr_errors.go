@@ -0,0 +1,76 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/services/lifecycle"
+)
+
+// asrErrorKindUnknown 未分类的识别错误，如网络中断、结果解析失败
+const asrErrorKindUnknown = "unknown"
+
+// asrErrors 语音识别错误次数
+var asrErrors = metrics.NewCounterVec("ai_dialer_asr_errors_total",
+	"语音识别错误次数，按错误分类和服务方错误码统计", "kind", "code")
+
+// diagnosable 带分类和处理建议的识别错误，如xfyun.ServerError
+type diagnosable interface {
+	error
+	Kind() string
+	ErrorCode() string
+	Remediation() string
+}
+
+// diagnoseASR 识别错误的分类、错误码和处理建议，无法分类时分类为unknown
+func diagnoseASR(err error) (kind, code, remediation string) {
+	var d diagnosable
+	if errors.As(err, &d) {
+		return d.Kind(), d.ErrorCode(), d.Remediation()
+	}
+	return asrErrorKindUnknown, "", ""
+}
+
+// asrErrorState 语音识别服务最近一次失败的情况，在就绪检查中展示
+type asrErrorState struct {
+	mu       sync.Mutex
+	err      error
+	failures int
+	since    time.Time
+}
+
+// record 记录一次识别结果，成功时清除失败情况
+func (a *asrErrorState) record(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		a.err, a.failures, a.since = nil, 0, time.Time{}
+		return
+	}
+	a.err = err
+	a.failures++
+	a.since = time.Now()
+}
+
+// ASRDependency 语音识别服务的最近状态，登记到进程生命周期后在就绪检查中展示错误分类和处理建议
+func (s *ASRServer) ASRDependency() lifecycle.Dependency {
+	a := &s.asrState
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		return lifecycle.Dependency{Status: lifecycle.DependencyOK}
+	}
+	kind, code, remediation := diagnoseASR(a.err)
+	since := a.since
+	return lifecycle.Dependency{
+		Status:      lifecycle.DependencyError,
+		Error:       a.err.Error(),
+		Kind:        kind,
+		Code:        code,
+		Remediation: remediation,
+		Failures:    a.failures,
+		Since:       &since,
+	}
+}
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"log"
+
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/assist"
+)
+
+// callAssists 可查询通话是否为坐席通话的通话会话跟踪，由services.CallSessionManager实现
+type callAssists interface {
+	CallAssisted(callUUID string) bool
+}
+
+// assistMarker 可将通话标记为坐席通话的通话会话跟踪，由services.CallSessionManager实现
+type assistMarker interface {
+	SetAssist(callUUID string) bool
+}
+
+// assisted 通话是否为坐席通话：坐席确认预览线索后发起的呼叫由坐席接听，AI只作为助手
+func (s *ASRServer) assisted(callUUID string) bool {
+	if callUUID == "" {
+		return false
+	}
+	lookup, ok := s.Calls.(callAssists)
+	return ok && lookup.CallAssisted(callUUID)
+}
+
+// handoff 通话转接到人工坐席后，音频流连接继续识别客户语音，AI不再说话，改为推送建议话术；
+// 坐席说话不经过音频流，同时停止静音检测
+func (s *ASRServer) handoff(conn *lockedConn) {
+	if conn.assist.Swap(true) {
+		return
+	}
+	conn.deadAir.Close()
+	if marker, ok := s.Calls.(assistMarker); ok {
+		marker.SetAssist(conn.callUUID)
+	}
+	log.Printf("通话已转人工，AI改为坐席辅助: %s", conn.callUUID)
+}
+
+// suggest 坐席通话中为客户的一句话推送转写、相关知识片段和建议话术，不合成语音也不在通话中播放
+// 建议话术同样写入对话历史，后续建议可以参考
+func (s *ASRServer) suggest(conn *lockedConn, sessionID, text string) {
+	s.Assist.Publish(assist.Message{Type: assist.MessageTranscript, CallUUID: conn.callUUID, Text: text})
+	if snippets := s.Assist.Knowledge(text); len(snippets) > 0 {
+		s.Assist.Publish(assist.Message{Type: assist.MessageKnowledge, CallUUID: conn.callUUID, Snippets: snippets})
+	}
+
+	suggestion, err := s.DialogSvc.ProcessMessage(sessionID, text)
+	if err != nil {
+		log.Printf("生成建议话术失败: %v", err)
+		return
+	}
+	_, suggestion = tts.SplitStyle(suggestion)
+	s.Assist.Publish(assist.Message{Type: assist.MessageSuggestion, CallUUID: conn.callUUID, Text: suggestion})
+	s.publishEvent(sessionID, models.EventTypeSuggestion, models.SpeakerAI, suggestion, true)
+	if err := conn.WriteJSON(ASRResponse{Text: text, Suggestion: suggestion, IsEnd: true}); err != nil {
+		log.Printf("发送建议话术失败: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/audio/vad"
+)
+
+// MessageTypeBargeIn 客户打断AI说话时服务端发送的消息类型
+const MessageTypeBargeIn = "barge_in"
+
+// ErrInterrupted 回复被客户打断
+var ErrInterrupted = errors.New("回复被客户打断")
+
+// BargeInMessage 客户打断消息，客户端收到后应立即停止播放已收到的语音
+type BargeInMessage struct {
+	Type string `json:"type"` // 消息类型，固定为barge_in
+}
+
+// replyState 单个连接正在进行的回复，客户开口时可打断
+type replyState struct {
+	mu            sync.Mutex
+	cancel        context.CancelFunc
+	done          chan struct{}
+	speakingUntil time.Time // 已发送的AI语音预计播放结束的时间
+}
+
+// start 在后台执行一轮回复，之前未完成的回复先被打断
+func (r *replyState) start(fn func(ctx context.Context)) {
+	r.interrupt()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.cancel, r.done = cancel, done
+	r.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		fn(ctx)
+	}()
+}
+
+// interrupt 打断进行中的回复并等待其退出，返回AI是否仍在说话（回复未完成或语音尚未播放完）
+func (r *replyState) interrupt() bool {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	speaking := time.Now().Before(r.speakingUntil)
+	r.cancel, r.done = nil, nil
+	r.speakingUntil = time.Time{}
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return speaking
+	}
+	select {
+	case <-done:
+	default:
+		speaking = true
+		cancel()
+		<-done
+	}
+	return speaking
+}
+
+// wait 等待进行中的回复结束
+func (r *replyState) wait() {
+	r.mu.Lock()
+	done := r.done
+	r.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// spoke 记录已发送一段时长为d的AI语音，客户端按顺序排队播放
+func (r *replyState) spoke(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if r.speakingUntil.Before(now) {
+		r.speakingUntil = now
+	}
+	r.speakingUntil = r.speakingUntil.Add(d)
+}
+
+// remaining 已发送的AI语音预计还需播放的时长
+func (r *replyState) remaining() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d := time.Until(r.speakingUntil); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// detectBargeIn 检测客户是否开始说话，AI正在说话时打断回复、停止通话中的播放并通知客户端
+func (s *ASRServer) detectBargeIn(conn *lockedConn, sessionID string, detector *vad.Detector, data []byte) {
+	if detector == nil || !detector.Write(data) {
+		return
+	}
+	if !conn.replies.interrupt() {
+		return
+	}
+
+	log.Printf("客户打断AI说话: %s", sessionID)
+	if s.Playback != nil && conn.callUUID != "" {
+		if err := s.Playback.Stop(conn.callUUID); err != nil {
+			log.Printf("停止通话中播放失败: %v", err)
+		}
+	}
+	if err := conn.WriteJSON(BargeInMessage{Type: MessageTypeBargeIn}); err != nil {
+		log.Printf("发送打断消息失败: %v", err)
+	}
+}
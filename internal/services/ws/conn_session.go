@@ -0,0 +1,70 @@
+package ws
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connSession 单个WebSocket连接的状态：最后活动时间、语法设置和服务端关闭原因。
+// 由连接的处理goroutine创建并在连接结束时注销，各连接的状态互不加锁，心跳检查不阻塞消息处理
+type connSession struct {
+	conn         *websocket.Conn
+	lastActivity atomic.Int64 // 最后活动时间（UnixNano）
+
+	mu          sync.Mutex
+	grammar     string // 客户端设置的识别语法
+	closeReason string // 服务端主动关闭连接的原因，未关闭时为空
+}
+
+// openSession 登记新连接，返回的会话需在连接结束时通过finishConn注销
+func (s *ASRServer) openSession(conn *websocket.Conn) *connSession {
+	sess := &connSession{conn: conn}
+	sess.touch()
+	s.sessions.Store(conn, sess)
+	return sess
+}
+
+// eachSession 遍历进行中的连接
+func (s *ASRServer) eachSession(fn func(*connSession)) {
+	s.sessions.Range(func(_, value any) bool {
+		fn(value.(*connSession))
+		return true
+	})
+}
+
+// touch 更新最后活动时间
+func (c *connSession) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// idle 距最后活动的时长
+func (c *connSession) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, c.lastActivity.Load()))
+}
+
+// setGrammar 记录客户端设置的识别语法
+func (c *connSession) setGrammar(grammar string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grammar = grammar
+}
+
+// close 记录关闭原因后关闭连接，已有关闭原因时保留先记录的原因
+func (c *connSession) close(reason string) {
+	c.mu.Lock()
+	if c.closeReason == "" {
+		c.closeReason = reason
+	}
+	c.mu.Unlock()
+	c.conn.Close()
+}
+
+// closedBy 服务端主动关闭连接的原因
+func (c *connSession) closedBy() (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeReason, c.closeReason != ""
+}
@@ -0,0 +1,36 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"ai_dialer_mini/internal/services/deadair"
+)
+
+// causeHanger 支持指定挂断原因的通话控制
+type causeHanger interface {
+	HangupCall(ctx context.Context, callUUID, cause string) error
+}
+
+// watchDeadAir 为连接启动静音看门狗，静音超时后记录诊断快照，挂断通话并关闭连接；未启用时返回nil
+func (s *ASRServer) watchDeadAir(conn *lockedConn) *deadair.Watchdog {
+	return deadair.New(s.Config.DeadAir, conn.callUUID, conn.sessionID, func(snapshot deadair.Snapshot) {
+		diagnostic, _ := json.Marshal(snapshot)
+		log.Printf("静音超时，挂断通话: %s", diagnostic)
+		if conn.callUUID != "" && s.Control != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+			defer cancel()
+			var err error
+			if hanger, ok := s.Control.(causeHanger); ok {
+				err = hanger.HangupCall(ctx, conn.callUUID, snapshot.Cause)
+			} else {
+				err = s.Control.EndCall(ctx, conn.callUUID)
+			}
+			if err != nil {
+				log.Printf("静音超时挂机失败: %v", err)
+			}
+		}
+		conn.session.close(DisconnectDeadAir)
+	})
+}
@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"errors"
+	"net"
+
+	"ai_dialer_mini/internal/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket端点，作为断开统计的endpoint标签
+const (
+	EndpointASR        = "asr"         // /ws 语音识别
+	EndpointMic        = "mic"         // /ws/mic 浏览器麦克风音频流
+	EndpointCallStream = "call_stream" // /ws/calls/:uuid/stream FreeSWITCH通话音频流
+)
+
+// WebSocket断开原因，作为断开统计的reason标签
+// 客户端关闭和网络异常多为网络或对端问题，写入失败、协议错误集中出现时需排查服务端
+const (
+	DisconnectClientClose   = "client_close"   // 客户端正常发送关闭帧
+	DisconnectTimeout       = "timeout"        // 超过pong_wait未收到消息或心跳
+	DisconnectAuthFailure   = "auth_failure"   // 签名校验失败，未建立连接
+	DisconnectWriteError    = "write_error"    // 向客户端发送消息失败
+	DisconnectServerDrain   = "server_drain"   // 排空期间拒绝新连接，或排空超时后关闭
+	DisconnectCallEnded     = "call_ended"     // 通话挂断，由通话会话关闭音频流
+	DisconnectNetworkError  = "network_error"  // 连接未发送关闭帧即中断，如网络闪断、对端进程退出
+	DisconnectProtocolError = "protocol_error" // 消息超过长度限制或其他协议错误
+	DisconnectDeadAir       = "dead_air"       // 长时间既没有客户语音也没有AI语音，由静音看门狗挂断
+)
+
+// disconnects WebSocket断开次数
+var disconnects = metrics.NewCounterVec("ai_dialer_ws_disconnects_total",
+	"WebSocket连接断开次数，按端点和原因分类", "endpoint", "reason")
+
+// RecordDisconnect 记录一次WebSocket断开或拒绝连接
+func RecordDisconnect(endpoint, reason string) {
+	disconnects.Inc(endpoint, reason)
+}
+
+// classifyReadError 按读取错误判断断开原因
+func classifyReadError(err error) string {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived:
+			return DisconnectClientClose
+		case websocket.CloseAbnormalClosure:
+			return DisconnectNetworkError
+		}
+		return DisconnectProtocolError
+	}
+	if errors.Is(err, websocket.ErrReadLimit) {
+		return DisconnectProtocolError
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return DisconnectTimeout
+	}
+	return DisconnectNetworkError
+}
+
+// finishConn 连接结束时注销连接并记录断开原因：
+// 优先使用服务端关闭连接时记录的原因，其次为发送失败，最后按读取错误判断
+func (s *ASRServer) finishConn(sess *connSession, endpoint string, writeFailed bool, readErr error) {
+	s.sessions.Delete(sess.conn)
+	reason, closed := sess.closedBy()
+	switch {
+	case closed:
+	case writeFailed:
+		reason = DisconnectWriteError
+	default:
+		reason = classifyReadError(readErr)
+	}
+	RecordDisconnect(endpoint, reason)
+}
+
+// CloseAll 关闭所有WebSocket连接，用于排空超时后仍未结束的连接
+func (s *ASRServer) CloseAll() {
+	s.eachSession(func(sess *connSession) {
+		sess.close(DisconnectServerDrain)
+	})
+}
@@ -0,0 +1,47 @@
+package ws
+
+import (
+	"context"
+	"log"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/turn"
+)
+
+// MenuOpener 为通话打开按键菜单，由services.CallSessionManager实现
+type MenuOpener interface {
+	OpenMenu(callUUID, name string) error
+}
+
+// openMenu AI回复提示按键时为通话打开对应的按键菜单
+func (s *ASRServer) openMenu(conn *lockedConn, reply string) {
+	if s.Menus == nil || conn.callUUID == "" {
+		return
+	}
+	name, ok := s.Config.DTMF.Match(reply)
+	if !ok {
+		return
+	}
+	if err := s.Menus.OpenMenu(conn.callUUID, name); err != nil {
+		log.Printf("打开按键菜单失败 - UUID: %s: %v", conn.callUUID, err)
+	}
+}
+
+// HandleDTMF 按键菜单输入结束后，将选项对应的输入作为客户这一轮的话交给对话服务，不经过语音识别；
+// 按键会打断AI正在进行的回复
+func (s *ASRServer) HandleDTMF(callUUID string, result dtmf.Result) {
+	if result.Input == "" {
+		return
+	}
+	conn, err := s.stream(callUUID)
+	if err != nil {
+		log.Printf("按键菜单输入未交给对话: %v", err)
+		return
+	}
+	s.publishEvent(conn.sessionID, models.EventTypeTranscript, models.SpeakerCustomer, result.Input, true)
+	response := ASRResponse{Text: result.Input, DTMF: result.Digits}
+	conn.replies.start(func(ctx context.Context) {
+		s.respond(ctx, conn, conn.sessionID, turn.ActionReply, response)
+	})
+}
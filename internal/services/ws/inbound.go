@@ -0,0 +1,46 @@
+package ws
+
+import (
+	"context"
+	"log"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/inbound"
+)
+
+// InboundRoutes 呼入通话的路由，由services.CallSessionManager实现
+type InboundRoutes interface {
+	InboundRoute(callUUID string) (inbound.Route, bool)
+}
+
+// sessionConfigurer 可设置会话提示词配置的对话服务
+type sessionConfigurer interface {
+	SetSessionOptions(sessionID, campaignID, profile string, overrides models.GenerationOverrides) (*models.SessionOptions, error)
+}
+
+// startInbound 呼入通话的音频流接入后按路由设置AI人设，配置了问候语时AI先开口并记入对话历史
+func (s *ASRServer) startInbound(ctx context.Context, conn *lockedConn, route inbound.Route) {
+	if route.Persona != "" {
+		configurer, ok := s.DialogSvc.(sessionConfigurer)
+		if !ok {
+			log.Printf("对话服务不支持设置提示词配置，呼入通话使用默认人设: %s", conn.callUUID)
+		} else if _, err := configurer.SetSessionOptions(conn.sessionID, "", route.Persona, models.GenerationOverrides{}); err != nil {
+			log.Printf("设置呼入通话人设失败 - UUID: %s: %v", conn.callUUID, err)
+		}
+	}
+
+	greeting := s.say(ctx, conn, conn.sessionID, route.Greeting)
+	if greeting == "" {
+		return
+	}
+	if appender, ok := s.DialogSvc.(messageAppender); ok {
+		if err := appender.AppendMessage(conn.sessionID, models.Message{Role: models.RoleAssistant, Content: greeting}); err != nil {
+			log.Printf("记录呼入问候语失败: %v", err)
+		}
+	}
+	s.publishEvent(conn.sessionID, models.EventTypeDialog, models.SpeakerAI, greeting, true)
+	s.openMenu(conn, greeting)
+	if err := conn.WriteJSON(ASRResponse{AIReply: greeting, IsEnd: true}); err != nil {
+		log.Printf("发送响应失败: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/intent"
+)
+
+// CallCommander 通话控制，由services.CallServiceImpl实现
+type CallCommander interface {
+	EndCall(ctx context.Context, callUUID string) error
+	TransferCall(ctx context.Context, callUUID, destination, dialplanContext string) error
+}
+
+// commandTimeout 执行挂机、转人工命令的超时时间
+const commandTimeout = 5 * time.Second
+
+// handleIntent 命中意图时不调用大模型，回复固定话术并记入对话历史，返回回复话术
+// 挂机和转人工在话术播放完后执行，只对通话音频流连接生效
+func (s *ASRServer) handleIntent(ctx context.Context, conn *lockedConn, sessionID, text string, match *intent.Match) string {
+	log.Printf("命中意图 %s（关键词 %q），不调用大模型: %s", match.Rule, match.Keyword, sessionID)
+	aiReply := s.say(ctx, conn, sessionID, match.Reply)
+
+	if appender, ok := s.DialogSvc.(messageAppender); ok {
+		messages := []models.Message{{Role: models.RoleUser, Content: text}}
+		if aiReply != "" {
+			messages = append(messages, models.Message{Role: models.RoleAssistant, Content: aiReply})
+		}
+		for _, msg := range messages {
+			if err := appender.AppendMessage(sessionID, msg); err != nil {
+				log.Printf("记录意图对话失败: %v", err)
+				break
+			}
+		}
+	}
+
+	if match.Action == intent.ActionReply {
+		return aiReply
+	}
+	if conn.callUUID == "" || s.Control == nil {
+		log.Printf("连接未关联通话，跳过意图动作 %s: %s", match.Action, sessionID)
+		return aiReply
+	}
+	time.AfterFunc(conn.replies.remaining(), func() {
+		s.executeIntent(conn, match)
+	})
+	return aiReply
+}
+
+// executeIntent 执行意图的挂机或转人工
+func (s *ASRServer) executeIntent(conn *lockedConn, match *intent.Match) {
+	if err := s.executeAction(conn, match.Action, match.Destination, s.Intents.DialplanContext()); err != nil {
+		log.Printf("执行意图 %s 的动作 %s 失败: %v", match.Rule, match.Action, err)
+		return
+	}
+	log.Printf("已执行意图 %s 的动作 %s: %s", match.Rule, match.Action, conn.callUUID)
+}
+
+// executeAction 对连接所属的通话执行挂机或转人工，转人工后连接改为坐席辅助
+func (s *ASRServer) executeAction(conn *lockedConn, action, destination, dialplanContext string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var err error
+	switch action {
+	case intent.ActionHangup:
+		err = s.Control.EndCall(ctx, conn.callUUID)
+	case intent.ActionTransfer:
+		err = s.Control.TransferCall(ctx, conn.callUUID, destination, dialplanContext)
+	}
+	if err != nil {
+		return err
+	}
+	if action == intent.ActionTransfer {
+		s.handoff(conn)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidMessage 无法识别的客户端文本消息
+var ErrInvalidMessage = errors.New("无效的消息")
+
+// TextMessage 客户端文本消息，语法设置和音频数据二选一
+type TextMessage struct {
+	Grammar *ASRGrammar
+	Audio   *AudioData
+}
+
+// ParseTextMessage 解析客户端文本消息
+// 只含grammar字段的是语法设置，含data字段的是音频数据，两者都有或都没有时返回ErrInvalidMessage
+func ParseTextMessage(data []byte) (TextMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return TextMessage{}, fmt.Errorf("%w: 不是JSON对象", ErrInvalidMessage)
+	}
+
+	_, hasGrammar := fields["grammar"]
+	_, hasData := fields["data"]
+	switch {
+	case hasGrammar && hasData:
+		return TextMessage{}, fmt.Errorf("%w: 不能同时包含grammar和data", ErrInvalidMessage)
+	case hasGrammar:
+		var grammar ASRGrammar
+		if err := json.Unmarshal(data, &grammar); err != nil {
+			return TextMessage{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		return TextMessage{Grammar: &grammar}, nil
+	case hasData:
+		var audio AudioData
+		if err := json.Unmarshal(data, &audio); err != nil {
+			return TextMessage{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if len(audio.Data)%2 != 0 {
+			return TextMessage{}, fmt.Errorf("%w: 16位PCM数据长度必须为偶数", ErrInvalidMessage)
+		}
+		return TextMessage{Audio: &audio}, nil
+	}
+	return TextMessage{}, fmt.Errorf("%w: 缺少grammar或data字段", ErrInvalidMessage)
+}
@@ -0,0 +1,184 @@
+package ws
+
+import (
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"ai_dialer_mini/internal/metrics"
+
+	"github.com/gorilla/websocket"
+)
+
+// 消息被丢弃的原因，作为丢弃统计的reason标签
+const (
+	DropClosed      = "closed"       // 连接已被服务端关闭，如通话挂断、心跳超时
+	DropRateLimited = "rate_limited" // 超过 websocket.max_message_rate
+	DropInvalid     = "invalid"      // 无法解析的文本消息
+)
+
+// 消息被丢弃时的错误
+var (
+	ErrConnectionClosed = errors.New("连接已关闭")
+	ErrRateLimited      = errors.New("消息过于频繁")
+)
+
+// messagesHandled 处理的WebSocket消息数
+var messagesHandled = metrics.NewCounterVec("ai_dialer_ws_messages_total",
+	"处理的WebSocket消息数，按端点、消息类型和处理结果分类", "endpoint", "type", "result")
+
+// messagesDropped 丢弃的WebSocket消息数
+var messagesDropped = metrics.NewCounterVec("ai_dialer_ws_messages_dropped_total",
+	"未经处理即丢弃的WebSocket消息数，按端点和原因分类", "endpoint", "reason")
+
+// abortIndex 处理链被跳过后的位置，远大于处理链长度，据此区分跳过与正常执行完毕
+const abortIndex = math.MaxInt / 2
+
+// MessageHandler WebSocket消息处理函数，与gin.HandlerFunc相同，中间件调用c.Next()执行后续处理，
+// 不调用或调用c.Abort()时后续处理不再执行
+type MessageHandler func(c *MessageContext)
+
+// MessageContext 一条WebSocket消息的处理上下文，同一连接的各条消息复用同一个上下文，只在读取消息的goroutine中使用
+type MessageContext struct {
+	Endpoint  string // 连接所属端点，见Endpoint*
+	SessionID string // 对话会话ID
+	CallUUID  string // 通话音频流连接所属的通话UUID，其他连接为空
+	Type      int    // 消息类型，websocket.TextMessage或websocket.BinaryMessage
+	Data      []byte // 消息原文
+
+	Grammar *ASRGrammar // 语法设置，由DecodeMessage填写
+	Audio   *AudioData  // 音频数据，由DecodeMessage填写并转换为16kHz的16位PCM，二进制消息的整条消息为音频
+	Err     error       // 处理失败或被丢弃的原因
+
+	session  *connSession
+	format   *inputFormat // 上行音频格式，为nil时音频不转换
+	handlers []MessageHandler
+	index    int
+}
+
+// Next 执行后续处理
+func (c *MessageContext) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// Abort 跳过后续处理，已在执行的中间件在c.Next()返回后继续执行
+func (c *MessageContext) Abort() {
+	c.index = abortIndex
+}
+
+// AbortWithError 记录错误并跳过后续处理
+func (c *MessageContext) AbortWithError(err error) {
+	c.Err = err
+	c.Abort()
+}
+
+// IsAborted 后续处理是否已被跳过，处理链正常执行完毕时返回false
+func (c *MessageContext) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// drop 丢弃消息并计入丢弃统计
+func (c *MessageContext) drop(reason string, err error) {
+	messagesDropped.Inc(c.Endpoint, reason)
+	c.AbortWithError(err)
+}
+
+// handle 按处理链处理一条消息
+func (c *MessageContext) handle(messageType int, data []byte) {
+	c.Type, c.Data = messageType, data
+	c.Grammar, c.Audio, c.Err = nil, nil, nil
+	c.index = -1
+	c.Next()
+}
+
+// Use 添加消息中间件，在内置的关闭检查、限流、解析和统计之后、消息处理之前按添加顺序执行，需在开始服务前调用
+func (s *ASRServer) Use(middleware ...MessageHandler) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// newMessageContext 为新连接创建消息处理上下文，处理链为 关闭检查 -> 限流 -> 解析 -> 统计 -> 自定义中间件 -> handler
+func (s *ASRServer) newMessageContext(endpoint, sessionID, callUUID string, sess *connSession, format *inputFormat, handler MessageHandler) *MessageContext {
+	handlers := []MessageHandler{DropClosedMessage, RateLimit(s.Config.WebSocket.MaxMessageRate), DecodeMessage, MessageMetrics}
+	handlers = append(handlers, s.middleware...)
+	return &MessageContext{
+		Endpoint:  endpoint,
+		SessionID: sessionID,
+		CallUUID:  callUUID,
+		session:   sess,
+		format:    format,
+		handlers:  append(handlers, handler),
+	}
+}
+
+// DropClosedMessage 丢弃已被服务端关闭的连接（通话挂断、心跳超时、服务排空）在关闭完成前继续发来的消息。
+// 不做鉴权：鉴权在升级连接时完成（通话音频流校验签名地址，其他端点校验Origin），需要逐条消息鉴权时通过Use添加中间件
+func DropClosedMessage(c *MessageContext) {
+	if _, closed := c.session.closedBy(); closed {
+		c.drop(DropClosed, ErrConnectionClosed)
+		return
+	}
+	c.Next()
+}
+
+// RateLimit 按令牌桶限制单个连接每秒处理的消息数，允许突发rate条，超出的消息丢弃；rate不大于0时不限制。
+// 返回的中间件保存连接的限流状态，每个连接需单独创建
+func RateLimit(rate int) MessageHandler {
+	if rate <= 0 {
+		return func(c *MessageContext) { c.Next() }
+	}
+	limit := float64(rate)
+	tokens := limit
+	var last time.Time
+	return func(c *MessageContext) {
+		now := time.Now()
+		if !last.IsZero() {
+			tokens = min(limit, tokens+now.Sub(last).Seconds()*limit)
+		}
+		last = now
+		if tokens < 1 {
+			c.drop(DropRateLimited, ErrRateLimited)
+			return
+		}
+		tokens--
+		c.Next()
+	}
+}
+
+// DecodeMessage 解析消息：文本消息解析为语法设置或音频数据，二进制消息整条作为音频，音频按连接协商的格式转换为16kHz的16位PCM
+func DecodeMessage(c *MessageContext) {
+	switch c.Type {
+	case websocket.TextMessage:
+		msg, err := ParseTextMessage(c.Data)
+		if err != nil {
+			log.Printf("忽略无法解析的消息: %v", err)
+			c.drop(DropInvalid, err)
+			return
+		}
+		c.Grammar, c.Audio = msg.Grammar, msg.Audio
+	case websocket.BinaryMessage:
+		c.Audio = &AudioData{Data: c.Data}
+	}
+	if c.Audio != nil && c.format != nil {
+		c.Audio.Data = c.format.decode(c.Audio.Data)
+	}
+	c.Next()
+}
+
+// MessageMetrics 按端点、消息类型和处理结果统计处理的消息数
+func MessageMetrics(c *MessageContext) {
+	c.Next()
+	messageType := "binary"
+	if c.Type == websocket.TextMessage {
+		messageType = "text"
+	}
+	result := "ok"
+	if c.Err != nil {
+		result = "error"
+	}
+	messagesHandled.Inc(c.Endpoint, messageType, result)
+}
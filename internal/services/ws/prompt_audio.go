@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gorilla/websocket"
+
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/tap"
+)
+
+// sayLine 播放一句固定话术并返回记入对话历史的文本：话术为 audio:名称 引用时播放预录提示音，
+// 提示音不可用时改为合成竖线后的文本；其他话术直接合成
+func (s *ASRServer) sayLine(ctx context.Context, conn *lockedConn, sessionID, text string) (string, error) {
+	name, fallback, ok := promptaudio.ParseRef(text)
+	if !ok {
+		return text, s.sendSpeech(ctx, conn, sessionID, text, s.voiceOptions(sessionID, ""))
+	}
+	spoken, err := s.playPrompt(ctx, conn, sessionID, name)
+	if err == nil {
+		return spoken, nil
+	}
+	if fallback == "" {
+		return "", err
+	}
+	log.Printf("播放提示音失败，改用语音合成: %v", err)
+	return fallback, s.sendSpeech(ctx, conn, sessionID, fallback, s.voiceOptions(sessionID, ""))
+}
+
+// playPrompt 播放预录提示音，通话音频流为8kHz时使用8kHz的提示音，返回提示音的文字内容
+func (s *ASRServer) playPrompt(ctx context.Context, conn *lockedConn, sessionID, name string) (string, error) {
+	if s.Prompts == nil {
+		return "", fmt.Errorf("未启用提示音: %s", name)
+	}
+	rate := inputSampleRate
+	if conn.callUUID != "" && s.Config.AudioStream.SampleRate == g711SampleRate {
+		rate = g711SampleRate
+	}
+	audio, prompt, err := s.Prompts.Load(ctx, name, rate)
+	if err != nil {
+		return "", err
+	}
+	s.Tap.Publish(sessionID, tap.LegAI, audio.SampleRate, audio.PCM)
+	s.play(conn, audio)
+	return prompt.Text, conn.WriteMessage(websocket.BinaryMessage, audio.WAV())
+}
@@ -0,0 +1,189 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/throttle"
+)
+
+// transcript 连接上的一条识别结果
+type transcript struct {
+	Text    string
+	IsFinal bool
+	Start   time.Time // 该句识别会话开始的时间，只在最终结果中设置
+}
+
+// recognition 连接上的流式识别：音频分片到达后立即送入讯飞，
+// 讯飞检测到句尾结束一次识别会话后，下一段音频开启新的会话；各会话的结果汇入同一通道
+type recognition struct {
+	server    *ASRServer
+	provider  models.ASRProvider // 连接使用的语音识别后端
+	sessionID string
+
+	mu      sync.Mutex
+	stream  models.ASRStream
+	pending sync.WaitGroup  // 未结束的识别会话
+	results chan transcript // 中间结果和每句的最终结果，close后所有会话结束时关闭
+}
+
+// newRecognition 创建连接的流式识别
+func (s *ASRServer) newRecognition(sessionID string, provider models.ASRProvider) *recognition {
+	return &recognition{server: s, provider: provider, sessionID: sessionID, results: make(chan transcript, 16)}
+}
+
+// callASRProviders 可查询通话指定的语音识别后端的通话会话跟踪，由services.CallSessionManager实现
+type callASRProviders interface {
+	CallASRProvider(callUUID string) string
+}
+
+// asrProvider 连接使用的语音识别后端：通话音频流使用通道变量指定的后端，其他连接使用asr查询参数指定的后端，
+// 未指定时使用默认后端；租户配置了讯飞凭据时讯飞后端使用租户的客户端。
+// 查询参数指定了未配置的后端时返回错误，通道变量指定了未配置的后端时使用默认后端，不中断通话识别
+func (s *ASRServer) asrProvider(r *http.Request, callUUID, tenantID string) (models.ASRProvider, error) {
+	name := r.URL.Query().Get("asr")
+	if callUUID != "" {
+		name = ""
+		if lookup, ok := s.Calls.(callASRProviders); ok {
+			name = lookup.CallASRProvider(callUUID)
+		}
+	}
+	provider, err := s.selectASR(name, tenantID)
+	if err != nil && callUUID != "" {
+		log.Printf("通话 %s 使用默认语音识别后端: %v", callUUID, err)
+		return s.selectASR("", tenantID)
+	}
+	return provider, err
+}
+
+// selectASR 按名称选择语音识别后端，name为空时使用默认后端
+func (s *ASRServer) selectASR(name, tenantID string) (models.ASRProvider, error) {
+	xf := s.asrClient(tenantID).Provider()
+	if s.ASR == nil {
+		if name != "" && name != xf.Name() {
+			return nil, fmt.Errorf("%w: %s", asr.ErrUnknownProvider, name)
+		}
+		return xf, nil
+	}
+	return s.ASR.Select(name, xf)
+}
+
+// write 发送一段音频，当前没有进行中的识别会话时先开启新会话
+func (r *recognition) write(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stream != nil {
+		select {
+		case <-r.stream.Done():
+			r.stream = nil
+		default:
+		}
+	}
+	if r.stream == nil {
+		stream, err := r.provider.OpenStream(r.sessionID)
+		if err != nil {
+			r.server.reportASR(0, err)
+			return err
+		}
+		r.stream = stream
+		r.pending.Add(1)
+		go r.forward(stream)
+	}
+
+	if _, err := r.stream.Write(data); err != nil {
+		go r.stream.Close()
+		r.stream = nil
+		return err
+	}
+	return nil
+}
+
+// finish 结束当前识别会话，最终结果随后送达
+func (r *recognition) finish() {
+	r.mu.Lock()
+	stream := r.stream
+	r.stream = nil
+	r.mu.Unlock()
+	if stream != nil {
+		go stream.Close()
+	}
+}
+
+// close 结束当前识别会话，等待所有会话的结果送达后关闭结果通道，并释放为连接保持的讯飞备用连接
+func (r *recognition) close() {
+	r.finish()
+	r.pending.Wait()
+	r.provider.ReleaseSession(r.sessionID)
+	close(r.results)
+}
+
+// forward 转发一次识别会话的结果，最终结果添加标点后送出
+func (r *recognition) forward(stream models.ASRStream) {
+	defer r.pending.Done()
+
+	var last string
+	final := false
+	start := time.Now()
+	for result := range stream.Results() {
+		if !result.IsFinal {
+			r.results <- transcript{Text: result.Text}
+			last = result.Text
+			continue
+		}
+		final = true
+		r.results <- transcript{Text: r.server.postprocess(result.Text), IsFinal: true, Start: start}
+		start = time.Now()
+	}
+
+	err := stream.Err()
+	r.server.reportASR(stream.Latency(), err)
+	if err != nil {
+		log.Printf("流式识别异常结束: %v", err)
+		// 会话异常结束时以最近的中间结果作为该句的最终结果，避免丢失客户已说的话
+		if !final && last != "" {
+			r.results <- transcript{Text: r.server.postprocess(last), IsFinal: true, Start: start}
+		}
+	}
+}
+
+// reportASR 上报识别结果，用于判断识别服务是否可用；latency为最终结果的延迟，未收到最终结果时为0
+func (s *ASRServer) reportASR(latency time.Duration, err error) {
+	if s.Metrics != nil && (err != nil || latency > 0) {
+		s.Metrics.Observe(throttle.ComponentASR, latency, err)
+	}
+	if err != nil {
+		kind, code, _ := diagnoseASR(err)
+		asrErrors.Inc(kind, code)
+	}
+	s.asrState.record(err)
+	if s.ASRHealth == nil {
+		return
+	}
+	if err != nil {
+		s.ASRHealth.ReportFailure(err)
+	} else {
+		s.ASRHealth.ReportSuccess()
+	}
+}
+
+// postprocess 配置了文本后处理时处理最终识别结果，失败的步骤跳过
+func (s *ASRServer) postprocess(text string) string {
+	if s.PostProcess == nil || text == "" {
+		return text
+	}
+	processed, err := s.PostProcess.Process(context.Background(), text)
+	if err != nil {
+		log.Printf("识别结果后处理部分步骤失败，已跳过: %v", err)
+	}
+	return processed
+}
@@ -0,0 +1,59 @@
+package ws
+
+import (
+	"strings"
+
+	"ai_dialer_mini/internal/clients/tts"
+)
+
+// MessageTypeAIDelta AI回复生成过程中推送回复片段的消息类型
+const MessageTypeAIDelta = "ai_delta"
+
+// maxStyleTagBytes 语气标签的最大长度，回复开头超过该长度仍未闭合的“[”按正文处理
+const maxStyleTagBytes = 32
+
+// AIDeltaMessage AI回复片段，按顺序拼接即为完整回复；生成结束后仍会发送带ai_reply的最终结果
+type AIDeltaMessage struct {
+	Type  string `json:"type"`  // 消息类型，固定为ai_delta
+	Delta string `json:"delta"` // 新生成的回复文本
+}
+
+// replyStream 向客户端逐段推送AI回复，回复开头的语气标签不推送
+type replyStream struct {
+	conn    *lockedConn
+	head    strings.Builder // 尚未确定是否为语气标签的开头片段
+	started bool
+}
+
+// write 推送一个回复片段，开头片段缓存到能判断是否为语气标签后再推送
+func (r *replyStream) write(token string) error {
+	if !r.started {
+		r.head.WriteString(token)
+		head := strings.TrimLeft(r.head.String(), " \t\r\n")
+		if head == "" {
+			return nil
+		}
+		if strings.HasPrefix(head, "[") && !strings.Contains(head, "]") && len(head) < maxStyleTagBytes {
+			return nil
+		}
+		r.started = true
+		_, token = tts.SplitStyle(r.head.String())
+	}
+	if token == "" {
+		return nil
+	}
+	return r.conn.WriteJSON(AIDeltaMessage{Type: MessageTypeAIDelta, Delta: token})
+}
+
+// flush 回复结束，推送仍在缓存中的开头片段
+func (r *replyStream) flush() error {
+	if r.started {
+		return nil
+	}
+	r.started = true
+	_, rest := tts.SplitStyle(r.head.String())
+	if rest = strings.TrimSpace(rest); rest == "" {
+		return nil
+	}
+	return r.conn.WriteJSON(AIDeltaMessage{Type: MessageTypeAIDelta, Delta: rest})
+}
@@ -2,16 +2,37 @@
 package ws
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/audio/resample"
+	"ai_dialer_mini/internal/audio/vad"
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/clients/tts"
 	"ai_dialer_mini/internal/clients/xfyun"
 	"ai_dialer_mini/internal/config"
 	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/services/assist"
+	"ai_dialer_mini/internal/services/deadair"
+	"ai_dialer_mini/internal/services/filler"
+	"ai_dialer_mini/internal/services/hooks"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/tap"
+	"ai_dialer_mini/internal/services/tenant"
+	"ai_dialer_mini/internal/services/turn"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -22,7 +43,9 @@ type ASRResponse struct {
 	Text       string  `json:"text"`
 	Confidence float64 `json:"confidence"`
 	IsEnd      bool    `json:"is_end"`
-	AIReply    string  `json:"ai_reply,omitempty"` // AI的回复，只在最终结果时返回
+	AIReply    string  `json:"ai_reply,omitempty"`   // AI的回复，只在最终结果时返回
+	DTMF       string  `json:"dtmf,omitempty"`       // 客户输入来自按键菜单时为按键串，text为选项对应的输入
+	Suggestion string  `json:"suggestion,omitempty"` // 坐席通话中AI给坐席的建议话术，不播放
 }
 
 // ASRGrammar 定义语法设置请求的结构
@@ -39,13 +62,142 @@ type AudioData struct {
 
 // ASRServer 处理语音识别的WebSocket服务器
 type ASRServer struct {
-	Config       *config.Config
-	Upgrader     websocket.Upgrader
-	Mu           sync.Mutex
-	Grammars     map[*websocket.Conn]string
-	LastActivity map[*websocket.Conn]time.Time
-	ASRClient    *xfyun.ASRClient
-	DialogSvc    models.DialogService
+	Config      *config.Config
+	Upgrader    websocket.Upgrader
+	Mu          sync.Mutex // 保护通话音频流连接和租户客户端的索引
+	ASRClient   *xfyun.ASRClient
+	DialogSvc   models.DialogService
+	Events      models.EventPublisher // 通话实时事件发布，为nil时不发布
+	Recorder    *recorder.Recorder    // 飞行记录仪，连接关闭时写入存储，为nil时不记录
+	TTS         tts.Provider          // 语音合成，AI回复合成为WAV后以二进制消息发送，为nil时只返回文本
+	Fillers     *filler.Pool          // 回复较慢时播放的应答语音，为nil时不播放
+	Tap         *tap.Tap              // 通话实时监听，收发的音频复制一份给质检坐席，为nil时不复制
+	ASRHealth   ASRHealthReporter     // 语音识别可用性监控，为nil时不上报
+	Playback    SpeechPlayer          // 通话语音播放，通话音频流连接的AI语音同时在通话中播放，为nil时只通过WebSocket发送
+	PostProcess TextProcessor         // 识别结果后处理（数字规整、标点恢复、词语替换），为nil时不处理
+	Calls       CallTracker           // 通话会话跟踪，通话音频流连接登记到所属通话，挂断时由通话会话关闭连接，为nil时不登记
+	Lifecycle   *lifecycle.Manager    // 进程生命周期，排空时拒绝新的会话连接，已接通通话的音频流不受影响，为nil时不检查
+	Intents     *intent.Engine        // 调用大模型前的意图识别，命中时直接回复固定话术，为nil时不识别
+	Control     CallCommander         // 通话控制，执行意图的挂机和转人工，为nil时只回复话术
+	Metrics     LatencyObserver       // 语音识别延迟和错误统计，用于外呼自动限速，为nil时不统计
+	Consent     ConsentDetector       // 录音授权，通话音频流的客户回答用于判断是否同意录音，为nil时不判断
+	Inbound     InboundRoutes         // 呼入通话路由，呼入通话的音频流接入时设置AI人设并播放问候语，为nil时不处理
+	Menus       MenuOpener            // 按键菜单，通话中AI回复提示按键时打开菜单，为nil时不打开
+	Prompts     *promptaudio.Library  // 预录提示音，固定话术以 audio:名称 引用时播放提示音，为nil时改为合成引用中的备用文本
+	Hooks       *hooks.Dispatcher     // 会话事件钩子，客户说完一句话和AI完成一轮回复时调用，为nil时不调用
+	Tenants     *tenant.Registry      // 多租户，按连接所属租户使用独立的讯飞凭据、大模型和AI人设，为nil时使用全局配置
+	ASR         *asr.Router           // 语音识别后端路由，连接可通过asr查询参数选择后端，后端连续失败时切换，为nil时只使用讯飞
+	Transcripts TranscriptRecorder    // 通话转写实时保存，通话音频流的客户语音和AI回复写入数据库，为nil时不保存
+	Assist      *assist.Hub           // 坐席辅助，坐席通话的客户转写、建议话术和知识片段推送给订阅的坐席，为nil时只发布suggestion事件
+
+	streams    map[string]*lockedConn      // 按通话UUID索引的通话音频流连接
+	tenantASR  map[string]*xfyun.ASRClient // 按租户ID缓存的语音识别客户端，由Mu保护
+	sessions   sync.Map                    // 进行中的连接，*websocket.Conn到*connSession
+	active     int64                       // 进行中的WebSocket连接数
+	asrState   asrErrorState               // 语音识别服务最近一次失败的情况
+	middleware []MessageHandler            // 自定义消息中间件，由Use添加
+}
+
+// ErrCallNotStreaming 通话没有接入本实例的音频流连接
+var ErrCallNotStreaming = errors.New("通话未接入音频流")
+
+// messageAppender 可直接向会话历史追加消息的对话服务
+type messageAppender interface {
+	AppendMessage(sessionID string, msg models.Message) error
+}
+
+// ASRHealthReporter 上报语音识别结果，用于判断服务是否可用
+type ASRHealthReporter interface {
+	ReportSuccess()
+	ReportFailure(err error)
+}
+
+// LatencyObserver 记录外部服务调用的耗时和结果
+type LatencyObserver interface {
+	Observe(component string, latency time.Duration, err error)
+}
+
+// ConsentDetector 根据客户的回答判断通话的录音授权
+type ConsentDetector interface {
+	DetectConsent(callUUID, text string)
+}
+
+// TranscriptRecorder 通话转写实时保存，由services.TranscriptRecorder实现
+type TranscriptRecorder interface {
+	Record(ctx context.Context, callUUID, speaker, kind, text string, start, end time.Time)
+}
+
+// TextProcessor 识别结果后处理，由postprocess.Chain实现
+type TextProcessor interface {
+	Process(ctx context.Context, text string) (string, error)
+}
+
+// CallTracker 跟踪通话音频流连接，返回的函数在连接结束时调用
+type CallTracker interface {
+	AttachStream(callUUID, sessionID string, closeStream func()) (func(), error)
+}
+
+// SpeechPlayer 在通话中播放语音
+type SpeechPlayer interface {
+	Play(callUUID string, audio *tts.Audio) error
+	Stop(callUUID string) error
+}
+
+// callContextKey 请求上下文中通话UUID的键
+type callContextKey struct{}
+
+// WithCall 标记请求为FreeSWITCH通话音频流，该连接的AI语音同时在通话中播放
+func WithCall(r *http.Request, callUUID string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), callContextKey{}, callUUID))
+}
+
+// inputSampleRate 客户端上行音频的采样率，与讯飞ASR的audio/L16;rate=16000一致
+const inputSampleRate = 16000
+
+// g711SampleRate G.711音频的采样率
+const g711SampleRate = 8000
+
+// inputFormat 客户端上行音频格式，由连接地址的codec和rate查询参数协商
+type inputFormat struct {
+	encoding  string
+	resampler *resample.Resampler // 采样率与inputSampleRate不同时转换，否则为nil
+}
+
+// parseInputFormat 解析上行音频格式：codec支持L16（默认）和G.711的PCMU、PCMA，
+// rate未指定时L16按16kHz、G.711按8kHz
+func parseInputFormat(query url.Values) (*inputFormat, error) {
+	encoding, err := codec.ParseEncoding(query.Get("codec"))
+	if err != nil {
+		return nil, err
+	}
+	rate := inputSampleRate
+	if encoding != codec.EncodingL16 {
+		rate = g711SampleRate
+	}
+	if v := query.Get("rate"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 8000 || n > 48000 {
+			return nil, fmt.Errorf("无效的采样率: %s", v)
+		}
+		rate = n
+	}
+
+	format := &inputFormat{encoding: encoding}
+	if rate != inputSampleRate {
+		if format.resampler, err = resample.New(rate, inputSampleRate); err != nil {
+			return nil, err
+		}
+	}
+	return format, nil
+}
+
+// decode 将上行音频转换为inputSampleRate的16位PCM
+func (f *inputFormat) decode(data []byte) []byte {
+	data = codec.DecodeToL16(f.encoding, data)
+	if f.resampler != nil {
+		data = f.resampler.Process(data)
+	}
+	return data
 }
 
 // NewASRServer 创建新的ASR服务器实例
@@ -57,20 +209,14 @@ func NewASRServer(cfg *config.Config, dialogSvc models.DialogService) *ASRServer
 	server := &ASRServer{
 		Config: cfg,
 		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				origin := r.Header.Get("Origin")
-				log.Printf("检查WebSocket连接来源: %s, Origin: %s", r.RemoteAddr, origin)
-				return true // 在生产环境中应该实现适当的源检查
-			},
+			CheckOrigin:      cfg.WebSocket.CheckOrigin,
 			HandshakeTimeout: 10 * time.Second,
 			ReadBufferSize:   cfg.WebSocket.ReadBufferSize,
 			WriteBufferSize:  cfg.WebSocket.WriteBufferSize,
 			Subprotocols:     []string{"WSBRIDGE"},
 		},
-		Grammars:     make(map[*websocket.Conn]string),
-		LastActivity: make(map[*websocket.Conn]time.Time),
-		ASRClient:    xfyun.NewASRClient(cfg.XFYun, dialogSvc),
-		DialogSvc:    dialogSvc,
+		ASRClient: xfyun.NewASRClient(cfg.ASR.XFYun, dialogSvc),
+		DialogSvc: dialogSvc,
 	}
 
 	// 启动心跳检查
@@ -79,33 +225,27 @@ func NewASRServer(cfg *config.Config, dialogSvc models.DialogService) *ASRServer
 	return server
 }
 
+// ActiveConnections 进行中的WebSocket连接数，包括通话音频流连接
+func (s *ASRServer) ActiveConnections() int {
+	return int(atomic.LoadInt64(&s.active))
+}
+
 // heartbeatChecker 定期检查连接活跃状态
 func (s *ASRServer) heartbeatChecker() {
 	ticker := time.NewTicker(s.Config.WebSocket.PingPeriod)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.Mu.Lock()
 		now := time.Now()
-		for conn, lastActivity := range s.LastActivity {
-			if now.Sub(lastActivity) > s.Config.WebSocket.PongWait {
-				log.Printf("连接超时，关闭连接: %s", conn.RemoteAddr().String())
-				conn.Close()
-				delete(s.LastActivity, conn)
-				delete(s.Grammars, conn)
+		s.eachSession(func(sess *connSession) {
+			if sess.idle(now) > s.Config.WebSocket.PongWait {
+				log.Printf("连接超时，关闭连接: %s", sess.conn.RemoteAddr().String())
+				sess.close(DisconnectTimeout)
 			}
-		}
-		s.Mu.Unlock()
+		})
 	}
 }
 
-// updateActivity 更新连接的最后活动时间
-func (s *ASRServer) updateActivity(conn *websocket.Conn) {
-	s.Mu.Lock()
-	s.LastActivity[conn] = time.Now()
-	s.Mu.Unlock()
-}
-
 // ServeHTTP 处理WebSocket连接
 func (s *ASRServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// 检查必要的头信息
@@ -113,6 +253,32 @@ func (s *ASRServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "无效的WebSocket请求", http.StatusBadRequest)
 		return
 	}
+	// FreeSWITCH推送G.711或8kHz音频时，识别和打断检测前先转换为16kHz的16位PCM
+	format, err := parseInputFormat(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	callUUID, _ := r.Context().Value(callContextKey{}).(string)
+	tenantID, err := s.resolveTenant(r, callUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	provider, err := s.asrProvider(r, callUUID, tenantID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	endpoint := EndpointMic
+	if callUUID != "" {
+		endpoint = EndpointCallStream
+	}
+	if callUUID == "" && !s.Lifecycle.Accepting() {
+		RecordDisconnect(endpoint, DisconnectServerDrain)
+		http.Error(w, "服务正在排空，不接受新的连接", http.StatusServiceUnavailable)
+		return
+	}
 
 	// 升级HTTP连接为WebSocket连接
 	conn, err := s.Upgrader.Upgrade(w, r, nil)
@@ -121,92 +287,550 @@ func (s *ASRServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	defer conn.Close()
-
-	// 记录连接活动时间
-	s.updateActivity(conn)
+	atomic.AddInt64(&s.active, 1)
+	defer atomic.AddInt64(&s.active, -1)
+
+	// 连接结束时按原因统计断开次数
+	sess := s.openSession(conn)
+	var out *lockedConn
+	var readErr error
+	writeFailed := false
+	defer func() {
+		s.finishConn(sess, endpoint, writeFailed || (out != nil && out.writeFailed.Load()), readErr)
+	}()
 
 	// 设置连接属性
 	conn.SetReadLimit(1024 * 1024) // 1MB
 	conn.SetReadDeadline(time.Now().Add(s.Config.WebSocket.PongWait))
 	conn.SetPongHandler(func(string) error {
 		conn.SetReadDeadline(time.Now().Add(s.Config.WebSocket.PongWait))
-		s.updateActivity(conn)
+		sess.touch()
 		return nil
 	})
 
-	// 获取会话ID
+	// 获取会话ID，客户端未提供时生成新的会话ID并在第一条消息中返回
 	sessionID := r.URL.Query().Get("session_id")
 	if sessionID == "" {
-		sessionID = "default"
+		sessionID = models.NewSessionID()
+	}
+	if err := conn.WriteJSON(models.NewSessionMessage(sessionID)); err != nil {
+		log.Printf("发送会话ID失败: %v", err)
+		writeFailed = true
+		return
 	}
+	defer func() {
+		if err := s.Recorder.Flush(context.Background(), sessionID); err != nil {
+			log.Printf("写入飞行记录失败: %v", err)
+		}
+	}()
+	// 启用转写脱敏时原始对话历史只供通话中使用，配置了purge_live时通话音频流结束即清除
+	if callUUID != "" && s.Config.Redaction.Enabled && s.Config.Redaction.PurgeLive {
+		defer s.DialogSvc.ClearHistory(sessionID)
+	}
+
+	// 暂停超时恢复时会从计时器goroutine发送消息，写操作需串行化
+	out = &lockedConn{Conn: conn, session: sess, callUUID: callUUID, sessionID: sessionID, tenant: tenantID}
+	out.assist.Store(s.assisted(callUUID))
+	if callUUID != "" {
+		s.attachStream(callUUID, out)
+		defer s.detachStream(callUUID, out)
+		s.bindCall(sessionID, callUUID)
+	}
+	if callUUID != "" && s.Calls != nil {
+		detach, err := s.Calls.AttachStream(callUUID, sessionID, func() { sess.close(DisconnectCallEnded) })
+		if err != nil {
+			log.Printf("通话音频流未登记到通话会话: %v", err)
+		} else {
+			defer detach()
+		}
+	}
+	s.applyTenant(out)
+	if callUUID != "" && s.Inbound != nil {
+		if route, ok := s.Inbound.InboundRoute(callUUID); ok {
+			s.startInbound(r.Context(), out, route)
+		}
+	}
+	// 坐席通话中坐席说话不经过音频流，不做静音检测
+	if !out.assist.Load() {
+		out.deadAir = s.watchDeadAir(out)
+	}
+	defer out.deadAir.Close()
+	turns := turn.New(s.Config.Turn, func() {
+		s.resumeAfterHold(out, sessionID)
+	})
+	defer turns.Close()
+
+	// 启用打断时回复在后台进行，继续读取客户音频以便检测客户开口
+	var detector *vad.Detector
+	if s.Config.BargeIn.Enabled {
+		detector = vad.NewDetector(s.Config.BargeIn, inputSampleRate)
+	}
+	defer out.replies.wait()
+
+	// 音频分片到达后立即送入流式识别，识别结果在单独的goroutine中处理，不阻塞音频读取
+	rec := s.newRecognition(sessionID, provider)
+	transcripts := make(chan struct{})
+	go func() {
+		defer close(transcripts)
+		s.handleTranscripts(out, sessionID, rec.results, turns, detector)
+	}()
+	defer func() {
+		rec.close()
+		<-transcripts
+	}()
+
+	// 处理WebSocket消息，经关闭检查、限流、解析和统计中间件后，语法设置保存到连接，音频送入识别
+	messages := s.newMessageContext(endpoint, sessionID, callUUID, sess, format, func(c *MessageContext) {
+		if c.Grammar != nil {
+			sess.setGrammar(c.Grammar.Grammar)
+			return
+		}
 
-	// 处理WebSocket消息
+		// is_end标记客户端一段音频发送完毕，结束当前识别会话
+		audio := c.Audio.Data
+		s.Tap.Publish(sessionID, tap.LegCustomer, inputSampleRate, audio)
+		out.deadAir.Audio(audio)
+		s.detectBargeIn(out, sessionID, detector, audio)
+		if err := rec.write(audio); err != nil {
+			log.Printf("处理音频失败: %v", err)
+			c.Err = err
+		}
+		if c.Audio.IsEnd {
+			rec.finish()
+		}
+	})
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("读取WebSocket消息失败: %v", err)
 			}
+			readErr = err
 			break
 		}
 
 		// 更新连接活动时间
-		s.updateActivity(conn)
-
-		// 处理不同类型的消息
-		switch messageType {
-		case websocket.TextMessage:
-			// 尝试解析为语法设置请求
-			var grammar ASRGrammar
-			if err := json.Unmarshal(message, &grammar); err == nil {
-				s.Mu.Lock()
-				s.Grammars[conn] = grammar.Grammar
-				s.Mu.Unlock()
+		sess.touch()
+
+		messages.handle(messageType, message)
+	}
+}
+
+// handleTranscripts 发送识别结果，每句的最终结果交给对话服务生成AI回复
+func (s *ASRServer) handleTranscripts(out *lockedConn, sessionID string, results <-chan transcript, turns *turn.Manager, detector *vad.Detector) {
+	for result := range results {
+		response := ASRResponse{Text: result.Text}
+		s.publishEvent(sessionID, models.EventTypeTranscript, models.SpeakerCustomer, result.Text, result.IsFinal)
+		if result.IsFinal && result.Text != "" {
+			s.recordTranscript(out, models.SpeakerCustomer, result.Text, result.Start)
+			s.Hooks.TranscriptFinal(context.Background(), hooks.Transcript{CallUUID: out.callUUID, SessionID: sessionID, Text: result.Text})
+			if out.callUUID != "" && s.Consent != nil {
+				s.Consent.DetectConsent(out.callUUID, result.Text)
+			}
+		}
+
+		if result.IsFinal && result.Text != "" && s.DialogSvc != nil && out.assist.Load() {
+			s.suggest(out, sessionID, result.Text)
+			continue
+		}
+		if result.IsFinal && result.Text != "" && s.DialogSvc != nil {
+			action := turns.OnTranscript(result.Text)
+			if detector != nil {
+				// 启用打断时回复在后台进行，客户再次开口时可被打断
+				out.replies.start(func(ctx context.Context) {
+					s.respond(ctx, out, sessionID, action, response)
+				})
 				continue
 			}
+			s.respond(context.Background(), out, sessionID, action, response)
+			continue
+		}
+
+		if result.Text == "" {
+			continue
+		}
+		response.IsEnd = result.IsFinal
+		if err := out.WriteJSON(response); err != nil {
+			log.Printf("发送识别结果失败: %v", err)
+		}
+	}
+}
+
+// respond 按轮次动作生成AI回复，连同识别结果一起发送
+func (s *ASRServer) respond(ctx context.Context, conn *lockedConn, sessionID string, action turn.Action, response ASRResponse) {
+	start := time.Now()
+	var aiReply, intentName string
+	var err error
+	switch action {
+	case turn.ActionHold:
+		// 客户要求稍等，只回复提示语，暂停期间不调用大模型
+		log.Printf("客户要求稍等，对话已暂停: %s", sessionID)
+		aiReply = s.say(ctx, conn, sessionID, s.Config.Turn.HoldReply)
+	case turn.ActionIgnore:
+	default:
+		if match, ok := s.Intents.Match(response.Text); ok {
+			intentName = match.Rule
+			aiReply = s.handleIntent(ctx, conn, sessionID, response.Text, match)
+			break
+		}
+		aiReply, err = s.reply(ctx, conn, sessionID, response.Text)
+		// 回复被打断或生成失败时工具已要求的动作仍然执行
+		s.runToolAction(conn, sessionID)
+	}
+	if errors.Is(err, ErrInterrupted) {
+		log.Printf("%v: %s", err, sessionID)
+	} else if err != nil {
+		log.Printf("处理对话失败: %v", err)
+	} else if aiReply != "" {
+		s.recordTranscript(conn, models.SpeakerAI, aiReply, start)
+		response.AIReply = aiReply
+		response.IsEnd = true
+		s.publishEvent(sessionID, models.EventTypeDialog, models.SpeakerAI, aiReply, true)
+		s.openMenu(conn, aiReply)
+		s.Hooks.DialogTurn(ctx, hooks.Turn{
+			CallUUID:  conn.callUUID,
+			SessionID: sessionID,
+			Input:     response.Text,
+			Reply:     aiReply,
+			Intent:    intentName,
+			Latency:   time.Since(start),
+		})
+	}
+
+	if err := conn.WriteJSON(response); err != nil {
+		log.Printf("发送响应失败: %v", err)
+	}
+}
+
+// lockedConn 串行化WebSocket写操作，允许多个goroutine向同一连接发送消息
+type lockedConn struct {
+	*websocket.Conn
+	session   *connSession
+	mu        sync.Mutex
+	callUUID  string // 通话音频流连接对应的通话UUID
+	sessionID string // 连接的识别和对话会话ID
+	tenant    string // 连接所属的租户ID，未指定租户时为空
+	// assist 是否为坐席通话，AI只推送建议话术；转人工后置为true
+	assist  atomic.Bool
+	replies replyState
+	deadAir *deadair.Watchdog // 静音看门狗，未启用时为nil
+	// writeFailed 是否有消息发送失败，连接结束时据此统计断开原因
+	writeFailed atomic.Bool
+}
+
+// WriteJSON 发送JSON消息
+func (c *lockedConn) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.Conn.WriteJSON(v)
+	if err != nil {
+		c.writeFailed.Store(true)
+	}
+	return err
+}
+
+// WriteMessage 发送消息
+func (c *lockedConn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	err := c.Conn.WriteMessage(messageType, data)
+	if err != nil {
+		c.writeFailed.Store(true)
+	}
+	return err
+}
+
+// say 播放固定话术（合成语音或预录提示音），返回记入对话历史的文本
+func (s *ASRServer) say(ctx context.Context, conn *lockedConn, sessionID, text string) string {
+	if text == "" {
+		return ""
+	}
+	spoken, err := s.sayLine(ctx, conn, sessionID, text)
+	if err != nil {
+		log.Printf("发送合成语音失败: %v", err)
+	}
+	return spoken
+}
+
+// attachStream 登记通话音频流连接，音频流重启时新连接替换旧连接
+func (s *ASRServer) attachStream(callUUID string, conn *lockedConn) {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	if s.streams == nil {
+		s.streams = make(map[string]*lockedConn)
+	}
+	s.streams[callUUID] = conn
+}
+
+// detachStream 注销通话音频流连接，已被新连接替换时不做处理
+func (s *ASRServer) detachStream(callUUID string, conn *lockedConn) {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	if s.streams[callUUID] == conn {
+		delete(s.streams, callUUID)
+	}
+}
+
+// stream 返回通话的音频流连接
+func (s *ASRServer) stream(callUUID string) (*lockedConn, error) {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	conn, ok := s.streams[callUUID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCallNotStreaming, callUUID)
+	}
+	return conn, nil
+}
+
+// Say 让AI在通话中说出指定话术，话术记入对话历史，之后的回复可以接续
+func (s *ASRServer) Say(ctx context.Context, callUUID, text string) error {
+	conn, err := s.stream(callUUID)
+	if err != nil {
+		return err
+	}
+	text, err = s.sayLine(ctx, conn, conn.sessionID, text)
+	if err != nil {
+		return err
+	}
+	if appender, ok := s.DialogSvc.(messageAppender); ok {
+		if err := appender.AppendMessage(conn.sessionID, models.Message{Role: models.RoleAssistant, Content: text}); err != nil {
+			log.Printf("记录插入话术失败: %v", err)
+		}
+	}
+	s.publishEvent(conn.sessionID, models.EventTypeDialog, models.SpeakerAI, text, true)
+	return conn.WriteJSON(ASRResponse{AIReply: text, IsEnd: true})
+}
+
+// InjectContext 向通话的对话上下文补充背景信息，不打断当前对话，对之后的回复生效
+func (s *ASRServer) InjectContext(callUUID, text string) error {
+	conn, err := s.stream(callUUID)
+	if err != nil {
+		return err
+	}
+	appender, ok := s.DialogSvc.(messageAppender)
+	if !ok {
+		return fmt.Errorf("对话服务不支持补充上下文")
+	}
+	return appender.AppendMessage(conn.sessionID, models.Message{Role: models.RoleSystem, Content: text})
+}
+
+// resumeAfterHold 暂停超时后恢复对话，配置了恢复提示语时主动询问客户
+func (s *ASRServer) resumeAfterHold(conn *lockedConn, sessionID string) {
+	if conn.assist.Load() {
+		return
+	}
+	log.Printf("暂停超时，对话已恢复: %s", sessionID)
+	aiReply := s.say(context.Background(), conn, sessionID, s.Config.Turn.ResumeReply)
+	if aiReply == "" {
+		return
+	}
+	s.publishEvent(sessionID, models.EventTypeDialog, models.SpeakerAI, aiReply, true)
+	if err := conn.WriteJSON(ASRResponse{AIReply: aiReply, IsEnd: true}); err != nil {
+		log.Printf("发送响应失败: %v", err)
+	}
+}
 
-			// 尝试解析为音频数据
-			var audioData AudioData
-			if err := json.Unmarshal(message, &audioData); err == nil {
-				// 处理音频数据
-				result, err := s.ASRClient.ProcessAudio(sessionID, audioData.Data)
-				if err != nil {
-					log.Printf("处理音频失败: %v", err)
-					continue
-				}
-
-				// 发送识别结果
-				response := ASRResponse{
-					Text:  result,
-					IsEnd: audioData.IsEnd,
-				}
-
-				if err := conn.WriteJSON(response); err != nil {
-					log.Printf("发送识别结果失败: %v", err)
-					break
-				}
+// reply 生成AI回复并发送合成语音，返回去掉语气标签的回复
+// 对话服务支持流式生成时逐句合成，第一句生成后即开始发送语音，不必等待完整回复；
+// 启用websocket.stream_reply时同时以ai_delta消息逐段推送回复文本
+// ctx取消（客户打断）时停止生成和合成，返回ErrInterrupted
+func (s *ASRServer) reply(ctx context.Context, conn *lockedConn, sessionID, text string) (string, error) {
+	var deltas *replyStream
+	if s.Config.WebSocket.StreamReply {
+		deltas = &replyStream{conn: conn}
+	}
+	if s.TTS == nil && deltas == nil {
+		aiReply, err := s.DialogSvc.ProcessMessage(sessionID, text)
+		_, aiReply = tts.SplitStyle(aiReply)
+		return aiReply, err
+	}
+
+	// 合成和发送在单独的goroutine中进行，与大模型生成并行
+	var sentences chan string
+	done := make(chan struct{})
+	if s.TTS != nil {
+		sentences = make(chan string, 16)
+		go func() {
+			defer close(done)
+			s.speak(ctx, conn, sessionID, sentences)
+		}()
+	} else {
+		close(done)
+	}
+	onSentence := func(sentence string) error {
+		if sentences == nil {
+			return nil
+		}
+		select {
+		case sentences <- sentence:
+			return nil
+		case <-ctx.Done():
+			return ErrInterrupted
+		}
+	}
+
+	var aiReply string
+	var err error
+	tokens, hasTokens := s.DialogSvc.(models.TokenStreamingDialogService)
+	streaming, hasSentences := s.DialogSvc.(models.StreamingDialogService)
+	switch {
+	case deltas != nil && hasTokens:
+		aiReply, err = tokens.ProcessMessageTokens(sessionID, text, func(token string) error {
+			if ctx.Err() != nil {
+				return ErrInterrupted
 			}
+			return deltas.write(token)
+		}, onSentence)
+		if err == nil && ctx.Err() == nil {
+			err = deltas.flush()
+		}
+	case sentences != nil && hasSentences:
+		aiReply, err = streaming.ProcessMessageStream(sessionID, text, onSentence)
+	default:
+		aiReply, err = s.DialogSvc.ProcessMessage(sessionID, text)
+		if err == nil {
+			err = onSentence(aiReply)
+		}
+	}
+	if sentences != nil {
+		close(sentences)
+	}
+	<-done
 
-		case websocket.BinaryMessage:
-			// 直接处理二进制音频数据
-			result, err := s.ASRClient.ProcessAudio(sessionID, message)
-			if err != nil {
-				log.Printf("处理音频失败: %v", err)
+	// 客户端的流式回调错误可能被大模型客户端包装，以ctx判断是否被打断
+	if ctx.Err() != nil {
+		err = ErrInterrupted
+	}
+	if errors.Is(err, ErrInterrupted) {
+		return "", ErrInterrupted
+	}
+	_, aiReply = tts.SplitStyle(aiReply)
+	return aiReply, err
+}
+
+// speak 依次合成并发送回复语音，第一句迟迟未就绪时先播放应答语音
+// 回复开头的语气标签决定整段回复使用的合成参数
+func (s *ASRServer) speak(ctx context.Context, conn *lockedConn, sessionID string, sentences <-chan string) {
+	turn := s.Fillers.Start()
+	defer turn.Responded()
+
+	var options *tts.Options
+	for {
+		select {
+		case sentence, ok := <-sentences:
+			if !ok {
+				return
+			}
+			turn.Responded()
+			if options == nil {
+				var style string
+				style, sentence = tts.SplitStyle(sentence)
+				resolved := s.voiceOptions(sessionID, style)
+				options = &resolved
+			}
+			if sentence == "" {
 				continue
 			}
-
-			// 发送识别结果
-			response := ASRResponse{
-				Text:  result,
-				IsEnd: false,
+			if err := s.sendSpeech(ctx, conn, sessionID, sentence, *options); err != nil {
+				log.Printf("发送合成语音失败: %v", err)
 			}
+		case <-turn.C():
+			clip := turn.Clip()
+			s.Tap.Publish(sessionID, tap.LegAI, clip.SampleRate, clip.PCM)
+			s.play(conn, clip)
+			if err := conn.WriteMessage(websocket.BinaryMessage, clip.WAV()); err != nil {
+				log.Printf("发送应答语音失败: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sessionCampaign 可查询会话所属活动的对话服务
+type sessionCampaign interface {
+	GetSessionOptions(sessionID string) (*models.SessionOptions, error)
+}
+
+// voiceOptions 获取语气风格对应的合成参数
+// 回复未带标签时依次使用会话所属活动的默认风格和全局默认风格
+func (s *ASRServer) voiceOptions(sessionID, style string) tts.Options {
+	styles := s.Config.TTS.Styles
+	if _, ok := styles[style]; style != "" && ok {
+		return styles[style]
+	}
+	if style != "" {
+		log.Printf("未定义的语气风格: %s，使用默认风格", style)
+	}
 
-			if err := conn.WriteJSON(response); err != nil {
-				log.Printf("发送响应失败: %v", err)
-				break
+	if lookup, ok := s.DialogSvc.(sessionCampaign); ok {
+		if options, err := lookup.GetSessionOptions(sessionID); err == nil {
+			if campaignStyle, ok := s.Config.TTS.Campaigns[options.CampaignID]; ok {
+				return styles[campaignStyle]
 			}
 		}
 	}
+	return styles[s.Config.TTS.DefaultStyle]
+}
+
+// sendSpeech 将AI回复合成为语音并以WAV二进制消息发送
+func (s *ASRServer) sendSpeech(ctx context.Context, conn *lockedConn, sessionID, text string, options tts.Options) error {
+	if s.TTS == nil {
+		return nil
+	}
+
+	audio, err := s.TTS.Synthesize(ctx, text, options)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("合成语音失败: %v", err)
+	}
+	s.Tap.Publish(sessionID, tap.LegAI, audio.SampleRate, audio.PCM)
+	s.play(conn, audio)
+	return conn.WriteMessage(websocket.BinaryMessage, audio.WAV())
+}
+
+// play 记录AI语音的播放时长，通话音频流连接的语音同时在通话中播放
+func (s *ASRServer) play(conn *lockedConn, audio *tts.Audio) {
+	conn.replies.spoke(audio.Duration())
+	conn.deadAir.Playing(audio.Duration())
+	if s.Playback == nil || conn.callUUID == "" {
+		return
+	}
+	if err := s.Playback.Play(conn.callUUID, audio); err != nil {
+		log.Printf("通话中播放语音失败: %v", err)
+	}
+}
+
+// publishEvent 发布通话实时事件，空文本不发布
+func (s *ASRServer) publishEvent(sessionID, eventType, speaker, text string, isFinal bool) {
+	if s.Events == nil || text == "" {
+		return
+	}
+
+	event := &models.CallEvent{
+		Type:      eventType,
+		SessionID: sessionID,
+		Speaker:   speaker,
+		Text:      text,
+		IsFinal:   isFinal,
+	}
+	if err := s.Events.Publish(context.Background(), event); err != nil {
+		log.Printf("发布通话事件失败: %v", err)
+	}
+}
+
+// recordTranscript 保存通话音频流连接上的一条语音转写，start为开始时间，结束时间为当前时间
+func (s *ASRServer) recordTranscript(conn *lockedConn, speaker, text string, start time.Time) {
+	if s.Transcripts == nil || conn.callUUID == "" {
+		return
+	}
+	now := time.Now()
+	if start.IsZero() {
+		start = now
+	}
+	s.Transcripts.Record(context.Background(), conn.callUUID, speaker, models.TranscriptKindSpeech, text, start, now)
 }
 
 // checkWebSocketHeaders 检查WebSocket必要的头信息
@@ -240,6 +864,12 @@ func (s *ASRServer) processAudio(data []byte, format string) (string, float64) {
 
 // HandleConnection 处理WebSocket连接
 func (s *ASRServer) HandleConnection(c *gin.Context) {
+	if !s.Lifecycle.Accepting() {
+		RecordDisconnect(EndpointASR, DisconnectServerDrain)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "服务正在排空，不接受新的连接"})
+		return
+	}
+
 	// 升级HTTP连接为WebSocket
 	conn, err := s.Upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -247,20 +877,28 @@ func (s *ASRServer) HandleConnection(c *gin.Context) {
 		return
 	}
 	defer conn.Close()
+	atomic.AddInt64(&s.active, 1)
+	defer atomic.AddInt64(&s.active, -1)
 
-	// 初始化连接
-	s.Mu.Lock()
-	s.LastActivity[conn] = time.Now()
-	s.Mu.Unlock()
-
-	// 处理连接关闭
+	// 处理连接关闭，按原因统计断开次数
+	sess := s.openSession(conn)
+	var readErr error
+	writeFailed := false
 	defer func() {
-		s.Mu.Lock()
-		delete(s.LastActivity, conn)
-		delete(s.Grammars, conn)
-		s.Mu.Unlock()
+		s.finishConn(sess, EndpointASR, writeFailed, readErr)
 	}()
 
+	// 获取会话ID，客户端未提供时生成新的会话ID并在第一条消息中返回
+	sessionID := c.Query("session_id")
+	if sessionID == "" {
+		sessionID = models.NewSessionID()
+	}
+	if err := conn.WriteJSON(models.NewSessionMessage(sessionID)); err != nil {
+		log.Printf("发送会话ID失败: %v", err)
+		writeFailed = true
+		return
+	}
+
 	// 设置连接配置
 	conn.SetReadLimit(int64(s.Config.WebSocket.ReadBufferSize))
 	conn.SetReadDeadline(time.Now().Add(s.Config.WebSocket.PongWait))
@@ -269,54 +907,56 @@ func (s *ASRServer) HandleConnection(c *gin.Context) {
 		return nil
 	})
 
-	// 处理消息
+	// 处理消息，语法设置保存到连接，音频识别后交给对话服务处理
+	messages := s.newMessageContext(EndpointASR, sessionID, "", sess, nil, func(c *MessageContext) {
+		if c.Grammar != nil {
+			if c.Grammar.Grammar != "" {
+				sess.setGrammar(c.Grammar.Grammar)
+			}
+			return
+		}
+
+		s.Tap.Publish(sessionID, tap.LegCustomer, inputSampleRate, c.Audio.Data)
+		text, confidence := s.processAudio(c.Audio.Data, "pcm")
+		response := ASRResponse{
+			Text:       text,
+			Confidence: confidence,
+			IsEnd:      false,
+		}
+
+		// 如果有文本结果，发送给对话服务处理
+		if text != "" {
+			aiReply, err := s.DialogSvc.ProcessMessage(sessionID, text)
+			if err != nil {
+				log.Printf("处理对话失败: %v", err)
+			} else {
+				response.AIReply = aiReply
+				response.IsEnd = true
+			}
+		}
+
+		if err := conn.WriteJSON(response); err != nil {
+			log.Printf("发送响应失败: %v", err)
+			writeFailed = true
+			c.Err = err
+		}
+	})
 	for {
 		messageType, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("读取WebSocket消息错误: %v", err)
 			}
+			readErr = err
 			break
 		}
 
 		// 更新活动时间
-		s.updateActivity(conn)
-
-		// 处理消息
-		switch messageType {
-		case websocket.BinaryMessage:
-			// 处理音频数据
-			text, confidence := s.processAudio(message, "pcm")
-			response := ASRResponse{
-				Text:       text,
-				Confidence: confidence,
-				IsEnd:      false,
-			}
-			
-			// 如果有文本结果，发送给对话服务处理
-			if text != "" {
-				aiReply, err := s.DialogSvc.ProcessMessage("default", text)
-				if err != nil {
-					log.Printf("处理对话失败: %v", err)
-				} else {
-					response.AIReply = aiReply
-					response.IsEnd = true
-				}
-			}
+		sess.touch()
 
-			if err := conn.WriteJSON(response); err != nil {
-				log.Printf("发送响应失败: %v", err)
-				return
-			}
-
-		case websocket.TextMessage:
-			// 处理文本消息（如语法设置）
-			var grammar ASRGrammar
-			if err := json.Unmarshal(message, &grammar); err == nil && grammar.Grammar != "" {
-				s.Mu.Lock()
-				s.Grammars[conn] = grammar.Grammar
-				s.Mu.Unlock()
-			}
+		messages.handle(messageType, message)
+		if writeFailed {
+			return
 		}
 	}
 }
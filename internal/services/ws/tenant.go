@@ -0,0 +1,83 @@
+package ws
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+	"ai_dialer_mini/internal/models"
+)
+
+// callTenants 可查询通话所属租户的通话会话跟踪，由services.CallSessionManager实现
+type callTenants interface {
+	CallTenant(callUUID string) string
+}
+
+// sessionModelSetter 可设置会话大模型的对话服务
+type sessionModelSetter interface {
+	SetSessionModel(sessionID, model string) error
+}
+
+// resolveTenant 连接所属的租户：通话音频流使用通道变量指定的租户，其他连接使用tenant查询参数；
+// 查询参数指定了未配置的租户时返回错误
+func (s *ASRServer) resolveTenant(r *http.Request, callUUID string) (string, error) {
+	if callUUID != "" {
+		if lookup, ok := s.Calls.(callTenants); ok {
+			return lookup.CallTenant(callUUID), nil
+		}
+		return "", nil
+	}
+	id := r.URL.Query().Get("tenant")
+	if id == "" {
+		return "", nil
+	}
+	if _, ok := s.Tenants.Get(id); !ok {
+		return "", fmt.Errorf("未配置的租户: %s", id)
+	}
+	return id, nil
+}
+
+// applyTenant 按租户设置会话的大模型和AI人设，呼入路由配置的人设随后设置，优先级更高
+func (s *ASRServer) applyTenant(conn *lockedConn) {
+	t, ok := s.Tenants.Get(conn.tenant)
+	if !ok {
+		return
+	}
+	if t.Model != "" {
+		if setter, ok := s.DialogSvc.(sessionModelSetter); !ok {
+			log.Printf("对话服务不支持设置模型，租户 %s 使用全局模型", conn.tenant)
+		} else if err := setter.SetSessionModel(conn.sessionID, t.Model); err != nil {
+			log.Printf("设置租户 %s 的大模型失败: %v", conn.tenant, err)
+		}
+	}
+	if t.Persona != "" {
+		if configurer, ok := s.DialogSvc.(sessionConfigurer); !ok {
+			log.Printf("对话服务不支持设置提示词配置，租户 %s 使用默认人设", conn.tenant)
+		} else if _, err := configurer.SetSessionOptions(conn.sessionID, "", t.Persona, models.GenerationOverrides{}); err != nil {
+			log.Printf("设置租户 %s 的人设失败: %v", conn.tenant, err)
+		}
+	}
+}
+
+// asrClient 租户使用的语音识别客户端：租户配置了讯飞凭据时使用该凭据的独立客户端（不使用连接预热池），
+// 否则使用默认客户端
+func (s *ASRServer) asrClient(tenantID string) *xfyun.ASRClient {
+	t, ok := s.Tenants.Get(tenantID)
+	if !ok || t.XFYun.Empty() {
+		return s.ASRClient
+	}
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	if client, ok := s.tenantASR[tenantID]; ok {
+		return client
+	}
+	config := s.Config.ASR.XFYun
+	config.AppID, config.APIKey, config.APISecret = t.XFYun.AppID, t.XFYun.APIKey, t.XFYun.APISecret
+	client := xfyun.NewASRClient(config, s.DialogSvc)
+	if s.tenantASR == nil {
+		s.tenantASR = make(map[string]*xfyun.ASRClient)
+	}
+	s.tenantASR[tenantID] = client
+	return client
+}
@@ -0,0 +1,52 @@
+package ws
+
+import (
+	"log"
+	"time"
+
+	"ai_dialer_mini/internal/services/tools"
+)
+
+// callBinder 可将对话会话关联到通话的对话服务，工具调用据此获取通话UUID
+type callBinder interface {
+	BindCall(sessionID, callUUID string) error
+}
+
+// toolActionTaker 支持工具调用的对话服务，工具可要求在AI回复后执行挂机或转人工
+type toolActionTaker interface {
+	TakeToolAction(sessionID string) (tools.Action, bool)
+}
+
+// bindCall 通话音频流接入时将对话会话关联到通话
+func (s *ASRServer) bindCall(sessionID, callUUID string) {
+	binder, ok := s.DialogSvc.(callBinder)
+	if !ok {
+		return
+	}
+	if err := binder.BindCall(sessionID, callUUID); err != nil {
+		log.Printf("关联对话会话与通话失败: %v", err)
+	}
+}
+
+// runToolAction 取出本轮工具要求的通话动作，在AI回复播放完后执行；只对通话音频流连接生效
+func (s *ASRServer) runToolAction(conn *lockedConn, sessionID string) {
+	taker, ok := s.DialogSvc.(toolActionTaker)
+	if !ok {
+		return
+	}
+	action, ok := taker.TakeToolAction(sessionID)
+	if !ok {
+		return
+	}
+	if conn.callUUID == "" || s.Control == nil {
+		log.Printf("连接未关联通话，跳过工具要求的动作 %s: %s", action.Type, sessionID)
+		return
+	}
+	time.AfterFunc(conn.replies.remaining(), func() {
+		if err := s.executeAction(conn, action.Type, action.Destination, action.DialplanContext); err != nil {
+			log.Printf("执行工具要求的动作 %s 失败: %v", action.Type, err)
+			return
+		}
+		log.Printf("已执行工具要求的动作 %s: %s", action.Type, conn.callUUID)
+	})
+}
@@ -1,35 +1,85 @@
 package services
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
+
+	"ai_dialer_mini/internal/config"
 
 	"github.com/gorilla/websocket"
 )
 
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin: config.CheckWebSocketOrigin,
+}
+
+// hubWriteWait 发送单条消息的写超时
+const hubWriteWait = 5 * time.Second
+
+// hubSendBuffer 每个客户端待发送消息的缓冲条数，缓冲已满的客户端视为过慢并断开，避免拖慢其他客户端
+const hubSendBuffer = 64
+
+// hubClient 推送中心的客户端连接，消息经缓冲通道由独立的写goroutine发送
+type hubClient struct {
+	conn   *websocket.Conn
+	send   chan []byte
+	filter *eventFilter // 只推送满足条件的通话事件，为nil时推送所有消息
+}
+
+// eventFilter 事件订阅条件
+type eventFilter struct {
+	types     map[string]bool // 事件类型，为空时不限
+	sessionID string          // 会话ID（通话UUID），为空时不限
+}
+
+// parseEventFilter 解析订阅条件：types为逗号分隔的事件类型，session_id为会话ID
+func parseEventFilter(r *http.Request) *eventFilter {
+	filter := &eventFilter{sessionID: r.URL.Query().Get("session_id")}
+	for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			if filter.types == nil {
+				filter.types = make(map[string]bool)
+			}
+			filter.types[t] = true
+		}
+	}
+	return filter
+}
+
+// match 判断事件是否满足订阅条件
+func (f *eventFilter) match(event *eventHeader) bool {
+	if f.sessionID != "" && event.SessionID != f.sessionID {
+		return false
+	}
+	return len(f.types) == 0 || f.types[event.Type]
+}
+
+// eventHeader 过滤时需要的事件字段，非事件消息解析后为空
+type eventHeader struct {
+	Type      string `json:"type"`
+	SessionID string `json:"session_id"`
 }
 
 // WSService WebSocket服务
 type WSService struct {
-	clients    map[*websocket.Conn]bool
+	clients    map[*hubClient]bool
 	broadcast  chan []byte
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
+	register   chan *hubClient
+	unregister chan *hubClient
 	mu         sync.RWMutex
 }
 
 // NewWSService 创建新的WebSocket服务实例
 func NewWSService() *WSService {
 	return &WSService{
-		clients:    make(map[*websocket.Conn]bool),
+		clients:    make(map[*hubClient]bool),
 		broadcast:  make(chan []byte),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
+		register:   make(chan *hubClient),
+		unregister: make(chan *hubClient),
 	}
 }
 
@@ -44,28 +94,70 @@ func (s *WSService) Run() {
 
 		case client := <-s.unregister:
 			s.mu.Lock()
-			if _, ok := s.clients[client]; ok {
-				delete(s.clients, client)
-				client.Close()
-			}
+			s.removeClient(client)
 			s.mu.Unlock()
 
 		case message := <-s.broadcast:
-			s.mu.RLock()
+			s.mu.Lock()
+			var header *eventHeader
 			for client := range s.clients {
-				if err := client.WriteMessage(websocket.TextMessage, message); err != nil {
-					log.Printf("发送消息失败: %v", err)
-					client.Close()
-					delete(s.clients, client)
+				if client.filter != nil {
+					// 有订阅条件的客户端存在时才解析事件，每条消息只解析一次
+					if header == nil {
+						header = &eventHeader{}
+						json.Unmarshal(message, header)
+					}
+					if !client.filter.match(header) {
+						continue
+					}
+				}
+				select {
+				case client.send <- message:
+				default:
+					log.Printf("客户端接收过慢，断开连接: %s", client.conn.RemoteAddr())
+					s.removeClient(client)
 				}
 			}
-			s.mu.RUnlock()
+			s.mu.Unlock()
 		}
 	}
 }
 
-// HandleConnection 处理WebSocket连接
+// removeClient 移除客户端并关闭连接，需持有锁
+func (s *WSService) removeClient(client *hubClient) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	delete(s.clients, client)
+	close(client.send)
+	client.conn.Close()
+}
+
+// writePump 依次发送客户端缓冲中的消息，发送失败或缓冲关闭时结束
+func (s *WSService) writePump(client *hubClient) {
+	defer client.conn.Close()
+	for message := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(hubWriteWait))
+		if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			log.Printf("发送消息失败: %v", err)
+			return
+		}
+	}
+}
+
+// HandleConnection 处理WebSocket连接，客户端发送的消息广播给所有客户端
 func (s *WSService) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, nil)
+}
+
+// HandleEvents 处理只读的通话事件订阅连接，按查询参数types（逗号分隔的事件类型）和session_id过滤，
+// 客户端发送的消息不转发
+func (s *WSService) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, parseEventFilter(r))
+}
+
+// serve 注册客户端并读取消息直至连接关闭，filter为nil时广播客户端发送的消息
+func (s *WSService) serve(w http.ResponseWriter, r *http.Request, filter *eventFilter) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("升级WebSocket连接失败: %v", err)
@@ -73,11 +165,13 @@ func (s *WSService) HandleConnection(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 注册新客户端
-	s.register <- conn
+	client := &hubClient{conn: conn, send: make(chan []byte, hubSendBuffer), filter: filter}
+	s.register <- client
+	go s.writePump(client)
 
 	// 处理连接关闭
 	defer func() {
-		s.unregister <- conn
+		s.unregister <- client
 	}()
 
 	// 读取消息
@@ -90,8 +184,10 @@ func (s *WSService) HandleConnection(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 
-		// 广播消息
-		s.broadcast <- message
+		// 广播消息，订阅连接只接收事件
+		if filter == nil {
+			s.broadcast <- message
+		}
 	}
 }
 
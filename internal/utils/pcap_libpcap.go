@@ -0,0 +1,10 @@
+//go:build libpcap
+
+package utils
+
+import "github.com/google/gopacket/pcap"
+
+// openOffline 通过libpcap打开离线抓包文件
+func openOffline(filename string) (packetHandle, error) {
+	return pcap.OpenOffline(filename)
+}
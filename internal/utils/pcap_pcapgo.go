@@ -0,0 +1,64 @@
+//go:build !libpcap
+
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+// pcapngMagic pcapng文件的节头块类型
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// pcapgoSource pcapgo读取器需要实现的方法，pcap和pcapng格式分别由Reader和NgReader实现
+type pcapgoSource interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+	LinkType() layers.LinkType
+}
+
+// fileHandle 纯Go实现的离线抓包文件句柄
+type fileHandle struct {
+	pcapgoSource
+	file *os.File
+}
+
+// Close 关闭文件
+func (h *fileHandle) Close() {
+	h.file.Close()
+}
+
+// openOffline 用pcapgo打开离线抓包文件，支持pcap和pcapng格式，无需libpcap
+func openOffline(filename string) (packetHandle, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(pcapngMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("读取文件头失败: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	var source pcapgoSource
+	if bytes.Equal(magic, pcapngMagic) {
+		source, err = pcapgo.NewNgReader(file, pcapgo.DefaultNgReaderOptions)
+	} else {
+		source, err = pcapgo.NewReader(file)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileHandle{pcapgoSource: source, file: file}, nil
+}
@@ -7,19 +7,26 @@ import (
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
-	"github.com/google/gopacket/pcap"
 )
 
+// packetHandle 离线抓包文件句柄，默认由纯Go的pcapgo实现，
+// 使用 -tags libpcap 构建时改用libpcap（需要cgo）
+type packetHandle interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+	Close()
+}
+
 // PCAPReader 用于读取和解析PCAP文件
 type PCAPReader struct {
 	filename string
-	handle   *pcap.Handle
+	handle   packetHandle
 }
 
 // NewPCAPReader 创建新的PCAP读取器
 func NewPCAPReader(filename string) (*PCAPReader, error) {
 	// 打开PCAP文件
-	handle, err := pcap.OpenOffline(filename)
+	handle, err := openOffline(filename)
 	if err != nil {
 		return nil, fmt.Errorf("打开PCAP文件失败: %v", err)
 	}
@@ -43,7 +50,7 @@ func (r *PCAPReader) reopenHandle() error {
 		r.handle.Close()
 	}
 
-	handle, err := pcap.OpenOffline(r.filename)
+	handle, err := openOffline(r.filename)
 	if err != nil {
 		return fmt.Errorf("重新打开PCAP文件失败: %v", err)
 	}
@@ -165,111 +172,121 @@ func (r *PCAPReader) ReadWebSocketFrames() ([][]byte, error) {
 		data = data[tcpHeaderLen:]
 
 		// 尝试在原始数据中查找WebSocket帧
-		for i := 0; i < len(data)-2; i++ {
-			// 检查是否为WebSocket帧的起始
-			// 第一个字节的FIN位应该为1，RSV1-3位应该为0，opcode应该是文本或二进制
-			if (data[i]&0x80 != 0) && (data[i]&0x70 == 0) && (data[i]&0x0F == 0x1 || data[i]&0x0F == 0x2) {
-				opcode := data[i] & 0x0F
-				if opcode != 0x1 && opcode != 0x2 {
-					continue
-				}
+		frames = append(frames, ScanWebSocketFrames(data)...)
+	}
 
-				// 获取payload长度
-				payloadLen := int(data[i+1] & 0x7F)
-				headerLen := 2
+	return frames, nil
+}
 
-				if len(data) < i+headerLen {
-					continue
-				}
+// maxFramePayload 扫描时接受的最大帧负载长度
+const maxFramePayload = 65535
 
-				// 处理扩展长度
-				if payloadLen == 126 {
-					if len(data) < i+4 {
-						continue
-					}
-					payloadLen = int(data[i+2])<<8 | int(data[i+3])
-					headerLen += 2
-				} else if payloadLen == 127 {
-					if len(data) < i+10 {
-						continue
-					}
-					payloadLen = 0
-					for j := 0; j < 8; j++ {
-						payloadLen = payloadLen<<8 | int(data[i+2+j])
-					}
-					headerLen += 8
-				}
+// ScanWebSocketFrames 在TCP负载中扫描文本和二进制WebSocket帧，返回解除掩码后的帧负载
+// 不完整、长度编码不规范或内容无意义（全部相同字节、全是空白、无效UTF-8文本）的候选帧被跳过
+func ScanWebSocketFrames(data []byte) [][]byte {
+	var frames [][]byte
+	for i := 0; i < len(data)-2; i++ {
+		// 检查是否为WebSocket帧的起始
+		// 第一个字节的FIN位应该为1，RSV1-3位应该为0，opcode应该是文本或二进制
+		if (data[i]&0x80 != 0) && (data[i]&0x70 == 0) && (data[i]&0x0F == 0x1 || data[i]&0x0F == 0x2) {
+			opcode := data[i] & 0x0F
+			if opcode != 0x1 && opcode != 0x2 {
+				continue
+			}
+
+			// 获取payload长度
+			payloadLen := int(data[i+1] & 0x7F)
+			headerLen := 2
+
+			if len(data) < i+headerLen {
+				continue
+			}
 
-				// 验证payload长度是否合理
-				if payloadLen <= 0 || payloadLen > 65535 {
+			// 处理扩展长度
+			if payloadLen == 126 {
+				if len(data) < i+4 {
 					continue
 				}
-
-				// 检查掩码位
-				masked := (data[i+1] & 0x80) != 0
-				if masked {
-					headerLen += 4
+				payloadLen = int(data[i+2])<<8 | int(data[i+3])
+				headerLen += 2
+				// 规范编码中小于126的长度不使用扩展长度，否则多半是误识别
+				if payloadLen < 126 {
+					continue
 				}
+			} else if payloadLen == 127 {
+				// 64位扩展长度必然超过maxFramePayload
+				continue
+			}
 
-				// 确保有足够的数据
-				if len(data) < i+headerLen+payloadLen {
-					continue
+			// 验证payload长度是否合理
+			if payloadLen <= 0 || payloadLen > maxFramePayload {
+				continue
+			}
+
+			// 检查掩码位
+			masked := (data[i+1] & 0x80) != 0
+			if masked {
+				headerLen += 4
+			}
+
+			// 确保有足够的数据
+			if len(data) < i+headerLen+payloadLen {
+				continue
+			}
+
+			// 提取帧数据
+			frameData := make([]byte, payloadLen)
+			copy(frameData, data[i+headerLen:i+headerLen+payloadLen])
+
+			// 如果数据被掩码，则解码
+			if masked {
+				maskKey := data[i+headerLen-4 : i+headerLen]
+				for j := 0; j < payloadLen; j++ {
+					frameData[j] ^= maskKey[j%4]
 				}
+			}
 
-				// 提取帧数据
-				frameData := make([]byte, payloadLen)
-				copy(frameData, data[i+headerLen:i+headerLen+payloadLen])
+			// 验证数据是否是有效的UTF-8文本（如果是文本帧）
+			if opcode == 0x1 && !utf8.Valid(frameData) {
+				continue
+			}
 
-				// 如果数据被掩码，则解码
-				if masked {
-					maskKey := data[i+headerLen-4 : i+headerLen]
-					for j := 0; j < payloadLen; j++ {
-						frameData[j] ^= maskKey[j%4]
+			// 验证数据不是全零或全相同字节
+			if len(frameData) > 0 {
+				allSame := true
+				firstByte := frameData[0]
+				for _, b := range frameData[1:] {
+					if b != firstByte {
+						allSame = false
+						break
 					}
 				}
-
-				// 验证数据是否是有效的UTF-8文本（如果是文本帧）
-				if opcode == 0x1 && !utf8.Valid(frameData) {
+				if allSame {
 					continue
 				}
+			}
 
-				// 验证数据不是全零或全相同字节
-				if len(frameData) > 0 {
-					allSame := true
-					firstByte := frameData[0]
-					for _, b := range frameData[1:] {
-						if b != firstByte {
-							allSame = false
-							break
-						}
-					}
-					if allSame {
-						continue
+			// 验证数据不是全空白字符
+			if len(frameData) > 0 {
+				allWhitespace := true
+				for _, b := range frameData {
+					if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+						allWhitespace = false
+						break
 					}
 				}
-
-				// 验证数据不是全空白字符
-				if len(frameData) > 0 {
-					allWhitespace := true
-					for _, b := range frameData {
-						if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
-							allWhitespace = false
-							break
-						}
-					}
-					if allWhitespace {
-						continue
-					}
+				if allWhitespace {
+					continue
 				}
-
-				frames = append(frames, frameData)
-				// 跳过已处理的数据
-				i += headerLen + payloadLen - 1
 			}
+
+			frames = append(frames, frameData)
+			// 跳过已处理的数据
+			i += headerLen + payloadLen - 1
 		}
 	}
 
-	return frames, nil
+	return frames
 }
 
 // WebSocketHandshake WebSocket握手信息
@@ -0,0 +1,23 @@
+// Package web 提供内嵌的演示网页客户端
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// staticFiles 演示页面静态资源，编译时嵌入二进制
+//
+//go:embed static
+var staticFiles embed.FS
+
+// FileSystem 获取演示页面的静态文件系统
+func FileSystem() http.FileSystem {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		// static 目录在编译期嵌入，这里出错说明构建有问题
+		panic(err)
+	}
+	return http.FS(sub)
+}
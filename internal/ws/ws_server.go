@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"sync"
 
+	"ai_dialer_mini/internal/config"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -34,9 +36,7 @@ type ASRServer struct {
 func NewASRServer() *ASRServer {
 	return &ASRServer{
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				return true // 在生产环境中应该实现适当的源检查
-			},
+			CheckOrigin: config.CheckWebSocketOrigin,
 		},
 		grammars: make(map[*websocket.Conn]string),
 	}
@@ -0,0 +1,59 @@
+package codec_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/clients/tts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWAV 一秒16kHz单声道静音
+func testWAV() []byte {
+	audio := tts.Audio{PCM: make([]byte, 32000), SampleRate: 16000, Channels: 1}
+	return audio.WAV()
+}
+
+func TestNew_WAV(t *testing.T) {
+	c, err := codec.New(codec.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, "wav", c.Extension())
+	assert.Equal(t, "audio/wav", c.ContentType())
+
+	wav := testWAV()
+	data, err := c.Encode(context.Background(), wav)
+	require.NoError(t, err)
+	assert.Equal(t, wav, data)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	_, err := codec.New(codec.Config{Format: "mp3"})
+	assert.ErrorContains(t, err, "不支持")
+
+	_, err = codec.New(codec.Config{Format: codec.FormatFLAC, FFmpeg: "/nonexistent/ffmpeg"})
+	assert.ErrorContains(t, err, "ffmpeg")
+}
+
+func TestFFmpegCodecs(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("未安装ffmpeg")
+	}
+
+	flac, err := codec.New(codec.Config{Format: codec.FormatFLAC})
+	require.NoError(t, err)
+	data, err := flac.Encode(context.Background(), testWAV())
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("fLaC")))
+
+	opus, err := codec.New(codec.Config{Format: codec.FormatOpus, Bitrate: 16})
+	require.NoError(t, err)
+	assert.Equal(t, "opus", opus.Extension())
+	data, err = opus.Encode(context.Background(), testWAV())
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("OggS")))
+}
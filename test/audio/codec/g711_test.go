@@ -0,0 +1,78 @@
+package codec_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"ai_dialer_mini/internal/audio/codec"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeSamples 解码为采样，便于比较
+func decodeSamples(encoding string, data []byte) []int16 {
+	pcm := codec.DecodeToL16(encoding, data)
+	out := make([]int16, len(pcm)/2)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(pcm[i*2:]))
+	}
+	return out
+}
+
+func TestDecodeToL16_KnownValues(t *testing.T) {
+	// G.711参考实现的解码结果
+	assert.Equal(t, []int16{0, 0, -32124, 32124, -15996}, decodeSamples(codec.EncodingPCMU, []byte{0xFF, 0x7F, 0x00, 0x80, 0x10}))
+	assert.Equal(t, []int16{8, -8, -32256, 32256, 1056}, decodeSamples(codec.EncodingPCMA, []byte{0xD5, 0x55, 0x2A, 0xAA, 0xE5}))
+
+	// L16原样返回
+	pcm := []byte{1, 2, 3, 4}
+	assert.Equal(t, pcm, codec.DecodeToL16(codec.EncodingL16, pcm))
+}
+
+func TestG711_RoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 100, -100, 1000, -1000, 12345, -12345, 32767, -32768}
+	pcm := make([]byte, len(samples)*2)
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(s))
+	}
+
+	for name, encode := range map[string]func([]byte) []byte{
+		codec.EncodingPCMU: codec.EncodeULaw,
+		codec.EncodingPCMA: codec.EncodeALaw,
+	} {
+		encoded := encode(pcm)
+		require.Len(t, encoded, len(samples), name)
+		decoded := decodeSamples(name, encoded)
+		for i, s := range samples {
+			// 对数量化的误差随幅度增大，不超过幅度的1/16
+			tolerance := int(abs(s))/16 + 16
+			assert.InDelta(t, int(s), int(decoded[i]), float64(tolerance), "%s: %d", name, s)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for name, want := range map[string]string{
+		"":      codec.EncodingL16,
+		"L16":   codec.EncodingL16,
+		"pcm":   codec.EncodingL16,
+		"PCMU":  codec.EncodingPCMU,
+		"ulaw":  codec.EncodingPCMU,
+		"alaw":  codec.EncodingPCMA,
+		"g711a": codec.EncodingPCMA,
+	} {
+		got, err := codec.ParseEncoding(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, got, name)
+	}
+	_, err := codec.ParseEncoding("opus")
+	assert.Error(t, err)
+}
+
+func abs(v int16) int32 {
+	if v < 0 {
+		return -int32(v)
+	}
+	return int32(v)
+}
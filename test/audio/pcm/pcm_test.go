@@ -0,0 +1,75 @@
+package pcm_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/clients/tts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleRate = 16000
+
+// samples 生成指定时长、固定幅度的单声道PCM
+func samples(d time.Duration, amplitude int16) []byte {
+	n := int(d * sampleRate / time.Second)
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := amplitude
+		if i%2 == 1 {
+			v = -amplitude
+		}
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v))
+	}
+	return out
+}
+
+// interleave 将左右声道交错为双声道PCM
+func interleave(left, right []byte) []byte {
+	out := make([]byte, 0, len(left)*2)
+	for i := 0; i+1 < len(left); i += 2 {
+		out = append(out, left[i], left[i+1], right[i], right[i+1])
+	}
+	return out
+}
+
+func TestDecodeWAV(t *testing.T) {
+	left := samples(100*time.Millisecond, 1000)
+	right := samples(100*time.Millisecond, 2000)
+	wav := (&tts.Audio{PCM: interleave(left, right), SampleRate: sampleRate, Channels: 2}).WAV()
+
+	audio, err := pcm.DecodeWAV(wav)
+	require.NoError(t, err)
+	assert.Equal(t, sampleRate, audio.SampleRate)
+	assert.Equal(t, 2, audio.Channels)
+	assert.Equal(t, 100*time.Millisecond, audio.Duration())
+	assert.Equal(t, left, audio.Channel(0))
+	assert.Equal(t, right, audio.Channel(1))
+}
+
+func TestDecodeWAV_Invalid(t *testing.T) {
+	_, err := pcm.DecodeWAV([]byte("not a wav file"))
+	assert.ErrorIs(t, err, pcm.ErrInvalidWAV)
+}
+
+func TestSplit(t *testing.T) {
+	var data []byte
+	data = append(data, samples(500*time.Millisecond, 0)...)
+	data = append(data, samples(time.Second, 3000)...)
+	data = append(data, samples(time.Second, 0)...)
+	data = append(data, samples(100*time.Millisecond, 3000)...) // 短于最短语音，丢弃
+	data = append(data, samples(time.Second, 0)...)
+	data = append(data, samples(500*time.Millisecond, 3000)...)
+
+	segments := pcm.Split(data, sampleRate, pcm.DefaultSegmentConfig)
+	require.Len(t, segments, 2)
+	assert.Equal(t, 500*time.Millisecond, segments[0].Start)
+	assert.Equal(t, 1500*time.Millisecond, segments[0].End)
+	assert.Len(t, segments[0].PCM, sampleRate*2)
+	assert.Equal(t, 3600*time.Millisecond, segments[1].Start)
+	assert.Equal(t, 4100*time.Millisecond, segments[1].End)
+}
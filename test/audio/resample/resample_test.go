@@ -0,0 +1,80 @@
+package resample_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"ai_dialer_mini/internal/audio/resample"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pcm 将采样编码为16位小端PCM
+func pcm(samples ...int16) []byte {
+	out := make([]byte, 0, len(samples)*2)
+	for _, s := range samples {
+		out = binary.LittleEndian.AppendUint16(out, uint16(s))
+	}
+	return out
+}
+
+// samples 将16位小端PCM解码为采样
+func samples(data []byte) []int16 {
+	out := make([]int16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		out = append(out, int16(binary.LittleEndian.Uint16(data[i:])))
+	}
+	return out
+}
+
+func TestConvert_Upsample(t *testing.T) {
+	out, err := resample.Convert(pcm(0, 100, 200, -200), 8000, 16000)
+	require.NoError(t, err)
+	// 相邻采样之间插入中点，末尾补上最后一个采样
+	assert.Equal(t, []int16{0, 50, 100, 150, 200, 0, -200, -200}, samples(out))
+}
+
+func TestConvert_Downsample(t *testing.T) {
+	out, err := resample.Convert(pcm(0, 10, 20, 30, 40, 50, 60, 70), 16000, 8000)
+	require.NoError(t, err)
+	assert.Equal(t, []int16{0, 20, 40, 60}, samples(out))
+}
+
+func TestConvert_SameRate(t *testing.T) {
+	in := pcm(1, 2, 3)
+	out, err := resample.Convert(in, 16000, 16000)
+	require.NoError(t, err)
+	assert.Equal(t, in, out)
+}
+
+func TestResampler_Chunks(t *testing.T) {
+	in := make([]int16, 320)
+	for i := range in {
+		in[i] = int16((i * 97) % 2000)
+	}
+	data := pcm(in...)
+
+	whole, err := resample.New(8000, 16000)
+	require.NoError(t, err)
+	want := whole.Process(data)
+
+	// 分片边界（包括落在采样中间的奇数字节）不影响结果
+	chunked, err := resample.New(8000, 16000)
+	require.NoError(t, err)
+	var got []byte
+	for _, size := range []int{1, 7, 100, 33, 499} {
+		got = append(got, chunked.Process(data[:size])...)
+		data = data[size:]
+	}
+	got = append(got, chunked.Process(data)...)
+	assert.Equal(t, want, got)
+	assert.Len(t, samples(got), 2*len(in)-2)
+}
+
+func TestNew_InvalidRate(t *testing.T) {
+	_, err := resample.New(0, 16000)
+	assert.Error(t, err)
+	_, err = resample.Convert(pcm(1), 8000, -1)
+	assert.Error(t, err)
+}
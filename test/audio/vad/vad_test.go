@@ -0,0 +1,85 @@
+package vad_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/audio/vad"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleRate = 16000
+
+var config = vad.Config{
+	Enabled:    true,
+	Threshold:  800,
+	MinSpeech:  200 * time.Millisecond,
+	MinSilence: 500 * time.Millisecond,
+}
+
+// samples 生成指定时长、固定幅度的单声道PCM
+func samples(d time.Duration, amplitude int16) []byte {
+	n := int(d * sampleRate / time.Second)
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := amplitude
+		if i%2 == 1 {
+			v = -amplitude
+		}
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v))
+	}
+	return out
+}
+
+func TestDetector_SpeechStart(t *testing.T) {
+	d := vad.NewDetector(config, sampleRate)
+
+	assert.False(t, d.Write(samples(time.Second, 50)))
+	assert.False(t, d.Write(samples(100*time.Millisecond, 3000)))
+	assert.False(t, d.Speaking())
+
+	// 有声累计达到200ms时触发，且一次说话只触发一次
+	assert.True(t, d.Write(samples(100*time.Millisecond, 3000)))
+	assert.True(t, d.Speaking())
+	assert.False(t, d.Write(samples(time.Second, 3000)))
+
+	// 短暂停顿不算说完
+	assert.False(t, d.Write(samples(300*time.Millisecond, 0)))
+	assert.False(t, d.Write(samples(300*time.Millisecond, 3000)))
+
+	// 静音超过500ms后再次开口重新触发
+	assert.False(t, d.Write(samples(600*time.Millisecond, 0)))
+	assert.False(t, d.Speaking())
+	assert.True(t, d.Write(samples(300*time.Millisecond, 3000)))
+}
+
+func TestDetector_IgnoresShortNoise(t *testing.T) {
+	d := vad.NewDetector(config, sampleRate)
+
+	// 短促声音中间有静音时不累计
+	for i := 0; i < 10; i++ {
+		assert.False(t, d.Write(samples(100*time.Millisecond, 3000)))
+		assert.False(t, d.Write(samples(40*time.Millisecond, 0)))
+	}
+}
+
+func TestDetector_PartialFrames(t *testing.T) {
+	d := vad.NewDetector(config, sampleRate)
+
+	// 不足一个窗口的数据跨调用累积
+	audio := samples(300*time.Millisecond, 3000)
+	started := false
+	for i := 0; i < len(audio); i += 100 {
+		end := i + 100
+		if end > len(audio) {
+			end = len(audio)
+		}
+		started = d.Write(audio[i:end]) || started
+	}
+	assert.True(t, started)
+
+	d.Reset()
+	assert.False(t, d.Speaking())
+}
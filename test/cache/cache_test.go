@@ -0,0 +1,112 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_LRUEviction(t *testing.T) {
+	c := cache.New[string, int](cache.Options{Capacity: 2})
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, _ = c.Get("a") // a 变为最近使用
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+}
+
+func TestCache_TTL(t *testing.T) {
+	c := cache.New[string, int](cache.Options{TTL: 20 * time.Millisecond})
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, 0)
+
+	time.Sleep(40 * time.Millisecond)
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	_, ok = c.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), c.Stats().Expirations)
+}
+
+func TestCache_GetOrLoadSingleflight(t *testing.T) {
+	c := cache.New[string, string](cache.Options{})
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "key", load)
+			assert.NoError(t, err)
+			results[i] = v
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, v := range results {
+		assert.Equal(t, "value", v)
+	}
+
+	// 已缓存，不再加载
+	v, err := c.GetOrLoad(context.Background(), "key", load)
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, uint64(1), c.Stats().Loads)
+}
+
+func TestCache_GetOrLoadErrorNotCached(t *testing.T) {
+	c := cache.New[string, int](cache.Options{})
+	_, err := c.GetOrLoad(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, errors.New("boom")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, uint64(1), c.Stats().LoadErrors)
+}
+
+func TestAll(t *testing.T) {
+	c := cache.New[string, int](cache.Options{Name: "test.all", Capacity: 10})
+	c.Set("a", 1)
+	_, _ = c.Get("a")
+	_, _ = c.Get("missing")
+
+	var found *cache.Stats
+	for _, s := range cache.All() {
+		if s.Name == "test.all" {
+			s := s
+			found = &s
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, 1, found.Size)
+	assert.Equal(t, uint64(1), found.Hits)
+	assert.Equal(t, uint64(1), found.Misses)
+	assert.InDelta(t, 0.5, found.HitRate, 1e-9)
+}
@@ -0,0 +1,133 @@
+package asr_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStream 立即结束的识别会话，err为会话结束的原因
+type fakeStream struct {
+	provider string
+	err      error
+	results  chan models.ASRResult
+	done     chan struct{}
+}
+
+func newFakeStream(provider string, err error) *fakeStream {
+	s := &fakeStream{provider: provider, err: err, results: make(chan models.ASRResult), done: make(chan struct{})}
+	close(s.results)
+	close(s.done)
+	return s
+}
+
+func (s *fakeStream) Write(p []byte) (int, error)      { return len(p), nil }
+func (s *fakeStream) Close() error                     { return nil }
+func (s *fakeStream) Results() <-chan models.ASRResult { return s.results }
+func (s *fakeStream) Done() <-chan struct{}            { return s.done }
+func (s *fakeStream) Err() error                       { return s.err }
+func (s *fakeStream) Latency() time.Duration           { return 0 }
+
+// fakeProvider 按设置返回成功或失败的识别会话
+type fakeProvider struct {
+	name      string
+	openErr   error
+	streamErr error
+	released  []string
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) OpenStream(sessionID string) (models.ASRStream, error) {
+	if p.openErr != nil {
+		return nil, p.openErr
+	}
+	return newFakeStream(p.name, p.streamErr), nil
+}
+
+func (p *fakeProvider) ReleaseSession(sessionID string) { p.released = append(p.released, sessionID) }
+
+func (p *fakeProvider) Ping(ctx context.Context) error { return p.openErr }
+
+// openWith 开始一次识别，返回实际使用的后端
+func openWith(t *testing.T, provider models.ASRProvider) string {
+	stream, err := provider.OpenStream("session-1")
+	require.NoError(t, err)
+	// 会话结果在后台计入失败统计
+	time.Sleep(10 * time.Millisecond)
+	return stream.(*fakeStream).provider
+}
+
+func TestRouter_Select(t *testing.T) {
+	xf := &fakeProvider{name: "xfyun"}
+	whisper := &fakeProvider{name: "whisper"}
+	router := asr.NewRouter(asr.FailoverConfig{}, xf, whisper)
+
+	provider, err := router.Select("")
+	require.NoError(t, err)
+	assert.Equal(t, "xfyun", provider.Name())
+	assert.Equal(t, "xfyun", openWith(t, provider))
+
+	provider, err = router.Select("whisper")
+	require.NoError(t, err)
+	assert.Equal(t, "whisper", openWith(t, provider))
+
+	_, err = router.Select("google")
+	assert.ErrorIs(t, err, asr.ErrUnknownProvider)
+
+	// 同名后端替换为租户的客户端
+	tenantXF := &fakeProvider{name: "xfyun"}
+	provider, err = router.Select("", tenantXF)
+	require.NoError(t, err)
+	provider.ReleaseSession("session-1")
+	assert.Equal(t, []string{"session-1"}, tenantXF.released)
+	assert.Empty(t, xf.released)
+}
+
+func TestRouter_Failover(t *testing.T) {
+	xf := &fakeProvider{name: "xfyun", streamErr: errors.New("读取识别结果失败")}
+	whisper := &fakeProvider{name: "whisper"}
+	router := asr.NewRouter(asr.FailoverConfig{Enabled: true, Threshold: 2, Cooldown: 50 * time.Millisecond}, xf, whisper)
+	provider, err := router.Select("")
+	require.NoError(t, err)
+
+	// 连续失败达到阈值前仍使用首选后端
+	assert.Equal(t, "xfyun", openWith(t, provider))
+	assert.Equal(t, "xfyun", openWith(t, provider))
+	assert.Equal(t, "whisper", openWith(t, provider))
+
+	// 冷却后再试原后端，恢复后不再切换
+	time.Sleep(60 * time.Millisecond)
+	xf.streamErr = nil
+	assert.Equal(t, "xfyun", openWith(t, provider))
+	assert.Equal(t, "xfyun", openWith(t, provider))
+
+	// 建立连接失败时立即尝试下一个后端
+	xf.openErr = errors.New("连接失败")
+	assert.Equal(t, "whisper", openWith(t, provider))
+}
+
+func TestRouter_FailoverDisabled(t *testing.T) {
+	xf := &fakeProvider{name: "xfyun", openErr: errors.New("连接失败")}
+	router := asr.NewRouter(asr.FailoverConfig{}, xf, &fakeProvider{name: "whisper"})
+	provider, err := router.Select("")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = provider.OpenStream("session-1")
+		assert.Error(t, err)
+	}
+	assert.NoError(t, router.Ping(context.Background()))
+}
+
+func TestFailoverConfig_Validate(t *testing.T) {
+	assert.NoError(t, asr.FailoverConfig{}.Validate())
+	assert.Error(t, asr.FailoverConfig{Enabled: true, Cooldown: time.Second}.Validate())
+	assert.Error(t, asr.FailoverConfig{Enabled: true, Threshold: 3}.Validate())
+}
@@ -0,0 +1,134 @@
+package asr_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/models"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWhisperServer 模拟Whisper识别服务：每收到一帧音频返回到目前为止的文本，收到结束帧时返回最终结果
+func newWhisperServer(t *testing.T, requests chan<- models.WhisperRequest, texts []string) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for i := 0; ; i++ {
+			var req models.WhisperRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			requests <- req
+			if req.Data.Status == asr.StatusLastFrame {
+				conn.WriteJSON(models.WhisperResponse{Type: "result", Text: texts[len(texts)-1]})
+				return
+			}
+			if i < len(texts)-1 {
+				conn.WriteJSON(models.WhisperResponse{Type: "result", Text: texts[i]})
+			}
+		}
+	}))
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/asr"
+}
+
+func TestWhisperClient_OpenStream(t *testing.T) {
+	requests := make(chan models.WhisperRequest, 16)
+	server := newWhisperServer(t, requests, []string{"今天", "今天天气好"})
+	defer server.Close()
+
+	client := asr.NewWhisperClient(asr.WhisperConfig{ServerURL: wsURL(server), Model: "large-v3", Language: "zh"})
+	assert.Equal(t, asr.WhisperProviderName, client.Name())
+	stream, err := client.OpenStream("session-1")
+	require.NoError(t, err)
+
+	_, err = stream.Write(make([]byte, 640))
+	require.NoError(t, err)
+	first := <-requests
+	assert.Equal(t, asr.StatusFirstFrame, first.Data.Status)
+	assert.Equal(t, "large-v3", first.Model)
+	assert.Equal(t, "zh", first.Language)
+	assert.Equal(t, models.ASRResult{Text: "今天"}, <-stream.Results())
+
+	_, err = stream.Write(make([]byte, 640))
+	require.NoError(t, err)
+	second := <-requests
+	assert.Equal(t, asr.StatusContinueFrame, second.Data.Status)
+	assert.Empty(t, second.Model)
+
+	done := make(chan []models.ASRResult, 1)
+	go func() {
+		var results []models.ASRResult
+		for result := range stream.Results() {
+			results = append(results, result)
+		}
+		done <- results
+	}()
+	require.NoError(t, stream.Close())
+	assert.Equal(t, asr.StatusLastFrame, (<-requests).Data.Status)
+	assert.Equal(t, []models.ASRResult{{Text: "今天天气好", IsFinal: true}}, <-done)
+	require.NoError(t, stream.Err())
+
+	_, err = stream.Write([]byte{0, 0})
+	assert.ErrorIs(t, err, asr.ErrWhisperStreamClosed)
+}
+
+func TestWhisperClient_ServerFinalAndError(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var req models.WhisperRequest
+		conn.ReadJSON(&req)
+		if r.URL.Query().Get("fail") != "" {
+			conn.WriteJSON(models.WhisperResponse{Type: "result", Error: "model not loaded"})
+			return
+		}
+		// 服务端检测到句尾，直接返回最终结果
+		conn.WriteJSON(models.WhisperResponse{Type: "pong"})
+		conn.WriteJSON(models.WhisperResponse{Type: "result", Text: "你好", Final: true})
+	}))
+	defer server.Close()
+
+	stream, err := asr.NewWhisperClient(asr.WhisperConfig{ServerURL: wsURL(server)}).OpenStream("session-1")
+	require.NoError(t, err)
+	_, err = stream.Write(make([]byte, 640))
+	require.NoError(t, err)
+	assert.Equal(t, models.ASRResult{Text: "你好", IsFinal: true}, <-stream.Results())
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("识别会话未结束")
+	}
+	assert.NoError(t, stream.Err())
+	assert.Greater(t, stream.Latency(), time.Duration(0))
+
+	stream, err = asr.NewWhisperClient(asr.WhisperConfig{ServerURL: wsURL(server) + "?fail=1"}).OpenStream("session-1")
+	require.NoError(t, err)
+	_, err = stream.Write(make([]byte, 640))
+	require.NoError(t, err)
+	assert.ErrorContains(t, stream.Err(), "model not loaded")
+	require.NoError(t, stream.Close())
+}
+
+func TestWhisperClient_Ping(t *testing.T) {
+	server := newWhisperServer(t, make(chan models.WhisperRequest, 1), []string{""})
+	client := asr.NewWhisperClient(asr.WhisperConfig{ServerURL: wsURL(server)})
+	assert.NoError(t, client.Ping(context.Background()))
+	server.Close()
+	assert.Error(t, client.Ping(context.Background()))
+}
@@ -0,0 +1,151 @@
+package freeswitch_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender 记录下发的命令
+type recordingSender struct {
+	mu       sync.Mutex
+	commands []string
+	reply    string
+}
+
+func (r *recordingSender) SendCommand(command string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, command)
+	if r.reply != "" {
+		return r.reply, nil
+	}
+	return "+OK Success", nil
+}
+
+func (r *recordingSender) Commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.commands...)
+}
+
+func newTestManager(sender *recordingSender, maxRestarts int) *freeswitch.AudioStreamManager {
+	signed := 0
+	return freeswitch.NewAudioStreamManager(sender, func(uuid string) (string, error) {
+		signed++
+		return fmt.Sprintf("ws://dialer:8080/ws/calls/%s/stream?n=%d", uuid, signed), nil
+	}, freeswitch.AudioStreamConfig{MaxRestarts: maxRestarts, RestartDelay: time.Millisecond})
+}
+
+func audioStreamEvent(subclass, uuid string) map[string]string {
+	return map[string]string{"Event-Name": "CUSTOM", "Event-Subclass": subclass, "Unique-ID": uuid}
+}
+
+func TestAudioStreamManager_StartAndConnect(t *testing.T) {
+	sender := &recordingSender{}
+	manager := newTestManager(sender, 1)
+
+	require.NoError(t, manager.Start("call-1"))
+	assert.Equal(t, []string{"uuid_audio_stream call-1 start ws://dialer:8080/ws/calls/call-1/stream?n=1 mono 16k"}, sender.Commands())
+
+	stream, ok := manager.Get("call-1")
+	require.True(t, ok)
+	assert.Equal(t, freeswitch.AudioStreamStarting, stream.State)
+
+	require.NoError(t, manager.HandleEvent(audioStreamEvent(freeswitch.AudioStreamEventConnect, "call-1")))
+	stream, _ = manager.Get("call-1")
+	assert.Equal(t, freeswitch.AudioStreamStreaming, stream.State)
+	assert.Len(t, manager.List(), 1)
+}
+
+func TestAudioStreamManager_Start8k(t *testing.T) {
+	sender := &recordingSender{}
+	manager := freeswitch.NewAudioStreamManager(sender, func(uuid string) (string, error) {
+		return "ws://dialer:8080/ws/calls/" + uuid + "/stream?sig=abc", nil
+	}, freeswitch.AudioStreamConfig{SampleRate: 8000})
+
+	// 非默认采样率通过rate参数告知音频流服务
+	require.NoError(t, manager.Start("call-1"))
+	assert.Equal(t, []string{"uuid_audio_stream call-1 start ws://dialer:8080/ws/calls/call-1/stream?rate=8000&sig=abc mono 8k"}, sender.Commands())
+}
+
+func TestAudioStreamManager_StartG711(t *testing.T) {
+	sender := &recordingSender{}
+	manager := freeswitch.NewAudioStreamManager(sender, func(uuid string) (string, error) {
+		return "ws://dialer:8080/ws/calls/" + uuid + "/stream?sig=abc", nil
+	}, freeswitch.AudioStreamConfig{SampleRate: 8000, Codec: "pcmu"})
+
+	require.NoError(t, manager.Start("call-1"))
+	assert.Equal(t, []string{"uuid_audio_stream call-1 start ws://dialer:8080/ws/calls/call-1/stream?codec=pcmu&rate=8000&sig=abc mono 8k"}, sender.Commands())
+}
+
+func TestAudioStreamManager_StartRejected(t *testing.T) {
+	sender := &recordingSender{reply: "-ERR no such channel"}
+	manager := newTestManager(sender, 1)
+
+	err := manager.Start("call-1")
+	assert.ErrorContains(t, err, "no such channel")
+	_, ok := manager.Get("call-1")
+	assert.False(t, ok)
+}
+
+func TestAudioStreamManager_RestartOnError(t *testing.T) {
+	sender := &recordingSender{}
+	manager := newTestManager(sender, 1)
+	require.NoError(t, manager.Start("call-1"))
+
+	event := audioStreamEvent(freeswitch.AudioStreamEventError, "call-1")
+	event["_body"] = `{"status":"error","message":"connection refused"}`
+	require.NoError(t, manager.HandleEvent(event))
+
+	// 重启使用新签名的地址
+	require.Eventually(t, func() bool { return len(sender.Commands()) == 2 }, time.Second, time.Millisecond)
+	assert.True(t, strings.Contains(sender.Commands()[1], "?n=2 mono 16k"))
+	stream, _ := manager.Get("call-1")
+	assert.Equal(t, 1, stream.Restarts)
+	assert.Contains(t, stream.LastError, "connection refused")
+
+	// 重启次数用尽后不再重启
+	require.NoError(t, manager.HandleEvent(audioStreamEvent(freeswitch.AudioStreamEventDisconnect, "call-1")))
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, sender.Commands(), 2)
+	stream, _ = manager.Get("call-1")
+	assert.Equal(t, freeswitch.AudioStreamFailed, stream.State)
+}
+
+func TestAudioStreamManager_HangupStopsTracking(t *testing.T) {
+	sender := &recordingSender{}
+	manager := newTestManager(sender, 3)
+	require.NoError(t, manager.Start("call-1"))
+
+	require.NoError(t, manager.HandleEvent(map[string]string{"Event-Name": "CHANNEL_HANGUP", "Unique-ID": "call-1"}))
+	_, ok := manager.Get("call-1")
+	assert.False(t, ok)
+
+	// 挂断后mod_audio_stream的断开事件不触发重启
+	require.NoError(t, manager.HandleEvent(audioStreamEvent(freeswitch.AudioStreamEventDisconnect, "call-1")))
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, sender.Commands(), 1)
+}
+
+func TestAudioStreamManager_Stop(t *testing.T) {
+	sender := &recordingSender{}
+	manager := newTestManager(sender, 3)
+	require.NoError(t, manager.Start("call-1"))
+
+	require.NoError(t, manager.Stop("call-1"))
+	assert.Equal(t, "uuid_audio_stream call-1 stop", sender.Commands()[1])
+	_, ok := manager.Get("call-1")
+	assert.False(t, ok)
+
+	// 未跟踪的通话无需下发命令
+	require.NoError(t, manager.Stop("call-2"))
+	assert.Len(t, sender.Commands(), 2)
+}
@@ -1,4 +1,4 @@
-package freeswitch
+package freeswitch_test
 
 import (
 	"fmt"
@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"ai_dialer_mini/internal/clients/freeswitch"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,7 +95,7 @@ func TestClientConnect(t *testing.T) {
 	host, portStr, _ := net.SplitHostPort(addr)
 	port, _ := strconv.Atoi(portStr)
 
-	client := NewESLClient(ESLConfig{
+	client := freeswitch.NewESLClient(freeswitch.ESLConfig{
 		Host:     host,
 		Port:     port,
 		Password: "ClueCon",
@@ -112,7 +114,7 @@ func TestClientSendCommand(t *testing.T) {
 	host, portStr, _ := net.SplitHostPort(addr)
 	port, _ := strconv.Atoi(portStr)
 
-	client := NewESLClient(ESLConfig{
+	client := freeswitch.NewESLClient(freeswitch.ESLConfig{
 		Host:     host,
 		Port:     port,
 		Password: "ClueCon",
@@ -135,7 +137,7 @@ func TestClientEventHandler(t *testing.T) {
 	host, portStr, _ := net.SplitHostPort(addr)
 	port, _ := strconv.Atoi(portStr)
 
-	client := NewESLClient(ESLConfig{
+	client := freeswitch.NewESLClient(freeswitch.ESLConfig{
 		Host:     host,
 		Port:     port,
 		Password: "ClueCon",
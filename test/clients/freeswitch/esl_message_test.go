@@ -0,0 +1,89 @@
+package freeswitch_test
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventPlain 构造text/event-plain事件消息
+func eventPlain(body string) string {
+	return "Content-Length: " + strconv.Itoa(len(body)) + "\nContent-Type: text/event-plain\n\n" + body
+}
+
+func TestReadMessage_EventPlain(t *testing.T) {
+	body := "Event-Name: CHANNEL_ANSWER\nUnique-ID: uuid-1\nCaller-Caller-ID-Number: %2B8613800000000\n" +
+		"Event-Date-Local: 2024-01-01%2012%3A00%3A00\n\n"
+	r := bufio.NewReader(strings.NewReader(eventPlain(body) + eventPlain("Event-Name: HEARTBEAT\n\n")))
+
+	headers, err := freeswitch.ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "CHANNEL_ANSWER", headers["Event-Name"])
+	assert.Equal(t, "+8613800000000", headers["Caller-Caller-ID-Number"])
+	assert.Equal(t, "2024-01-01 12:00:00", headers["Event-Date-Local"])
+
+	// 完整读取消息体，下一条消息不受影响
+	headers, err = freeswitch.ReadMessage(r)
+	require.NoError(t, err)
+	assert.Equal(t, "HEARTBEAT", headers["Event-Name"])
+}
+
+func TestReadMessage_EventWithBody(t *testing.T) {
+	body := "Event-Name: BACKGROUND_JOB\nJob-UUID: job-1\nContent-Length: 3\n\n+OK"
+	headers, err := freeswitch.ReadMessage(bufio.NewReader(strings.NewReader(eventPlain(body))))
+	require.NoError(t, err)
+	assert.Equal(t, "BACKGROUND_JOB", headers["Event-Name"])
+	assert.Equal(t, "+OK", headers[freeswitch.BodyKey])
+}
+
+func TestReadMessage_APIResponse(t *testing.T) {
+	msg := "Content-Type: api/response\nContent-Length: 18\n\n-ERR Invalid UUID\n"
+	headers, err := freeswitch.ReadMessage(bufio.NewReader(strings.NewReader(msg)))
+	require.NoError(t, err)
+	assert.Equal(t, "-ERR Invalid UUID\n", headers[freeswitch.BodyKey])
+	assert.Empty(t, headers["Event-Name"])
+}
+
+func TestReadMessage_Malformed(t *testing.T) {
+	for _, msg := range []string{
+		"Content-Length: abc\n\n",
+		"Content-Length: -1\n\n",
+		"Content-Length: 999999999\n\n",
+		"Content-Length: 10\n\nshort",
+		"Content-Type: command/reply\n",
+	} {
+		_, err := freeswitch.ReadMessage(bufio.NewReader(strings.NewReader(msg)))
+		assert.Error(t, err, msg)
+	}
+
+	// 缺少分隔符或键为空的行被忽略
+	headers, err := freeswitch.ReadMessage(bufio.NewReader(strings.NewReader("garbage\n: value\nReply-Text: +OK\r\n\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Reply-Text": "+OK"}, headers)
+}
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add("Content-Type: command/reply\nReply-Text: +OK\n\n")
+	f.Add(eventPlain("Event-Name: CHANNEL_CREATE\nUnique-ID: uuid-1\n\n"))
+	f.Add(eventPlain("Event-Name: BACKGROUND_JOB\nContent-Length: 3\n\n+OK"))
+	f.Add("Content-Type: api/response\nContent-Length: 4\n\n+OK\n")
+	f.Add("Content-Length: 99999999999999999999\n\n")
+
+	f.Fuzz(func(t *testing.T, msg string) {
+		headers, err := freeswitch.ReadMessage(bufio.NewReader(strings.NewReader(msg)))
+		if err != nil {
+			return
+		}
+		for key := range headers {
+			if key == "" || strings.Contains(key, "\n") {
+				t.Fatalf("无效的头部名: %q", key)
+			}
+		}
+	})
+}
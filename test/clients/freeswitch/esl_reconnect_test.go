@@ -0,0 +1,177 @@
+package freeswitch_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedFreeSWITCH 按连接序号记录收到的命令，第一条连接订阅事件后被断开
+type scriptedFreeSWITCH struct {
+	listener net.Listener
+	commands chan string // 格式为 “连接序号:命令”
+}
+
+func newScriptedFreeSWITCH(t *testing.T) *scriptedFreeSWITCH {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	m := &scriptedFreeSWITCH{listener: listener, commands: make(chan string, 32)}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(n, conn)
+		}
+	}()
+	return m
+}
+
+func (m *scriptedFreeSWITCH) serve(n int, conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprintf(conn, "Content-Type: auth/request\n\n")
+	for {
+		cmd, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if n > 1 || !strings.HasPrefix(cmd, "auth ") {
+			m.commands <- fmt.Sprintf("%d:%s", n, cmd)
+		}
+		switch {
+		case strings.HasPrefix(cmd, "auth "):
+			fmt.Fprintf(conn, "Content-Type: command/reply\nReply-Text: +OK accepted\n\n")
+		case strings.HasPrefix(cmd, "api "):
+			body := "+OK " + strings.TrimPrefix(cmd, "api ") + "\n"
+			fmt.Fprintf(conn, "Content-Type: api/response\nContent-Length: %d\n\n%s", len(body), body)
+		default:
+			fmt.Fprintf(conn, "Content-Type: command/reply\nReply-Text: +OK\n\n")
+			if n == 1 && strings.HasPrefix(cmd, "event ") {
+				// 模拟FreeSWITCH重启
+				return
+			}
+			if strings.HasPrefix(cmd, "event ") {
+				fmt.Fprint(conn, eventPlain("Event-Name: CHANNEL_HANGUP\nUnique-ID: call-1\nHangup-Cause: NORMAL_CLEARING\n\n"))
+			}
+		}
+	}
+}
+
+// readCommand 读取一条以空行结束的ESL命令
+func readCommand(r *bufio.Reader) (string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(lines) == 0 {
+				continue
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func (m *scriptedFreeSWITCH) client() *freeswitch.ESLClient {
+	host, portStr, _ := net.SplitHostPort(m.listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return freeswitch.NewESLClient(freeswitch.ESLConfig{
+		Host:              host,
+		Port:              port,
+		Password:          "ClueCon",
+		Filters:           []freeswitch.EventFilter{{Header: "Caller-Context", Value: "ai_dialer"}},
+		ReconnectDelay:    10 * time.Millisecond,
+		MaxReconnectDelay: 50 * time.Millisecond,
+	})
+}
+
+func (m *scriptedFreeSWITCH) next(t *testing.T) string {
+	select {
+	case cmd := <-m.commands:
+		return cmd
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待命令超时")
+		return ""
+	}
+}
+
+func TestESLClient_ReconnectAndResubscribe(t *testing.T) {
+	mock := newScriptedFreeSWITCH(t)
+	client := mock.client()
+	defer client.Close()
+
+	disconnected := make(chan error, 1)
+	reconnected := make(chan struct{}, 1)
+	hangups := make(chan freeswitch.ChannelHangup, 1)
+	client.OnDisconnect(func(err error) { disconnected <- err })
+	client.OnReconnect(func() { reconnected <- struct{}{} })
+	client.OnChannelHangup(func(event freeswitch.ChannelHangup) error {
+		hangups <- event
+		return nil
+	})
+
+	require.NoError(t, client.Connect())
+	// 第一条连接在订阅后被断开，订阅可能因此失败
+	client.SubscribeEvents()
+	assert.Equal(t, "1:event plain CHANNEL_HANGUP", mock.next(t))
+
+	select {
+	case err := <-disconnected:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("未通知连接断开")
+	}
+
+	// 重新认证并恢复事件订阅和过滤
+	assert.Equal(t, "2:auth ClueCon", mock.next(t))
+	assert.Equal(t, "2:event plain CHANNEL_HANGUP", mock.next(t))
+	assert.Equal(t, "2:filter Caller-Context ai_dialer", mock.next(t))
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("未通知重连成功")
+	}
+	assert.True(t, client.Connected())
+
+	select {
+	case event := <-hangups:
+		assert.Equal(t, "call-1", event.UUID)
+		assert.Equal(t, "NORMAL_CLEARING", event.Cause)
+	case <-time.After(2 * time.Second):
+		t.Fatal("重连后未收到事件")
+	}
+
+	// 命令响应由读取循环转交，与事件互不干扰
+	reply, err := client.SendCommand("status")
+	require.NoError(t, err)
+	assert.Equal(t, "+OK status\n", reply)
+}
+
+func TestESLClient_CloseStopsReconnect(t *testing.T) {
+	mock := newScriptedFreeSWITCH(t)
+	client := mock.client()
+	require.NoError(t, client.Connect())
+	require.NoError(t, client.Close())
+	assert.False(t, client.Connected())
+
+	_, err := client.SendCommand("status")
+	assert.True(t, errors.Is(err, freeswitch.ErrNotConnected))
+	assert.True(t, errors.Is(client.SubscribeEvents(), freeswitch.ErrNotConnected))
+}
@@ -0,0 +1,97 @@
+package freeswitch_test
+
+import (
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func channelHeaders(name string) map[string]string {
+	return map[string]string{
+		"Event-Name":                name,
+		"Unique-ID":                 "call-1",
+		"Call-Direction":            "outbound",
+		"Caller-Caller-ID-Number":   "1000",
+		"Caller-Destination-Number": "13800138000",
+		"Event-Date-Timestamp":      "1700000000123456",
+	}
+}
+
+func TestParseChannelHangup(t *testing.T) {
+	headers := channelHeaders(freeswitch.EventChannelHangup)
+	headers["Hangup-Cause"] = "USER_BUSY"
+
+	event, err := freeswitch.ParseChannelHangup(headers)
+	require.NoError(t, err)
+	assert.Equal(t, "call-1", event.UUID)
+	assert.Equal(t, "outbound", event.Direction)
+	assert.Equal(t, "1000", event.Caller)
+	assert.Equal(t, "13800138000", event.Callee)
+	assert.Equal(t, "USER_BUSY", event.Cause)
+	assert.Equal(t, time.UnixMicro(1700000000123456), event.Timestamp)
+}
+
+func TestParseChannelEvents_Errors(t *testing.T) {
+	// 事件类型不匹配
+	_, err := freeswitch.ParseChannelAnswer(channelHeaders(freeswitch.EventChannelCreate))
+	assert.Error(t, err)
+
+	// 缺少通道UUID
+	headers := channelHeaders(freeswitch.EventChannelCreate)
+	delete(headers, "Unique-ID")
+	_, err = freeswitch.ParseChannelCreate(headers)
+	assert.Error(t, err)
+
+	// 缺少时间戳时为零值
+	headers = channelHeaders(freeswitch.EventChannelAnswer)
+	delete(headers, "Event-Date-Timestamp")
+	event, err := freeswitch.ParseChannelAnswer(headers)
+	require.NoError(t, err)
+	assert.True(t, event.Timestamp.IsZero())
+}
+
+func TestParseDTMF(t *testing.T) {
+	headers := channelHeaders(freeswitch.EventDTMF)
+	headers["DTMF-Digit"] = "#"
+	headers["DTMF-Duration"] = "2000"
+	headers["DTMF-Source"] = "RTP"
+
+	event, err := freeswitch.ParseDTMF(headers)
+	require.NoError(t, err)
+	assert.Equal(t, "#", event.Digit)
+	assert.Equal(t, 2000, event.Duration)
+	assert.Equal(t, "RTP", event.Source)
+
+	delete(headers, "DTMF-Digit")
+	_, err = freeswitch.ParseDTMF(headers)
+	assert.Error(t, err)
+}
+
+func TestEventSubscription_FromHandlers(t *testing.T) {
+	client := freeswitch.NewESLClient(freeswitch.ESLConfig{})
+	assert.Equal(t, "", client.EventSubscription())
+
+	client.OnChannelHangup(func(freeswitch.ChannelHangup) error { return nil })
+	client.OnChannelAnswer(func(freeswitch.ChannelAnswer) error { return nil })
+	client.OnDTMF(func(freeswitch.DTMF) error { return nil })
+	assert.Equal(t, "CHANNEL_ANSWER CHANNEL_HANGUP DTMF", client.EventSubscription())
+
+	// 音频流事件只订阅mod_audio_stream子类
+	client.EnableAudioStreams(func(uuid string) (string, error) { return "ws://dialer/" + uuid, nil }, freeswitch.AudioStreamConfig{})
+	assert.Equal(t, "CHANNEL_ANSWER CHANNEL_HANGUP DTMF CUSTOM mod_audio_stream::connect mod_audio_stream::disconnect mod_audio_stream::error",
+		client.EventSubscription())
+}
+
+func TestEventSubscription_FromConfig(t *testing.T) {
+	client := freeswitch.NewESLClient(freeswitch.ESLConfig{Events: []string{"channel_hangup", "CUSTOM"}})
+	client.RegisterCustomHandler(func(map[string]string) error { return nil }, "sofia::register")
+	client.OnChannelAnswer(func(freeswitch.ChannelAnswer) error { return nil })
+	assert.Equal(t, "CHANNEL_HANGUP CUSTOM sofia::register", client.EventSubscription())
+
+	client = freeswitch.NewESLClient(freeswitch.ESLConfig{Events: []string{"CHANNEL_ANSWER", "all"}})
+	assert.Equal(t, "all", client.EventSubscription())
+}
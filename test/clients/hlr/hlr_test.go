@@ -0,0 +1,74 @@
+package hlr_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/hlr"
+	"ai_dialer_mini/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPClient_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("number") {
+		case "13800000000":
+			w.Write([]byte(`{"state": 0, "operator": "中国移动"}`))
+		case "13900000000":
+			w.Write([]byte(`{"state": 1, "operator": "中国联通"}`))
+		default:
+			w.Write([]byte(`{"state": 9}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := hlr.NewHTTPClient(hlr.Config{
+		URL:          server.URL + "/lookup?region=cn",
+		APIKey:       "secret",
+		Timeout:      time.Second,
+		StatusField:  "state",
+		CarrierField: "operator",
+		StatusMap:    map[string]string{"0": models.NumberStatusActive, "1": models.NumberStatusDisconnected},
+	})
+	require.NoError(t, err)
+
+	lookup, err := client.Lookup(context.Background(), "13800000000")
+	require.NoError(t, err)
+	assert.Equal(t, models.NumberStatusActive, lookup.Status)
+	assert.Equal(t, "中国移动", lookup.Carrier)
+	assert.False(t, lookup.CheckedAt.IsZero())
+
+	lookup, err = client.Lookup(context.Background(), "13900000000")
+	require.NoError(t, err)
+	assert.Equal(t, models.NumberStatusDisconnected, lookup.Status)
+	assert.False(t, lookup.Reachable())
+
+	// 未映射的状态值视为无法确定，按可接通处理
+	lookup, err = client.Lookup(context.Background(), "13700000000")
+	require.NoError(t, err)
+	assert.Equal(t, models.NumberStatusUnknown, lookup.Status)
+	assert.True(t, lookup.Reachable())
+}
+
+func TestHTTPClient_LookupError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := hlr.NewHTTPClient(hlr.Config{URL: server.URL})
+	require.NoError(t, err)
+
+	_, err = client.Lookup(context.Background(), "13800000000")
+	assert.ErrorContains(t, err, "HTTP 429")
+
+	_, err = hlr.NewHTTPClient(hlr.Config{})
+	assert.Error(t, err)
+}
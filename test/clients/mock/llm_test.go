@@ -0,0 +1,68 @@
+package mock_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/mock"
+	"ai_dialer_mini/internal/clients/ollama"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLLM(t *testing.T) *mock.LLMClient {
+	client, err := mock.NewLLMClient(mock.LLMConfig{
+		Default: "收到: {{.Input}}",
+		Rules: []mock.LLMRule{
+			{Pattern: "你好", Response: "您好！"},
+			{Pattern: `(\d+)号订单`, Response: "正在查询{{index .Groups 1}}号订单"},
+		},
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestLLMClient_Rules(t *testing.T) {
+	client := newLLM(t)
+	ctx := context.Background()
+
+	resp, err := client.GenerateContext(ctx, "用户: 你好\n", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "您好！", resp.Response)
+	assert.True(t, resp.Done)
+
+	// 只匹配最后一行，历史中的“你好”不影响本轮回复
+	resp, err = client.GenerateContext(ctx, "用户: 你好\n助手: 您好！\n用户: 查一下12号订单\n", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "正在查询12号订单", resp.Response)
+
+	resp, err = client.GenerateContext(ctx, "用户: 再见\n", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "收到: 用户: 再见", resp.Response)
+}
+
+func TestLLMClient_Stream(t *testing.T) {
+	client := newLLM(t)
+
+	var parts []string
+	var done bool
+	err := client.GenerateStream("你好", ollama.Options{}, func(resp *ollama.GenerateResponse) error {
+		parts = append(parts, resp.Response)
+		done = resp.Done
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "您好！", strings.Join(parts, ""))
+	assert.Len(t, parts, 3)
+	assert.True(t, done)
+}
+
+func TestLLMClient_InvalidRule(t *testing.T) {
+	_, err := mock.NewLLMClient(mock.LLMConfig{Rules: []mock.LLMRule{{Pattern: "(", Response: "x"}}})
+	assert.Error(t, err)
+
+	_, err = mock.NewLLMClient(mock.LLMConfig{Rules: []mock.LLMRule{{Pattern: "x", Response: "{{.Missing"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package mock_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/mock"
+	"ai_dialer_mini/internal/clients/tts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTSClient_Tone(t *testing.T) {
+	client, err := mock.NewTTSClient(mock.TTSConfig{CharDuration: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	audio, err := client.Synthesize(context.Background(), "您好 世界", tts.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, 16000, audio.SampleRate)
+	assert.Equal(t, 400*time.Millisecond, audio.Duration())
+
+	// 提示音部分有声音，字间间隔为静音
+	assert.NotZero(t, binary.LittleEndian.Uint16(audio.PCM[2:]))
+	gap := audio.PCM[1500*2 : 1600*2]
+	assert.Equal(t, make([]byte, len(gap)), gap)
+
+	wav := audio.WAV()
+	assert.Equal(t, "RIFF", string(wav[:4]))
+	assert.Equal(t, "WAVE", string(wav[8:12]))
+	assert.Len(t, wav, 44+len(audio.PCM))
+
+	// 相同文本输出相同音频
+	again, err := client.Synthesize(context.Background(), "您好 世界", tts.Options{})
+	require.NoError(t, err)
+	assert.True(t, bytes.Equal(audio.PCM, again.PCM))
+}
+
+func TestTTSClient_SilenceAndSpeed(t *testing.T) {
+	client, err := mock.NewTTSClient(mock.TTSConfig{Mode: mock.TTSModeSilence})
+	require.NoError(t, err)
+
+	audio, err := client.Synthesize(context.Background(), "你好", tts.Options{SampleRate: 8000, Speed: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 8000, audio.SampleRate)
+	assert.Equal(t, 200*time.Millisecond, audio.Duration())
+	assert.Equal(t, make([]byte, len(audio.PCM)), audio.PCM)
+
+	_, err = mock.NewTTSClient(mock.TTSConfig{Mode: "noise"})
+	assert.Error(t, err)
+}
+
+func TestTTSClient_Pitch(t *testing.T) {
+	client, err := mock.NewTTSClient(mock.TTSConfig{CharDuration: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	normal, err := client.Synthesize(context.Background(), "你好", tts.Options{})
+	require.NoError(t, err)
+	higher, err := client.Synthesize(context.Background(), "你好", tts.Options{Pitch: 1.2})
+	require.NoError(t, err)
+
+	assert.Equal(t, normal.Duration(), higher.Duration())
+	assert.NotEqual(t, normal.PCM, higher.PCM)
+}
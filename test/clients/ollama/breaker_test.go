@@ -0,0 +1,144 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer 模拟Ollama服务，healthy为false时生成和探测都返回503
+func flakyServer(t *testing.T, healthy *atomic.Bool, generates *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/generate" {
+			atomic.AddInt32(generates, 1)
+		}
+		if !healthy.Load() {
+			http.Error(w, "ollama down", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/api/tags" {
+			w.Write([]byte(`{"models":[]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ollama.GenerateResponse{Response: "好的", Done: true})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBreaker_TripsAndServesFallback(t *testing.T) {
+	var healthy atomic.Bool
+	var generates int32
+	server := flakyServer(t, &healthy, &generates)
+	breaker := ollama.NewBreaker(ollama.NewClient(ollama.Config{Host: server.URL}),
+		ollama.BreakerConfig{Enabled: true, Threshold: 2, Cooldown: time.Hour, Fallback: "稍后会有专人联系您"})
+
+	for i := 0; i < 2; i++ {
+		_, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+		require.Error(t, err)
+	}
+	assert.True(t, breaker.Open())
+
+	// 熔断期间不再请求Ollama，直接返回兜底回复
+	resp, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "稍后会有专人联系您", resp.Response)
+
+	var chunks []string
+	err = breaker.GenerateStream("你好", ollama.Options{}, func(r *ollama.GenerateResponse) error {
+		chunks = append(chunks, r.Response)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"稍后会有专人联系您"}, chunks)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&generates))
+}
+
+func TestBreaker_OpenWithoutFallback(t *testing.T) {
+	var healthy atomic.Bool
+	var generates int32
+	server := flakyServer(t, &healthy, &generates)
+	breaker := ollama.NewBreaker(ollama.NewClient(ollama.Config{Host: server.URL}),
+		ollama.BreakerConfig{Enabled: true, Threshold: 1, Cooldown: time.Hour})
+
+	_, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.Error(t, err)
+	_, err = breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	assert.True(t, errors.Is(err, ollama.ErrCircuitOpen))
+}
+
+func TestBreaker_TrialAfterCooldown(t *testing.T) {
+	var healthy atomic.Bool
+	var generates int32
+	server := flakyServer(t, &healthy, &generates)
+	breaker := ollama.NewBreaker(ollama.NewClient(ollama.Config{Host: server.URL}),
+		ollama.BreakerConfig{Enabled: true, Threshold: 1, Cooldown: 20 * time.Millisecond, Fallback: "兜底"})
+
+	_, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.Error(t, err)
+	require.True(t, breaker.Open())
+
+	// 冷却后的试探请求失败，继续熔断
+	time.Sleep(30 * time.Millisecond)
+	_, err = breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.Error(t, err)
+	resp, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "兜底", resp.Response)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&generates))
+
+	// 服务恢复后试探请求成功，恢复请求
+	healthy.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	resp, err = breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "好的", resp.Response)
+	assert.False(t, breaker.Open())
+}
+
+func TestBreaker_CallerAbortNotCounted(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	var generates int32
+	server := flakyServer(t, &healthy, &generates)
+	breaker := ollama.NewBreaker(ollama.NewClient(ollama.Config{Host: server.URL}),
+		ollama.BreakerConfig{Enabled: true, Threshold: 1})
+
+	// 客户打断时回调返回错误，不计为Ollama失败
+	aborted := errors.New("客户打断")
+	err := breaker.GenerateStream("你好", ollama.Options{}, func(*ollama.GenerateResponse) error { return aborted })
+	require.Error(t, err)
+	assert.False(t, breaker.Open())
+}
+
+func TestBreaker_RunProbesTags(t *testing.T) {
+	var healthy atomic.Bool
+	var generates int32
+	server := flakyServer(t, &healthy, &generates)
+	breaker := ollama.NewBreaker(ollama.NewClient(ollama.Config{Host: server.URL}),
+		ollama.BreakerConfig{Enabled: true, Threshold: 1, Cooldown: time.Hour, ProbeInterval: 10 * time.Millisecond})
+
+	require.Error(t, breaker.Ping(context.Background()))
+	_, err := breaker.GenerateContext(context.Background(), "你好", ollama.Options{})
+	require.Error(t, err)
+	require.True(t, breaker.Open())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go breaker.Run(ctx)
+
+	// 探测 /api/tags 成功后恢复，无需等待冷却时间
+	healthy.Store(true)
+	assert.Eventually(t, func() bool { return !breaker.Open() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&generates))
+}
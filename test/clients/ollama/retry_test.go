@@ -0,0 +1,127 @@
+package ollama_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			http.Error(w, "model is loading", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(ollama.GenerateResponse{Response: "好的", Done: true})
+	}))
+	defer server.Close()
+
+	client := ollama.NewClient(ollama.Config{Host: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	resp, err := client.Generate("你好", ollama.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "好的", resp.Response)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := ollama.NewClient(ollama.Config{Host: server.URL, MaxRetries: 1, RetryBackoff: time.Millisecond})
+	_, err := client.Generate("你好", ollama.Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad gateway")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := ollama.NewClient(ollama.Config{Host: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	err := client.GenerateStream("你好", ollama.Options{}, func(*ollama.GenerateResponse) error { return nil })
+	require.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := ollama.NewClient(ollama.Config{Host: server.URL, Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := client.Generate("你好", ollama.Options{})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClient_ContextCancelStopsRetries(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := ollama.NewClient(ollama.Config{Host: server.URL, MaxRetries: 5, RetryBackoff: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := client.GenerateStreamContext(ctx, "你好", ollama.Options{}, func(*ollama.GenerateResponse) error { return nil })
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, ollama.Config{}.Validate())
+	assert.NoError(t, ollama.Config{Timeout: time.Minute, MaxRetries: 2, RetryBackoff: time.Millisecond}.Validate())
+	assert.Error(t, ollama.Config{Timeout: -time.Second}.Validate())
+	assert.Error(t, ollama.Config{MaxIdleConnsPerHost: -1}.Validate())
+	assert.Error(t, ollama.Config{MaxRetries: -1}.Validate())
+	assert.Error(t, ollama.Config{RetryJitter: 1.5}.Validate())
+	assert.Error(t, ollama.Config{Breaker: ollama.BreakerConfig{Threshold: -1}}.Validate())
+}
+
+func TestClient_RetryJitter(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(ollama.GenerateResponse{Response: "好的", Done: true})
+	}))
+	defer server.Close()
+
+	// 浮动后的等待时间不超过退避时间的(1+jitter)倍
+	client := ollama.NewClient(ollama.Config{Host: server.URL, MaxRetries: 1, RetryBackoff: 50 * time.Millisecond, RetryJitter: 0.5})
+	start := time.Now()
+	_, err := client.Generate("你好", ollama.Options{})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,94 @@
+package ollama_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamServer 按指定分片发送流式响应，每个分片单独刷新，模拟网络分片到达
+func streamServer(chunks ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher := w.(http.Flusher)
+		for _, chunk := range chunks {
+			w.Write([]byte(chunk))
+			flusher.Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+}
+
+// collect 流式生成并收集每个数据块的文本
+func collect(server *httptest.Server) ([]string, error) {
+	client := ollama.NewClient(ollama.Config{Host: server.URL, Model: "test-model"})
+	var parts []string
+	err := client.GenerateStream("你好", ollama.Options{}, func(resp *ollama.GenerateResponse) error {
+		parts = append(parts, resp.Response)
+		return nil
+	})
+	return parts, err
+}
+
+func TestGenerateStream_FragmentedReads(t *testing.T) {
+	server := streamServer(
+		`{"response":"你`,
+		`好"}`+"\n"+`{"resp`,
+		`onse":"，请问"}`,
+		"\n",
+		`{"response":"有什么事","done":true}`,
+	)
+	defer server.Close()
+
+	parts, err := collect(server)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"你好", "，请问", "有什么事"}, parts)
+}
+
+func TestGenerateStream_SkipsKeepAliveLines(t *testing.T) {
+	server := streamServer(
+		"\n",
+		`{"response":"第一句"}`+"\r\n",
+		"\n\n  \n",
+		`{"response":"第二句","done":true}`+"\n",
+	)
+	defer server.Close()
+
+	parts, err := collect(server)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"第一句", "第二句"}, parts)
+}
+
+func TestGenerateStream_LineTooLong(t *testing.T) {
+	server := streamServer(`{"response":"` + strings.Repeat("长", 512*1024) + `"}` + "\n")
+	defer server.Close()
+
+	_, err := collect(server)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "单行超过")
+}
+
+func TestGenerateStream_TruncatedStream(t *testing.T) {
+	server := streamServer(`{"response":"说到一半"}`+"\n", `{"response":"被`)
+	defer server.Close()
+
+	parts, err := collect(server)
+	require.Error(t, err)
+	assert.Equal(t, []string{"说到一半"}, parts)
+}
+
+func TestGenerateStream_ErrorLine(t *testing.T) {
+	server := streamServer(`{"response":"你好"}`+"\n", `{"error":"model runner has unexpectedly stopped"}`+"\n")
+	defer server.Close()
+
+	_, err := collect(server)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpectedly stopped")
+}
@@ -0,0 +1,119 @@
+package openai_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/clients/openai"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Generate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+
+		var req openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "deepseek-chat", req.Model)
+		assert.False(t, req.Stream)
+		require.Len(t, req.Messages, 2)
+		assert.Equal(t, "system", req.Messages[0].Role)
+		assert.Equal(t, "用户: 你好\n", req.Messages[1].Content)
+		assert.InDelta(t, 0.3, req.Temperature, 1e-9)
+		assert.Equal(t, 256, req.MaxTokens)
+
+		w.Write([]byte(`{"id":"c1","model":"deepseek-chat","created":1714528800,
+			"choices":[{"message":{"role":"assistant","content":"您好！"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(openai.Config{
+		BaseURL:      server.URL + "/v1/",
+		APIKey:       "sk-test",
+		Model:        "deepseek-chat",
+		SystemPrompt: "你是电话客服",
+	})
+	resp, err := client.Generate("用户: 你好\n", ollama.Options{Temperature: 0.3, MaxTokens: 256})
+	require.NoError(t, err)
+	assert.Equal(t, "您好！", resp.Response)
+	assert.True(t, resp.Done)
+	assert.Equal(t, 12, resp.PromptEvalCount)
+	assert.Equal(t, 3, resp.EvalCount)
+}
+
+func TestClient_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		require.NotNil(t, req.StreamOptions)
+		assert.True(t, req.StreamOptions.IncludeUsage)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"您好", "，请问", "有什么可以帮您？"} {
+			fmt.Fprintf(w, "data: {\"model\":\"gpt-4o-mini\",\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o-mini\",\"choices\":[],\"usage\":{\"prompt_tokens\":20,\"completion_tokens\":9,\"total_tokens\":29}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(openai.Config{BaseURL: server.URL, Model: "gpt-4o-mini"})
+
+	var parts []string
+	var last *ollama.GenerateResponse
+	err := client.GenerateStream("你好", ollama.Options{}, func(resp *ollama.GenerateResponse) error {
+		parts = append(parts, resp.Response)
+		last = resp
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "您好，请问有什么可以帮您？", strings.Join(parts, ""))
+	require.NotNil(t, last)
+	assert.True(t, last.Done)
+	assert.Equal(t, 20, last.PromptEvalCount)
+	assert.Equal(t, 9, last.EvalCount)
+}
+
+func TestClient_Azure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/gpt4/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-02-01", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+		http.Error(w, `{"error":{"message":"rate limited"}}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(openai.Config{
+		BaseURL:    server.URL + "/openai/deployments/gpt4",
+		APIKey:     "azure-key",
+		APIVersion: "2024-02-01",
+	})
+	_, err := client.Generate("你好", ollama.Options{})
+	assert.ErrorContains(t, err, "HTTP 429")
+}
+
+func TestClient_ModelOverride(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "qwen-plus", req.Model)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"好的"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(openai.Config{BaseURL: server.URL, APIKey: "sk-test", Model: "deepseek-chat"})
+	resp, err := client.Generate("用户: 你好\n", ollama.Options{Model: "qwen-plus"})
+	require.NoError(t, err)
+	assert.Equal(t, "好的", resp.Response)
+}
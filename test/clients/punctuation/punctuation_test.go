@@ -0,0 +1,131 @@
+package punctuation_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/punctuation"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newServer 模拟文本后处理服务，在每条文本末尾添加句号
+func newServer(t *testing.T, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var req struct {
+			Texts []string `json:"texts"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		results := make([]map[string]interface{}, len(req.Texts))
+		for i, text := range req.Texts {
+			results[i] = map[string]interface{}{"text": text + "。", "sentences": []string{text + "。"}}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+	}))
+}
+
+func TestClient_Punctuate(t *testing.T) {
+	var requests int32
+	server := newServer(t, &requests)
+	defer server.Close()
+
+	client, err := punctuation.NewClient(punctuation.Config{URL: server.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	text, err := client.Punctuate(context.Background(), "你好请问是张先生吗")
+	require.NoError(t, err)
+	assert.Equal(t, "你好请问是张先生吗。", text)
+
+	// 已有标点或为空的文本不请求服务
+	text, err = client.Punctuate(context.Background(), "你好，请问是张先生吗？")
+	require.NoError(t, err)
+	assert.Equal(t, "你好，请问是张先生吗？", text)
+	text, err = client.Punctuate(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, text)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestClient_Batching(t *testing.T) {
+	var requests int32
+	server := newServer(t, &requests)
+	defer server.Close()
+
+	client, err := punctuation.NewClient(punctuation.Config{
+		URL:         server.URL,
+		Timeout:     time.Second,
+		BatchSize:   4,
+		BatchWindow: time.Second,
+	})
+	require.NoError(t, err)
+
+	// 凑满一批立即发送，不等待合并时间
+	start := time.Now()
+	var wg sync.WaitGroup
+	results := make([]punctuation.Result, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := client.Process(context.Background(), strings.Repeat("好", i+1))
+			assert.NoError(t, err)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+	for i, result := range results {
+		assert.Equal(t, strings.Repeat("好", i+1)+"。", result.Text)
+		assert.Equal(t, []string{result.Text}, result.Sentences)
+	}
+}
+
+func TestClient_BatchWindow(t *testing.T) {
+	var requests int32
+	server := newServer(t, &requests)
+	defer server.Close()
+
+	client, err := punctuation.NewClient(punctuation.Config{
+		URL:         server.URL,
+		Timeout:     time.Second,
+		BatchSize:   16,
+		BatchWindow: 20 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// 不足一批时在合并时间到期后发送
+	result, err := client.Process(context.Background(), "喂")
+	require.NoError(t, err)
+	assert.Equal(t, "喂。", result.Text)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&requests))
+}
+
+func TestClient_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := punctuation.NewClient(punctuation.Config{URL: server.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	// 服务异常时返回原文和错误
+	text, err := client.Punctuate(context.Background(), "你好")
+	assert.Error(t, err)
+	assert.Equal(t, "你好", text)
+
+	_, err = punctuation.NewClient(punctuation.Config{})
+	assert.Error(t, err)
+}
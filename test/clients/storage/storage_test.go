@@ -0,0 +1,101 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "a/b/c.json", []byte(`{"ok":true}`), "application/json"))
+	data, err := store.Get(ctx, "a/b/c.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(data))
+
+	_, err = store.Get(ctx, "missing.json")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.Error(t, store.Put(ctx, "../escape", []byte("x"), ""))
+
+	require.NoError(t, store.Delete(ctx, "a/b/c.json"))
+	_, err = store.Get(ctx, "a/b/c.json")
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.NoError(t, store.Delete(ctx, "a/b/c.json"), "删除不存在的对象")
+}
+
+// fakeS3 模拟S3服务，校验签名头并保存对象
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+		!strings.Contains(auth, "/cn-north-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	store, err := storage.New(storage.Config{
+		Driver:    storage.DriverS3,
+		Endpoint:  srv.URL,
+		Region:    "cn-north-1",
+		Bucket:    "recordings",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "flight-recorder/uuid 1.ndjson", []byte("line\n"), "application/x-ndjson"))
+	assert.Contains(t, fake.objects, "/recordings/flight-recorder/uuid 1.ndjson")
+
+	data, err := store.Get(ctx, "flight-recorder/uuid 1.ndjson")
+	require.NoError(t, err)
+	assert.Equal(t, "line\n", string(data))
+
+	_, err = store.Get(ctx, "missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	require.NoError(t, store.Delete(ctx, "flight-recorder/uuid 1.ndjson"))
+	assert.NotContains(t, fake.objects, "/recordings/flight-recorder/uuid 1.ndjson")
+}
@@ -0,0 +1,28 @@
+package tts_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/clients/tts"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStyle(t *testing.T) {
+	tests := []struct {
+		text  string
+		style string
+		rest  string
+	}{
+		{"[apologetic]非常抱歉给您带来不便。", "apologetic", "非常抱歉给您带来不便。"},
+		{"  [enthusiastic] 太好了！", "enthusiastic", "太好了！"},
+		{"您好，请问有什么可以帮您？", "", "您好，请问有什么可以帮您？"},
+		{"套餐包含[流量]和通话", "", "套餐包含[流量]和通话"},
+		{"[Apologetic]抱歉", "", "[Apologetic]抱歉"},
+	}
+	for _, tt := range tests {
+		style, rest := tts.SplitStyle(tt.text)
+		assert.Equal(t, tt.style, style, tt.text)
+		assert.Equal(t, tt.rest, rest, tt.text)
+	}
+}
@@ -0,0 +1,228 @@
+package ws_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ConnectSendsHeadersAndCookies(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client := ws.NewClient(ws.Config{
+		URL:     "ws" + strings.TrimPrefix(server.URL, "http"),
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Cookies: []*http.Cookie{
+			{Name: "session", Value: "abc"},
+			{Name: "tenant", Value: "t1"},
+		},
+	})
+	assert.NoError(t, client.Connect())
+	defer client.Close()
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		cookie, err := r.Cookie("session")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", cookie.Value)
+		cookie, err = r.Cookie("tenant")
+		assert.NoError(t, err)
+		assert.Equal(t, "t1", cookie.Value)
+	case <-time.After(time.Second):
+		t.Fatal("服务器未收到握手请求")
+	}
+}
+
+func TestClient_ConnectContextCanceled(t *testing.T) {
+	client := ws.NewClient(ws.Config{URL: "ws://127.0.0.1:1/ws"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Error(t, client.ConnectContext(ctx))
+}
+
+func TestClient_ConnectReportsHandshakeStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := ws.NewClient(ws.Config{URL: "ws" + strings.TrimPrefix(server.URL, "http")})
+	err := client.Connect()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+// resultMessage 测试用的识别结果消息
+type resultMessage struct {
+	Type string `json:"type" ws:"result"`
+	Text string `json:"text"`
+}
+
+func TestRegisterTypedHandler_RoutesByTag(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"other","text":"忽略"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"result","text":"你好"}`))
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client := ws.NewClient(ws.Config{URL: "ws" + strings.TrimPrefix(server.URL, "http")})
+	received := make(chan string, 2)
+	err := ws.RegisterTypedHandler(client, func(msg *resultMessage) error {
+		received <- msg.Text
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.Connect())
+	defer client.Close()
+
+	select {
+	case text := <-received:
+		assert.Equal(t, "你好", text)
+	case <-time.After(time.Second):
+		t.Fatal("未收到类型化消息")
+	}
+}
+
+func TestRegisterTypedHandler_InvalidTags(t *testing.T) {
+	client := ws.NewClient(ws.Config{URL: "ws://127.0.0.1:1/ws"})
+
+	type noTag struct {
+		Type string `json:"type"`
+	}
+	assert.Error(t, ws.RegisterTypedHandler(client, func(*noTag) error { return nil }))
+
+	type wrongField struct {
+		Kind string `json:"kind" ws:"result"`
+	}
+	assert.Error(t, ws.RegisterTypedHandler(client, func(*wrongField) error { return nil }))
+}
+
+func TestClient_BinaryAndDefaultHandlers(t *testing.T) {
+	echoed := make(chan []byte, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"code":0,"sid":"iat001"}`))
+		kind, data, err := conn.ReadMessage()
+		if err != nil || kind != websocket.BinaryMessage {
+			return
+		}
+		echoed <- data
+		conn.WriteMessage(websocket.BinaryMessage, data)
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client := ws.NewClient(ws.Config{URL: "ws" + strings.TrimPrefix(server.URL, "http")})
+	untyped := make(chan string, 1)
+	binary := make(chan []byte, 1)
+	client.SetDefaultHandler(func(message []byte) error {
+		untyped <- string(message)
+		return nil
+	})
+	client.SetBinaryHandler(func(message []byte) error {
+		binary <- message
+		return nil
+	})
+	assert.NoError(t, client.Connect())
+	defer client.Close()
+
+	// 没有类型字段的消息交给默认处理器
+	select {
+	case message := <-untyped:
+		assert.Contains(t, message, "iat001")
+	case <-time.After(time.Second):
+		t.Fatal("未收到无类型消息")
+	}
+
+	assert.NoError(t, client.SendBinary([]byte{1, 2, 3}))
+	assert.Equal(t, []byte{1, 2, 3}, <-echoed)
+	select {
+	case message := <-binary:
+		assert.Equal(t, []byte{1, 2, 3}, message)
+	case <-time.After(time.Second):
+		t.Fatal("未收到二进制消息")
+	}
+}
+
+func TestClient_ReconnectsWithFreshURL(t *testing.T) {
+	var dials int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// 第一条连接建立后立即断开，触发重连
+		if atomic.AddInt32(&dials, 1) == 1 {
+			return
+		}
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	var urls int32
+	client := ws.NewClient(ws.Config{
+		URLFunc: func() (string, error) {
+			n := atomic.AddInt32(&urls, 1)
+			return fmt.Sprintf("ws%s?n=%d", strings.TrimPrefix(server.URL, "http"), n), nil
+		},
+		ReconnectInterval: 10 * time.Millisecond,
+		MaxRetries:        3,
+	})
+	reconnected := make(chan struct{}, 1)
+	client.OnReconnect(func() { reconnected <- struct{}{} })
+	assert.NoError(t, client.Connect())
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("未重新连接")
+	}
+	assert.True(t, client.Connected())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&urls))
+
+	// 关闭后可以再次连接
+	assert.NoError(t, client.Close())
+	assert.False(t, client.Connected())
+	assert.NoError(t, client.Connect())
+	assert.NoError(t, client.Close())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dials))
+}
@@ -2,6 +2,7 @@ package xfyun_test
 
 import (
 	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -27,17 +28,19 @@ func (m *MockDialogService) ClearHistory(sessionID string) {
 }
 
 func TestASRClient_ProcessAudio(t *testing.T) {
-	// 创建测试配置
+	// 创建测试配置，密钥从环境变量读取，未设置时跳过
 	config := xfyun.Config{
-		AppID:             "c0de4f24",
-		APIKey:            "51012a35448538a8396dc564cf050f68",
-		APISecret:         "NWRhZDBkNzA5ZDQxNGMzYmQ1NWMwMWNh",
+		AppID:             os.Getenv("XFYUN_APP_ID"),
+		APIKey:            os.Getenv("XFYUN_API_KEY"),
+		APISecret:         os.Getenv("XFYUN_API_SECRET"),
 		ServerURL:         "wss://iat-api.xfyun.cn/v2/iat",
 		MaxRetries:        3,
 		ReconnectInterval: time.Second,
 	}
 
-	t.Logf("ASR配置: %+v", config)
+	if config.AppID == "" || config.APIKey == "" || config.APISecret == "" {
+		t.Skip("未设置XFYUN_APP_ID、XFYUN_API_KEY、XFYUN_API_SECRET环境变量")
+	}
 
 	// 创建ASR客户端
 	client := xfyun.NewASRClient(config, &MockDialogService{})
@@ -0,0 +1,276 @@
+package xfyun_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// asrFrame 客户端发送的音频帧
+type asrFrame struct {
+	Data struct {
+		Status int    `json:"status"`
+		Audio  string `json:"audio"`
+	} `json:"data"`
+}
+
+// asrResult 构造讯飞听写结果，text按字拆分为词
+func asrResult(sn int, pgs string, rg []int, text string, status int) map[string]interface{} {
+	ws := []map[string]interface{}{}
+	for _, w := range strings.Split(text, "") {
+		ws = append(ws, map[string]interface{}{"cw": []map[string]interface{}{{"w": w}}})
+	}
+	result := map[string]interface{}{"sn": sn, "pgs": pgs, "ws": ws}
+	if rg != nil {
+		result["rg"] = rg
+	}
+	return map[string]interface{}{"code": 0, "sid": "iat001", "data": map[string]interface{}{"status": status, "result": result}}
+}
+
+// newASRServer 模拟讯飞听写接口，每收到一帧返回一条结果，收到最后一帧时返回最终结果
+func newASRServer(t *testing.T, frames chan<- asrFrame, replies []map[string]interface{}) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		for i := 0; ; i++ {
+			var frame asrFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			frames <- frame
+			if frame.Data.Status == xfyun.STATUS_LAST_FRAME {
+				conn.WriteJSON(replies[len(replies)-1])
+				return
+			}
+			if i < len(replies)-1 {
+				conn.WriteJSON(replies[i])
+			}
+		}
+	}))
+}
+
+func newStreamClient(server *httptest.Server) *xfyun.ASRClient {
+	return xfyun.NewASRClient(xfyun.Config{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+	}, nil)
+}
+
+func TestASRClient_OpenStream(t *testing.T) {
+	frames := make(chan asrFrame, 16)
+	server := newASRServer(t, frames, []map[string]interface{}{
+		asrResult(1, "apd", nil, "今天", xfyun.STATUS_CONTINUE_FRAME),
+		asrResult(2, "rpl", []int{1, 1}, "今天天气", xfyun.STATUS_CONTINUE_FRAME),
+		asrResult(3, "apd", nil, "好", xfyun.STATUS_LAST_FRAME),
+	})
+	defer server.Close()
+
+	stream, err := newStreamClient(server).OpenStream("session-1")
+	require.NoError(t, err)
+
+	// 超过单帧大小的分片拆分为多帧发送
+	n, err := stream.Write(make([]byte, 2000))
+	require.NoError(t, err)
+	assert.Equal(t, 2000, n)
+	assert.Equal(t, xfyun.STATUS_FIRST_FRAME, (<-frames).Data.Status)
+	assert.Equal(t, xfyun.STATUS_CONTINUE_FRAME, (<-frames).Data.Status)
+
+	var results []xfyun.StreamResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for result := range stream.Results() {
+			results = append(results, result)
+		}
+	}()
+
+	require.NoError(t, stream.Close())
+	assert.Equal(t, xfyun.STATUS_LAST_FRAME, (<-frames).Data.Status)
+	<-done
+	require.NoError(t, stream.Err())
+
+	// 动态修正的结果替换之前的中间结果
+	require.Len(t, results, 3)
+	assert.Equal(t, xfyun.StreamResult{Text: "今天"}, results[0])
+	assert.Equal(t, xfyun.StreamResult{Text: "今天天气"}, results[1])
+	assert.Equal(t, xfyun.StreamResult{Text: "今天天气好", IsFinal: true}, results[2])
+
+	// 会话结束后不能继续发送音频
+	_, err = stream.Write([]byte{0, 0})
+	assert.True(t, errors.Is(err, xfyun.ErrStreamClosed))
+}
+
+func TestASRClient_OpenStreamServerError(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var frame json.RawMessage
+		conn.ReadJSON(&frame)
+		conn.WriteJSON(map[string]interface{}{"code": 10165, "message": "invalid handle", "sid": "iat002"})
+	}))
+	defer server.Close()
+
+	stream, err := newStreamClient(server).OpenStream("session-1")
+	require.NoError(t, err)
+	_, err = stream.Write([]byte{0, 0})
+	require.NoError(t, err)
+
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("识别会话未结束")
+	}
+	assert.ErrorContains(t, stream.Err(), "invalid handle")
+	var serr *xfyun.ServerError
+	require.ErrorAs(t, stream.Err(), &serr)
+	assert.Equal(t, 10165, serr.Code)
+	assert.Equal(t, "iat002", serr.SID)
+	assert.ErrorIs(t, stream.Err(), xfyun.ErrEngine)
+	for range stream.Results() {
+		t.Fatal("出错的会话不应返回结果")
+	}
+	assert.NoError(t, stream.Close())
+}
+
+func TestASRClient_OpenStreamRequiresSession(t *testing.T) {
+	_, err := xfyun.NewASRClient(xfyun.Config{}, nil).OpenStream("")
+	assert.Error(t, err)
+}
+
+// newDroppingASRServer 模拟中途断开的讯飞听写接口：第一条连接收到一帧后返回中间结果并断开，
+// 之后的连接把收到的帧转发到frames，收到最后一帧时返回final
+func newDroppingASRServer(t *testing.T, partial string, frames chan<- asrFrame, final string) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	var conns int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		first := atomic.AddInt32(&conns, 1) == 1
+		for {
+			var frame asrFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			if first {
+				conn.WriteJSON(asrResult(1, "apd", nil, partial, xfyun.STATUS_CONTINUE_FRAME))
+				return
+			}
+			frames <- frame
+			if frame.Data.Status == xfyun.STATUS_LAST_FRAME {
+				conn.WriteJSON(asrResult(1, "apd", nil, final, xfyun.STATUS_LAST_FRAME))
+				return
+			}
+		}
+	}))
+}
+
+// collectResults 读取识别结果直至会话结束
+func collectResults(stream *xfyun.Stream) <-chan []xfyun.StreamResult {
+	out := make(chan []xfyun.StreamResult, 1)
+	go func() {
+		var results []xfyun.StreamResult
+		for result := range stream.Results() {
+			results = append(results, result)
+		}
+		out <- results
+	}()
+	return out
+}
+
+func TestASRClient_StreamResume(t *testing.T) {
+	frames := make(chan asrFrame, 16)
+	server := newDroppingASRServer(t, "今天", frames, "今天天气好")
+	defer server.Close()
+	client := xfyun.NewASRClient(xfyun.Config{
+		AppID: "app", APIKey: "key", APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+		Resume:    xfyun.ResumeConfig{Enabled: true, Buffer: 3 * time.Second, MaxAttempts: 2},
+	}, nil)
+
+	stream, err := client.OpenStream("session-1")
+	require.NoError(t, err)
+	results := collectResults(stream)
+	_, err = stream.Write(make([]byte, 1000))
+	require.NoError(t, err)
+
+	// 重连后以第一帧参数重新发送缓存的全部音频
+	first := <-frames
+	assert.Equal(t, xfyun.STATUS_FIRST_FRAME, first.Data.Status)
+	audio, err := base64.StdEncoding.DecodeString(first.Data.Audio)
+	require.NoError(t, err)
+	assert.Len(t, audio, 1000)
+
+	require.NoError(t, stream.Close())
+	assert.Equal(t, xfyun.STATUS_LAST_FRAME, (<-frames).Data.Status)
+	require.NoError(t, stream.Err())
+
+	// 缓存覆盖全部音频，新会话的结果即完整文本
+	got := <-results
+	require.NotEmpty(t, got)
+	assert.Equal(t, xfyun.StreamResult{Text: "今天天气好", IsFinal: true}, got[len(got)-1])
+}
+
+func TestASRClient_StreamResumeTrimmedBuffer(t *testing.T) {
+	frames := make(chan asrFrame, 16)
+	server := newDroppingASRServer(t, "今天天气", frames, "天气好")
+	defer server.Close()
+	client := xfyun.NewASRClient(xfyun.Config{
+		AppID: "app", APIKey: "key", APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+		Resume:    xfyun.ResumeConfig{Enabled: true, Buffer: 20 * time.Millisecond, MaxAttempts: 1},
+	}, nil)
+
+	stream, err := client.OpenStream("session-1")
+	require.NoError(t, err)
+	results := collectResults(stream)
+	_, err = stream.Write(make([]byte, 1000))
+	require.NoError(t, err)
+
+	// 只重新发送最近20ms的音频，新会话的结果接在之前的文本后，重复部分只保留一份
+	audio, err := base64.StdEncoding.DecodeString((<-frames).Data.Audio)
+	require.NoError(t, err)
+	assert.Len(t, audio, 640)
+
+	require.NoError(t, stream.Close())
+	<-frames
+	got := <-results
+	assert.Equal(t, []xfyun.StreamResult{{Text: "今天天气"}, {Text: "今天天气好", IsFinal: true}}, got)
+}
+
+func TestASRClient_StreamResumeDisabled(t *testing.T) {
+	frames := make(chan asrFrame, 16)
+	server := newDroppingASRServer(t, "今天", frames, "")
+	defer server.Close()
+
+	stream, err := newStreamClient(server).OpenStream("session-1")
+	require.NoError(t, err)
+	results := collectResults(stream)
+	_, err = stream.Write(make([]byte, 1000))
+	require.NoError(t, err)
+
+	got := <-results
+	assert.Equal(t, []xfyun.StreamResult{{Text: "今天"}}, got)
+	assert.ErrorContains(t, stream.Err(), "读取识别结果失败")
+}
@@ -0,0 +1,56 @@
+package xfyun_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerError_Kinds(t *testing.T) {
+	tests := []struct {
+		code int
+		kind string
+		err  error
+	}{
+		{10105, xfyun.ErrorKindAuth, xfyun.ErrAuth},
+		{10114, xfyun.ErrorKindTimeout, xfyun.ErrTimeout},
+		{11200, xfyun.ErrorKindQuota, xfyun.ErrQuota},
+		{10043, xfyun.ErrorKindInvalidRequest, xfyun.ErrInvalidRequest},
+		{10700, xfyun.ErrorKindEngine, xfyun.ErrEngine},
+	}
+	for _, tt := range tests {
+		err := &xfyun.ServerError{Code: tt.code, Message: "msg", SID: "iat001"}
+		assert.Equal(t, tt.kind, err.Kind(), "错误码 %d", tt.code)
+		assert.ErrorIs(t, err, tt.err, "错误码 %d", tt.code)
+		assert.NotEmpty(t, err.Remediation(), "错误码 %d", tt.code)
+		assert.Contains(t, err.Error(), err.Remediation())
+		assert.Contains(t, err.Error(), "iat001")
+	}
+
+	unknown := &xfyun.ServerError{Code: 99999, Message: "msg"}
+	assert.Equal(t, xfyun.ErrorKindUnknown, unknown.Kind())
+	assert.Equal(t, "99999", unknown.ErrorCode())
+	assert.NotErrorIs(t, unknown, xfyun.ErrAuth)
+}
+
+func TestASRClient_OpenStreamHandshakeRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"HMAC signature cannot be verified, a valid date or x-date header is required"}`))
+	}))
+	defer server.Close()
+
+	_, err := newStreamClient(server).OpenStream("session-1")
+	require.Error(t, err)
+	var serr *xfyun.ServerError
+	require.ErrorAs(t, err, &serr)
+	assert.Equal(t, http.StatusForbidden, serr.Code)
+	assert.Contains(t, serr.Message, "HMAC signature")
+	assert.ErrorIs(t, err, xfyun.ErrAuth)
+	assert.Contains(t, serr.Remediation(), "时钟")
+}
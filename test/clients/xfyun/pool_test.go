@@ -0,0 +1,99 @@
+package xfyun_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdleServer 模拟讯飞听写接口，只接受连接并计数，连接保持到客户端关闭
+func newIdleServer(t *testing.T, dials *int32) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt32(dials, 1)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func poolConfig(server *httptest.Server, size int, ttl time.Duration) xfyun.Config {
+	return xfyun.Config{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+		Pool:      xfyun.PoolConfig{Enabled: true, Size: size, TTL: ttl},
+	}
+}
+
+func TestConnPool_WarmAndCheckout(t *testing.T) {
+	var dials int32
+	config := poolConfig(newIdleServer(t, &dials), 2, time.Minute)
+	pool := xfyun.NewConnPool(config)
+
+	// 预热前取不到连接
+	_, ok := pool.Get()
+	assert.False(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pool.Run(ctx)
+	require.Eventually(t, func() bool { return pool.Stats().Idle == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	client := xfyun.NewASRClient(config, nil)
+	client.SetPool(pool)
+	stream, err := client.OpenStream("session-1")
+	require.NoError(t, err)
+	defer stream.Close()
+
+	// 取走后补充到配置的数量
+	require.Eventually(t, func() bool { return pool.Stats().Idle == 2 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&dials) == 3 }, time.Second, 10*time.Millisecond)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.InDelta(t, 0.5, stats.HitRate, 0.001)
+	assert.Zero(t, stats.DialFailures)
+}
+
+func TestConnPool_ExpiresIdleConnections(t *testing.T) {
+	var dials int32
+	pool := xfyun.NewConnPool(poolConfig(newIdleServer(t, &dials), 1, 200*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pool.Run(ctx)
+
+	// 过期的连接关闭后重新建立
+	require.Eventually(t, func() bool { return pool.Stats().Expired >= 1 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return pool.Stats().Idle == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&dials), int32(2))
+}
+
+func TestPoolConfig_Validate(t *testing.T) {
+	assert.NoError(t, xfyun.PoolConfig{}.Validate())
+	assert.NoError(t, xfyun.PoolConfig{Enabled: true, Size: 1, TTL: time.Second}.Validate())
+	assert.Error(t, xfyun.PoolConfig{Enabled: true, TTL: time.Second}.Validate())
+	assert.Error(t, xfyun.PoolConfig{Enabled: true, Size: 1}.Validate())
+}
@@ -0,0 +1,55 @@
+package xfyun_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASRClient_ProcessAudioReconnectsPerCall(t *testing.T) {
+	var dials int32
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt32(&dials, 1)
+		for {
+			var frame asrFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			if frame.Data.Status == xfyun.STATUS_LAST_FRAME {
+				conn.WriteJSON(asrResult(1, "rpl", []int{1, 1}, "你好", xfyun.STATUS_LAST_FRAME))
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	client := xfyun.NewASRClient(xfyun.Config{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+	}, &MockDialogService{})
+
+	// 讯飞每次识别后断开连接，同一客户端的每次识别都重新鉴权建立连接
+	for i := 0; i < 2; i++ {
+		text, err := client.ProcessAudio("session-1", make([]byte, 2560))
+		require.NoError(t, err)
+		assert.Equal(t, "你好", text)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&dials))
+}
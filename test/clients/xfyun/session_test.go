@@ -0,0 +1,115 @@
+package xfyun_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUtteranceServer 模拟讯飞听写接口：每条连接只识别一句，收到最后一帧后返回最终结果并断开；
+// 分别统计建立和关闭的连接数
+func newUtteranceServer(t *testing.T, dials, closed *int32) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		atomic.AddInt32(dials, 1)
+		defer atomic.AddInt32(closed, 1)
+		defer conn.Close()
+		for {
+			var frame asrFrame
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			if frame.Data.Status == xfyun.STATUS_LAST_FRAME {
+				conn.WriteJSON(asrResult(1, "apd", nil, "你好", xfyun.STATUS_LAST_FRAME))
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sessionClient(server *httptest.Server, idle, maxIdle time.Duration) *xfyun.ASRClient {
+	return xfyun.NewASRClient(xfyun.Config{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/iat",
+		Session:   xfyun.SessionConfig{Enabled: true, IdleTimeout: idle, MaxIdle: maxIdle},
+	}, nil)
+}
+
+// recognize 完成一句识别，返回最终结果
+func recognize(t *testing.T, client *xfyun.ASRClient, sessionID string) string {
+	stream, err := client.OpenStream(sessionID)
+	require.NoError(t, err)
+	_, err = stream.Write(make([]byte, 640))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+
+	var final string
+	for result := range stream.Results() {
+		final = result.Text
+	}
+	require.NoError(t, stream.Err())
+	return final
+}
+
+func TestASRClient_SessionReusesPreparedConnection(t *testing.T) {
+	var dials, closed int32
+	client := sessionClient(newUtteranceServer(t, &dials, &closed), time.Minute, time.Minute)
+
+	assert.Equal(t, "你好", recognize(t, client, "session-1"))
+	// 第一句结束后为下一句预先建立连接
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&dials) == 2 }, 2*time.Second, 10*time.Millisecond)
+
+	// 第二句使用备用连接，无需重新握手
+	assert.Equal(t, "你好", recognize(t, client, "session-1"))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&dials) == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&closed))
+
+	// 通话结束后关闭备用连接
+	client.ReleaseSession("session-1")
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&closed) == 3 }, 2*time.Second, 10*time.Millisecond)
+
+	// 释放后的会话不再保持连接
+	client.ReleaseSession("session-1")
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dials))
+}
+
+func TestASRClient_SessionExpiresIdleConnection(t *testing.T) {
+	var dials, closed int32
+	client := sessionClient(newUtteranceServer(t, &dials, &closed), 100*time.Millisecond, 250*time.Millisecond)
+
+	recognize(t, client, "session-1")
+
+	// 备用连接空闲超时后重新鉴权建立，会话超过保持时间后不再建立
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&dials) >= 3 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&closed) == atomic.LoadInt32(&dials)
+	}, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, atomic.LoadInt32(&closed), atomic.LoadInt32(&dials))
+}
+
+func TestSessionConfig_Validate(t *testing.T) {
+	assert.NoError(t, xfyun.SessionConfig{}.Validate())
+	assert.NoError(t, xfyun.SessionConfig{Enabled: true, IdleTimeout: time.Second, MaxIdle: time.Minute}.Validate())
+	assert.Error(t, xfyun.SessionConfig{Enabled: true, MaxIdle: time.Minute}.Validate())
+	assert.Error(t, xfyun.SessionConfig{Enabled: true, IdleTimeout: time.Minute, MaxIdle: time.Second}.Validate())
+}
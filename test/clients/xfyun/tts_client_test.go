@@ -0,0 +1,118 @@
+package xfyun_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/clients/xfyun"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTTSServer 模拟讯飞语音合成接口，收到请求后分两帧返回音频
+func newTTSServer(t *testing.T, requests chan<- map[string]interface{}, code int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.URL.Query().Get("authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		var req map[string]interface{}
+		require.NoError(t, conn.ReadJSON(&req))
+		requests <- req
+
+		if code != 0 {
+			conn.WriteJSON(map[string]interface{}{"code": code, "message": "invalid vcn", "sid": "tts001"})
+			return
+		}
+		for i, chunk := range [][]byte{{1, 0, 2, 0}, {3, 0}} {
+			status := 1
+			if i == 1 {
+				status = 2
+			}
+			conn.WriteJSON(map[string]interface{}{
+				"code": 0,
+				"sid":  "tts001",
+				"data": map[string]interface{}{"audio": base64.StdEncoding.EncodeToString(chunk), "status": status},
+			})
+		}
+	}))
+}
+
+func TestTTSClient_Synthesize(t *testing.T) {
+	requests := make(chan map[string]interface{}, 1)
+	server := newTTSServer(t, requests, 0)
+	defer server.Close()
+
+	client, err := xfyun.NewTTSClient(xfyun.TTSConfig{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/tts",
+	})
+	require.NoError(t, err)
+
+	audio, err := client.Synthesize(context.Background(), "您好", tts.Options{Voice: "aisjiuxu", SampleRate: 8000, Speed: 1.2})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 0, 2, 0, 3, 0}, audio.PCM)
+	assert.Equal(t, 8000, audio.SampleRate)
+	assert.Equal(t, 1, audio.Channels)
+
+	req := <-requests
+	raw, _ := json.Marshal(req)
+	var parsed struct {
+		Common struct {
+			AppID string `json:"app_id"`
+		}
+		Business struct {
+			Auf   string `json:"auf"`
+			Vcn   string `json:"vcn"`
+			Speed int    `json:"speed"`
+			Pitch int    `json:"pitch"`
+		}
+		Data struct {
+			Status int    `json:"status"`
+			Text   string `json:"text"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(raw, &parsed))
+	assert.Equal(t, "app", parsed.Common.AppID)
+	assert.Equal(t, "audio/L16;rate=8000", parsed.Business.Auf)
+	assert.Equal(t, "aisjiuxu", parsed.Business.Vcn)
+	assert.Equal(t, 60, parsed.Business.Speed)
+	assert.Equal(t, 50, parsed.Business.Pitch)
+	assert.Equal(t, 2, parsed.Data.Status)
+	text, _ := base64.StdEncoding.DecodeString(parsed.Data.Text)
+	assert.Equal(t, "您好", string(text))
+}
+
+func TestTTSClient_Error(t *testing.T) {
+	server := newTTSServer(t, make(chan map[string]interface{}, 1), 10005)
+	defer server.Close()
+
+	client, err := xfyun.NewTTSClient(xfyun.TTSConfig{
+		AppID:     "app",
+		APIKey:    "key",
+		APISecret: "secret",
+		ServerURL: "ws" + strings.TrimPrefix(server.URL, "http") + "/v2/tts",
+	})
+	require.NoError(t, err)
+
+	_, err = client.Synthesize(context.Background(), "您好", tts.Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "10005")
+}
+
+func TestNewTTSClient_RequiresCredentials(t *testing.T) {
+	_, err := xfyun.NewTTSClient(xfyun.TTSConfig{})
+	assert.Error(t, err)
+}
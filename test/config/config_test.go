@@ -0,0 +1,585 @@
+package config_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/services/guardrail"
+	"ai_dialer_mini/internal/services/sentiment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig 将配置内容写入临时文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad_RepositoryConfig(t *testing.T) {
+	t.Setenv("XFYUN_APP_ID", "app")
+	t.Setenv("XFYUN_API_KEY", "key")
+	t.Setenv("XFYUN_API_SECRET", "secret")
+	cfg, err := config.Load("../../config.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, config.ProviderXFYun, cfg.ASR.Provider)
+	assert.Equal(t, "app", cfg.ASR.XFYun.AppID)
+	assert.Empty(t, cfg.FreeSWITCH.Password)
+	assert.Equal(t, time.Second, cfg.ASR.XFYun.ReconnectInterval)
+	assert.Equal(t, "qwen:0.5b", cfg.LLM.Ollama.Model)
+	assert.Nil(t, cfg.DeprecatedXFYun)
+}
+
+func TestLoad_RepositoryConfigRequiresCredentials(t *testing.T) {
+	t.Setenv("XFYUN_APP_ID", "")
+	_, err := config.Load("../../config.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "XFYUN_APP_ID")
+}
+
+func TestLoad_EnvInterpolation(t *testing.T) {
+	t.Setenv("TEST_FS_PASSWORD", `p@ss: "word"`)
+	t.Setenv("TEST_SERVER_PORT", "9090")
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: ${TEST_SERVER_PORT}
+  host: "${TEST_SERVER_HOST:-127.0.0.1}"
+freeswitch:
+  password: "${TEST_FS_PASSWORD}"
+asr:
+  xfyun:
+    app_id: "app-${TEST_FS_MISSING:-default}"
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, "127.0.0.1", cfg.Server.Host)
+	assert.Equal(t, `p@ss: "word"`, cfg.FreeSWITCH.Password)
+	assert.Equal(t, "app-default", cfg.ASR.XFYun.AppID)
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("AI_DIALER_SERVER_PORT", "9091")
+	t.Setenv("AI_DIALER_ASR_XFYUN_API_KEY", "override-key")
+	t.Setenv("AI_DIALER_ASR_XFYUN_RECONNECT_INTERVAL", "3s")
+	t.Setenv("AI_DIALER_API_TOKENS", "a, b")
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  xfyun:
+    api_key: "file-key"
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9091, cfg.Server.Port)
+	assert.Equal(t, "override-key", cfg.ASR.XFYun.APIKey)
+	assert.Equal(t, 3*time.Second, cfg.ASR.XFYun.ReconnectInterval)
+	assert.Equal(t, []string{"a", "b"}, cfg.API.Tokens)
+
+	t.Setenv("AI_DIALER_SERVER_PORT", "abc")
+	_, err = config.Load(writeConfig(t, "server:\n  port: 8080\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AI_DIALER_SERVER_PORT")
+}
+
+func TestLoad_DeprecatedKeys(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+xfyun:
+  app_id: "legacy-app"
+  reconnect_interval: 2
+ollama:
+  host: "http://ollama:11434"
+  model: "qwen"
+dialog:
+  provider: "mock"
+  mock:
+    default: "好的"
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "legacy-app", cfg.ASR.XFYun.AppID)
+	assert.Equal(t, 2*time.Second, cfg.ASR.XFYun.ReconnectInterval)
+	assert.Equal(t, "http://ollama:11434", cfg.LLM.Ollama.Host)
+	assert.Equal(t, config.ProviderMock, cfg.LLM.Provider)
+	assert.Equal(t, "好的", cfg.LLM.Mock.Default)
+}
+
+func TestLoad_NewKeysOverrideDeprecated(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+xfyun:
+  app_id: "legacy-app"
+asr:
+  xfyun:
+    app_id: "new-app"
+llm:
+  provider: "ollama"
+dialog:
+  provider: "mock"
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "new-app", cfg.ASR.XFYun.AppID)
+	assert.Equal(t, config.ProviderOllama, cfg.LLM.Provider)
+}
+
+func TestLoad_UnknownProvider(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  provider: "gpt"
+`))
+	assert.Error(t, err)
+}
+
+func TestLoad_OpenAIProvider(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  provider: "openai"
+  openai:
+    base_url: "https://api.deepseek.com/v1"
+    model: "deepseek-chat"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, config.ProviderOpenAI, cfg.LLM.Provider)
+	assert.Equal(t, 60*time.Second, cfg.LLM.OpenAI.Timeout)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  provider: "openai"
+`))
+	assert.ErrorContains(t, err, "llm.openai.base_url")
+}
+
+func TestLoad_InvalidCampaignOptions(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  campaigns:
+    "7":
+      temperature: 5
+`))
+	assert.ErrorContains(t, err, "llm.campaigns.7")
+}
+
+func TestLoad_UndefinedTTSStyle(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+tts:
+  styles:
+    apologetic:
+      speed: 0.9
+  campaigns:
+    "1": "cheerful"
+`))
+	assert.ErrorContains(t, err, "tts.campaigns.1")
+}
+
+func TestLoad_InvalidLogLevel(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+logging:
+  levels:
+    esl.event: verbose
+`))
+	assert.ErrorContains(t, err, "logging.levels.esl.event")
+}
+
+func TestSaveLogLevels(t *testing.T) {
+	path := writeConfig(t, `# 服务配置
+server:
+  port: 8080  # 监听端口
+logging:
+  level: info  # 日志级别
+  sampling:
+    default:
+      every: 1
+`)
+
+	require.NoError(t, config.SaveLogLevels(path, "warn", map[string]string{"esl.event": "debug"}))
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Logging.Level)
+	assert.Equal(t, map[string]string{"esl.event": "debug"}, cfg.Logging.Levels)
+	assert.Equal(t, 1, cfg.Logging.Sampling["default"].Every)
+
+	// 其他配置项和注释保留
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "port: 8080 # 监听端口")
+	assert.Contains(t, string(data), "level: warn # 日志级别")
+}
+
+func TestParseRoles(t *testing.T) {
+	roles, err := config.ParseRoles("")
+	require.NoError(t, err)
+	assert.Equal(t, "api,media,worker", roles.String())
+
+	roles, err = config.ParseRoles("all")
+	require.NoError(t, err)
+	assert.True(t, roles.Has(config.RoleMedia))
+
+	roles, err = config.ParseRoles(" Worker, api ")
+	require.NoError(t, err)
+	assert.True(t, roles.Has(config.RoleAPI))
+	assert.True(t, roles.Has(config.RoleWorker))
+	assert.False(t, roles.Has(config.RoleMedia))
+	assert.Equal(t, "api,worker", roles.String())
+
+	_, err = config.ParseRoles("api,scheduler")
+	assert.Error(t, err)
+}
+
+func TestLoad_InvalidRole(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+  role: "gateway"
+`))
+	assert.Error(t, err)
+}
+
+func TestLoad_InvalidAllowedOrigin(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+websocket:
+  allowed_origins: ["crm.example.com"]
+`))
+	assert.ErrorContains(t, err, "websocket.allowed_origins")
+}
+
+func TestLoad_DeadAirTimeoutExceedsHold(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+turn:
+  hold_phrases: ["稍等"]
+  hold_timeout: 60s
+dead_air:
+  enabled: true
+  timeout: 30s
+`))
+	assert.ErrorContains(t, err, "dead_air.timeout")
+
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+dead_air:
+  enabled: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, cfg.DeadAir.Timeout)
+	assert.Equal(t, "MEDIA_TIMEOUT", cfg.DeadAir.Cause)
+}
+
+func TestLoad_ASRResume(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 3*time.Second, cfg.ASR.XFYun.Resume.Buffer)
+	assert.Equal(t, 2, cfg.ASR.XFYun.Resume.MaxAttempts)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  xfyun:
+    resume:
+      enabled: true
+      buffer: 2m
+`))
+	assert.ErrorContains(t, err, "asr.xfyun.resume.buffer")
+}
+
+func TestLoad_ASRPostProcess(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  postprocess:
+    itn: true
+    replacements:
+      智能外乎: 智能外呼
+    censor: ["混蛋"]
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.ASR.PostProcess.ITN)
+	assert.Equal(t, "智能外呼", cfg.ASR.PostProcess.Replacements["智能外乎"])
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  postprocess:
+    censor: [""]
+`))
+	assert.ErrorContains(t, err, "asr.postprocess.censor")
+}
+
+func TestLoad_LLMGuardrail(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  guardrail:
+    max_length: 60
+    keywords: ["稳赚不赔"]
+    action: fallback
+    fallback: "抱歉，这个问题稍后请专人为您解答。"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 60, cfg.LLM.Guardrail.MaxLength)
+	assert.Equal(t, guardrail.ActionFallback, cfg.LLM.Guardrail.Action)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  guardrail:
+    patterns: ["("]
+`))
+	assert.ErrorContains(t, err, "llm.guardrail.patterns[0]")
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  guardrail:
+    action: fallback
+`))
+	assert.ErrorContains(t, err, "llm.guardrail.fallback")
+}
+
+func TestLoad_TranscriptSentiment(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+transcript:
+  live: true
+  sentiment:
+    enabled: true
+`))
+	require.NoError(t, err)
+	assert.True(t, cfg.Transcript.Sentiment.Enabled)
+	assert.Equal(t, sentiment.ProviderLexicon, cfg.Transcript.Sentiment.Provider)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+transcript:
+  sentiment:
+    enabled: true
+    provider: bert
+`))
+	assert.ErrorContains(t, err, "transcript.sentiment.provider")
+}
+
+func TestLoad_CampaignPreview(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, cfg.Campaign.Preview.Timeout)
+	assert.Equal(t, 5, cfg.Campaign.Preview.MaxPending)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+campaign:
+  preview:
+    max_pending: -1
+`))
+	assert.ErrorContains(t, err, "campaign.preview.max_pending")
+}
+
+func TestLoad_LLMHistory(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+llm:
+  history:
+    max_tokens: 200
+    summarize:
+      enabled: true
+      max_tokens: 300
+`))
+	assert.ErrorContains(t, err, "llm.history.summarize.max_tokens")
+}
+
+func TestLoad_CampaignMetrics(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Second, cfg.Campaign.Metrics.Interval)
+	assert.Equal(t, 5*time.Second, cfg.Campaign.Metrics.AbandonThreshold)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+campaign:
+  metrics:
+    conversion_dispositions: ["成交", ""]
+`))
+	assert.ErrorContains(t, err, "campaign.metrics.conversion_dispositions[1]")
+}
+
+func TestLoad_Database(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+`))
+	require.NoError(t, err)
+	assert.Equal(t, database.MySQL, cfg.Database.Driver)
+
+	cfg, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+database:
+  driver: sqlite
+`))
+	require.NoError(t, err)
+	assert.Equal(t, database.DefaultSQLitePath, cfg.Database.SQLite.Path)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+database:
+  driver: postgres
+`))
+	assert.ErrorContains(t, err, "database.driver")
+}
+
+func TestLoad_Assist(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+assist:
+  enabled: true
+`))
+	assert.ErrorContains(t, err, "assist.tokens")
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+assist:
+  enabled: true
+  tokens: ["agent-token"]
+  knowledge: ["月租59元含30G流量", " "]
+`))
+	assert.ErrorContains(t, err, "assist.knowledge[1]")
+}
+
+func TestLoad_WhisperProvider(t *testing.T) {
+	_, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  provider: whisper
+`))
+	assert.ErrorContains(t, err, "asr.whisper.server_url")
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  failover:
+    enabled: true
+`))
+	assert.ErrorContains(t, err, "asr.failover")
+
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+asr:
+  provider: whisper
+  whisper:
+    server_url: ws://localhost:9000/asr
+    model: large-v3
+  failover:
+    enabled: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "large-v3", cfg.ASR.Whisper.Model)
+	assert.Equal(t, 3, cfg.ASR.Failover.Threshold)
+	assert.Equal(t, time.Minute, cfg.ASR.Failover.Cooldown)
+}
+
+func TestLoad_TenantUndefinedPersona(t *testing.T) {
+	t.Setenv("BANK_XFYUN_SECRET", "secret")
+	cfg, err := config.Load(writeConfig(t, `
+server:
+  port: 8080
+tenancy:
+  tenants:
+    bank:
+      xfyun:
+        app_id: app
+        api_key: key
+        api_secret: ${BANK_XFYUN_SECRET}
+      model: qwen2:7b
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", cfg.Tenancy.Tenants["bank"].XFYun.APISecret)
+
+	_, err = config.Load(writeConfig(t, `
+server:
+  port: 8080
+tenancy:
+  tenants:
+    bank:
+      persona: missing
+`))
+	assert.ErrorContains(t, err, "tenancy.tenants.bank.persona")
+}
+
+func TestWebSocketConfig_CheckOrigin(t *testing.T) {
+	cfg := config.WebSocketConfig{AllowedOrigins: []string{"https://crm.example.com", "https://*.example.org"}}
+	check := func(origin string) bool {
+		r := httptest.NewRequest(http.MethodGet, "http://media.internal:8080/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return cfg.CheckOrigin(r)
+	}
+
+	// 非浏览器客户端和同源请求
+	assert.True(t, check(""))
+	assert.True(t, check("http://media.internal:8080"))
+	// 精确匹配和通配子域名，协议和主机名不区分大小写
+	assert.True(t, check("https://CRM.example.com"))
+	assert.True(t, check("https://agent.example.org"))
+	assert.False(t, check("https://example.org"))
+	assert.False(t, check("http://crm.example.com"))
+	assert.False(t, check("https://crm.example.com.evil.com"))
+	assert.False(t, check("https://evil.com"))
+
+	cfg.DevAllowAllOrigins = true
+	assert.True(t, check("https://evil.com"))
+}
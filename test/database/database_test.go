@@ -0,0 +1,32 @@
+package database_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, database.Config{}.Validate())
+	assert.NoError(t, database.Config{Driver: database.MySQL}.Validate())
+	assert.NoError(t, database.Config{Driver: database.SQLite, SQLite: database.SQLiteConfig{Path: "data/ai_dialer.db"}}.Validate())
+	assert.ErrorContains(t, database.Config{Driver: database.SQLite}.Validate(), "sqlite.path")
+	assert.ErrorContains(t, database.Config{Driver: "postgres"}.Validate(), "driver")
+}
+
+func TestDialect(t *testing.T) {
+	// 零值按MySQL处理
+	for _, d := range []database.Dialect{"", database.MySQL} {
+		assert.Equal(t, "INSERT IGNORE", d.InsertIgnore())
+		assert.Equal(t, " FOR UPDATE", d.ForUpdate())
+		assert.Equal(t, " FOR UPDATE SKIP LOCKED", d.ForUpdateSkipLocked())
+		assert.Equal(t, "TIMESTAMPDIFF(MICROSECOND, a, b)", d.MicrosecondsBetween("a", "b"))
+	}
+
+	assert.Equal(t, "INSERT OR IGNORE", database.SQLite.InsertIgnore())
+	assert.Empty(t, database.SQLite.ForUpdate())
+	assert.Empty(t, database.SQLite.ForUpdateSkipLocked())
+	assert.Contains(t, database.SQLite.MicrosecondsBetween("a", "b"), "julianday(b) - julianday(a)")
+}
@@ -0,0 +1,133 @@
+package encryption_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/encryption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	newKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32)))
+)
+
+func newKeyring(t *testing.T, active string, keys map[string]string) *encryption.Keyring {
+	keyring, err := encryption.New(encryption.Config{
+		Enabled: true,
+		Tenant:  "acme",
+		Tenants: map[string]encryption.TenantKeys{"acme": {Active: active, Keys: keys}},
+	})
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	keyring := newKeyring(t, "k1", map[string]string{"k1": oldKey})
+
+	first, err := keyring.Encrypt("您好，请问是张先生吗")
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(first))
+	assert.True(t, strings.HasPrefix(first, "enc:v1:acme:k1:"))
+	assert.NotContains(t, first, "张先生")
+
+	// 每条数据使用独立的数据密钥，相同明文的密文不同
+	second, err := keyring.Encrypt("您好，请问是张先生吗")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	plaintext, err := keyring.Decrypt(first)
+	require.NoError(t, err)
+	assert.Equal(t, "您好，请问是张先生吗", plaintext)
+
+	// 启用加密前写入的明文原样返回
+	plaintext, err = keyring.Decrypt("历史明文")
+	require.NoError(t, err)
+	assert.Equal(t, "历史明文", plaintext)
+}
+
+func TestKeyring_Tampered(t *testing.T) {
+	keyring := newKeyring(t, "k1", map[string]string{"k1": oldKey})
+	value, err := keyring.Encrypt("你好")
+	require.NoError(t, err)
+
+	// 篡改密文
+	tampered := value[:len(value)-2] + "AA"
+	if tampered == value {
+		tampered = value[:len(value)-2] + "BB"
+	}
+	_, err = keyring.Decrypt(tampered)
+	assert.ErrorIs(t, err, encryption.ErrMalformed)
+
+	// 替换租户或密钥ID
+	_, err = keyring.Decrypt(strings.Replace(value, ":k1:", ":k2:", 1))
+	assert.ErrorIs(t, err, encryption.ErrUnknownKey)
+
+	_, err = keyring.Decrypt("enc:v1:acme:k1:bad")
+	assert.ErrorIs(t, err, encryption.ErrMalformed)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old := newKeyring(t, "k1", map[string]string{"k1": oldKey})
+	value, err := old.Encrypt("你好")
+	require.NoError(t, err)
+
+	// 轮换后新数据使用新密钥，旧数据仍可解密
+	rotated := newKeyring(t, "k2", map[string]string{"k1": oldKey, "k2": newKey})
+	plaintext, err := rotated.Decrypt(value)
+	require.NoError(t, err)
+	assert.Equal(t, "你好", plaintext)
+
+	rewrapped, changed, err := rotated.Rewrap(value)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(rewrapped, "enc:v1:acme:k2:"))
+
+	_, changed, err = rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// 重新加密后可移除旧密钥
+	current := newKeyring(t, "k2", map[string]string{"k2": newKey})
+	plaintext, err = current.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "你好", plaintext)
+	_, err = current.Decrypt(value)
+	assert.ErrorIs(t, err, encryption.ErrUnknownKey)
+
+	// 明文数据重新加密
+	encrypted, changed, err := current.Rewrap("历史明文")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, encryption.IsEncrypted(encrypted))
+}
+
+func TestKeyring_Nil(t *testing.T) {
+	var keyring *encryption.Keyring
+	value, err := keyring.Encrypt("你好")
+	require.NoError(t, err)
+	assert.Equal(t, "你好", value)
+
+	encrypted, err := newKeyring(t, "k1", map[string]string{"k1": oldKey}).Encrypt("你好")
+	require.NoError(t, err)
+	_, err = keyring.Decrypt(encrypted)
+	assert.ErrorIs(t, err, encryption.ErrUnknownKey)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, encryption.Config{}.Validate())
+
+	invalid := []encryption.Config{
+		{Enabled: true, Tenant: "acme"},
+		{Enabled: true, Tenant: "acme", Tenants: map[string]encryption.TenantKeys{"acme": {Active: "k1", Keys: map[string]string{"k1": "c2hvcnQ="}}}},
+		{Enabled: true, Tenant: "acme", Tenants: map[string]encryption.TenantKeys{"acme": {Active: "k2", Keys: map[string]string{"k1": oldKey}}}},
+		{Enabled: true, Tenant: "acme", Tenants: map[string]encryption.TenantKeys{"acme": {Active: "k:1", Keys: map[string]string{"k:1": oldKey}}}},
+	}
+	for _, config := range invalid {
+		assert.Error(t, config.Validate(), "%+v", config)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAnnotationRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	store := repositories.NewStore(db)
+	routes.RegisterAnnotationRoutes(r, handlers.NewAnnotationHandler(services.NewAnnotationService(store)), []string{"secret"})
+	return r, mock
+}
+
+func TestAnnotationHandler_Annotate(t *testing.T) {
+	r, mock := newAnnotationRouter(t)
+	now := time.Now()
+
+	// 标签规范化为小写并去重，与备注在同一事务中写入
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO call_tags").WithArgs("uuid-1", "escalation", "质检员A", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO call_tags").WithArgs("uuid-1", "vip", "质检员A", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO call_notes").WithArgs("uuid-1", "质检员A", "客户要求退款", 42000, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT tag, author, created_at FROM call_tags").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "author", "created_at"}).
+			AddRow("escalation", "质检员A", now).AddRow("vip", "质检员A", now))
+	mock.ExpectQuery("FROM call_notes").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "call_uuid", "author", "note", "offset_ms", "created_at"}).
+			AddRow(3, "uuid-1", "质检员A", "客户要求退款", 42000, now))
+
+	body := `{"tags":["Escalation"," vip ","escalation"],"note":"客户要求退款","offset_ms":42000,"author":"质检员A"}`
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calls/uuid-1/tags", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp models.CallAnnotations
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Tags, 2)
+	require.Len(t, resp.Notes, 1)
+	assert.Equal(t, 42000, *resp.Notes[0].OffsetMs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationHandler_Invalid(t *testing.T) {
+	r, mock := newAnnotationRouter(t)
+
+	for _, body := range []string{
+		`{}`,
+		`{"tags":["  "]}`,
+		`{"note":"备注","offset_ms":-1}`,
+		`{"tags":["` + strings.Repeat("长", models.MaxTagLength+1) + `"]}`,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/calls/uuid-1/tags", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnnotationHandler_RequireToken(t *testing.T) {
+	r, mock := newAnnotationRouter(t)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calls/uuid-1/tags", strings.NewReader(`{"tags":["vip"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,67 @@
+package handlers_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoWSService 模拟WebSocket服务，将会话ID作为识别结果返回
+type echoWSService struct{}
+
+func (echoWSService) HandleConnection(c *gin.Context) {}
+
+func (echoWSService) ProcessAudio(sessionID string, data []byte) (string, error) {
+	return sessionID, nil
+}
+
+// dialASR 连接ASR WebSocket并读取第一条会话消息
+func dialASR(t *testing.T, url string) (*websocket.Conn, models.SessionMessage) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+
+	var msg models.SessionMessage
+	require.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, models.MessageTypeSession, msg.Type)
+	return conn, msg
+}
+
+func TestASRHandler_SessionID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	handlers.NewASRHandler(echoWSService{}).RegisterRoutes(r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/asr"
+
+	// 未提供会话ID时为每个连接生成不同的UUIDv7
+	first, firstMsg := dialASR(t, url)
+	defer first.Close()
+	second, secondMsg := dialASR(t, url)
+	defer second.Close()
+
+	id, err := uuid.Parse(firstMsg.SessionID)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.Version(7), id.Version())
+	assert.NotEqual(t, firstMsg.SessionID, secondMsg.SessionID)
+
+	// 后续消息使用分配的会话ID
+	require.NoError(t, first.WriteMessage(websocket.BinaryMessage, []byte{0, 0}))
+	var result map[string]interface{}
+	require.NoError(t, first.ReadJSON(&result))
+	assert.Equal(t, firstMsg.SessionID, result["text"])
+
+	// 客户端提供的会话ID原样使用
+	resumed, resumedMsg := dialASR(t, url+"?session_id="+firstMsg.SessionID)
+	defer resumed.Close()
+	assert.Equal(t, firstMsg.SessionID, resumedMsg.SessionID)
+}
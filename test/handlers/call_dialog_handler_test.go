@@ -0,0 +1,66 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubCallDialog 模拟通话中对话干预，只有call-1接入了音频流
+type stubCallDialog struct {
+	said     []string
+	injected []string
+}
+
+func (s *stubCallDialog) Say(ctx context.Context, callUUID, text string) error {
+	if callUUID != "call-1" {
+		return fmt.Errorf("%w: %s", ws.ErrCallNotStreaming, callUUID)
+	}
+	s.said = append(s.said, text)
+	return nil
+}
+
+func (s *stubCallDialog) InjectContext(callUUID, text string) error {
+	if callUUID != "call-1" {
+		return fmt.Errorf("%w: %s", ws.ErrCallNotStreaming, callUUID)
+	}
+	s.injected = append(s.injected, text)
+	return nil
+}
+
+func TestCallDialogHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dialog := &stubCallDialog{}
+	r := gin.New()
+	routes.RegisterCallDialogRoutes(r, handlers.NewCallDialogHandler(dialog), []string{"api-token"})
+
+	post := func(path, body, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("/api/v1/calls/call-1/say", `{"text":"您好"}`, ""))
+	assert.Equal(t, http.StatusOK, post("/api/v1/calls/call-1/say", `{"text":"您好"}`, "api-token"))
+	assert.Equal(t, http.StatusOK, post("/api/v1/calls/call-1/inject-context", `{"text":"客户已付款"}`, "api-token"))
+	assert.Equal(t, http.StatusBadRequest, post("/api/v1/calls/call-1/say", `{}`, "api-token"))
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/calls/call-2/say", `{"text":"您好"}`, "api-token"))
+
+	assert.Equal(t, []string{"您好"}, dialog.said)
+	assert.Equal(t, []string{"客户已付款"}, dialog.injected)
+}
@@ -0,0 +1,299 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/fallback"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockCallService 模拟通话服务，记录发起呼叫次数
+type mockCallService struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *mockCallService) InitiateCall(ctx context.Context, fromNumber, toNumber string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	return fmt.Sprintf("uuid-%d", m.calls), nil
+}
+
+func (m *mockCallService) EndCall(ctx context.Context, callID string) error {
+	return nil
+}
+
+func (m *mockCallService) HandleCallEvent(ctx context.Context, eventType string, eventData map[string]string) error {
+	return nil
+}
+
+func newCallRouter(t *testing.T, callService services.CallService) *gin.Engine {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	idempotency := services.NewIdempotencyService(client, time.Hour)
+	routes.RegisterCallRoutes(r, handlers.NewCallHandler(callService, idempotency))
+	return r
+}
+
+func originate(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calls", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCallHandler_OriginateIdempotent(t *testing.T) {
+	callService := &mockCallService{}
+	r := newCallRouter(t, callService)
+
+	first := originate(r, "key-1", `{"from":"1000","to":"1004"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	second := originate(r, "key-1", `{"from":"1000","to":"1004"}`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+
+	var firstCall, secondCall models.CallInfo
+	assert.NoError(t, json.Unmarshal(first.Body.Bytes(), &firstCall))
+	assert.NoError(t, json.Unmarshal(second.Body.Bytes(), &secondCall))
+	assert.Equal(t, firstCall.CallID, secondCall.CallID)
+	assert.Equal(t, 1, callService.calls)
+}
+
+func TestCallHandler_OriginateKeyReusedWithDifferentBody(t *testing.T) {
+	r := newCallRouter(t, &mockCallService{})
+
+	assert.Equal(t, http.StatusCreated, originate(r, "key-2", `{"from":"1000","to":"1004"}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, originate(r, "key-2", `{"from":"1000","to":"1005"}`).Code)
+}
+
+func TestCallHandler_OriginateWithoutKey(t *testing.T) {
+	callService := &mockCallService{}
+	r := newCallRouter(t, callService)
+
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+	assert.Equal(t, 2, callService.calls)
+	assert.Equal(t, http.StatusBadRequest, originate(r, "", `{"from":"1000"}`).Code)
+}
+
+// stubLookup 模拟号码状态查询，指定号码为空号
+type stubLookup struct {
+	disconnected string
+	err          error
+}
+
+func (s stubLookup) Lookup(ctx context.Context, phone string) (models.NumberLookup, error) {
+	if s.err != nil {
+		return models.NumberLookup{}, s.err
+	}
+	if phone == s.disconnected {
+		return models.NumberLookup{Status: models.NumberStatusDisconnected, CheckedAt: time.Now()}, nil
+	}
+	return models.NumberLookup{Status: models.NumberStatusActive, CheckedAt: time.Now()}, nil
+}
+
+func TestCallHandler_OriginateUnreachable(t *testing.T) {
+	callService := &mockCallService{}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	callHandler := handlers.NewCallHandler(callService, nil)
+	callHandler.SetReachability(services.NewReachabilityService(stubLookup{disconnected: "13900000000"}, nil, time.Hour))
+	routes.RegisterCallRoutes(r, callHandler)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, originate(r, "", `{"from":"1000","to":"13900000000"}`).Code)
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"13800000000"}`).Code)
+	assert.Equal(t, 1, callService.calls)
+
+	// 查询服务异常时照常拨打
+	callHandler.SetReachability(services.NewReachabilityService(stubLookup{err: errors.New("timeout")}, nil, time.Hour))
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"13900000000"}`).Code)
+	assert.Equal(t, 2, callService.calls)
+}
+
+// fallbackCallService 支持降级外呼的模拟通话服务
+type fallbackCallService struct {
+	mockCallService
+	policies []fallback.Policy
+}
+
+func (m *fallbackCallService) InitiateFallbackCall(ctx context.Context, callUUID, toNumber string, policy fallback.Policy) (string, error) {
+	m.policies = append(m.policies, policy)
+	return "uuid-fallback", nil
+}
+
+// stubLeads 记录回拨安排
+type stubLeads struct {
+	id   int64
+	next *time.Time
+}
+
+func (s *stubLeads) UpdateStatus(ctx context.Context, id int64, status string, nextAttemptAt *time.Time) error {
+	s.id, s.next = id, nextAttemptAt
+	return nil
+}
+
+func TestCallHandler_OriginateASRFallback(t *testing.T) {
+	callService := &fallbackCallService{}
+	leads := &stubLeads{}
+	monitor := fallback.NewMonitor(fallback.Config{
+		FailureThreshold: 1,
+		Default:          fallback.Policy{Action: fallback.ActionPause},
+		Campaigns: map[string]fallback.Policy{
+			"1": {Action: fallback.ActionHuman, Queue: "8000"},
+			"2": {Action: fallback.ActionApology, Message: "/sounds/sorry.wav", CallbackDelay: 2 * time.Hour},
+		},
+	}, nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	callHandler := handlers.NewCallHandler(callService, nil)
+	callHandler.SetASRFallback(monitor, leads)
+	routes.RegisterCallRoutes(r, callHandler)
+
+	// 识别服务可用时照常拨打
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+	assert.Equal(t, 1, callService.calls)
+
+	monitor.ReportFailure(errors.New("connection refused"))
+	assert.Equal(t, http.StatusServiceUnavailable, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+
+	w := originate(r, "", `{"from":"1000","to":"1004","campaign_id":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var call models.CallInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &call))
+	assert.Equal(t, fallback.ActionHuman, call.Fallback)
+
+	before := time.Now()
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004","campaign_id":2,"lead_id":7}`).Code)
+	assert.Equal(t, int64(7), leads.id)
+	if assert.NotNil(t, leads.next) {
+		assert.WithinDuration(t, before.Add(2*time.Hour), *leads.next, time.Minute)
+	}
+	assert.Len(t, callService.policies, 2)
+	assert.Equal(t, 1, callService.calls)
+
+	// 识别恢复后照常拨打
+	monitor.ReportSuccess()
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004","campaign_id":1}`).Code)
+	assert.Equal(t, 2, callService.calls)
+}
+
+// controlCallService 模拟支持通话中控制的通话服务，按UUID返回FreeSWITCH命令错误
+type controlCallService struct {
+	mockCallService
+	commands []string
+	errs     map[string]error
+}
+
+func (m *controlCallService) EndCall(ctx context.Context, callID string) error {
+	m.commands = append(m.commands, "hangup "+callID)
+	return m.errs[callID]
+}
+
+func (m *controlCallService) TransferCall(ctx context.Context, callUUID, destination, dialplanContext string) error {
+	m.commands = append(m.commands, "transfer "+callUUID+" "+destination+" "+dialplanContext)
+	return m.errs[callUUID]
+}
+
+func (m *controlCallService) SendDTMF(ctx context.Context, callUUID, digits string, duration time.Duration) error {
+	m.commands = append(m.commands, fmt.Sprintf("dtmf %s %s %v", callUUID, digits, duration))
+	return m.errs[callUUID]
+}
+
+func (m *controlCallService) SetDisposition(ctx context.Context, callUUID, disposition string) error {
+	m.commands = append(m.commands, "disposition "+callUUID+" "+disposition)
+	return m.errs[callUUID]
+}
+
+func (m *controlCallService) CompleteWrapUp(ctx context.Context, callUUID string) error {
+	m.commands = append(m.commands, "wrapup "+callUUID)
+	return m.errs[callUUID]
+}
+
+func TestCallHandler_CallControl(t *testing.T) {
+	callService := &controlCallService{errs: map[string]error{
+		"gone":    fmt.Errorf("挂断失败: %w: No such channel!", services.ErrCallNotFound),
+		"bad":     fmt.Errorf("%w: 通话UUID格式错误", services.ErrInvalidCallCommand),
+		"offline": fmt.Errorf("%w: 未连接", services.ErrSwitchUnavailable),
+		"reject":  fmt.Errorf("%w: INVALID ARGS", services.ErrCommandRejected),
+		"talking": services.ErrNotInWrapUp,
+	}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterCallControlRoutes(r, handlers.NewCallHandler(callService, nil), []string{"secret"})
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodDelete, "/api/v1/calls/uuid-1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"call_id":"uuid-1"}`, w.Body.String())
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/calls/uuid-1/transfer", `{"destination":"1005"}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/calls/uuid-1/dtmf", `{"digits":"12#","duration_ms":200}`).Code)
+	assert.Equal(t, []string{"hangup uuid-1", "transfer uuid-1 1005 ", "dtmf uuid-1 12# 200ms"}, callService.commands)
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/calls/uuid-1/transfer", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/calls/uuid-1/dtmf", `{"digits":""}`).Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/calls/gone", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/calls/bad/dtmf", `{"digits":"1"}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodPost, "/api/v1/calls/offline/transfer", `{"destination":"1005"}`).Code)
+	assert.Equal(t, http.StatusBadGateway, do(http.MethodPost, "/api/v1/calls/reject/transfer", `{"destination":"1005"}`).Code)
+
+	// 话后处理期间修改通话结果、提前结束话后处理
+	callService.commands = nil
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, "/api/v1/calls/uuid-1/disposition", `{"disposition":"interested"}`).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/api/v1/calls/uuid-1/wrapup", "").Code)
+	assert.Equal(t, []string{"disposition uuid-1 interested", "wrapup uuid-1"}, callService.commands)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/api/v1/calls/uuid-1/disposition", `{}`).Code)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/api/v1/calls/talking/wrapup", "").Code)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/calls/uuid-1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestCallHandler_CallControlNotSupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterCallControlRoutes(r, handlers.NewCallHandler(&mockCallService{}, nil), []string{"secret"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/calls/uuid-1/dtmf", strings.NewReader(`{"digits":"1"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
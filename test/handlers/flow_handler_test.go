@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/flow"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func flowRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/flows/validate", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestFlowHandler_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterFlowRoutes(r, handlers.NewFlowHandler(flow.NewLinter(nil)), []string{"secret"})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, flowRequest(`
+dtmf:
+  enabled: true
+  menus:
+    confirm:
+      triggers: ["确认请按1"]
+      options:
+        "12": {input: "确认"}
+`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report flow.Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(t, report.Valid)
+	require.NotEmpty(t, report.Diagnostics)
+	assert.Equal(t, "dtmf.menus.confirm.options.12", report.Diagnostics[0].Path)
+	assert.Equal(t, flow.CodeInvalidTransition, report.Diagnostics[0].Code)
+
+	// JSON格式
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, flowRequest(`{"turn": {"hold_reply": "好的"}}`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"valid":true`)
+
+	// 未知字段和空请求
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, flowRequest("dtmf:\n  menu: {}\n"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, flowRequest(""))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestFlowHandler_Export(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterFlowRoutes(r, handlers.NewFlowHandler(flow.NewLinter(nil)), []string{"secret"})
+	body := "intent:\n  enabled: true\n  rules:\n    - {name: agent, keywords: [转人工], action: transfer, destination: \"1000\"}\n"
+
+	exportRequest := func(format string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/flows/export?format="+format, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, exportRequest(""))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "flowchart TD")
+	assert.Contains(t, w.Body.String(), `(("转人工 1000"))`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, exportRequest("graphviz"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "digraph flow")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, exportRequest("json"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph flow.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	assert.Len(t, graph.Nodes, 3)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, exportRequest("svg"))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,66 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/lifecycle"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLifecycleHandler_ReadyAndDrain(t *testing.T) {
+	manager := lifecycle.New(lifecycle.Config{})
+	manager.Track("calls", func() int { return 1 })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterLifecycleRoutes(r, handlers.NewLifecycleHandler(manager), []string{"secret"})
+	do := func(method, url string, token bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		if token {
+			req.Header.Set("Authorization", "Bearer secret")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/ready", false).Code)
+	manager.MarkReady()
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/ready", false).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/v1/admin/drain", false).Code)
+	w := do(http.MethodPost, "/api/v1/admin/drain", true)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var status lifecycle.Status
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, lifecycle.StateDraining, status.State)
+	assert.Equal(t, 1, status.Active["calls"])
+
+	assert.Equal(t, http.StatusServiceUnavailable, do(http.MethodGet, "/ready", false).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/admin/drain", true).Code)
+}
+
+func TestCallHandler_OriginateRejectedWhileDraining(t *testing.T) {
+	callService := &mockCallService{}
+	manager := lifecycle.New(lifecycle.Config{})
+	manager.MarkReady()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	callHandler := handlers.NewCallHandler(callService, nil)
+	callHandler.SetLifecycle(manager)
+	routes.RegisterCallRoutes(r, callHandler)
+
+	assert.Equal(t, http.StatusCreated, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+	manager.Drain()
+	assert.Equal(t, http.StatusServiceUnavailable, originate(r, "", `{"from":"1000","to":"1004"}`).Code)
+	assert.Equal(t, 1, callService.calls)
+}
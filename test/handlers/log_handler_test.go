@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/logger"
+	"ai_dialer_mini/internal/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelHandler_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Configure(logger.Config{})
+	t.Cleanup(func() { logger.Configure(logger.Config{}) })
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 8080\nlogging:\n  level: info\n"), 0o644))
+	r := gin.New()
+	routes.RegisterAdminRoutes(r, handlers.NewLogLevelHandler(path), []string{"ops-token"})
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer ops-token")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未携带令牌的请求被拒绝
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/admin/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	level, _ := logger.Levels()
+	assert.Equal(t, "info", level)
+
+	w = put(`{"level":"verbose"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = put(`{"subsystems":{"esl.event":"debug"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, logger.Sampled("esl.event").Enabled(logger.LevelDebug))
+
+	// 写回配置文件，重启后仍然生效
+	w = put(`{"level":"warn","persist":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Level      string            `json:"level"`
+		Subsystems map[string]string `json:"subsystems"`
+		Persisted  bool              `json:"persisted"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "warn", resp.Level)
+	assert.Equal(t, map[string]string{"esl.event": "debug"}, resp.Subsystems)
+	assert.True(t, resp.Persisted)
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Logging.Level)
+	assert.Equal(t, map[string]string{"esl.event": "debug"}, cfg.Logging.Levels)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/loglevel", nil)
+	req.Header.Set("Authorization", "Bearer ops-token")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"level":"warn"`)
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/promptaudio"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPromptAudioRouter(t *testing.T) *gin.Engine {
+	objects, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	library := promptaudio.New(objects, promptaudio.Config{Enabled: true})
+	routes.RegisterPromptAudioRoutes(r, handlers.NewPromptAudioHandler(library), []string{"secret"})
+	return r
+}
+
+func promptUploadRequest(t *testing.T, name, text string, file []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	require.NoError(t, form.WriteField("name", name))
+	require.NoError(t, form.WriteField("text", text))
+	part, err := form.CreateFormFile("file", "prompt.wav")
+	require.NoError(t, err)
+	_, err = part.Write(file)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/prompts/audio", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestPromptAudioHandler_UploadAndDownload(t *testing.T) {
+	r := newPromptAudioRouter(t)
+	wav := (&tts.Audio{PCM: make([]byte, 22050*2), SampleRate: 22050, Channels: 1}).WAV()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, promptUploadRequest(t, "greeting", "您好，这里是客服中心", wav))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var prompt promptaudio.Prompt
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prompt))
+	assert.Equal(t, "greeting", prompt.Name)
+	assert.Equal(t, int64(1000), prompt.DurationMs)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/prompts/audio/greeting/audio?rate=8000"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	audio, err := pcm.DecodeWAV(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 8000, audio.SampleRate)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/prompts/audio"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodDelete, "/api/v1/prompts/audio/greeting"))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/prompts/audio/greeting"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPromptAudioHandler_RejectsInvalidAudio(t *testing.T) {
+	r := newPromptAudioRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, promptUploadRequest(t, "greeting", "您好", []byte("ID3 mp3 data")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req := promptUploadRequest(t, "greeting", "您好", nil)
+	req.Header.Del("Authorization")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var recordingColumns = []string{"call_uuid", "object_key", "content_type", "size", "channels", "duration_ms", "caller", "callee",
+	"started_at", "answered_at", "ended_at", "archived_at"}
+
+func newRecordingRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock, storage.Store) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	objects, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	service := services.NewRecordingService(repositories.NewStore(db), objects)
+	routes.RegisterRecordingRoutes(r, handlers.NewRecordingHandler(service), []string{"secret"})
+	return r, mock, objects
+}
+
+func recordingRequest(method, url string) *http.Request {
+	req := httptest.NewRequest(method, url, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestRecordingHandler_List(t *testing.T) {
+	r, mock, _ := newRecordingRouter(t)
+	now := time.Now()
+
+	mock.ExpectQuery("FROM recordings WHERE ended_at >= \\? AND \\(caller = \\? OR callee = \\?\\) ORDER BY ended_at DESC").
+		WithArgs(sqlmock.AnyArg(), "13812345678", "13812345678", 20, 0).
+		WillReturnRows(sqlmock.NewRows(recordingColumns).
+			AddRow("uuid-1", "recordings/uuid-1.wav", "audio/wav", 64000, 2, 2000, "4001", "13812345678", now, now, now, now))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/recordings?from=2026-10-01&number=13812345678"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Recordings []map[string]interface{} `json:"recordings"`
+		Count      int                      `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Count)
+	assert.Equal(t, "13812345678", resp.Recordings[0]["callee"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordingHandler_Download(t *testing.T) {
+	r, mock, objects := newRecordingRouter(t)
+	now := time.Now()
+	require.NoError(t, objects.Put(context.Background(), "recordings/uuid-1.wav", []byte("RIFF-test"), "audio/wav"))
+
+	mock.ExpectQuery("FROM recordings WHERE call_uuid = \\?").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows(recordingColumns).
+			AddRow("uuid-1", "recordings/uuid-1.wav", "audio/wav", 9, 0, 0, "4001", "13812345678", now, nil, now, now))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/recordings/uuid-1/audio"))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio/wav", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "uuid-1.wav")
+	assert.Equal(t, "RIFF-test", w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordingHandler_NotFoundAndAuth(t *testing.T) {
+	r, mock, _ := newRecordingRouter(t)
+	mock.ExpectQuery("FROM recordings WHERE call_uuid = \\?").WithArgs("uuid-2").
+		WillReturnRows(sqlmock.NewRows(recordingColumns))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, recordingRequest(http.MethodGet, "/api/v1/recordings/uuid-2"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 未携带令牌时拒绝访问
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/recordings", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
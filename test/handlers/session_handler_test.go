@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSessionRouter 创建使用模拟大模型的会话路由
+func newSessionRouter(t *testing.T) (*gin.Engine, *services.DialogService) {
+	gin.SetMode(gin.TestMode)
+	temperature := 0.2
+	dialogService := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Options:  models.GenerationOptions{Temperature: 0.7, MaxTokens: 1024},
+			Campaigns: map[string]models.GenerationOverrides{
+				"42": {Temperature: &temperature},
+			},
+		},
+	})
+	r := gin.New()
+	routes.RegisterSessionRoutes(r, handlers.NewSessionHandler(dialogService))
+	return r, dialogService
+}
+
+func TestSessionHandler_UpdateOptions(t *testing.T) {
+	r, dialogService := newSessionRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/sessions/s1/options",
+		strings.NewReader(`{"campaign_id":"42","overrides":{"max_tokens":256}}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var options models.SessionOptions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &options))
+	assert.Equal(t, models.GenerationOptions{Temperature: 0.2, MaxTokens: 256}, options.Effective)
+
+	// 每轮回复记录实际使用的参数
+	_, err := dialogService.ProcessMessage("s1", "你好")
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sessions/s1/history", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		History []models.Message `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.History, 2)
+	assert.Nil(t, body.History[0].Options)
+	require.NotNil(t, body.History[1].Options)
+	assert.Equal(t, options.Effective, *body.History[1].Options)
+}
+
+func TestSessionHandler_UpdateOptionsOutOfRange(t *testing.T) {
+	r, _ := newSessionRouter(t)
+
+	for _, body := range []string{
+		`{"overrides":{"temperature":3}}`,
+		`{"overrides":{"max_tokens":100000}}`,
+		`{"overrides":{"top_p":-0.1}}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/sessions/s1/options", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	// 无效参数不会生效
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/sessions/s1/options", nil))
+	var options models.SessionOptions
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &options))
+	assert.Equal(t, models.GenerationOptions{Temperature: 0.7, MaxTokens: 1024}, options.Effective)
+}
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/tap"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTapHandler_Listen(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audioTap := tap.New(tap.Config{})
+	r := gin.New()
+	routes.RegisterTapRoutes(r, handlers.NewTapHandler(audioTap), []string{"secret"})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/calls/call-1/tap"
+
+	// 未携带令牌或令牌错误时拒绝
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=wrong", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// 无效的通道
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=secret&leg=agent", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": []string{"Bearer secret"}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 订阅在连接建立后才生效，持续发布音频直到收到第一帧
+	received := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-received:
+				return
+			case <-ticker.C:
+				audioTap.Publish("call-1", tap.LegCustomer, 16000, []byte{1, 2, 3, 4})
+			}
+		}
+	}()
+	defer close(received)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var format handlers.TapFormat
+	require.NoError(t, conn.ReadJSON(&format))
+	assert.Equal(t, handlers.TapFormat{Type: "format", Leg: tap.LegCustomer, Encoding: "pcm_s16le", SampleRate: 16000, Channels: 1}, format)
+
+	messageType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, []byte{1, 2, 3, 4}, data)
+}
@@ -0,0 +1,253 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/handlers"
+	"ai_dialer_mini/internal/lang"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTranscriptRouter(t *testing.T) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	store := repositories.NewStore(db)
+	routes.RegisterTranscriptRoutes(r, handlers.NewTranscriptHandler(services.NewTranscriptService(store)), []string{"secret"})
+	return r, mock
+}
+
+// transcriptRequest 携带接口令牌的请求
+func transcriptRequest(method, url string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, url, body)
+	req.Header.Set("Authorization", "Bearer secret")
+	return req
+}
+
+func TestTranscriptRoutes_RequireToken(t *testing.T) {
+	r, _ := newTranscriptRouter(t)
+	for _, url := range []string{"/api/v1/transcripts/search?q=test", "/api/v1/calls/call-1/transcript", "/api/v1/calls/call-1/sentiment"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, url)
+	}
+}
+
+func TestTranscriptSearch(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+	now := time.Now()
+	from, _ := time.ParseInLocation("2006-01-02", "2024-05-01", time.Local)
+
+	mock.ExpectQuery(`SELECT (.+) FROM transcripts t LEFT JOIN calls c (.+) AND t.created_at >= \? AND c.campaign_id = \? AND c.disposition = \?`).
+		WithArgs(`"不需要了"`, `"不需要了"`, from, int64(5), "NO_ANSWER", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at",
+			"campaign_id", "disposition", "score"}).
+			AddRow(1, "uuid-1", "customer", "我不需要了谢谢", 1000, 2500, "zh", now, 5, "NO_ANSWER", 1.5))
+
+	w := httptest.NewRecorder()
+	req := transcriptRequest(http.MethodGet,
+		"/api/v1/transcripts/search?q=%E4%B8%8D%E9%9C%80%E8%A6%81%E4%BA%86&from=2024-05-01&campaign_id=5&disposition=NO_ANSWER", nil)
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Results []struct {
+			CallUUID   string `json:"call_uuid"`
+			CampaignID int64  `json:"campaign_id"`
+			Text       string `json:"text"`
+		} `json:"results"`
+		Count int `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Count)
+	assert.Equal(t, "uuid-1", resp.Results[0].CallUUID)
+	assert.Equal(t, int64(5), resp.Results[0].CampaignID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTranscriptSearch_InvalidQuery(t *testing.T) {
+	r, _ := newTranscriptRouter(t)
+
+	for _, url := range []string{
+		"/api/v1/transcripts/search?q=a",
+		"/api/v1/transcripts/search?q=hello&from=yesterday",
+		"/api/v1/transcripts/search?q=hello&campaign_id=abc",
+		"/api/v1/transcripts/search?q=hello&from=2024-05-02&to=2024-05-01",
+		"/api/v1/transcripts/search?q=hello&romanize=latin",
+		"/api/v1/transcripts/search?q=hello&romanize=pinyin", // 未配置拼音字典
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, transcriptRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestTranscriptSearch_ByTag(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+
+	mock.ExpectQuery(`FROM transcripts t (.+) AND EXISTS \(SELECT 1 FROM call_tags ct WHERE ct.call_uuid = t.call_uuid AND ct.tag = \?\)`).
+		WithArgs(`"投诉"`, `"投诉"`, "escalation", 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at",
+			"campaign_id", "disposition", "score"}))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/transcripts/search?q=%E6%8A%95%E8%AF%89&tag=escalation", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTranscriptSearch_Romanize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	pinyin, err := lang.ParsePinyin(strings.NewReader("U+4E0D: bù\nU+9700: xū\nU+8981: yào\nU+4E86: le\n"))
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	transcriptService := services.NewTranscriptService(repositories.NewStore(db))
+	transcriptService.SetPinyin(pinyin)
+	routes.RegisterTranscriptRoutes(r, handlers.NewTranscriptHandler(transcriptService), []string{"secret"})
+
+	now := time.Now()
+	mock.ExpectQuery("FROM transcripts t").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at",
+			"campaign_id", "disposition", "score"}).
+			AddRow(1, "uuid-1", "customer", "不需要了", 0, 900, "zh", now, nil, "", 1.2).
+			AddRow(2, "uuid-2", "customer", "No, 不需要", 0, 900, "en", now, nil, "", 1.0).
+			AddRow(3, "uuid-3", "customer", "不需要", 0, 900, "", now, nil, "", 0.8))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/transcripts/search?q=%E4%B8%8D%E9%9C%80%E8%A6%81&romanize=pinyin", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Results []struct {
+			Language  string `json:"language"`
+			Romanized string `json:"romanized"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "bù xū yào le", resp.Results[0].Romanized)
+	// 非中文片段不附加拼音，未识别语种的历史片段按文本识别
+	assert.Empty(t, resp.Results[1].Romanized)
+	assert.Equal(t, "bù xū yào", resp.Results[2].Romanized)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var timelineColumns = []string{"id", "call_uuid", "speaker", "kind", "text", "start_ms", "end_ms", "language", "sentiment", "created_at"}
+
+func expectTimeline(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery(`SELECT id, call_uuid, speaker, kind, text, start_ms, end_ms, language, sentiment, created_at FROM transcripts\s+WHERE call_uuid = \?`).
+		WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(timelineColumns).
+			AddRow(1, "call-1", "system", "event", "通话接通", 0, 0, "", "", now).
+			AddRow(2, "call-1", "ai", "speech", "您好，这里是客服中心", 200, 2500, "zh", "", now).
+			AddRow(3, "call-1", "customer", "speech", "你好", 3000, 3600, "zh", "", now).
+			AddRow(4, "call-1", "customer", "dtmf", "1", 65432, 65432, "", "", now).
+			AddRow(5, "call-1", "system", "event", "通话挂断: NORMAL_CLEARING", 70000, 70000, "", "", now))
+}
+
+func TestTranscriptExport_JSON(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+	expectTimeline(mock)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/calls/call-1/transcript?from=1s&to=70s&speaker=customer,ai", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body struct {
+		CallUUID    string `json:"call_uuid"`
+		Count       int    `json:"count"`
+		Transcripts []struct {
+			Speaker string `json:"speaker"`
+			Kind    string `json:"kind"`
+			Text    string `json:"text"`
+		} `json:"transcripts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "call-1", body.CallUUID)
+	require.Equal(t, 2, body.Count)
+	assert.Equal(t, "你好", body.Transcripts[0].Text)
+	assert.Equal(t, "dtmf", body.Transcripts[1].Kind)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTranscriptExport_Text(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+	expectTimeline(mock)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/calls/call-1/transcript?format=text", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "[00:00.000] 系统: 通话接通\n"+
+		"[00:00.200] AI: 您好，这里是客服中心\n"+
+		"[00:03.000] 客户: 你好\n"+
+		"[01:05.432] 客户(按键): 1\n"+
+		"[01:10.000] 系统: 通话挂断: NORMAL_CLEARING\n", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+}
+
+func TestTranscriptExport_Errors(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+	for _, url := range []string{
+		"/api/v1/calls/call-1/transcript?format=csv",
+		"/api/v1/calls/call-1/transcript?from=abc",
+		"/api/v1/calls/call-1/transcript?from=10s&to=5s",
+		"/api/v1/calls/call-1/transcript?speaker=robot",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, transcriptRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	mock.ExpectQuery("FROM transcripts").WithArgs("call-2").WillReturnRows(sqlmock.NewRows(timelineColumns))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/calls/call-2/transcript", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTranscriptSentiment(t *testing.T) {
+	r, mock := newTranscriptRouter(t)
+	mock.ExpectQuery(`SELECT sentiment FROM transcripts\s+WHERE call_uuid = \? AND speaker = 'customer'`).
+		WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows([]string{"sentiment"}).
+			AddRow("neutral").AddRow("negative").AddRow("negative"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/calls/call-1/sentiment", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var summary models.SentimentSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, "call-1", summary.CallUUID)
+	assert.Equal(t, 3, summary.Utterances)
+	assert.Equal(t, 2, summary.Negative)
+	assert.Equal(t, models.SentimentNegative, summary.Overall)
+	assert.True(t, summary.FollowUp)
+
+	mock.ExpectQuery("SELECT sentiment FROM transcripts").WithArgs("call-2").WillReturnRows(sqlmock.NewRows([]string{"sentiment"}))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, transcriptRequest(http.MethodGet, "/api/v1/calls/call-2/sentiment", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
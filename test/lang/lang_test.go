@@ -0,0 +1,73 @@
+package lang_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/lang"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDict = `# pinyin-data 格式
+U+4F60: nǐ  # 你
+U+597D: hǎo,hào  # 好
+U+8BF7: qǐng  # 请
+U+95EE: wèn  # 问
+
+U+9700: xū  # 需
+U+8981: yào,yāo  # 要
+`
+
+func TestDetect(t *testing.T) {
+	cases := map[string]string{
+		"你好，请问是张先生吗？":               lang.Chinese,
+		"我想买iPhone":                 lang.Chinese,
+		"Hello, is this Mr. Zhang?": lang.English,
+		"Please hold on 一下":         lang.English,
+		"もしもし、田中です":                 lang.Japanese,
+		"여보세요":                      lang.Korean,
+		"12345，。":                   "",
+		"":                          "",
+	}
+	for text, want := range cases {
+		assert.Equal(t, want, lang.Detect(text), text)
+	}
+}
+
+func TestPinyin_Romanize(t *testing.T) {
+	pinyin, err := lang.ParsePinyin(strings.NewReader(testDict))
+	require.NoError(t, err)
+
+	assert.Equal(t, "nǐ hǎo，qǐng wèn", pinyin.Romanize("你好，请问"))
+	// 多音字取第一个读音，与字母数字之间加空格，字典中没有的汉字原样保留
+	assert.Equal(t, "不 xū yào VIP3个", pinyin.Romanize("不需要VIP3个"))
+	assert.Equal(t, "Hello", pinyin.Romanize("Hello"))
+
+	var nilPinyin *lang.Pinyin
+	assert.Empty(t, nilPinyin.Romanize("你好"))
+}
+
+func TestParsePinyin_Invalid(t *testing.T) {
+	_, err := lang.ParsePinyin(strings.NewReader("# 只有注释\n"))
+	assert.Error(t, err)
+	_, err = lang.ParsePinyin(strings.NewReader("4F60 nǐ\n"))
+	assert.Error(t, err)
+	_, err = lang.ParsePinyin(strings.NewReader("U+XYZ: nǐ\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadPinyin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pinyin.txt")
+	require.NoError(t, os.WriteFile(path, []byte(testDict), 0644))
+
+	pinyin, err := lang.LoadPinyin(path)
+	require.NoError(t, err)
+	assert.Equal(t, "nǐ hǎo", pinyin.Romanize("你好"))
+
+	_, err = lang.LoadPinyin(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,54 @@
+package logger_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampler_Levels(t *testing.T) {
+	buf := captureLog(t)
+	logger.Configure(logger.Config{Level: "warn", Levels: map[string]string{"test.debug": "debug"}})
+	t.Cleanup(func() { logger.Configure(logger.Config{}) })
+
+	quiet := logger.Sampled("test.quiet")
+	quiet.Debugf("quiet debug")
+	quiet.Printf("quiet info")
+	quiet.Warnf("quiet warn")
+	quiet.Errorf("quiet error")
+
+	verbose := logger.Sampled("test.debug")
+	verbose.Debugf("verbose debug")
+
+	assert.Equal(t, []string{"quiet warn", "quiet error", "verbose debug"}, lines(buf))
+}
+
+func TestSetLevels(t *testing.T) {
+	logger.Configure(logger.Config{})
+	t.Cleanup(func() { logger.Configure(logger.Config{}) })
+	s := logger.Sampled("test.runtime")
+	assert.False(t, s.Enabled(logger.LevelDebug))
+
+	// 运行时调低单个子系统的级别，已创建的采样器立即生效
+	require.NoError(t, logger.SetLevels("", map[string]string{"test.runtime": "DEBUG"}))
+	assert.True(t, s.Enabled(logger.LevelDebug))
+	level, levels := logger.Levels()
+	assert.Equal(t, "info", level)
+	assert.Equal(t, map[string]string{"test.runtime": "debug"}, levels)
+
+	// 值为空时恢复使用全局级别
+	require.NoError(t, logger.SetLevels("error", map[string]string{"test.runtime": ""}))
+	assert.False(t, s.Enabled(logger.LevelWarn))
+	level, levels = logger.Levels()
+	assert.Equal(t, "error", level)
+	assert.Empty(t, levels)
+
+	// 无效级别不修改当前配置
+	assert.Error(t, logger.SetLevels("verbose", nil))
+	assert.Error(t, logger.SetLevels("", map[string]string{"test.runtime": "trace"}))
+	level, _ = logger.Levels()
+	assert.Equal(t, "error", level)
+}
@@ -0,0 +1,80 @@
+package logger_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLog 捕获标准日志输出
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+func lines(buf *bytes.Buffer) []string {
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestSampler_Every(t *testing.T) {
+	buf := captureLog(t)
+	logger.Configure(logger.Config{Sampling: map[string]logger.SampleConfig{
+		"test.every": {Every: 5},
+	}})
+
+	s := logger.Sampled("test.every")
+	for i := 0; i < 12; i++ {
+		s.Printf("frame %d", i)
+	}
+
+	out := lines(buf)
+	assert.Equal(t, []string{
+		"frame 0",
+		"frame 5 (已省略4条[test.every]日志)",
+		"frame 10 (已省略4条[test.every]日志)",
+	}, out)
+}
+
+func TestSampler_ErrorsAlwaysLogged(t *testing.T) {
+	buf := captureLog(t)
+	logger.Configure(logger.Config{Sampling: map[string]logger.SampleConfig{
+		"test.errors": {Every: 100},
+	}})
+
+	s := logger.Sampled("test.errors")
+	for i := 0; i < 3; i++ {
+		s.Errorf("发送失败 %d", i)
+	}
+	assert.Len(t, lines(buf), 3)
+}
+
+func TestSampler_IntervalAndDefault(t *testing.T) {
+	buf := captureLog(t)
+	logger.Configure(logger.Config{Sampling: map[string]logger.SampleConfig{
+		logger.DefaultSubsystem: {Interval: time.Hour},
+	}})
+
+	s := logger.Sampled("test.unconfigured")
+	s.Printf("first")
+	s.Printf("second")
+	assert.Equal(t, []string{"first"}, lines(buf))
+
+	// 重新配置后立即生效
+	logger.Configure(logger.Config{})
+	s.Printf("third")
+	assert.Equal(t, []string{"first", "third (已省略1条[test.unconfigured]日志)"}, lines(buf))
+}
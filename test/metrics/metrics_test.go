@@ -0,0 +1,70 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec_Write(t *testing.T) {
+	counter := metrics.NewCounterVec("test_requests_total", "测试请求数", "method", "path")
+	counter.Inc("GET", "/a")
+	counter.Inc("GET", "/a")
+	counter.Add(2.5, "POST", `/b"c`)
+	counter.Add(-1, "GET", "/a") // 计数器不能减少
+
+	assert.Equal(t, float64(2), counter.Value("GET", "/a"))
+	assert.Equal(t, float64(0), counter.Value("PUT", "/a"))
+
+	var out strings.Builder
+	require.NoError(t, counter.Write(&out))
+	assert.Equal(t, "# HELP test_requests_total 测试请求数\n"+
+		"# TYPE test_requests_total counter\n"+
+		"test_requests_total{method=\"GET\",path=\"/a\"} 2\n"+
+		"test_requests_total{method=\"POST\",path=\"/b\\\"c\"} 2.5\n", out.String())
+
+	// 标签值数量不一致
+	assert.Panics(t, func() { counter.Inc("GET") })
+}
+
+func TestGaugeVec_Write(t *testing.T) {
+	gauge := metrics.NewGaugeVec("test_leads_remaining", "测试剩余线索数", "campaign_id")
+	gauge.Set(10, "1")
+	gauge.Set(3, "1")
+	gauge.Set(0.25, "2")
+	gauge.Set(7, "3")
+	gauge.Delete("3")
+
+	assert.Equal(t, float64(3), gauge.Value("1"))
+
+	var out strings.Builder
+	require.NoError(t, gauge.Write(&out))
+	assert.Equal(t, "# HELP test_leads_remaining 测试剩余线索数\n"+
+		"# TYPE test_leads_remaining gauge\n"+
+		"test_leads_remaining{campaign_id=\"1\"} 3\n"+
+		"test_leads_remaining{campaign_id=\"2\"} 0.25\n", out.String())
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	registry := metrics.NewRegistry()
+	b := metrics.NewCounterVec("test_b_total", "b")
+	a := metrics.NewCounterVec("test_a_total", "a")
+	registry.MustRegister(b)
+	registry.MustRegister(a)
+	a.Inc()
+	assert.Panics(t, func() { registry.MustRegister(a) })
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "version=0.0.4")
+	// 按名称排序输出
+	assert.Equal(t, "# HELP test_a_total a\n# TYPE test_a_total counter\ntest_a_total 1\n"+
+		"# HELP test_b_total b\n# TYPE test_b_total counter\n", w.Body.String())
+}
@@ -0,0 +1,86 @@
+package migrations_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/sqlite"
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	list, err := migrations.Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, list)
+
+	for i, m := range list {
+		assert.Equal(t, i+1, m.Version, "迁移版本号应连续")
+		assert.NotEmpty(t, m.Up, "版本 %d 缺少升级脚本", m.Version)
+		assert.NotEmpty(t, m.Down, "版本 %d 缺少回滚脚本", m.Version)
+		assert.NotEmpty(t, migrations.SplitStatements(m.Up))
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- 注释
+CREATE TABLE a (
+	id INT
+);
+
+DROP TABLE b;
+SELECT 1`
+
+	stmts := migrations.SplitStatements(script)
+	require.Len(t, stmts, 3)
+	assert.Equal(t, "CREATE TABLE a (\n\tid INT\n)", stmts[0])
+	assert.Equal(t, "DROP TABLE b", stmts[1])
+	assert.Equal(t, "SELECT 1", stmts[2])
+}
+
+func TestLoadDialect_SQLite(t *testing.T) {
+	mysqlList, err := migrations.Load()
+	require.NoError(t, err)
+	sqliteList, err := migrations.LoadDialect(database.SQLite)
+	require.NoError(t, err)
+
+	// 两种方言的迁移版本和名称一一对应
+	require.Len(t, sqliteList, len(mysqlList))
+	for i, m := range sqliteList {
+		assert.Equal(t, mysqlList[i].Version, m.Version)
+		assert.Equal(t, mysqlList[i].Name, m.Name)
+		assert.NotEmpty(t, m.Down, "版本 %d 缺少回滚脚本", m.Version)
+	}
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "ai_dialer.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	migrator, err := migrations.NewWithDialect(db, database.SQLite)
+	require.NoError(t, err)
+	require.Error(t, migrator.Verify(ctx))
+
+	count, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), count)
+	require.NoError(t, migrator.Verify(ctx))
+
+	// 全部回滚后可以重新迁移
+	count, err = migrator.Down(ctx, migrator.Latest())
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), count)
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.Current)
+
+	count, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), count)
+}
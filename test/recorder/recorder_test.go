@@ -0,0 +1,107 @@
+package recorder_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/recorder"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	payload := []byte(`{"common":{"app_id":"test_app_id"},"api_key":"test_api_key","data":{"audio":"AAAAAQID","status":1},` +
+		`"url":"wss://iat-api.xfyun.cn/v2/iat?authorization=YXBpX2tleT0&date=Mon&host=iat"}`)
+
+	out := string(recorder.Redact(payload))
+	assert.NotContains(t, out, "test_app_id")
+	assert.NotContains(t, out, "test_api_key")
+	assert.NotContains(t, out, "AAAAAQID")
+	assert.NotContains(t, out, "YXBpX2tleT0")
+	assert.Contains(t, out, `"status":1`)
+	assert.Contains(t, out, "&date=Mon")
+}
+
+func TestSampled(t *testing.T) {
+	rec := recorder.New(nil, recorder.Config{Enabled: true, SampleRate: 0.3})
+
+	hits := 0
+	for i := 0; i < 1000; i++ {
+		callUUID := fmt.Sprintf("call-%d", i)
+		if rec.Sampled(callUUID) {
+			hits++
+		}
+		// 同一通话的抽样结果稳定
+		assert.Equal(t, rec.Sampled(callUUID), rec.Sampled(callUUID))
+	}
+	assert.InDelta(t, 300, hits, 60)
+
+	disabled := recorder.New(nil, recorder.Config{SampleRate: 1})
+	assert.False(t, disabled.Sampled("call-1"))
+
+	var nilRecorder *recorder.Recorder
+	assert.False(t, nilRecorder.Sampled("call-1"))
+	assert.NoError(t, nilRecorder.Flush(context.Background(), "call-1"))
+}
+
+func TestRecordFlushGet(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	rec := recorder.New(store, recorder.Config{Enabled: true, SampleRate: 1, MaxEntries: 2})
+
+	ctx := recorder.WithCall(context.Background(), "uuid-1")
+	rec.Record(ctx, "ollama", recorder.KindRequest, []byte(`{"model":"qwen","prompt":"你好"}`))
+	rec.Record(ctx, "ollama", recorder.KindResponse, []byte(`not json`))
+	rec.Record(ctx, "ollama", recorder.KindResponse, []byte(`{"dropped":true}`))
+	// 没有通话标记的报文不记录
+	rec.Record(context.Background(), "ollama", recorder.KindRequest, []byte(`{}`))
+
+	_, err = rec.Get(context.Background(), "uuid-1")
+	assert.Equal(t, recorder.ErrNotFound, err)
+
+	require.NoError(t, rec.Flush(context.Background(), "uuid-1"))
+
+	entries, err := rec.Get(context.Background(), "uuid-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "ollama", entries[0].Provider)
+	assert.Equal(t, recorder.KindRequest, entries[0].Kind)
+	assert.JSONEq(t, `{"model":"qwen","prompt":"你好"}`, string(entries[0].Payload))
+	assert.JSONEq(t, `"not json"`, string(entries[1].Payload))
+}
+
+func TestIdleFlushWritesSegments(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	rec := recorder.New(store, recorder.Config{Enabled: true, SampleRate: 1, IdleTimeout: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rec.Run(ctx)
+
+	count := func() int {
+		entries, _ := rec.Get(context.Background(), "uuid-1")
+		return len(entries)
+	}
+
+	call := recorder.WithCall(context.Background(), "uuid-1")
+	rec.Record(call, "xfyun", recorder.KindRequest, []byte(`{"seq":1}`))
+	require.Eventually(t, func() bool { return count() == 1 }, time.Second, 5*time.Millisecond)
+
+	// 空闲写入后的新记录写入下一个分段，不覆盖之前的记录
+	rec.Record(call, "xfyun", recorder.KindResponse, []byte(`{"seq":2}`))
+	require.Eventually(t, func() bool { return count() == 2 }, time.Second, 5*time.Millisecond)
+
+	rec.Record(call, "xfyun", recorder.KindResponse, []byte(`{"seq":3}`))
+	require.NoError(t, rec.Flush(context.Background(), "uuid-1"))
+
+	entries, err := rec.Get(context.Background(), "uuid-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.JSONEq(t, fmt.Sprintf(`{"seq":%d}`, i+1), string(entry.Payload))
+	}
+}
@@ -0,0 +1,234 @@
+package repositories_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T) (*repositories.Store, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return repositories.NewStore(db), mock
+}
+
+func TestTransaction_Commit(t *testing.T) {
+	store, mock := newStore(t)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO transcripts").
+		WithArgs("uuid-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "你好", 0, 1200, "zh", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE leads SET status").
+		WithArgs(models.LeadStatusDialing, "uuid-1", sqlmock.AnyArg(), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	transcript := &models.Transcript{CallUUID: "uuid-1", Speaker: models.SpeakerCustomer, Text: "你好", EndMs: 1200, Language: "zh"}
+	err := store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		if err := uow.Transcripts.Append(ctx, transcript); err != nil {
+			return err
+		}
+		return uow.Leads.RecordAttempt(ctx, 3, "uuid-1")
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), transcript.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_RollbackOnError(t *testing.T) {
+	store, mock := newStore(t)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE calls SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		return uow.Calls.MarkAnswered(ctx, "missing", time.Now())
+	})
+
+	assert.True(t, errors.Is(err, repositories.ErrNotFound))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTransaction_RollbackOnPanic(t *testing.T) {
+	store, mock := newStore(t)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	assert.Panics(t, func() {
+		store.Transaction(context.Background(), func(uow *repositories.UnitOfWork) error {
+			panic("boom")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCallRepo_Get(t *testing.T) {
+	store, mock := newStore(t)
+	now := time.Now()
+
+	columns := []string{"call_uuid", "campaign_id", "lead_id", "direction", "caller", "callee", "status", "disposition",
+		"started_at", "answered_at", "ended_at", "created_at", "updated_at"}
+	mock.ExpectQuery("SELECT (.+) FROM calls WHERE call_uuid").
+		WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("uuid-1", 2, nil, "outbound", "1000", "13800000000", models.CallStatusAnswered, "", now, now, nil, now, now))
+	mock.ExpectQuery("SELECT (.+) FROM calls WHERE call_uuid").
+		WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	call, err := store.Calls.Get(context.Background(), "uuid-1")
+	require.NoError(t, err)
+	require.NotNil(t, call.CampaignID)
+	assert.Equal(t, int64(2), *call.CampaignID)
+	assert.Nil(t, call.LeadID)
+	assert.NotNil(t, call.AnsweredAt)
+	assert.Nil(t, call.EndedAt)
+
+	_, err = store.Calls.Get(context.Background(), "missing")
+	assert.Equal(t, repositories.ErrNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeadRepo_CreateAndClaimDue(t *testing.T) {
+	store, mock := newStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	mock.ExpectExec("INSERT INTO leads").WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectQuery("SELECT (.+) FROM leads (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(int64(1), models.LeadStatusQueued, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "campaign_id", "phone", "name", "status", "attempts",
+			"next_attempt_at", "last_call_uuid", "lookup_status", "lookup_carrier", "looked_up_at", "data", "created_at", "updated_at"}).
+			AddRow(42, 1, "13800000000", "张三", models.LeadStatusQueued, 0, nil, "", "", "", nil, []byte(`{"vip":true}`), now, now))
+
+	lead := &models.Lead{CampaignID: 1, Phone: "13800000000", Name: "张三"}
+	require.NoError(t, store.Leads.Create(ctx, lead))
+	assert.Equal(t, int64(42), lead.ID)
+	assert.Equal(t, models.LeadStatusQueued, lead.Status)
+
+	leads, err := store.Leads.ClaimDue(ctx, 1, now, 10)
+	require.NoError(t, err)
+	require.Len(t, leads, 1)
+	assert.Equal(t, "张三", leads[0].Name)
+	assert.Nil(t, leads[0].NextAttemptAt)
+	assert.JSONEq(t, `{"vip":true}`, string(leads[0].Data))
+	assert.Nil(t, leads[0].Lookup)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// encryptedArg 匹配加密格式的参数
+type encryptedArg struct{}
+
+func (encryptedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	return ok && encryption.IsEncrypted(s)
+}
+
+func TestTranscriptRepo_Encryption(t *testing.T) {
+	store, mock := newStore(t)
+	keyring, err := encryption.New(encryption.Config{
+		Enabled: true,
+		Tenant:  "default",
+		Tenants: map[string]encryption.TenantKeys{"default": {
+			Active: "k1",
+			Keys:   map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		}},
+	})
+	require.NoError(t, err)
+	store.SetKeyring(keyring)
+	ctx := context.Background()
+
+	// 写入时加密
+	mock.ExpectExec("INSERT INTO transcripts").
+		WithArgs("uuid-1", models.SpeakerCustomer, models.TranscriptKindSpeech, encryptedArg{}, 0, 1200, "zh", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	transcript := &models.Transcript{CallUUID: "uuid-1", Speaker: models.SpeakerCustomer, Text: "我的卡号是6222", EndMs: 1200, Language: "zh"}
+	require.NoError(t, store.Transcripts.Append(ctx, transcript))
+	assert.Equal(t, "我的卡号是6222", transcript.Text)
+
+	// 读取时透明解密，加密前写入的明文原样返回
+	stored, err := keyring.Encrypt("我的卡号是6222")
+	require.NoError(t, err)
+	now := time.Now()
+	mock.ExpectQuery("FROM transcripts\\s+WHERE call_uuid = \\?").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at"}).
+			AddRow(6, "uuid-1", models.SpeakerAI, "您好", 0, 800, "zh", now).
+			AddRow(7, "uuid-1", models.SpeakerCustomer, stored, 900, 1200, "zh", now))
+	transcripts, err := store.Transcripts.ListByCall(ctx, "uuid-1")
+	require.NoError(t, err)
+	require.Len(t, transcripts, 2)
+	assert.Equal(t, "您好", transcripts[0].Text)
+	assert.Equal(t, "我的卡号是6222", transcripts[1].Text)
+
+	// 密文无法全文检索
+	_, err = store.Transcripts.Search(ctx, models.TranscriptSearchQuery{Query: "卡号"})
+	assert.ErrorIs(t, err, repositories.ErrSearchEncrypted)
+
+	// 重新加密：已使用当前主密钥的跳过，明文加密
+	mock.ExpectQuery("SELECT id, text FROM transcripts WHERE id > \\?").WithArgs(int64(0), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "text"}).AddRow(6, "您好").AddRow(7, stored))
+	mock.ExpectExec("UPDATE transcripts SET text = \\? WHERE id = \\? AND text = \\?").
+		WithArgs(encryptedArg{}, int64(6), "您好").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	lastID, updated, err := store.Transcripts.Rewrap(ctx, 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), lastID)
+	assert.Equal(t, 1, updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// digitRedactor 将数字替换为*
+type digitRedactor struct{}
+
+func (digitRedactor) Redact(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '*'
+		}
+		return r
+	}, text)
+}
+
+func TestTranscriptRepo_Redaction(t *testing.T) {
+	store, mock := newStore(t)
+	store.SetRedactor(digitRedactor{})
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO transcripts").
+		WithArgs("uuid-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "我的卡号是****", 0, 1200, "zh", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	transcript := &models.Transcript{CallUUID: "uuid-1", Speaker: models.SpeakerCustomer, Text: "我的卡号是6222", EndMs: 1200, Language: "zh"}
+	require.NoError(t, store.Transcripts.Append(ctx, transcript))
+	assert.Equal(t, "我的卡号是****", transcript.Text)
+
+	// 事务中的仓储同样脱敏
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO transcripts").
+		WithArgs("uuid-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "身份证****", 0, 0, "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectCommit()
+	err := store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		return uow.Transcripts.Append(ctx, &models.Transcript{CallUUID: "uuid-1", Speaker: models.SpeakerCustomer, Text: "身份证1101"})
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,180 @@
+package repositories_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/sqlite"
+	"ai_dialer_mini/internal/database"
+	"ai_dialer_mini/internal/migrations"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSQLiteStore 在临时目录创建已迁移到最新版本的SQLite数据库
+func newSQLiteStore(t *testing.T) *repositories.Store {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "data", "ai_dialer.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.NewWithDialect(db, database.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	store := repositories.NewStore(db)
+	store.SetDialect(database.SQLite)
+	return store
+}
+
+func TestSQLite_CampaignLeadsAndCalls(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	campaign := &models.Campaign{Name: "回访", Status: models.CampaignStatusRunning, PacingPerMinute: 10, MaxAttempts: 3}
+	require.NoError(t, store.Campaigns.Create(ctx, campaign))
+	got, err := store.Campaigns.Get(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "回访", got.Name)
+
+	later := time.Now().Add(time.Hour)
+	for _, lead := range []*models.Lead{
+		{CampaignID: campaign.ID, Phone: "13800000001", Data: []byte(`{"name":"张三"}`)},
+		{CampaignID: campaign.ID, Phone: "13800000002"},
+		{CampaignID: campaign.ID, Phone: "13800000003", NextAttemptAt: &later},
+	} {
+		require.NoError(t, store.Leads.Create(ctx, lead))
+	}
+
+	// 事务中领取到期线索，未到重拨时间的线索不领取
+	var claimed []*models.Lead
+	require.NoError(t, store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		var err error
+		claimed, err = uow.Leads.ClaimDue(ctx, campaign.ID, time.Now(), 10)
+		if err != nil {
+			return err
+		}
+		if _, err := uow.Leads.GetForUpdate(ctx, claimed[0].ID); err != nil {
+			return err
+		}
+		return uow.Leads.RecordAttempt(ctx, claimed[0].ID, "uuid-1")
+	}))
+	require.Len(t, claimed, 2)
+	assert.JSONEq(t, `{"name":"张三"}`, string(claimed[0].Data))
+
+	counts, err := store.Leads.CountByStatus(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{models.LeadStatusQueued: 2, models.LeadStatusDialing: 1}, counts)
+
+	// 接通3秒后挂断计为放弃，接通1分钟并成交计为转化
+	start := time.Now().Add(-10 * time.Minute)
+	for i, call := range []struct {
+		uuid        string
+		talk        time.Duration
+		disposition string
+	}{
+		{"uuid-1", 3 * time.Second, "no_interest"},
+		{"uuid-2", time.Minute, "sale"},
+		{"uuid-3", 0, "no_answer"},
+	} {
+		require.NoError(t, store.Calls.Create(ctx, &models.Call{CallUUID: call.uuid, CampaignID: &campaign.ID, StartedAt: &start}))
+		answeredAt := start.Add(time.Duration(i) * time.Minute)
+		if call.talk > 0 {
+			require.NoError(t, store.Calls.MarkAnswered(ctx, call.uuid, answeredAt))
+		}
+		require.NoError(t, store.Calls.MarkEnded(ctx, call.uuid, answeredAt.Add(call.talk), call.disposition))
+	}
+
+	stats, err := store.Calls.CampaignStats(ctx, campaign.ID, 5*time.Second, []string{"sale"})
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStats{Attempts: 3, Connects: 2, Abandoned: 1, Conversions: 1}, stats)
+
+	call, err := store.Calls.Get(ctx, "uuid-2")
+	require.NoError(t, err)
+	require.NotNil(t, call.AnsweredAt)
+	assert.WithinDuration(t, start.Add(time.Minute), *call.AnsweredAt, time.Millisecond)
+}
+
+func TestSQLite_InsertIgnore(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	start := time.Now().Add(-time.Minute)
+	cdr := models.CDR{CallUUID: "uuid-1", Caller: "1000", Callee: "13800000001", Gateway: "gw1", StartTime: start, AnswerTime: &start, EndTime: time.Now(), BillSec: 60}
+	require.NoError(t, store.CDRs.Insert(ctx, cdr))
+	cdr.BillSec = 1
+	require.NoError(t, store.CDRs.Insert(ctx, cdr))
+	require.NoError(t, store.CDRs.SetACW(ctx, "uuid-1", 15))
+
+	stats, err := store.CDRs.GatewayStats(ctx, start.Add(-time.Second), "")
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Calls)
+	assert.Equal(t, 60, stats[0].BillSec)
+
+	require.NoError(t, store.Annotations.AddTag(ctx, "uuid-1", &models.CallTag{Tag: "投诉", Author: "alice"}))
+	require.NoError(t, store.Annotations.AddTag(ctx, "uuid-1", &models.CallTag{Tag: "投诉", Author: "bob"}))
+	tags, err := store.Annotations.ListTags(ctx, "uuid-1")
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	assert.Equal(t, "alice", tags[0].Author)
+}
+
+func TestSQLite_TranscriptSearch(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	for _, text := range []string{"我想办理宽带业务", "优惠力度是100%吗", "不需要了谢谢"} {
+		require.NoError(t, store.Transcripts.Append(ctx, &models.Transcript{CallUUID: "uuid-1", Speaker: models.SpeakerCustomer, Text: text}))
+	}
+
+	hits, err := store.Transcripts.Search(ctx, models.TranscriptSearchQuery{Query: "宽带", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	assert.Equal(t, "我想办理宽带业务", hits[0].Text)
+
+	// 通配符按普通字符匹配
+	hits, err = store.Transcripts.Search(ctx, models.TranscriptSearchQuery{Query: "100%", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	hits, err = store.Transcripts.Search(ctx, models.TranscriptSearchQuery{Query: "%", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, hits, 1)
+}
+
+func TestSQLite_QAAndOutbox(t *testing.T) {
+	store := newSQLiteStore(t)
+	ctx := context.Background()
+
+	score, total := 80, 80.0
+	eval := &models.QAEvaluation{CallUUID: "uuid-1", Status: "done", TotalScore: &total, Passed: true, EvaluatedAt: time.Now(),
+		Scores: []*models.QAScore{{Criterion: "greeting", Score: &score, Passed: true, Reason: "问候规范"}}}
+	require.NoError(t, store.Transaction(ctx, func(uow *repositories.UnitOfWork) error { return uow.QA.Save(ctx, eval) }))
+	eval.Passed = false
+	require.NoError(t, store.Transaction(ctx, func(uow *repositories.UnitOfWork) error { return uow.QA.Save(ctx, eval) }))
+
+	got, err := store.QA.Get(ctx, "uuid-1")
+	require.NoError(t, err)
+	assert.False(t, got.Passed)
+	require.Len(t, got.Scores, 1)
+	assert.True(t, got.Scores[0].Passed)
+
+	// 相同幂等键的发件箱消息只入库一次
+	ob := outbox.New(nil, outbox.Config{})
+	ob.SetDialect(database.SQLite)
+	msg := outbox.Message{IdempotencyKey: "cdr:uuid-1", Destination: outbox.DestinationWebhook, TargetURL: "http://example.com/hook", EventType: "call.ended", Payload: map[string]string{"call_uuid": "uuid-1"}}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, store.Transaction(ctx, func(uow *repositories.UnitOfWork) error { return ob.Enqueue(ctx, uow.Tx(), msg) }))
+	}
+	var count int
+	require.NoError(t, store.Transaction(ctx, func(uow *repositories.UnitOfWork) error {
+		return uow.Tx().QueryRowContext(ctx, `SELECT COUNT(*) FROM outbox`).Scan(&count)
+	}))
+	assert.Equal(t, 1, count)
+}
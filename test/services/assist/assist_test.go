@@ -0,0 +1,63 @@
+package assist_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/assist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	var hub *assist.Hub = assist.New(assist.Config{}, nil)
+	assert.Nil(t, hub)
+	// 未启用时可直接调用
+	hub.Publish(assist.Message{Type: assist.MessageTranscript, CallUUID: "uuid-1", Text: "你好"})
+	assert.Nil(t, hub.Knowledge("价格是多少"))
+}
+
+func TestHub_PublishToCallSubscribers(t *testing.T) {
+	hub := assist.New(assist.Config{Enabled: true, Buffer: 1}, nil)
+	messages, cancel := hub.Subscribe("uuid-1")
+	other, cancelOther := hub.Subscribe("uuid-2")
+	defer cancelOther()
+
+	hub.Publish(assist.Message{Type: assist.MessageTranscript, CallUUID: "uuid-1", Text: "价格是多少"})
+	// 坐席缓存已满时丢弃，不阻塞
+	hub.Publish(assist.Message{Type: assist.MessageSuggestion, CallUUID: "uuid-1", Text: "月租59元"})
+
+	msg := <-messages
+	assert.Equal(t, assist.MessageTranscript, msg.Type)
+	assert.Equal(t, "价格是多少", msg.Text)
+	assert.False(t, msg.At.IsZero())
+	assert.Empty(t, other)
+
+	cancel()
+	_, ok := <-messages
+	assert.False(t, ok)
+	cancel()
+}
+
+func TestHub_Knowledge(t *testing.T) {
+	knowledge := []string{
+		"办理宽带需要提供身份证，安装约三个工作日",
+		"5G套餐月租59元，含30G通用流量",
+		"套餐价格可在营业厅或APP查询，月租最低19元",
+	}
+	hub := assist.New(assist.Config{Enabled: true, MaxSnippets: 2}, knowledge)
+
+	// 按共有词语数排序，相关度相同时按配置顺序
+	snippets := hub.Knowledge("你们的套餐月租多少钱？")
+	require.Len(t, snippets, 2)
+	assert.Equal(t, knowledge[1], snippets[0])
+	assert.Equal(t, knowledge[2], snippets[1])
+
+	assert.Equal(t, []string{knowledge[0]}, hub.Knowledge("宽带安装要多久"))
+	assert.Empty(t, hub.Knowledge("不需要，谢谢"))
+
+	// 配置了知识片段时不使用默认知识
+	hub = assist.New(assist.Config{Enabled: true, Knowledge: []string{"退订回复TD"}}, knowledge)
+	assert.Equal(t, []string{"退订回复TD"}, hub.Knowledge("退订要回复什么"))
+	assert.Empty(t, hub.Knowledge("套餐月租多少"))
+}
@@ -0,0 +1,240 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/hooks"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallService_CallControlValidation(t *testing.T) {
+	// 未连接FreeSWITCH，参数有效的命令返回不可用，无效参数在发送前拒绝
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	ctx := context.Background()
+
+	invalid := []error{
+		callService.EndCall(ctx, "uuid-1\n\napi shutdown"),
+		callService.TransferCall(ctx, "uuid-1", "1005 XML public", ""),
+		callService.TransferCall(ctx, "uuid-1", "1005", "default;"),
+		callService.SendDTMF(ctx, "uuid-1", "12x", 0),
+		callService.SendDTMF(ctx, "uuid-1", "12", 10*time.Second),
+		callService.SetVariable(ctx, "uuid-1", "bad name", "x"),
+		callService.HangupCall(ctx, "uuid-1", "MEDIA_TIMEOUT api"),
+		callService.SetVariable(ctx, "uuid-1", "result", "x\napi shutdown"),
+		func() error { _, err := callService.InitiateCall(ctx, "1000", "1004 &park"); return err }(),
+	}
+	for i, err := range invalid {
+		assert.True(t, errors.Is(err, services.ErrInvalidCallCommand), "%d: %v", i, err)
+	}
+
+	unavailable := []error{
+		callService.EndCall(ctx, "0f9c5b3e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"),
+		callService.TransferCall(ctx, "uuid-1", "+8613800000000", "public"),
+		callService.SendDTMF(ctx, "uuid-1", "1w2#", 100*time.Millisecond),
+		callService.SetVariable(ctx, "uuid-1", "ai_result", "已同意 还款"),
+		callService.HangupCall(ctx, "uuid-1", "MEDIA_TIMEOUT"),
+		func() error { _, err := callService.InitiateCall(ctx, "1000", "1004"); return err }(),
+	}
+	for i, err := range unavailable {
+		assert.True(t, errors.Is(err, services.ErrSwitchUnavailable), "%d: %v", i, err)
+	}
+}
+
+// recordingPublisher 记录发布的通话事件
+type recordingPublisher struct {
+	events []*models.CallEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *models.CallEvent) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestCallService_PublishesCallEvents(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	publisher := &recordingPublisher{}
+	callService.SetEventPublisher(publisher)
+
+	headers := map[string]string{
+		"Unique-ID":                 "uuid-1",
+		"Caller-Caller-ID-Number":   "1000",
+		"Caller-Destination-Number": "13800000000",
+	}
+	ctx := context.Background()
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", headers))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_PROGRESS", headers))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_ANSWER", headers))
+	headers["Hangup-Cause"] = "NORMAL_CLEARING"
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_HANGUP", headers))
+
+	require.Len(t, publisher.events, 3)
+	assert.Equal(t, models.EventTypeCallCreated, publisher.events[0].Type)
+	assert.Equal(t, models.EventTypeCallAnswered, publisher.events[1].Type)
+	hangup := publisher.events[2]
+	assert.Equal(t, models.EventTypeCallHangup, hangup.Type)
+	assert.Equal(t, "uuid-1", hangup.SessionID)
+	assert.Equal(t, "1000", hangup.Caller)
+	assert.Equal(t, "13800000000", hangup.Callee)
+	assert.Equal(t, "NORMAL_CLEARING", hangup.Cause)
+}
+
+func TestCallService_AnswersInboundCalls(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	callService.SetInbound(inbound.New(inbound.Config{
+		Enabled:  true,
+		Profiles: []string{"external"},
+		Numbers:  map[string]inbound.Route{"4008001234": {Persona: "collection"}},
+	}))
+	ctx := context.Background()
+
+	// 未匹配路由的通道不应答
+	outbound := map[string]string{"Unique-ID": "uuid-1", "Call-Direction": "outbound", "Caller-Destination-Number": "4008001234"}
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", outbound))
+	_, ok := callService.Sessions().InboundRoute("uuid-1")
+	assert.False(t, ok)
+
+	// 匹配路由的呼入通道登记到通话会话后应答，未连接FreeSWITCH时应答失败
+	headers := map[string]string{
+		"Unique-ID":                   "uuid-2",
+		"Call-Direction":              "inbound",
+		"variable_sofia_profile_name": "external",
+		"Caller-Destination-Number":   "4008001234",
+	}
+	assert.Error(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", headers))
+	route, ok := callService.Sessions().InboundRoute("uuid-2")
+	assert.True(t, ok)
+	assert.Equal(t, "collection", route.Persona)
+}
+
+// callHooks 记录通话服务调用的钩子
+type callHooks struct {
+	hooks.Base
+	answered []hooks.Call
+	hangups  []models.CDR
+}
+
+func (h *callHooks) OnCallAnswered(_ context.Context, call hooks.Call) {
+	h.answered = append(h.answered, call)
+}
+
+func (h *callHooks) OnHangup(_ context.Context, cdr models.CDR) {
+	h.hangups = append(h.hangups, cdr)
+}
+
+func TestCallService_Hooks(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	h := &callHooks{}
+	callService.SetHooks(hooks.New(h))
+	ctx := context.Background()
+	headers := map[string]string{
+		"Unique-ID":                 "uuid-1",
+		"Call-Direction":            "outbound",
+		"Caller-Caller-ID-Number":   "4001",
+		"Caller-Destination-Number": "13800000000",
+		"variable_campaign_id":      "7",
+	}
+
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_ANSWER", headers))
+	require.Len(t, h.answered, 1)
+	assert.Equal(t, "uuid-1", h.answered[0].UUID)
+	assert.Equal(t, "13800000000", h.answered[0].Callee)
+	assert.Equal(t, "outbound", h.answered[0].Direction)
+	assert.Equal(t, "7", h.answered[0].Headers["variable_campaign_id"])
+
+	headers["Hangup-Cause"] = "NORMAL_CLEARING"
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_HANGUP", headers))
+	require.Len(t, h.hangups, 1)
+	assert.Equal(t, "NORMAL_CLEARING", h.hangups[0].HangupCause)
+}
+
+func TestCallService_Tenants(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	callService.SetTenants(tenant.New(tenant.Config{Tenants: map[string]tenant.Tenant{
+		"bank": {CallerID: "4008001234"},
+	}}))
+
+	// 未配置的租户在发送命令前拒绝，已配置的租户在未连接FreeSWITCH时返回不可用
+	_, err := callService.InitiateCall(tenant.WithID(context.Background(), "other"), "1000", "1004")
+	assert.True(t, errors.Is(err, services.ErrInvalidCallCommand), "%v", err)
+	_, err = callService.InitiateOutboundCall(tenant.WithID(context.Background(), "other"), "", "", "13800000000", "1000")
+	assert.True(t, errors.Is(err, services.ErrInvalidCallCommand), "%v", err)
+	_, err = callService.InitiateCall(tenant.WithID(context.Background(), "bank"), "1000", "1004")
+	assert.True(t, errors.Is(err, services.ErrSwitchUnavailable), "%v", err)
+
+	// 通道创建时记录通道变量指定的租户，未配置的租户忽略
+	ctx := context.Background()
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", map[string]string{
+		"Unique-ID": "uuid-1", "Call-Direction": "outbound", "variable_ai_dialer_tenant": "bank",
+	}))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", map[string]string{
+		"Unique-ID": "uuid-2", "Call-Direction": "outbound", "variable_ai_dialer_tenant": "other",
+	}))
+	assert.Equal(t, "bank", callService.Sessions().CallTenant("uuid-1"))
+	assert.Empty(t, callService.Sessions().CallTenant("uuid-2"))
+	info, ok := callService.Sessions().Get("uuid-1")
+	require.True(t, ok)
+	assert.Equal(t, "bank", info.Info().Tenant)
+}
+
+func TestCallService_ASRProvider(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+
+	_, err := callService.InitiateCall(services.WithASRProvider(context.Background(), "whisper,api"), "1000", "1004")
+	assert.True(t, errors.Is(err, services.ErrInvalidCallCommand), "%v", err)
+	_, err = callService.InitiateCall(services.WithASRProvider(context.Background(), "whisper"), "1000", "1004")
+	assert.True(t, errors.Is(err, services.ErrSwitchUnavailable), "%v", err)
+
+	require.NoError(t, callService.HandleCallEvent(context.Background(), "CHANNEL_CREATE", map[string]string{
+		"Unique-ID": "uuid-1", "Call-Direction": "outbound", "variable_ai_dialer_asr": "whisper",
+	}))
+	assert.Equal(t, "whisper", callService.Sessions().CallASRProvider("uuid-1"))
+	assert.Empty(t, callService.Sessions().CallASRProvider("uuid-2"))
+}
+
+func TestCallService_Assist(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	ctx := context.Background()
+
+	// 坐席确认的预览外呼带AssistVariable通道变量，音频流接入时AI只推送建议话术
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", map[string]string{
+		"Unique-ID": "uuid-1", "Call-Direction": "outbound", "variable_" + services.AssistVariable: "true",
+	}))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", map[string]string{
+		"Unique-ID": "uuid-2", "Call-Direction": "outbound",
+	}))
+	assert.True(t, callService.Sessions().CallAssisted("uuid-1"))
+	assert.False(t, callService.Sessions().CallAssisted("uuid-2"))
+	assert.False(t, callService.Sessions().CallAssisted("uuid-3"))
+	info, ok := callService.Sessions().Get("uuid-1")
+	require.True(t, ok)
+	assert.True(t, info.Info().Assist)
+}
+
+func TestCallService_Transcripts(t *testing.T) {
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	store := &memoryTranscripts{}
+	callService.SetTranscripts(services.NewTranscriptRecorder(store))
+	ctx := context.Background()
+
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_CREATE", map[string]string{"Unique-ID": "uuid-1"}))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_ANSWER", map[string]string{"Unique-ID": "uuid-1"}))
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_HANGUP", map[string]string{
+		"Unique-ID": "uuid-1", "Hangup-Cause": "NORMAL_CLEARING",
+	}))
+
+	require.Len(t, store.items, 2)
+	assert.Equal(t, models.SpeakerSystem, store.items[0].Speaker)
+	assert.Equal(t, models.TranscriptKindEvent, store.items[0].Kind)
+	assert.Equal(t, "通话接通", store.items[0].Text)
+	assert.Equal(t, "通话挂断: NORMAL_CLEARING", store.items[1].Text)
+}
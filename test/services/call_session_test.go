@@ -0,0 +1,254 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/consent"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/inbound"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stopRecorder 记录停止播放的通话
+type stopRecorder struct {
+	stopped []string
+}
+
+func (s *stopRecorder) Stop(callUUID string) error {
+	s.stopped = append(s.stopped, callUUID)
+	return nil
+}
+
+func TestCallSessionManager_Lifecycle(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	playback := &stopRecorder{}
+	manager.SetPlayback(playback)
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	session, ok := manager.Get("call-1")
+	require.True(t, ok)
+	assert.Equal(t, services.CallStateCreated, session.State())
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_PROGRESS", "call-1"))
+	assert.Equal(t, services.CallStateRinging, session.State())
+	require.NoError(t, manager.HandleEvent("CHANNEL_ANSWER", "call-1"))
+	assert.Equal(t, services.CallStateAnswered, session.State())
+
+	closed := 0
+	detach, err := manager.AttachStream("call-1", "session-1", func() { closed++ })
+	require.NoError(t, err)
+	defer detach()
+	assert.Equal(t, services.CallStateTalking, session.State())
+
+	info := session.Info()
+	assert.Equal(t, "session-1", info.SessionID)
+	assert.NotNil(t, info.AnsweredAt)
+	assert.Len(t, manager.List(), 1)
+
+	// 挂断时停止播放、关闭音频流连接并移除会话
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+	assert.Equal(t, services.CallStateHangup, session.State())
+	assert.Equal(t, []string{"call-1"}, playback.stopped)
+	assert.Equal(t, 1, closed)
+	_, ok = manager.Get("call-1")
+	assert.False(t, ok)
+
+	// 挂断后乱序到达的事件不会重新创建会话
+	require.NoError(t, manager.HandleEvent("CHANNEL_ANSWER", "call-1"))
+	_, ok = manager.Get("call-1")
+	assert.False(t, ok)
+}
+
+func TestCallSessionManager_OutOfOrderEvents(t *testing.T) {
+	manager := services.NewCallSessionManager()
+
+	// 应答事件先于创建事件处理
+	require.NoError(t, manager.HandleEvent("CHANNEL_ANSWER", "call-1"))
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	session, ok := manager.Get("call-1")
+	require.True(t, ok)
+	assert.Equal(t, services.CallStateAnswered, session.State())
+}
+
+func TestCallSessionManager_AttachStream(t *testing.T) {
+	manager := services.NewCallSessionManager()
+
+	_, err := manager.AttachStream("unknown", "session-1", func() {})
+	assert.Error(t, err)
+
+	// 未应答的通话不能接入音频流
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	_, err = manager.AttachStream("call-1", "session-1", func() {})
+	assert.True(t, errors.Is(err, services.ErrInvalidCallTransition))
+
+	// 音频流重启后，旧连接注销不影响新连接
+	require.NoError(t, manager.HandleEvent("CHANNEL_ANSWER", "call-1"))
+	first, second := 0, 0
+	detachFirst, err := manager.AttachStream("call-1", "session-1", func() { first++ })
+	require.NoError(t, err)
+	_, err = manager.AttachStream("call-1", "session-1", func() { second++ })
+	require.NoError(t, err)
+	detachFirst()
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+	assert.Equal(t, 0, first)
+	assert.Equal(t, 1, second)
+}
+
+func TestCallSessionManager_Consent(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	assert.False(t, manager.SetConsent("call-1", consent.StatusGranted), "通话不存在")
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	assert.Equal(t, consent.StatusPending, manager.Consent("call-1"))
+	assert.False(t, manager.SetConsent("call-1", consent.StatusPending))
+	assert.True(t, manager.SetConsent("call-1", consent.StatusGranted))
+	assert.False(t, manager.SetConsent("call-1", consent.StatusGranted))
+
+	// 同意后仍可拒绝，拒绝后不再改变
+	assert.True(t, manager.SetConsent("call-1", consent.StatusRefused))
+	assert.False(t, manager.SetConsent("call-1", consent.StatusGranted))
+	assert.Equal(t, consent.StatusRefused, manager.Consent("call-1"))
+	assert.Equal(t, consent.StatusRefused, manager.List()[0].RecordingConsent)
+}
+
+func TestCallSessionManager_Inbound(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	route := inbound.Route{Persona: "collection", Greeting: "您好"}
+	assert.False(t, manager.SetInbound("call-1", route), "通话不存在")
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	_, ok := manager.InboundRoute("call-1")
+	assert.False(t, ok, "外呼通话")
+
+	require.True(t, manager.SetInbound("call-1", route))
+	got, ok := manager.InboundRoute("call-1")
+	assert.True(t, ok)
+	assert.Equal(t, route, got)
+
+	// 音频流重启后重新接入时不再重复问候
+	got, ok = manager.InboundRoute("call-1")
+	assert.True(t, ok)
+	assert.Equal(t, "collection", got.Persona)
+	assert.Empty(t, got.Greeting)
+
+	info := manager.List()[0]
+	assert.True(t, info.Inbound)
+	assert.Equal(t, "collection", info.Persona)
+}
+
+func TestCallSessionManager_DTMFMenu(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	results := make(chan dtmf.Result, 1)
+	manager.SetMenus(dtmf.Config{
+		Enabled: true,
+		Timeout: time.Second,
+		Menus: map[string]dtmf.Menu{
+			"confirm": {Options: map[string]dtmf.Option{"1": {Input: "确认办理"}}},
+		},
+	}, func(callUUID string, result dtmf.Result) {
+		assert.Equal(t, "call-1", callUUID)
+		results <- result
+	})
+
+	assert.Error(t, manager.OpenMenu("call-1", "confirm"), "通话不存在")
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	assert.Error(t, manager.OpenMenu("call-1", "unknown"))
+
+	// 没有菜单时按键只记录，不被接收
+	assert.False(t, manager.HandleDTMF("call-1", "5"))
+
+	require.NoError(t, manager.OpenMenu("call-1", "confirm"))
+	assert.Equal(t, "confirm", manager.List()[0].DTMFMenu)
+	assert.True(t, manager.HandleDTMF("call-1", "1"))
+	select {
+	case result := <-results:
+		assert.Equal(t, "确认办理", result.Input)
+	case <-time.After(time.Second):
+		t.Fatal("等待按键菜单结果超时")
+	}
+
+	info := manager.List()[0]
+	assert.Equal(t, "51", info.DTMF)
+	assert.Empty(t, info.DTMFMenu)
+	require.Len(t, info.DTMFInputs, 1)
+	assert.Equal(t, "1", info.DTMFInputs[0].Digits)
+
+	// 挂断时关闭等待中的菜单，不再回调
+	require.NoError(t, manager.OpenMenu("call-1", "confirm"))
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+	assert.False(t, manager.HandleDTMF("call-1", "1"))
+}
+
+func TestCallSessionManager_WrapUp(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	type ended struct {
+		uuid        string
+		acw         time.Duration
+		disposition string
+	}
+	endedCh := make(chan ended, 2)
+	manager.SetWrapUp(time.Hour, func(uuid string, acw time.Duration, disposition string) {
+		endedCh <- ended{uuid, acw, disposition}
+	})
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_ANSWER", "call-1"))
+	closed := 0
+	_, err := manager.AttachStream("call-1", "session-1", func() { closed++ })
+	require.NoError(t, err)
+
+	// 未挂断时不能修改通话结果
+	assert.ErrorIs(t, manager.SetDisposition("call-1", "interested"), services.ErrNotInWrapUp)
+
+	// 挂断后释放通话资源，会话保留到话后处理结束
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+	session, ok := manager.Get("call-1")
+	require.True(t, ok)
+	assert.Equal(t, services.CallStateWrapUp, session.State())
+	assert.Equal(t, 1, closed)
+	require.NotNil(t, session.Info().EndedAt)
+	_, err = manager.AttachStream("call-1", "session-2", func() {})
+	assert.Error(t, err)
+
+	// 重复的挂断事件不影响话后处理
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+	require.NoError(t, manager.SetDisposition("call-1", "interested"))
+	assert.Equal(t, "interested", session.Info().Disposition)
+
+	require.NoError(t, manager.EndWrapUp("call-1"))
+	result := <-endedCh
+	assert.Equal(t, "call-1", result.uuid)
+	assert.Equal(t, "interested", result.disposition)
+	assert.Less(t, result.acw, time.Minute)
+	assert.Equal(t, services.CallStateHangup, session.State())
+	_, ok = manager.Get("call-1")
+	assert.False(t, ok)
+	assert.ErrorIs(t, manager.EndWrapUp("call-1"), services.ErrNotInWrapUp)
+	assert.ErrorIs(t, manager.SetDisposition("call-1", "callback"), services.ErrNotInWrapUp)
+}
+
+func TestCallSessionManager_WrapUpTimeout(t *testing.T) {
+	manager := services.NewCallSessionManager()
+	acws := make(chan time.Duration, 1)
+	manager.SetWrapUp(20*time.Millisecond, func(uuid string, acw time.Duration, disposition string) {
+		acws <- acw
+	})
+
+	require.NoError(t, manager.HandleEvent("CHANNEL_CREATE", "call-1"))
+	require.NoError(t, manager.HandleEvent("CHANNEL_HANGUP", "call-1"))
+
+	// 话后处理到时自动结束并移除会话
+	select {
+	case acw := <-acws:
+		assert.GreaterOrEqual(t, acw, 20*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("话后处理未到时结束")
+	}
+	_, ok := manager.Get("call-1")
+	assert.False(t, ok)
+}
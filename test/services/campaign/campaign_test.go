@@ -0,0 +1,404 @@
+package campaign_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/campaign"
+	"ai_dialer_mini/internal/services/dnd"
+	"ai_dialer_mini/internal/services/outbox"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	campaignColumns = []string{"id", "name", "status", "caller_id", "pacing_per_minute", "max_attempts",
+		"retry_interval_seconds", "webhook_url", "dial_mode", "agent_extension", "created_at", "updated_at"}
+	callColumns = []string{"call_uuid", "campaign_id", "lead_id", "direction", "caller", "callee", "status", "disposition",
+		"started_at", "answered_at", "ended_at", "created_at", "updated_at"}
+	leadColumns = []string{"id", "campaign_id", "phone", "name", "status", "attempts", "next_attempt_at", "last_call_uuid",
+		"lookup_status", "lookup_carrier", "looked_up_at", "data", "created_at", "updated_at"}
+)
+
+// stubDialer 记录外呼请求
+type stubDialer struct {
+	dialed []string
+	uuids  []string
+	err    error
+	onDial func() // 发起呼叫时回调，用于检查呼叫前已完成的登记
+}
+
+func (d *stubDialer) InitiateOutboundCall(ctx context.Context, callUUID, callerID, toNumber, extension string) (string, error) {
+	if d.onDial != nil {
+		d.onDial()
+	}
+	d.dialed = append(d.dialed, callerID+">"+toNumber+">"+extension)
+	d.uuids = append(d.uuids, callUUID)
+	if d.err != nil {
+		return "", d.err
+	}
+	return callUUID, nil
+}
+
+func newManager(t *testing.T, dialer campaign.Dialer) (*campaign.Manager, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return campaign.New(repositories.NewStore(db), dialer, campaign.Config{Extension: "1000"}), mock
+}
+
+// expectRunning 期望查询到一个拨打中的任务，每分钟60通，最多拨打2次
+func expectRunning(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusRunning).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, "", models.DialModeAuto, "", now, now))
+}
+
+// expectClaim 期望领取到一条待拨打线索并标记为拨打中
+func expectClaim(mock sqlmock.Sqlmock, now time.Time) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM leads\\s+WHERE campaign_id = \\? AND status = \\?").
+		WithArgs(int64(1), models.LeadStatusQueued, sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusQueued,
+			0, nil, "", "", "", nil, nil, now, now))
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusDialing, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// expectRegister 期望在发起呼叫前登记通话和拨打次数
+func expectRegister(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO calls").
+		WithArgs(sqlmock.AnyArg(), int64(1), int64(7), "outbound", "4001", "13800000000",
+			models.CallStatusCreated, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE leads SET status = \\?, attempts = attempts \\+ 1").
+		WithArgs(models.LeadStatusDialing, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestManager_TickDialsDueLeads(t *testing.T) {
+	dialer := &stubDialer{}
+	m, mock := newManager(t, dialer)
+	now := time.Now()
+
+	expectRunning(mock)
+	expectClaim(mock, now)
+	expectRegister(mock)
+	// 通话在发起呼叫前已登记，被叫很快挂断时详单也能关联到线索
+	dialer.onDial = func() { assert.NoError(t, mock.ExpectationsWereMet()) }
+
+	// 首次调度只发放一个令牌
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Equal(t, []string{"4001>13800000000>1000"}, dialer.dialed)
+	require.Len(t, dialer.uuids, 1)
+	assert.NotEmpty(t, dialer.uuids[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickRetriesFailedOriginate(t *testing.T) {
+	dialer := &stubDialer{err: errors.New("-ERR GATEWAY_DOWN")}
+	m, mock := newManager(t, dialer)
+	now := time.Now()
+
+	expectRunning(mock)
+	expectClaim(mock, now)
+	expectRegister(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE calls SET status").
+		WithArgs(models.CallStatusEnded, sqlmock.AnyArg(), "ORIGINATE_FAILED", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE leads SET status = \\?, next_attempt_at").
+		WithArgs(models.LeadStatusQueued, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickRequeuesWhenCallNotRegistered(t *testing.T) {
+	dialer := &stubDialer{}
+	m, mock := newManager(t, dialer)
+	now := time.Now()
+
+	expectRunning(mock)
+	expectClaim(mock, now)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO calls").WillReturnError(errors.New("deadlock"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE leads SET status = \\?, next_attempt_at").
+		WithArgs(models.LeadStatusQueued, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 通话未登记时不发起呼叫
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Empty(t, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// stubReachability 模拟号码状态检查，空号将线索标记为unreachable
+type stubReachability struct {
+	unreachable map[string]bool
+	err         error
+}
+
+func (r *stubReachability) CheckLead(ctx context.Context, lead *models.Lead) error {
+	if r.unreachable[lead.Phone] {
+		lead.Status = models.LeadStatusUnreachable
+		return errors.New("号码为空号或已停机")
+	}
+	return r.err
+}
+
+func TestManager_TickSkipsUnreachableLeads(t *testing.T) {
+	dialer := &stubDialer{}
+	m, mock := newManager(t, dialer)
+	m.SetReachability(&stubReachability{unreachable: map[string]bool{"13800000000": true}})
+	now := time.Now()
+
+	expectRunning(mock)
+	expectClaim(mock, now)
+
+	// 空号不登记通话也不发起呼叫
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Empty(t, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickDialsWhenReachabilityFails(t *testing.T) {
+	dialer := &stubDialer{}
+	m, mock := newManager(t, dialer)
+	m.SetReachability(&stubReachability{err: errors.New("记录号码状态失败")})
+	now := time.Now()
+
+	expectRunning(mock)
+	expectClaim(mock, now)
+	expectRegister(mock)
+
+	// 检查服务异常时放行
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Equal(t, []string{"4001>13800000000>1000"}, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickDefersLeadsInQuietHours(t *testing.T) {
+	dialer := &stubDialer{}
+	m, mock := newManager(t, dialer)
+	quietHours, err := dnd.New(dnd.Config{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "86"})
+	require.NoError(t, err)
+	m.SetQuietHours(quietHours)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	// 服务器时间为白天，被叫当地（北京时间）为晚上10点
+	now := time.Date(2024, 3, 1, 22, 0, 0, 0, shanghai)
+	next := time.Date(2024, 3, 2, 9, 0, 0, 0, shanghai)
+
+	expectRunning(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM leads\\s+WHERE campaign_id = \\? AND status = \\?").
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusQueued,
+			0, nil, "", "", "", nil, nil, now, now))
+	mock.ExpectExec("UPDATE leads SET status = \\?, next_attempt_at").
+		WithArgs(models.LeadStatusQueued, next, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT status, COUNT").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow(models.LeadStatusQueued, 1))
+
+	// 推迟的线索不发起呼叫，任务不会因此完成
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Empty(t, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickCompletesWhenNoLeadsLeft(t *testing.T) {
+	m, mock := newManager(t, &stubDialer{})
+
+	expectRunning(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM leads").WillReturnRows(sqlmock.NewRows(leadColumns))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT status, COUNT").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow(models.LeadStatusCompleted, 3))
+	mock.ExpectExec("UPDATE campaigns SET status = \\?, updated_at = \\? WHERE id = \\? AND status IN \\(\\?\\)").
+		WithArgs(models.CampaignStatusCompleted, sqlmock.AnyArg(), int64(1), models.CampaignStatusRunning).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, m.Tick(context.Background(), time.Now()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_StartInvalidTransition(t *testing.T) {
+	m, mock := newManager(t, nil)
+	now := time.Now()
+
+	mock.ExpectExec("UPDATE campaigns SET status").
+		WithArgs(models.CampaignStatusRunning, sqlmock.AnyArg(), int64(1), models.CampaignStatusDraft, models.CampaignStatusPaused).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusCompleted, "", 10, 3, 3600, "", models.DialModeAuto, "", now, now))
+
+	assert.ErrorIs(t, m.Start(context.Background(), 1), campaign.ErrInvalidTransition)
+
+	mock.ExpectExec("UPDATE campaigns SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM campaigns WHERE id").WillReturnError(sql.ErrNoRows)
+	assert.ErrorIs(t, m.Start(context.Background(), 2), repositories.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// recordResult 在事务中更新线索结果
+func recordResult(m *campaign.Manager, db *sql.DB, cdr models.CDR) error {
+	return repositories.NewStore(db).Transaction(context.Background(), func(uow *repositories.UnitOfWork) error {
+		return m.RecordResult(context.Background(), uow, cdr)
+	})
+}
+
+func TestManager_RecordResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	m := campaign.New(repositories.NewStore(db), nil, campaign.Config{})
+	now := time.Now()
+
+	expectLead := func(attempts int) {
+		mock.ExpectQuery("FROM calls WHERE call_uuid").WithArgs("uuid-1").
+			WillReturnRows(sqlmock.NewRows(callColumns).AddRow("uuid-1", 1, 7, "outbound", "4001",
+				"13800000000", "ended", "NO_ANSWER", now, nil, now, now, now))
+		mock.ExpectQuery("FROM leads WHERE id").WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusDialing,
+				attempts, nil, "uuid-1", "", "", nil, nil, now, now))
+	}
+
+	expectCampaign := func() {
+		mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, "", models.DialModeAuto, "", now, now))
+	}
+
+	// 接通：线索完成
+	mock.ExpectBegin()
+	expectLead(1)
+	expectCampaign()
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusCompleted, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, recordResult(m, db, models.CDR{CallUUID: "uuid-1", AnswerTime: &now, EndTime: now}))
+
+	// 未接通且已达最大拨打次数：线索失败
+	mock.ExpectBegin()
+	expectLead(2)
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, "", models.DialModeAuto, "", now, now))
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusFailed, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, recordResult(m, db, models.CDR{CallUUID: "uuid-1", HangupCause: "NO_ANSWER", EndTime: now}))
+
+	// 非外呼任务的通话忽略
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM calls WHERE call_uuid").WithArgs("uuid-2").WillReturnError(sql.ErrNoRows)
+	mock.ExpectCommit()
+	require.NoError(t, recordResult(m, db, models.CDR{CallUUID: "uuid-2", EndTime: now}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// leadEventPayload 匹配线索事件推送内容
+type leadEventPayload struct {
+	event    string
+	status   string
+	attempts int
+}
+
+func (p leadEventPayload) Match(v driver.Value) bool {
+	data, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	var event models.LeadEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return false
+	}
+	return event.Event == p.event && event.Status == p.status && event.Attempts == p.attempts && event.LeadID == 7
+}
+
+func TestManager_LeadWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	m := campaign.New(repositories.NewStore(db), nil, campaign.Config{})
+	m.SetOutbox(outbox.New(db, outbox.Config{}))
+	now := time.Now()
+	hook := "https://crm.example.com/leads"
+
+	expectEvent := func(event, status string, attempts int) {
+		mock.ExpectExec("INSERT IGNORE INTO outbox").
+			WithArgs(fmt.Sprintf("webhook:%s:7:%d", event, attempts), outbox.DestinationWebhook, hook, event,
+				leadEventPayload{event: event, status: status, attempts: attempts}, outbox.StatusPending, 0, 0,
+				sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	// 创建任务时推送线索加入名单
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO campaigns").WithArgs("回访", models.CampaignStatusDraft, "", 10, 3, 3600, hook, models.DialModeAuto, "",
+		sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO leads").WillReturnResult(sqlmock.NewResult(7, 1))
+	expectEvent(models.LeadEventQueued, models.LeadStatusQueued, 0)
+	mock.ExpectCommit()
+	require.NoError(t, m.Create(context.Background(), &models.Campaign{Name: "回访", WebhookURL: hook},
+		[]*models.Lead{{Phone: "13800000000"}}))
+
+	// 未接通且未达最大拨打次数：推送安排重拨
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM calls WHERE call_uuid").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows(callColumns).AddRow("uuid-1", 1, 7, "outbound", "4001",
+			"13800000000", "ended", "NO_ANSWER", now, nil, now, now, now))
+	mock.ExpectQuery("FROM leads WHERE id").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusDialing,
+			1, nil, "uuid-1", "", "", nil, nil, now, now))
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, hook, models.DialModeAuto, "", now, now))
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusQueued, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectEvent(models.LeadEventRetryScheduled, models.LeadStatusQueued, 1)
+	mock.ExpectCommit()
+	require.NoError(t, recordResult(m, db, models.CDR{CallUUID: "uuid-1", HangupCause: "NO_ANSWER", EndTime: now}))
+
+	// 重拨前查询为空号：推送不再拨打
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, hook, models.DialModeAuto, "", now, now))
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusUnreachable, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectEvent(models.LeadEventUnreachable, models.LeadStatusUnreachable, 1)
+	mock.ExpectCommit()
+	lead := &models.Lead{ID: 7, CampaignID: 1, Phone: "13800000000", Status: models.LeadStatusDialing, Attempts: 1}
+	require.NoError(t, repositories.NewStore(db).Transaction(context.Background(), func(uow *repositories.UnitOfWork) error {
+		return m.MarkUnreachable(context.Background(), uow, lead)
+	}))
+	assert.Equal(t, models.LeadStatusUnreachable, lead.Status)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_CreateInvalidWebhook(t *testing.T) {
+	m, mock := newManager(t, nil)
+	err := m.Create(context.Background(), &models.Campaign{Name: "回访", WebhookURL: "ftp://crm"}, nil)
+	assert.ErrorContains(t, err, "Webhook地址无效")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,72 @@
+package campaign_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/campaign"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrape 读取默认注册表导出的指标
+func scrape(t *testing.T) string {
+	w := httptest.NewRecorder()
+	metrics.Default.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
+
+// expectStats 期望统计任务42的线索和通话
+func expectStats(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT status, COUNT").WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).
+			AddRow(models.LeadStatusQueued, 5).AddRow(models.LeadStatusDialing, 2).AddRow(models.LeadStatusCompleted, 8))
+	mock.ExpectQuery("FROM calls WHERE campaign_id = \\?").
+		WithArgs(int64(3*time.Second/time.Microsecond), "成交", "预约", int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"attempts", "connects", "abandoned", "conversions"}).AddRow(20, 8, 2, 3))
+}
+
+func TestManager_RefreshMetrics(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	m := campaign.New(repositories.NewStore(db), nil, campaign.Config{Metrics: campaign.MetricsConfig{
+		AbandonThreshold:       3 * time.Second,
+		ConversionDispositions: []string{"成交", "预约"},
+	}})
+	now := time.Now()
+
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusRunning).
+		WillReturnRows(sqlmock.NewRows(campaignColumns).AddRow(42, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, "", models.DialModeAuto, "", now, now))
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusPaused).
+		WillReturnRows(sqlmock.NewRows(campaignColumns))
+	expectStats(mock)
+
+	require.NoError(t, m.RefreshMetrics(context.Background()))
+	body := scrape(t)
+	assert.Contains(t, body, "# TYPE ai_dialer_campaign_leads_remaining gauge\n")
+	assert.Contains(t, body, `ai_dialer_campaign_leads_remaining{campaign_id="42"} 7`)
+	assert.Contains(t, body, `ai_dialer_campaign_attempts{campaign_id="42"} 20`)
+	assert.Contains(t, body, `ai_dialer_campaign_connects{campaign_id="42"} 8`)
+	assert.Contains(t, body, `ai_dialer_campaign_conversions{campaign_id="42"} 3`)
+	assert.Contains(t, body, `ai_dialer_campaign_abandon_rate{campaign_id="42"} 0.25`)
+
+	// 任务完成后不再导出
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusRunning).
+		WillReturnRows(sqlmock.NewRows(campaignColumns))
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusPaused).
+		WillReturnRows(sqlmock.NewRows(campaignColumns))
+
+	require.NoError(t, m.RefreshMetrics(context.Background()))
+	assert.NotContains(t, scrape(t), `campaign_id="42"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
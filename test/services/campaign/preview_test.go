@@ -0,0 +1,170 @@
+package campaign_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/campaign"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAssistDialer 支持坐席预览外呼的拨号器
+type stubAssistDialer struct {
+	stubDialer
+}
+
+func (d *stubAssistDialer) InitiateAssistedCall(ctx context.Context, callUUID, callerID, toNumber, agent string) (string, error) {
+	d.dialed = append(d.dialed, "assist:"+callerID+">"+toNumber+">"+agent)
+	d.uuids = append(d.uuids, callUUID)
+	return callUUID, d.err
+}
+
+// previewCampaignRow 预览拨号任务，默认坐席分机2001
+func previewCampaignRow(now time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows(campaignColumns).AddRow(1, "回访", models.CampaignStatusRunning, "4001", 60, 2, 600, "",
+		models.DialModePreview, "2001", now, now)
+}
+
+// expectLockPreview 期望锁定一条线索，status为线索当前状态
+func expectLockPreview(mock sqlmock.Sqlmock, now time.Time, status string) {
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(1)).WillReturnRows(previewCampaignRow(now))
+	mock.ExpectQuery("FROM leads WHERE id = \\? FOR UPDATE").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", status,
+			0, now.Add(time.Minute), "", "", "", nil, nil, now, now))
+}
+
+func TestManager_TickPreviewsLeads(t *testing.T) {
+	dialer := &stubAssistDialer{}
+	m, mock := newManager(t, dialer)
+	now := time.Now()
+
+	mock.ExpectQuery("FROM campaigns WHERE status").WithArgs(models.CampaignStatusRunning).WillReturnRows(previewCampaignRow(now))
+	mock.ExpectExec("UPDATE leads SET status = \\?, next_attempt_at = NULL").
+		WithArgs(models.LeadStatusQueued, now, int64(1), models.LeadStatusPreview, now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT status, COUNT").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow(models.LeadStatusQueued, 3))
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM leads\\s+WHERE campaign_id = \\? AND status = \\?").
+		WithArgs(int64(1), models.LeadStatusQueued, now, 1).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusQueued,
+			0, nil, "", "", "", nil, nil, now, now))
+	// 等待确认的线索以next_attempt_at记录确认截止时间
+	mock.ExpectExec("UPDATE leads SET status").
+		WithArgs(models.LeadStatusPreview, now.Add(2*time.Minute), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.Empty(t, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_TickPreviewLimitsPending(t *testing.T) {
+	m, mock := newManager(t, &stubAssistDialer{})
+	now := time.Now()
+
+	mock.ExpectQuery("FROM campaigns WHERE status").WillReturnRows(previewCampaignRow(now))
+	mock.ExpectExec("UPDATE leads SET status = \\?, next_attempt_at = NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT status, COUNT").WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).AddRow(models.LeadStatusPreview, 5))
+
+	// 等待确认的线索已达上限，不再领取
+	require.NoError(t, m.Tick(context.Background(), now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_ConfirmDialsAgent(t *testing.T) {
+	dialer := &stubAssistDialer{}
+	m, mock := newManager(t, dialer)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	expectLockPreview(mock, now, models.LeadStatusPreview)
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusDialing, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectRegister(mock)
+
+	// 未指定坐席时使用任务的默认坐席分机
+	lead, err := m.Confirm(context.Background(), 1, 7, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"assist:4001>13800000000>2001"}, dialer.dialed)
+	assert.Equal(t, 1, lead.Attempts)
+	assert.Equal(t, dialer.uuids[0], lead.LastCallUUID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_ConfirmRejects(t *testing.T) {
+	now := time.Now()
+
+	// 拨号器不支持坐席外呼
+	m, _ := newManager(t, &stubDialer{})
+	_, err := m.Confirm(context.Background(), 1, 7, "2002")
+	assert.ErrorIs(t, err, campaign.ErrAssistUnsupported)
+
+	dialer := &stubAssistDialer{}
+	m, mock := newManager(t, dialer)
+
+	// 线索已被其他坐席确认
+	mock.ExpectBegin()
+	expectLockPreview(mock, now, models.LeadStatusDialing)
+	mock.ExpectRollback()
+	_, err = m.Confirm(context.Background(), 1, 7, "2002")
+	assert.ErrorIs(t, err, campaign.ErrNotPreviewing)
+
+	// 坐席分机写入originate命令，拒绝非法字符
+	mock.ExpectBegin()
+	expectLockPreview(mock, now, models.LeadStatusPreview)
+	mock.ExpectRollback()
+	_, err = m.Confirm(context.Background(), 1, 7, "2002 &hangup")
+	assert.ErrorContains(t, err, "坐席分机格式无效")
+
+	// 线索不属于该任务
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM campaigns WHERE id").WithArgs(int64(2)).WillReturnRows(previewCampaignRow(now))
+	mock.ExpectQuery("FROM leads WHERE id = \\? FOR UPDATE").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusPreview,
+			0, nil, "", "", "", nil, nil, now, now))
+	mock.ExpectRollback()
+	_, err = m.Confirm(context.Background(), 2, 7, "")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	assert.Empty(t, dialer.dialed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_SkipRequeuesLead(t *testing.T) {
+	m, mock := newManager(t, &stubAssistDialer{})
+	now := time.Now()
+
+	mock.ExpectBegin()
+	expectLockPreview(mock, now, models.LeadStatusPreview)
+	mock.ExpectExec("UPDATE leads SET status").WithArgs(models.LeadStatusQueued, sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	lead, err := m.Skip(context.Background(), 1, 7)
+	require.NoError(t, err)
+	assert.Equal(t, models.LeadStatusQueued, lead.Status)
+	assert.Zero(t, lead.Attempts)
+	require.NotNil(t, lead.NextAttemptAt)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), *lead.NextAttemptAt, time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_CreateValidatesDialMode(t *testing.T) {
+	m, mock := newManager(t, nil)
+
+	err := m.Create(context.Background(), &models.Campaign{Name: "回访", DialMode: "predictive"}, nil)
+	assert.ErrorContains(t, err, "不支持的拨号方式")
+	err = m.Create(context.Background(), &models.Campaign{Name: "回访", DialMode: models.DialModePreview, AgentExtension: "2001;x"}, nil)
+	assert.ErrorContains(t, err, "坐席分机格式无效")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
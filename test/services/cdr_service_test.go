@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/freeswitch"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/outbox"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCDRFromHeaders_Answered(t *testing.T) {
+	created := time.UnixMicro(1714528800000000)
+	hangup := time.UnixMicro(1714528867000000)
+
+	cdr := services.CDRFromHeaders(map[string]string{
+		"Unique-ID":                    "uuid-1",
+		"Caller-Caller-ID-Number":      "1000",
+		"Caller-Destination-Number":    "13800000000",
+		"variable_sip_gateway_name":    "carrier-a",
+		"Hangup-Cause":                 "NORMAL_CLEARING",
+		"Caller-Channel-Created-Time":  "1714528800000000",
+		"Caller-Channel-Answered-Time": "1714528805000000",
+		"Caller-Channel-Hangup-Time":   "1714528867000000",
+	})
+
+	assert.Equal(t, "uuid-1", cdr.CallUUID)
+	assert.Equal(t, "1000", cdr.Caller)
+	assert.Equal(t, "13800000000", cdr.Callee)
+	assert.Equal(t, "carrier-a", cdr.Gateway)
+	assert.Equal(t, "NORMAL_CLEARING", cdr.HangupCause)
+	assert.True(t, cdr.StartTime.Equal(created))
+	assert.NotNil(t, cdr.AnswerTime)
+	assert.True(t, cdr.EndTime.Equal(hangup))
+	assert.Equal(t, 62, cdr.BillSec)
+}
+
+func TestCDRFromHeaders_NotAnswered(t *testing.T) {
+	cdr := services.CDRFromHeaders(map[string]string{
+		"Unique-ID":                    "uuid-2",
+		"Hangup-Cause":                 "NO_ANSWER",
+		"Caller-Channel-Answered-Time": "0",
+	})
+
+	assert.Nil(t, cdr.AnswerTime)
+	assert.Equal(t, 0, cdr.BillSec)
+	assert.False(t, cdr.EndTime.IsZero())
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, 2*time.Second, outbox.Backoff(1))
+	assert.Equal(t, 8*time.Second, outbox.Backoff(3))
+	assert.Equal(t, 10*time.Minute, outbox.Backoff(20))
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, outbox.RetryDelay(30*time.Second, 1))
+	assert.Equal(t, 2*time.Minute, outbox.RetryDelay(30*time.Second, 3))
+	assert.Equal(t, 10*time.Minute, outbox.RetryDelay(30*time.Second, 50))
+}
+
+func TestCDRService_Record(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	svc := services.NewCDRService(repositories.NewStore(db), outbox.New(db, outbox.Config{}),
+		config.WebhookConfig{URL: "http://hooks.example.com/calls"})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO cdr").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE calls SET status").WillReturnResult(sqlmock.NewResult(0, 0))
+	// 未配置CRM地址，只写入一条Webhook消息
+	mock.ExpectExec("INSERT IGNORE INTO outbox").
+		WithArgs("webhook:call.completed:uuid-1", outbox.DestinationWebhook, "http://hooks.example.com/calls",
+			"call.completed", sqlmock.AnyArg(), outbox.StatusPending, 0, 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = svc.Record(context.Background(), models.CDR{CallUUID: "uuid-1", HangupCause: "NORMAL_CLEARING", EndTime: time.Now()})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCallService_WrapUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	callService := services.NewCallService(freeswitch.NewESLClient(freeswitch.ESLConfig{}))
+	callService.SetCDRService(services.NewCDRService(repositories.NewStore(db), outbox.New(db, outbox.Config{}), config.WebhookConfig{}))
+	callService.SetWrapUp(time.Hour)
+	ctx := context.Background()
+
+	// 挂机后照常写入通话详单，会话进入话后处理
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT IGNORE INTO cdr").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE calls SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	require.NoError(t, callService.HandleCallEvent(ctx, "CHANNEL_HANGUP", map[string]string{
+		"Unique-ID": "uuid-1", "Hangup-Cause": "NORMAL_CLEARING",
+	}))
+	session, ok := callService.Sessions().Get("uuid-1")
+	require.True(t, ok)
+	assert.Equal(t, services.CallStateWrapUp, session.State())
+
+	// 主管修改通话结果
+	mock.ExpectExec("UPDATE calls SET disposition").WithArgs("interested", sqlmock.AnyArg(), "uuid-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, callService.SetDisposition(ctx, "uuid-1", "interested"))
+	assert.ErrorIs(t, callService.SetDisposition(ctx, "uuid-1", ""), services.ErrInvalidCallCommand)
+	assert.ErrorIs(t, callService.SetDisposition(ctx, "uuid-2", "interested"), services.ErrNotInWrapUp)
+
+	// 提前结束话后处理，话后处理时长写入详单
+	mock.ExpectExec("UPDATE cdr SET acw_sec").WithArgs(0, "uuid-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, callService.CompleteWrapUp(ctx, "uuid-1"))
+	assert.ErrorIs(t, callService.CompleteWrapUp(ctx, "uuid-1"), services.ErrNotInWrapUp)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
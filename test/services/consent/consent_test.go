@@ -0,0 +1,60 @@
+package consent_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/consent"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDetector() *consent.Detector {
+	return consent.New(consent.Config{
+		Enabled:       true,
+		GrantDigits:   "1",
+		RefuseDigits:  "2#",
+		GrantPhrases:  []string{"同意", "可以"},
+		RefusePhrases: []string{"不同意", "不可以", "不要录"},
+	})
+}
+
+func TestDetector_FromDigit(t *testing.T) {
+	d := newDetector()
+	assert.Equal(t, consent.StatusGranted, d.FromDigit("1"))
+	assert.Equal(t, consent.StatusRefused, d.FromDigit("2"))
+	assert.Equal(t, consent.StatusRefused, d.FromDigit("#"))
+	assert.Equal(t, consent.StatusPending, d.FromDigit("5"))
+	assert.Equal(t, consent.StatusPending, d.FromDigit("12"))
+}
+
+func TestDetector_FromText(t *testing.T) {
+	d := newDetector()
+	// 拒绝的说法包含同意的说法时按拒绝处理
+	assert.Equal(t, consent.StatusRefused, d.FromText("我不同意。"))
+	assert.Equal(t, consent.StatusRefused, d.FromText("不 可以"))
+	assert.Equal(t, consent.StatusRefused, d.FromText("你们不要录音"))
+	assert.Equal(t, consent.StatusGranted, d.FromText("可以，你说吧"))
+	assert.Equal(t, consent.StatusPending, d.FromText("你好，哪位？"))
+	assert.Equal(t, consent.StatusPending, d.FromText("，。"))
+}
+
+func TestDetector_Disabled(t *testing.T) {
+	d := consent.New(consent.Config{GrantDigits: "1"})
+	assert.Nil(t, d)
+	assert.Equal(t, consent.StatusPending, d.FromDigit("1"))
+	assert.Equal(t, consent.StatusPending, d.FromText("同意"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, consent.Config{}.Validate())
+	assert.NoError(t, consent.Config{Enabled: true, RefuseDigits: "2"}.Validate())
+
+	for _, c := range []consent.Config{
+		{Enabled: true, GrantDigits: "1"},                                 // 没有拒绝方式
+		{Enabled: true, RefuseDigits: "x"},                                // 无效的按键
+		{Enabled: true, GrantDigits: "12", RefuseDigits: "2"},             // 按键重复
+		{Enabled: true, RefuseDigits: "2", GrantPhrases: []string{" ， "}}, // 空说法
+	} {
+		assert.Error(t, c.Validate(), "%+v", c)
+	}
+}
@@ -0,0 +1,148 @@
+package cron_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/cron"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	loc := time.UTC
+	// 2026-10-16 是周五
+	from := time.Date(2026, 10, 16, 10, 17, 30, 0, loc)
+	cases := map[string]time.Time{
+		"*/15 * * * *":   time.Date(2026, 10, 16, 10, 30, 0, 0, loc),
+		"0 3 * * *":      time.Date(2026, 10, 17, 3, 0, 0, 0, loc),
+		"30 9 * * 1-5":   time.Date(2026, 10, 19, 9, 30, 0, 0, loc),
+		"0 0 1 * *":      time.Date(2026, 11, 1, 0, 0, 0, 0, loc),
+		"0 12 * * 7":     time.Date(2026, 10, 18, 12, 0, 0, 0, loc),
+		"0 8,20 * * *":   time.Date(2026, 10, 16, 20, 0, 0, 0, loc),
+		"@daily":         time.Date(2026, 10, 17, 0, 0, 0, 0, loc),
+		"@hourly":        time.Date(2026, 10, 16, 11, 0, 0, 0, loc),
+		"@every 90s":     from.Add(90 * time.Second),
+		"0 0 13 * 5":     time.Date(2026, 10, 23, 0, 0, 0, 0, loc), // 日和星期满足其一即可
+		"0 0 29 2 *":     time.Date(2028, 2, 29, 0, 0, 0, 0, loc),
+		"17 10 16 10 *":  time.Date(2027, 10, 16, 10, 17, 0, 0, loc),
+		"5-10/5 1 * * *": time.Date(2026, 10, 17, 1, 5, 0, 0, loc),
+	}
+	for spec, want := range cases {
+		schedule, err := cron.Parse(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, want, schedule.Next(from), spec)
+	}
+
+	// 永远不会到达的日期
+	schedule, err := cron.Parse("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, schedule.Next(from).IsZero())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@every 10ms", "@every x", "@often"} {
+		_, err := cron.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduler_RunsAndRecordsStatus(t *testing.T) {
+	scheduler := cron.New(nil)
+	var runs int32
+	require.NoError(t, scheduler.Register("ok", "@every 1s", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}))
+	require.NoError(t, scheduler.Register("fail", "@every 1s", func(ctx context.Context) error {
+		return errors.New("数据库不可用")
+	}))
+	assert.Error(t, scheduler.Register("ok", "@every 1s", func(ctx context.Context) error { return nil }))
+	assert.Error(t, scheduler.Register("bad", "* *", func(ctx context.Context) error { return nil }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scheduler.Run(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		jobs := scheduler.Jobs()
+		return len(jobs) == 2 && jobs[0].Runs >= 1 && jobs[1].Runs >= 1
+	}, 3*time.Second, 20*time.Millisecond)
+	cancel()
+	<-done
+
+	jobs := scheduler.Jobs()
+	assert.Equal(t, "fail", jobs[0].Name)
+	assert.Equal(t, "数据库不可用", jobs[0].LastError)
+	assert.Equal(t, jobs[0].Runs, jobs[0].Failures)
+	assert.Equal(t, "ok", jobs[1].Name)
+	assert.Empty(t, jobs[1].LastError)
+	assert.NotNil(t, jobs[1].LastEnd)
+	assert.NotNil(t, jobs[1].NextRun)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), int32(1))
+}
+
+func TestScheduler_PreventsOverlap(t *testing.T) {
+	scheduler := cron.New(nil)
+	release := make(chan struct{})
+	var runs int32
+	require.NoError(t, scheduler.Register("slow", "@every 1s", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+
+	// 调度器启动前不能手动执行
+	assert.Error(t, scheduler.Trigger("slow"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scheduler.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool { return scheduler.Trigger("slow") == nil }, time.Second, 10*time.Millisecond)
+	assert.True(t, errors.Is(scheduler.Trigger("slow"), cron.ErrJobRunning))
+	assert.True(t, errors.Is(scheduler.Trigger("missing"), cron.ErrJobNotFound))
+
+	// 执行期间到期的调度被跳过
+	require.Eventually(t, func() bool { return scheduler.Jobs()[0].Skipped >= 1 }, 3*time.Second, 20*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.True(t, scheduler.Jobs()[0].Running)
+
+	close(release)
+	require.Eventually(t, func() bool { return !scheduler.Jobs()[0].Running }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestScheduler_RecoversPanic(t *testing.T) {
+	scheduler := cron.New(nil)
+	require.NoError(t, scheduler.Register("panic", "@daily", func(ctx context.Context) error {
+		panic("boom")
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go scheduler.Run(ctx)
+
+	require.Eventually(t, func() bool { return scheduler.Trigger("panic") == nil }, time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return scheduler.Jobs()[0].Failures == 1 }, time.Second, 10*time.Millisecond)
+	assert.Contains(t, scheduler.Jobs()[0].LastError, "boom")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, cron.Config{}.Validate())
+	assert.NoError(t, cron.Config{Timezone: "Asia/Shanghai"}.Validate())
+	assert.Error(t, cron.Config{Timezone: "Mars/Olympus"}.Validate())
+}
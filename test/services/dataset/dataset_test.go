@@ -0,0 +1,131 @@
+package dataset_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/dataset"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	callColumns = []string{"call_uuid", "campaign_id", "lead_id", "direction", "caller", "callee", "status", "disposition",
+		"started_at", "answered_at", "ended_at", "created_at", "updated_at"}
+	transcriptColumns = []string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at"}
+)
+
+func newExporter(t *testing.T, config dataset.Config) (*dataset.Exporter, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return dataset.New(repositories.NewStore(db), config), mock
+}
+
+// expectCalls 期望查询到两通已结束的通话：call-1对话完整，call-2只有AI开场白
+func expectCalls(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("FROM calls WHERE status = \\? AND EXISTS (.+) AND disposition IN \\(\\?, \\?\\)").
+		WithArgs(models.CallStatusEnded, "NORMAL_CLEARING", "USER_BUSY", 10000).
+		WillReturnRows(sqlmock.NewRows(callColumns).
+			AddRow("call-1", 3, nil, "outbound", "4001", "13812345678", models.CallStatusEnded, "NORMAL_CLEARING", now, now, now, now, now).
+			AddRow("call-2", nil, nil, "outbound", "4001", "13900001111", models.CallStatusEnded, "USER_BUSY", now, nil, now, now, now))
+
+	mock.ExpectQuery("FROM transcripts").WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(transcriptColumns).
+			AddRow(1, "call-1", models.SpeakerAI, "您好，请问是尾号5678的机主吗？", 0, 2000, "zh", now).
+			AddRow(2, "call-1", models.SpeakerCustomer, "是的，我的号码是13812345678", 2000, 4000, "zh", now).
+			AddRow(3, "call-1", models.SpeakerCustomer, "邮箱是zhang@example.com", 4000, 5000, "zh", now).
+			AddRow(4, "call-1", models.SpeakerAI, "好的，已为您登记。", 5000, 6000, "zh", now).
+			AddRow(5, "call-1", models.SpeakerCustomer, "身份证号110101199003071234", 6000, 8000, "zh", now).
+			AddRow(6, "call-1", models.SpeakerAgent, "收到，稍后给您回电。", 8000, 9000, "zh", now).
+			AddRow(7, "call-1", models.SpeakerCustomer, "好", 9000, 9500, "zh", now))
+	mock.ExpectQuery("FROM transcripts").WithArgs("call-2").
+		WillReturnRows(sqlmock.NewRows(transcriptColumns).
+			AddRow(8, "call-2", models.SpeakerAI, "您好", 0, 1000, "zh", now))
+}
+
+func TestExporter_WriteTo(t *testing.T) {
+	exporter, mock := newExporter(t, dataset.Config{SystemPrompt: "你是银行客服", MinTurns: 2})
+	expectCalls(mock)
+
+	var buf bytes.Buffer
+	dialogs, skipped, err := exporter.WriteTo(context.Background(),
+		models.DatasetQuery{Dispositions: []string{"NORMAL_CLEARING", "USER_BUSY"}}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, dialogs)
+	assert.Equal(t, 1, skipped)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	var sample dataset.Sample
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &sample))
+
+	// 客户连续的发言合并，最后没有回复的客户发言去掉，个人信息替换为占位符
+	assert.Equal(t, []dataset.Message{
+		{Role: dataset.RoleSystem, Content: "你是银行客服"},
+		{Role: dataset.RoleAssistant, Content: "您好，请问是尾号5678的机主吗？"},
+		{Role: dataset.RoleUser, Content: "是的，我的号码是[电话] 邮箱是[邮箱]"},
+		{Role: dataset.RoleAssistant, Content: "好的，已为您登记。"},
+		{Role: dataset.RoleUser, Content: "身份证号[身份证号]"},
+		{Role: dataset.RoleAssistant, Content: "收到，稍后给您回电。"},
+	}, sample.Messages)
+	assert.Equal(t, "NORMAL_CLEARING", sample.Metadata.Disposition)
+	require.NotNil(t, sample.Metadata.CampaignID)
+	assert.Equal(t, int64(3), *sample.Metadata.CampaignID)
+	assert.NotContains(t, buf.String(), "call-1")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExporter_Export(t *testing.T) {
+	dir := t.TempDir()
+	exporter, mock := newExporter(t, dataset.Config{Dir: dir, MinTurns: 1})
+	expectCalls(mock)
+
+	export, err := exporter.Export(context.Background(), models.DatasetQuery{Dispositions: []string{"NORMAL_CLEARING", "USER_BUSY"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, export.Dialogs)
+	assert.True(t, strings.HasSuffix(export.File, ".jsonl"))
+
+	data, err := os.ReadFile(export.File)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+	// 只留下完整的导出文件
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScrub(t *testing.T) {
+	cases := map[string]string{
+		"我的手机是138-1234-5678":    "我的手机是[手机号]",
+		"打+86 13912345678找我":    "打[手机号]找我",
+		"座机010-88886666":        "座机[电话]",
+		"卡号6222020200112233445": "卡号[银行卡号]",
+		"验证码是482913":            "验证码是[数字]",
+		"号码是一三八一二三四五六七八":        "号码是[数字]",
+		"尾号5678":                "尾号5678",
+		"身份证11010119900307123X": "身份证[身份证号]",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, dataset.Scrub(in), in)
+	}
+	assert.Equal(t, "分机[电话]转人工", dataset.Scrub("分机40015转人工", "40015"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, dataset.Config{}.Validate())
+	assert.Error(t, dataset.Config{MaxDialogs: -1}.Validate())
+	assert.Error(t, dataset.Config{MinTurns: -1}.Validate())
+}
@@ -0,0 +1,103 @@
+package deadair_test
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/deadair"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tone 生成振幅恒定的16位PCM，RMS等于振幅
+func tone(amplitude int16, samples int) []byte {
+	data := make([]byte, samples*2)
+	for i := 0; i < samples; i++ {
+		v := amplitude
+		if i%2 == 1 {
+			v = -amplitude
+		}
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(v))
+	}
+	return data
+}
+
+func newWatchdog(t *testing.T, timeout time.Duration) (*deadair.Watchdog, chan deadair.Snapshot) {
+	fired := make(chan deadair.Snapshot, 1)
+	w := deadair.New(deadair.Config{Enabled: true, Timeout: timeout, Threshold: 300}, "call-1", "session-1", func(s deadair.Snapshot) {
+		fired <- s
+	})
+	require.NotNil(t, w)
+	t.Cleanup(w.Close)
+	return w, fired
+}
+
+func TestWatchdog_FiresAfterSilence(t *testing.T) {
+	w, fired := newWatchdog(t, 60*time.Millisecond)
+	w.Audio(tone(50, 320))
+
+	select {
+	case s := <-fired:
+		assert.Equal(t, "call-1", s.CallUUID)
+		assert.Equal(t, deadair.DefaultCause, s.Cause)
+		assert.True(t, s.AudioReceived)
+		assert.Equal(t, int64(640), s.AudioBytes)
+		assert.Zero(t, s.VoicedChunks)
+		assert.Nil(t, s.LastVoice)
+		assert.GreaterOrEqual(t, s.SilenceMs, int64(60))
+	case <-time.After(time.Second):
+		t.Fatal("静音超时未触发")
+	}
+}
+
+func TestWatchdog_VoiceAndPlaybackExtend(t *testing.T) {
+	w, fired := newWatchdog(t, 100*time.Millisecond)
+
+	// 客户持续说话期间不触发
+	for i := 0; i < 4; i++ {
+		time.Sleep(40 * time.Millisecond)
+		w.Audio(tone(1000, 320))
+	}
+	// AI播放的语音结束后才开始计算静音
+	w.Playing(150 * time.Millisecond)
+	select {
+	case <-fired:
+		t.Fatal("有活动时不应触发")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	select {
+	case s := <-fired:
+		assert.Equal(t, int64(4), s.VoicedChunks)
+		assert.NotNil(t, s.LastVoice)
+		assert.NotNil(t, s.LastPlayback)
+	case <-time.After(time.Second):
+		t.Fatal("静音超时未触发")
+	}
+}
+
+func TestWatchdog_CloseAndDisabled(t *testing.T) {
+	w, fired := newWatchdog(t, 30*time.Millisecond)
+	w.Close()
+	select {
+	case <-fired:
+		t.Fatal("关闭后不应触发")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	var disabled *deadair.Watchdog = deadair.New(deadair.Config{}, "call-1", "session-1", nil)
+	assert.Nil(t, disabled)
+	disabled.Audio(tone(1000, 10))
+	disabled.Playing(time.Second)
+	disabled.Close()
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, deadair.Config{}.Validate())
+	assert.NoError(t, deadair.Config{Enabled: true, Timeout: time.Second}.Validate())
+	assert.Error(t, deadair.Config{Enabled: true}.Validate())
+	assert.Error(t, deadair.Config{Enabled: true, Timeout: time.Second, Threshold: 40000}.Validate())
+	assert.Error(t, deadair.Config{Enabled: true, Timeout: time.Second, Cause: "media timeout"}.Validate())
+}
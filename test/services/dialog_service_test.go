@@ -0,0 +1,497 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/mock"
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/recorder"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/guardrail"
+	"ai_dialer_mini/internal/services/history"
+	"ai_dialer_mini/internal/services/llmcache"
+	"ai_dialer_mini/internal/services/prompt"
+	"ai_dialer_mini/internal/services/session"
+	"ai_dialer_mini/internal/services/tools"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialogService_MockProvider(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{{Pattern: "价格", Response: "每月99元"}},
+			},
+		},
+	})
+
+	reply, err := svc.ProcessMessage("session-1", "你们的价格是多少")
+	require.NoError(t, err)
+	assert.Equal(t, "每月99元", reply)
+
+	reply, err = svc.ProcessMessage("session-1", "好的")
+	require.NoError(t, err)
+	assert.NotEmpty(t, reply)
+
+	history := svc.GetHistory("session-1")
+	require.Len(t, history, 4)
+	assert.Equal(t, "assistant", history[1].Role)
+	assert.Equal(t, "每月99元", history[1].Content)
+}
+
+func TestDialogService_RequiresSessionID(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{Provider: config.ProviderMock},
+	})
+
+	_, err := svc.ProcessMessage("", "你好")
+	assert.ErrorIs(t, err, models.ErrSessionIDRequired)
+	assert.Empty(t, svc.GetHistory(""))
+}
+
+func TestDialogService_ProcessMessageStream(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{{Pattern: "价格", Response: "您好。我们的套餐每月99元！需要为您办理吗"}},
+			},
+		},
+	})
+
+	var sentences []string
+	reply, err := svc.ProcessMessageStream("session-1", "价格", func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "您好。我们的套餐每月99元！需要为您办理吗", reply)
+	assert.Equal(t, []string{"您好。", "我们的套餐每月99元！", "需要为您办理吗"}, sentences)
+
+	history := svc.GetHistory("session-1")
+	require.Len(t, history, 2)
+	assert.Equal(t, reply, history[1].Content)
+}
+
+func TestDialogService_ProcessMessageTokens(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{{Pattern: "价格", Response: "每月99元。需要办理吗"}},
+			},
+		},
+	})
+
+	var tokens, sentences []string
+	reply, err := svc.ProcessMessageTokens("session-1", "价格", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	}, func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "每月99元。需要办理吗", reply)
+	assert.Equal(t, reply, strings.Join(tokens, ""))
+	assert.Greater(t, len(tokens), 1)
+	assert.Equal(t, []string{"每月99元。", "需要办理吗"}, sentences)
+
+	// 回调返回错误时停止生成，用户消息仍保留在历史中
+	_, err = svc.ProcessMessageTokens("session-2", "价格", func(token string) error {
+		return errors.New("连接已关闭")
+	}, nil)
+	require.Error(t, err)
+	history := svc.GetHistory("session-2")
+	require.Len(t, history, 1)
+	assert.Equal(t, models.RoleUser, history[0].Role)
+}
+
+func TestDialogService_Guardrail(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{
+					{Pattern: "价格", Response: "每月99元。回电请拨13800138000。需要办理吗"},
+					{Pattern: "收益", Response: "我们保证收益，稳赚不赔。"},
+				},
+			},
+			Guardrail: guardrail.Config{
+				Patterns: []string{`1[3-9]\d{9}`},
+				Keywords: []string{"稳赚不赔"},
+				Action:   guardrail.ActionFallback,
+				Fallback: "抱歉，这个问题稍后请专人为您解答。",
+			},
+		},
+	})
+
+	reply, err := svc.ProcessMessage("session-1", "收益怎么样")
+	require.NoError(t, err)
+	assert.Equal(t, "抱歉，这个问题稍后请专人为您解答。", reply)
+	history := svc.GetHistory("session-1")
+	require.Len(t, history, 2)
+	assert.Equal(t, reply, history[1].Content)
+
+	// 流式回复逐句过滤，客户端只收到过滤后的整句，命中前已输出的句子保留
+	var tokens, sentences []string
+	reply, err = svc.ProcessMessageTokens("session-2", "价格", func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	}, func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "每月99元。", reply)
+	assert.Equal(t, []string{"每月99元。"}, tokens)
+	assert.Equal(t, []string{"每月99元。"}, sentences)
+	assert.Equal(t, reply, svc.GetHistory("session-2")[1].Content)
+
+	// 第一句即命中时改用兜底话术
+	sentences = nil
+	reply, err = svc.ProcessMessageStream("session-3", "收益", func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "抱歉，这个问题稍后请专人为您解答。", reply)
+	assert.Equal(t, []string{reply}, sentences)
+}
+
+func TestDialogService_SharedRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	cfg := &config.Config{LLM: config.LLMConfig{Provider: config.ProviderMock}}
+	first := services.NewDialogService(cfg)
+	first.SetSessionStore(session.NewRedisStore(client, session.Config{}))
+	_, err := first.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	temperature := 0.2
+	_, err = first.SetSessionOptions("session-1", "1", "", models.GenerationOverrides{Temperature: &temperature})
+	require.NoError(t, err)
+
+	// 另一实例（或重启后）读取到相同的历史和参数
+	second := services.NewDialogService(cfg)
+	second.SetSessionStore(session.NewRedisStore(client, session.Config{}))
+	require.Len(t, second.GetHistory("session-1"), 2)
+	options, err := second.GetSessionOptions("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", options.CampaignID)
+	assert.Equal(t, 0.2, options.Effective.Temperature)
+
+	second.ClearHistory("session-1")
+	assert.Empty(t, first.GetHistory("session-1"))
+	options, err = first.GetSessionOptions("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", options.CampaignID)
+}
+
+func TestDialogService_AppendMessage(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{{Pattern: ".*", Response: "{{.Prompt}}"}},
+			},
+		},
+	})
+
+	require.NoError(t, svc.AppendMessage("session-1", models.Message{Role: models.RoleAssistant, Content: "您好，这里是客服中心"}))
+	require.NoError(t, svc.AppendMessage("session-1", models.Message{Role: models.RoleSystem, Content: "客户昨天已付款"}))
+	assert.ErrorIs(t, svc.AppendMessage("", models.Message{Role: models.RoleSystem, Content: "x"}), models.ErrSessionIDRequired)
+
+	// 追加的话术和背景信息出现在之后轮次的提示词中
+	reply, err := svc.ProcessMessage("session-1", "我的订单呢")
+	require.NoError(t, err)
+	assert.Contains(t, reply, "助手: 您好，这里是客服中心")
+	assert.Contains(t, reply, "背景信息: 客户昨天已付款")
+	assert.Len(t, svc.GetHistory("session-1"), 4)
+}
+
+func TestDialogService_PromptProfiles(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{
+			Provider: config.ProviderMock,
+			Mock: mock.LLMConfig{
+				Rules: []mock.LLMRule{{Pattern: ".*", Response: "{{.Prompt}}"}},
+			},
+			Prompt: prompt.Config{
+				Profile:  prompt.Profile{Persona: "你是客服小美。"},
+				Profiles: map[string]prompt.Profile{"collection": {Persona: "你是还款提醒专员。"}},
+			},
+		},
+	})
+
+	reply, err := svc.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	assert.Equal(t, "你是客服小美。\n用户: 你好\n", reply)
+
+	_, err = svc.SetSessionOptions("session-1", "", "missing", models.GenerationOverrides{})
+	assert.ErrorIs(t, err, prompt.ErrUnknownProfile)
+
+	options, err := svc.SetSessionOptions("session-1", "", "collection", models.GenerationOverrides{})
+	require.NoError(t, err)
+	assert.Equal(t, "collection", options.Prompt)
+	reply, err = svc.ProcessMessage("session-1", "什么事")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(reply, "你是还款提醒专员。\n"), reply)
+}
+
+func TestDialogService_SetSessionModel(t *testing.T) {
+	svc := services.NewDialogService(&config.Config{
+		LLM: config.LLMConfig{Provider: config.ProviderMock},
+	})
+
+	assert.ErrorIs(t, svc.SetSessionModel("", "qwen2:7b"), models.ErrSessionIDRequired)
+	require.NoError(t, svc.SetSessionModel("session-1", "qwen2:7b"))
+	options, err := svc.GetSessionOptions("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen2:7b", options.Model)
+
+	_, err = svc.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	require.NoError(t, svc.SetSessionModel("session-1", ""))
+	options, err = svc.GetSessionOptions("session-1")
+	require.NoError(t, err)
+	assert.Empty(t, options.Model)
+}
+
+// countingLLM 记录调用次数的大模型客户端，流式输出时分两个片段返回
+type countingLLM struct {
+	reply    string
+	fallback bool
+	calls    int
+}
+
+func (c *countingLLM) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	c.calls++
+	return &ollama.GenerateResponse{Response: c.reply, Done: true, Fallback: c.fallback}, nil
+}
+
+func (c *countingLLM) GenerateStream(prompt string, options ollama.Options, callback func(*ollama.GenerateResponse) error) error {
+	c.calls++
+	runes := []rune(c.reply)
+	if err := callback(&ollama.GenerateResponse{Response: string(runes[:len(runes)/2]), Fallback: c.fallback}); err != nil {
+		return err
+	}
+	return callback(&ollama.GenerateResponse{Response: string(runes[len(runes)/2:]), Done: true, Fallback: c.fallback})
+}
+
+func (c *countingLLM) SetRecorder(recorder.Hook) {}
+
+func TestDialogService_Cache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	llm := &countingLLM{reply: "您好，我是智能客服。请问有什么可以帮您"}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetCache(llmcache.New(client, llmcache.Config{Enabled: true}, "ollama:qwen"))
+
+	reply, err := svc.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	assert.Equal(t, llm.reply, reply)
+
+	// 其他通话的相同开场直接使用缓存的回复，流式输出时按句回调
+	var sentences []string
+	reply, err = svc.ProcessMessageStream("session-2", "你好", func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, llm.reply, reply)
+	assert.Equal(t, []string{"您好，我是智能客服。", "请问有什么可以帮您"}, sentences)
+	assert.Equal(t, 1, llm.calls)
+	require.Len(t, svc.GetHistory("session-2"), 2)
+
+	// 对话历史不同时请求大模型
+	_, err = svc.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	assert.Equal(t, 2, llm.calls)
+}
+
+func TestDialogService_CacheSkipsFallback(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	llm := &countingLLM{reply: "系统有点忙，稍后联系您", fallback: true}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetCache(llmcache.New(client, llmcache.Config{Enabled: true}, "ollama:qwen"))
+
+	// 熔断期间的兜底回复不缓存，恢复后重新请求大模型
+	_, err := svc.ProcessMessage("session-1", "你好")
+	require.NoError(t, err)
+	_, err = svc.ProcessMessageStream("session-2", "你好", func(string) error { return nil })
+	require.NoError(t, err)
+	llm.fallback = false
+	_, err = svc.ProcessMessage("session-3", "你好")
+	require.NoError(t, err)
+	assert.Equal(t, 3, llm.calls)
+}
+
+func TestDialogService_HistoryWindow(t *testing.T) {
+	llm := &countingLLM{reply: "好的，已为您记录"}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetHistoryWindow(history.New(history.Config{MaxTokens: 40}, nil))
+
+	for i := 0; i < 5; i++ {
+		_, err := svc.ProcessMessage("session-1", "我想咨询一下宽带套餐")
+		require.NoError(t, err)
+	}
+
+	// 较早的历史被丢弃，会话历史不随轮数无限增长
+	messages := svc.GetHistory("session-1")
+	assert.Less(t, len(messages), 10)
+	assert.LessOrEqual(t, history.Tokens(messages[:len(messages)-1]), 40)
+	assert.Equal(t, "好的，已为您记录", messages[len(messages)-1].Content)
+}
+
+// scriptedLLM 依次返回预设回复并记录提示词的大模型客户端，流式输出时逐字返回
+type scriptedLLM struct {
+	replies []string
+	prompts []string
+}
+
+func (l *scriptedLLM) next(prompt string) string {
+	l.prompts = append(l.prompts, prompt)
+	reply := l.replies[0]
+	l.replies = l.replies[1:]
+	return reply
+}
+
+func (l *scriptedLLM) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	return &ollama.GenerateResponse{Response: l.next(prompt), Done: true}, nil
+}
+
+func (l *scriptedLLM) GenerateStream(prompt string, options ollama.Options, callback func(*ollama.GenerateResponse) error) error {
+	for _, r := range l.next(prompt) {
+		if err := callback(&ollama.GenerateResponse{Response: string(r)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *scriptedLLM) SetRecorder(recorder.Hook) {}
+
+// newToolRegistry 包含转人工和订单查询工具的工具集
+func newToolRegistry(t *testing.T) *tools.Registry {
+	registry, err := tools.New(tools.Config{Enabled: true, MaxRounds: 2, Transfer: tools.TransferConfig{Destination: "2000"}})
+	require.NoError(t, err)
+	require.NoError(t, registry.Add(tools.Tool{
+		Name:        "lookup_order",
+		Description: "按订单号查询订单状态",
+		Parameters:  []tools.Parameter{{Name: "order_id", Required: true}},
+		Handler: func(ctx context.Context, inv tools.Invocation) (tools.Result, error) {
+			return tools.Result{Content: "订单" + inv.Arguments["order_id"] + "已发货"}, nil
+		},
+	}))
+	return registry
+}
+
+func TestDialogService_ToolCalling(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{
+		`{"tool": "lookup_order", "arguments": {"order_id": "A123"}}`,
+		"您的订单已经发货了。",
+	}}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetTools(newToolRegistry(t))
+
+	reply, err := svc.ProcessMessage("session-1", "我的订单A123到哪了")
+	require.NoError(t, err)
+	assert.Equal(t, "您的订单已经发货了。", reply)
+
+	// 提示词开头列出工具，第二次生成时可以看到工具结果
+	require.Len(t, llm.prompts, 2)
+	assert.True(t, strings.HasPrefix(llm.prompts[0], "可用工具"))
+	assert.Contains(t, llm.prompts[1], "工具结果: lookup_order: 订单A123已发货")
+	history := svc.GetHistory("session-1")
+	require.Len(t, history, 4)
+	assert.Equal(t, models.RoleTool, history[2].Role)
+	_, ok := svc.TakeToolAction("session-1")
+	assert.False(t, ok)
+
+	// 连续调用工具超过上限时返回错误
+	call := `{"tool": "lookup_order", "arguments": {"order_id": "A123"}}`
+	llm.replies = []string{call, call, call}
+	_, err = svc.ProcessMessage("session-2", "查订单")
+	assert.ErrorContains(t, err, "连续调用工具超过2次")
+
+	// 失败的一轮中工具要求的转人工被丢弃
+	transfer := `{"tool": "transfer_to_agent", "arguments": {"reason": "客户要求"}}`
+	llm.replies = []string{transfer, call, call}
+	require.NoError(t, svc.BindCall("session-3", "uuid-3"))
+	_, err = svc.ProcessMessage("session-3", "我要找人工")
+	require.Error(t, err)
+	_, ok = svc.TakeToolAction("session-3")
+	assert.False(t, ok)
+}
+
+func TestDialogService_ToolResultWithinHistoryWindow(t *testing.T) {
+	registry, err := tools.New(tools.Config{Enabled: true, MaxRounds: 2})
+	require.NoError(t, err)
+	require.NoError(t, registry.Add(tools.Tool{
+		Name:        "lookup_order",
+		Description: "按订单号查询订单状态",
+		Handler: func(ctx context.Context, inv tools.Invocation) (tools.Result, error) {
+			return tools.Result{Content: strings.Repeat("物流信息", 50)}, nil
+		},
+	}))
+	llm := &scriptedLLM{replies: []string{"好的", "好的", `{"tool": "lookup_order", "arguments": {}}`, "您的订单已经发货了。"}}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetTools(registry)
+	svc.SetHistoryWindow(history.New(history.Config{MaxTokens: 300, KeepRecent: 1}, nil))
+
+	for _, text := range []string{"我想咨询一下宽带套餐", "我的订单到哪了"} {
+		_, err := svc.ProcessMessage("session-1", text)
+		require.NoError(t, err)
+	}
+	_, err = svc.ProcessMessage("session-1", "帮我查一下订单")
+	require.NoError(t, err)
+
+	// 工具结果写入历史后再次生成前重新按预算处理，较早的对话被丢弃
+	require.Len(t, llm.prompts, 4)
+	assert.Contains(t, llm.prompts[3], "物流信息")
+	assert.NotContains(t, llm.prompts[3], "宽带套餐")
+}
+
+func TestDialogService_ToolCallingStream(t *testing.T) {
+	llm := &scriptedLLM{replies: []string{
+		`{"tool": "transfer_to_agent", "arguments": {"reason": "客户要求"}}`,
+		"好的，正在为您转接人工。",
+	}}
+	svc := services.NewDialogServiceWithClient(llm)
+	svc.SetTools(newToolRegistry(t))
+	require.NoError(t, svc.BindCall("session-1", "uuid-1"))
+
+	// 工具调用的JSON不输出给客户
+	var sentences []string
+	reply, err := svc.ProcessMessageStream("session-1", "我要找人工", func(sentence string) error {
+		sentences = append(sentences, sentence)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "好的，正在为您转接人工。", reply)
+	assert.Equal(t, []string{"好的，正在为您转接人工。"}, sentences)
+
+	// 转人工在回复播放完后由调用方执行，只取出一次
+	action, ok := svc.TakeToolAction("session-1")
+	require.True(t, ok)
+	assert.Equal(t, tools.Action{Type: tools.ActionTransfer, Destination: "2000"}, action)
+	_, ok = svc.TakeToolAction("session-1")
+	assert.False(t, ok)
+}
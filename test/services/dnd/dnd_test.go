@@ -0,0 +1,98 @@
+package dnd_test
+
+import (
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/dnd"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChecker(t *testing.T, prefixes map[string][]string) *dnd.Checker {
+	checker, err := dnd.New(dnd.Config{
+		Enabled:            true,
+		Start:              "09:00",
+		End:                "21:00",
+		DefaultCountryCode: "86",
+		DefaultTimezone:    "Asia/Shanghai",
+		Prefixes:           prefixes,
+	})
+	require.NoError(t, err)
+	return checker
+}
+
+func TestChecker_Locations(t *testing.T) {
+	checker := newChecker(t, map[string][]string{"861399910": {"Asia/Urumqi"}})
+
+	cases := map[string][]string{
+		"13800138000":       {"Asia/Shanghai"},
+		"+86 138 0013 8000": {"Asia/Shanghai"},
+		"0991-1234567":      {"Asia/Shanghai", "Asia/Urumqi"},
+		"13999101234":       {"Asia/Urumqi"},
+		"+1 415 555 0100":   {"America/New_York", "America/Chicago", "America/Denver", "America/Los_Angeles"},
+		"0081312345678":     {"Asia/Tokyo"},
+		"+85291234567":      {"Asia/Hong_Kong"},
+		"+999123":           {"Asia/Shanghai"},
+	}
+	for phone, want := range cases {
+		var got []string
+		for _, loc := range checker.Locations(phone) {
+			got = append(got, loc.String())
+		}
+		assert.Equal(t, want, got, phone)
+	}
+}
+
+func TestChecker_Next(t *testing.T) {
+	checker := newChecker(t, nil)
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+
+	// 北京时间上午10点：国内号码允许拨打
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, shanghai)
+	assert.Equal(t, now, checker.Next("13800138000", now))
+
+	// 同一时刻伦敦为凌晨2点，推迟到当地9点
+	london, _ := time.LoadLocation("Europe/London")
+	assert.True(t, checker.Next("+447700900123", now).Equal(time.Date(2024, 3, 1, 9, 0, 0, 0, london)))
+
+	// 北京时间晚上10点推迟到次日9点
+	late := time.Date(2024, 3, 1, 22, 0, 0, 0, shanghai)
+	assert.True(t, checker.Next("13800138000", late).Equal(time.Date(2024, 3, 2, 9, 0, 0, 0, shanghai)))
+
+	// 新疆号码须同时处于北京时间和新疆时间的允许时段，北京时间9点时新疆为7点
+	early := time.Date(2024, 3, 1, 9, 30, 0, 0, shanghai)
+	assert.True(t, checker.Next("09911234567", early).Equal(time.Date(2024, 3, 1, 11, 0, 0, 0, shanghai)))
+
+	// 美国号码须在东西海岸都处于允许时段
+	newYork, _ := time.LoadLocation("America/New_York")
+	morning := time.Date(2024, 3, 1, 8, 0, 0, 0, newYork)
+	assert.True(t, checker.Next("+14155550100", morning).Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, newYork)))
+}
+
+func TestChecker_NextNil(t *testing.T) {
+	var checker *dnd.Checker
+	now := time.Now()
+	assert.Equal(t, now, checker.Next("13800138000", now))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := dnd.Config{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "86"}
+	assert.NoError(t, valid.Validate())
+
+	invalid := []dnd.Config{
+		{Enabled: true, Start: "9点", End: "21:00", DefaultCountryCode: "86"},
+		{Enabled: true, Start: "21:00", End: "09:00", DefaultCountryCode: "86"},
+		{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "+86"},
+		{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "86", DefaultTimezone: "Mars/Base"},
+		{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "86", Prefixes: map[string][]string{"86991": nil}},
+		{Enabled: true, Start: "09:00", End: "21:00", DefaultCountryCode: "86", Prefixes: map[string][]string{"86991": {"Asia/Nowhere"}}},
+	}
+	for _, config := range invalid {
+		assert.Error(t, config.Validate(), "%+v", config)
+	}
+
+	// 未启用时不校验
+	assert.NoError(t, dnd.Config{Start: "bad"}.Validate())
+}
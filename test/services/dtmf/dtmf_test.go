@@ -0,0 +1,143 @@
+package dtmf_test
+
+import (
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/dtmf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() dtmf.Config {
+	return dtmf.Config{
+		Enabled:           true,
+		Timeout:           50 * time.Millisecond,
+		InterDigitTimeout: 30 * time.Millisecond,
+		Menus: map[string]dtmf.Menu{
+			"confirm": {
+				Triggers:     []string{"确认请按1"},
+				Options:      map[string]dtmf.Option{"1": {Input: "确认办理"}, "2": {Input: "暂不办理"}},
+				TimeoutInput: "客户没有按键",
+			},
+			"verify": {
+				Triggers:   []string{"身份证后四位"},
+				MaxDigits:  4,
+				Terminator: "#",
+			},
+		},
+	}
+}
+
+// open 打开菜单，结果写入返回的通道
+func open(t *testing.T, config dtmf.Config, name string) (*dtmf.Collector, chan dtmf.Result) {
+	results := make(chan dtmf.Result, 1)
+	collector, err := config.Open(name, func(result dtmf.Result) { results <- result })
+	require.NoError(t, err)
+	t.Cleanup(collector.Close)
+	return collector, results
+}
+
+func receive(t *testing.T, results chan dtmf.Result) dtmf.Result {
+	select {
+	case result := <-results:
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("等待按键菜单结果超时")
+		return dtmf.Result{}
+	}
+}
+
+func TestCollector_Option(t *testing.T) {
+	collector, results := open(t, testConfig(), "confirm")
+	assert.True(t, collector.Add("1"))
+
+	result := receive(t, results)
+	assert.Equal(t, dtmf.Result{Menu: "confirm", Digits: "1", Input: "确认办理"}, result)
+	assert.True(t, collector.Closed())
+	assert.False(t, collector.Add("2"), "菜单结束后不再接收按键")
+}
+
+func TestCollector_UnknownOption(t *testing.T) {
+	collector, results := open(t, testConfig(), "confirm")
+	collector.Add("9")
+	assert.Equal(t, "客户按键9", receive(t, results).Input)
+}
+
+func TestCollector_Timeout(t *testing.T) {
+	_, results := open(t, testConfig(), "confirm")
+	result := receive(t, results)
+	assert.True(t, result.TimedOut)
+	assert.Equal(t, "客户没有按键", result.Input)
+}
+
+func TestCollector_MaxDigitsAndTerminator(t *testing.T) {
+	collector, results := open(t, testConfig(), "verify")
+	for _, digit := range []string{"1", "2", "3", "4"} {
+		collector.Add(digit)
+	}
+	assert.Equal(t, "1234", receive(t, results).Digits)
+
+	collector, results = open(t, testConfig(), "verify")
+	collector.Add("5")
+	collector.Add("#")
+	result := receive(t, results)
+	assert.Equal(t, "5", result.Digits)
+	assert.Equal(t, "客户按键5", result.Input)
+}
+
+func TestCollector_InterDigitTimeout(t *testing.T) {
+	collector, results := open(t, testConfig(), "verify")
+	collector.Add("1")
+	collector.Add("2")
+
+	result := receive(t, results)
+	assert.Equal(t, "12", result.Digits)
+	assert.False(t, result.TimedOut)
+}
+
+func TestCollector_Close(t *testing.T) {
+	collector, results := open(t, testConfig(), "confirm")
+	collector.Close()
+	select {
+	case result := <-results:
+		t.Fatalf("关闭后不应回调: %+v", result)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestConfig_Match(t *testing.T) {
+	config := testConfig()
+	name, ok := config.Match("好的，确认请按1，取消请按2")
+	assert.True(t, ok)
+	assert.Equal(t, "confirm", name)
+
+	_, ok = config.Match("请问还有什么可以帮您？")
+	assert.False(t, ok)
+
+	config.Enabled = false
+	_, ok = config.Match("确认请按1")
+	assert.False(t, ok)
+
+	_, err := testConfig().Open("unknown", func(dtmf.Result) {})
+	assert.Error(t, err)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, testConfig().Validate())
+	assert.NoError(t, dtmf.Config{Timeout: -1}.Validate())
+
+	invalid := []dtmf.Config{
+		{Enabled: true, Timeout: -time.Second},
+		{Enabled: true, InterDigitTimeout: -time.Second},
+		{Enabled: true, Menus: map[string]dtmf.Menu{"m": {MaxDigits: -1}}},
+		{Enabled: true, Menus: map[string]dtmf.Menu{"m": {Terminator: "x"}}},
+		{Enabled: true, Menus: map[string]dtmf.Menu{"m": {Terminator: "##"}}},
+		{Enabled: true, Menus: map[string]dtmf.Menu{"m": {Options: map[string]dtmf.Option{"1a": {}}}}},
+		{Enabled: true, Menus: map[string]dtmf.Menu{"m": {Triggers: []string{" "}}}},
+	}
+	for i, config := range invalid {
+		assert.Error(t, config.Validate(), "%d", i)
+	}
+}
@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newInstance 模拟一个服务实例：推送中心加事件桥接
+func newInstance(t *testing.T, ctx context.Context, client *goredis.Client) (*services.WSService, *services.EventBridge) {
+	hub := services.NewWSService()
+	go hub.Run()
+	bridge := services.NewEventBridge(client, "test:events", hub)
+	go bridge.Run(ctx)
+	return hub, bridge
+}
+
+// dialHub 以监控面板身份连接推送中心
+func dialHub(t *testing.T, hub *services.WSService) *websocket.Conn {
+	ts := httptest.NewServer(http.HandlerFunc(hub.HandleConnection))
+	t.Cleanup(ts.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestEventBridge_CrossInstance(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clientA := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	clientB := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer clientA.Close()
+	defer clientB.Close()
+
+	_, bridgeA := newInstance(t, ctx, clientA)
+	hubB, _ := newInstance(t, ctx, clientB)
+	dashboard := dialHub(t, hubB)
+
+	// 等待两个实例完成订阅
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub("test:events")["test:events"] == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	err := bridgeA.Publish(ctx, &models.CallEvent{
+		Type:      models.EventTypeTranscript,
+		SessionID: "call-1",
+		Speaker:   models.SpeakerCustomer,
+		Text:      "你好",
+		IsFinal:   true,
+	})
+	require.NoError(t, err)
+
+	dashboard.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := dashboard.ReadMessage()
+	require.NoError(t, err)
+
+	var event models.CallEvent
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, "call-1", event.SessionID)
+	assert.Equal(t, "你好", event.Text)
+	assert.NotEmpty(t, event.Instance)
+	assert.False(t, event.Timestamp.IsZero())
+}
+
+func TestEventBridge_LocalOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hub, bridge := newInstance(t, ctx, nil)
+	dashboard := dialHub(t, hub)
+
+	// 等待连接注册到推送中心
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, bridge.Publish(ctx, &models.CallEvent{Type: models.EventTypeDialog, Text: "您好，请问有什么可以帮您"}))
+
+	dashboard.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := dashboard.ReadMessage()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "您好，请问有什么可以帮您")
+}
@@ -0,0 +1,85 @@
+package fallback_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/fallback"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitor_Threshold(t *testing.T) {
+	m := fallback.NewMonitor(fallback.Config{FailureThreshold: 2, Default: fallback.Policy{Action: fallback.ActionPause}}, nil)
+	assert.True(t, m.Available())
+
+	m.ReportFailure(errors.New("timeout"))
+	assert.True(t, m.Available())
+	m.ReportSuccess()
+	m.ReportFailure(errors.New("timeout"))
+	assert.True(t, m.Available(), "成功后重新计数")
+
+	m.ReportFailure(errors.New("timeout"))
+	assert.False(t, m.Available())
+	policy, degraded := m.Policy(nil)
+	assert.True(t, degraded)
+	assert.Equal(t, fallback.ActionPause, policy.Action)
+
+	m.ReportSuccess()
+	assert.True(t, m.Available())
+}
+
+func TestMonitor_CampaignPolicy(t *testing.T) {
+	m := fallback.NewMonitor(fallback.Config{
+		FailureThreshold: 1,
+		Campaigns:        map[string]fallback.Policy{"3": {Action: fallback.ActionHuman, Queue: "8000"}},
+	}, nil)
+	m.ReportFailure(errors.New("down"))
+
+	// 默认策略未配置时照常拨打
+	_, degraded := m.Policy(nil)
+	assert.False(t, degraded)
+
+	id := int64(3)
+	policy, degraded := m.Policy(&id)
+	assert.True(t, degraded)
+	assert.Equal(t, "8000", policy.Queue)
+}
+
+func TestMonitor_NilIsAvailable(t *testing.T) {
+	var m *fallback.Monitor
+	assert.True(t, m.Available())
+	_, degraded := m.Policy(nil)
+	assert.False(t, degraded)
+}
+
+func TestMonitor_ProbeRecovers(t *testing.T) {
+	probed := make(chan struct{}, 1)
+	m := fallback.NewMonitor(fallback.Config{FailureThreshold: 1, ProbeInterval: 10 * time.Millisecond}, func(ctx context.Context) error {
+		select {
+		case probed <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	m.ReportFailure(errors.New("down"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	<-probed
+	assert.Eventually(t, m.Available, time.Second, 10*time.Millisecond)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, fallback.Config{}.Validate())
+	assert.Error(t, fallback.Config{Default: fallback.Policy{Action: fallback.ActionHuman}}.Validate())
+	assert.Error(t, fallback.Config{Campaigns: map[string]fallback.Policy{"1": {Action: fallback.ActionApology}}}.Validate())
+	assert.Error(t, fallback.Config{Default: fallback.Policy{Action: "hangup"}}.Validate())
+
+	now := time.Now()
+	assert.Equal(t, now.Add(fallback.DefaultCallbackDelay), fallback.Policy{}.CallbackAt(now))
+}
@@ -0,0 +1,84 @@
+package filler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/mock"
+	"ai_dialer_mini/internal/services/filler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPool 创建使用模拟语音合成的应答语音池
+func newPool(t *testing.T, threshold time.Duration) *filler.Pool {
+	provider, err := mock.NewTTSClient(mock.TTSConfig{CharDuration: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	pool, err := filler.New(context.Background(), provider, filler.Config{
+		Phrases:   []string{"嗯", "好的"},
+		Threshold: threshold,
+	})
+	require.NoError(t, err)
+	return pool
+}
+
+func TestPool_FiresAfterThreshold(t *testing.T) {
+	pool := newPool(t, 20*time.Millisecond)
+
+	turn := pool.Start()
+	select {
+	case <-turn.C():
+	case <-time.After(time.Second):
+		t.Fatal("超过阈值后未触发应答语音")
+	}
+
+	// 应答语音轮流播放
+	first, second, third := turn.Clip(), turn.Clip(), turn.Clip()
+	assert.Equal(t, 100*time.Millisecond, first.Duration())
+	assert.Equal(t, 200*time.Millisecond, second.Duration())
+	assert.Same(t, first, third)
+	turn.Responded()
+	assert.Greater(t, pool.Predicted(), 20*time.Millisecond)
+
+	// 预测耗时超过阈值时立即触发
+	turn = pool.Start()
+	select {
+	case <-turn.C():
+	case <-time.After(10 * time.Millisecond):
+		t.Fatal("预测较慢时未立即触发应答语音")
+	}
+	turn.Responded()
+}
+
+func TestPool_QuickResponse(t *testing.T) {
+	pool := newPool(t, time.Second)
+
+	turn := pool.Start()
+	turn.Responded()
+	select {
+	case <-turn.C():
+		t.Fatal("回复及时就绪时不应播放应答语音")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Less(t, pool.Predicted(), time.Second)
+}
+
+func TestPool_Nil(t *testing.T) {
+	var pool *filler.Pool
+	turn := pool.Start()
+	assert.Nil(t, turn.C())
+	turn.Responded()
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	provider, err := mock.NewTTSClient(mock.TTSConfig{})
+	require.NoError(t, err)
+
+	_, err = filler.New(context.Background(), provider, filler.Config{Threshold: time.Second})
+	assert.Error(t, err)
+	_, err = filler.New(context.Background(), provider, filler.Config{Phrases: []string{"嗯"}})
+	assert.Error(t, err)
+}
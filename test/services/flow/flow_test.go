@@ -0,0 +1,97 @@
+package flow_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/flow"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/prompt"
+	"ai_dialer_mini/internal/services/promptaudio"
+	"ai_dialer_mini/internal/services/turn"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validScenario 没有问题的通话流程
+func validScenario() flow.Scenario {
+	return flow.Scenario{
+		Prompt: prompt.Config{Profiles: map[string]prompt.Profile{"bank": {Persona: "你是银行客服"}}},
+		Inbound: inbound.Config{Enabled: true, Numbers: map[string]inbound.Route{
+			"4001": {Persona: "bank", Greeting: "audio:greeting|您好，确认请按1"},
+		}},
+		Intent: intent.Config{Enabled: true, Rules: []intent.Rule{
+			{Name: "refuse", Keywords: []string{"不需要"}, Action: intent.ActionHangup},
+			{Name: "agent", Keywords: []string{"转人工"}, Action: intent.ActionTransfer, Destination: "1000"},
+		}},
+		DTMF: dtmf.Config{Enabled: true, Menus: map[string]dtmf.Menu{
+			"confirm": {Triggers: []string{"确认请按1"}, Options: map[string]dtmf.Option{"1": {Input: "确认"}}},
+		}},
+		Turn: turn.Config{HoldReply: "好的，您慢慢来"},
+	}
+}
+
+func newLinter(t *testing.T) *flow.Linter {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	library := promptaudio.New(store, promptaudio.Config{Enabled: true})
+	wav := (&tts.Audio{PCM: make([]byte, 3200), SampleRate: 16000, Channels: 1}).WAV()
+	_, err = library.Upload(context.Background(), promptaudio.Prompt{Name: "greeting", Text: "您好，确认请按1"}, wav)
+	require.NoError(t, err)
+	return flow.NewLinter(library)
+}
+
+func codes(report flow.Report) map[string]string {
+	result := make(map[string]string)
+	for _, d := range report.Diagnostics {
+		result[d.Path] = d.Severity + "/" + d.Code
+	}
+	return result
+}
+
+func TestLinter_ValidScenario(t *testing.T) {
+	report := newLinter(t).Lint(context.Background(), validScenario())
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Diagnostics)
+}
+
+func TestLinter_Diagnostics(t *testing.T) {
+	scenario := validScenario()
+	scenario.Prompt.Profiles["sales"] = prompt.Profile{Template: "{{.Persona}} {{.Customer}}"}
+	scenario.Inbound.Numbers["4002"] = inbound.Route{Greeting: "audio:notice"}
+	scenario.Intent.Rules = append(scenario.Intent.Rules,
+		intent.Rule{Name: "refuse_firm", Keywords: []string{"真的不需要"}, Action: intent.ActionHangup})
+	scenario.DTMF.Menus["survey"] = dtmf.Menu{Options: map[string]dtmf.Option{"12": {Input: "满意"}, "3": {}}}
+	scenario.Turn.ResumeReply = "audio:resume|我们继续"
+
+	report := newLinter(t).Lint(context.Background(), scenario)
+	assert.False(t, report.Valid)
+	assert.Equal(t, map[string]string{
+		"prompt.profiles.sales.template":    "error/undefined_variable",
+		"inbound.numbers.4002.greeting":     "error/missing_prompt",
+		"turn.resume_reply":                 "warning/missing_prompt",
+		"intent.rules[2].keywords":          "warning/unreachable",
+		"dtmf.menus.survey.triggers":        "warning/unreachable",
+		"dtmf.menus.survey.options.12":      "error/invalid_transition",
+		"dtmf.menus.survey.options.3.input": "warning/invalid_transition",
+	}, codes(report))
+	// error级别在前
+	assert.Equal(t, flow.SeverityError, report.Diagnostics[0].Severity)
+}
+
+func TestLinter_InvalidSections(t *testing.T) {
+	scenario := validScenario()
+	scenario.Inbound.Numbers["4001"] = inbound.Route{Persona: "unknown"}
+	scenario.DTMF.Timeout = -time.Second
+
+	report := flow.NewLinter(nil).Lint(context.Background(), scenario)
+	assert.False(t, report.Valid)
+	assert.Equal(t, "error/invalid_transition", codes(report)["inbound"])
+	assert.Equal(t, "error/invalid", codes(report)["dtmf"])
+}
@@ -0,0 +1,69 @@
+package flow_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/dtmf"
+	"ai_dialer_mini/internal/services/flow"
+	"ai_dialer_mini/internal/services/inbound"
+	"ai_dialer_mini/internal/services/intent"
+	"ai_dialer_mini/internal/services/turn"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportScenario 包含呼入、意图、按键菜单和暂停的通话流程
+func exportScenario() flow.Scenario {
+	return flow.Scenario{
+		Inbound: inbound.Config{Enabled: true, Numbers: map[string]inbound.Route{
+			"4001": {Persona: "bank", Greeting: "audio:greeting|您好"},
+		}},
+		Intent: intent.Config{Enabled: true, Rules: []intent.Rule{
+			{Name: "refuse", Keywords: []string{"不需要"}, Action: intent.ActionHangup, Reply: `好的，"打扰"了`},
+		}},
+		DTMF: dtmf.Config{Enabled: true, Menus: map[string]dtmf.Menu{
+			"confirm": {Triggers: []string{"确认请按1"}, Options: map[string]dtmf.Option{"1": {Input: "确认"}}},
+		}},
+		Turn: turn.Config{HoldPhrases: []string{"稍等"}, HoldReply: "好的"},
+	}
+}
+
+func TestBuildGraph_Mermaid(t *testing.T) {
+	graph := flow.BuildGraph(exportScenario())
+	assert.Equal(t, `flowchart TD
+    n0["AI对话"]
+    n1(["外呼接通"])
+    n2(["呼入 4001"])
+    n3("提示音 greeting: 您好")
+    n4(("挂机"))
+    n5("AI: 好的，#quot;打扰#quot;了")
+    n6{"按键菜单 confirm"}
+    n7("AI: 好的")
+    n1 -->|"默认人设"| n0
+    n2 -->|"自动应答"| n3
+    n3 -->|"人设 bank"| n0
+    n0 -->|"意图 refuse: 不需要"| n5
+    n5 --> n4
+    n0 -->|"AI: 确认请按1"| n6
+    n6 -->|"按1 → 确认"| n0
+    n6 -->|"超时"| n0
+    n0 -->|"客户: 稍等"| n7
+    n7 -->|"客户再次开口"| n0
+`, graph.Mermaid())
+
+	// 同一流程多次导出的结果相同
+	assert.Equal(t, graph.Mermaid(), flow.BuildGraph(exportScenario()).Mermaid())
+}
+
+func TestBuildGraph_Graphviz(t *testing.T) {
+	text, err := flow.BuildGraph(exportScenario()).Render(flow.FormatGraphviz)
+	require.NoError(t, err)
+	assert.Contains(t, text, "digraph flow {")
+	assert.Contains(t, text, `n5 [label="AI: 好的，\"打扰\"了", shape=box, style=rounded];`)
+	assert.Contains(t, text, `n0 -> n6 [label="AI: 确认请按1"];`)
+	assert.Contains(t, text, "n5 -> n4;")
+
+	_, err = flow.Graph{}.Render("svg")
+	assert.Error(t, err)
+}
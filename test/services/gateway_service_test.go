@@ -0,0 +1,73 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayService_DegradesLowASR(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	svc := services.NewGatewayService(repositories.NewStore(db), config.RoutingConfig{
+		Gateways: []string{"carrier-a", "carrier-b", "carrier-c"},
+		Window:   time.Hour,
+		MinCalls: 50,
+		MinASR:   0.2,
+	})
+	assert.Equal(t, []string{"carrier-a", "carrier-b", "carrier-c"}, svc.Gateways())
+
+	columns := []string{"gateway", "calls", "answered", "billsec"}
+	mock.ExpectQuery("SELECT gateway, COUNT(.+) FROM cdr").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("carrier-a", 100, 5, 300).  // 接通率5%，降级
+			AddRow("carrier-b", 10, 0, 0).     // 呼叫次数不足，不判断
+			AddRow("carrier-c", 80, 40, 2400)) // 接通率50%
+	require.NoError(t, svc.Refresh(context.Background()))
+	assert.Equal(t, []string{"carrier-b", "carrier-c", "carrier-a"}, svc.Gateways())
+
+	// 接通率恢复后重新按配置顺序路由
+	mock.ExpectQuery("SELECT gateway, COUNT(.+) FROM cdr").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("carrier-a", 100, 30, 1800))
+	require.NoError(t, svc.Refresh(context.Background()))
+	assert.Equal(t, []string{"carrier-a", "carrier-b", "carrier-c"}, svc.Gateways())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGatewayService_Stats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	svc := services.NewGatewayService(repositories.NewStore(db), config.RoutingConfig{
+		Gateways: []string{"carrier-a", "carrier-b"},
+		Window:   time.Hour,
+	})
+
+	mock.ExpectQuery("SELECT gateway, COUNT(.+) FROM cdr").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"gateway", "calls", "answered", "billsec"}).
+			AddRow("carrier-a", 4, 2, 90))
+	stats, err := svc.Stats(context.Background(), 24*time.Hour, "")
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+
+	assert.Equal(t, "carrier-a", stats[0].Gateway)
+	assert.InDelta(t, 0.5, stats[0].ASR, 1e-9)
+	assert.InDelta(t, 45, stats[0].ACD, 1e-9)
+	assert.False(t, stats[0].Degraded)
+
+	// 窗口内没有呼叫的网关也会列出
+	assert.Equal(t, "carrier-b", stats[1].Gateway)
+	assert.Equal(t, 0, stats[1].Calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
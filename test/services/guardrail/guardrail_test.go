@@ -0,0 +1,118 @@
+package guardrail_test
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/services/guardrail"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func filteredCount(t *testing.T, reason, action string) float64 {
+	var out strings.Builder
+	require.NoError(t, metrics.Default.Write(&out))
+	prefix := `ai_dialer_llm_filtered_total{reason="` + reason + `",action="` + action + `"} `
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), prefix); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return n
+		}
+	}
+	return 0
+}
+
+func TestNew_Disabled(t *testing.T) {
+	filter, err := guardrail.New(guardrail.Config{Fallback: "抱歉"})
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+	assert.Equal(t, "原样返回", filter.Apply("原样返回"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.ErrorContains(t, guardrail.Config{MaxLength: -1}.Validate(), "max_length")
+	assert.ErrorContains(t, guardrail.Config{Patterns: []string{"("}}.Validate(), "patterns[0]")
+	assert.ErrorContains(t, guardrail.Config{Keywords: []string{""}}.Validate(), "keywords[0]")
+	assert.ErrorContains(t, guardrail.Config{Action: "block"}.Validate(), "action")
+	assert.ErrorContains(t, guardrail.Config{Action: guardrail.ActionFallback}.Validate(), "fallback")
+	assert.NoError(t, guardrail.Config{Action: guardrail.ActionFallback, Fallback: "抱歉"}.Validate())
+}
+
+func TestFilter_Strip(t *testing.T) {
+	filter, err := guardrail.New(guardrail.Config{
+		Patterns: []string{`1[3-9]\d{9}`},
+		Keywords: []string{"稳赚不赔", "VIP"},
+	})
+	require.NoError(t, err)
+
+	before := filteredCount(t, guardrail.ReasonPattern, guardrail.ActionStrip)
+	assert.Equal(t, "请拨打咨询。", filter.Apply("请拨打13800138000咨询。"))
+	assert.Equal(t, before+1, filteredCount(t, guardrail.ReasonPattern, guardrail.ActionStrip))
+	assert.Equal(t, "这款产品，欢迎办理会员。", filter.Apply("这款产品稳赚不赔，欢迎办理vip会员。"))
+	assert.Equal(t, "您好。", filter.Apply("您好。"))
+}
+
+func TestFilter_Fallback(t *testing.T) {
+	filter, err := guardrail.New(guardrail.Config{
+		Keywords: []string{"保证收益"},
+		Action:   guardrail.ActionFallback,
+		Fallback: "抱歉，这个问题稍后请专人为您解答。",
+	})
+	require.NoError(t, err)
+
+	before := filteredCount(t, guardrail.ReasonKeyword, guardrail.ActionFallback)
+	assert.Equal(t, "抱歉，这个问题稍后请专人为您解答。", filter.Apply("我们保证收益10%。"))
+	assert.Equal(t, before+1, filteredCount(t, guardrail.ReasonKeyword, guardrail.ActionFallback))
+
+	// 空回复也改用兜底话术
+	before = filteredCount(t, guardrail.ReasonEmpty, guardrail.ActionFallback)
+	assert.Equal(t, "抱歉，这个问题稍后请专人为您解答。", filter.Apply("  "))
+	assert.Equal(t, before+1, filteredCount(t, guardrail.ReasonEmpty, guardrail.ActionFallback))
+}
+
+func TestFilter_MaxLength(t *testing.T) {
+	filter, err := guardrail.New(guardrail.Config{MaxLength: 11})
+	require.NoError(t, err)
+
+	before := filteredCount(t, guardrail.ReasonLength, guardrail.ActionStrip)
+	assert.Equal(t, "您好，套餐每月99元。", filter.Apply("您好，套餐每月99元。需要为您办理吗？"))
+	assert.Equal(t, before+1, filteredCount(t, guardrail.ReasonLength, guardrail.ActionStrip))
+	assert.Equal(t, "一二三四五六七八九十十", filter.Apply("一二三四五六七八九十十一二"))
+}
+
+func TestStream(t *testing.T) {
+	filter, err := guardrail.New(guardrail.Config{
+		MaxLength: 12,
+		Keywords:  []string{"保证收益"},
+		Action:    guardrail.ActionFallback,
+		Fallback:  "抱歉。",
+	})
+	require.NoError(t, err)
+
+	// 已输出的句子保留，命中后丢弃后续内容
+	stream := filter.Stream()
+	assert.Equal(t, "您好。", stream.Write("您好。"))
+	assert.Empty(t, stream.Write("我们保证收益。"))
+	assert.Empty(t, stream.Write("欢迎办理。"))
+	assert.Empty(t, stream.Close())
+	assert.Equal(t, "您好。", stream.Reply())
+
+	// 第一句即命中时改用兜底话术
+	stream = filter.Stream()
+	assert.Equal(t, "抱歉。", stream.Write("保证收益。"))
+	assert.Empty(t, stream.Close())
+	assert.Equal(t, "抱歉。", stream.Reply())
+
+	// 超过长度时截断
+	stream = filter.Stream()
+	assert.Equal(t, "套餐每月99元。", stream.Write("套餐每月99元。"))
+	assert.Equal(t, "需要为您", stream.Write("需要为您办理吗？"))
+	assert.Empty(t, stream.Write("还有别的问题吗？"))
+	assert.Equal(t, "套餐每月99元。需要为您", stream.Reply())
+}
@@ -0,0 +1,124 @@
+package history_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/history"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGenerator 返回固定摘要并记录请求的大模型客户端
+type stubGenerator struct {
+	reply   string
+	err     error
+	prompt  string
+	options ollama.Options
+}
+
+func (g *stubGenerator) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	g.prompt, g.options = prompt, options
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &ollama.GenerateResponse{Response: g.reply, Done: true}, nil
+}
+
+// conversation 每条消息10个汉字（约12个token）的对话
+func conversation(n int) []models.Message {
+	messages := make([]models.Message, n)
+	for i := range messages {
+		role := models.RoleUser
+		if i%2 == 1 {
+			role = models.RoleAssistant
+		}
+		messages[i] = models.Message{Role: role, Content: strings.Repeat(string(rune('一'+i)), 10)}
+	}
+	return messages
+}
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 4, history.EstimateTokens("你好世界"))
+	assert.Equal(t, 3, history.EstimateTokens("hello world"))
+	assert.Equal(t, 3, history.EstimateTokens("套餐 99"))
+	assert.Zero(t, history.EstimateTokens(" \n"))
+}
+
+func TestWindow_FitDropsOldest(t *testing.T) {
+	assert.Nil(t, history.New(history.Config{}, nil))
+	var nilWindow *history.Window
+	messages := conversation(6)
+	assert.Equal(t, messages, nilWindow.Fit(context.Background(), messages, 0))
+
+	w := history.New(history.Config{MaxTokens: 50}, nil)
+	// 未超出预算时原样返回
+	assert.Equal(t, messages[:4], w.Fit(context.Background(), messages[:4], 0))
+
+	// 固定部分占用14个token，只放得下最近3条
+	fitted := w.Fit(context.Background(), messages, 14)
+	assert.Equal(t, messages[3:], fitted)
+
+	// 最近keep_recent条即使超出预算也保留
+	w = history.New(history.Config{MaxTokens: 10, KeepRecent: 2}, nil)
+	assert.Equal(t, messages[4:], w.Fit(context.Background(), messages, 0))
+}
+
+func TestWindow_FitSummarizes(t *testing.T) {
+	generator := &stubGenerator{reply: " 客户咨询套餐价格，已告知每月99元 "}
+	w := history.New(history.Config{
+		MaxTokens: 60,
+		Summarize: history.SummaryConfig{Enabled: true, Model: "qwen:0.5b", MaxTokens: 20},
+	}, generator)
+	messages := conversation(6)
+
+	// 预留摘要的22个token后只放得下最近3条，较早的3条摘要为一条背景信息
+	fitted := w.Fit(context.Background(), messages, 0)
+	require.Len(t, fitted, 4)
+	assert.Equal(t, models.Message{Role: models.RoleSystem, Content: history.SummaryPrefix + "客户咨询套餐价格，已告知每月99元"}, fitted[0])
+	assert.Equal(t, messages[3:], fitted[1:])
+	assert.Equal(t, "qwen:0.5b", generator.options.Model)
+	assert.Equal(t, 20, generator.options.MaxTokens)
+	assert.Contains(t, generator.prompt, "客户: "+messages[0].Content)
+	assert.Contains(t, generator.prompt, "客服: "+messages[1].Content)
+	assert.NotContains(t, generator.prompt, messages[3].Content)
+
+	// 再次超出预算时上一次的摘要与较早的消息一起重新摘要
+	fitted = w.Fit(context.Background(), append(fitted, conversation(2)...), 0)
+	assert.Contains(t, generator.prompt, "背景信息: 客户咨询套餐价格")
+	assert.Equal(t, models.RoleSystem, fitted[0].Role)
+
+	// 摘要失败时丢弃较早的消息
+	generator.err = errors.New("timeout")
+	fitted = w.Fit(context.Background(), messages, 0)
+	assert.Equal(t, messages[3:], fitted)
+}
+
+func TestWindow_FitTruncatesOversizedSummary(t *testing.T) {
+	// 大模型不遵守max_tokens时返回的长摘要
+	generator := &stubGenerator{reply: strings.Repeat("客户咨询套餐价格", 10)}
+	w := history.New(history.Config{
+		MaxTokens: 60,
+		Summarize: history.SummaryConfig{Enabled: true, MaxTokens: 20},
+	}, generator)
+
+	fitted := w.Fit(context.Background(), conversation(6), 0)
+	require.Len(t, fitted, 4)
+	summary := strings.TrimPrefix(fitted[0].Content, history.SummaryPrefix)
+	assert.Equal(t, 20, history.EstimateTokens(summary))
+	assert.True(t, strings.HasPrefix(generator.reply, summary))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, history.Config{}.Validate())
+	assert.ErrorContains(t, history.Config{MaxTokens: -1}.Validate(), "max_tokens")
+	assert.ErrorContains(t, history.Config{
+		MaxTokens: 100,
+		Summarize: history.SummaryConfig{Enabled: true, MaxTokens: 100},
+	}.Validate(), "summarize.max_tokens")
+}
@@ -0,0 +1,69 @@
+package hooks_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/hooks"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingHooks 记录收到的事件，只实现关心的事件
+type recordingHooks struct {
+	hooks.Base
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordingHooks) OnTranscriptFinal(_ context.Context, transcript hooks.Transcript) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, "transcript:"+transcript.Text)
+}
+
+func (h *recordingHooks) OnHangup(_ context.Context, cdr models.CDR) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, "hangup:"+cdr.HangupCause)
+}
+
+// panicHooks 每个事件都panic
+type panicHooks struct{ hooks.Base }
+
+func (panicHooks) OnHangup(context.Context, models.CDR) { panic("boom") }
+
+func TestDispatcher_CallsHooksInOrder(t *testing.T) {
+	first, second := &recordingHooks{}, &recordingHooks{}
+	d := hooks.New(first, panicHooks{}, second)
+	ctx := context.Background()
+
+	d.CallAnswered(ctx, hooks.Call{UUID: "uuid-1"})
+	d.TranscriptFinal(ctx, hooks.Transcript{Text: "你好"})
+	d.DialogTurn(ctx, hooks.Turn{Reply: "您好"})
+	// 前一个钩子panic不影响后面的钩子
+	d.Hangup(ctx, models.CDR{HangupCause: "NORMAL_CLEARING"})
+
+	assert.Equal(t, []string{"transcript:你好", "hangup:NORMAL_CLEARING"}, first.events)
+	assert.Equal(t, first.events, second.events)
+	assert.Equal(t, 3, d.Len())
+}
+
+func TestDispatcher_Nil(t *testing.T) {
+	assert.Nil(t, hooks.New())
+	var d *hooks.Dispatcher
+	assert.Equal(t, 0, d.Len())
+	assert.NotPanics(t, func() { d.Hangup(context.Background(), models.CDR{}) })
+}
+
+func TestRegister(t *testing.T) {
+	h := &recordingHooks{}
+	hooks.Register(h)
+
+	d := hooks.Default()
+	assert.Equal(t, 1, d.Len())
+	d.TranscriptFinal(context.Background(), hooks.Transcript{Text: "喂"})
+	assert.Equal(t, []string{"transcript:喂"}, h.events)
+}
@@ -0,0 +1,55 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotencyService(t *testing.T) (*services.IdempotencyService, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return services.NewIdempotencyService(client, 24*time.Hour), mr
+}
+
+func TestIdempotencyService_LockThenFullTTL(t *testing.T) {
+	svc, mr := newIdempotencyService(t)
+
+	var out string
+	replayed, err := svc.Do(context.Background(), "key-1", "fp", &out, func() (interface{}, error) {
+		// 处理期间只短暂锁定
+		ttl := mr.TTL("idempotency:key-1")
+		assert.True(t, ttl > 0 && ttl <= time.Minute, "处理中的有效期: %v", ttl)
+		return "uuid-1", nil
+	})
+	require.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, "uuid-1", out)
+
+	// 保存结果后延长到完整窗口期
+	assert.Equal(t, 24*time.Hour, mr.TTL("idempotency:key-1"))
+}
+
+func TestIdempotencyService_ReleaseAfterCancel(t *testing.T) {
+	svc, mr := newIdempotencyService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var out string
+	_, err := svc.Do(ctx, "key-2", "fp", &out, func() (interface{}, error) {
+		cancel()
+		return nil, errors.New("客户端已断开")
+	})
+	assert.EqualError(t, err, "客户端已断开")
+
+	// 请求被取消后仍释放key，客户端可以重试
+	assert.False(t, mr.Exists("idempotency:key-2"))
+}
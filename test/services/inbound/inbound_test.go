@@ -0,0 +1,86 @@
+package inbound_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/inbound"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func inboundHeaders(profile, callee string) map[string]string {
+	return map[string]string{
+		"Call-Direction":              "inbound",
+		"variable_sofia_profile_name": profile,
+		"Caller-Destination-Number":   callee,
+	}
+}
+
+func TestRouter_Match(t *testing.T) {
+	router := inbound.New(inbound.Config{
+		Enabled:  true,
+		Profiles: []string{"external"},
+		Numbers: map[string]inbound.Route{
+			"4008001234": {Persona: "collection", Greeting: "您好"},
+		},
+	})
+
+	route, ok := router.Match(inboundHeaders("external", "4008001234"))
+	assert.True(t, ok)
+	assert.Equal(t, "collection", route.Persona)
+	assert.Equal(t, "您好", route.Greeting)
+
+	// 未配置的号码、其他sofia配置和外呼通道不接入
+	_, ok = router.Match(inboundHeaders("external", "4008009999"))
+	assert.False(t, ok)
+	_, ok = router.Match(inboundHeaders("internal", "4008001234"))
+	assert.False(t, ok)
+	headers := inboundHeaders("external", "4008001234")
+	headers["Call-Direction"] = "outbound"
+	_, ok = router.Match(headers)
+	assert.False(t, ok)
+}
+
+func TestRouter_Wildcard(t *testing.T) {
+	router := inbound.New(inbound.Config{
+		Enabled: true,
+		Numbers: map[string]inbound.Route{
+			"4008001234":     {Persona: "collection"},
+			inbound.Wildcard: {Greeting: "您好，请问有什么可以帮您？"},
+		},
+	})
+
+	route, ok := router.Match(inboundHeaders("internal", "1000"))
+	assert.True(t, ok)
+	assert.Empty(t, route.Persona)
+	assert.Equal(t, "您好，请问有什么可以帮您？", route.Greeting)
+
+	route, ok = router.Match(inboundHeaders("external", "4008001234"))
+	assert.True(t, ok)
+	assert.Equal(t, "collection", route.Persona)
+}
+
+func TestRouter_Disabled(t *testing.T) {
+	router := inbound.New(inbound.Config{Numbers: map[string]inbound.Route{inbound.Wildcard: {}}})
+	assert.Nil(t, router)
+	_, ok := router.Match(inboundHeaders("external", "1000"))
+	assert.False(t, ok)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	hasPersona := func(name string) bool { return name == "collection" }
+
+	assert.NoError(t, inbound.Config{}.Validate(hasPersona))
+	assert.NoError(t, inbound.Config{Enabled: true, Numbers: map[string]inbound.Route{
+		"4008001234": {Persona: "collection"},
+		"*":          {},
+	}}.Validate(hasPersona))
+
+	assert.Error(t, inbound.Config{Enabled: true}.Validate(hasPersona))
+	assert.Error(t, inbound.Config{Enabled: true, Numbers: map[string]inbound.Route{
+		"4008001234": {Persona: "sales"},
+	}}.Validate(hasPersona))
+	assert.Error(t, inbound.Config{Enabled: true, Numbers: map[string]inbound.Route{
+		" ": {},
+	}}.Validate(hasPersona))
+}
@@ -0,0 +1,88 @@
+package intent_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/intent"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = intent.Config{
+	Enabled: true,
+	Rules: []intent.Rule{
+		{Name: "语音信箱", Keywords: []string{"请在提示音后留言"}, Action: intent.ActionHangup},
+		{Name: "拒绝", Keywords: []string{"不需要", "不感兴趣"}, MaxRunes: 10, Action: intent.ActionHangup, Reply: "好的，再见。"},
+		{Name: "转人工", Keywords: []string{"转人工", "Agent"}, Action: intent.ActionTransfer, Reply: "正在为您转接。", Destination: "8000"},
+		{Name: "价格", Keywords: []string{"多少钱"}, Action: intent.ActionReply, Reply: "套餐每月三十元。"},
+	},
+}
+
+func TestEngine_Match(t *testing.T) {
+	engine, err := intent.New(testConfig)
+	require.NoError(t, err)
+
+	match, ok := engine.Match("不需要，谢谢。")
+	require.True(t, ok)
+	assert.Equal(t, "拒绝", match.Rule)
+	assert.Equal(t, "不需要", match.Keyword)
+	assert.Equal(t, intent.ActionHangup, match.Action)
+	assert.Equal(t, "好的，再见。", match.Reply)
+
+	// 忽略标点、空格和英文大小写
+	match, ok = engine.Match("帮我转 人工！")
+	require.True(t, ok)
+	assert.Equal(t, intent.ActionTransfer, match.Action)
+	assert.Equal(t, "8000", match.Destination)
+	match, ok = engine.Match("I want an agent")
+	require.True(t, ok)
+	assert.Equal(t, "转人工", match.Rule)
+
+	// 按规则顺序匹配
+	match, ok = engine.Match("您好，请在提示音后留言，不需要回复")
+	require.True(t, ok)
+	assert.Equal(t, "语音信箱", match.Rule)
+
+	match, ok = engine.Match("这个多少钱")
+	require.True(t, ok)
+	assert.Equal(t, intent.ActionReply, match.Action)
+}
+
+func TestEngine_NoMatch(t *testing.T) {
+	engine, err := intent.New(testConfig)
+	require.NoError(t, err)
+
+	_, ok := engine.Match("你们的套餐包含哪些内容")
+	assert.False(t, ok)
+	_, ok = engine.Match("，。")
+	assert.False(t, ok)
+
+	// 超过字数上限的长句不匹配，交给大模型判断
+	_, ok = engine.Match("我现在不需要，但是下个月可能会考虑一下你们的产品")
+	assert.False(t, ok)
+
+	// nil上调用从不命中
+	var nilEngine *intent.Engine
+	_, ok = nilEngine.Match("不需要")
+	assert.False(t, ok)
+	assert.Empty(t, nilEngine.DialplanContext())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, intent.Config{}.Validate())
+	assert.NoError(t, testConfig.Validate())
+
+	invalid := []intent.Rule{
+		{Keywords: []string{"不需要"}, Action: intent.ActionHangup},
+		{Name: "拒绝", Action: intent.ActionHangup},
+		{Name: "拒绝", Keywords: []string{"不需要"}, Action: "ignore"},
+		{Name: "拒绝", Keywords: []string{"不需要"}, Action: intent.ActionHangup, MaxRunes: -1},
+		{Name: "价格", Keywords: []string{"多少钱"}, Action: intent.ActionReply},
+		{Name: "转人工", Keywords: []string{"转人工"}, Action: intent.ActionTransfer},
+	}
+	for _, rule := range invalid {
+		_, err := intent.New(intent.Config{Enabled: true, Rules: []intent.Rule{rule}})
+		assert.Error(t, err, rule.Name)
+	}
+}
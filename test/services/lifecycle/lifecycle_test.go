@@ -0,0 +1,94 @@
+package lifecycle_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/lifecycle"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_StartupAndDrain(t *testing.T) {
+	manager := lifecycle.New(lifecycle.Config{GracePeriod: 5 * time.Second})
+	var calls int64 = 2
+	manager.Track("calls", func() int { return int(atomic.LoadInt64(&calls)) })
+	manager.Track("websockets", func() int { return 1 })
+
+	// 启动完成前未就绪，但已可以接受请求
+	assert.False(t, manager.Ready())
+	assert.True(t, manager.Accepting())
+	manager.MarkReady()
+	assert.True(t, manager.Ready())
+
+	assert.True(t, manager.Drain())
+	assert.False(t, manager.Drain())
+	assert.False(t, manager.Ready())
+	assert.False(t, manager.Accepting())
+	// 排空后不会重新就绪
+	manager.MarkReady()
+	assert.False(t, manager.Ready())
+
+	status := manager.Status()
+	assert.Equal(t, lifecycle.StateDraining, status.State)
+	assert.Equal(t, map[string]int{"calls": 2, "websockets": 1}, status.Active)
+	assert.Equal(t, 3, status.Total)
+	require.NotNil(t, status.Deadline)
+	assert.Equal(t, 5*time.Second, status.Deadline.Sub(*status.DrainStarted))
+}
+
+func TestManager_WaitUntilDrained(t *testing.T) {
+	manager := lifecycle.New(lifecycle.Config{GracePeriod: 5 * time.Second})
+	var calls int64 = 1
+	manager.Track("calls", func() int { return int(atomic.LoadInt64(&calls)) })
+	manager.MarkReady()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		atomic.StoreInt64(&calls, 0)
+	}()
+	require.NoError(t, manager.Wait(context.Background()))
+	assert.Equal(t, lifecycle.StateDrained, manager.Status().State)
+}
+
+func TestManager_WaitTimeout(t *testing.T) {
+	manager := lifecycle.New(lifecycle.Config{GracePeriod: 300 * time.Millisecond})
+	manager.Track("calls", func() int { return 1 })
+
+	err := manager.Wait(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "排空超时")
+	assert.Equal(t, lifecycle.StateDraining, manager.Status().State)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	busy := lifecycle.New(lifecycle.Config{})
+	busy.Track("calls", func() int { return 1 })
+	assert.ErrorIs(t, busy.Wait(ctx), context.Canceled)
+
+	// 没有进行中的工作时立即完成
+	assert.NoError(t, lifecycle.New(lifecycle.Config{}).Wait(context.Background()))
+}
+
+func TestManager_Nil(t *testing.T) {
+	var manager *lifecycle.Manager
+	assert.True(t, manager.Ready())
+	assert.True(t, manager.Accepting())
+}
+
+func TestManager_Dependencies(t *testing.T) {
+	manager := lifecycle.New(lifecycle.Config{})
+	assert.Nil(t, manager.Status().Dependencies)
+
+	manager.Depend("asr", func() lifecycle.Dependency {
+		return lifecycle.Dependency{Status: lifecycle.DependencyError, Kind: "quota", Code: "11200"}
+	})
+	manager.MarkReady()
+	status := manager.Status()
+	assert.Equal(t, "11200", status.Dependencies["asr"].Code)
+	// 依赖出错不影响就绪，语音识别不可用时由降级处理
+	assert.True(t, manager.Ready())
+}
@@ -0,0 +1,84 @@
+package llmcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/services/llmcache"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCache(t *testing.T, config llmcache.Config) (*llmcache.Cache, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return llmcache.New(client, config, "ollama:qwen"), mr
+}
+
+func TestCache_GetSet(t *testing.T) {
+	cache, _ := newCache(t, llmcache.Config{Enabled: true})
+	ctx := context.Background()
+	options := ollama.Options{Temperature: 0.7}
+
+	_, ok := cache.Get(ctx, "用户: 你好", options)
+	assert.False(t, ok)
+
+	cache.Set(ctx, "用户: 你好", options, "您好，我是智能客服")
+	reply, ok := cache.Get(ctx, "用户: 你好", options)
+	require.True(t, ok)
+	assert.Equal(t, "您好，我是智能客服", reply)
+
+	// 只有空白不同的提示词使用同一条缓存
+	reply, ok = cache.Get(ctx, "  用户:   你好\n", options)
+	require.True(t, ok)
+	assert.Equal(t, "您好，我是智能客服", reply)
+
+	// 模型或生成参数不同时不使用缓存
+	_, ok = cache.Get(ctx, "用户: 你好", ollama.Options{Temperature: 0.2})
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "用户: 你好", ollama.Options{Temperature: 0.7, Model: "qwen:7b"})
+	assert.False(t, ok)
+
+	// 空回复不缓存
+	cache.Set(ctx, "用户: 嗯", options, " ")
+	_, ok = cache.Get(ctx, "用户: 嗯", options)
+	assert.False(t, ok)
+}
+
+func TestCache_TTLAndMaxEntries(t *testing.T) {
+	cache, mr := newCache(t, llmcache.Config{Enabled: true, TTL: time.Minute, MaxEntries: 2})
+	ctx := context.Background()
+
+	cache.Set(ctx, "问题1", ollama.Options{}, "回答1")
+	cache.Set(ctx, "问题2", ollama.Options{}, "回答2")
+	cache.Set(ctx, "问题3", ollama.Options{}, "回答3")
+
+	// 超过上限时淘汰最早写入的回复
+	_, ok := cache.Get(ctx, "问题1", ollama.Options{})
+	assert.False(t, ok)
+	_, ok = cache.Get(ctx, "问题3", ollama.Options{})
+	assert.True(t, ok)
+
+	mr.FastForward(2 * time.Minute)
+	_, ok = cache.Get(ctx, "问题3", ollama.Options{})
+	assert.False(t, ok)
+}
+
+func TestCache_Nil(t *testing.T) {
+	var cache *llmcache.Cache
+	cache.Set(context.Background(), "你好", ollama.Options{}, "您好")
+	_, ok := cache.Get(context.Background(), "你好", ollama.Options{})
+	assert.False(t, ok)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, llmcache.Config{}.Validate())
+	assert.Error(t, llmcache.Config{TTL: -time.Second}.Validate())
+	assert.Error(t, llmcache.Config{MaxEntries: -1}.Validate())
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// amplitudeASR 按片段幅度返回固定文本的识别桩，用于区分声道
+type amplitudeASR struct {
+	mu       sync.Mutex
+	sessions []string
+}
+
+func (a *amplitudeASR) ProcessAudio(sessionID string, audioData []byte) (string, error) {
+	a.mu.Lock()
+	a.sessions = append(a.sessions, sessionID)
+	a.mu.Unlock()
+	return fmt.Sprintf("幅度%d", int16(binary.LittleEndian.Uint16(audioData))), nil
+}
+
+// memoryTranscripts 内存转写存储
+type memoryTranscripts struct {
+	items []*models.Transcript
+}
+
+func (m *memoryTranscripts) Append(ctx context.Context, t *models.Transcript) error {
+	t.ID = int64(len(m.items) + 1)
+	m.items = append(m.items, t)
+	return nil
+}
+
+// tone 生成指定时长、固定幅度的单声道PCM，幅度为0时生成静音
+func tone(d time.Duration, amplitude int16) []byte {
+	n := int(d * 16000 / time.Second)
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(amplitude))
+	}
+	return out
+}
+
+func TestOfflineTranscriber_StereoSpeakers(t *testing.T) {
+	// 左声道（坐席）先说话，右声道（客户）随后应答
+	left := append(append(tone(time.Second, 3000), tone(2*time.Second, 0)...), tone(time.Second, 3000)...)
+	right := append(append(tone(1500*time.Millisecond, 0), tone(time.Second, 5000)...), tone(1500*time.Millisecond, 0)...)
+	stereo := make([]byte, 0, len(left)*2)
+	for i := 0; i < len(left); i += 2 {
+		stereo = append(stereo, left[i], left[i+1], right[i], right[i+1])
+	}
+	wav := (&tts.Audio{PCM: stereo, SampleRate: 16000, Channels: 2}).WAV()
+
+	asr := &amplitudeASR{}
+	store := &memoryTranscripts{}
+	transcriber := services.NewOfflineTranscriber(asr, store, models.SpeakerAgent, models.SpeakerCustomer)
+
+	transcripts, err := transcriber.Transcribe(context.Background(), "uuid-1", wav)
+	require.NoError(t, err)
+	require.Len(t, transcripts, 3)
+	assert.Equal(t, store.items, transcripts)
+
+	assert.Equal(t, models.SpeakerAgent, transcripts[0].Speaker)
+	assert.Equal(t, "幅度3000", transcripts[0].Text)
+	assert.Equal(t, 0, transcripts[0].StartMs)
+	assert.Equal(t, 1000, transcripts[0].EndMs)
+	assert.Equal(t, "zh", transcripts[0].Language)
+
+	assert.Equal(t, models.SpeakerCustomer, transcripts[1].Speaker)
+	assert.Equal(t, "幅度5000", transcripts[1].Text)
+	assert.Equal(t, 1500, transcripts[1].StartMs)
+
+	assert.Equal(t, models.SpeakerAgent, transcripts[2].Speaker)
+	assert.Equal(t, 3000, transcripts[2].StartMs)
+	for _, tr := range transcripts {
+		assert.Equal(t, "uuid-1", tr.CallUUID)
+	}
+	for _, s := range asr.sessions {
+		assert.True(t, strings.HasPrefix(s, "uuid-1-"))
+	}
+}
+
+func TestOfflineTranscriber_Resamples8k(t *testing.T) {
+	// 8kHz录音：0.5秒静音后说话1秒
+	pcm8k := append(tone(250*time.Millisecond, 0), tone(500*time.Millisecond, 3000)...)
+	wav := (&tts.Audio{PCM: pcm8k, SampleRate: 8000, Channels: 1}).WAV()
+	asr := &amplitudeASR{}
+	transcriber := services.NewOfflineTranscriber(asr, &memoryTranscripts{}, models.SpeakerCustomer)
+
+	transcripts, err := transcriber.Transcribe(context.Background(), "uuid-1", wav)
+	require.NoError(t, err)
+	require.Len(t, transcripts, 1)
+	assert.Equal(t, "幅度3000", transcripts[0].Text)
+	// 转换为16kHz后时间戳与原录音一致
+	assert.Equal(t, 500, transcripts[0].StartMs)
+	assert.Equal(t, 1500, transcripts[0].EndMs)
+}
@@ -0,0 +1,87 @@
+package postprocess_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai_dialer_mini/internal/services/postprocess"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestITN(t *testing.T) {
+	tests := map[string]string{
+		"一千五百块":  "1500块",
+		"大概两千三吧": "大概2300吧",
+		"一万五的额度": "15000的额度",
+		"一千零三号":  "1003号",
+		"三十五岁":   "35岁",
+		"十五号":    "15号",
+		"一百二十三":  "123",
+		"一亿三千万":  "130000000",
+		"一万零五":   "10005",
+		"我的手机是幺三八零零一三八零零零": "我的手机是13800138000",
+		"验证码五六七": "验证码567",
+		"等一下":    "等一下",
+		"十分感谢":   "十分感谢",
+		"千万不要":   "千万不要",
+		"一一说明":   "一一说明",
+		"两个人":    "两个人",
+		"三五百":    "三五百",
+	}
+	for in, want := range tests {
+		got, err := postprocess.ITN{}.Process(context.Background(), in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, in)
+	}
+}
+
+func TestReplacer(t *testing.T) {
+	r := postprocess.NewReplacer(map[string]string{
+		"智能外乎": "智能外呼",
+		"外乎":   "外出",
+	}, []string{"混蛋"}, "")
+	require.NotNil(t, r)
+	got, err := r.Process(context.Background(), "智能外乎系统，外乎了，你这个混蛋")
+	require.NoError(t, err)
+	assert.Equal(t, "智能外呼系统，外出了，你这个**", got)
+
+	assert.Nil(t, postprocess.NewReplacer(nil, nil, ""))
+}
+
+type fakePunctuator struct {
+	err error
+}
+
+func (p fakePunctuator) Punctuate(ctx context.Context, text string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	return text + "。", nil
+}
+
+func TestNew_Chain(t *testing.T) {
+	config := postprocess.Config{ITN: true, Censor: []string{"混蛋"}, Mask: "#"}
+	require.NoError(t, config.Validate())
+
+	chain := postprocess.New(config, fakePunctuator{})
+	require.Len(t, chain, 3)
+	got, err := chain.Process(context.Background(), "混蛋欠了两千三")
+	require.NoError(t, err)
+	assert.Equal(t, "##欠了2300。", got)
+
+	// 标点恢复失败时跳过该步骤，其余步骤照常执行
+	chain = postprocess.New(config, fakePunctuator{err: errors.New("timeout")})
+	got, err = chain.Process(context.Background(), "混蛋欠了两千三")
+	assert.ErrorContains(t, err, "添加标点失败")
+	assert.Equal(t, "##欠了2300", got)
+
+	assert.Nil(t, postprocess.New(postprocess.Config{}, nil))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.Error(t, postprocess.Config{Censor: []string{""}}.Validate())
+	assert.Error(t, postprocess.Config{Replacements: map[string]string{"": "x"}}.Validate())
+}
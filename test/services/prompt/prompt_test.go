@@ -0,0 +1,81 @@
+package prompt_test
+
+import (
+	"errors"
+	"testing"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var history = []models.Message{
+	{Role: models.RoleAssistant, Content: "您好，这里是XX宽带"},
+	{Role: models.RoleSystem, Content: "客户已欠费3天"},
+	{Role: models.RoleUser, Content: "多少钱一个月"},
+}
+
+func TestBuilder_DefaultTemplate(t *testing.T) {
+	builder, err := prompt.New(prompt.Config{})
+	require.NoError(t, err)
+
+	text, err := builder.Build("", "", history)
+	require.NoError(t, err)
+	assert.Equal(t, "助手: 您好，这里是XX宽带\n背景信息: 客户已欠费3天\n用户: 多少钱一个月\n", text)
+}
+
+func TestBuilder_PersonaAndKnowledge(t *testing.T) {
+	builder, err := prompt.New(prompt.Config{
+		Profile: prompt.Profile{
+			Persona:       "你是客服小美。",
+			Knowledge:     []string{"500M宽带每月99元", "新装免安装费"},
+			HistoryWindow: 1,
+		},
+	})
+	require.NoError(t, err)
+
+	text, err := builder.Build("", "", history)
+	require.NoError(t, err)
+	assert.Equal(t, "你是客服小美。\n业务知识: 500M宽带每月99元\n业务知识: 新装免安装费\n用户: 多少钱一个月\n", text)
+}
+
+func TestBuilder_ProfileSelection(t *testing.T) {
+	builder, err := prompt.New(prompt.Config{
+		Profile: prompt.Profile{Persona: "默认人设", Knowledge: []string{"通用知识"}},
+		Profiles: map[string]prompt.Profile{
+			"collection": {Persona: "还款提醒专员"},
+			"sales":      {Template: "{{.Persona}}|{{len .Knowledge}}|{{.Input}}"},
+		},
+		Campaigns: map[string]string{"1": "collection"},
+	})
+	require.NoError(t, err)
+	assert.True(t, builder.Has("sales"))
+	assert.False(t, builder.Has("missing"))
+
+	// 活动对应的配置，未设置的字段沿用默认配置
+	text, err := builder.Build("", "1", history)
+	require.NoError(t, err)
+	assert.Contains(t, text, "还款提醒专员\n业务知识: 通用知识\n")
+
+	// 会话指定的配置优先于活动配置
+	text, err = builder.Build("sales", "1", history)
+	require.NoError(t, err)
+	assert.Equal(t, "默认人设|1|多少钱一个月", text)
+
+	// 未知的活动使用默认配置
+	text, err = builder.Build("", "9", history)
+	require.NoError(t, err)
+	assert.Contains(t, text, "默认人设\n")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, prompt.Config{}.Validate())
+	assert.Error(t, prompt.Config{Profile: prompt.Profile{Template: "{{.Persona"}}.Validate())
+	assert.Error(t, prompt.Config{Profile: prompt.Profile{HistoryWindow: -1}}.Validate())
+	assert.Error(t, prompt.Config{Profiles: map[string]prompt.Profile{"x": {Template: "{{nosuchfunc}}"}}}.Validate())
+
+	err := prompt.Config{Campaigns: map[string]string{"1": "missing"}}.Validate()
+	assert.True(t, errors.Is(err, prompt.ErrUnknownProfile))
+}
@@ -0,0 +1,130 @@
+package promptaudio_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/audio/pcm"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/services/promptaudio"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stereoWAV 生成指定采样率和时长的双声道WAV
+func stereoWAV(sampleRate int, duration time.Duration) []byte {
+	samples := int(duration * time.Duration(sampleRate) / time.Second)
+	audio := &tts.Audio{PCM: make([]byte, samples*4), SampleRate: sampleRate, Channels: 2}
+	for i := 0; i < samples; i++ {
+		audio.PCM[i*4] = byte(i)
+	}
+	return audio.WAV()
+}
+
+func newLibrary(t *testing.T) (*promptaudio.Library, storage.Store) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	return promptaudio.New(store, promptaudio.Config{Enabled: true, MaxDuration: 10 * time.Second}), store
+}
+
+func TestLibrary_UploadResamples(t *testing.T) {
+	library, store := newLibrary(t)
+	ctx := context.Background()
+
+	prompt, err := library.Upload(ctx, promptaudio.Prompt{Name: "legal_notice", Text: "本通话将被录音"}, stereoWAV(44100, time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), prompt.DurationMs)
+	assert.Equal(t, 44100, prompt.SampleRate)
+	assert.Equal(t, 2, prompt.Channels)
+
+	// 保存单声道8kHz和16kHz两份
+	for _, rate := range []int{8000, 16000} {
+		data, err := store.Get(ctx, fmt.Sprintf("prompts/legal_notice/%d.wav", rate))
+		require.NoError(t, err)
+		wav, err := pcm.DecodeWAV(data)
+		require.NoError(t, err)
+		assert.Equal(t, rate, wav.SampleRate)
+		assert.Equal(t, 1, wav.Channels)
+		assert.InDelta(t, time.Second, wav.Duration(), float64(10*time.Millisecond))
+	}
+
+	// 其他进程的提示音库从存储读取
+	other := promptaudio.New(store, promptaudio.Config{Enabled: true})
+	audio, loaded, err := other.Load(ctx, "legal_notice", 8000)
+	require.NoError(t, err)
+	assert.Equal(t, 8000, audio.SampleRate)
+	assert.Equal(t, "本通话将被录音", loaded.Text)
+	audio, _, err = other.Load(ctx, "legal_notice", 44100)
+	require.NoError(t, err)
+	assert.Equal(t, 16000, audio.SampleRate)
+
+	prompts, err := other.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "legal_notice", prompts[0].Name)
+}
+
+func TestLibrary_UploadValidation(t *testing.T) {
+	library, _ := newLibrary(t)
+	ctx := context.Background()
+	wav := stereoWAV(16000, time.Second)
+
+	_, err := library.Upload(ctx, promptaudio.Prompt{Name: "Greeting!", Text: "您好"}, wav)
+	assert.ErrorIs(t, err, promptaudio.ErrInvalidName)
+	_, err = library.Upload(ctx, promptaudio.Prompt{Name: "greeting"}, wav)
+	assert.ErrorIs(t, err, promptaudio.ErrInvalidAudio)
+	_, err = library.Upload(ctx, promptaudio.Prompt{Name: "greeting", Text: "您好"}, []byte("ID3 not a wav"))
+	assert.ErrorIs(t, err, promptaudio.ErrInvalidAudio)
+	_, err = library.Upload(ctx, promptaudio.Prompt{Name: "greeting", Text: "您好"}, stereoWAV(16000, 11*time.Second))
+	assert.ErrorIs(t, err, promptaudio.ErrInvalidAudio)
+
+	prompts, err := library.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, prompts)
+}
+
+func TestLibrary_ReplaceAndDelete(t *testing.T) {
+	library, store := newLibrary(t)
+	other := promptaudio.New(store, promptaudio.Config{Enabled: true})
+	ctx := context.Background()
+
+	_, err := library.Upload(ctx, promptaudio.Prompt{Name: "greeting", Text: "您好"}, stereoWAV(16000, time.Second))
+	require.NoError(t, err)
+	audio, _, err := other.Load(ctx, "greeting", 16000)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, audio.Duration())
+
+	// 替换后其他进程不使用缓存的旧音频
+	_, err = library.Upload(ctx, promptaudio.Prompt{Name: "greeting", Text: "您好，这里是客服中心"}, stereoWAV(16000, 2*time.Second))
+	require.NoError(t, err)
+	audio, prompt, err := other.Load(ctx, "greeting", 16000)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, audio.Duration())
+	assert.Equal(t, "您好，这里是客服中心", prompt.Text)
+
+	require.NoError(t, library.Delete(ctx, "greeting"))
+	_, _, err = other.Load(ctx, "greeting", 16000)
+	assert.ErrorIs(t, err, promptaudio.ErrNotFound)
+	_, err = store.Get(ctx, "prompts/greeting/16000.wav")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.ErrorIs(t, library.Delete(ctx, "greeting"), promptaudio.ErrNotFound)
+}
+
+func TestParseRef(t *testing.T) {
+	name, fallback, ok := promptaudio.ParseRef("audio:greeting|您好，这里是XX银行")
+	assert.True(t, ok)
+	assert.Equal(t, "greeting", name)
+	assert.Equal(t, "您好，这里是XX银行", fallback)
+
+	name, fallback, ok = promptaudio.ParseRef("audio:legal_notice")
+	assert.True(t, ok)
+	assert.Equal(t, "legal_notice", name)
+	assert.Empty(t, fallback)
+
+	_, _, ok = promptaudio.ParseRef("您好")
+	assert.False(t, ok)
+}
@@ -0,0 +1,185 @@
+package qa_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/qa"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var transcriptColumns = []string{"id", "call_uuid", "speaker", "text", "start_ms", "end_ms", "language", "created_at"}
+
+// stubGenerator 返回固定的评分结果并记录提示词
+type stubGenerator struct {
+	response string
+	err      error
+	prompts  []string
+}
+
+func (g *stubGenerator) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	g.prompts = append(g.prompts, prompt)
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &ollama.GenerateResponse{Response: g.response}, nil
+}
+
+func newEvaluator(t *testing.T, llm qa.Generator, config qa.Config) (*qa.Evaluator, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return qa.New(repositories.NewStore(db), llm, config), mock
+}
+
+// expectTranscripts 期望查询到一通AI与客户的对话
+func expectTranscripts(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("FROM transcripts").WithArgs("call-1").
+		WillReturnRows(sqlmock.NewRows(transcriptColumns).
+			AddRow(1, "call-1", models.SpeakerAI, "您好，这里是某某银行客服，请问是张先生吗？", 0, 3000, "zh", now).
+			AddRow(2, "call-1", models.SpeakerCustomer, "是我，有什么事？", 3000, 5000, "zh", now))
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	llm := &stubGenerator{response: "评分如下：\n```json\n" +
+		`{"greeting": {"score": 90, "reason": "礼貌问候并说明身份"},
+		  "identity_verified": {"score": 50, "reason": "只确认了姓氏"},
+		  "objection_handled": {"score": null, "reason": "客户没有异议"}}` + "\n```"}
+	evaluator, mock := newEvaluator(t, llm, qa.Config{PassScore: 60})
+
+	expectTranscripts(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("REPLACE INTO qa_evaluations").
+		WithArgs("call-1", models.QAStatusDone, 70.0, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM qa_scores").WithArgs("call-1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO qa_scores").WithArgs("call-1", "greeting", 90, true, "礼貌问候并说明身份").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO qa_scores").WithArgs("call-1", "identity_verified", 50, false, "只确认了姓氏").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO qa_scores").WithArgs("call-1", "objection_handled", nil, false, "客户没有异议").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	eval, err := evaluator.Evaluate(context.Background(), "call-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.QAStatusDone, eval.Status)
+	require.NotNil(t, eval.TotalScore)
+	assert.Equal(t, 70.0, *eval.TotalScore)
+	// 有不及格的评分项时整通电话不及格
+	assert.False(t, eval.Passed)
+	require.Len(t, eval.Scores, 3)
+	assert.Nil(t, eval.Scores[2].Score)
+
+	// 提示词包含评分标准和带说话方的通话记录
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "identity_verified")
+	assert.Contains(t, llm.prompts[0], "客户: 是我，有什么事？")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluator_WeightedScorecard(t *testing.T) {
+	llm := &stubGenerator{response: `{"greeting": {"score": 100}, "closing": {"score": 70}}`}
+	evaluator, mock := newEvaluator(t, llm, qa.Config{PassScore: 60, Scorecard: []qa.Criterion{
+		{Key: "greeting", Description: "问候", Weight: 1},
+		{Key: "closing", Description: "结束语", Weight: 3},
+	}})
+
+	expectTranscripts(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("REPLACE INTO qa_evaluations").
+		WithArgs("call-1", models.QAStatusDone, 77.5, true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM qa_scores").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO qa_scores").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO qa_scores").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	eval, err := evaluator.Evaluate(context.Background(), "call-1")
+	require.NoError(t, err)
+	assert.True(t, eval.Passed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluator_GradingFailed(t *testing.T) {
+	for name, llm := range map[string]*stubGenerator{
+		"大模型不可用":   {err: errors.New("connection refused")},
+		"回复不是JSON": {response: "这通电话表现不错"},
+		"缺少评分项":    {response: `{"greeting": {"score": 80}}`},
+		"得分超出范围":   {response: `{"greeting": {"score": 120}, "identity_verified": {"score": 80}, "objection_handled": {"score": 80}}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			evaluator, mock := newEvaluator(t, llm, qa.Config{PassScore: 60})
+
+			// 评分失败时保存为失败状态，不写入得分
+			expectTranscripts(mock)
+			mock.ExpectBegin()
+			mock.ExpectExec("REPLACE INTO qa_evaluations").
+				WithArgs("call-1", models.QAStatusFailed, nil, false, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("DELETE FROM qa_scores").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+
+			eval, err := evaluator.Evaluate(context.Background(), "call-1")
+			require.NoError(t, err)
+			assert.Equal(t, models.QAStatusFailed, eval.Status)
+			assert.NotEmpty(t, eval.Error)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestEvaluator_NoTranscript(t *testing.T) {
+	llm := &stubGenerator{}
+	evaluator, mock := newEvaluator(t, llm, qa.Config{})
+
+	mock.ExpectQuery("FROM transcripts").WithArgs("call-1").WillReturnRows(sqlmock.NewRows(transcriptColumns))
+
+	_, err := evaluator.Evaluate(context.Background(), "call-1")
+	assert.True(t, errors.Is(err, qa.ErrNoTranscript))
+	assert.Empty(t, llm.prompts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluator_Dashboard(t *testing.T) {
+	evaluator, mock := newEvaluator(t, &stubGenerator{}, qa.Config{})
+	campaignID := int64(3)
+
+	mock.ExpectQuery("FROM qa_evaluations e LEFT JOIN calls c ON c.call_uuid = e.call_uuid WHERE c.campaign_id = \\?").
+		WithArgs(campaignID).
+		WillReturnRows(sqlmock.NewRows([]string{"evaluated", "failed", "avg_score", "pass_rate"}).AddRow(8, 1, 72.5, 0.75))
+	mock.ExpectQuery("FROM qa_scores s JOIN qa_evaluations e (.+) WHERE c.campaign_id = \\? GROUP BY s.criterion").
+		WithArgs(campaignID).
+		WillReturnRows(sqlmock.NewRows([]string{"criterion", "evaluated", "avg_score", "pass_rate"}).
+			AddRow("greeting", 8, 88.0, 1.0).
+			AddRow("objection_handled", 3, 60.0, 0.5))
+
+	dashboard, err := evaluator.Dashboard(context.Background(), models.QADashboardQuery{CampaignID: &campaignID})
+	require.NoError(t, err)
+	assert.Equal(t, 8, dashboard.Evaluated)
+	assert.Equal(t, 0.75, dashboard.PassRate)
+	require.Len(t, dashboard.Criteria, 2)
+	assert.Equal(t, 3, dashboard.Criteria[1].Evaluated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, qa.Config{PassScore: 60}.Validate())
+	assert.Error(t, qa.Config{PassScore: 101}.Validate())
+	assert.Error(t, qa.Config{Scorecard: []qa.Criterion{{Key: "greeting"}}}.Validate())
+	err := qa.Config{Scorecard: []qa.Criterion{
+		{Key: "greeting", Description: "问候"},
+		{Key: "greeting", Description: "问候"},
+	}}.Validate()
+	assert.True(t, err != nil && strings.Contains(err.Error(), "重复"))
+}
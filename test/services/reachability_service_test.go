@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedLookup 模拟号码状态查询，返回固定状态并记录查询次数
+type fixedLookup struct {
+	status string
+	calls  int
+}
+
+func (f *fixedLookup) Lookup(ctx context.Context, phone string) (models.NumberLookup, error) {
+	f.calls++
+	return models.NumberLookup{Status: f.status, Carrier: "中国移动", CheckedAt: time.Now()}, nil
+}
+
+func TestReachabilityService_CheckLeadDisconnected(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	lookup := &fixedLookup{status: models.NumberStatusDisconnected}
+	svc := services.NewReachabilityService(lookup, repositories.NewStore(db), time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE leads SET lookup_status").
+		WithArgs(models.NumberStatusDisconnected, "中国移动", sqlmock.AnyArg(), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE leads SET status").
+		WithArgs(models.LeadStatusUnreachable, nil, sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	lead := &models.Lead{ID: 7, Phone: "13900000000", Status: models.LeadStatusQueued}
+	err = svc.CheckLead(context.Background(), lead)
+	assert.ErrorIs(t, err, services.ErrNumberUnreachable)
+	assert.Equal(t, models.LeadStatusUnreachable, lead.Status)
+	require.NotNil(t, lead.Lookup)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// 有效期内使用线索上记录的结果，不重复查询
+	assert.ErrorIs(t, svc.CheckLead(context.Background(), lead), services.ErrNumberUnreachable)
+	assert.Equal(t, 1, lookup.calls)
+}
+
+func TestReachabilityService_CheckLeadActive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	lookup := &fixedLookup{status: models.NumberStatusActive}
+	svc := services.NewReachabilityService(lookup, repositories.NewStore(db), time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE leads SET lookup_status").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 过期的查询结果会重新查询
+	expired := models.NumberLookup{Status: models.NumberStatusDisconnected, CheckedAt: time.Now().Add(-2 * time.Hour)}
+	lead := &models.Lead{ID: 8, Phone: "13800000000", Status: models.LeadStatusQueued, Lookup: &expired}
+	require.NoError(t, svc.CheckLead(context.Background(), lead))
+	assert.Equal(t, models.LeadStatusQueued, lead.Status)
+	assert.Equal(t, models.NumberStatusActive, lead.Lookup.Status)
+	assert.Equal(t, 1, lookup.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// 未启用时直接放行
+	var disabled *services.ReachabilityService
+	assert.NoError(t, disabled.CheckLead(context.Background(), lead))
+}
+
+func TestReachabilityService_CheckLeadCachedUnreachable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	lookup := &fixedLookup{status: models.NumberStatusActive}
+	svc := services.NewReachabilityService(lookup, repositories.NewStore(db), time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE leads SET status").
+		WithArgs(models.LeadStatusUnreachable, nil, sqlmock.AnyArg(), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 已领取的线索使用有效期内的空号结果，不重复查询但仍标记为unreachable
+	cached := models.NumberLookup{Status: models.NumberStatusDisconnected, CheckedAt: time.Now()}
+	lead := &models.Lead{ID: 9, Phone: "13700000000", Status: models.LeadStatusDialing, Lookup: &cached}
+	assert.ErrorIs(t, svc.CheckLead(context.Background(), lead), services.ErrNumberUnreachable)
+	assert.Equal(t, models.LeadStatusUnreachable, lead.Status)
+	assert.Equal(t, 0, lookup.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
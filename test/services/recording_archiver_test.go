@@ -0,0 +1,124 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/audio/codec"
+	"ai_dialer_mini/internal/clients/storage"
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/consent"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingArchiver_Archive(t *testing.T) {
+	recordings := t.TempDir()
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	wav, err := codec.New(codec.Config{Format: codec.FormatWAV})
+	require.NoError(t, err)
+
+	archiver := services.NewRecordingArchiver(store, wav, config.RecordingConfig{
+		Dir:          recordings,
+		Prefix:       "recordings",
+		DeleteSource: true,
+	})
+	source := filepath.Join(recordings, "uuid-1.wav")
+	require.NoError(t, os.WriteFile(source, []byte("RIFF-test"), 0o644))
+
+	require.NoError(t, archiver.Archive(context.Background(), models.CDR{CallUUID: "uuid-1"}))
+	assert.Equal(t, "recordings/uuid-1.wav", archiver.Key(models.CDR{CallUUID: "uuid-1"}))
+
+	data, err := store.Get(context.Background(), "recordings/uuid-1.wav")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF-test"), data)
+	assert.NoFileExists(t, source)
+
+	// 没有录音的通话直接跳过
+	assert.NoError(t, archiver.Archive(context.Background(), models.CDR{CallUUID: "uuid-2"}))
+}
+
+func TestRecordingArchiver_ConsentRefused(t *testing.T) {
+	recordings := t.TempDir()
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	wav, err := codec.New(codec.Config{Format: codec.FormatWAV})
+	require.NoError(t, err)
+
+	// 未配置删除原始录音时，客户拒绝录音的录音也会删除
+	archiver := services.NewRecordingArchiver(store, wav, config.RecordingConfig{Dir: recordings, Prefix: "recordings"})
+	source := filepath.Join(recordings, "uuid-1.wav")
+	require.NoError(t, os.WriteFile(source, []byte("RIFF-test"), 0o644))
+
+	cdr := models.CDR{CallUUID: "uuid-1", RecordingConsent: string(consent.StatusRefused)}
+	require.NoError(t, archiver.Archive(context.Background(), cdr))
+	assert.NoFileExists(t, source)
+	_, err = store.Get(context.Background(), archiver.Key(cdr))
+	assert.Error(t, err)
+}
+
+// memoryRecordings 内存录音索引
+type memoryRecordings struct {
+	items []*models.Recording
+}
+
+func (m *memoryRecordings) Save(ctx context.Context, rec *models.Recording) error {
+	m.items = append(m.items, rec)
+	return nil
+}
+
+func TestRecordingArchiver_Metadata(t *testing.T) {
+	recordings := t.TempDir()
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	wav, err := codec.New(codec.Config{Format: codec.FormatWAV})
+	require.NoError(t, err)
+
+	archiver := services.NewRecordingArchiver(store, wav, config.RecordingConfig{
+		Dir:      recordings,
+		Prefix:   "recordings",
+		Filename: "{date}/{callee}-{uuid}",
+	})
+	index := &memoryRecordings{}
+	archiver.SetIndex(index)
+
+	// 两秒双声道16kHz录音
+	audio := tts.Audio{PCM: make([]byte, 2*16000*2*2), SampleRate: 16000, Channels: 2}
+	require.NoError(t, os.WriteFile(filepath.Join(recordings, "uuid-1.wav"), audio.WAV(), 0o644))
+
+	start := time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local)
+	answer := start.Add(5 * time.Second)
+	cdr := models.CDR{CallUUID: "uuid-1", Caller: "4001", Callee: "+86 138", StartTime: start, AnswerTime: &answer, EndTime: start.Add(time.Minute)}
+	require.NoError(t, archiver.Archive(context.Background(), cdr))
+
+	// 文件名按模板生成，号码中的空格替换为下划线
+	key := "recordings/2026-10-16/+86_138-uuid-1.wav"
+	assert.Equal(t, key, archiver.Key(cdr))
+	_, err = store.Get(context.Background(), key)
+	require.NoError(t, err)
+
+	// 元数据文件与录音同名
+	data, err := store.Get(context.Background(), "recordings/2026-10-16/+86_138-uuid-1.json")
+	require.NoError(t, err)
+	var meta models.Recording
+	require.NoError(t, json.Unmarshal(data, &meta))
+	assert.Equal(t, "4001", meta.Caller)
+	assert.Equal(t, "+86 138", meta.Callee)
+	assert.Equal(t, key, meta.Key)
+	assert.Equal(t, 2, meta.Channels)
+	assert.Equal(t, 2000, meta.DurationMs)
+	require.NotNil(t, meta.AnsweredAt)
+	assert.True(t, answer.Equal(*meta.AnsweredAt))
+
+	require.Len(t, index.items, 1)
+	assert.Equal(t, "uuid-1", index.items[0].CallUUID)
+}
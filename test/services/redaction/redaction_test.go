@@ -0,0 +1,50 @@
+package redaction_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/redaction"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRules_DefaultRules(t *testing.T) {
+	rules, err := redaction.New(redaction.Config{Enabled: true})
+	require.NoError(t, err)
+
+	// 银行卡号保留末4位，分组的空格保持不变
+	assert.Equal(t, "我的卡号是************1111", rules.Redact("我的卡号是4111111111111111"))
+	assert.Equal(t, "卡号**** **** **** 1111对吧", rules.Redact("卡号4111 1111 1111 1111对吧"))
+	// 身份证号全部隐藏
+	assert.Equal(t, "身份证号******************", rules.Redact("身份证号11010519491231002X"))
+	// 未通过Luhn校验的数字串不是银行卡号
+	assert.Equal(t, "订单号4111111111111112", rules.Redact("订单号4111111111111112"))
+	assert.Equal(t, "手机号13800000000", rules.Redact("手机号13800000000"))
+}
+
+func TestRules_CustomRules(t *testing.T) {
+	rules, err := redaction.New(redaction.Config{Enabled: true, Rules: []redaction.Rule{
+		{Name: "order_no", Pattern: `\bDD(\d{6})\b`, Replacement: "[订单号]"},
+		{Name: "phone", Pattern: `\b1[3-9]\d{9}\b`, KeepLast: 4},
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "订单[订单号]，手机*******0000", rules.Redact("订单DD123456，手机13800000000"))
+	// 配置规则后不再使用默认规则
+	assert.Equal(t, "4111111111111111", rules.Redact("4111111111111111"))
+}
+
+func TestRules_Disabled(t *testing.T) {
+	rules, err := redaction.New(redaction.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+	assert.Equal(t, "4111111111111111", rules.Redact("4111111111111111"))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, redaction.Config{Rules: []redaction.Rule{{Pattern: "("}}}.Validate())
+	assert.Error(t, redaction.Config{Enabled: true, Rules: []redaction.Rule{{Name: "x", Pattern: "("}}}.Validate())
+	assert.Error(t, redaction.Config{Enabled: true, Rules: []redaction.Rule{{Name: "x"}}}.Validate())
+	assert.Error(t, redaction.Config{Enabled: true, Rules: []redaction.Rule{{Pattern: `\d+`, KeepLast: -1}}}.Validate())
+}
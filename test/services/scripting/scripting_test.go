@@ -0,0 +1,166 @@
+package scripting_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/hooks"
+	"ai_dialer_mini/internal/services/scripting"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCall 记录脚本设置的通道变量和播放的话术
+type fakeCall struct {
+	mu   sync.Mutex
+	vars map[string]string
+	said chan string
+}
+
+func newFakeCall() *fakeCall {
+	return &fakeCall{vars: make(map[string]string), said: make(chan string, 4)}
+}
+
+func (f *fakeCall) SetVariable(ctx context.Context, callUUID, name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.vars[callUUID+"/"+name] = value
+	return nil
+}
+
+func (f *fakeCall) Say(ctx context.Context, callUUID, text string) error {
+	f.said <- callUUID + "/" + text
+	return nil
+}
+
+func (f *fakeCall) variable(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.vars[key]
+}
+
+func newEngine(t *testing.T, source string, config scripting.Config) (*scripting.Engine, *fakeCall) {
+	path := filepath.Join(t.TempDir(), "test.lua")
+	require.NoError(t, os.WriteFile(path, []byte(source), 0o644))
+	config.Enabled = true
+	config.Files = []string{path}
+	engine, err := scripting.New(config)
+	require.NoError(t, err)
+	call := newFakeCall()
+	engine.SetVariables(call)
+	engine.SetSpeaker(call)
+	return engine, call
+}
+
+func TestEngine_DialogTurnSetsVariableAndSays(t *testing.T) {
+	engine, call := newEngine(t, `
+function on_dialog_turn(turn)
+  if turn.intent == "refuse" then
+    dialer.set_var("ai_result", "refused")
+    dialer.say("audio:goodbye|感谢您的接听，再见")
+  end
+end
+`, scripting.Config{})
+
+	engine.OnDialogTurn(context.Background(), hooks.Turn{CallUUID: "call-1", Input: "不需要", Intent: "refuse"})
+	assert.Equal(t, "refused", call.variable("call-1/ai_result"))
+	select {
+	case said := <-call.said:
+		assert.Equal(t, "call-1/audio:goodbye|感谢您的接听，再见", said)
+	case <-time.After(time.Second):
+		t.Fatal("脚本未播放话术")
+	}
+
+	engine.OnDialogTurn(context.Background(), hooks.Turn{CallUUID: "call-2", Intent: "other"})
+	assert.Empty(t, call.variable("call-2/ai_result"))
+}
+
+func TestEngine_HTTPPostAllowedHosts(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	host := mustHost(t, server.URL)
+
+	engine, call := newEngine(t, `
+function on_hangup(cdr)
+  local status, body = dialer.http_post("`+server.URL+`/hangup", {uuid = cdr.call_uuid, billsec = cdr.billsec, tags = {"a", "b"}})
+  dialer.set_var("post", tostring(status) .. " " .. body)
+  local ok, err = dialer.http_post("http://blocked.example.com/", {})
+  dialer.set_var("blocked", tostring(ok) .. " " .. err)
+end
+`, scripting.Config{WebhookHosts: []string{host}})
+
+	engine.OnHangup(context.Background(), models.CDR{CallUUID: "call-1", BillSec: 42})
+	body := <-received
+	assert.Equal(t, "call-1", body["uuid"])
+	assert.Equal(t, float64(42), body["billsec"])
+	assert.Equal(t, []interface{}{"a", "b"}, body["tags"])
+	assert.Equal(t, "200 ok", call.variable("call-1/post"))
+	assert.Contains(t, call.variable("call-1/blocked"), "nil 主机 blocked.example.com")
+}
+
+func TestEngine_Sandbox(t *testing.T) {
+	engine, call := newEngine(t, `
+function on_call_answered(call)
+  dialer.set_var("sandboxed", tostring(os == nil and io == nil and dofile == nil and require == nil and load == nil))
+  dialer.set_var("caller", call.caller)
+end
+`, scripting.Config{})
+
+	engine.OnCallAnswered(context.Background(), hooks.Call{UUID: "call-1", Caller: "13800000000"})
+	assert.Equal(t, "true", call.variable("call-1/sandboxed"))
+	assert.Equal(t, "13800000000", call.variable("call-1/caller"))
+}
+
+func TestEngine_Timeout(t *testing.T) {
+	engine, call := newEngine(t, `
+function on_transcript(t)
+  while true do end
+end
+function on_dialog_turn(turn)
+  dialer.set_var("after", "yes")
+end
+`, scripting.Config{Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	engine.OnTranscriptFinal(context.Background(), hooks.Transcript{CallUUID: "call-1", Text: "你好"})
+	assert.Less(t, time.Since(start), time.Second)
+
+	engine.OnDialogTurn(context.Background(), hooks.Turn{CallUUID: "call-1"})
+	assert.Equal(t, "yes", call.variable("call-1/after"))
+}
+
+func TestNew_SyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.lua")
+	require.NoError(t, os.WriteFile(path, []byte("function on_hangup(cdr"), 0o644))
+	_, err := scripting.New(scripting.Config{Enabled: true, Files: []string{path}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad.lua")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, scripting.Config{}.Validate())
+	assert.Error(t, scripting.Config{Enabled: true}.Validate())
+	assert.Error(t, scripting.Config{Enabled: true, Files: []string{"a.lua"}, WebhookHosts: []string{"http://x"}}.Validate())
+}
+
+func mustHost(t *testing.T, raw string) string {
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u.Host
+}
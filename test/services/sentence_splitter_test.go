@@ -0,0 +1,29 @@
+package services_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	var splitter services.SentenceSplitter
+
+	assert.Empty(t, splitter.Write("您好，"))
+	assert.Equal(t, []string{"您好，这里是客服。"}, splitter.Write("这里是客服。请"))
+	assert.Equal(t, []string{"请问有什么可以帮您？", "我们的套餐每月99元！"}, splitter.Write("问有什么可以帮您？我们的套餐每月99元！"))
+	assert.Empty(t, splitter.Write("谢谢"))
+	assert.Equal(t, "谢谢", splitter.Flush())
+	assert.Empty(t, splitter.Flush())
+}
+
+func TestSentenceSplitter_LongClause(t *testing.T) {
+	var splitter services.SentenceSplitter
+
+	// 长句遇到逗号即切分，短句中的逗号不切分
+	sentences := splitter.Write("我们目前有三种套餐可以选择分别适合不同的通话需求，您可以根据实际情况挑选")
+	assert.Equal(t, []string{"我们目前有三种套餐可以选择分别适合不同的通话需求，"}, sentences)
+	assert.Equal(t, "您可以根据实际情况挑选", splitter.Flush())
+}
@@ -0,0 +1,89 @@
+package sentiment_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/sentiment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubGenerator 返回固定回答的大模型
+type stubGenerator struct {
+	answer string
+	err    error
+	prompt string
+}
+
+func (g *stubGenerator) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	g.prompt = prompt
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &ollama.GenerateResponse{Response: g.answer}, nil
+}
+
+func TestAnalyzer_Lexicon(t *testing.T) {
+	analyzer := sentiment.New(sentiment.Config{Enabled: true, Negative: []string{"退订"}}, nil)
+	tests := map[string]string{
+		"好的，谢谢你":        models.SentimentPositive,
+		"没问题，可以的":       models.SentimentPositive,
+		"我不需要，别再打了":     models.SentimentNegative,
+		"你们这是骚扰电话，我要投诉": models.SentimentNegative,
+		"不满意":           models.SentimentNegative,
+		"不可以":           models.SentimentNegative,
+		"我要退订":          models.SentimentNegative,
+		"我在开车":          models.SentimentNeutral,
+		"不烦":            models.SentimentNeutral,
+		"价格不错，就是太贵了":    models.SentimentNeutral,
+	}
+	for text, want := range tests {
+		assert.Equal(t, want, analyzer.Lexicon(text), text)
+	}
+	assert.False(t, analyzer.UsesLLM())
+}
+
+func TestAnalyzer_Disabled(t *testing.T) {
+	analyzer := sentiment.New(sentiment.Config{}, nil)
+	assert.Nil(t, analyzer)
+	assert.Empty(t, analyzer.Lexicon("好的"))
+	assert.False(t, analyzer.UsesLLM())
+	_, err := analyzer.Classify(context.Background(), "好的")
+	assert.Error(t, err)
+}
+
+func TestAnalyzer_Classify(t *testing.T) {
+	llm := &stubGenerator{answer: " Negative\n"}
+	analyzer := sentiment.New(sentiment.Config{Enabled: true, Provider: sentiment.ProviderLLM}, llm)
+	require.True(t, analyzer.UsesLLM())
+
+	label, err := analyzer.Classify(context.Background(), "行吧，随便你们")
+	require.NoError(t, err)
+	assert.Equal(t, models.SentimentNegative, label)
+	assert.Contains(t, llm.prompt, "行吧，随便你们")
+
+	llm.answer = "中性"
+	label, err = analyzer.Classify(context.Background(), "嗯")
+	require.NoError(t, err)
+	assert.Equal(t, models.SentimentNeutral, label)
+
+	llm.answer = "不确定"
+	_, err = analyzer.Classify(context.Background(), "嗯")
+	assert.ErrorContains(t, err, "无法解析")
+
+	llm.err = errors.New("连接被拒绝")
+	_, err = analyzer.Classify(context.Background(), "嗯")
+	assert.ErrorContains(t, err, "连接被拒绝")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, sentiment.Config{Provider: "bert"}.Validate())
+	assert.ErrorContains(t, sentiment.Config{Enabled: true, Provider: "bert"}.Validate(), "provider")
+	assert.ErrorContains(t, sentiment.Config{Enabled: true, Positive: []string{""}}.Validate(), "positive[0]")
+	assert.ErrorContains(t, sentiment.Config{Enabled: true, Negative: []string{""}}.Validate(), "negative[0]")
+}
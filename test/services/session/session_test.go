@@ -0,0 +1,121 @@
+package session_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/encryption"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/session"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store session.Store) {
+	ctx := context.Background()
+
+	empty, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, empty.History)
+
+	temperature := 0.3
+	saved := &session.Session{
+		History:    []models.Message{{Role: "user", Content: "你好"}},
+		CampaignID: "1",
+		Overrides:  models.GenerationOverrides{Temperature: &temperature},
+	}
+	require.NoError(t, store.Save(ctx, "s1", saved))
+
+	// 修改已保存的会话不影响存储中的内容
+	saved.History[0].Content = "已修改"
+
+	loaded, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	require.Len(t, loaded.History, 1)
+	assert.Equal(t, "你好", loaded.History[0].Content)
+	assert.Equal(t, "1", loaded.CampaignID)
+	require.NotNil(t, loaded.Overrides.Temperature)
+	assert.Equal(t, 0.3, *loaded.Overrides.Temperature)
+
+	require.NoError(t, store.Delete(ctx, "s1"))
+	loaded, err = store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, loaded.History)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, session.NewMemoryStore(time.Hour))
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := session.NewMemoryStore(20 * time.Millisecond)
+	ctx := context.Background()
+	require.NoError(t, store.Save(ctx, "s1", &session.Session{History: []models.Message{{Role: "user", Content: "你好"}}}))
+
+	time.Sleep(30 * time.Millisecond)
+	loaded, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, loaded.History)
+	assert.Zero(t, store.Len())
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testStore(t, session.NewRedisStore(client, session.Config{}))
+}
+
+func TestRedisStore_Encryption(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	keyring, err := encryption.New(encryption.Config{
+		Enabled: true,
+		Tenant:  "default",
+		Tenants: map[string]encryption.TenantKeys{"default": {
+			Active: "k1",
+			Keys:   map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		}},
+	})
+	require.NoError(t, err)
+	store := session.NewRedisStore(client, session.Config{Prefix: "test:"})
+	store.SetKeyring(keyring)
+
+	testStore(t, store)
+
+	// Redis中保存的是密文
+	require.NoError(t, store.Save(context.Background(), "s2", &session.Session{History: []models.Message{{Role: "user", Content: "我的身份证号"}}}))
+	raw, err := mr.Get("test:s2")
+	require.NoError(t, err)
+	assert.True(t, encryption.IsEncrypted(raw))
+	assert.NotContains(t, raw, "身份证")
+}
+
+func TestRedisStore_TTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := session.NewRedisStore(client, session.Config{TTL: time.Minute, Prefix: "test:"})
+	ctx := context.Background()
+
+	require.NoError(t, store.Save(ctx, "s1", &session.Session{History: []models.Message{{Role: "user", Content: "你好"}}}))
+	assert.Equal(t, time.Minute, mr.TTL("test:s1"))
+
+	mr.FastForward(2 * time.Minute)
+	loaded, err := store.Load(ctx, "s1")
+	require.NoError(t, err)
+	assert.Empty(t, loaded.History)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, session.Config{Store: session.StoreRedis}.Validate())
+	assert.Error(t, session.Config{Store: "etcd"}.Validate())
+	assert.Error(t, session.Config{TTL: -time.Second}.Validate())
+}
@@ -0,0 +1,66 @@
+package services_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ai_dialer_mini/internal/clients/tts"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCommands 记录发送的FreeSWITCH命令
+type fakeCommands struct {
+	commands []string
+	resp     string
+}
+
+func (f *fakeCommands) SendCommand(cmd string) (string, error) {
+	f.commands = append(f.commands, cmd)
+	return f.resp, nil
+}
+
+func TestSpeechPlayback_Play(t *testing.T) {
+	dir := t.TempDir()
+	fs := &fakeCommands{resp: "+OK Message queued"}
+	playback, err := services.NewSpeechPlayback(fs, config.PlaybackConfig{
+		Dir:           dir,
+		FreeSWITCHDir: "/fs/sounds/",
+		Leg:           "aleg",
+	})
+	require.NoError(t, err)
+
+	audio := &tts.Audio{PCM: []byte{1, 0, 2, 0}, SampleRate: 16000, Channels: 1}
+	require.NoError(t, playback.Play("uuid-1", audio))
+
+	require.Len(t, fs.commands, 1)
+	assert.Equal(t, "uuid_broadcast uuid-1 /fs/sounds/uuid-1-1.wav aleg", fs.commands[0])
+	data, err := os.ReadFile(filepath.Join(dir, "uuid-1-1.wav"))
+	require.NoError(t, err)
+	assert.Equal(t, audio.WAV(), data)
+}
+
+func TestSpeechPlayback_Error(t *testing.T) {
+	fs := &fakeCommands{resp: "-ERR No such channel!"}
+	playback, err := services.NewSpeechPlayback(fs, config.PlaybackConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	err = playback.Play("uuid-1", &tts.Audio{SampleRate: 16000, Channels: 1})
+	assert.Error(t, err)
+}
+
+func TestSpeechPlayback_Stop(t *testing.T) {
+	fs := &fakeCommands{resp: "+OK"}
+	playback, err := services.NewSpeechPlayback(fs, config.PlaybackConfig{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	require.NoError(t, playback.Stop("uuid-1"))
+	assert.Equal(t, []string{"uuid_break uuid-1 all"}, fs.commands)
+
+	fs.resp = "-ERR No such channel!"
+	assert.Error(t, playback.Stop("uuid-1"))
+}
@@ -0,0 +1,117 @@
+package streamauth_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/streamauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "test-secret"
+
+func newSigner(t *testing.T) *streamauth.Signer {
+	signer, err := streamauth.New(streamauth.Config{BaseURL: "ws://dialer:8080/", Secret: secret, TTL: time.Minute})
+	require.NoError(t, err)
+	return signer
+}
+
+// signedQuery 生成签名地址并返回其查询参数
+func signedQuery(t *testing.T, signer *streamauth.Signer, callUUID string) url.Values {
+	raw, err := signer.Sign(callUUID)
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, streamauth.Path(callUUID), u.Path)
+	return u.Query()
+}
+
+func TestSigner_VerifyOnce(t *testing.T) {
+	signer := newSigner(t)
+	query := signedQuery(t, signer, "uuid-1")
+
+	require.NoError(t, signer.Verify("uuid-1", query))
+	assert.ErrorIs(t, signer.Verify("uuid-1", query), streamauth.ErrReplayed)
+}
+
+func TestSigner_RejectsMismatch(t *testing.T) {
+	signer := newSigner(t)
+	query := signedQuery(t, signer, "uuid-1")
+
+	// 签名地址不能用于其他通话
+	assert.ErrorIs(t, signer.Verify("uuid-2", query), streamauth.ErrInvalidSignature)
+
+	// 篡改过期时间
+	tampered := url.Values{}
+	for k, v := range query {
+		tampered[k] = v
+	}
+	tampered.Set("expires", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	assert.ErrorIs(t, signer.Verify("uuid-1", tampered), streamauth.ErrInvalidSignature)
+
+	// 其他密钥签发的地址
+	other, err := streamauth.New(streamauth.Config{BaseURL: "ws://dialer:8080", Secret: "other"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, signer.Verify("uuid-1", signedQuery(t, other, "uuid-1")), streamauth.ErrInvalidSignature)
+
+	assert.ErrorIs(t, signer.Verify("uuid-1", url.Values{}), streamauth.ErrInvalidSignature)
+}
+
+func TestSigner_RejectsExpired(t *testing.T) {
+	signer := newSigner(t)
+
+	expires := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("uuid-1\n" + expires + "\nabc"))
+	query := url.Values{
+		"expires": {expires},
+		"nonce":   {"abc"},
+		"sig":     {hex.EncodeToString(mac.Sum(nil))},
+	}
+	assert.ErrorIs(t, signer.Verify("uuid-1", query), streamauth.ErrExpired)
+}
+
+func TestRegisterStreamRoutes(t *testing.T) {
+	signer := newSigner(t)
+	var sessionID string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID = r.URL.Query().Get("session_id")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterStreamRoutes(r, handler, signer)
+
+	raw, err := signer.Sign("uuid-1")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(raw, "ws://dialer:8080/ws/calls/uuid-1/stream?"))
+	target := strings.TrimPrefix(raw, "ws://dialer:8080")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "uuid-1", sessionID)
+
+	// 重复使用同一地址在升级前被拒绝
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestNew_RejectsEmptySecret(t *testing.T) {
+	_, err := streamauth.New(streamauth.Config{BaseURL: "ws://dialer:8080"})
+	assert.Error(t, err)
+}
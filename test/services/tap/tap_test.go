@@ -0,0 +1,56 @@
+package tap_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/tap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTap_LegFiltering(t *testing.T) {
+	tp := tap.New(tap.Config{})
+
+	customer, cancelCustomer := tp.Subscribe("call-1", tap.LegCustomer)
+	defer cancelCustomer()
+	mixed, cancelMixed := tp.Subscribe("call-1", tap.LegMixed)
+	defer cancelMixed()
+
+	tp.Publish("call-1", tap.LegCustomer, 16000, []byte{1, 2})
+	tp.Publish("call-1", tap.LegAI, 8000, []byte{3, 4})
+	tp.Publish("call-2", tap.LegCustomer, 16000, []byte{5, 6})
+
+	frame := <-customer
+	assert.Equal(t, tap.Frame{Leg: tap.LegCustomer, SampleRate: 16000, PCM: []byte{1, 2}}, frame)
+	assert.Empty(t, customer)
+
+	require.Len(t, mixed, 2)
+	assert.Equal(t, tap.LegCustomer, (<-mixed).Leg)
+	assert.Equal(t, tap.LegAI, (<-mixed).Leg)
+}
+
+func TestTap_SlowListenerDropsFrames(t *testing.T) {
+	tp := tap.New(tap.Config{Buffer: 2})
+	frames, cancel := tp.Subscribe("call-1", tap.LegMixed)
+
+	for i := 0; i < 5; i++ {
+		tp.Publish("call-1", tap.LegCustomer, 16000, []byte{byte(i)})
+	}
+	assert.Len(t, frames, 2)
+
+	// 取消订阅后关闭通道，不再接收音频
+	cancel()
+	cancel()
+	tp.Publish("call-1", tap.LegCustomer, 16000, []byte{9})
+	var received [][]byte
+	for frame := range frames {
+		received = append(received, frame.PCM)
+	}
+	assert.Equal(t, [][]byte{{0}, {1}}, received)
+}
+
+func TestTap_NilPublish(t *testing.T) {
+	var tp *tap.Tap
+	tp.Publish("call-1", tap.LegCustomer, 16000, []byte{1})
+}
@@ -0,0 +1,57 @@
+package tenant_test
+
+import (
+	"context"
+	"testing"
+
+	"ai_dialer_mini/internal/services/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	var none *tenant.Registry
+	_, ok := none.Get("bank")
+	assert.False(t, ok)
+	assert.Equal(t, tenant.DefaultVariable, none.Variable())
+	assert.Nil(t, tenant.New(tenant.Config{}))
+
+	registry := tenant.New(tenant.Config{
+		Variable: "biz_tenant",
+		Tenants:  map[string]tenant.Tenant{"bank": {Model: "qwen2:7b"}},
+	})
+	bank, ok := registry.Get("bank")
+	assert.True(t, ok)
+	assert.Equal(t, "qwen2:7b", bank.Model)
+	assert.Equal(t, "bank", registry.FromHeaders(map[string]string{"variable_biz_tenant": "bank"}))
+	assert.Empty(t, registry.FromHeaders(map[string]string{"variable_biz_tenant": "other"}))
+	assert.Empty(t, registry.FromHeaders(map[string]string{"variable_ai_dialer_tenant": "bank"}))
+}
+
+func TestContext(t *testing.T) {
+	assert.Empty(t, tenant.IDFromContext(context.Background()))
+	assert.Equal(t, "bank", tenant.IDFromContext(tenant.WithID(context.Background(), "bank")))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	personas := func(name string) bool { return name == "collection" }
+	valid := tenant.Config{Tenants: map[string]tenant.Tenant{
+		"bank": {
+			XFYun:    tenant.Credentials{AppID: "a", APIKey: "k", APISecret: "s"},
+			Persona:  "collection",
+			CallerID: "+864008001234",
+		},
+	}}
+	assert.NoError(t, valid.Validate(personas))
+
+	invalid := []tenant.Config{
+		{Variable: "bad var"},
+		{Tenants: map[string]tenant.Tenant{"bad id": {}}},
+		{Tenants: map[string]tenant.Tenant{"bank": {XFYun: tenant.Credentials{AppID: "a"}}}},
+		{Tenants: map[string]tenant.Tenant{"bank": {Persona: "sales"}}},
+		{Tenants: map[string]tenant.Tenant{"bank": {CallerID: "400 800"}}},
+	}
+	for i, config := range invalid {
+		assert.Error(t, config.Validate(personas), "%d", i)
+	}
+}
@@ -0,0 +1,106 @@
+package throttle_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/throttle"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher 记录发布的事件
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []*models.CallEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *models.CallEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func testConfig(window time.Duration) throttle.Config {
+	return throttle.Config{
+		Enabled:    true,
+		Window:     window,
+		MinSamples: 5,
+		Factor:     0.5,
+		ASR:        throttle.Thresholds{P95: time.Second, ErrorRate: 0.2},
+		LLM:        throttle.Thresholds{P95: 3 * time.Second, ErrorRate: 0.2},
+	}
+}
+
+func observe(g *throttle.Governor, component string, n int, latency time.Duration, err error) {
+	for i := 0; i < n; i++ {
+		g.Observe(component, latency, err)
+	}
+}
+
+func TestGovernor_ThrottlesOnSlowASRAndRecovers(t *testing.T) {
+	publisher := &recordingPublisher{}
+	g := throttle.New(testConfig(100 * time.Millisecond))
+	g.SetPublisher(publisher)
+
+	// 样本不足时不限速
+	observe(g, throttle.ComponentASR, 4, 2*time.Second, nil)
+	assert.Equal(t, 1.0, g.Factor(time.Now()))
+
+	observe(g, throttle.ComponentASR, 1, 2*time.Second, nil)
+	assert.Equal(t, 0.5, g.Factor(time.Now()))
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, models.EventTypeCampaignThrottle, publisher.events[0].Type)
+	assert.Contains(t, publisher.events[0].Text, "asr")
+
+	// 窗口外的样本清理后，样本不足时保持限速
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 0.5, g.Factor(time.Now()))
+
+	// 所有组件都恢复后解除限速
+	observe(g, throttle.ComponentASR, 5, 200*time.Millisecond, nil)
+	assert.Equal(t, 0.5, g.Factor(time.Now()))
+	observe(g, throttle.ComponentLLM, 5, time.Second, nil)
+	assert.Equal(t, 1.0, g.Factor(time.Now()))
+	require.Len(t, publisher.events, 2)
+	assert.Contains(t, publisher.events[1].Text, "解除")
+}
+
+func TestGovernor_ThrottlesOnLLMErrorRate(t *testing.T) {
+	g := throttle.New(testConfig(time.Minute))
+
+	observe(g, throttle.ComponentLLM, 4, time.Second, nil)
+	observe(g, throttle.ComponentLLM, 1, 0, errors.New("timeout"))
+	assert.Equal(t, 1.0, g.Factor(time.Now()))
+
+	observe(g, throttle.ComponentLLM, 1, 0, errors.New("timeout"))
+	assert.Equal(t, 0.5, g.Factor(time.Now()))
+}
+
+func TestGovernor_Nil(t *testing.T) {
+	var g *throttle.Governor
+	g.Observe(throttle.ComponentASR, time.Second, nil)
+	assert.Equal(t, 1.0, g.Factor(time.Now()))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, throttle.Config{}.Validate())
+	assert.NoError(t, testConfig(time.Minute).Validate())
+
+	config := testConfig(time.Minute)
+	config.Factor = 1
+	assert.Error(t, config.Validate())
+
+	config = testConfig(time.Minute)
+	config.LLM.ErrorRate = 1.5
+	assert.Error(t, config.Validate())
+
+	config = testConfig(0)
+	assert.Error(t, config.Validate())
+}
@@ -0,0 +1,132 @@
+package tools_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ai_dialer_mini/internal/services/tools"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_Parse(t *testing.T) {
+	var nilRegistry *tools.Registry
+	_, ok := nilRegistry.Parse(`{"tool": "lookup_order"}`)
+	assert.False(t, ok)
+
+	r, err := tools.New(tools.Config{Enabled: true})
+	require.NoError(t, err)
+
+	call, ok := r.Parse(` {"tool": "lookup_order", "arguments": {"order_id": "A123", "count": 2}} `)
+	require.True(t, ok)
+	assert.Equal(t, "lookup_order", call.Tool)
+	assert.Equal(t, map[string]string{"order_id": "A123", "count": "2"}, call.Arguments)
+
+	// 允许包在代码块中
+	call, ok = r.Parse("```json\n{\"tool\": \"transfer_to_agent\"}\n```")
+	require.True(t, ok)
+	assert.Equal(t, "transfer_to_agent", call.Tool)
+
+	for _, reply := range []string{"您好，请问有什么可以帮您", `{"name": "x"}`, `好的 {"tool": "x"}`, `{"tool": `} {
+		_, ok = r.Parse(reply)
+		assert.False(t, ok, reply)
+	}
+
+	assert.True(t, r.MaybeCall("  {\"to"))
+	assert.True(t, r.MaybeCall("``"))
+	assert.False(t, r.MaybeCall("您好"))
+}
+
+func TestRegistry_Execute(t *testing.T) {
+	r, err := tools.New(tools.Config{Enabled: true, Transfer: tools.TransferConfig{Destination: "2000"}})
+	require.NoError(t, err)
+	require.NoError(t, r.Add(tools.Tool{
+		Name:        "lookup_order",
+		Description: "按订单号查询订单状态",
+		Parameters:  []tools.Parameter{{Name: "order_id", Description: "订单号", Required: true}},
+		Handler: func(ctx context.Context, inv tools.Invocation) (tools.Result, error) {
+			if inv.Arguments["order_id"] == "panic" {
+				panic("boom")
+			}
+			return tools.Result{Content: "订单 " + inv.Arguments["order_id"] + " 已发货"}, nil
+		},
+	}))
+	assert.ErrorContains(t, r.Add(tools.Tool{Name: "lookup_order", Description: "重复", Handler: func(context.Context, tools.Invocation) (tools.Result, error) {
+		return tools.Result{}, nil
+	}}), "工具名重复")
+	assert.Equal(t, []string{"lookup_order", "transfer_to_agent"}, r.Names())
+	assert.Contains(t, r.Prompt(), "- lookup_order: 按订单号查询订单状态；参数: order_id（订单号）必填\n")
+
+	result, err := r.Execute(context.Background(), tools.Invocation{Tool: "lookup_order", Arguments: map[string]string{"order_id": "A123"}})
+	require.NoError(t, err)
+	assert.Equal(t, "订单 A123 已发货", result.Content)
+	assert.Nil(t, result.Action)
+
+	_, err = r.Execute(context.Background(), tools.Invocation{Tool: "lookup_order"})
+	assert.ErrorContains(t, err, "缺少必填参数: order_id")
+	_, err = r.Execute(context.Background(), tools.Invocation{Tool: "lookup_order", Arguments: map[string]string{"order_id": "panic"}})
+	assert.ErrorContains(t, err, "工具执行异常")
+	_, err = r.Execute(context.Background(), tools.Invocation{Tool: "refund"})
+	assert.ErrorIs(t, err, tools.ErrUnknownTool)
+
+	// 转人工需要关联通话，动作在回复播放完后由调用方执行
+	_, err = r.Execute(context.Background(), tools.Invocation{Tool: tools.TransferToolName})
+	assert.Error(t, err)
+	result, err = r.Execute(context.Background(), tools.Invocation{Tool: tools.TransferToolName, CallUUID: "uuid-1"})
+	require.NoError(t, err)
+	assert.Equal(t, &tools.Action{Type: tools.ActionTransfer, Destination: "2000"}, result.Action)
+}
+
+func TestWebhook(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		switch r.URL.Path {
+		case "/json":
+			w.Write([]byte(`{"result": "订单已发货，预计明天送达"}`))
+		case "/text":
+			w.Write([]byte("已预约明天下午3点回电\n"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	r, err := tools.New(tools.Config{Enabled: true, Webhooks: []tools.WebhookConfig{
+		{Name: "lookup_order", Description: "查询订单", URL: server.URL + "/json"},
+		{Name: "schedule_callback", Description: "预约回电", URL: server.URL + "/text"},
+		{Name: "broken", Description: "出错的接口", URL: server.URL + "/error"},
+	}})
+	require.NoError(t, err)
+
+	result, err := r.Execute(context.Background(), tools.Invocation{
+		Tool: "lookup_order", SessionID: "s1", CallUUID: "uuid-1", Arguments: map[string]string{"order_id": "A123"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "订单已发货，预计明天送达", result.Content)
+	assert.Equal(t, "lookup_order", received["tool"])
+	assert.Equal(t, "uuid-1", received["call_uuid"])
+	assert.Equal(t, map[string]interface{}{"order_id": "A123"}, received["arguments"])
+
+	result, err = r.Execute(context.Background(), tools.Invocation{Tool: "schedule_callback", SessionID: "s1"})
+	require.NoError(t, err)
+	assert.Equal(t, "已预约明天下午3点回电", result.Content)
+
+	_, err = r.Execute(context.Background(), tools.Invocation{Tool: "broken", SessionID: "s1"})
+	assert.ErrorContains(t, err, "500")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, tools.Config{Webhooks: []tools.WebhookConfig{{Name: "Bad"}}}.Validate())
+	assert.ErrorContains(t, tools.Config{Enabled: true, Webhooks: []tools.WebhookConfig{{Name: "Bad"}}}.Validate(), "webhooks[0].name")
+	assert.ErrorContains(t, tools.Config{Enabled: true, Webhooks: []tools.WebhookConfig{
+		{Name: "lookup", Description: "查询", URL: "ftp://example.com"},
+	}}.Validate(), "webhooks[0].url")
+	assert.ErrorContains(t, tools.Config{Enabled: true, Transfer: tools.TransferConfig{Destination: "2000"}, Webhooks: []tools.WebhookConfig{
+		{Name: tools.TransferToolName, Description: "转人工", URL: "http://example.com"},
+	}}.Validate(), "工具名重复")
+}
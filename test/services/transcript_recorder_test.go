@@ -0,0 +1,124 @@
+package services_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/ollama"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services"
+	"ai_dialer_mini/internal/services/sentiment"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptRecorder(t *testing.T) {
+	store := &memoryTranscripts{}
+	recorder := services.NewTranscriptRecorder(store)
+	ctx := context.Background()
+	answered := time.Now()
+
+	recorder.Start("call-1", answered)
+	recorder.Record(ctx, "call-1", models.SpeakerAI, models.TranscriptKindSpeech, "您好", answered.Add(200*time.Millisecond), answered.Add(time.Second))
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindDTMF, "1", answered.Add(3*time.Second), answered.Add(3*time.Second))
+	// 空文本和没有通话UUID的条目不保存
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "", answered, answered)
+	recorder.Record(ctx, "", models.SpeakerCustomer, models.TranscriptKindSpeech, "你好", answered, answered)
+
+	require.Len(t, store.items, 2)
+	assert.Equal(t, 200, store.items[0].StartMs)
+	assert.Equal(t, 1000, store.items[0].EndMs)
+	assert.Equal(t, "zh", store.items[0].Language)
+	assert.Equal(t, models.TranscriptKindDTMF, store.items[1].Kind)
+	assert.Equal(t, 3000, store.items[1].StartMs)
+	assert.Empty(t, store.items[1].Language)
+
+	// 未收到接通事件的通话以第一条转写为起点
+	recorder.Finish("call-1")
+	first := time.Now()
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "喂", first, first.Add(500*time.Millisecond))
+	recorder.Record(ctx, "call-1", models.SpeakerAI, models.TranscriptKindSpeech, "您好", first.Add(time.Second), first.Add(2*time.Second))
+	assert.Equal(t, 0, store.items[2].StartMs)
+	assert.Equal(t, 1000, store.items[3].StartMs)
+
+	var nilRecorder *services.TranscriptRecorder
+	nilRecorder.Start("call-1", answered)
+	nilRecorder.Record(ctx, "call-1", models.SpeakerAI, models.TranscriptKindSpeech, "您好", answered, answered)
+	nilRecorder.Finish("call-1")
+}
+
+// sentimentTranscripts 可更新情绪的内存转写存储
+type sentimentTranscripts struct {
+	memoryTranscripts
+	mu      sync.Mutex
+	updates map[int64]string
+}
+
+func (m *sentimentTranscripts) SetSentiment(ctx context.Context, id int64, sentiment string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates[id] = sentiment
+	return nil
+}
+
+func (m *sentimentTranscripts) update(id int64) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updates[id]
+}
+
+// negativeLLM 总是判断为负面的大模型
+type negativeLLM struct{}
+
+func (negativeLLM) GenerateContext(ctx context.Context, prompt string, options ollama.Options) (*ollama.GenerateResponse, error) {
+	return &ollama.GenerateResponse{Response: "negative"}, nil
+}
+
+func TestTranscriptRecorder_Sentiment(t *testing.T) {
+	store := &sentimentTranscripts{updates: make(map[int64]string)}
+	recorder := services.NewTranscriptRecorder(store)
+	recorder.SetSentiment(sentiment.New(sentiment.Config{Enabled: true}, nil))
+	ctx := context.Background()
+	now := time.Now()
+
+	// 只标注客户语音
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "好的，谢谢", now, now)
+	recorder.Record(ctx, "call-1", models.SpeakerAI, models.TranscriptKindSpeech, "不客气，谢谢您", now, now)
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindDTMF, "1", now, now)
+	require.Len(t, store.items, 3)
+	assert.Equal(t, models.SentimentPositive, store.items[0].Sentiment)
+	assert.Empty(t, store.items[1].Sentiment)
+	assert.Empty(t, store.items[2].Sentiment)
+
+	// 配置大模型分类时先保存词典标注的结果，分类结果不同时在后台更新
+	recorder.SetSentiment(sentiment.New(sentiment.Config{Enabled: true, Provider: sentiment.ProviderLLM}, negativeLLM{}))
+	recorder.Record(ctx, "call-1", models.SpeakerCustomer, models.TranscriptKindSpeech, "行吧，可以", now, now)
+	require.Len(t, store.items, 4)
+	assert.Equal(t, models.SentimentPositive, store.items[3].Sentiment)
+	assert.Eventually(t, func() bool {
+		return store.update(4) == models.SentimentNegative
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSummarizeSentiment(t *testing.T) {
+	summary := services.SummarizeSentiment("call-1", []string{
+		models.SentimentPositive, models.SentimentNeutral, models.SentimentPositive, models.SentimentNegative,
+	})
+	assert.Equal(t, 4, summary.Utterances)
+	assert.Equal(t, 2, summary.Positive)
+	assert.Equal(t, 1, summary.Neutral)
+	assert.Equal(t, 1, summary.Negative)
+	assert.InDelta(t, 0.25, summary.Score, 1e-9)
+	assert.Equal(t, models.SentimentPositive, summary.Overall)
+	assert.Equal(t, models.SentimentNegative, summary.Last)
+	assert.True(t, summary.FollowUp)
+
+	summary = services.SummarizeSentiment("call-2", []string{
+		models.SentimentNeutral, models.SentimentNeutral, models.SentimentNegative, models.SentimentPositive,
+	})
+	assert.Equal(t, models.SentimentNeutral, summary.Overall)
+	assert.False(t, summary.FollowUp)
+}
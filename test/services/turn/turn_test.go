@@ -0,0 +1,73 @@
+package turn_test
+
+import (
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services/turn"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var holdConfig = turn.Config{
+	HoldPhrases: []string{"稍等", "等一下"},
+	HoldTimeout: time.Minute,
+}
+
+func TestManager_HoldAndResume(t *testing.T) {
+	m := turn.New(holdConfig, nil)
+	defer m.Close()
+
+	assert.Equal(t, turn.ActionReply, m.OnTranscript("你们的套餐多少钱"))
+	assert.Equal(t, turn.ActionHold, m.OnTranscript("您稍等。"))
+	assert.Equal(t, turn.StateHeld, m.State())
+
+	// 暂停中再次要求稍等不回复
+	assert.Equal(t, turn.ActionIgnore, m.OnTranscript("等一下"))
+	assert.Equal(t, turn.StateHeld, m.State())
+
+	// 客户重新开口后恢复
+	assert.Equal(t, turn.ActionResume, m.OnTranscript("好了，刚才说到哪了"))
+	assert.Equal(t, turn.StateActive, m.State())
+}
+
+func TestManager_LongSentenceIsNotHold(t *testing.T) {
+	m := turn.New(holdConfig, nil)
+	defer m.Close()
+
+	assert.Equal(t, turn.ActionReply, m.OnTranscript("等一下，我想再问问你们套餐的价格是多少"))
+	assert.Equal(t, turn.StateActive, m.State())
+}
+
+func TestManager_Disabled(t *testing.T) {
+	m := turn.New(turn.Config{}, nil)
+	defer m.Close()
+
+	assert.Equal(t, turn.ActionReply, m.OnTranscript("稍等"))
+}
+
+func TestManager_Timeout(t *testing.T) {
+	resumed := make(chan struct{})
+	m := turn.New(turn.Config{HoldPhrases: []string{"稍等"}, HoldTimeout: 20 * time.Millisecond}, func() {
+		close(resumed)
+	})
+	defer m.Close()
+
+	assert.Equal(t, turn.ActionHold, m.OnTranscript("稍等"))
+	select {
+	case <-resumed:
+	case <-time.After(time.Second):
+		t.Fatal("暂停超时后未自动恢复")
+	}
+	assert.Equal(t, turn.StateActive, m.State())
+}
+
+func TestManager_CloseStopsTimer(t *testing.T) {
+	m := turn.New(turn.Config{HoldPhrases: []string{"稍等"}, HoldTimeout: 10 * time.Millisecond}, func() {
+		t.Error("会话结束后不应恢复对话")
+	})
+
+	m.OnTranscript("稍等")
+	m.Close()
+	time.Sleep(30 * time.Millisecond)
+}
@@ -0,0 +1,157 @@
+package wrapup_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/repositories"
+	"ai_dialer_mini/internal/services/outbox"
+	"ai_dialer_mini/internal/services/wrapup"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	callColumns = []string{"call_uuid", "campaign_id", "lead_id", "direction", "caller", "callee", "status", "disposition",
+		"started_at", "answered_at", "ended_at", "created_at", "updated_at"}
+	leadColumns = []string{"id", "campaign_id", "phone", "name", "status", "attempts", "next_attempt_at", "last_call_uuid",
+		"lookup_status", "lookup_carrier", "looked_up_at", "data", "created_at", "updated_at"}
+)
+
+// run 在事务中执行收尾动作
+func run(t *testing.T, db *sql.DB, executor *wrapup.Executor, cdr models.CDR) error {
+	store := repositories.NewStore(db)
+	ob := outbox.New(db, outbox.Config{})
+	return store.Transaction(context.Background(), func(uow *repositories.UnitOfWork) error {
+		return executor.Run(context.Background(), uow, ob, cdr)
+	})
+}
+
+// expectCampaignCall 期望查询到属于活动1、线索7的通话及其线索
+func expectCampaignCall(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("FROM calls WHERE call_uuid").WithArgs("uuid-1").
+		WillReturnRows(sqlmock.NewRows(callColumns).AddRow("uuid-1", 1, 7, "outbound", "1000",
+			"13800000000", "completed", "NO_ANSWER", now, nil, now, now, now))
+	mock.ExpectQuery("FROM leads WHERE id").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(leadColumns).AddRow(7, 1, "13800000000", "张三", models.LeadStatusDialing,
+			1, nil, "uuid-1", "", "", nil, nil, now, now))
+}
+
+func TestNew_InvalidAction(t *testing.T) {
+	_, err := wrapup.New(wrapup.Config{Default: []wrapup.Action{{Type: "fax"}}}, "")
+	assert.ErrorContains(t, err, "不支持")
+
+	_, err = wrapup.New(wrapup.Config{Default: []wrapup.Action{{Type: wrapup.ActionCRMTask}}}, "")
+	assert.ErrorContains(t, err, "crm_url")
+
+	_, err = wrapup.New(wrapup.Config{Campaigns: map[string][]wrapup.Action{
+		"1": {{Type: wrapup.ActionCallback}},
+	}}, "")
+	assert.ErrorContains(t, err, "wrapup.campaigns.1")
+
+	_, err = wrapup.New(wrapup.Config{Default: []wrapup.Action{
+		{Type: wrapup.ActionWebhook, URL: "http://a"},
+		{Type: wrapup.ActionWebhook, URL: "http://b"},
+	}}, "")
+	assert.ErrorContains(t, err, "重复")
+
+	_, err = wrapup.New(wrapup.Config{Default: []wrapup.Action{
+		{Type: wrapup.ActionSMS, URL: "http://sms", Template: "{{.Lead.Name"},
+	}}, "")
+	assert.ErrorContains(t, err, "模板无效")
+
+	_, err = wrapup.New(wrapup.Config{Duration: -time.Second}, "")
+	assert.ErrorContains(t, err, "wrapup.duration")
+}
+
+func TestExecutor_CampaignActions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor, err := wrapup.New(wrapup.Config{
+		Default: []wrapup.Action{{Type: wrapup.ActionWebhook, URL: "http://hooks.example.com/default"}},
+		Campaigns: map[string][]wrapup.Action{
+			"1": {
+				{Type: wrapup.ActionCallback, Delay: 2 * time.Hour, Dispositions: []string{"NO_ANSWER", "USER_BUSY"}},
+				{Type: wrapup.ActionSMS, URL: "http://sms.example.com/send", Template: "{{.Lead.Name}}您好，稍后再联系您",
+					MaxAttempts: 3, Backoff: 30 * time.Second},
+				{Type: wrapup.ActionCRMTask, Template: "回访", Dispositions: []string{"NORMAL_CLEARING"}},
+			},
+		},
+	}, "http://crm.example.com")
+	require.NoError(t, err)
+
+	end := time.Now()
+	var sms []byte
+	mock.ExpectBegin()
+	expectCampaignCall(mock)
+	// 未接通：执行回拨和短信，不创建CRM任务
+	mock.ExpectExec("UPDATE leads SET status").
+		WithArgs(models.LeadStatusQueued, end.Add(2*time.Hour), sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT IGNORE INTO outbox").
+		WithArgs("wrapup:sms:uuid-1", outbox.DestinationSMS, "http://sms.example.com/send", "sms.send",
+			payloadArg{&sms}, outbox.StatusPending, 3, 30, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = run(t, db, executor, models.CDR{CallUUID: "uuid-1", Callee: "13800000000", HangupCause: "NO_ANSWER", EndTime: end})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal(sms, &payload))
+	assert.Equal(t, "13800000000", payload["to"])
+	assert.Equal(t, "张三您好，稍后再联系您", payload["text"])
+}
+
+func TestExecutor_DefaultActions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	executor, err := wrapup.New(wrapup.Config{
+		Default: []wrapup.Action{
+			{Type: wrapup.ActionWebhook, URL: "http://hooks.example.com/default"},
+			{Type: wrapup.ActionCallback, Delay: time.Hour},
+		},
+	}, "")
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	// 未登记的通话使用默认动作，没有线索时跳过回拨
+	mock.ExpectQuery("FROM calls WHERE call_uuid").WithArgs("uuid-2").
+		WillReturnRows(sqlmock.NewRows(callColumns))
+	mock.ExpectExec("INSERT IGNORE INTO outbox").
+		WithArgs("wrapup:webhook:uuid-2", outbox.DestinationWebhook, "http://hooks.example.com/default", "call.wrapup",
+			sqlmock.AnyArg(), outbox.StatusPending, 0, 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = run(t, db, executor, models.CDR{CallUUID: "uuid-2", HangupCause: "NORMAL_CLEARING", EndTime: time.Now()})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// payloadArg 匹配任意消息内容并保存，用于检查序列化后的JSON
+type payloadArg struct {
+	dst *[]byte
+}
+
+// Match 实现sqlmock.Argument
+func (a payloadArg) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	if ok {
+		*a.dst = b
+	}
+	return ok
+}
@@ -0,0 +1,89 @@
+package ws_test
+
+import (
+	"bufio"
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/clients/xfyun"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/lifecycle"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider 开始识别即返回错误的识别后端
+type failingProvider struct {
+	err error
+}
+
+func (p failingProvider) Name() string { return "failing" }
+
+func (p failingProvider) OpenStream(string) (models.ASRStream, error) { return nil, p.err }
+
+func (p failingProvider) ReleaseSession(string) {}
+
+func (p failingProvider) Ping(context.Context) error { return p.err }
+
+func asrErrorCount(t *testing.T, kind, code string) float64 {
+	var out strings.Builder
+	require.NoError(t, metrics.Default.Write(&out))
+	prefix := `ai_dialer_asr_errors_total{kind="` + kind + `",code="` + code + `"} `
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), prefix); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return n
+		}
+	}
+	return 0
+}
+
+func TestASRServer_ASRErrorDiagnosis(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	asrServer := ws.NewASRServer(cfg, nil)
+	asrServer.ASR = asr.NewRouter(asr.FailoverConfig{}, failingProvider{
+		err: &xfyun.ServerError{Code: 11200, Message: "licc limit", SID: "iat001"},
+	})
+	server := httptest.NewServer(asrServer)
+	t.Cleanup(server.Close)
+	addr := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	assert.Equal(t, lifecycle.DependencyOK, asrServer.ASRDependency().Status)
+	before := asrErrorCount(t, xfyun.ErrorKindQuota, "11200")
+
+	closed := disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose)
+	conn := dialMic(t, addr)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, make([]byte, 640)))
+	require.Eventually(t, func() bool {
+		return asrErrorCount(t, xfyun.ErrorKindQuota, "11200") == before+1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	dep := asrServer.ASRDependency()
+	assert.Equal(t, lifecycle.DependencyError, dep.Status)
+	assert.Equal(t, xfyun.ErrorKindQuota, dep.Kind)
+	assert.Equal(t, "11200", dep.Code)
+	assert.Contains(t, dep.Remediation, "服务量")
+	assert.Equal(t, 1, dep.Failures)
+	assert.NotNil(t, dep.Since)
+
+	// 等待连接结束，避免影响其他测试的断开统计
+	require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose) == closed+1
+	}, 2*time.Second, 10*time.Millisecond)
+}
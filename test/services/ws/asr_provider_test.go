@@ -0,0 +1,48 @@
+package ws_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services/tenant"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASRServer_RejectsUnknownProviderAndTenant(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	asrServer := ws.NewASRServer(cfg, nil)
+	asrServer.Tenants = tenant.New(tenant.Config{Tenants: map[string]tenant.Tenant{"bank": {}}})
+	server := httptest.NewServer(asrServer)
+	t.Cleanup(server.Close)
+	addr := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// 未配置Whisper时只能使用讯飞
+	_, resp, err := websocket.DefaultDialer.Dial(addr+"?asr=whisper", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(addr+"?tenant=other", nil)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// 等待连接结束，避免影响其他测试的断开统计
+	closed := disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose)
+	conn := dialMic(t, addr+"?asr=xfyun&tenant=bank")
+	require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose) == closed+1
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,154 @@
+package ws_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/clients/asr"
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/models"
+	"ai_dialer_mini/internal/services/assist"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedStream 收到第一段音频即返回固定最终结果的识别会话
+type scriptedStream struct {
+	text    string
+	once    sync.Once
+	results chan models.ASRResult
+	done    chan struct{}
+}
+
+func (s *scriptedStream) Write(p []byte) (int, error) {
+	s.once.Do(func() {
+		s.results <- models.ASRResult{Text: s.text, IsFinal: true}
+		close(s.results)
+		close(s.done)
+	})
+	return len(p), nil
+}
+func (s *scriptedStream) Close() error                     { return nil }
+func (s *scriptedStream) Results() <-chan models.ASRResult { return s.results }
+func (s *scriptedStream) Done() <-chan struct{}            { return s.done }
+func (s *scriptedStream) Err() error                       { return nil }
+func (s *scriptedStream) Latency() time.Duration           { return 0 }
+
+// scriptedProvider 每次识别都返回text的识别后端
+type scriptedProvider struct {
+	text string
+}
+
+func (p scriptedProvider) Name() string { return "scripted" }
+
+func (p scriptedProvider) OpenStream(string) (models.ASRStream, error) {
+	return &scriptedStream{text: p.text, results: make(chan models.ASRResult, 1), done: make(chan struct{})}, nil
+}
+
+func (p scriptedProvider) ReleaseSession(string) {}
+
+func (p scriptedProvider) Ping(context.Context) error { return nil }
+
+// assistCalls 所有通话都是坐席通话
+type assistCalls struct{}
+
+func (assistCalls) AttachStream(string, string, func()) (func(), error) { return func() {}, nil }
+
+func (assistCalls) CallAssisted(string) bool { return true }
+
+// suggestDialog 以固定格式回复客户的对话服务
+type suggestDialog struct{}
+
+func (suggestDialog) ProcessMessage(sessionID, text string) (string, error) {
+	return "[cheerful]可以回答：" + text, nil
+}
+func (suggestDialog) GetHistory(string) []models.Message { return nil }
+func (suggestDialog) ClearHistory(string)                {}
+
+// capturedEvents 记录发布的通话事件
+type capturedEvents struct {
+	mu     sync.Mutex
+	events []models.CallEvent
+}
+
+func (c *capturedEvents) Publish(_ context.Context, event *models.CallEvent) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, *event)
+	return nil
+}
+
+func (c *capturedEvents) ofType(eventType string) []models.CallEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []models.CallEvent
+	for _, event := range c.events {
+		if event.Type == eventType {
+			out = append(out, event)
+		}
+	}
+	return out
+}
+
+func TestASRServer_AssistSuggestsWithoutSpeaking(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	asrServer := ws.NewASRServer(cfg, suggestDialog{})
+	asrServer.ASR = asr.NewRouter(asr.FailoverConfig{}, scriptedProvider{text: "价格是多少"})
+	asrServer.Calls = assistCalls{}
+	events := &capturedEvents{}
+	asrServer.Events = events
+	asrServer.Assist = assist.New(assist.Config{Enabled: true, Tokens: []string{"agent"}}, []string{"套餐价格是多少取决于套餐档位"})
+	messages, cancel := asrServer.Assist.Subscribe("uuid-1")
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asrServer.ServeHTTP(w, ws.WithCall(r, "uuid-1"))
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	var session map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&session))
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, make([]byte, 640)))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	var response ws.ASRResponse
+	require.NoError(t, conn.ReadJSON(&response))
+
+	// 建议话术只推送给坐席，不作为AI回复播放
+	assert.Equal(t, "价格是多少", response.Text)
+	assert.Equal(t, "可以回答：价格是多少", response.Suggestion)
+	assert.Empty(t, response.AIReply)
+	suggestions := events.ofType(models.EventTypeSuggestion)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "可以回答：价格是多少", suggestions[0].Text)
+	assert.Empty(t, events.ofType(models.EventTypeDialog))
+
+	// 坐席辅助通道依次收到客户转写、知识片段和建议话术
+	var received []assist.Message
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-messages:
+			received = append(received, msg)
+		case <-time.After(2 * time.Second):
+			t.Fatal("未收到坐席辅助消息")
+		}
+	}
+	assert.Equal(t, assist.MessageTranscript, received[0].Type)
+	assert.Equal(t, "价格是多少", received[0].Text)
+	assert.Equal(t, assist.MessageKnowledge, received[1].Type)
+	assert.Equal(t, []string{"套餐价格是多少取决于套餐档位"}, received[1].Snippets)
+	assert.Equal(t, assist.MessageSuggestion, received[2].Type)
+	assert.Equal(t, "可以回答：价格是多少", received[2].Text)
+}
@@ -0,0 +1,72 @@
+package ws_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASRServer_CloseAllConcurrentConnections(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	asrServer := ws.NewASRServer(cfg, nil)
+	server := httptest.NewServer(asrServer)
+	t.Cleanup(server.Close)
+	addr := "ws" + strings.TrimPrefix(server.URL, "http")
+	drained := disconnectCount(t, ws.EndpointMic, ws.DisconnectServerDrain)
+
+	const n = 50
+	conns := make([]*websocket.Conn, n)
+	var wg sync.WaitGroup
+	for i := range conns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conns[i] = dialMic(t, addr)
+			// 连接期间并发设置语法，各连接的状态互不影响
+			conns[i].WriteJSON(map[string]string{"grammar": "digits"})
+		}(i)
+	}
+	wg.Wait()
+	require.Eventually(t, func() bool { return asrServer.ActiveConnections() == n }, 2*time.Second, 10*time.Millisecond)
+
+	asrServer.CloseAll()
+	for _, conn := range conns {
+		_, _, err := conn.ReadMessage()
+		assert.Error(t, err)
+		conn.Close()
+	}
+	assert.Eventually(t, func() bool {
+		return asrServer.ActiveConnections() == 0 &&
+			disconnectCount(t, ws.EndpointMic, ws.DisconnectServerDrain) == drained+n
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestASRServer_HeartbeatClosesIdleConnection(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = 20 * time.Millisecond
+	cfg.WebSocket.PongWait = 100 * time.Millisecond
+	asrServer := ws.NewASRServer(cfg, nil)
+	server := httptest.NewServer(asrServer)
+	t.Cleanup(server.Close)
+	timedOut := disconnectCount(t, ws.EndpointMic, ws.DisconnectTimeout)
+
+	conn := dialMic(t, "ws"+strings.TrimPrefix(server.URL, "http"))
+	defer conn.Close()
+	_, _, err := conn.ReadMessage()
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool {
+		return asrServer.ActiveConnections() == 0 &&
+			disconnectCount(t, ws.EndpointMic, ws.DisconnectTimeout) == timedOut+1
+	}, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,102 @@
+package ws_test
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/routes"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// disconnectCount 从导出的指标中读取断开次数
+func disconnectCount(t *testing.T, endpoint, reason string) float64 {
+	var out strings.Builder
+	require.NoError(t, metrics.Default.Write(&out))
+	prefix := `ai_dialer_ws_disconnects_total{endpoint="` + endpoint + `",reason="` + reason + `"} `
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), prefix); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return n
+		}
+	}
+	return 0
+}
+
+func newMicServer(t *testing.T) string {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	server := httptest.NewServer(ws.NewASRServer(cfg, nil))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dialMic(t *testing.T, addr string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(addr, nil)
+	require.NoError(t, err)
+	// 读取会话ID消息
+	_, _, err = conn.ReadMessage()
+	require.NoError(t, err)
+	return conn
+}
+
+func TestDisconnect_ClientCloseAndNetworkError(t *testing.T) {
+	addr := newMicServer(t)
+	closed := disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose)
+	dropped := disconnectCount(t, ws.EndpointMic, ws.DisconnectNetworkError)
+
+	// 客户端正常发送关闭帧
+	conn := dialMic(t, addr)
+	require.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return disconnectCount(t, ws.EndpointMic, ws.DisconnectClientClose) == closed+1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// 连接未发送关闭帧即中断
+	conn = dialMic(t, addr)
+	conn.UnderlyingConn().Close()
+	assert.Eventually(t, func() bool {
+		return disconnectCount(t, ws.EndpointMic, ws.DisconnectNetworkError) == dropped+1
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// rejectVerifier 拒绝所有音频流地址
+type rejectVerifier struct{}
+
+func (rejectVerifier) Verify(string, url.Values) error {
+	return errors.New("签名已过期")
+}
+
+func TestDisconnect_AuthFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes.RegisterStreamRoutes(r, http.NotFoundHandler(), rejectVerifier{})
+	routes.RegisterMetricsRoutes(r)
+	before := disconnectCount(t, ws.EndpointCallStream, ws.DisconnectAuthFailure)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws/calls/call-1/stream", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, before+1, disconnectCount(t, ws.EndpointCallStream, ws.DisconnectAuthFailure))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `ai_dialer_ws_disconnects_total{endpoint="call_stream",reason="auth_failure"}`)
+}
@@ -0,0 +1,65 @@
+package ws_test
+
+import (
+	"testing"
+
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextMessage(t *testing.T) {
+	msg, err := ws.ParseTextMessage([]byte(`{"grammar": "builtin:digits"}`))
+	require.NoError(t, err)
+	require.NotNil(t, msg.Grammar)
+	assert.Nil(t, msg.Audio)
+	assert.Equal(t, "builtin:digits", msg.Grammar.Grammar)
+
+	// 音频数据不会被误当作语法设置
+	msg, err = ws.ParseTextMessage([]byte(`{"data": "AQACAA==", "format": "pcm", "is_end": true}`))
+	require.NoError(t, err)
+	require.NotNil(t, msg.Audio)
+	assert.Nil(t, msg.Grammar)
+	assert.Equal(t, []byte{1, 0, 2, 0}, msg.Audio.Data)
+	assert.True(t, msg.Audio.IsEnd)
+}
+
+func TestParseTextMessage_Invalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`null`,
+		`[]`,
+		`"grammar"`,
+		`{}`,
+		`{"format": "pcm"}`,
+		`{"grammar": 1}`,
+		`{"grammar": "a", "data": "AQA="}`,
+		`{"data": "not base64!"}`,
+		`{"data": "AQID"}`,
+	} {
+		_, err := ws.ParseTextMessage([]byte(data))
+		assert.ErrorIs(t, err, ws.ErrInvalidMessage, data)
+	}
+}
+
+func FuzzParseTextMessage(f *testing.F) {
+	f.Add([]byte(`{"grammar": "builtin:digits"}`))
+	f.Add([]byte(`{"data": "AQACAA==", "format": "pcm", "is_end": false}`))
+	f.Add([]byte(`{"data": null, "is_end": true}`))
+	f.Add([]byte(`{"grammar": null}`))
+	f.Add([]byte(`{"grammar": "a", "data": ""}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := ws.ParseTextMessage(data)
+		if err != nil {
+			return
+		}
+		if (msg.Grammar == nil) == (msg.Audio == nil) {
+			t.Fatalf("语法设置和音频数据必须二选一: %q", data)
+		}
+		if msg.Audio != nil && len(msg.Audio.Data)%2 != 0 {
+			t.Fatalf("音频数据长度为奇数: %q", data)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package ws_test
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/metrics"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func droppedCount(t *testing.T, endpoint, reason string) float64 {
+	var out strings.Builder
+	require.NoError(t, metrics.Default.Write(&out))
+	prefix := `ai_dialer_ws_messages_dropped_total{endpoint="` + endpoint + `",reason="` + reason + `"} `
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), prefix); ok {
+			n, err := strconv.ParseFloat(value, 64)
+			require.NoError(t, err)
+			return n
+		}
+	}
+	return 0
+}
+
+func TestASRServer_MessageMiddleware(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	cfg.WebSocket.MaxMessageRate = 3
+	asrServer := ws.NewASRServer(cfg, nil)
+
+	// 自定义中间件在解析之后执行，收到的是已解析的消息
+	var mu sync.Mutex
+	var grammars []string
+	asrServer.Use(func(c *ws.MessageContext) {
+		mu.Lock()
+		if assert.NotNil(t, c.Grammar) {
+			grammars = append(grammars, c.Grammar.Grammar)
+		}
+		mu.Unlock()
+		c.Next()
+	})
+	server := httptest.NewServer(asrServer)
+	t.Cleanup(server.Close)
+	invalid := droppedCount(t, ws.EndpointMic, ws.DropInvalid)
+	limited := droppedCount(t, ws.EndpointMic, ws.DropRateLimited)
+
+	conn := dialMic(t, "ws"+strings.TrimPrefix(server.URL, "http"))
+	defer conn.Close()
+	// 无法解析的消息同样占用限流额度，额度用完后的消息被丢弃
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	for _, grammar := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, conn.WriteJSON(map[string]string{"grammar": grammar}))
+	}
+
+	assert.Eventually(t, func() bool {
+		return droppedCount(t, ws.EndpointMic, ws.DropInvalid) == invalid+1 &&
+			droppedCount(t, ws.EndpointMic, ws.DropRateLimited) == limited+2
+	}, 2*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"a", "b"}, grammars)
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ws.RateLimit(2)
+	handled := 0
+	handle := func() {
+		c := &ws.MessageContext{Endpoint: ws.EndpointMic}
+		limit(c)
+		if c.Err == nil {
+			handled++
+		} else {
+			assert.ErrorIs(t, c.Err, ws.ErrRateLimited)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		handle()
+	}
+	assert.Equal(t, 2, handled)
+
+	// 令牌按速率恢复
+	time.Sleep(600 * time.Millisecond)
+	handle()
+	assert.Equal(t, 3, handled)
+}
+
+func TestDecodeMessage(t *testing.T) {
+	c := &ws.MessageContext{Type: websocket.BinaryMessage, Data: []byte{1, 0, 2, 0}}
+	ws.DecodeMessage(c)
+	require.NoError(t, c.Err)
+	require.NotNil(t, c.Audio)
+	assert.Equal(t, []byte{1, 0, 2, 0}, c.Audio.Data)
+	// 处理链正常执行完毕不算跳过
+	assert.False(t, c.IsAborted())
+
+	c = &ws.MessageContext{Type: websocket.TextMessage, Data: []byte(`{"grammar":"digits"}`)}
+	ws.DecodeMessage(c)
+	require.NotNil(t, c.Grammar)
+	assert.Equal(t, "digits", c.Grammar.Grammar)
+	assert.Nil(t, c.Audio)
+
+	c = &ws.MessageContext{Type: websocket.TextMessage, Data: []byte(`{}`)}
+	ws.DecodeMessage(c)
+	assert.ErrorIs(t, c.Err, ws.ErrInvalidMessage)
+	assert.True(t, c.IsAborted())
+}
@@ -0,0 +1,37 @@
+package ws_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/config"
+	"ai_dialer_mini/internal/services/ws"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASRServer_CheckOrigin(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.WebSocket.PingPeriod = time.Hour
+	cfg.WebSocket.PongWait = time.Minute
+	cfg.WebSocket.AllowedOrigins = []string{"https://*.example.com"}
+	server := httptest.NewServer(ws.NewASRServer(cfg, nil))
+	t.Cleanup(server.Close)
+	addr := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// 其他网站的页面不能连接
+	_, resp, err := websocket.DefaultDialer.Dial(addr, http.Header{"Origin": {"https://evil.com"}})
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 允许的来源
+	conn, _, err := websocket.DefaultDialer.Dial(addr, http.Header{"Origin": {"https://crm.example.com"}})
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,78 @@
+package services_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/services"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWSService_SlowClientDoesNotBlockOthers(t *testing.T) {
+	hub := services.NewWSService()
+	go hub.Run()
+
+	// 慢客户端连接后从不读取消息
+	dialHub(t, hub)
+	fast := dialHub(t, hub)
+	time.Sleep(50 * time.Millisecond)
+
+	// 慢客户端的套接字缓冲和发送缓冲填满后被断开，其他客户端持续收到消息
+	message := bytes.Repeat([]byte("x"), 64*1024)
+	deadline := time.Now().Add(3 * time.Second)
+	require.NoError(t, fast.SetReadDeadline(deadline))
+	for i := 0; i < 200; i++ {
+		hub.Broadcast(message)
+		_, data, err := fast.ReadMessage()
+		require.NoError(t, err, "第%d条消息", i+1)
+		assert.Len(t, data, len(message))
+	}
+	assert.True(t, time.Now().Before(deadline))
+}
+
+// dialEvents 连接只读的通话事件订阅
+func dialEvents(t *testing.T, hub *services.WSService, query string) *websocket.Conn {
+	ts := httptest.NewServer(http.HandlerFunc(hub.HandleEvents))
+	t.Cleanup(ts.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+query, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestWSService_EventSubscriptionFilters(t *testing.T) {
+	hub := services.NewWSService()
+	go hub.Run()
+
+	all := dialEvents(t, hub, "")
+	hangups := dialEvents(t, hub, "?types=call_answered,call_hangup&session_id=uuid-1")
+	time.Sleep(50 * time.Millisecond)
+
+	// 订阅连接发送的消息不转发
+	require.NoError(t, all.WriteMessage(websocket.TextMessage, []byte(`{"type":"call_hangup","session_id":"uuid-1"}`)))
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast([]byte(`{"type":"call_created","session_id":"uuid-1"}`))
+	hub.Broadcast([]byte(`{"type":"call_hangup","session_id":"uuid-2"}`))
+	hub.Broadcast([]byte(`{"type":"call_hangup","session_id":"uuid-1","cause":"NORMAL_CLEARING"}`))
+
+	require.NoError(t, hangups.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, data, err := hangups.ReadMessage()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"call_hangup","session_id":"uuid-1","cause":"NORMAL_CLEARING"}`, string(data))
+
+	require.NoError(t, all.SetReadDeadline(time.Now().Add(2*time.Second)))
+	for _, want := range []string{"call_created", "call_hangup", "call_hangup"} {
+		_, data, err := all.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), want)
+	}
+}
@@ -0,0 +1,138 @@
+package utils_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ai_dialer_mini/internal/utils"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const handshake = "GET /v2/iat?host=iat-api.xfyun.cn HTTP/1.1\r\n" +
+	"Host: iat-api.xfyun.cn\r\n" +
+	"Upgrade: websocket\r\n" +
+	"Connection: Upgrade\r\n" +
+	"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+	"Sec-WebSocket-Version: 13\r\n\r\n"
+
+// tcpPacket 构造以太网/IPv4/TCP数据包
+func tcpPacket(t *testing.T, payload []byte) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{6, 7, 8, 9, 10, 11},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.IP{10, 0, 0, 1},
+		DstIP:    net.IP{10, 0, 0, 2},
+	}
+	tcp := &layers.TCP{SrcPort: 50000, DstPort: 443, PSH: true, ACK: true, Window: 1024}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)))
+	return buf.Bytes()
+}
+
+// writePCAP 将数据包写入pcap文件
+func writePCAP(t *testing.T, packets ...[]byte) string {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := pcapgo.NewWriter(f)
+	require.NoError(t, w.WriteFileHeader(65536, layers.LinkTypeEthernet))
+	for _, data := range packets {
+		ci := gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}
+		require.NoError(t, w.WritePacket(ci, data))
+	}
+	return path
+}
+
+func TestPCAPReader(t *testing.T) {
+	text := []byte(`{"data":{"status":0}}`)
+	frame := append([]byte{0x81, byte(len(text))}, text...)
+	path := writePCAP(t, tcpPacket(t, []byte(handshake)), tcpPacket(t, frame))
+
+	reader, err := utils.NewPCAPReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	hs, err := reader.ExtractWebSocketHandshake()
+	require.NoError(t, err)
+	require.NotNil(t, hs)
+	assert.Equal(t, "/v2/iat?host=iat-api.xfyun.cn", hs.Path)
+	assert.Equal(t, "dGhlIHNhbXBsZSBub25jZQ==", hs.Key)
+	assert.Equal(t, "13", hs.Version)
+
+	frames, err := reader.ReadWebSocketFrames()
+	require.NoError(t, err)
+	assert.Contains(t, frames, text)
+}
+
+func TestPCAPReader_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.pcap")
+	require.NoError(t, os.WriteFile(path, []byte("not a capture"), 0o644))
+
+	_, err := utils.NewPCAPReader(path)
+	assert.Error(t, err)
+
+	_, err = utils.NewPCAPReader(filepath.Join(t.TempDir(), "missing.pcap"))
+	assert.Error(t, err)
+}
+
+func TestScanWebSocketFrames(t *testing.T) {
+	text := []byte("你好")
+	masked := []byte{0x81, 0x80 | byte(len(text)), 1, 2, 3, 4}
+	for i, b := range text {
+		masked = append(masked, b^[]byte{1, 2, 3, 4}[i%4])
+	}
+	assert.Equal(t, [][]byte{text}, utils.ScanWebSocketFrames(masked))
+
+	// 扩展长度
+	long := make([]byte, 300)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	frame := append([]byte{0x82, 126, 0x01, 0x2c}, long...)
+	assert.Equal(t, [][]byte{long}, utils.ScanWebSocketFrames(frame))
+
+	// 不规范的扩展长度、64位长度和截断的帧
+	assert.Empty(t, utils.ScanWebSocketFrames([]byte{0x81, 126, 0, 2, 'h', 'i'}))
+	assert.Empty(t, utils.ScanWebSocketFrames([]byte{0x82, 127, 0, 0, 0, 0, 0, 0, 0, 2, 1, 2}))
+	assert.Empty(t, utils.ScanWebSocketFrames([]byte{0x81, 5, 'h', 'i'}))
+}
+
+func FuzzScanWebSocketFrames(f *testing.F) {
+	f.Add([]byte{0x81, 2, 'h', 'i'})
+	f.Add([]byte{0x82, 0x82, 1, 2, 3, 4, 5, 6})
+	f.Add([]byte{0x81, 126, 0xff, 0xff})
+	f.Add([]byte{0x82, 127, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		total := 0
+		for _, frame := range utils.ScanWebSocketFrames(data) {
+			if len(frame) == 0 || len(frame) > 65535 {
+				t.Fatalf("无效的帧长度: %d", len(frame))
+			}
+			total += len(frame)
+		}
+		// 扫描出的帧互不重叠，总长度不超过输入
+		if total > len(data) {
+			t.Fatalf("帧总长度%d超过输入长度%d", total, len(data))
+		}
+	})
+}